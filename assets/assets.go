@@ -1,13 +1,18 @@
 package assets
 
 import (
+	"container/list"
 	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"path"
 	"sync"
 
 	"github.com/gopxl/beep/v2"
 	"github.com/gopxl/beep/v2/mp3"
+	"golang.org/x/sync/singleflight"
 )
 
 //go:embed audio
@@ -15,14 +20,35 @@ var AudioFS embed.FS
 
 // PredecodedAudio holds a predecoded audio stream and its format
 type PredecodedAudio struct {
-	Buffer beep.Buffer
+	Buffer *beep.Buffer
 	Format beep.Format
 }
 
-// PredecodedCache holds all predecoded audio files
+// size returns the memory used by the decoded samples in bytes
+func (a *PredecodedAudio) size() int64 {
+	// beep stores each frame as two float64 samples
+	return int64(a.Buffer.Len()) * 16
+}
+
+// cacheEntry is an element of the LRU list
+type cacheEntry struct {
+	filePath string
+	audio    *PredecodedAudio
+}
+
+// PredecodedCache holds predecoded audio files. Files are decoded on first
+// use unless Preload is called, and the least recently used ones are evicted
+// once the decoded size exceeds the memory budget.
 type PredecodedCache struct {
-	mu    sync.RWMutex
-	cache map[string]*PredecodedAudio
+	mu     sync.Mutex
+	fsys   fs.FS
+	dir    string // assets directory overlaying fsys, empty if none
+	cache  map[string]*list.Element
+	lru    *list.List
+	size   int64
+	budget int64
+	group  singleflight.Group
+	gen    map[string]int // bumped by Invalidate, so stale decodes aren't cached
 }
 
 var (
@@ -34,70 +60,192 @@ var (
 func GetPredecodedCache() *PredecodedCache {
 	initOnce.Do(func() {
 		predecodedCache = &PredecodedCache{
-			cache: make(map[string]*PredecodedAudio),
+			fsys:  AudioFS,
+			cache: make(map[string]*list.Element),
+			lru:   list.New(),
+			gen:   make(map[string]int),
 		}
-		predecodedCache.loadAllAudio()
 	})
 	return predecodedCache
 }
 
-// loadAllAudio preloads and decodes all MP3 files in the audio directory
-func (pc *PredecodedCache) loadAllAudio() {
+// SetBudget sets the maximum size in bytes of decoded audio kept in memory.
+// Zero or less means unlimited.
+func (pc *PredecodedCache) SetBudget(budget int64) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.budget = budget
+	pc.evictLocked(nil)
+}
+
+// Preload decodes all MP3 files in the audio directory. A file failing to
+// decode doesn't stop the others, the failures are returned together.
+func (pc *PredecodedCache) Preload() error {
 	log.Println("Preloading and decoding audio files...")
 
-	entries, err := AudioFS.ReadDir("audio")
+	entries, err := fs.ReadDir(pc.files(), "audio")
 	if err != nil {
-		log.Fatalf("Failed to read audio directory: %v", err)
+		return fmt.Errorf("failed to read audio directory: %w", err)
 	}
 
+	var errs []error
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
-		filePath := fmt.Sprintf("audio/%s", entry.Name())
-		if err := pc.preloadFile(filePath); err != nil {
+		filePath := path.Join("audio", entry.Name())
+		if _, err := pc.Load(filePath); err != nil {
 			log.Printf("Failed to preload %s: %v", filePath, err)
+			errs = append(errs, err)
 		} else {
 			log.Printf("Successfully preloaded %s", filePath)
 		}
 	}
 
-	log.Printf("Preloading complete. Loaded %d audio files.", len(pc.cache))
+	log.Printf("Preloading complete. Loaded %d audio files.", pc.Len())
+	return errors.Join(errs...)
 }
 
-// preloadFile loads and decodes a single MP3 file into the cache
-func (pc *PredecodedCache) preloadFile(filePath string) error {
-	file, err := AudioFS.Open(filePath)
+// decodeFile loads and decodes a single MP3 file
+func (pc *PredecodedCache) decodeFile(filePath string) (*PredecodedAudio, error) {
+	file, err := pc.files().Open(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to open file %s: %w", filePath, err)
+		return nil, fmt.Errorf("failed to open file %s: %w", filePath, err)
 	}
 	defer file.Close()
 
 	streamer, format, err := mp3.Decode(file)
 	if err != nil {
-		return fmt.Errorf("failed to decode MP3 %s: %w", filePath, err)
+		return nil, fmt.Errorf("failed to decode MP3 %s: %w", filePath, err)
 	}
 
 	// Convert streamer to buffer to store in memory
 	buffer := beep.NewBuffer(format)
 	buffer.Append(streamer)
 	streamer.Close()
+	log.Printf("Decoded %s from %s", filePath, pc.Source(filePath))
 
-	pc.mu.Lock()
-	pc.cache[filePath] = &PredecodedAudio{
-		Buffer: *buffer,
+	return &PredecodedAudio{
+		Buffer: buffer,
 		Format: format,
+	}, nil
+}
+
+// Load returns a predecoded audio file, decoding it if it isn't cached.
+// Concurrent loads of the same file share a single decode.
+func (pc *PredecodedCache) Load(filePath string) (*PredecodedAudio, error) {
+	pc.mu.Lock()
+	if elem, ok := pc.cache[filePath]; ok {
+		pc.lru.MoveToFront(elem)
+		pc.mu.Unlock()
+		return elem.Value.(*cacheEntry).audio, nil
 	}
 	pc.mu.Unlock()
 
-	return nil
+	v, err, _ := pc.group.Do(filePath, func() (interface{}, error) {
+		pc.mu.Lock()
+		gen := pc.gen[filePath]
+		pc.mu.Unlock()
+
+		audio, err := pc.decodeFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		pc.mu.Lock()
+		defer pc.mu.Unlock()
+
+		if elem, ok := pc.cache[filePath]; ok {
+			return elem.Value.(*cacheEntry).audio, nil
+		}
+		if pc.gen[filePath] != gen {
+			// The file changed while it was decoded, play it but let the
+			// next load decode it again
+			return audio, nil
+		}
+		elem := pc.lru.PushFront(&cacheEntry{filePath: filePath, audio: audio})
+		pc.cache[filePath] = elem
+		pc.size += audio.size()
+		pc.evictLocked(elem)
+		return audio, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*PredecodedAudio), nil
+}
+
+// evictLocked drops least recently used entries until the cache fits the
+// budget, never evicting keep
+func (pc *PredecodedCache) evictLocked(keep *list.Element) {
+	if pc.budget <= 0 {
+		return
+	}
+
+	for pc.size > pc.budget {
+		elem := pc.lru.Back()
+		if elem == nil || elem == keep {
+			return
+		}
+		entry := elem.Value.(*cacheEntry)
+		pc.lru.Remove(elem)
+		delete(pc.cache, entry.filePath)
+		pc.size -= entry.audio.size()
+		log.Printf("Evicted %s from the audio cache", entry.filePath)
+	}
 }
 
 // GetAudio retrieves a predecoded audio file
 func (pc *PredecodedCache) GetAudio(filePath string) (*PredecodedAudio, bool) {
-	pc.mu.RLock()
-	defer pc.mu.RUnlock()
+	audio, err := pc.Load(filePath)
+	if err != nil {
+		log.Printf("Failed to load %s: %v", filePath, err)
+		return nil, false
+	}
+	return audio, true
+}
+
+// Streamer returns a streamer over a cached file with trimStart samples cut
+// from the beginning and trimEnd from the end. Clips too short to be trimmed
+// are played in full rather than producing an invalid range.
+func (pc *PredecodedCache) Streamer(filePath string, trimStart, trimEnd int) (beep.StreamSeeker, beep.Format, error) {
+	audio, err := pc.Load(filePath)
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
 
-	audio, exists := pc.cache[filePath]
-	return audio, exists
+	length := audio.Buffer.Len()
+	from, to := trimStart, length-trimEnd
+	if from < 0 || to > length || from >= to {
+		log.Printf("Clip %s is too short to trim (%d samples), playing it in full", filePath, length)
+		from, to = 0, length
+	}
+
+	return audio.Buffer.Streamer(from, to), audio.Format, nil
+}
+
+// Invalidate drops filePath from the cache, so it's decoded again on its
+// next load. It reports whether the file was cached.
+func (pc *PredecodedCache) Invalidate(filePath string) bool {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.gen[filePath]++
+	pc.group.Forget(filePath)
+	elem, ok := pc.cache[filePath]
+	if !ok {
+		return false
+	}
+	pc.lru.Remove(elem)
+	delete(pc.cache, filePath)
+	pc.size -= elem.Value.(*cacheEntry).audio.size()
+	return true
+}
+
+// Len returns the number of decoded files currently cached
+func (pc *PredecodedCache) Len() int {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return len(pc.cache)
 }
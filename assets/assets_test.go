@@ -0,0 +1,194 @@
+package assets
+
+import (
+	"bytes"
+	"container/list"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/generators"
+)
+
+// newTestCache returns a cache holding a clip of the given length
+func newTestCache(t *testing.T, filePath string, samples int) *PredecodedCache {
+	t.Helper()
+
+	format := beep.Format{SampleRate: 48000, NumChannels: 2, Precision: 2}
+	buffer := beep.NewBuffer(format)
+	buffer.Append(generators.Silence(samples))
+
+	pc := &PredecodedCache{
+		cache: make(map[string]*list.Element),
+		lru:   list.New(),
+		gen:   make(map[string]int),
+	}
+	audio := &PredecodedAudio{Buffer: buffer, Format: format}
+	pc.cache[filePath] = pc.lru.PushFront(&cacheEntry{filePath: filePath, audio: audio})
+	pc.size = audio.size()
+	return pc
+}
+
+// silentMP3 returns four frames of MPEG-1 layer III silence, 4608 samples
+func silentMP3() []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	return bytes.Repeat(frame, 4)
+}
+
+// countingFS counts the files opened and holds each open until release is
+// closed
+type countingFS struct {
+	fs.FS
+	opens   atomic.Int32
+	release chan struct{}
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.opens.Add(1)
+	<-c.release
+	return c.FS.Open(name)
+}
+
+// newFSCache returns an empty cache over fsys
+func newFSCache(fsys fs.FS) *PredecodedCache {
+	return &PredecodedCache{
+		fsys:  fsys,
+		cache: make(map[string]*list.Element),
+		lru:   list.New(),
+		gen:   make(map[string]int),
+	}
+}
+
+func TestLoadEvictsLeastRecentlyUsed(t *testing.T) {
+	data := silentMP3()
+	pc := newFSCache(fstest.MapFS{
+		"audio/a.mp3": {Data: data},
+		"audio/b.mp3": {Data: data},
+		"audio/c.mp3": {Data: data},
+	})
+
+	a, err := pc.Load("audio/a.mp3")
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	// Room for two files
+	pc.SetBudget(2 * a.size())
+
+	if _, err := pc.Load("audio/b.mp3"); err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	// a is now more recent than b
+	if _, err := pc.Load("audio/a.mp3"); err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if _, err := pc.Load("audio/c.mp3"); err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+
+	if got := pc.Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2", got)
+	}
+	for filePath, want := range map[string]bool{"audio/a.mp3": true, "audio/b.mp3": false, "audio/c.mp3": true} {
+		if _, ok := pc.cache[filePath]; ok != want {
+			t.Errorf("%s cached = %v, want %v", filePath, ok, want)
+		}
+	}
+
+	// A file larger than the budget is still kept until the next load
+	pc.SetBudget(1)
+	if got := pc.Len(); got != 0 {
+		t.Errorf("Len() after shrinking the budget = %d, want 0", got)
+	}
+	if _, err := pc.Load("audio/b.mp3"); err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if _, ok := pc.cache["audio/b.mp3"]; !ok || pc.Len() != 1 {
+		t.Errorf("the file just loaded was evicted, Len() = %d", pc.Len())
+	}
+}
+
+func TestLoadSharesDecode(t *testing.T) {
+	fsys := &countingFS{
+		FS:      fstest.MapFS{"audio/a.mp3": {Data: silentMP3()}},
+		release: make(chan struct{}),
+	}
+	pc := newFSCache(fsys)
+
+	const loaders = 8
+	results := make([]*PredecodedAudio, loaders)
+	var wg sync.WaitGroup
+	for i := range loaders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			audio, err := pc.Load("audio/a.mp3")
+			if err != nil {
+				t.Errorf("Load() = %v", err)
+			}
+			results[i] = audio
+		}()
+	}
+
+	// Let the other loads queue up behind the first decode
+	for fsys.opens.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(fsys.release)
+	wg.Wait()
+
+	if got := fsys.opens.Load(); got != 1 {
+		t.Errorf("the file was opened %d times, want once", got)
+	}
+	for i, audio := range results {
+		if audio == nil || audio != results[0] {
+			t.Errorf("load %d got a different decode", i)
+		}
+	}
+	if got := pc.Len(); got != 1 {
+		t.Errorf("Len() = %d, want 1", got)
+	}
+}
+
+func TestStreamerTrimsLongClip(t *testing.T) {
+	pc := newTestCache(t, "audio/long.mp3", 20000)
+
+	streamer, _, err := pc.Streamer("audio/long.mp3", 3000, 7000)
+	if err != nil {
+		t.Fatalf("Streamer() = %v", err)
+	}
+	if got := streamer.Len(); got != 10000 {
+		t.Errorf("trimmed length = %d, want 10000", got)
+	}
+}
+
+func TestStreamerShortClip(t *testing.T) {
+	// Shorter than the trim offsets combined, which used to panic
+	pc := newTestCache(t, "audio/short.mp3", 5000)
+
+	streamer, _, err := pc.Streamer("audio/short.mp3", 3000, 7000)
+	if err != nil {
+		t.Fatalf("Streamer() = %v", err)
+	}
+	if got := streamer.Len(); got != 5000 {
+		t.Errorf("short clip length = %d, want the full 5000", got)
+	}
+
+	buf := make([][2]float64, 512)
+	total := 0
+	for {
+		n, ok := streamer.Stream(buf)
+		total += n
+		if !ok {
+			break
+		}
+	}
+	if total != 5000 {
+		t.Errorf("streamed %d samples, want 5000", total)
+	}
+}
@@ -0,0 +1,128 @@
+package assets
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SourceEmbedded is the source of prompts built into the binary
+const SourceEmbedded = "embedded"
+
+// overlayFS serves the files of an external directory over the embedded
+// audio directory, at the same paths: audio/x.mp3 is x.mp3 in the
+// directory if it's there
+type overlayFS struct {
+	dir      fs.FS
+	dirName  string // shown as the source of its files
+	embedded fs.FS
+}
+
+// external returns the path in the external directory of an audio path
+func (o overlayFS) external(name string) (string, bool) {
+	rel, ok := strings.CutPrefix(name, "audio/")
+	return rel, ok && o.dir != nil
+}
+
+// Open opens name in the external directory, or else in the embedded files
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if rel, ok := o.external(name); ok {
+		f, err := o.dir.Open(rel)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return o.embedded.Open(name)
+}
+
+// ReadDir lists both directories, the external files shadowing the
+// embedded ones of the same name
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	embedded, embeddedErr := fs.ReadDir(o.embedded, name)
+
+	var external []fs.DirEntry
+	externalErr := fs.ErrNotExist
+	if name == "audio" && o.dir != nil {
+		external, externalErr = fs.ReadDir(o.dir, ".")
+	} else if rel, ok := o.external(name); ok {
+		external, externalErr = fs.ReadDir(o.dir, rel)
+	}
+	if embeddedErr != nil && externalErr != nil {
+		return nil, embeddedErr
+	}
+
+	entries := external
+	for _, entry := range embedded {
+		if !slices.ContainsFunc(external, func(e fs.DirEntry) bool { return e.Name() == entry.Name() }) {
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, nil
+}
+
+// source returns where name is read from, the external directory or
+// SourceEmbedded
+func (o overlayFS) source(name string) string {
+	if rel, ok := o.external(name); ok {
+		if _, err := fs.Stat(o.dir, rel); err == nil {
+			return filepath.Join(o.dirName, filepath.FromSlash(rel))
+		}
+	}
+	return SourceEmbedded
+}
+
+// SetDir makes the prompts in dir override or extend the embedded ones,
+// audio/x.mp3 being read from dir/x.mp3 if it exists. An empty dir only
+// serves the embedded prompts. Cached prompts are dropped.
+func (pc *PredecodedCache) SetDir(dir string) error {
+	var fsys fs.FS = AudioFS
+	if dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("failed to open the audio assets directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("audio assets directory %s is not a directory", dir)
+		}
+		fsys = overlayFS{dir: os.DirFS(dir), dirName: dir, embedded: AudioFS}
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.fsys = fsys
+	pc.dir = dir
+	for filePath := range pc.cache {
+		pc.gen[filePath]++
+		pc.group.Forget(filePath)
+	}
+	pc.cache = make(map[string]*list.Element)
+	pc.lru.Init()
+	pc.size = 0
+	return nil
+}
+
+// Source returns where filePath is read from, a file of the assets directory
+// or SourceEmbedded
+func (pc *PredecodedCache) Source(filePath string) string {
+	if o, ok := pc.files().(overlayFS); ok {
+		return o.source(filePath)
+	}
+	return SourceEmbedded
+}
+
+// files returns the file system the prompts are read from
+func (pc *PredecodedCache) files() fs.FS {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.fsys
+}
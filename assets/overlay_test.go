@@ -0,0 +1,74 @@
+package assets
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOverlayFS(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{"ringback.mp3": "custom", "extra.mp3": "extra"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o := overlayFS{
+		dir:     os.DirFS(dir),
+		dirName: dir,
+		embedded: fstest.MapFS{
+			"audio/ringback.mp3": {Data: []byte("embedded")},
+			"audio/welcome.mp3":  {Data: []byte("embedded")},
+		},
+	}
+
+	for name, want := range map[string]string{
+		"audio/ringback.mp3": "custom",
+		"audio/extra.mp3":    "extra",
+		"audio/welcome.mp3":  "embedded",
+	} {
+		data, err := fs.ReadFile(o, name)
+		if err != nil || string(data) != want {
+			t.Errorf("ReadFile(%s) = %q, %v, want %q", name, data, err, want)
+		}
+	}
+	if _, err := o.Open("audio/missing.mp3"); err == nil {
+		t.Error("Open() of a missing prompt succeeded")
+	}
+
+	entries, err := fs.ReadDir(o, "audio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"extra.mp3", "ringback.mp3", "welcome.mp3"}; !slices.Equal(names, want) {
+		t.Errorf("ReadDir() = %q, want %q", names, want)
+	}
+
+	if got, want := o.source("audio/ringback.mp3"), filepath.Join(dir, "ringback.mp3"); got != want {
+		t.Errorf("source() = %q, want %q", got, want)
+	}
+	if got := o.source("audio/welcome.mp3"); got != SourceEmbedded {
+		t.Errorf("source() = %q, want %q", got, SourceEmbedded)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	pc := newTestCache(t, "audio/long.mp3", 20000)
+
+	if !pc.Invalidate("audio/long.mp3") {
+		t.Error("Invalidate() of a cached prompt = false")
+	}
+	if pc.Len() != 0 || pc.size != 0 {
+		t.Errorf("after Invalidate() Len() = %d, size = %d", pc.Len(), pc.size)
+	}
+	if pc.Invalidate("audio/long.mp3") {
+		t.Error("Invalidate() of an uncached prompt = true")
+	}
+}
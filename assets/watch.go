@@ -0,0 +1,82 @@
+package assets
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch reloads the prompts of the assets directory set with SetDir when
+// they change, so they can be edited without restarting. A prompt that was
+// cached is decoded again right away, others on their next use. stop ends
+// the watch.
+func (pc *PredecodedCache) Watch() (stop func(), err error) {
+	pc.mu.Lock()
+	dir := pc.dir
+	pc.mu.Unlock()
+	if dir == "" {
+		return nil, errors.New("no audio assets directory to watch")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch the audio assets directory: %w", err)
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch the audio assets directory: %w", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				pc.reload(event)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Watching the audio assets directory failed: %v", err)
+			}
+		}
+	}()
+
+	return func() {
+		watcher.Close()
+		<-done
+	}, nil
+}
+
+// reload drops the prompt changed by event from the cache, decoding it again
+// if it was cached
+func (pc *PredecodedCache) reload(event fsnotify.Event) {
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) &&
+		!event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+		return
+	}
+
+	filePath := path.Join("audio", filepath.Base(event.Name))
+	if !pc.Invalidate(filePath) {
+		return
+	}
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		log.Printf("%s was removed, using %s", event.Name, pc.Source(filePath))
+		return
+	}
+
+	// A file being written may not decode yet, it's then loaded on its next
+	// use
+	if _, err := pc.Load(filePath); err != nil {
+		log.Printf("Failed to reload %s: %v", filePath, err)
+		pc.Invalidate(filePath)
+	}
+}
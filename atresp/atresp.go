@@ -0,0 +1,148 @@
+// Package atresp reads the responses of AT commands, the lines the modem
+// answers before OK. Information lines like +CSQ: 20,99 are split into their
+// fields, respecting quotes, so call sites don't each re-parse them.
+package atresp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Payload returns what follows prefix and its colon in line, trimmed, e.g.
+// 20,99 for +CSQ: 20,99. ok is false if line doesn't start with prefix.
+func Payload(line, prefix string) (payload string, ok bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), prefix+":")
+	if !ok {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// Find returns the fields of the first line of response starting with
+// prefix, e.g. +CSQ for +CSQ: 20,99. The ? of a read command is ignored, so
+// the command itself can be passed.
+func Find(response []string, prefix string) (Fields, bool) {
+	prefix = strings.TrimSuffix(prefix, "?")
+	for _, line := range response {
+		if payload, ok := Payload(line, prefix); ok {
+			return Split(payload), true
+		}
+	}
+	return nil, false
+}
+
+// FindAll returns the fields of every line of response starting with
+// prefix, e.g. each call of +CLCC
+func FindAll(response []string, prefix string) []Fields {
+	prefix = strings.TrimSuffix(prefix, "?")
+	var all []Fields
+	for _, line := range response {
+		if payload, ok := Payload(line, prefix); ok {
+			all = append(all, Split(payload))
+		}
+	}
+	return all
+}
+
+// Keyed returns the lines starting with prefix by their first field, for
+// the answers listing settings by name like +QCFG: "nwscanmode",0. The key
+// is left out of the fields. A key seen twice keeps its first line.
+func Keyed(response []string, prefix string) map[string]Fields {
+	keyed := make(map[string]Fields)
+	for _, fields := range FindAll(response, prefix) {
+		if len(fields) == 0 {
+			continue
+		}
+		if _, ok := keyed[fields[0]]; !ok {
+			keyed[fields[0]] = fields[1:]
+		}
+	}
+	return keyed
+}
+
+// TrimEcho returns response without the echo of cmd, e.g. AT+CSQ when the
+// modem echoes commands, and without empty lines
+func TrimEcho(response []string, cmd string) []string {
+	echo := "AT" + strings.TrimPrefix(strings.ToUpper(cmd), "AT")
+	trimmed := make([]string, 0, len(response))
+	for _, line := range response {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.EqualFold(line, echo) {
+			continue
+		}
+		trimmed = append(trimmed, line)
+	}
+	return trimmed
+}
+
+// Split splits comma separated fields, ignoring the commas within quotes or
+// parentheses. Fields are trimmed and unquoted, a list in parentheses like
+// (0-5) is kept as one field.
+func Split(s string) Fields {
+	var (
+		fields Fields
+		field  strings.Builder
+		quoted bool
+		depth  int
+	)
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+			field.WriteRune(r)
+		case r == '(':
+			depth++
+			field.WriteRune(r)
+		case r == ')' && depth > 0:
+			depth--
+			field.WriteRune(r)
+		case r == ',' && depth == 0:
+			fields = append(fields, strings.TrimSpace(field.String()))
+			field.Reset()
+		default:
+			field.WriteRune(r)
+		}
+	}
+	return append(fields, strings.TrimSpace(field.String()))
+}
+
+// Unquote trims s and the quotes around it, e.g. "+33612345678"
+func Unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// Fields are the comma separated values of a line, unquoted
+type Fields []string
+
+// String returns the field at i, empty if the line has fewer
+func (f Fields) String(i int) string {
+	if i < 0 || i >= len(f) {
+		return ""
+	}
+	return f[i]
+}
+
+// Int returns the field at i as a number, false if it's missing or isn't
+// one
+func (f Fields) Int(i int) (int, bool) {
+	n, err := strconv.Atoi(f.String(i))
+	return n, err == nil
+}
+
+// Ints returns every field as a number, false if one isn't
+func (f Fields) Ints() ([]int, bool) {
+	values := make([]int, len(f))
+	for i := range f {
+		n, ok := f.Int(i)
+		if !ok {
+			return nil, false
+		}
+		values[i] = n
+	}
+	return values, true
+}
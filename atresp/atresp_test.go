@@ -0,0 +1,159 @@
+package atresp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Fields
+	}{
+		{"20,99", Fields{"20", "99"}},
+		{" 20 , 99 ", Fields{"20", "99"}},
+		{`0,0,"Orange F",7`, Fields{"0", "0", "Orange F", "7"}},
+		{`"REC READ","+33612345678",,"24/01/01,12:00:00+04"`, Fields{"REC READ", "+33612345678", "", "24/01/01,12:00:00+04"}},
+		{`1,"+33612345678",145,"Doe, John"`, Fields{"1", "+33612345678", "145", "Doe, John"}},
+		{`(1-250),40,18`, Fields{"(1-250)", "40", "18"}},
+		{`("signal",(0-5)),("service",(0,1))`, Fields{"(signal,(0-5))", "(service,(0,1))"}},
+		{`"a (b", c`, Fields{"a (b", "c"}},
+		{"", Fields{""}},
+		{",", Fields{"", ""}},
+	}
+	for _, tt := range tests {
+		if got := Split(tt.s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Split(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	for s, want := range map[string]string{
+		`"+33612345678"`: "+33612345678",
+		` "LTE" `:        "LTE",
+		`""`:             "",
+		`"`:              `"`,
+		`20`:             "20",
+		`"a","b"`:        `a","b`,
+	} {
+		if got := Unquote(s); got != want {
+			t.Errorf("Unquote(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestPayload(t *testing.T) {
+	tests := []struct {
+		line, prefix, want string
+		ok                 bool
+	}{
+		{"+CSQ: 20,99", "+CSQ", "20,99", true},
+		{"+CSQ:20,99", "+CSQ", "20,99", true},
+		{"  +CMUT: 1  ", "+CMUT", "1", true},
+		{"+CSQ: ", "+CSQ", "", true},
+		{"+CESQ: 99,99", "+CSQ", "", false},
+		{"+CSQ 20,99", "+CSQ", "", false},
+		{"AT+CSQ", "+CSQ", "", false},
+		{"+CSQN: 1", "+CSQ", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Payload(tt.line, tt.prefix)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Payload(%q, %q) = %q, %v, want %q, %v", tt.line, tt.prefix, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	response := []string{"AT+COPS?", `+COPS: 0,0,"Orange F",7`, `+COPS: 1,0,"SFR",2`}
+
+	fields, ok := Find(response, "+COPS?")
+	if want := (Fields{"0", "0", "Orange F", "7"}); !ok || !reflect.DeepEqual(fields, want) {
+		t.Errorf("Find() = %q, %v, want %q", fields, ok, want)
+	}
+	if fields, ok := Find(response, "+CSQ"); ok || fields != nil {
+		t.Errorf("Find() of a missing prefix = %q, %v", fields, ok)
+	}
+	if fields, ok := Find(nil, "+CSQ"); ok || fields.String(0) != "" {
+		t.Errorf("Find(nil) = %q, %v", fields, ok)
+	}
+
+	all := FindAll([]string{
+		`+CLCC: 1,1,4,0,0,"+33612345678",145`,
+		"OK",
+		`+CLCC: 2,0,0,0,0,"+33698765432",145`,
+	}, "+CLCC")
+	if len(all) != 2 || all[0].String(5) != "+33612345678" || all[1].String(5) != "+33698765432" {
+		t.Errorf("FindAll() = %q", all)
+	}
+	if all := FindAll(response, "+CSQ"); all != nil {
+		t.Errorf("FindAll() of a missing prefix = %q", all)
+	}
+}
+
+func TestKeyed(t *testing.T) {
+	keyed := Keyed([]string{
+		`+QCFG: "nwscanmode",0`,
+		`+QCFG: "band",0x260,0x42000000000000381a,0x0`,
+		`+QCFG: "nwscanmode",3`,
+		`+QCSQ: "LTE",-52`,
+	}, "+QCFG")
+
+	want := map[string]Fields{
+		"nwscanmode": {"0"},
+		"band":       {"0x260", "0x42000000000000381a", "0x0"},
+	}
+	if !reflect.DeepEqual(keyed, want) {
+		t.Errorf("Keyed() = %q, want %q", keyed, want)
+	}
+	if keyed := Keyed(nil, "+QCFG"); len(keyed) != 0 {
+		t.Errorf("Keyed(nil) = %q", keyed)
+	}
+}
+
+func TestTrimEcho(t *testing.T) {
+	response := []string{"AT+CSQ", "", " +CSQ: 20,99 ", "at+csq"}
+	want := []string{"+CSQ: 20,99"}
+	for _, cmd := range []string{"+CSQ", "AT+CSQ", "at+csq"} {
+		if got := TrimEcho(response, cmd); !reflect.DeepEqual(got, want) {
+			t.Errorf("TrimEcho(%q) = %q, want %q", cmd, got, want)
+		}
+	}
+	if got := TrimEcho([]string{"AT+CSQ"}, "+CSQ"); len(got) != 0 {
+		t.Errorf("TrimEcho() of an echo alone = %q", got)
+	}
+}
+
+func TestFields(t *testing.T) {
+	fields := Split(`1,"x", 3 ,-4`)
+
+	if got := fields.String(1); got != "x" {
+		t.Errorf("String(1) = %q", got)
+	}
+	if got := fields.String(4); got != "" {
+		t.Errorf("String() past the end = %q", got)
+	}
+	if got := fields.String(-1); got != "" {
+		t.Errorf("String(-1) = %q", got)
+	}
+	if n, ok := fields.Int(2); !ok || n != 3 {
+		t.Errorf("Int(2) = %d, %v", n, ok)
+	}
+	if n, ok := fields.Int(3); !ok || n != -4 {
+		t.Errorf("Int(3) = %d, %v", n, ok)
+	}
+	if _, ok := fields.Int(1); ok {
+		t.Error("Int() of a string should fail")
+	}
+	if _, ok := fields.Int(9); ok {
+		t.Error("Int() past the end should fail")
+	}
+
+	if values, ok := Split("99,99,255,255,20,45").Ints(); !ok || !reflect.DeepEqual(values, []int{99, 99, 255, 255, 20, 45}) {
+		t.Errorf("Ints() = %v, %v", values, ok)
+	}
+	if _, ok := fields.Ints(); ok {
+		t.Error("Ints() with a string should fail")
+	}
+}
@@ -3,13 +3,13 @@ package call
 import (
 	"fmt"
 	"log"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 
+	"golte/atresp"
+
 	"github.com/warthog618/modem/at"
-	"github.com/warthog618/modem/info"
 )
 
 // IncomingCallHandler is a callback for incoming calls with phone number
@@ -80,6 +80,25 @@ func (c *Call) HangUpSpecific(callIndex int, options ...at.CommandOption) error
 	return err
 }
 
+// Transfer connects the active and the held call together and drops out of
+// both (explicit call transfer)
+// Uses AT+CHLD=4 command
+func (c *Call) Transfer(options ...at.CommandOption) error {
+	_, err := c.Command("+CHLD=4", options...)
+	return err
+}
+
+// SendDTMF plays DTMF tones to the other party of the active call, one
+// AT+VTS per digit as not every modem takes a string
+func (c *Call) SendDTMF(digits string, options ...at.CommandOption) error {
+	for _, digit := range digits {
+		if _, err := c.Command(fmt.Sprintf("+VTS=%c", digit), options...); err != nil {
+			return fmt.Errorf("failed to send DTMF %c: %w", digit, err)
+		}
+	}
+	return nil
+}
+
 // GetCallStatus retrieves the status of all current calls
 // Uses AT+CLCC command (List Current Calls)
 func (c *Call) GetCallStatus(options ...at.CommandOption) ([]CallStatus, error) {
@@ -89,25 +108,19 @@ func (c *Call) GetCallStatus(options ...at.CommandOption) ([]CallStatus, error)
 	}
 
 	var calls []CallStatus
-	for _, line := range response {
-		if info.HasPrefix(line, "+CLCC") {
-			call, parseErr := parseCallStatus(line)
-			if parseErr == nil {
-				calls = append(calls, call)
-			}
+	for _, fields := range atresp.FindAll(response, "+CLCC") {
+		call, parseErr := parseCallStatus(fields)
+		if parseErr == nil {
+			calls = append(calls, call)
 		}
 	}
 
 	return calls, nil
 }
 
-// parseCallStatus parses a +CLCC response line into a CallStatus struct
+// parseCallStatus parses the fields of a +CLCC response line into a CallStatus struct
 // Format: +CLCC: <id1>,<dir>,<stat>,<mode>,<mpty>[,<number>,<type>[,<alpha>[,<priority>]]]
-func parseCallStatus(line string) (CallStatus, error) {
-	// Remove the "+CLCC: " prefix
-	data := info.TrimPrefix(line, "+CLCC")
-	parts := strings.Split(data, ",")
-
+func parseCallStatus(parts atresp.Fields) (CallStatus, error) {
 	if len(parts) < 5 {
 		return CallStatus{}, fmt.Errorf("invalid CLCC response format")
 	}
@@ -116,13 +129,13 @@ func parseCallStatus(line string) (CallStatus, error) {
 	var err error
 
 	// Parse call index
-	call.Index, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	call.Index, err = strconv.Atoi(parts[0])
 	if err != nil {
 		return CallStatus{}, fmt.Errorf("invalid call index: %w", err)
 	}
 
 	// Parse direction (0=MO, 1=MT)
-	switch strings.TrimSpace(parts[1]) {
+	switch parts[1] {
 	case "0":
 		call.Direction = "MO"
 	case "1":
@@ -132,7 +145,7 @@ func parseCallStatus(line string) (CallStatus, error) {
 	}
 
 	// Parse status
-	switch strings.TrimSpace(parts[2]) {
+	switch parts[2] {
 	case "0":
 		call.Status = "ACTIVE"
 	case "1":
@@ -150,7 +163,7 @@ func parseCallStatus(line string) (CallStatus, error) {
 	}
 
 	// Parse mode
-	switch strings.TrimSpace(parts[3]) {
+	switch parts[3] {
 	case "0":
 		call.Mode = "VOICE"
 	case "1":
@@ -161,15 +174,9 @@ func parseCallStatus(line string) (CallStatus, error) {
 		call.Mode = "UNKNOWN"
 	}
 
-	// Parse number if available
-	if len(parts) > 5 {
-		call.Number = strings.Trim(strings.TrimSpace(parts[5]), "\"")
-	}
-
-	// Parse number type if available
-	if len(parts) > 6 {
-		call.Type = strings.TrimSpace(parts[6])
-	}
+	// Number and number type, empty if not available
+	call.Number = parts.String(5)
+	call.Type = parts.String(6)
 
 	return call, nil
 }
@@ -195,15 +202,11 @@ func (c *Call) GetMuteStatus(options ...at.CommandOption) (bool, error) {
 		return false, err
 	}
 
-	for _, line := range response {
-		if info.HasPrefix(line, "+CMUT") {
-			data := info.TrimPrefix(line, "+CMUT")
-			status := strings.TrimSpace(data)
-			return status == "1", nil
-		}
+	fields, ok := atresp.Find(response, "+CMUT")
+	if !ok {
+		return false, fmt.Errorf("no mute status found in response")
 	}
-
-	return false, fmt.Errorf("no mute status found in response")
+	return fields.String(0) == "1", nil
 }
 
 // VMute controls voice muting during calls
@@ -227,15 +230,11 @@ func (c *Call) GetVMuteStatus(options ...at.CommandOption) (bool, error) {
 		return false, err
 	}
 
-	for _, line := range response {
-		if info.HasPrefix(line, "+VMUTE") {
-			data := info.TrimPrefix(line, "+VMUTE")
-			status := strings.TrimSpace(data)
-			return status == "1", nil
-		}
+	fields, ok := atresp.Find(response, "+VMUTE")
+	if !ok {
+		return false, fmt.Errorf("no voice mute status found in response")
 	}
-
-	return false, fmt.Errorf("no voice mute status found in response")
+	return fields.String(0) == "1", nil
 }
 
 // StartListening begins listening for incoming calls using AT indications
@@ -314,25 +313,24 @@ func (c *Call) IsListening() bool {
 // extractPhoneNumber extracts the phone number from a CLIP indication
 // CLIP format: +CLIP: "number",type
 func (c *Call) extractPhoneNumber(clipData string) string {
-	// Use regex to extract phone number from CLIP indication
-	reg := regexp.MustCompile(`\+CLIP:\s*"([^"]+)"`)
-	matches := reg.FindStringSubmatch(clipData)
-	if len(matches) > 1 {
-		return matches[1]
+	fields, ok := atresp.Find([]string{clipData}, "+CLIP")
+	if !ok {
+		return ""
 	}
-	return ""
+	return fields.String(0)
 }
 
+// dtmfDigits are the digits +RXDTMF reports
+const dtmfDigits = "0123456789ABCD#*"
+
 // extractDTMFDigit extracts the DTMF digit from a +RXDTMF indication
 // RXDTMF format: +RXDTMF: digit
 func (c *Call) extractDTMFDigit(dtmfData string) string {
-	// Use regex to extract DTMF digit from RXDTMF indication
-	reg := regexp.MustCompile(`\+RXDTMF:\s*([0-9A-D#*])`)
-	matches := reg.FindStringSubmatch(dtmfData)
-	if len(matches) > 1 {
-		return matches[1]
+	payload, ok := atresp.Payload(dtmfData, "+RXDTMF")
+	if !ok || payload == "" || !strings.ContainsRune(dtmfDigits, rune(payload[0])) {
+		return ""
 	}
-	return ""
+	return payload[:1]
 }
 
 // SetDTMFHandler sets the DTMF detection handler
@@ -385,3 +383,34 @@ func (c *Call) DisableDTMFDetection(options ...at.CommandOption) error {
 	c.dtmfHandler = nil
 	return nil
 }
+
+// Result codes sent by the modem when a voice call ends
+const (
+	EndNoCarrier  = "NO CARRIER"
+	EndBusy       = "BUSY"
+	EndNoAnswer   = "NO ANSWER"
+	EndNoDialtone = "NO DIALTONE"
+)
+
+var endCodes = []string{EndNoCarrier, EndBusy, EndNoAnswer, EndNoDialtone}
+
+// WatchEnd calls handler with the result code the modem sends when a call
+// ends, until StopWatchingEnd
+func (c *Call) WatchEnd(handler func(code string)) error {
+	for i, code := range endCodes {
+		if err := c.AddIndication(code, func([]string) { handler(code) }); err != nil {
+			for _, registered := range endCodes[:i] {
+				c.CancelIndication(registered)
+			}
+			return fmt.Errorf("failed to watch for %s: %w", code, err)
+		}
+	}
+	return nil
+}
+
+// StopWatchingEnd stops reporting the end of calls to the WatchEnd handler
+func (c *Call) StopWatchingEnd() {
+	for _, code := range endCodes {
+		c.CancelIndication(code)
+	}
+}
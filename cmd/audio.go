@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golte/config"
+	"golte/ffmpeg"
+	"golte/logger"
+	"golte/machine"
+
+	"github.com/spf13/cobra"
+)
+
+// audioCmd represents the audio command
+var audioCmd = &cobra.Command{
+	Use:   "audio",
+	Short: "Audio commands",
+	Long:  "Commands using the modem's sound card directly, without Discord or a call.",
+}
+
+// audioTestCmd exercises the playback and capture paths of calls
+var audioTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Play a tone, record the capture or measure a loopback",
+	Long: `Exercise the call audio with the same ffmpeg capture, playback and devices as
+during calls, without Discord or a phone call. Pick one of:
+
+  --tone 3s       play the 1kHz beep for 3 seconds
+  --record 5s     capture 5 seconds to --output as a WAV file
+  --loopback 2s   play the beep for 2 seconds while capturing, and report how
+                  long it took to be heard and how loud (the modem's output
+                  must be looped back to its input)
+
+The loopback exits with 1 if the beep wasn't heard above --threshold.`,
+	Example: `  golte audio test --tone 3s
+  golte audio test --record 10s --output capture.wav`,
+	RunE: runAudioTest,
+}
+
+func init() {
+	rootCmd.AddCommand(audioCmd)
+	audioCmd.AddCommand(audioTestCmd)
+	audioTestCmd.Flags().Duration("tone", 0, "play the 1kHz beep for this long")
+	audioTestCmd.Flags().Duration("record", 0, "capture this long to --output")
+	audioTestCmd.Flags().String("output", "golte-capture.wav", "WAV file written by --record")
+	audioTestCmd.Flags().Duration("loopback", 0, "measure the latency and level of a beep this long")
+	audioTestCmd.Flags().Float64("threshold", -40, "level in dBFS counted as hearing the beep with --loopback")
+	audioTestCmd.MarkFlagsMutuallyExclusive("tone", "record", "loopback")
+	audioTestCmd.MarkFlagsOneRequired("tone", "record", "loopback")
+}
+
+func runAudioTest(cmd *cobra.Command, args []string) error {
+	tone, _ := cmd.Flags().GetDuration("tone")
+	record, _ := cmd.Flags().GetDuration("record")
+	output, _ := cmd.Flags().GetString("output")
+	loopback, _ := cmd.Flags().GetDuration("loopback")
+	threshold, _ := cmd.Flags().GetFloat64("threshold")
+	cmd.SilenceUsage = true
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := logger.Setup("warn", cfg.Logging.Format); err != nil {
+		return fmt.Errorf("failed to setup logging: %w", err)
+	}
+	if _, err := ffmpeg.Probe(cmd.Context(), cfg.Audio.FFmpegPath); err != nil {
+		return fmt.Errorf("ffmpeg check failed: %w", err)
+	}
+
+	switch {
+	case tone > 0:
+		pb, err := machine.NewPlayback(cfg)
+		if err != nil {
+			return err
+		}
+		defer pb.Close()
+
+		fmt.Printf("Playing a 1kHz beep for %s\n", tone)
+		return machine.PlayTestTone(cmd.Context(), pb, tone)
+
+	case record > 0:
+		fmt.Printf("Recording %s from %s\n", record, cfg.Audio.Device)
+		samples, err := machine.RecordCapture(cmd.Context(), cfg, record)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		// The capture is mono at 48kHz, as sent to Discord
+		if err := ffmpeg.WriteWAV(f, samples, ffmpeg.SampleRate, 1); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", output)
+		return nil
+
+	case loopback > 0:
+		pb, err := machine.NewPlayback(cfg)
+		if err != nil {
+			return err
+		}
+		defer pb.Close()
+
+		result, err := machine.MeasureLoopback(cmd.Context(), cfg, pb, loopback, threshold)
+		if err != nil {
+			return err
+		}
+		if !result.Detected {
+			return fmt.Errorf("the beep was not heard above %.0f dBFS (loudest %.1f dBFS)", threshold, result.LevelDB)
+		}
+		fmt.Printf("Heard the beep after %s at %.1f dBFS\n", result.Latency.Round(time.Millisecond), result.LevelDB)
+		return nil
+	}
+	return errors.New("pick one of --tone, --record or --loopback")
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golte/config"
+	"golte/logger"
+	"golte/machine"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of call, besides 1 for errors
+const (
+	exitCallNoAnswer = 2
+	exitCallBusy     = 3
+	exitCallFailed   = 4 // ended without being answered for another reason
+)
+
+// callCmd places a test call
+var callCmd = &cobra.Command{
+	Use:   "call",
+	Short: "Place a test call",
+	Long: `Place a call through the modem without starting the bridge, to check the audio
+wiring. The call status is printed as it changes. --play plays a prompt once
+the call is answered, and the call is hung up after --hangup-after or on
+Ctrl-C.
+
+Exits with 2 if the call wasn't answered, 3 if the line was busy and 4 if it
+failed otherwise.`,
+	RunE: runCall,
+}
+
+func init() {
+	rootCmd.AddCommand(callCmd)
+	callCmd.Flags().String("to", "", "phone number to call")
+	callCmd.Flags().String("play", "", "prompt to play once answered, e.g. audio/mot_de_passe_correct.mp3")
+	callCmd.Flags().Duration("hangup-after", 30*time.Second, "hang up this long after the call is answered, 0 waits for the other side")
+	callCmd.MarkFlagRequired("to")
+}
+
+func runCall(cmd *cobra.Command, args []string) error {
+	to, _ := cmd.Flags().GetString("to")
+	play, _ := cmd.Flags().GetString("play")
+	hangupAfter, _ := cmd.Flags().GetDuration("hangup-after")
+	cmd.SilenceUsage = true
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.ValidateModem(); err != nil {
+		return fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if cfg.Modem.Type == config.ModemTypeSMPP {
+		return fmt.Errorf("calls need a GSM modem, modem.type is %s", cfg.Modem.Type)
+	}
+	if err := logger.Setup(cfg.Logging.Level, cfg.Logging.Format); err != nil {
+		return fmt.Errorf("failed to setup logging: %w", err)
+	}
+	logger.SetRedaction(cfg.Logging.Redaction)
+
+	pb, err := machine.NewPlayback(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to start playback: %w", err)
+	}
+	defer pb.Close()
+
+	modem := machine.NewModemManager(cfg, pb, nil, nil, nil)
+	if err := modem.InitializeDialer(); err != nil {
+		return err
+	}
+	defer modem.Close()
+
+	// The hang up timer replaces call.max_duration
+	if err := modem.StartCall(to, -1); err != nil {
+		return fmt.Errorf("call has not been placed: %w", err)
+	}
+	fmt.Printf("Calling %s\n", to)
+
+	// Ctrl-C hangs up, the call is tracked until the modem reports its end
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	hangup := make(chan struct{}, 1)
+	go func() {
+		<-signals
+		fmt.Println("Hanging up")
+		hangup <- struct{}{}
+	}()
+	go func() {
+		<-hangup
+		if err := modem.HangUpCall(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to hang up: %v\n", err)
+		}
+	}()
+
+	start := time.Now()
+	outcome, err := modem.TrackCall(context.Background(), func(status string) {
+		fmt.Printf("%6.1fs %s\n", time.Since(start).Seconds(), status)
+		if status != "ACTIVE" {
+			return
+		}
+		if play != "" {
+			if err := pb.AddPredecoded(play); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to play %s: %v\n", play, err)
+			}
+		}
+		if hangupAfter > 0 {
+			time.AfterFunc(hangupAfter, func() {
+				select {
+				case hangup <- struct{}{}:
+				default:
+				}
+			})
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Call ended: %s\n", outcome)
+	switch outcome {
+	case machine.CallNoAnswer:
+		return &exitError{code: exitCallNoAnswer, err: fmt.Errorf("%s didn't answer", to)}
+	case machine.CallBusy:
+		return &exitError{code: exitCallBusy, err: fmt.Errorf("%s is busy", to)}
+	case machine.CallFailed:
+		return &exitError{code: exitCallFailed, err: fmt.Errorf("the call to %s failed", to)}
+	}
+	return nil
+}
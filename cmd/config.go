@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 
 	"golte/config"
 	"golte/logger"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // configCmd represents the config command
@@ -34,10 +38,20 @@ var configValidateCmd = &cobra.Command{
 			return fmt.Errorf("failed to load configuration: %w", err)
 		}
 
-		// Validate configuration
+		// Validate configuration, listing every problem with its key
 		if err := cfg.Validate(); err != nil {
-			slog.Error("Configuration validation failed", slog.Any("error", err))
-			return err
+			var errs config.ValidationErrors
+			if !errors.As(err, &errs) {
+				slog.Error("Configuration validation failed", slog.Any("error", err))
+				return err
+			}
+
+			fmt.Printf("❌ Configuration has %d problem(s):\n", len(errs))
+			for _, e := range errs {
+				fmt.Printf("  %s: %s\n", e.Field, e.Message)
+			}
+			cmd.SilenceUsage = true
+			return fmt.Errorf("configuration is invalid")
 		}
 
 		slog.Info("Configuration is valid")
@@ -46,14 +60,20 @@ var configValidateCmd = &cobra.Command{
 	},
 }
 
-// configShowCmd shows the current configuration
+// configShowCmd shows the effective configuration
 var configShowCmd = &cobra.Command{
 	Use:   "show",
-	Short: "Show current configuration",
-	Long:  "Display the current configuration values from file and environment variables.",
+	Short: "Show the effective configuration",
+	Long: `Print every setting as the bridge would use it, merged from the defaults, the
+config file, environment variables and flags, with where each value comes from.
+Secrets (tokens, passwords, PINs, webhooks) are redacted unless --show-secrets
+is given.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Setup basic logging
-		if err := logger.Setup("info", "text"); err != nil {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		showSecrets, _ := cmd.Flags().GetBool("show-secrets")
+
+		// Logs would get in the way of the dump
+		if err := logger.Setup("warn", "text"); err != nil {
 			return fmt.Errorf("failed to setup logging: %w", err)
 		}
 
@@ -63,19 +83,23 @@ var configShowCmd = &cobra.Command{
 			return fmt.Errorf("failed to load configuration: %w", err)
 		}
 
-		fmt.Println("Current Configuration:")
-		fmt.Printf("  Modem:\n")
-		fmt.Printf("    Device: %s\n", cfg.Modem.Device)
-		fmt.Printf("    Baud: %d\n", cfg.Modem.Baud)
-		fmt.Printf("    Timeout: %s\n", cfg.Modem.Timeout)
-		fmt.Printf("  Discord:\n")
-		fmt.Printf("    Token: %s\n", maskToken(cfg.Discord.Token))
-		fmt.Printf("    Channel ID: %s\n", cfg.Discord.ChannelID)
-		fmt.Printf("  Logging:\n")
-		fmt.Printf("    Level: %s\n", cfg.Logging.Level)
-		fmt.Printf("    Format: %s\n", cfg.Logging.Format)
+		if asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(config.Settings(cfg, showSecrets))
+		}
 
-		return nil
+		out, err := config.DumpYAML(cfg, showSecrets)
+		if err != nil {
+			return err
+		}
+		if file := viper.ConfigFileUsed(); file != "" {
+			fmt.Printf("# Effective configuration, file: %s\n", file)
+		} else {
+			fmt.Println("# Effective configuration, no config file")
+		}
+		_, err = os.Stdout.Write(out)
+		return err
 	},
 }
 
@@ -83,12 +107,6 @@ func init() {
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(configValidateCmd)
 	configCmd.AddCommand(configShowCmd)
-}
-
-// maskToken masks a Discord token for display
-func maskToken(token string) string {
-	if len(token) <= 8 {
-		return "***"
-	}
-	return token[:8] + "***"
+	configShowCmd.Flags().Bool("json", false, "print JSON, one entry per setting")
+	configShowCmd.Flags().Bool("show-secrets", false, "print secrets instead of redacting them")
 }
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"golte/config"
+
+	"github.com/spf13/cobra"
+)
+
+// SampleConfig is the annotated config.yaml.example, set by main
+var SampleConfig []byte
+
+// initPrompts are the values asked for by config init --interactive
+var initPrompts = []struct {
+	key    string
+	prompt string
+}{
+	{"discord.token", "Discord bot token"},
+	{"discord.channel_id", "Discord channel ID for SMS"},
+	{"discord.guild_id", "Discord guild (server) ID"},
+	{"discord.voice_channel_id", "Discord voice channel ID for calls"},
+	{"modem.device", "Modem device"},
+}
+
+// configInitCmd writes an annotated sample configuration
+var configInitCmd = &cobra.Command{
+	Use:   "init [path]",
+	Short: "Write a sample configuration file",
+	Long: `Write a fully commented configuration file to path (default ./config.yaml).
+With --interactive, the Discord IDs, token and modem device are asked for.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "config.yaml"
+		if len(args) > 0 {
+			path = args[0]
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		interactive, _ := cmd.Flags().GetBool("interactive")
+
+		if _, err := os.Stat(path); err == nil && !force {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+		} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		data := SampleConfig
+		if interactive {
+			values, err := promptValues(cmd.InOrStdin(), cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			if data, err = config.FillSample(data, values); err != nil {
+				return err
+			}
+		}
+
+		// The file ends up holding the bot token
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "✅ Wrote %s\n", path)
+		if !interactive {
+			fmt.Fprintln(cmd.OutOrStdout(), "Fill in the Discord token and IDs, then run golte config validate")
+		}
+		return nil
+	},
+}
+
+// promptValues asks for each of initPrompts, empty answers are left out
+func promptValues(in io.Reader, out io.Writer) (map[string]string, error) {
+	values := make(map[string]string)
+	reader := bufio.NewReader(in)
+	for _, p := range initPrompts {
+		fmt.Fprintf(out, "%s: ", p.prompt)
+		answer, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if answer = strings.TrimSpace(answer); answer != "" {
+			values[p.key] = answer
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	return values, nil
+}
+
+func init() {
+	configCmd.AddCommand(configInitCmd)
+	configInitCmd.Flags().Bool("force", false, "overwrite an existing file")
+	configInitCmd.Flags().BoolP("interactive", "i", false, "ask for the required values")
+}
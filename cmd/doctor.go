@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"golte/config"
+	"golte/doctor"
+	"golte/ffmpeg"
+	"golte/machine"
+
+	"github.com/disgoorg/disgo/rest"
+	"github.com/spf13/cobra"
+	"github.com/warthog618/modem/at"
+	"github.com/warthog618/modem/serial"
+)
+
+// doctorCmd checks the environment of the bridge
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the setup: config, modem, SIM, audio and Discord",
+	Long: `Run every check of the bridge's environment and print pass, warn or fail
+for each: the configuration, the modem's serial device and its answers, the SIM
+and network registration, ffmpeg and the ALSA device, the RNNoise model, the
+Discord token and access to the configured guild and channels.
+
+Checks depending on a failed one are skipped. The bridge must not be running,
+it holds the serial port. Exits with 1 if any check failed.`,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().Bool("json", false, "print JSON instead of a table")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	cmd.SilenceUsage = true
+
+	results := checkEnvironment(cmd.Context())
+
+	failed := 0
+	for _, r := range results {
+		if r.Status == doctor.Fail {
+			failed++
+		}
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", strings.ToUpper(string(r.Status)), r.Check, r.Detail)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// checkEnvironment runs the doctor checks in order, skipping those that
+// depend on a failed one
+func checkEnvironment(ctx context.Context) []doctor.Result {
+	cfg, result := doctor.Config(config.LoadConfig)
+	results := []doctor.Result{result}
+	if cfg == nil {
+		return results
+	}
+
+	switch cfg.Modem.Type {
+	case config.ModemTypeSMPP:
+		results = append(results, doctor.Result{Check: "modem", Status: doctor.Skip, Detail: "SMS go through SMPP"})
+	case config.ModemTypeMock:
+		results = append(results, doctor.Result{Check: "modem", Status: doctor.Skip, Detail: "the modem is simulated"})
+	default:
+		results = append(results, checkModem(cfg)...)
+	}
+
+	if cfg.Voice.Enabled {
+		results = append(results,
+			doctor.FFmpeg(ctx, cfg.Audio.FFmpegPath, probeFFmpeg),
+			doctor.ALSADevice(cfg.Audio.Device, "/dev/snd"),
+			doctor.RNNoiseModel(ffmpeg.RNNoiseModel),
+		)
+	} else {
+		results = append(results, doctor.Result{Check: "audio", Status: doctor.Skip, Detail: "voice is disabled"})
+	}
+
+	if !cfg.DiscordEnabled() {
+		return append(results, doctor.Result{Check: "discord", Status: doctor.Skip, Detail: "Telegram replaces Discord"})
+	}
+	api := rest.New(rest.NewClient(cfg.Discord.Token))
+	result = doctor.DiscordToken(api)
+	results = append(results, result)
+	if result.Status != doctor.Fail {
+		results = append(results, doctor.DiscordGuild(api, cfg.Discord.GuildID))
+		for i, id := range cfg.Discord.ChannelIDs {
+			// Mirrors may be in other guilds
+			guildID := cfg.Discord.GuildID
+			if i > 0 {
+				guildID = ""
+			}
+			results = append(results, doctor.DiscordChannel(api, "channel", id, guildID))
+		}
+		if cfg.Voice.Enabled {
+			results = append(results, doctor.DiscordChannel(api, "voice channel", cfg.Discord.VoiceChannelID, cfg.Discord.GuildID))
+		}
+	}
+	return results
+}
+
+// checkModem runs the serial device, modem, SIM and registration checks
+func checkModem(cfg *config.Config) []doctor.Result {
+	result := doctor.SerialDevice(cfg.Modem.Device)
+	results := []doctor.Result{result}
+	if result.Status == doctor.Fail {
+		return results
+	}
+
+	baud := cfg.Modem.Baud
+	if baud == config.BaudAuto {
+		detected, err := machine.DetectBaud(cfg.Modem.Device, config.AutoBauds)
+		if err != nil {
+			return append(results, doctor.Result{Check: "baud", Status: doctor.Fail, Detail: err.Error()})
+		}
+		baud = detected
+		results = append(results, doctor.Result{Check: "baud", Status: doctor.Pass, Detail: fmt.Sprintf("detected %d, set modem.baud to it to skip detection", baud)})
+	}
+
+	port, err := serial.New(serial.WithPort(cfg.Modem.Device), serial.WithBaud(baud))
+	if err != nil {
+		return append(results, doctor.Result{Check: "modem", Status: doctor.Fail, Detail: err.Error()})
+	}
+	defer port.Close()
+
+	// Only turn echo off, a reset would drop settings the bridge relies on
+	modem := at.New(port, at.WithTimeout(cfg.Modem.Timeout), at.WithCmds("E0"))
+	result = doctor.ModemAnswers(modem)
+	results = append(results, result)
+	if result.Status == doctor.Fail {
+		return results
+	}
+
+	result = doctor.SIM(modem)
+	results = append(results, result)
+	if result.Status == doctor.Fail {
+		return results
+	}
+	return append(results, doctor.Registration(modem))
+}
+
+// probeFFmpeg adapts ffmpeg.Probe to the doctor check
+func probeFFmpeg(ctx context.Context, path string) (string, error) {
+	info, err := ffmpeg.Probe(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s at %s", info.Version, info.Path), nil
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golte/config"
+	"golte/health"
+
+	"github.com/spf13/cobra"
+)
+
+// staleIntervals is how many health.interval the state file may be old
+// before the bridge is considered stuck
+const staleIntervals = 3
+
+// healthcheckCmd tells whether a running bridge is okay
+var healthcheckCmd = &cobra.Command{
+	Use:   "healthcheck",
+	Short: "Check that the running bridge is healthy",
+	Long: `Check the health of the bridge running with the same configuration, for a
+Docker HEALTHCHECK or a monitoring script, and print a one line summary such as
+
+  OK: modem ok, discord ok, last SMS rx 2m ago
+
+The running bridge records whether the modem answers and Discord is connected
+in health.state_file every health.interval. The state is unhealthy if either
+check failed or if the file is older than 3 intervals, the bridge being stuck
+or stopped.
+
+Exit codes:
+  0  healthy
+  1  unhealthy, or the state couldn't be read within --timeout`,
+	Args: cobra.NoArgs,
+	RunE: runHealthcheck,
+}
+
+func init() {
+	rootCmd.AddCommand(healthcheckCmd)
+	healthcheckCmd.Flags().Duration("timeout", 3*time.Second, "give up and report unhealthy after this long")
+}
+
+func runHealthcheck(cmd *cobra.Command, args []string) error {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	type outcome struct {
+		summary string
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		summary, err := checkHealth(time.Now())
+		done <- outcome{summary, err}
+	}()
+
+	var o outcome
+	select {
+	case o = <-done:
+	case <-time.After(timeout):
+		o.err = fmt.Errorf("no answer within %s", timeout)
+	}
+
+	if o.err != nil {
+		if o.summary == "" {
+			o.summary = o.err.Error()
+		}
+		fmt.Printf("UNHEALTHY: %s\n", o.summary)
+		return &exitError{code: 1, err: o.err}
+	}
+	fmt.Printf("OK: %s\n", o.summary)
+	return nil
+}
+
+// checkHealth reads the state recorded by the running bridge and returns its
+// summary, with an error if it's unhealthy or stale
+func checkHealth(now time.Time) (string, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.Health.StateFile == "" {
+		return "", errors.New("health.state_file is not set, the bridge doesn't record its health")
+	}
+
+	state, err := health.Read(cfg.Health.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%s doesn't exist, the bridge isn't running", cfg.Health.StateFile)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	summary := state.Summary(now)
+	if age := now.Sub(state.Checked); age > staleIntervals*cfg.Health.Interval {
+		return fmt.Sprintf("last checked %s, the bridge is stuck or stopped (%s)", health.Ago(age), summary),
+			errors.New("stale health state")
+	}
+	return summary, state.Err()
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golte/config"
+
+	"github.com/spf13/cobra"
+)
+
+// mockCmd represents the mock command
+var mockCmd = &cobra.Command{
+	Use:   "mock",
+	Short: "Inject SMS and calls into a bridge running with --dry-run",
+	Long: `Drive the simulated modem of a bridge started with --dry-run or modem.type
+mock, through its endpoint at modem.mock.listen. Injected SMS and calls reach
+Discord like real ones.`,
+}
+
+var mockSMSCmd = &cobra.Command{
+	Use:     "sms <message>",
+	Short:   "Deliver an SMS as if the modem received it",
+	Example: `  golte mock sms --from +33612345678 "Hello from the simulator"`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		return postMock(cmd, "/sms", map[string]string{"from": from, "message": args[0]})
+	},
+}
+
+var mockCallCmd = &cobra.Command{
+	Use:     "call",
+	Short:   "Ring the bridge as if a call came in",
+	Example: `  golte mock call --from +33612345678`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		return postMock(cmd, "/call", map[string]string{"from": from})
+	},
+}
+
+var mockHangupCmd = &cobra.Command{
+	Use:   "hangup",
+	Short: "End the simulated call",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return postMock(cmd, "/hangup", map[string]string{})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mockCmd)
+	mockCmd.AddCommand(mockSMSCmd, mockCallCmd, mockHangupCmd)
+	mockSMSCmd.Flags().String("from", "+33612345678", "sender number")
+	mockCallCmd.Flags().String("from", "+33612345678", "caller number")
+}
+
+// postMock sends body to the simulated modem's endpoint at path
+func postMock(cmd *cobra.Command, path string, body map[string]string) error {
+	cmd.SilenceUsage = true
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.Modem.Mock.Listen == "" {
+		return errors.New("modem.mock.listen is empty, the simulated modem has no endpoint")
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post("http://"+cfg.Modem.Mock.Listen+path, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("is golte running with --dry-run? %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("simulated modem refused: %s", strings.TrimSpace(string(message)))
+	}
+	fmt.Println("OK")
+	return nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"golte/config"
+	"golte/logger"
+	"golte/machine"
+
+	"github.com/spf13/cobra"
+)
+
+// modemCmd represents the modem command
+var modemCmd = &cobra.Command{
+	Use:   "modem",
+	Short: "Modem diagnostic commands",
+	Long:  "Commands querying the modem directly, without Discord.",
+}
+
+// modemInfoCmd prints the modem identity and network state
+var modemInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show modem, SIM and network information",
+	Long: `Open the modem and print its manufacturer, model, firmware, IMEI, the SIM's
+ICCID, IMSI and SMSC, the registration, operator and signal, and whether it
+supports voice calls, DTMF detection, USSD and PDU mode. Only the modem section
+of the configuration is needed.`,
+	RunE: runModemInfo,
+}
+
+func init() {
+	rootCmd.AddCommand(modemCmd)
+	modemCmd.AddCommand(modemInfoCmd)
+	modemInfoCmd.Flags().Bool("json", false, "print JSON instead of a table")
+	modemInfoCmd.Flags().Duration("query-timeout", 3*time.Second, "how long each query may take")
+}
+
+func runModemInfo(cmd *cobra.Command, args []string) error {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	timeout, _ := cmd.Flags().GetDuration("query-timeout")
+	cmd.SilenceUsage = true
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.ValidateModem(); err != nil {
+		return fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if cfg.Modem.Type == config.ModemTypeSMPP {
+		return fmt.Errorf("modem info needs a GSM modem, modem.type is %s", cfg.Modem.Type)
+	}
+	// Logs would get in the way of the table
+	if err := logger.Setup("warn", cfg.Logging.Format); err != nil {
+		return fmt.Errorf("failed to setup logging: %w", err)
+	}
+
+	// A half-dead modem shouldn't hang the initialization either
+	cfg.Modem.Timeout = timeout
+	modem := machine.NewModemManager(cfg, nil, nil, nil, nil)
+	if err := modem.InitializeSender(false); err != nil {
+		return err
+	}
+	defer modem.Close()
+
+	items, err := modem.Info(timeout)
+	if err != nil {
+		return err
+	}
+	caps, err := modem.Capabilities()
+	if err != nil {
+		return err
+	}
+	items = append(items, caps.Items()...)
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(items)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Device\t%s\n", cfg.Modem.Device)
+	for _, item := range items {
+		value := item.Value
+		if item.Error != "" {
+			value = "error: " + item.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\n", item.Name, value)
+	}
+	return w.Flush()
+}
@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
@@ -9,16 +12,20 @@ import (
 
 	"golte/assets"
 	"golte/config"
+	"golte/ffmpeg"
 	"golte/logger"
 	"golte/machine"
+	"golte/sdnotify"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile string
-	verbose bool
+	cfgFile            string
+	verbose            bool
+	dryRun             bool
+	allowUnknownConfig bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -36,17 +43,37 @@ while also providing Discord slash commands to send SMS messages through the mod
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	err := rootCmd.Execute()
+	var exit *exitError
+	if errors.As(err, &exit) {
+		os.Exit(exit.code)
+	}
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// exitError makes the process exit with code rather than 1
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./config.yaml)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().String("profile", "", "config profile applied over the config file")
+	rootCmd.PersistentFlags().BoolVar(&allowUnknownConfig, "allow-unknown-config", false, "warn about unknown settings in the config file instead of failing")
 
 	// Local flags for the server command
 	rootCmd.Flags().StringP("device", "d", "/dev/serial0", "path to modem device")
@@ -56,19 +83,22 @@ func init() {
 	rootCmd.Flags().String("discord-channel", "", "Discord channel ID")
 	rootCmd.Flags().String("log-level", "info", "log level (debug, info, warn, error)")
 	rootCmd.Flags().String("log-format", "text", "log format (text, json)")
-
-	// Bind flags to viper
-	viper.BindPFlag("modem.device", rootCmd.Flags().Lookup("device"))
-	viper.BindPFlag("modem.baud", rootCmd.Flags().Lookup("baud"))
-	viper.BindPFlag("modem.timeout", rootCmd.Flags().Lookup("timeout"))
-	viper.BindPFlag("discord.token", rootCmd.Flags().Lookup("discord-token"))
-	viper.BindPFlag("discord.channel_id", rootCmd.Flags().Lookup("discord-channel"))
-	viper.BindPFlag("logging.level", rootCmd.Flags().Lookup("log-level"))
-	viper.BindPFlag("logging.format", rootCmd.Flags().Lookup("log-format"))
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "use a simulated modem, see golte mock")
+
+	// Bind flags to viper, remembering them for config show
+	config.BindFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+	config.BindFlag("modem.device", rootCmd.Flags().Lookup("device"))
+	config.BindFlag("modem.baud", rootCmd.Flags().Lookup("baud"))
+	config.BindFlag("modem.timeout", rootCmd.Flags().Lookup("timeout"))
+	config.BindFlag("discord.token", rootCmd.Flags().Lookup("discord-token"))
+	config.BindFlag("discord.channel_id", rootCmd.Flags().Lookup("discord-channel"))
+	config.BindFlag("logging.level", rootCmd.Flags().Lookup("log-level"))
+	config.BindFlag("logging.format", rootCmd.Flags().Lookup("log-format"))
 }
 
 // initConfig reads in config file and ENV variables
 func initConfig() {
+	config.AllowUnknownKeys(allowUnknownConfig)
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
 	}
@@ -76,6 +106,9 @@ func initConfig() {
 	if verbose {
 		viper.Set("logging.level", "debug")
 	}
+	if dryRun {
+		viper.Set("modem.type", config.ModemTypeMock)
+	}
 }
 
 // runServer starts the main application
@@ -92,15 +125,25 @@ func runServer(cmd *cobra.Command, args []string) error {
 	}
 
 	// Setup logging
-	if err := logger.Setup(cfg.Logging.Level, cfg.Logging.Format); err != nil {
+	if err := logger.SetupOutput(cfg.Logging.Level, cfg.Logging.Format, logOutput(cfg.Logging)); err != nil {
 		return fmt.Errorf("failed to setup logging: %w", err)
 	}
-
-	// Initialize predecoded audio cache
-	_ = assets.GetPredecodedCache()
+	logger.SetRecentSize(cfg.Logging.BufferSize)
+	logger.SetRedaction(cfg.Logging.Redaction)
+
+	if cfg.Voice.Enabled {
+		stop, err := setupAudio(cmd.Context(), cfg)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	} else {
+		slog.Info("Voice is disabled, calls carry no audio and ffmpeg isn't used")
+	}
 
 	// Create and initialize the machine
-	m := machine.New(cfg)
+	m := machine.New(cfg, machine.WithStatusFunc(notifyStatus), machine.WithVersion(Version))
+	notifyStatus("Connecting to the modem and Discord")
 	if err := m.Initialize(); err != nil {
 		return fmt.Errorf("failed to initialize machine: %w", err)
 	}
@@ -110,22 +153,102 @@ func runServer(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to start machine: %w", err)
 	}
 
-	// Setup graceful shutdown
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	// Only now is the bridge up for systemd, with Type=notify
+	notify(sdnotify.Ready)
+	notifyStatus(machine.StatusRunning)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go superviseSystemd(ctx, m)
 
-	// Wait for shutdown signal or error
-	select {
-	case sig := <-signalChan:
-		fmt.Printf("\nReceived %s, shutting down gracefully...\n", sig)
-	case err := <-m.Error():
-		fmt.Printf("Error occurred: %v\n", err)
+	// Setup graceful shutdown, SIGHUP reloads the configuration and reopens
+	// the log file
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	// Wait for shutdown signal or a fatal error, transient errors are
+	// handled by the machine itself
+wait:
+	for {
+		select {
+		case sig := <-signalChan:
+			if sig == syscall.SIGHUP {
+				notify(sdnotify.Reload)
+				if err := logger.Reopen(); err != nil {
+					slog.Error("Failed to reopen the log file", slog.Any("error", err))
+				}
+				if _, err := m.Reload(); err != nil {
+					slog.Error("Failed to reload configuration", slog.Any("error", err))
+				}
+				notify(sdnotify.Ready)
+				continue
+			}
+			fmt.Printf("\nReceived %s, shutting down gracefully...\n", sig)
+			break wait
+		case err := <-m.Error():
+			fmt.Printf("Fatal error occurred: %v\n", err)
+			break wait
+		}
 	}
 
 	// Graceful shutdown
+	notify(sdnotify.Stopping)
+	cancel()
 	if err := m.Stop(); err != nil {
 		return fmt.Errorf("failed to stop machine gracefully: %w", err)
 	}
 
 	return nil
 }
+
+// logOutput returns where the logs of the bridge go
+func logOutput(cfg config.LoggingConfig) logger.Output {
+	stdout, file := cfg.Targets()
+	return logger.Output{
+		Stdout:     stdout,
+		File:       file,
+		MaxSize:    int64(cfg.Rotation.MaxSize) << 20,
+		MaxBackups: cfg.Rotation.MaxBackups,
+		MaxAge:     cfg.Rotation.MaxAge,
+	}
+}
+
+// setupAudio checks ffmpeg and prepares the prompts of calls. stop ends
+// watching the assets directory.
+func setupAudio(ctx context.Context, cfg *config.Config) (stop func(), err error) {
+	// Calls can't work without a usable ffmpeg, better to refuse to start
+	// than to fail with a broken pipe mid-call. Only a GSM modem has call
+	// audio to capture.
+	if cfg.Modem.CarriesAudio() {
+		ffmpegInfo, err := ffmpeg.Probe(ctx, cfg.Audio.FFmpegPath)
+		if err != nil {
+			return nil, fmt.Errorf("ffmpeg check failed: %w", err)
+		}
+		slog.Info("Using ffmpeg",
+			slog.String("path", ffmpegInfo.Path),
+			slog.String("version", ffmpegInfo.Version))
+	} else {
+		slog.Info("ffmpeg isn't checked, the modem carries no call audio", slog.String("type", cfg.Modem.Type))
+	}
+
+	// Initialize predecoded audio cache, prompts that fail to decode are
+	// only unavailable rather than fatal
+	stop = func() {}
+	cache := assets.GetPredecodedCache()
+	cache.SetBudget(int64(cfg.Audio.CacheBudgetMB) << 20)
+	if cfg.Audio.AssetsDir != "" {
+		if err := cache.SetDir(cfg.Audio.AssetsDir); err != nil {
+			return nil, err
+		}
+		if watchStop, err := cache.Watch(); err != nil {
+			slog.Warn("Changes to the audio assets directory need a restart", slog.Any("error", err))
+		} else {
+			stop = watchStop
+		}
+	}
+	if cfg.Audio.Preload {
+		if err := cache.Preload(); err != nil {
+			slog.Warn("Some audio prompts failed to preload", slog.Any("error", err))
+		}
+	}
+	return stop, nil
+}
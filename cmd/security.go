@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golte/config"
+
+	"github.com/spf13/cobra"
+)
+
+// securityCmd groups the helpers of the security section
+var securityCmd = &cobra.Command{
+	Use:   "security",
+	Short: "Helpers for the security settings",
+}
+
+// hashPINCmd hashes a DTMF PIN for security.dtmf_pin_hash
+var hashPINCmd = &cobra.Command{
+	Use:   "hash-pin",
+	Short: "Hash a DTMF PIN for security.dtmf_pin_hash",
+	Long: `Read a DTMF PIN from standard input and print its salted hash, to put in
+security.dtmf_pin_hash so the PIN itself is never written to the configuration.
+The PIN is 4 to 16 keys among 0-9, * and A-D, callers end it with #. It's asked for twice when
+typed, and read from the first line when piped:
+
+  printf '1234\n' | golte security hash-pin --algorithm argon2id
+
+The algorithm must match security.pin_hash_algorithm.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		algorithm, _ := cmd.Flags().GetString("algorithm")
+		cmd.SilenceUsage = true
+
+		pin, err := readPIN(cmd.InOrStdin(), cmd.ErrOrStderr(), isTerminal(cmd.InOrStdin()))
+		if err != nil {
+			return err
+		}
+		hash, err := config.HashPIN(pin, algorithm)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), hash)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(securityCmd)
+	securityCmd.AddCommand(hashPINCmd)
+	hashPINCmd.Flags().String("algorithm", config.PinHashBcrypt, "hash algorithm, bcrypt or argon2id")
+}
+
+// readPIN reads a PIN from in, asking for it twice on prompts when
+// interactive
+func readPIN(in io.Reader, prompts io.Writer, interactive bool) (string, error) {
+	reader := bufio.NewReader(in)
+	read := func(prompt string) (string, error) {
+		if interactive {
+			fmt.Fprint(prompts, prompt)
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			return "", fmt.Errorf("failed to read the PIN: %w", err)
+		}
+		return strings.TrimSpace(line), nil
+	}
+
+	pin, err := read("PIN (shown as typed): ")
+	if err != nil || !interactive {
+		return pin, err
+	}
+	again, err := read("Repeat the PIN: ")
+	if err != nil {
+		return "", err
+	}
+	if again != pin {
+		return "", errors.New("the PINs don't match")
+	}
+	return pin, nil
+}
+
+// isTerminal reports whether r is a character device, someone typing
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golte/config"
+	"golte/logger"
+	"golte/machine"
+
+	"github.com/spf13/cobra"
+)
+
+// selftestCmd sends an SMS to the SIM's own number and waits for it
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Check sending and receiving SMS with a loopback SMS",
+	Long: `Send an SMS to the SIM's own number and wait for it to come back, checking
+both the send and receive paths end to end. The own number is modem.own_number,
+or asked to the SIM with AT+CNUM. The test is skipped if neither knows it.
+
+The bridge must not be running, it would receive the SMS instead.`,
+	RunE: runSelftest,
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+	selftestCmd.Flags().String("number", "", "own number to use instead of modem.own_number or AT+CNUM")
+	selftestCmd.Flags().Duration("timeout", 2*time.Minute, "how long to wait for the SMS to come back")
+}
+
+func runSelftest(cmd *cobra.Command, args []string) error {
+	number, _ := cmd.Flags().GetString("number")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	cmd.SilenceUsage = true
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.ValidateModem(); err != nil {
+		return fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if cfg.Modem.Type == config.ModemTypeSMPP {
+		return fmt.Errorf("selftest needs a GSM modem, modem.type is %s", cfg.Modem.Type)
+	}
+	if err := logger.Setup("warn", cfg.Logging.Format); err != nil {
+		return fmt.Errorf("failed to setup logging: %w", err)
+	}
+	if number != "" {
+		cfg.Modem.OwnNumber = number
+	}
+
+	modem := machine.NewModemManager(cfg, nil, nil, nil, nil)
+	if err := modem.InitializeSender(false); err != nil {
+		return err
+	}
+	defer modem.Close()
+
+	number, err = modem.OwnNumber()
+	if errors.Is(err, machine.ErrOwnNumberUnknown) {
+		fmt.Printf("SKIP: %v\n", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Sending a loopback SMS to %s\n", number)
+	rtt, err := modem.LoopbackSMS(number, timeout)
+	if err != nil {
+		fmt.Printf("FAIL: %v\n", err)
+		cmd.SilenceErrors = true
+		return err
+	}
+	fmt.Printf("PASS: SMS came back in %s\n", rtt.Round(100*time.Millisecond))
+	return nil
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"golte/config"
+	"golte/logger"
+	"golte/machine"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of sms send, besides 1 for errors
+const (
+	exitNotDelivered = 2 // the status report says the SMS wasn't delivered
+	exitNoReport     = 3 // no final status report before --report-timeout
+)
+
+// smsCmd represents the sms command
+var smsCmd = &cobra.Command{
+	Use:   "sms",
+	Short: "SMS commands",
+	Long:  "Commands using the modem directly, without Discord.",
+}
+
+// smsSendCmd sends one SMS and exits
+var smsSendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "Send an SMS",
+	Long: `Send an SMS through the modem without starting the bridge, e.g. from scripts.
+
+The message references are printed once the modem accepted the SMS. With
+--wait-report the command waits for the status report, and exits with 2 if the
+SMS wasn't delivered or 3 if no report came in time.`,
+	RunE: runSMSSend,
+}
+
+func init() {
+	rootCmd.AddCommand(smsCmd)
+	smsCmd.AddCommand(smsSendCmd)
+	smsSendCmd.Flags().String("to", "", "phone number to text")
+	smsSendCmd.Flags().String("message", "", "message to send")
+	smsSendCmd.Flags().Bool("wait-report", false, "wait for the delivery status report")
+	smsSendCmd.Flags().Duration("report-timeout", 2*time.Minute, "how long to wait for the status report")
+	smsSendCmd.MarkFlagRequired("to")
+	smsSendCmd.MarkFlagRequired("message")
+}
+
+func runSMSSend(cmd *cobra.Command, args []string) error {
+	to, _ := cmd.Flags().GetString("to")
+	message, _ := cmd.Flags().GetString("message")
+	waitReport, _ := cmd.Flags().GetBool("wait-report")
+	reportTimeout, _ := cmd.Flags().GetDuration("report-timeout")
+	cmd.SilenceUsage = true
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.ValidateModem(); err != nil {
+		return fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if err := logger.Setup(cfg.Logging.Level, cfg.Logging.Format); err != nil {
+		return fmt.Errorf("failed to setup logging: %w", err)
+	}
+	logger.SetRedaction(cfg.Logging.Redaction)
+
+	if cfg.Modem.Type == config.ModemTypeSMPP && waitReport {
+		return errors.New("--wait-report needs a GSM modem")
+	}
+
+	modem := machine.NewModemManager(cfg, nil, nil, nil, nil)
+	if err := modem.InitializeSender(waitReport); err != nil {
+		return err
+	}
+	defer modem.Close()
+
+	reports := make(chan machine.StatusReport, 8)
+	if waitReport {
+		// Before sending, a fast SMSC may report before SubmitSMS returns
+		if err := modem.WatchStatusReports(func(r machine.StatusReport) { reports <- r }); err != nil {
+			return err
+		}
+	}
+
+	refs, err := modem.SubmitSMS(to, message)
+	if err != nil {
+		return fmt.Errorf("SMS has not been sent: %w", err)
+	}
+	if len(refs) == 0 {
+		// SMSC message IDs aren't TP message references
+		fmt.Println("SMS sent")
+	} else {
+		fmt.Printf("SMS sent, message reference(s): %s\n", joinRefs(refs))
+	}
+	if !waitReport {
+		return nil
+	}
+
+	// Every segment gets its own report
+	pending := slices.Clone(refs)
+	timeout := time.After(reportTimeout)
+	for len(pending) > 0 {
+		select {
+		case r := <-reports:
+			i := slices.Index(pending, r.Ref)
+			if i < 0 {
+				continue
+			}
+			fmt.Printf("Status report for %d: %s\n", r.Ref, r)
+			if !r.Final() {
+				continue
+			}
+			if !r.Delivered() {
+				return &exitError{code: exitNotDelivered, err: fmt.Errorf("SMS %d was not delivered", r.Ref)}
+			}
+			pending = slices.Delete(pending, i, i+1)
+		case <-timeout:
+			return &exitError{code: exitNoReport, err: fmt.Errorf("no status report for %s after %s", joinRefs(pending), reportTimeout)}
+		}
+	}
+	fmt.Println("SMS delivered")
+	return nil
+}
+
+func joinRefs(refs []int) string {
+	s := make([]string, len(refs))
+	for i, ref := range refs {
+		s[i] = fmt.Sprint(ref)
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"golte/config"
+	"golte/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// storageCmd groups the maintenance of the store
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Storage maintenance commands",
+	Long: `Commands looking after the store of storage.path, where the bridge keeps the
+SMS and calls it saw, runtime state and counters across restarts.`,
+}
+
+// storageStatsCmd prints what the store holds
+var storageStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show what the store holds and its size",
+	Args:  cobra.NoArgs,
+	RunE:  runStorageStats,
+}
+
+// storageVacuumCmd compacts the store
+var storageVacuumCmd = &cobra.Command{
+	Use:   "vacuum",
+	Short: "Reclaim the space of deleted and superseded data",
+	Long: `Compact the store, e.g. rewrite the journal of the file driver with only the
+current data. Stop the bridge first, the store is meant to have one writer.`,
+	Args: cobra.NoArgs,
+	RunE: runStorageVacuum,
+}
+
+// storageEncryptCmd encrypts an existing store
+var storageEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the store with storage.encryption_key",
+	Long: `Copy the store into a new one encrypted with storage.encryption_key and swap
+it in, for a store written before the key was set. With --old-key-file, re-encrypt
+a store encrypted with the key in that file, to rotate the key. Stop the bridge
+first.`,
+	Args: cobra.NoArgs,
+	RunE: runStorageEncrypt,
+}
+
+var storageOldKeyFile string
+
+func init() {
+	rootCmd.AddCommand(storageCmd)
+	storageCmd.AddCommand(storageStatsCmd, storageVacuumCmd, storageEncryptCmd)
+	storageEncryptCmd.Flags().StringVar(&storageOldKeyFile, "old-key-file", "", "file holding the key the store is encrypted with, to rotate it")
+}
+
+// openStorage opens the store of the configuration, decrypting it with
+// storage.encryption_key
+func openStorage() (storage.Store, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.Storage.Path == "" {
+		return nil, errors.New("storage.path is empty, the bridge keeps nothing")
+	}
+	key, err := cfg.Storage.Key()
+	if err != nil {
+		return nil, fmt.Errorf("storage.encryption_key: %w", err)
+	}
+	return storage.OpenWithKey(cfg.Storage.Driver, cfg.Storage.Path, key)
+}
+
+func runStorageStats(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	s, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	stats, err := s.Stats()
+	if err != nil {
+		return err
+	}
+	printStats(stats)
+	return nil
+}
+
+func runStorageVacuum(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	s, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	before, err := s.Stats()
+	if err != nil {
+		return err
+	}
+	if err := s.Vacuum(); err != nil {
+		return err
+	}
+	after, err := s.Stats()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Vacuumed %s: %d bytes, was %d\n", after.Path, after.Size, before.Size)
+	return nil
+}
+
+func runStorageEncrypt(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.Storage.Path == "" {
+		return errors.New("storage.path is empty, the bridge keeps nothing")
+	}
+	key, err := cfg.Storage.Key()
+	if err != nil {
+		return fmt.Errorf("storage.encryption_key: %w", err)
+	}
+	if key == nil {
+		return errors.New("storage.encryption_key is empty, set the key to encrypt with")
+	}
+
+	from, err := storage.Open(cfg.Storage.Driver, cfg.Storage.Path)
+	if err != nil {
+		return err
+	}
+	defer from.Close()
+	if from, err = decryptStorageFrom(from, cfg.Storage.Path); err != nil {
+		return err
+	}
+
+	// The copy is written next to the store and swapped in once complete
+	tmp := cfg.Storage.Path + ".encrypting"
+	removeStore(tmp)
+	stats, err := encryptStorage(from, cfg.Storage.Driver, tmp, key)
+	if err != nil {
+		removeStore(tmp)
+		return fmt.Errorf("failed to encrypt %s: %w", cfg.Storage.Path, err)
+	}
+	if err := from.Close(); err != nil {
+		removeStore(tmp)
+		return err
+	}
+
+	removeJournals(cfg.Storage.Path)
+	if err := os.Rename(tmp, cfg.Storage.Path); err != nil {
+		return fmt.Errorf("failed to replace %s, the encrypted copy is %s: %w", cfg.Storage.Path, tmp, err)
+	}
+	fmt.Printf("Encrypted %s: %d messages, %d calls, %d keys\n", cfg.Storage.Path, stats.Messages, stats.Calls, stats.Keys)
+	fmt.Println("The previous file was replaced, its content may linger on the disk until overwritten")
+	return nil
+}
+
+// decryptStorageFrom returns the store to encrypt, decrypted with the key of
+// --old-key-file if it already is
+func decryptStorageFrom(s storage.Store, path string) (storage.Store, error) {
+	encrypted, err := storage.IsEncrypted(s)
+	switch {
+	case err != nil:
+		return nil, err
+	case !encrypted && storageOldKeyFile == "":
+		return s, nil
+	case !encrypted:
+		return nil, fmt.Errorf("%s isn't encrypted, --old-key-file doesn't apply", path)
+	case storageOldKeyFile == "":
+		return nil, fmt.Errorf("%s is already encrypted, pass --old-key-file to re-encrypt it with another key", path)
+	}
+
+	data, err := os.ReadFile(storageOldKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := storage.ParseKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("--old-key-file: %w", err)
+	}
+	return storage.Encrypt(s, key)
+}
+
+// encryptStorage copies from into a new store of driver at path encrypted
+// with key, and returns what it holds
+func encryptStorage(from storage.Store, driver, path string, key []byte) (storage.Stats, error) {
+	s, err := storage.Open(driver, path)
+	if err != nil {
+		return storage.Stats{}, err
+	}
+	defer s.Close()
+
+	to, err := storage.Encrypt(s, key)
+	if err != nil {
+		return storage.Stats{}, err
+	}
+	if err := storage.Migrate(from, to); err != nil {
+		return storage.Stats{}, err
+	}
+	stats, err := to.Stats()
+	if err != nil {
+		return storage.Stats{}, err
+	}
+	return stats, s.Close()
+}
+
+// removeStore removes the store at path and its journals
+func removeStore(path string) {
+	os.Remove(path)
+	removeJournals(path)
+}
+
+// removeJournals removes the files SQLite keeps next to the database at
+// path, which mustn't outlive it
+func removeJournals(path string) {
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		os.Remove(path + suffix)
+	}
+}
+
+// printStats prints stats as a table
+func printStats(stats storage.Stats) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Driver\t%s\n", stats.Driver)
+	fmt.Fprintf(w, "Path\t%s\n", stats.Path)
+	fmt.Fprintf(w, "Schema\t%d\n", stats.Version)
+	fmt.Fprintf(w, "Size\t%d bytes\n", stats.Size)
+	fmt.Fprintf(w, "Messages\t%d\n", stats.Messages)
+	fmt.Fprintf(w, "Calls\t%d\n", stats.Calls)
+	fmt.Fprintf(w, "Keys\t%d\n", stats.Keys)
+	fmt.Fprintf(w, "Counters\t%d\n", stats.Counters)
+	fmt.Fprintf(w, "Reclaimable\t%d\n", stats.Reclaimable)
+	w.Flush()
+}
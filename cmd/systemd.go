@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"golte/machine"
+	"golte/sdnotify"
+)
+
+// healthInterval is how often the health of the bridge is checked for
+// systemctl status when systemd has no watchdog
+const healthInterval = 30 * time.Second
+
+// notify sends state to systemd, if it listens
+func notify(state string) {
+	if err := sdnotify.Notify(state); err != nil {
+		slog.Warn("Failed to notify systemd", slog.String("state", state), slog.Any("error", err))
+	}
+}
+
+// notifyStatus sets the status shown by systemctl status
+func notifyStatus(status string) {
+	notify("STATUS=" + status)
+}
+
+// superviseSystemd checks the health of m until ctx is done, pinging the
+// systemd watchdog while it's healthy so a wedged bridge gets restarted
+func superviseSystemd(ctx context.Context, m *machine.Machine) {
+	if !sdnotify.Enabled() {
+		return
+	}
+
+	// Ping twice per watchdog period, a single late check mustn't get the
+	// bridge killed
+	watchdog := sdnotify.WatchdogInterval()
+	interval := healthInterval
+	if watchdog > 0 {
+		interval = watchdog / 2
+		slog.Info("Pinging the systemd watchdog", slog.Duration("timeout", watchdog))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	healthy := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := m.Healthy()
+		switch {
+		case err == nil && watchdog > 0:
+			notify(sdnotify.Watchdog)
+		case err != nil:
+			slog.Warn("Health check failed", slog.Any("error", err))
+		}
+
+		if healthy != (err == nil) {
+			if err == nil {
+				notifyStatus(machine.StatusRunning)
+			} else {
+				notifyStatus(err.Error())
+			}
+		}
+		healthy = err == nil
+	}
+}
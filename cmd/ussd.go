@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golte/config"
+	"golte/logger"
+	"golte/machine"
+
+	"github.com/spf13/cobra"
+)
+
+// ussdCmd runs a USSD session
+var ussdCmd = &cobra.Command{
+	Use:   "ussd <code>",
+	Short: "Send a USSD code, e.g. a balance check",
+	Long: `Send a USSD code through the modem without starting the bridge and print the
+network's response. With --reply, menus are browsed by typing the next choice,
+an empty line ends the session.`,
+	Example: `  golte ussd '*123#'
+  golte ussd --reply '#100#'`,
+	Args: cobra.ExactArgs(1),
+	RunE: runUSSD,
+}
+
+func init() {
+	rootCmd.AddCommand(ussdCmd)
+	ussdCmd.Flags().Bool("reply", false, "answer menus, reading each reply from stdin")
+	ussdCmd.Flags().Duration("timeout", 30*time.Second, "how long the network may take to answer")
+}
+
+func runUSSD(cmd *cobra.Command, args []string) error {
+	reply, _ := cmd.Flags().GetBool("reply")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	cmd.SilenceUsage = true
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.ValidateModem(); err != nil {
+		return fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if cfg.Modem.Type == config.ModemTypeSMPP {
+		return fmt.Errorf("USSD needs a GSM modem, modem.type is %s", cfg.Modem.Type)
+	}
+	if err := logger.Setup("warn", cfg.Logging.Format); err != nil {
+		return fmt.Errorf("failed to setup logging: %w", err)
+	}
+
+	modem := machine.NewModemManager(cfg, nil, nil, nil, nil)
+	if err := modem.InitializeSender(false); err != nil {
+		return err
+	}
+	defer modem.Close()
+
+	session, response, err := modem.StartUSSD(args[0], timeout)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	input := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Println(response.Text)
+		if !response.ReplyExpected() || !reply {
+			return nil
+		}
+
+		fmt.Print("> ")
+		if !input.Scan() {
+			return input.Err()
+		}
+		text := strings.TrimSpace(input.Text())
+		if text == "" {
+			return nil
+		}
+		if response, err = session.Reply(text, timeout); err != nil {
+			return err
+		}
+	}
+}
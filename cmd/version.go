@@ -2,6 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"time"
+
+	"golte/config"
+	"golte/logger"
+	"golte/machine"
 
 	"github.com/spf13/cobra"
 )
@@ -13,18 +19,71 @@ var (
 	BuildDate = "unknown"
 )
 
+// versionModemTimeout bounds opening the modem and each query of --modem
+const versionModemTimeout = 3 * time.Second
+
 // versionCmd represents the version command
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information",
-	Long:  "Print version, git commit, and build date information for golte.",
+	Long: `Print version, git commit, and build date information for golte.
+
+With --modem the configured modem's manufacturer, model and firmware revision
+are printed too, handy for bug reports. The build information is printed even
+if the modem can't be reached.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Printf("golte version %s\n", Version)
 		fmt.Printf("Git commit: %s\n", GitCommit)
 		fmt.Printf("Built: %s\n", BuildDate)
+
+		if withModem, _ := cmd.Flags().GetBool("modem"); withModem {
+			if err := printModemVersion(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: modem information unavailable: %v\n", err)
+			}
+		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(versionCmd)
+	versionCmd.Flags().Bool("modem", false, "also query the modem's model and firmware")
+}
+
+// printModemVersion prints the modem identity, using only the modem section
+// of the configuration
+func printModemVersion() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.ValidateModem(); err != nil {
+		return err
+	}
+	if cfg.Modem.Type == config.ModemTypeSMPP {
+		return fmt.Errorf("modem.type is %s, there is no modem", cfg.Modem.Type)
+	}
+	if err := logger.Setup("error", cfg.Logging.Format); err != nil {
+		return fmt.Errorf("failed to setup logging: %w", err)
+	}
+
+	cfg.Modem.Timeout = versionModemTimeout
+	modem := machine.NewModemManager(cfg, nil, nil, nil, nil)
+	if err := modem.InitializeSender(false); err != nil {
+		return err
+	}
+	defer modem.Close()
+
+	items, err := modem.Firmware(versionModemTimeout)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Modem: %s\n", cfg.Modem.Device)
+	for _, item := range items {
+		value := item.Value
+		if item.Error != "" {
+			value = "unknown (" + item.Error + ")"
+		}
+		fmt.Printf("  %s: %s\n", item.Name, value)
+	}
+	return nil
 }
@@ -0,0 +1,65 @@
+package config
+
+import "strings"
+
+// Roles of discord.access.default_role and discord.access.commands, besides
+// the ID of a Discord role
+const (
+	AccessEveryone = "everyone"
+	AccessAdmin    = "admin"
+)
+
+// AccessConfig decides who may use the bridge from Discord. Admins, the
+// users of AdminUsers, discord.owner_ids and security.admin_user_ids, may do
+// everything.
+type AccessConfig struct {
+	// DefaultRole is needed for the commands without an entry in Commands:
+	// everyone, admin, or the ID of a Discord role. Empty is everyone.
+	DefaultRole string `mapstructure:"default_role"`
+
+	// Commands overrides DefaultRole per command or subcommand, e.g.
+	// "phonebook add": everyone. Commands changing the modem or the bridge
+	// are admin only unless listed here.
+	Commands map[string]string `mapstructure:"commands"`
+
+	Users          []string `mapstructure:"users"`            // allowed without the role, except admin only commands
+	AdminUsers     []string `mapstructure:"admin_users"`      // allowed everything
+	AuditChannelID string   `mapstructure:"audit_channel_id"` // refused attempts and HTTP API requests are reported there, empty for none
+}
+
+// validate checks the roles are everyone, admin or role IDs and the users
+// are user IDs
+func (a *AccessConfig) validate(errs *ValidationErrors) {
+	if !isAccessRole(a.DefaultRole) {
+		errs.add("discord.access.default_role", "%q must be everyone, admin or a Discord role ID", a.DefaultRole)
+	}
+	for command, role := range a.Commands {
+		if strings.TrimSpace(command) == "" {
+			errs.add("discord.access.commands", "has an empty command name")
+		}
+		if !isAccessRole(role) {
+			errs.add("discord.access.commands", "%q for %s must be everyone, admin or a Discord role ID", role, command)
+		}
+	}
+
+	for _, list := range []struct {
+		key string
+		ids []string
+	}{
+		{"discord.access.users", a.Users},
+		{"discord.access.admin_users", a.AdminUsers},
+	} {
+		for _, id := range list.ids {
+			if !isSnowflake(id) {
+				errs.add(list.key, "%q is not a Discord user ID", id)
+			}
+		}
+	}
+	if a.AuditChannelID != "" && !isSnowflake(a.AuditChannelID) {
+		errs.add("discord.access.audit_channel_id", "%q is not a Discord channel ID", a.AuditChannelID)
+	}
+}
+
+func isAccessRole(role string) bool {
+	return role == "" || role == AccessEveryone || role == AccessAdmin || isSnowflake(role)
+}
@@ -0,0 +1,30 @@
+package config
+
+import "net"
+
+// APIConfig controls the local HTTP API scripts send SMS and place calls
+// through
+type APIConfig struct {
+	ListenAddr string `mapstructure:"listen_addr"` // host:port, empty disables the API
+	Token      string `mapstructure:"token"`       // bearer token every request must carry
+	TokenFile  string `mapstructure:"token_file"`  // read the token from this file instead
+}
+
+// Enabled reports whether the API is served
+func (a APIConfig) Enabled() bool {
+	return a.ListenAddr != ""
+}
+
+// validate checks the address, and that the API can't be used without a
+// token
+func (a *APIConfig) validate(errs *ValidationErrors) {
+	if !a.Enabled() {
+		return
+	}
+	if _, _, err := net.SplitHostPort(a.ListenAddr); err != nil {
+		errs.add("api.listen_addr", "must be host:port, e.g. 127.0.0.1:8080")
+	}
+	if a.Token == "" {
+		errs.add("api.token", "is required with api.listen_addr")
+	}
+}
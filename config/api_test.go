@@ -0,0 +1,25 @@
+package config
+
+import "testing"
+
+func TestAPIValidate(t *testing.T) {
+	tests := []struct {
+		api   APIConfig
+		field string
+	}{
+		{APIConfig{}, ""},
+		{APIConfig{ListenAddr: "127.0.0.1:8080", Token: "secret"}, ""},
+		{APIConfig{ListenAddr: "127.0.0.1:8080"}, "api.token"},
+		{APIConfig{ListenAddr: "8080", Token: "secret"}, "api.listen_addr"},
+	}
+	for _, tt := range tests {
+		var errs ValidationErrors
+		tt.api.validate(&errs)
+		switch {
+		case tt.field == "" && len(errs) != 0:
+			t.Errorf("validate(%+v) = %v, want no error", tt.api, errs)
+		case tt.field != "" && (len(errs) != 1 || errs[0].Field != tt.field):
+			t.Errorf("validate(%+v) = %v, want a %s error", tt.api, errs, tt.field)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -9,56 +10,504 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
+	// Profile is the entry of profiles applied over the rest of the file
+	Profile string `mapstructure:"profile"`
+
+	// Timezone
+	General GeneralConfig `mapstructure:"general"`
+
 	// Modem configuration
 	Modem ModemConfig `mapstructure:"modem"`
 
+	// Signal quality monitor
+	Monitor MonitorConfig `mapstructure:"monitor"`
+
 	// Discord configuration
 	Discord DiscordConfig `mapstructure:"discord"`
 
+	// Call configuration
+	Call CallConfig `mapstructure:"call"`
+
+	// Broadcast configuration
+	Broadcast BroadcastConfig `mapstructure:"broadcast"`
+
+	// Audio configuration
+	Audio AudioConfig `mapstructure:"audio"`
+
+	// Voice channel configuration
+	Voice VoiceConfig `mapstructure:"voice"`
+
 	// Logging configuration
 	Logging LoggingConfig `mapstructure:"logging"`
+
+	// Debugging aids
+	Debug DebugConfig `mapstructure:"debug"`
+
+	// Health state for golte healthcheck
+	Health HealthConfig `mapstructure:"health"`
+
+	// History of SMS and calls
+	Storage StorageConfig `mapstructure:"storage"`
+
+	// PIN, lockout and access lists
+	Security SecurityConfig `mapstructure:"security"`
+
+	// Local HTTP API
+	API APIConfig `mapstructure:"api"`
+
+	// MQTT bridge
+	MQTT MQTTConfig `mapstructure:"mqtt"`
+
+	// Telegram bot, alongside or instead of Discord
+	Telegram TelegramConfig `mapstructure:"telegram"`
+
+	// Endpoints SMS and call events are POSTed to
+	Webhooks []WebhookConfig `mapstructure:"webhooks"`
 }
 
 // ModemConfig holds modem-specific configuration
 type ModemConfig struct {
+	Type    string        `mapstructure:"type"` // gsm or smpp
 	Device  string        `mapstructure:"device"`
-	Baud    int           `mapstructure:"baud"`
+	Baud    int           `mapstructure:"baud"` // BaudAuto to detect it
 	Timeout time.Duration `mapstructure:"timeout"`
+
+	// SMSTimeout bounds each segment of an SMS sent, so a long message gets
+	// it once per segment. QueryTimeout bounds the quick queries of the
+	// signal monitor and /status. 0 uses Timeout.
+	SMSTimeout   time.Duration `mapstructure:"sms_timeout"`
+	QueryTimeout time.Duration `mapstructure:"query_timeout"`
+
+	// CommandRetries is how many times sending an SMS, dialing or hanging
+	// up is retried after the modem stopped answering and was recovered
+	CommandRetries int `mapstructure:"command_retries"`
+
+	TransliterateOutbound bool `mapstructure:"transliterate_outbound"` // fold non GSM-7 characters instead of sending UCS2
+	MaxSMSSegments        int  `mapstructure:"max_sms_segments"`       // /send asks for confirmation above this many segments, 0 never asks
+
+	SMPP SMPPConfig `mapstructure:"smpp"`
+
+	Mock MockConfig `mapstructure:"mock"`
+
+	Reregister ReregisterConfig `mapstructure:"reregister"`
+
+	ClockSync string `mapstructure:"clock_sync"` // off, timestamps or system, see the ClockSync constants
+
+	OwnNumber string `mapstructure:"own_number"` // the SIM's number, when AT+CNUM doesn't know it
+
+	Label string `mapstructure:"label"` // names the modem in webhook events, empty uses the device
+
+	Trace TraceConfig `mapstructure:"trace"`
+}
+
+// Name returns modem.label, or the device or SMSC the bridge uses when it's
+// empty
+func (m ModemConfig) Name() string {
+	switch {
+	case m.Label != "":
+		return m.Label
+	case m.Type == ModemTypeSMPP:
+		return m.SMPP.Addr
+	default:
+		return m.Device
+	}
+}
+
+// CarriesAudio reports whether calls go through a sound card, which only a
+// GSM modem has. SMPP has no calls and simulated ones are silent.
+func (m ModemConfig) CarriesAudio() bool {
+	return m.Type == "" || m.Type == ModemTypeGSM
+}
+
+// ReregisterConfig controls nudging a modem stuck searching for the network
+type ReregisterConfig struct {
+	After    time.Duration `mapstructure:"after"`    // signal lost this long triggers a nudge, 0 disables
+	Interval time.Duration `mapstructure:"interval"` // minimum time between two nudges
+	Method   string        `mapstructure:"method"`   // cops (AT+COPS=0) or cfun (AT+CFUN=0 then 1)
+}
+
+// TraceConfig logs the bytes exchanged with the modem to a file of their own,
+// also switched on and off with /modem trace
+type TraceConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	Path       string `mapstructure:"path"`
+	MaxSize    int    `mapstructure:"max_size"`    // megabytes before the file is rotated, 0 uses 10
+	MaxBackups int    `mapstructure:"max_backups"` // rotated files kept
+	Redact     bool   `mapstructure:"redact"`      // leave SMS bodies out
+}
+
+// Re-registration methods
+const (
+	ReregisterCOPS = "cops"
+	ReregisterCFUN = "cfun"
+)
+
+// Uses of the network time read from the modem clock
+const (
+	ClockSyncOff        = "off"
+	ClockSyncTimestamps = "timestamps" // timestamp notifications with it, the host clock is left alone
+	ClockSyncSystem     = "system"     // set the host clock too, needs CAP_SYS_TIME
+)
+
+// Modem types
+const (
+	ModemTypeGSM  = "gsm"
+	ModemTypeSMPP = "smpp"
+	ModemTypeMock = "mock" // simulated modem, see MockConfig
+)
+
+// BaudAuto is modem.baud when set to auto, the rate is detected by trying
+// AutoBauds until the modem answers
+const BaudAuto = 0
+
+// AutoBauds are the rates tried when modem.baud is auto
+var AutoBauds = []int{9600, 19200, 57600, 115200, 230400}
+
+// SMPPConfig holds the SMSC account used when modem.type is smpp
+type SMPPConfig struct {
+	Addr         string        `mapstructure:"addr"` // host:port
+	TLS          bool          `mapstructure:"tls"`
+	SystemID     string        `mapstructure:"system_id"`
+	Password     string        `mapstructure:"password"`
+	PasswordFile string        `mapstructure:"password_file"` // read the password from this file instead
+	SystemType   string        `mapstructure:"system_type"`
+	SourceAddr   string        `mapstructure:"source_addr"` // sender number or alphanumeric ID
+	SourceTON    uint8         `mapstructure:"source_ton"`
+	SourceNPI    uint8         `mapstructure:"source_npi"`
+	EnquireLink  time.Duration `mapstructure:"enquire_link"`
+}
+
+// MockConfig tunes the simulated modem used when modem.type is mock or with
+// --dry-run
+type MockConfig struct {
+	SendDelay time.Duration `mapstructure:"send_delay"` // how long sending an SMS takes
+	Listen    string        `mapstructure:"listen"`     // host:port of the endpoint injecting SMS and calls, empty disables it
 }
 
 // DiscordConfig holds Discord-specific configuration
 type DiscordConfig struct {
-	Token          string `mapstructure:"token"`
-	ChannelID      string `mapstructure:"channel_id"`
-	GuildID        string `mapstructure:"guild_id"`
-	VoiceChannelID string `mapstructure:"voice_channel_id"`
+	Token          string   `mapstructure:"token"`
+	TokenFile      string   `mapstructure:"token_file"` // read the token from this file instead, e.g. a systemd credential
+	ChannelIDs     []string `mapstructure:"channel_id"` // one ID or a list, every channel gets the SMS and calls
+	GuildID        string   `mapstructure:"guild_id"`
+	VoiceChannelID string   `mapstructure:"voice_channel_id"`
+	OwnerIDs       []string `mapstructure:"owner_ids"`     // users allowed to run destructive commands
+	ShortenerURL   string   `mapstructure:"shortener_url"` // link shortener for /sendfile, %s is the escaped URL
+	LogOutbound    bool     `mapstructure:"log_outbound"`  // post sent SMS to the channel
+	SMSIcon        string   `mapstructure:"sms_icon"`      // icon URL shown next to the sender of SMS embeds
+	CallIcon       string   `mapstructure:"call_icon"`     // icon URL shown next to the caller of call embeds
+
+	// AnnounceOnReady posts the version, modem and network once the bridge
+	// is connected, once per process
+	AnnounceOnReady bool `mapstructure:"announce_on_ready"`
+
+	// Mentions maps phone numbers to the user ID or "role:<id>" pinged
+	// when they send an SMS
+	Mentions map[string]string `mapstructure:"mentions"`
+
+	// NumberChannels maps phone numbers to a channel of their own, their SMS
+	// are posted there instead of ChannelID
+	NumberChannels map[string]string `mapstructure:"number_channels"`
+
+	// ChannelReplyMode is embed to only send replies to an SMS embed, or any
+	// to send every message typed in a number's channel to that number
+	ChannelReplyMode string `mapstructure:"channel_reply_mode"`
+
+	// EnrichNumbers adds the country of international senders and callers
+	// to their embeds, and the carrier when CarrierPrefixes knows it
+	EnrichNumbers   bool              `mapstructure:"enrich_numbers"`
+	CarrierPrefixes map[string]string `mapstructure:"carrier_prefixes"` // E.164 prefix, e.g. +3366, to carrier name
+
+	Access AccessConfig `mapstructure:"access"`
+
+	Registration RegistrationConfig `mapstructure:"registration"`
+}
+
+// RegistrationConfig bounds the registration of the slash commands at
+// startup. When every attempt fails the bridge starts without them and keeps
+// trying in the background. 0 uses the defaults.
+type RegistrationConfig struct {
+	Attempts int           `mapstructure:"attempts"` // tries before starting without the commands
+	Timeout  time.Duration `mapstructure:"timeout"`  // of each try
+}
+
+// Channel reply modes
+const (
+	ChannelReplyEmbed = "embed" // only replies to an SMS embed are sent
+	ChannelReplyAny   = "any"   // any message in a number's channel is sent
+)
+
+// CallConfig holds voice call configuration
+type CallConfig struct {
+	KeypressFeedback string        `mapstructure:"keypress_feedback"` // tones, spoken or silent
+	MaxDuration      time.Duration `mapstructure:"max_duration"`      // answered calls are hung up after this, 0 means no limit
+
+	// DTMFTimeout ends a DTMF entry once the caller paused this long after a
+	// digit, 0 waits for # forever. DTMFTimeoutAction is then reset to
+	// discard it or evaluate to check it, the password being checked as it's
+	// typed with reset.
+	DTMFTimeout       time.Duration `mapstructure:"dtmf_timeout"`
+	DTMFTimeoutAction string        `mapstructure:"dtmf_timeout_action"`
+	DTMFTimeoutReplay bool          `mapstructure:"dtmf_timeout_replay"` // play the password prompt again after a timeout
+
+	// Routes decide what incoming calls do by caller, the first matching
+	// one wins. Callers matching none go through the password IVR.
+	Routes []CallRoute `mapstructure:"routes"`
+}
+
+// Actions of call.dtmf_timeout_action
+const (
+	DTMFTimeoutReset    = "reset"
+	DTMFTimeoutEvaluate = "evaluate"
+)
+
+// BroadcastConfig controls /broadcast, meant for small alert fan-outs
+type BroadcastConfig struct {
+	Groups        map[string][]string `mapstructure:"groups"`         // named lists of numbers, usable as recipients
+	MaxRecipients int                 `mapstructure:"max_recipients"` // numbers a single broadcast can reach
+	Interval      time.Duration       `mapstructure:"interval"`       // pause between two SMS of a broadcast
+}
+
+// maxBroadcastRecipients caps broadcast.max_recipients
+const maxBroadcastRecipients = 100
+
+// AudioConfig holds audio configuration
+type AudioConfig struct {
+	FFmpegPath     string        `mapstructure:"ffmpeg_path"`     // ffmpeg executable, looked up in PATH if not absolute
+	Device         string        `mapstructure:"device"`          // ALSA device of the modem's sound card
+	PlaybackDevice string        `mapstructure:"playback_device"` // ALSA device played to through ffmpeg, empty for the default output
+	Preload        bool          `mapstructure:"preload"`         // decode every prompt at startup
+	AssetsDir      string        `mapstructure:"assets_dir"`      // prompts overriding or adding to the embedded ones, empty for none
+	CacheBudgetMB  int           `mapstructure:"cache_budget_mb"` // 0 means unlimited
+	DuckDepthDB    float64       `mapstructure:"duck_depth_db"`   // Discord audio attenuation during prompts, 0 disables
+	Opus           OpusConfig    `mapstructure:"opus"`
+	Capture        CaptureConfig `mapstructure:"capture"`
+	VAD            VADConfig     `mapstructure:"vad"`
+}
+
+// VADConfig gates the call audio sent to Discord on voice activity
+type VADConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	ThresholdDB float64       `mapstructure:"threshold_db"` // frame level in dBFS counted as voice
+	Hangover    time.Duration `mapstructure:"hangover"`     // keep sending this long after the voice drops
+	PreRoll     time.Duration `mapstructure:"pre_roll"`     // audio sent from before the voice started
+}
+
+// CaptureConfig tunes how the call audio is read from ffmpeg
+type CaptureConfig struct {
+	Frame         time.Duration `mapstructure:"frame"`          // read size: 10ms, 20ms, 40ms or 60ms
+	BufferSize    int           `mapstructure:"buffer_size"`    // reader buffer in bytes
+	UnderrunGrace time.Duration `mapstructure:"underrun_grace"` // stall tolerated before ending the stream
+}
+
+// OpusConfig holds the settings of the encoder used towards Discord
+type OpusConfig struct {
+	Application string `mapstructure:"application"` // voip, audio or lowdelay
+	Bitrate     int    `mapstructure:"bitrate"`     // bits per second, 0 lets opus decide
+	Complexity  int    `mapstructure:"complexity"`  // 0 (fastest) to 10 (best)
+	InbandFEC   bool   `mapstructure:"inband_fec"`
+	DTX         bool   `mapstructure:"dtx"`
+}
+
+// VoiceConfig holds Discord voice channel configuration
+type VoiceConfig struct {
+	// Enabled bridges call audio with the voice channel. Off, no audio is
+	// played or captured, ffmpeg isn't needed and incoming calls are only
+	// notified.
+	Enabled bool `mapstructure:"enabled"`
+
+	TransmitUsers []string `mapstructure:"transmit_users"` // user IDs heard by the caller, empty means everyone
 }
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
 	Level  string `mapstructure:"level"`
 	Format string `mapstructure:"format"` // json or text
+
+	BufferSize int `mapstructure:"buffer_size"` // records kept in memory for /logs and GET /v1/logs, 0 keeps none
+
+	// Redaction masks phone numbers and SMS bodies: none, partial or full,
+	// see the Redact constants of the logger package
+	Redaction string `mapstructure:"redaction"`
+
+	// File is a path the logs are written to, see Output
+	File     string            `mapstructure:"file"`
+	Output   string            `mapstructure:"output"` // stdout, file or both, empty for the file if set and stdout otherwise
+	Rotation LogRotationConfig `mapstructure:"rotation"`
+}
+
+// Outputs of logging.output
+const (
+	LogOutputStdout = "stdout"
+	LogOutputFile   = "file"
+	LogOutputBoth   = "both"
+)
+
+// Targets returns whether the logs go to stdout and the file they go to,
+// empty for none
+func (l LoggingConfig) Targets() (stdout bool, file string) {
+	switch strings.ToLower(l.Output) {
+	case LogOutputStdout:
+		return true, ""
+	case LogOutputFile:
+		return false, l.File
+	case LogOutputBoth:
+		return true, l.File
+	}
+	return l.File == "", l.File
+}
+
+// LogRotationConfig controls the rotation of logging.file
+type LogRotationConfig struct {
+	MaxSize    int           `mapstructure:"max_size"`    // in megabytes, rotated past it, 0 never rotates
+	MaxBackups int           `mapstructure:"max_backups"` // rotated files kept
+	MaxAge     time.Duration `mapstructure:"max_age"`     // rotated files older than this are removed, 0 keeps them
+}
+
+// DebugConfig holds settings to investigate problems, off by default
+type DebugConfig struct {
+	IncludeRawPDU bool `mapstructure:"include_raw_pdu"` // log the PDU of every incoming SMS at debug level
+	RawPDUEmbed   bool `mapstructure:"raw_pdu_embed"`   // add a button showing the PDU to owners on SMS embeds
+}
+
+// HealthConfig holds where the running bridge records its health
+type HealthConfig struct {
+	StateFile string        `mapstructure:"state_file"` // refreshed every Interval, empty to disable
+	Interval  time.Duration `mapstructure:"interval"`
 }
 
 // LoadConfig loads configuration from file and environment variables
 func LoadConfig() (*Config, error) {
 	// Set defaults
+	viper.SetDefault("profile", "")
+	viper.SetDefault("general.timezone", "")
+	viper.SetDefault("modem.type", ModemTypeGSM)
 	viper.SetDefault("modem.device", "/dev/serial0")
 	viper.SetDefault("modem.baud", 115200)
 	viper.SetDefault("modem.timeout", "20s")
+	viper.SetDefault("modem.sms_timeout", "30s")
+	viper.SetDefault("modem.query_timeout", "3s")
+	viper.SetDefault("modem.command_retries", 1)
+	viper.SetDefault("modem.transliterate_outbound", false)
+	viper.SetDefault("modem.max_sms_segments", 4)
+	viper.SetDefault("modem.smpp.enquire_link", "30s")
+	viper.SetDefault("modem.mock.send_delay", "1s")
+	viper.SetDefault("modem.mock.listen", "127.0.0.1:8765")
+	viper.SetDefault("modem.reregister.after", 0)
+	viper.SetDefault("modem.reregister.interval", "30m")
+	viper.SetDefault("modem.reregister.method", ReregisterCOPS)
+	viper.SetDefault("modem.clock_sync", ClockSyncOff)
+	viper.SetDefault("modem.own_number", "")
+	viper.SetDefault("modem.label", "")
+	viper.SetDefault("modem.trace.enabled", false)
+	viper.SetDefault("modem.trace.path", "golte-at-trace.log")
+	viper.SetDefault("modem.trace.max_size", 10)
+	viper.SetDefault("modem.trace.max_backups", 3)
+	viper.SetDefault("modem.trace.redact", true)
+	viper.SetDefault("monitor.signal_interval", "1m")
+	viper.SetDefault("monitor.log_level", "debug")
+	viper.SetDefault("monitor.signal_reports", false)
+	viper.SetDefault("discord.channel_id", []string{})
+	viper.SetDefault("discord.guild_id", "")
+	viper.SetDefault("discord.voice_channel_id", "")
+	viper.SetDefault("discord.owner_ids", []string{})
+	viper.SetDefault("discord.log_outbound", true)
+	viper.SetDefault("discord.sms_icon", "")
+	viper.SetDefault("discord.call_icon", "")
+	viper.SetDefault("discord.announce_on_ready", false)
+	viper.SetDefault("discord.channel_reply_mode", ChannelReplyEmbed)
+	viper.SetDefault("discord.enrich_numbers", false)
+	viper.SetDefault("discord.access.default_role", AccessEveryone)
+	viper.SetDefault("discord.access.users", []string{})
+	viper.SetDefault("discord.access.admin_users", []string{})
+	viper.SetDefault("discord.access.audit_channel_id", "")
+	viper.SetDefault("discord.registration.attempts", 3)
+	viper.SetDefault("discord.registration.timeout", "10s")
+	viper.SetDefault("call.keypress_feedback", "tones")
+	viper.SetDefault("call.max_duration", 0)
+	viper.SetDefault("call.dtmf_timeout", "5s")
+	viper.SetDefault("call.dtmf_timeout_action", DTMFTimeoutReset)
+	viper.SetDefault("call.dtmf_timeout_replay", true)
+	viper.SetDefault("call.routes", []CallRoute{})
+	viper.SetDefault("broadcast.max_recipients", 20)
+	viper.SetDefault("broadcast.interval", "3s")
+	viper.SetDefault("audio.ffmpeg_path", "ffmpeg")
+	viper.SetDefault("audio.device", "hw:2,0")
+	viper.SetDefault("audio.playback_device", "")
+	viper.SetDefault("audio.preload", false)
+	viper.SetDefault("audio.assets_dir", "")
+	viper.SetDefault("audio.cache_budget_mb", 0)
+	viper.SetDefault("audio.duck_depth_db", 12)
+	viper.SetDefault("audio.capture.frame", "20ms")
+	viper.SetDefault("audio.capture.buffer_size", 65307)
+	viper.SetDefault("audio.capture.underrun_grace", "200ms")
+	viper.SetDefault("audio.vad.enabled", false)
+	viper.SetDefault("audio.vad.threshold_db", -45)
+	viper.SetDefault("audio.vad.hangover", "400ms")
+	viper.SetDefault("audio.vad.pre_roll", "100ms")
+	viper.SetDefault("audio.opus.application", "voip")
+	viper.SetDefault("audio.opus.bitrate", 0)
+	viper.SetDefault("audio.opus.complexity", 10)
+	viper.SetDefault("audio.opus.inband_fec", false)
+	viper.SetDefault("audio.opus.dtx", false)
+	viper.SetDefault("voice.enabled", true)
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "text")
+	viper.SetDefault("logging.buffer_size", 500)
+	viper.SetDefault("logging.redaction", "none")
+	viper.SetDefault("logging.file", "")
+	viper.SetDefault("logging.output", "")
+	viper.SetDefault("logging.rotation.max_size", 10)
+	viper.SetDefault("logging.rotation.max_backups", 5)
+	viper.SetDefault("logging.rotation.max_age", "0s")
+	viper.SetDefault("debug.include_raw_pdu", false)
+	viper.SetDefault("debug.raw_pdu_embed", false)
+	viper.SetDefault("health.state_file", "golte-health.json")
+	viper.SetDefault("health.interval", "10s")
+	viper.SetDefault("storage.driver", "sqlite")
+	viper.SetDefault("storage.path", "")
+	viper.SetDefault("storage.encryption_key", "")
+	viper.SetDefault("storage.encryption_key_file", "")
+	viper.SetDefault("security.dtmf_pin", "")
+	viper.SetDefault("security.dtmf_pin_hash", "")
+	viper.SetDefault("security.pin_hash_algorithm", PinHashBcrypt)
+	viper.SetDefault("security.max_pin_attempts", 0)
+	viper.SetDefault("security.lockout_duration", "0s")
+	viper.SetDefault("security.admin_user_ids", []string{})
+	viper.SetDefault("security.sms_sender_allowlist", []string{})
+	viper.SetDefault("api.listen_addr", "")
+	viper.SetDefault("api.token", "")
+	viper.SetDefault("api.token_file", "")
+	viper.SetDefault("mqtt.broker", "")
+	viper.SetDefault("mqtt.client_id", "golte")
+	viper.SetDefault("mqtt.username", "")
+	viper.SetDefault("mqtt.password", "")
+	viper.SetDefault("mqtt.password_file", "")
+	viper.SetDefault("mqtt.topic_prefix", "golte")
+	viper.SetDefault("mqtt.keepalive", "30s")
+	viper.SetDefault("mqtt.commands", []string{})
+	viper.SetDefault("telegram.token", "")
+	viper.SetDefault("telegram.token_file", "")
+	viper.SetDefault("telegram.chat_id", 0)
+	viper.SetDefault("telegram.api_url", "")
+	viper.SetDefault("telegram.allowed_chat_ids", []int64{})
+	viper.SetDefault("webhooks", []WebhookConfig{})
 
-	// Read config file
-	viper.SetConfigName("config")
+	// Read config file, setting the name would drop a file chosen with
+	// --config
 	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("$HOME/.golte")
-	viper.AddConfigPath("/etc/golte")
+	if viper.ConfigFileUsed() == "" {
+		viper.SetConfigName("config")
+		viper.AddConfigPath(".")
+		viper.AddConfigPath("$HOME/.golte")
+		viper.AddConfigPath("/etc/golte")
+	}
 
-	// Allow environment variables
-	viper.AutomaticEnv()
-	viper.SetEnvPrefix("GOLTE")
+	// Allow environment variables, e.g. GOLTE_MODEM_DEVICE for modem.device
+	slog.Debug("Settings are taken from flags, then environment variables, then the config file, then defaults")
+	if err := bindEnv(); err != nil {
+		return nil, err
+	}
 
 	// Read the config file
 	if err := viper.ReadInConfig(); err != nil {
@@ -69,30 +518,32 @@ func LoadConfig() (*Config, error) {
 	} else {
 		slog.Info("Using config file", slog.String("file", viper.ConfigFileUsed()))
 	}
-
-	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
+	if err := applyProfile(); err != nil {
 		return nil, err
 	}
-
-	return &config, nil
-}
-
-// ValidateConfig validates the configuration
-func (c *Config) Validate() error {
-	if c.Discord.Token == "" {
-		return &ConfigError{Field: "discord.token", Message: "Discord token is required"}
+	if unknown := unknownKeys(viper.AllSettings()); len(unknown) > 0 {
+		err := &UnknownKeysError{Keys: unknown}
+		if !allowUnknownKeys.Load() {
+			return nil, err
+		}
+		slog.Warn("Ignoring unknown settings in the config file", slog.String("keys", err.list()))
 	}
-	if c.Discord.ChannelID == "" {
-		return &ConfigError{Field: "discord.channel_id", Message: "Discord channel ID is required"}
+	if strings.EqualFold(strings.TrimSpace(viper.GetString("modem.baud")), "auto") {
+		viper.Set("modem.baud", BaudAuto)
 	}
-	if c.Discord.GuildID == "" {
-		return &ConfigError{Field: "discord.guild_id", Message: "Discord guild ID is required"}
+
+	var config Config
+	if err := viper.Unmarshal(&config, viper.DecodeHook(decodeHook)); err != nil {
+		return nil, err
+	}
+	if err := config.resolveSecrets(); err != nil {
+		return nil, err
 	}
-	if c.Discord.VoiceChannelID == "" {
-		return &ConfigError{Field: "discord.voice_channel_id", Message: "Discord voice channel ID is required"}
+	if err := config.Security.migratePIN(); err != nil {
+		return nil, err
 	}
-	return nil
+
+	return &config, nil
 }
 
 // ConfigError represents a configuration validation error
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const discordYAML = `
+discord:
+  token: "bot-token"
+  channel_id: "123456789012345678"
+  guild_id: "123456789012345677"
+  voice_channel_id: "123456789012345679"
+  owner_ids: ["123456789012345680"]
+  mentions:
+    "+33612345678": "role:123456789012345681"
+  number_channels:
+    "+33612345678": "123456789012345682"
+modem:
+  device: "/dev/null"
+`
+
+func loadYAML(t *testing.T, yaml string) *Config {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigFile(path)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() = %v", err)
+	}
+	return cfg
+}
+
+func TestLoadConfigDiscord(t *testing.T) {
+	cfg := loadYAML(t, discordYAML)
+
+	d := cfg.Discord
+	if d.Token != "bot-token" || !slices.Equal(d.ChannelIDs, []string{"123456789012345678"}) ||
+		d.GuildID != "123456789012345677" || d.VoiceChannelID != "123456789012345679" {
+		t.Errorf("Discord IDs not loaded: %+v", d)
+	}
+	if !slices.Equal(d.OwnerIDs, []string{"123456789012345680"}) {
+		t.Errorf("owner_ids = %q", d.OwnerIDs)
+	}
+	if d.Mentions["+33612345678"] != "role:123456789012345681" {
+		t.Errorf("mentions = %v", d.Mentions)
+	}
+	if d.NumberChannels["+33612345678"] != "123456789012345682" {
+		t.Errorf("number_channels = %v", d.NumberChannels)
+	}
+
+	// Validate wants ffmpeg to exist, any file will do
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Audio.FFmpegPath = executable
+	if fields := fieldsOf(cfg.Validate()); slices.Contains(fields, "discord.channel_id") {
+		t.Errorf("Validate() rejects the channel list: %q", fields)
+	}
+}
+
+func TestLoadConfigDiscordMissingIDs(t *testing.T) {
+	cfg := loadYAML(t, "discord:\n  token: \"bot-token\"\n  guild_id: \"my-server\"\n")
+
+	if cfg.Discord.VoiceChannelID != "" {
+		t.Errorf("voice_channel_id = %q, want empty", cfg.Discord.VoiceChannelID)
+	}
+	errs := fieldsOf(cfg.Validate())
+	for _, field := range []string{"discord.channel_id", "discord.guild_id", "discord.voice_channel_id"} {
+		if !slices.Contains(errs, field) {
+			t.Errorf("Validate() doesn't report %s, got %q", field, errs)
+		}
+	}
+}
+
+// fieldsOf returns the fields reported by a Validate error
+func fieldsOf(err error) []string {
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+	fields := make([]string, 0, len(errs))
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	return fields
+}
+
+func TestLoadConfigChannelList(t *testing.T) {
+	cfg := loadYAML(t, strings.Replace(discordYAML, `channel_id: "123456789012345678"`, `channel_id: ["123456789012345678", "123456789012345690"]`, 1))
+	if want := []string{"123456789012345678", "123456789012345690"}; !slices.Equal(cfg.Discord.ChannelIDs, want) {
+		t.Errorf("ChannelIDs = %q, want %q", cfg.Discord.ChannelIDs, want)
+	}
+	if fields := fieldsOf(cfg.Validate()); slices.Contains(fields, "discord.channel_id") {
+		t.Errorf("Validate() rejects the channel list: %q", fields)
+	}
+}
+
+func TestLoadConfigBaudAuto(t *testing.T) {
+	cfg := loadYAML(t, "modem:\n  device: /dev/null\n  baud: auto\n")
+	if cfg.Modem.Baud != BaudAuto {
+		t.Errorf("baud = %d, want BaudAuto", cfg.Modem.Baud)
+	}
+	if fields := fieldsOf(cfg.ValidateModem()); slices.Contains(fields, "modem.baud") {
+		t.Errorf("ValidateModem() rejects auto: %q", fields)
+	}
+
+	cfg = loadYAML(t, "modem:\n  device: /dev/null\n  baud: 9600\n")
+	if cfg.Modem.Baud != 9600 {
+		t.Errorf("baud = %d, want 9600", cfg.Modem.Baud)
+	}
+}
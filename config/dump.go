@@ -0,0 +1,172 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Where the value of a setting comes from
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceProfile = "profile"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Redacted replaces secret values in dumps
+const Redacted = "[redacted]"
+
+// secretKey matches the keys whose values are secrets, not files holding
+// them. The webhooks list holds their secrets and often tokens in URLs.
+var secretKey = regexp.MustCompile(`(^|_)(token|pin|webhooks?|password|secret|key)(_|$)`)
+
+var (
+	flagsMu sync.Mutex
+	flags   = make(map[string]*pflag.Flag)
+)
+
+// BindFlag binds a command line flag to a key like viper.BindPFlag, and
+// remembers it to tell where settings come from
+func BindFlag(key string, flag *pflag.Flag) error {
+	flagsMu.Lock()
+	flags[key] = flag
+	flagsMu.Unlock()
+	return viper.BindPFlag(key, flag)
+}
+
+// Source returns where the value of a setting comes from, in viper's order
+// of precedence
+func Source(key string) string {
+	flagsMu.Lock()
+	flag := flags[key]
+	flagsMu.Unlock()
+
+	switch {
+	case flag != nil && flag.Changed:
+		return SourceFlag
+	case os.Getenv(EnvVar(key)) != "":
+		return SourceEnv
+	case fromProfile(key):
+		return SourceProfile
+	case viper.InConfig(key):
+		return SourceFile
+	default:
+		return SourceDefault
+	}
+}
+
+// IsSecret reports whether the value of the setting key is a secret
+func IsSecret(key string) bool {
+	last := key[strings.LastIndexByte(key, '.')+1:]
+	return secretKey.MatchString(last) && !strings.HasSuffix(last, "_file")
+}
+
+// Setting is the effective value of a configuration key
+type Setting struct {
+	Key    string `json:"key"` // dotted, e.g. modem.device
+	Value  any    `json:"value"`
+	Source string `json:"source"`
+}
+
+// Settings returns every setting of cfg in the order of the config file.
+// Secrets are redacted unless showSecrets is set.
+func Settings(cfg *Config, showSecrets bool) []Setting {
+	var settings []Setting
+	walkSettings("", reflect.ValueOf(*cfg), func(key string, v reflect.Value) {
+		settings = append(settings, Setting{Key: key, Value: settingValue(key, v, showSecrets), Source: Source(key)})
+	})
+	return settings
+}
+
+// DumpYAML returns cfg as a YAML document, with the source of each setting
+// as a comment. Secrets are redacted unless showSecrets is set.
+func DumpYAML(cfg *Config, showSecrets bool) ([]byte, error) {
+	root, err := yamlNode("", reflect.ValueOf(*cfg), showSecrets)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), encoder.Close()
+}
+
+// walkSettings calls fn with every leaf setting under v, structs are
+// sections
+func walkSettings(prefix string, v reflect.Value, fn func(key string, v reflect.Value)) {
+	for i := range v.NumField() {
+		key := settingKey(prefix, v.Type().Field(i))
+		if field := v.Field(i); field.Kind() == reflect.Struct {
+			walkSettings(key, field, fn)
+		} else {
+			fn(key, field)
+		}
+	}
+}
+
+func yamlNode(prefix string, v reflect.Value, showSecrets bool) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i := range v.NumField() {
+		key := settingKey(prefix, v.Type().Field(i))
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: key[strings.LastIndexByte(key, '.')+1:]}
+
+		var (
+			value *yaml.Node
+			err   error
+		)
+		if field := v.Field(i); field.Kind() == reflect.Struct {
+			value, err = yamlNode(key, field, showSecrets)
+		} else {
+			value = &yaml.Node{}
+			err = value.Encode(settingValue(key, field, showSecrets))
+			// Comments on lists and maps would land after their last item
+			if value.Kind == yaml.ScalarNode || len(value.Content) == 0 {
+				value.LineComment = Source(key)
+			} else {
+				keyNode.LineComment = Source(key)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, keyNode, value)
+	}
+	return node, nil
+}
+
+// settingKey returns the dotted key of a field, as Diff names it
+func settingKey(prefix string, field reflect.StructField) string {
+	key := field.Tag.Get("mapstructure")
+	if key == "" {
+		key = strings.ToLower(field.Name)
+	}
+	if prefix != "" {
+		key = prefix + "." + key
+	}
+	return key
+}
+
+// settingValue returns the value of a setting as written in the config file
+func settingValue(key string, v reflect.Value, showSecrets bool) any {
+	if !showSecrets && IsSecret(key) && !v.IsZero() {
+		return Redacted
+	}
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	return v.Interface()
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDumpYAML(t *testing.T) {
+	cfg := loadYAML(t, strings.Replace(discordYAML, `device: "/dev/null"`, `device: "/dev/null"
+  smpp:
+    password: hunter2`, 1))
+
+	out, err := DumpYAML(cfg, false)
+	if err != nil {
+		t.Fatalf("DumpYAML() = %v", err)
+	}
+	dump := string(out)
+	for _, want := range []string{
+		"device: /dev/null # file",
+		"baud: 115200 # default",
+		"timeout: 20s # default",
+		"token: '" + Redacted + "' # file",
+		"password: '" + Redacted + "' # file",
+		"voice_channel_id: \"123456789012345679\" # file",
+		"token_file: \"\" # default",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("DumpYAML() is missing %q:\n%s", want, dump)
+		}
+	}
+	if strings.Contains(dump, "bot-token") || strings.Contains(dump, "hunter2") {
+		t.Errorf("DumpYAML() leaks a secret:\n%s", dump)
+	}
+
+	out, err = DumpYAML(cfg, true)
+	if err != nil {
+		t.Fatalf("DumpYAML(showSecrets) = %v", err)
+	}
+	if !strings.Contains(string(out), "token: bot-token") {
+		t.Errorf("DumpYAML(showSecrets) hides the token:\n%s", out)
+	}
+}
+
+func TestSettingsSource(t *testing.T) {
+	t.Setenv("GOLTE_LOGGING_LEVEL", "debug")
+	cfg := loadYAML(t, discordYAML)
+
+	sources := make(map[string]string)
+	for _, s := range Settings(cfg, false) {
+		sources[s.Key] = s.Source
+	}
+	for key, want := range map[string]string{
+		"discord.channel_id": SourceFile,
+		"modem.baud":         SourceDefault,
+		"logging.level":      SourceEnv,
+	} {
+		if sources[key] != want {
+			t.Errorf("source of %s = %q, want %q", key, sources[key], want)
+		}
+	}
+}
+
+func TestIsSecret(t *testing.T) {
+	for key, want := range map[string]bool{
+		"discord.token":               true,
+		"discord.token_file":          false,
+		"modem.smpp.password":         true,
+		"modem.sim_pin":               true,
+		"modem.device":                false,
+		"discord.owner_ids":           false,
+		"alerts.webhook_url":          true,
+		"webhooks":                    true,
+		"logging.format":              false,
+		"storage.encryption_key":      true,
+		"storage.encryption_key_file": false,
+		"call.keypress_feedback":      false,
+	} {
+		if got := IsSecret(key); got != want {
+			t.Errorf("IsSecret(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
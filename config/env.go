@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix starts the environment variables settings are read from
+const EnvPrefix = "GOLTE"
+
+// envKeyReplacer turns a dotted key into the rest of its variable name
+var envKeyReplacer = strings.NewReplacer(".", "_")
+
+// EnvVar returns the environment variable viper reads key from, e.g.
+// GOLTE_MODEM_DEVICE for modem.device
+func EnvVar(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
+// Keys returns the dotted key of every setting, in the order of the config
+// file
+func Keys() []string {
+	var keys []string
+	walkSettings("", reflect.ValueOf(Config{}), func(key string, _ reflect.Value) {
+		keys = append(keys, key)
+	})
+	return keys
+}
+
+// bindEnv makes every setting readable from its environment variable.
+// AutomaticEnv alone only finds the keys viper already knows, those without
+// a default would be missed.
+func bindEnv() error {
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(envKeyReplacer)
+	viper.AutomaticEnv()
+
+	for _, key := range Keys() {
+		if err := viper.BindEnv(key); err != nil {
+			return err
+		}
+		if _, ok := os.LookupEnv(EnvVar(key)); ok {
+			slog.Debug("Setting read from the environment", slog.String("key", key), slog.String("variable", EnvVar(key)))
+		}
+	}
+	return nil
+}
+
+// decodeHook is viper's default hook, with lists and maps also accepted as
+// JSON so environment variables can set them, e.g.
+// GOLTE_DISCORD_MENTIONS='{"+33612345678": "role:123"}'
+var decodeHook = mapstructure.ComposeDecodeHookFunc(
+	jsonHook,
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+)
+
+// jsonHook decodes a string holding a JSON array or object into a slice or
+// map, other strings are left to the next hooks
+func jsonHook(from, to reflect.Type, data any) (any, error) {
+	s, ok := data.(string)
+	if !ok || from.Kind() != reflect.String || (to.Kind() != reflect.Slice && to.Kind() != reflect.Map) {
+		return data, nil
+	}
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "[") && !strings.HasPrefix(trimmed, "{") {
+		return data, nil
+	}
+
+	v := reflect.New(to)
+	if err := json.Unmarshal([]byte(trimmed), v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
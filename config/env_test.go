@@ -0,0 +1,68 @@
+package config
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestEnvVar(t *testing.T) {
+	if got := EnvVar("modem.smpp.source_addr"); got != "GOLTE_MODEM_SMPP_SOURCE_ADDR" {
+		t.Errorf("EnvVar() = %q", got)
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	for name, value := range map[string]string{
+		"GOLTE_MODEM_DEVICE":                 "/dev/ttyUSB3",
+		"GOLTE_MODEM_TIMEOUT":                "45s",
+		"GOLTE_MODEM_SMPP_ADDR":              "smsc:2775",
+		"GOLTE_DISCORD_TOKEN":                "env-token",
+		"GOLTE_DISCORD_CHANNEL_ID":           "1,2",
+		"GOLTE_DISCORD_OWNER_IDS":            `["3", "4"]`,
+		"GOLTE_DISCORD_MENTIONS":             `{"+33612345678": "role:5"}`,
+		"GOLTE_CALL_MAX_DURATION":            "15m",
+		"GOLTE_BROADCAST_GROUPS":             `{"oncall": ["+33600000001", "+33600000002"]}`,
+		"GOLTE_AUDIO_OPUS_BITRATE":           "32000",
+		"GOLTE_AUDIO_VAD_ENABLED":            "true",
+		"GOLTE_VOICE_TRANSMIT_USERS":         "6",
+		"GOLTE_LOGGING_LEVEL":                "debug",
+		"GOLTE_DEBUG_INCLUDE_RAW_PDU":        "true",
+		"GOLTE_AUDIO_CAPTURE_UNDERRUN_GRACE": "250ms",
+	} {
+		t.Setenv(name, value)
+	}
+	cfg := loadYAML(t, discordYAML)
+
+	if cfg.Modem.Device != "/dev/ttyUSB3" || cfg.Modem.Timeout != 45*time.Second || cfg.Modem.SMPP.Addr != "smsc:2775" {
+		t.Errorf("modem = %+v", cfg.Modem)
+	}
+	if cfg.Discord.Token != "env-token" {
+		t.Errorf("discord.token = %q", cfg.Discord.Token)
+	}
+	if !slices.Equal(cfg.Discord.ChannelIDs, []string{"1", "2"}) {
+		t.Errorf("discord.channel_id = %q", cfg.Discord.ChannelIDs)
+	}
+	if !slices.Equal(cfg.Discord.OwnerIDs, []string{"3", "4"}) {
+		t.Errorf("discord.owner_ids = %q", cfg.Discord.OwnerIDs)
+	}
+	if !maps.Equal(cfg.Discord.Mentions, map[string]string{"+33612345678": "role:5"}) {
+		t.Errorf("discord.mentions = %q", cfg.Discord.Mentions)
+	}
+	if cfg.Call.MaxDuration != 15*time.Minute {
+		t.Errorf("call.max_duration = %s", cfg.Call.MaxDuration)
+	}
+	if got := cfg.Broadcast.Groups["oncall"]; !slices.Equal(got, []string{"+33600000001", "+33600000002"}) {
+		t.Errorf("broadcast.groups = %q", cfg.Broadcast.Groups)
+	}
+	if cfg.Audio.Opus.Bitrate != 32000 || !cfg.Audio.VAD.Enabled || cfg.Audio.Capture.UnderrunGrace != 250*time.Millisecond {
+		t.Errorf("audio = %+v", cfg.Audio)
+	}
+	if !slices.Equal(cfg.Voice.TransmitUsers, []string{"6"}) {
+		t.Errorf("voice.transmit_users = %q", cfg.Voice.TransmitUsers)
+	}
+	if cfg.Logging.Level != "debug" || !cfg.Debug.IncludeRawPDU {
+		t.Errorf("logging = %+v, debug = %+v", cfg.Logging, cfg.Debug)
+	}
+}
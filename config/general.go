@@ -0,0 +1,47 @@
+package config
+
+import (
+	"sync"
+	"time"
+
+	// Timezones load on systems without zoneinfo, like the Docker image
+	_ "time/tzdata"
+)
+
+// GeneralConfig holds the settings that aren't about one part of the bridge
+type GeneralConfig struct {
+	// Timezone is the IANA name, e.g. Europe/Paris, of the times shown to
+	// people. Empty is the system's.
+	Timezone string `mapstructure:"timezone"`
+}
+
+// locations caches the timezones loaded by name, Location is called for
+// every time shown
+var locations sync.Map
+
+// Location returns the timezone times are shown in, the system's if
+// general.timezone is empty or invalid
+func (g GeneralConfig) Location() *time.Location {
+	if g.Timezone == "" {
+		return time.Local
+	}
+	if loc, ok := locations.Load(g.Timezone); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(g.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	locations.Store(g.Timezone, loc)
+	return loc
+}
+
+// validate checks general.timezone names a timezone
+func (g *GeneralConfig) validate(errs *ValidationErrors) {
+	if g.Timezone == "" {
+		return
+	}
+	if _, err := time.LoadLocation(g.Timezone); err != nil {
+		errs.add("general.timezone", "%q is not an IANA timezone like Europe/Paris", g.Timezone)
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGeneralLocation(t *testing.T) {
+	if loc := (GeneralConfig{}).Location(); loc != time.Local {
+		t.Errorf("Location() without a timezone = %v, want Local", loc)
+	}
+
+	paris := GeneralConfig{Timezone: "Europe/Paris"}
+	loc := paris.Location()
+	if loc.String() != "Europe/Paris" {
+		t.Fatalf("Location() = %v, want Europe/Paris", loc)
+	}
+	if paris.Location() != loc {
+		t.Error("Location() loaded the timezone again")
+	}
+	summer := time.Date(2026, 7, 1, 10, 0, 0, 0, time.UTC)
+	if got := summer.In(loc).Hour(); got != 12 {
+		t.Errorf("10:00 UTC in Paris = %d:00, want 12:00", got)
+	}
+
+	var errs ValidationErrors
+	(&GeneralConfig{Timezone: "Mars/Olympus_Mons"}).validate(&errs)
+	if len(errs) != 1 || errs[0].Field != "general.timezone" {
+		t.Errorf("validate() = %v, want a general.timezone error", errs)
+	}
+	if got := (GeneralConfig{Timezone: "Mars/Olympus_Mons"}).Location(); got != time.Local {
+		t.Errorf("Location() of an invalid timezone = %v, want Local", got)
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoggingTargets(t *testing.T) {
+	tests := []struct {
+		logging LoggingConfig
+		stdout  bool
+		file    string
+	}{
+		{LoggingConfig{}, true, ""},
+		{LoggingConfig{File: "golte.log"}, false, "golte.log"},
+		{LoggingConfig{File: "golte.log", Output: LogOutputStdout}, true, ""},
+		{LoggingConfig{File: "golte.log", Output: LogOutputBoth}, true, "golte.log"},
+		{LoggingConfig{File: "golte.log", Output: LogOutputFile}, false, "golte.log"},
+	}
+	for _, tt := range tests {
+		stdout, file := tt.logging.Targets()
+		if stdout != tt.stdout || file != tt.file {
+			t.Errorf("%+v.Targets() = %v, %q, want %v, %q", tt.logging, stdout, file, tt.stdout, tt.file)
+		}
+	}
+}
+
+func TestLoggingValidate(t *testing.T) {
+	tests := []struct {
+		logging LoggingConfig
+		field   string
+	}{
+		{LoggingConfig{Output: LogOutputBoth, File: "golte.log"}, ""},
+		{LoggingConfig{Output: LogOutputFile}, "logging.file"},
+		{LoggingConfig{Output: "syslog"}, "logging.output"},
+		{LoggingConfig{Rotation: LogRotationConfig{MaxBackups: -1}}, "logging.rotation"},
+	}
+	for _, tt := range tests {
+		// Only the logging fields matter, the rest of the config is empty
+		var logging []string
+		for _, field := range fieldsOf((&Config{Logging: tt.logging}).Validate()) {
+			if strings.HasPrefix(field, "logging.") {
+				logging = append(logging, field)
+			}
+		}
+		if want := []string{tt.field}; tt.field == "" && len(logging) != 0 || tt.field != "" && !slices.Equal(logging, want) {
+			t.Errorf("Validate(%+v) logging fields = %q, want %q", tt.logging, logging, tt.field)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"log/slog"
+	"time"
+)
+
+// MonitorConfig controls the signal quality monitor of GSM modems
+type MonitorConfig struct {
+	// SignalInterval is how often the signal is polled, 0 disables the
+	// monitor and with it re-registration
+	SignalInterval time.Duration `mapstructure:"signal_interval"`
+
+	LogLevel string `mapstructure:"log_level"` // debug or info, the level each sample is logged at
+
+	// SignalReports has modems that can tell when their signal changes
+	// sampled on those reports instead of every SignalInterval
+	SignalReports bool `mapstructure:"signal_reports"`
+}
+
+// SampleLevel returns the level signal samples are logged at, debug unless
+// log_level is info
+func (m MonitorConfig) SampleLevel() slog.Level {
+	if m.LogLevel == "info" {
+		return slog.LevelInfo
+	}
+	return slog.LevelDebug
+}
+
+// validate checks the interval isn't negative or too short for the modem,
+// and the log level
+func (m *MonitorConfig) validate(errs *ValidationErrors) {
+	if m.SignalInterval < 0 {
+		errs.add("monitor.signal_interval", "must not be negative")
+	} else if m.SignalInterval > 0 && m.SignalInterval < time.Second {
+		errs.add("monitor.signal_interval", "must be at least 1s, or 0 to disable the monitor")
+	}
+	switch m.LogLevel {
+	case "", "debug", "info":
+	default:
+		errs.add("monitor.log_level", "must be debug or info")
+	}
+}
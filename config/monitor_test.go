@@ -0,0 +1,39 @@
+package config
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestMonitorValidate(t *testing.T) {
+	tests := []struct {
+		monitor MonitorConfig
+		field   string
+	}{
+		{MonitorConfig{SignalInterval: time.Minute, LogLevel: "debug"}, ""},
+		{MonitorConfig{}, ""},
+		{MonitorConfig{SignalInterval: -time.Second}, "monitor.signal_interval"},
+		{MonitorConfig{SignalInterval: 100 * time.Millisecond}, "monitor.signal_interval"},
+		{MonitorConfig{LogLevel: "warn"}, "monitor.log_level"},
+	}
+	for _, tt := range tests {
+		var errs ValidationErrors
+		tt.monitor.validate(&errs)
+		switch {
+		case tt.field == "" && len(errs) != 0:
+			t.Errorf("validate(%+v) = %v, want no error", tt.monitor, errs)
+		case tt.field != "" && (len(errs) != 1 || errs[0].Field != tt.field):
+			t.Errorf("validate(%+v) = %v, want a %s error", tt.monitor, errs, tt.field)
+		}
+	}
+}
+
+func TestMonitorSampleLevel(t *testing.T) {
+	if got := (MonitorConfig{}).SampleLevel(); got != slog.LevelDebug {
+		t.Errorf("SampleLevel() = %v, want debug", got)
+	}
+	if got := (MonitorConfig{LogLevel: "info"}).SampleLevel(); got != slog.LevelInfo {
+		t.Errorf("SampleLevel() = %v, want info", got)
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MQTT commands, each subscribed to on <topic_prefix>/cmd/<command>
+const (
+	MQTTCommandSMS    = "sms"
+	MQTTCommandCall   = "call"
+	MQTTCommandHangup = "hangup"
+	MQTTCommandDTMF   = "dtmf"
+)
+
+// MQTTCommands are the commands mqtt.commands can allow
+var MQTTCommands = []string{MQTTCommandSMS, MQTTCommandCall, MQTTCommandHangup, MQTTCommandDTMF}
+
+// MQTTConfig controls the bridge publishing events to, and taking commands
+// from, an MQTT broker
+type MQTTConfig struct {
+	Broker       string        `mapstructure:"broker"` // tcp://host:1883 or ssl://host:8883, empty disables MQTT
+	ClientID     string        `mapstructure:"client_id"`
+	Username     string        `mapstructure:"username"`
+	Password     string        `mapstructure:"password"`
+	PasswordFile string        `mapstructure:"password_file"` // read the password from this file instead
+	TopicPrefix  string        `mapstructure:"topic_prefix"`  // every topic starts with it
+	KeepAlive    time.Duration `mapstructure:"keepalive"`
+
+	// Commands are the command topics acted on, a message on any other is
+	// refused. Empty makes the bridge publish only.
+	Commands []string `mapstructure:"commands"`
+}
+
+// Enabled reports whether the bridge connects to a broker
+func (m MQTTConfig) Enabled() bool {
+	return m.Broker != ""
+}
+
+// Allows reports whether messages on the topic of command are acted on
+func (m MQTTConfig) Allows(command string) bool {
+	return slices.Contains(m.Commands, command)
+}
+
+func (m *MQTTConfig) validate(errs *ValidationErrors) {
+	if !m.Enabled() {
+		return
+	}
+	u, err := url.Parse(m.Broker)
+	switch {
+	case err != nil || u.Host == "":
+		errs.add("mqtt.broker", "must be a URL like tcp://host:1883")
+	case !slices.Contains([]string{"tcp", "mqtt", "ssl", "tls", "mqtts"}, u.Scheme):
+		errs.add("mqtt.broker", "scheme must be tcp or ssl, not %q", u.Scheme)
+	}
+	if m.ClientID == "" {
+		errs.add("mqtt.client_id", "is required with mqtt.broker")
+	}
+	if m.TopicPrefix == "" || strings.ContainsAny(m.TopicPrefix, "+#") || strings.HasSuffix(m.TopicPrefix, "/") {
+		errs.add("mqtt.topic_prefix", "must be a topic without wildcards or a trailing /")
+	}
+	if m.KeepAlive < time.Second || m.KeepAlive > 18*time.Hour {
+		errs.add("mqtt.keepalive", "must be between 1s and 18h")
+	}
+	for _, command := range m.Commands {
+		if !slices.Contains(MQTTCommands, command) {
+			errs.add("mqtt.commands", "unknown command %q, must be one of %s", command, strings.Join(MQTTCommands, ", "))
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMQTTValidate(t *testing.T) {
+	valid := MQTTConfig{Broker: "tcp://localhost:1883", ClientID: "golte", TopicPrefix: "golte", KeepAlive: 30 * time.Second}
+	with := func(change func(*MQTTConfig)) MQTTConfig {
+		m := valid
+		change(&m)
+		return m
+	}
+	tests := []struct {
+		mqtt  MQTTConfig
+		field string
+	}{
+		{MQTTConfig{}, ""},
+		{valid, ""},
+		{with(func(m *MQTTConfig) { m.Commands = []string{"sms", "dtmf"} }), ""},
+		{with(func(m *MQTTConfig) { m.Broker = "localhost:1883" }), "mqtt.broker"},
+		{with(func(m *MQTTConfig) { m.Broker = "ws://localhost" }), "mqtt.broker"},
+		{with(func(m *MQTTConfig) { m.ClientID = "" }), "mqtt.client_id"},
+		{with(func(m *MQTTConfig) { m.TopicPrefix = "home/#" }), "mqtt.topic_prefix"},
+		{with(func(m *MQTTConfig) { m.TopicPrefix = "home/" }), "mqtt.topic_prefix"},
+		{with(func(m *MQTTConfig) { m.KeepAlive = 0 }), "mqtt.keepalive"},
+		{with(func(m *MQTTConfig) { m.Commands = []string{"reboot"} }), "mqtt.commands"},
+	}
+	for _, tt := range tests {
+		var errs ValidationErrors
+		tt.mqtt.validate(&errs)
+		switch {
+		case tt.field == "" && len(errs) != 0:
+			t.Errorf("validate(%+v) = %v, want no error", tt.mqtt, errs)
+		case tt.field != "" && (len(errs) != 1 || errs[0].Field != tt.field):
+			t.Errorf("validate(%+v) = %v, want a %s error", tt.mqtt, errs, tt.field)
+		}
+	}
+}
+
+func TestMQTTReloadKeepsConnection(t *testing.T) {
+	active := &Config{MQTT: MQTTConfig{Broker: "tcp://a", TopicPrefix: "golte"}}
+	next := &Config{MQTT: MQTTConfig{Broker: "tcp://b", TopicPrefix: "home", Commands: []string{"sms"}}}
+
+	merged := Reloadable(active, next)
+	if merged.MQTT.Broker != "tcp://a" || merged.MQTT.TopicPrefix != "golte" || !merged.MQTT.Allows("sms") {
+		t.Errorf("Reloadable() MQTT = %+v", merged.MQTT)
+	}
+	if !RequiresRestart("mqtt.broker") || RequiresRestart("mqtt.commands") {
+		t.Error("mqtt.broker should need a restart, mqtt.commands not")
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	profileMu   sync.Mutex
+	profileKeys = make(map[string]bool)
+)
+
+// applyProfile merges the profile selected with --profile or GOLTE_PROFILE
+// over the config file. Profiles are named sets of settings under profiles:
+// in the file, environment variables and flags still win over them.
+func applyProfile() error {
+	keys := make(map[string]bool)
+	defer func() {
+		profileMu.Lock()
+		profileKeys = keys
+		profileMu.Unlock()
+	}()
+
+	name := strings.ToLower(viper.GetString("profile"))
+	if name == "" {
+		return nil
+	}
+
+	profiles := viper.GetStringMap("profiles")
+	overrides, ok := profiles[name]
+	if !ok {
+		if len(profiles) == 0 {
+			return fmt.Errorf("unknown profile %q, the config file defines none", name)
+		}
+		return fmt.Errorf("unknown profile %q, available: %s", name, strings.Join(slices.Sorted(maps.Keys(profiles)), ", "))
+	}
+	settings, ok := overrides.(map[string]any)
+	if !ok {
+		return fmt.Errorf("profile %q must be a map of settings", name)
+	}
+	if err := viper.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf("failed to apply profile %q: %w", name, err)
+	}
+
+	collectKeys("", settings, keys)
+	slog.Info("Using config profile", slog.String("profile", name), slog.Int("settings", len(keys)))
+	return nil
+}
+
+// collectKeys adds the dotted key of every section and setting of m
+func collectKeys(prefix string, m map[string]any, keys map[string]bool) {
+	for k, v := range m {
+		key := strings.ToLower(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		keys[key] = true
+		if sub, ok := v.(map[string]any); ok {
+			collectKeys(key, sub, keys)
+		}
+	}
+}
+
+// fromProfile reports whether the active profile sets key
+func fromProfile(key string) bool {
+	profileMu.Lock()
+	defer profileMu.Unlock()
+	return profileKeys[key]
+}
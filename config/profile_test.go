@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const profilesYAML = discordYAML + `
+logging:
+  level: "info"
+profiles:
+  staging:
+    modem:
+      device: "/dev/ttyUSB3"
+    discord:
+      channel_id: ["123456789012345690"]
+  production:
+    logging:
+      level: "warn"
+`
+
+func TestLoadConfigProfile(t *testing.T) {
+	t.Setenv("GOLTE_PROFILE", "staging")
+	cfg := loadYAML(t, profilesYAML)
+
+	if cfg.Profile != "staging" || cfg.Modem.Device != "/dev/ttyUSB3" {
+		t.Errorf("profile %q, modem.device = %q", cfg.Profile, cfg.Modem.Device)
+	}
+	if len(cfg.Discord.ChannelIDs) != 1 || cfg.Discord.ChannelIDs[0] != "123456789012345690" {
+		t.Errorf("discord.channel_id = %q", cfg.Discord.ChannelIDs)
+	}
+	// Settings the profile leaves out keep their value from the file
+	if cfg.Discord.GuildID != "123456789012345677" || cfg.Logging.Level != "info" {
+		t.Errorf("guild %q, logging.level %q", cfg.Discord.GuildID, cfg.Logging.Level)
+	}
+	if got := Source("modem.device"); got != SourceProfile {
+		t.Errorf("Source(modem.device) = %q, want %q", got, SourceProfile)
+	}
+	if got := Source("discord.guild_id"); got != SourceFile {
+		t.Errorf("Source(discord.guild_id) = %q, want %q", got, SourceFile)
+	}
+}
+
+func TestLoadConfigUnknownProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(profilesYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigFile(path)
+	t.Setenv("GOLTE_PROFILE", "prod")
+
+	_, err := LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), "available: production, staging") {
+		t.Errorf("LoadConfig() = %v, want the available profiles", err)
+	}
+}
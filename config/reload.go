@@ -0,0 +1,130 @@
+package config
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// restartSettings are the settings only read at startup, a key also covers
+// everything under it. keep puts the active value back in a configuration
+// being reloaded.
+var restartSettings = []struct {
+	key  string
+	keep func(merged, active *Config)
+}{
+	{"modem.type", func(m, a *Config) { m.Modem.Type = a.Modem.Type }},
+	{"modem.device", func(m, a *Config) { m.Modem.Device = a.Modem.Device }},
+	{"modem.baud", func(m, a *Config) { m.Modem.Baud = a.Modem.Baud }},
+	{"modem.timeout", func(m, a *Config) { m.Modem.Timeout = a.Modem.Timeout }},
+	{"modem.smpp", func(m, a *Config) { m.Modem.SMPP = a.Modem.SMPP }},
+	{"modem.mock.listen", func(m, a *Config) { m.Modem.Mock.Listen = a.Modem.Mock.Listen }},
+	{"monitor.signal_reports", func(m, a *Config) { m.Monitor.SignalReports = a.Monitor.SignalReports }},
+	{"discord.token", func(m, a *Config) { m.Discord.Token = a.Discord.Token }},
+	{"discord.token_file", func(m, a *Config) { m.Discord.TokenFile = a.Discord.TokenFile }},
+	{"discord.guild_id", func(m, a *Config) { m.Discord.GuildID = a.Discord.GuildID }},
+	{"discord.voice_channel_id", func(m, a *Config) { m.Discord.VoiceChannelID = a.Discord.VoiceChannelID }},
+	{"audio.ffmpeg_path", func(m, a *Config) { m.Audio.FFmpegPath = a.Audio.FFmpegPath }},
+	{"audio.playback_device", func(m, a *Config) { m.Audio.PlaybackDevice = a.Audio.PlaybackDevice }},
+	{"audio.preload", func(m, a *Config) { m.Audio.Preload = a.Audio.Preload }},
+	{"audio.assets_dir", func(m, a *Config) { m.Audio.AssetsDir = a.Audio.AssetsDir }},
+	{"audio.cache_budget_mb", func(m, a *Config) { m.Audio.CacheBudgetMB = a.Audio.CacheBudgetMB }},
+	{"voice.enabled", func(m, a *Config) { m.Voice.Enabled = a.Voice.Enabled }},
+	{"logging.format", func(m, a *Config) { m.Logging.Format = a.Logging.Format }},
+	{"logging.file", func(m, a *Config) { m.Logging.File = a.Logging.File }},
+	{"logging.output", func(m, a *Config) { m.Logging.Output = a.Logging.Output }},
+	{"logging.rotation", func(m, a *Config) { m.Logging.Rotation = a.Logging.Rotation }},
+	{"health.state_file", func(m, a *Config) { m.Health.StateFile = a.Health.StateFile }},
+	{"storage", func(m, a *Config) { m.Storage = a.Storage }},
+	{"api.listen_addr", func(m, a *Config) { m.API.ListenAddr = a.API.ListenAddr }},
+	{"mqtt.broker", func(m, a *Config) { m.MQTT.Broker = a.MQTT.Broker }},
+	{"mqtt.client_id", func(m, a *Config) { m.MQTT.ClientID = a.MQTT.ClientID }},
+	{"mqtt.username", func(m, a *Config) { m.MQTT.Username = a.MQTT.Username }},
+	{"mqtt.password", func(m, a *Config) { m.MQTT.Password = a.MQTT.Password }},
+	{"mqtt.password_file", func(m, a *Config) { m.MQTT.PasswordFile = a.MQTT.PasswordFile }},
+	{"mqtt.topic_prefix", func(m, a *Config) { m.MQTT.TopicPrefix = a.MQTT.TopicPrefix }},
+	{"mqtt.keepalive", func(m, a *Config) { m.MQTT.KeepAlive = a.MQTT.KeepAlive }},
+	{"telegram.token", func(m, a *Config) { m.Telegram.Token = a.Telegram.Token }},
+	{"telegram.token_file", func(m, a *Config) { m.Telegram.TokenFile = a.Telegram.TokenFile }},
+	{"telegram.api_url", func(m, a *Config) { m.Telegram.APIURL = a.Telegram.APIURL }},
+}
+
+// RequiresRestart reports whether a changed key only takes effect once the
+// bridge restarts
+func RequiresRestart(key string) bool {
+	for _, setting := range restartSettings {
+		if key == setting.key || strings.HasPrefix(key, setting.key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff returns the keys, dotted as in the config file, whose values differ
+// between a and b
+func Diff(a, b *Config) []string {
+	var keys []string
+	diffValue("", reflect.ValueOf(*a), reflect.ValueOf(*b), &keys)
+	return keys
+}
+
+func diffValue(prefix string, a, b reflect.Value, keys *[]string) {
+	if a.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*keys = append(*keys, prefix)
+		}
+		return
+	}
+
+	for i := range a.NumField() {
+		field := a.Type().Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		diffValue(key, a.Field(i), b.Field(i), keys)
+	}
+}
+
+// Reloadable returns next with the settings requiring a restart kept at
+// their values in active, so it can replace active in a running bridge
+func Reloadable(active, next *Config) *Config {
+	merged := *next
+	for _, setting := range restartSettings {
+		setting.keep(&merged, active)
+	}
+
+	// Maps and slices are shared with next, copy them so the caller can't
+	// change the running configuration through it
+	merged.Discord.ChannelIDs = slices.Clone(next.Discord.ChannelIDs)
+	merged.Discord.OwnerIDs = slices.Clone(next.Discord.OwnerIDs)
+	merged.Discord.Mentions = maps.Clone(next.Discord.Mentions)
+	merged.Discord.NumberChannels = maps.Clone(next.Discord.NumberChannels)
+	merged.Discord.CarrierPrefixes = maps.Clone(next.Discord.CarrierPrefixes)
+	merged.Discord.Access.Commands = maps.Clone(next.Discord.Access.Commands)
+	merged.Discord.Access.Users = slices.Clone(next.Discord.Access.Users)
+	merged.Discord.Access.AdminUsers = slices.Clone(next.Discord.Access.AdminUsers)
+	merged.Voice.TransmitUsers = slices.Clone(next.Voice.TransmitUsers)
+	merged.Security.AdminUserIDs = slices.Clone(next.Security.AdminUserIDs)
+	merged.Security.SMSSenderAllowlist = slices.Clone(next.Security.SMSSenderAllowlist)
+	merged.Call.Routes = slices.Clone(next.Call.Routes)
+	for i, route := range merged.Call.Routes {
+		merged.Call.Routes[i].Numbers = slices.Clone(route.Numbers)
+	}
+	merged.Webhooks = slices.Clone(next.Webhooks)
+	for i, w := range merged.Webhooks {
+		merged.Webhooks[i].Events = slices.Clone(w.Events)
+		merged.Webhooks[i].Headers = maps.Clone(w.Headers)
+	}
+	merged.MQTT.Commands = slices.Clone(next.MQTT.Commands)
+	merged.Telegram.AllowedChatIDs = slices.Clone(next.Telegram.AllowedChatIDs)
+	merged.Broadcast.Groups = maps.Clone(next.Broadcast.Groups)
+	for name, numbers := range merged.Broadcast.Groups {
+		merged.Broadcast.Groups[name] = slices.Clone(numbers)
+	}
+	return &merged
+}
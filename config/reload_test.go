@@ -0,0 +1,105 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReloadable(t *testing.T) {
+	active := &Config{
+		Modem:   ModemConfig{Device: "/dev/ttyUSB2", Baud: 115200, Timeout: 20 * time.Second},
+		Discord: DiscordConfig{Token: "old", ChannelIDs: []string{"1"}, Mentions: map[string]string{"+33600000000": "2"}},
+		Logging: LoggingConfig{Level: "info", Format: "text"},
+	}
+	next := &Config{
+		Modem:   ModemConfig{Device: "/dev/ttyUSB3", Baud: 9600, Timeout: 20 * time.Second},
+		Discord: DiscordConfig{Token: "new", ChannelIDs: []string{"1"}, Mentions: map[string]string{"+33600000000": "3"}},
+		Logging: LoggingConfig{Level: "debug", Format: "json"},
+	}
+
+	changed := Diff(active, next)
+	want := []string{"modem.device", "modem.baud", "discord.token", "discord.mentions", "logging.level", "logging.format"}
+	if !slices.Equal(changed, want) {
+		t.Fatalf("Diff() = %q, want %q", changed, want)
+	}
+
+	var restart []string
+	for _, key := range changed {
+		if RequiresRestart(key) {
+			restart = append(restart, key)
+		}
+	}
+	if want := []string{"modem.device", "modem.baud", "discord.token", "logging.format"}; !slices.Equal(restart, want) {
+		t.Errorf("restart keys = %q, want %q", restart, want)
+	}
+
+	merged := Reloadable(active, next)
+	if applied := Diff(active, merged); !slices.Equal(applied, []string{"discord.mentions", "logging.level"}) {
+		t.Errorf("Diff(active, Reloadable()) = %q", applied)
+	}
+
+	next.Discord.Mentions["+33600000000"] = "4"
+	if merged.Discord.Mentions["+33600000000"] != "3" {
+		t.Error("Reloadable() should not share maps with next")
+	}
+}
+
+func TestReloadableKeepsRestartKeys(t *testing.T) {
+	active := &Config{}
+	next := &Config{}
+	fillConfig(reflect.ValueOf(next).Elem())
+
+	changed := Diff(active, next)
+	for _, setting := range restartSettings {
+		if !slices.ContainsFunc(changed, func(key string) bool { return RequiresRestart(key) && strings.HasPrefix(key+".", setting.key+".") }) {
+			t.Errorf("%s isn't a setting", setting.key)
+		}
+	}
+	for _, key := range Diff(active, Reloadable(active, next)) {
+		if RequiresRestart(key) {
+			t.Errorf("Reloadable() changed %s, which requires a restart", key)
+		}
+	}
+}
+
+// fillConfig sets every setting under v to a value other than its zero
+// value
+func fillConfig(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				fillConfig(v.Field(i))
+			}
+		}
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1)
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), 1, 1)
+		fillConfig(s.Index(0))
+		v.Set(s)
+	case reflect.Map:
+		key := reflect.New(v.Type().Key()).Elem()
+		value := reflect.New(v.Type().Elem()).Elem()
+		fillConfig(key)
+		fillConfig(value)
+		m := reflect.MakeMap(v.Type())
+		m.SetMapIndex(key, value)
+		v.Set(m)
+	case reflect.Pointer:
+		p := reflect.New(v.Type().Elem())
+		fillConfig(p.Elem())
+		v.Set(p)
+	}
+}
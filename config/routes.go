@@ -0,0 +1,71 @@
+package config
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// Actions of a call route
+const (
+	CallActionIVR    = "ivr"    // answer and ask for the password, as without routes
+	CallActionVoice  = "voice"  // answer straight into the Discord voice channel
+	CallActionReject = "reject" // hang up without answering
+	CallActionSMS    = "sms"    // hang up without answering and reply with Message
+	CallActionIgnore = "ignore" // only tell Discord, let it ring
+
+	// CallActionAnnounce answers, plays Audio to the end and hangs up, then
+	// texts Message to the caller if set
+	CallActionAnnounce = "announce"
+)
+
+// CallActions are the actions a call route can take
+var CallActions = []string{CallActionIVR, CallActionVoice, CallActionReject, CallActionSMS, CallActionIgnore, CallActionAnnounce}
+
+// Patterns of call route numbers besides numbers and prefixes
+const (
+	CallPatternAny      = "*"
+	CallPatternContacts = "contacts" // callers saved in the SIM phonebook
+	CallPatternUnknown  = "unknown"  // callers not in it, withheld ones included
+	CallPatternWithheld = "withheld" // callers hiding their number
+)
+
+// CallRoute picks what an incoming call does from its caller. Numbers are
+// numbers, prefixes ending with *, or one of the CallPattern constants.
+type CallRoute struct {
+	Numbers []string `mapstructure:"numbers"`
+	Action  string   `mapstructure:"action"`
+	Message string   `mapstructure:"message"` // replied with the sms action, or after the announcement
+	Audio   string   `mapstructure:"audio"`   // MP3 prompt of the announce action, e.g. annonce.mp3
+}
+
+func validateCallRoutes(routes []CallRoute, errs *ValidationErrors) {
+	for i, route := range routes {
+		if !slices.Contains(CallActions, route.Action) {
+			errs.add("call.routes", "route %d: action must be one of %s", i+1, strings.Join(CallActions, ", "))
+		}
+		if route.Action == CallActionSMS && strings.TrimSpace(route.Message) == "" {
+			errs.add("call.routes", "route %d: the sms action needs a message", i+1)
+		}
+		if route.Action == CallActionAnnounce {
+			switch {
+			case route.Audio == "":
+				errs.add("call.routes", "route %d: the announce action needs an audio prompt", i+1)
+			case strings.ContainsAny(route.Audio, `/\`) || !strings.EqualFold(path.Ext(route.Audio), ".mp3"):
+				errs.add("call.routes", "route %d: audio must be the name of an MP3 prompt, e.g. annonce.mp3, got %q", i+1, route.Audio)
+			}
+		}
+		if len(route.Numbers) == 0 {
+			errs.add("call.routes", "route %d: no numbers, use \"*\" for every caller", i+1)
+		}
+		for _, pattern := range route.Numbers {
+			switch pattern {
+			case CallPatternAny, CallPatternContacts, CallPatternUnknown, CallPatternWithheld:
+				continue
+			}
+			if !strings.ContainsAny(strings.TrimSuffix(pattern, "*"), "0123456789") {
+				errs.add("call.routes", "route %d: %q is not a number, a prefix ending with * or one of *, contacts, unknown and withheld", i+1, pattern)
+			}
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package config
+
+import "testing"
+
+func TestValidateCallRoutes(t *testing.T) {
+	tests := []struct {
+		route CallRoute
+		valid bool
+	}{
+		{CallRoute{Numbers: []string{"+33612345678", "+3361*", "0033 6 12"}, Action: CallActionVoice}, true},
+		{CallRoute{Numbers: []string{"*", "contacts", "unknown", "withheld"}, Action: CallActionIgnore}, true},
+		{CallRoute{Numbers: []string{"unknown"}, Action: CallActionSMS, Message: "Text me"}, true},
+		{CallRoute{Numbers: []string{"unknown"}, Action: CallActionSMS}, false},
+		{CallRoute{Numbers: []string{"*"}, Action: "forward"}, false},
+		{CallRoute{Action: CallActionReject}, false},
+		{CallRoute{Numbers: []string{"friends"}, Action: CallActionReject}, false},
+		{CallRoute{Numbers: []string{"*"}, Action: CallActionAnnounce, Audio: "annonce.mp3", Message: "Text me"}, true},
+		{CallRoute{Numbers: []string{"*"}, Action: CallActionAnnounce, Audio: "Annonce.MP3"}, true},
+		{CallRoute{Numbers: []string{"*"}, Action: CallActionAnnounce}, false},
+		{CallRoute{Numbers: []string{"*"}, Action: CallActionAnnounce, Audio: "../annonce.mp3"}, false},
+		{CallRoute{Numbers: []string{"*"}, Action: CallActionAnnounce, Audio: "annonce.wav"}, false},
+	}
+	for _, tt := range tests {
+		var errs ValidationErrors
+		validateCallRoutes([]CallRoute{tt.route}, &errs)
+		if valid := len(errs) == 0; valid != tt.valid {
+			t.Errorf("validateCallRoutes(%+v) = %v, want valid %v", tt.route, errs, tt.valid)
+		}
+	}
+}
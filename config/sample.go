@@ -0,0 +1,73 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FillSample sets values, by dotted key, in an annotated sample config like
+// config.yaml.example. Only the value is replaced, the comments are kept.
+func FillSample(sample []byte, values map[string]string) ([]byte, error) {
+	var (
+		out     bytes.Buffer
+		path    []string
+		indents []int
+		found   = make(map[string]bool)
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(sample))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimLeft(line, " ")
+		key, rest, ok := strings.Cut(trimmed, ":")
+		if !ok || trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.ContainsAny(key, " \"") {
+			out.WriteString(line + "\n")
+			continue
+		}
+
+		// Leave the sections the line isn't nested in
+		indent := len(line) - len(trimmed)
+		for len(indents) > 0 && indents[len(indents)-1] >= indent {
+			path = path[:len(path)-1]
+			indents = indents[:len(indents)-1]
+		}
+		path = append(path, key)
+		indents = append(indents, indent)
+
+		dotted := strings.Join(path, ".")
+		if value, ok := values[dotted]; ok {
+			line = line[:indent+len(key)+1] + replaceValue(rest, strconv.Quote(value))
+			found[dotted] = true
+		}
+		out.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for key := range values {
+		if !found[key] {
+			return nil, fmt.Errorf("key %s is not in the sample config", key)
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// replaceValue replaces the value at the start of rest, what follows a key's
+// colon, keeping the comment after it
+func replaceValue(rest, value string) string {
+	trimmed := strings.TrimLeft(rest, " ")
+	end := strings.IndexByte(trimmed, ' ')
+	if strings.HasPrefix(trimmed, `"`) {
+		if i := strings.IndexByte(trimmed[1:], '"'); i >= 0 {
+			end = i + 2
+		}
+	}
+	if end < 0 {
+		end = len(trimmed)
+	}
+	return " " + value + trimmed[end:]
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestFillSampleRoundTrip(t *testing.T) {
+	sample, err := os.ReadFile("../config.yaml.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate wants the device and ffmpeg to exist, any file will do
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filled, err := FillSample(sample, map[string]string{
+		"discord.token":            "bot-token",
+		"discord.channel_id":       "1234567890",
+		"discord.guild_id":         "2345678901",
+		"discord.voice_channel_id": "3456789012",
+		"modem.device":             os.DevNull,
+		"audio.ffmpeg_path":        executable,
+	})
+	if err != nil {
+		t.Fatalf("FillSample() = %v", err)
+	}
+	if !strings.Contains(string(filled), `token: "bot-token"                # Discord bot token (required)`) {
+		t.Error("the comment after a filled value should be kept")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, filled, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigFile(path)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() = %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	if cfg.Modem.Device != os.DevNull || cfg.Discord.Token != "bot-token" {
+		t.Errorf("filled values not loaded: device %q, token %q", cfg.Modem.Device, cfg.Discord.Token)
+	}
+	if cfg.Modem.Reregister.Interval.String() != "30m0s" {
+		t.Errorf("modem.reregister.interval = %s, want the sample's 30m", cfg.Modem.Reregister.Interval)
+	}
+}
+
+func TestFillSampleUnknownKey(t *testing.T) {
+	if _, err := FillSample([]byte("modem:\n  device: \"\"\n"), map[string]string{"modem.baud": "9600"}); err == nil {
+		t.Error("FillSample() should fail for a key missing from the sample")
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// resolveSecrets fills in the secrets given as files, see resolveSecret
+func (c *Config) resolveSecrets() error {
+	var err error
+	if c.Discord.Token, err = resolveSecret("discord.token", c.Discord.Token, c.Discord.TokenFile); err != nil {
+		return err
+	}
+	if c.Modem.SMPP.Password, err = resolveSecret("modem.smpp.password", c.Modem.SMPP.Password, c.Modem.SMPP.PasswordFile); err != nil {
+		return err
+	}
+	if c.API.Token, err = resolveSecret("api.token", c.API.Token, c.API.TokenFile); err != nil {
+		return err
+	}
+	if c.Storage.EncryptionKey, err = resolveSecret("storage.encryption_key", c.Storage.EncryptionKey, c.Storage.EncryptionKeyFile); err != nil {
+		return err
+	}
+	if c.Telegram.Token, err = resolveSecret("telegram.token", c.Telegram.Token, c.Telegram.TokenFile); err != nil {
+		return err
+	}
+	if c.MQTT.Password, err = resolveSecret("mqtt.password", c.MQTT.Password, c.MQTT.PasswordFile); err != nil {
+		return err
+	}
+	// Environment variables can't name list entries, only the file is read
+	for i, w := range c.Webhooks {
+		if w.Secret == "" && w.SecretFile != "" {
+			if c.Webhooks[i].Secret, err = readSecretFile(fmt.Sprintf("webhooks[%d].secret_file", i), w.SecretFile); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveSecret returns the value of a secret setting. Environment variables
+// win over the config file, and in each an inline value wins over a file:
+// GOLTE_<KEY>, then the file named by GOLTE_<KEY>_FILE, then the value in the
+// config, then the file named by <key>_file in the config.
+func resolveSecret(key, inline, file string) (string, error) {
+	env := "GOLTE_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	if value := os.Getenv(env); value != "" {
+		return value, nil
+	}
+	if path := os.Getenv(env + "_FILE"); path != "" {
+		return readSecretFile(env+"_FILE", path)
+	}
+	if inline != "" {
+		return inline, nil
+	}
+	if file != "" {
+		return readSecretFile(key+"_file", file)
+	}
+	return "", nil
+}
+
+// readSecretFile reads a secret from a file, trimming the trailing newline
+// editors and echo leave
+func readSecretFile(field, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", &ConfigError{Field: field, Message: fmt.Sprintf("failed to read secret: %v", err)}
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", &ConfigError{Field: field, Message: fmt.Sprintf("secret file %s is empty", path)}
+	}
+	return secret, nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	configFile := write("config-token", "from-config-file\n")
+	envFile := write("env-token", "from-env-file\n")
+
+	tests := []struct {
+		name         string
+		env, envFile string
+		inline, file string
+		want         string
+	}{
+		{"nothing", "", "", "", "", ""},
+		{"config file", "", "", "", configFile, "from-config-file"},
+		{"inline wins over file", "", "", "inline", configFile, "inline"},
+		{"env file wins over config", "", envFile, "inline", configFile, "from-env-file"},
+		{"env wins over env file", "from-env", envFile, "inline", configFile, "from-env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOLTE_DISCORD_TOKEN", tt.env)
+			t.Setenv("GOLTE_DISCORD_TOKEN_FILE", tt.envFile)
+
+			got, err := resolveSecret("discord.token", tt.inline, tt.file)
+			if err != nil || got != tt.want {
+				t.Errorf("resolveSecret() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSecretErrors(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, path := range map[string]string{
+		"missing": filepath.Join(t.TempDir(), "missing"),
+		"empty":   empty,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := resolveSecret("discord.token", "", path)
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) || configErr.Field != "discord.token_file" {
+				t.Errorf("resolveSecret() = %v, want a discord.token_file ConfigError", err)
+			}
+		})
+	}
+}
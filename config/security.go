@@ -0,0 +1,226 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PIN hash algorithms of security.pin_hash_algorithm
+const (
+	PinHashBcrypt   = "bcrypt"
+	PinHashArgon2id = "argon2id"
+)
+
+// Argon2id parameters of new hashes, memory in KiB. Small enough for a
+// Raspberry Pi, a PIN is checked once per call.
+const (
+	argon2Memory  = 19 * 1024
+	argon2Time    = 2
+	argon2Threads = 1
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// PIN lengths accepted by ValidatePIN
+const (
+	MinPINLength = 4
+	MaxPINLength = 16
+)
+
+// SecurityConfig gathers the settings deciding who may do what
+type SecurityConfig struct {
+	// DTMFPin is a plaintext PIN, hashed into DTMFPinHash by LoadConfig.
+	// Prefer putting the output of golte security hash-pin in DTMFPinHash.
+	DTMFPin          string `mapstructure:"dtmf_pin"`
+	DTMFPinHash      string `mapstructure:"dtmf_pin_hash"`
+	PinHashAlgorithm string `mapstructure:"pin_hash_algorithm"` // bcrypt or argon2id, for DTMFPinHash
+
+	// After MaxPinAttempts wrong PINs in a row, PINs are refused for
+	// LockoutDuration. 0 never locks out.
+	MaxPinAttempts  int           `mapstructure:"max_pin_attempts"`
+	LockoutDuration time.Duration `mapstructure:"lockout_duration"`
+
+	AdminUserIDs       []string `mapstructure:"admin_user_ids"`       // Discord users allowed owner commands, along with discord.owner_ids
+	SMSSenderAllowlist []string `mapstructure:"sms_sender_allowlist"` // only SMS from these senders are forwarded, empty for all
+}
+
+// ValidatePIN checks pin is 4 to 16 keys of a phone keypad, without # which
+// ends an entry
+func ValidatePIN(pin string) error {
+	if len(pin) < MinPINLength || len(pin) > MaxPINLength {
+		return fmt.Errorf("must be %d to %d keys long", MinPINLength, MaxPINLength)
+	}
+	if strings.Trim(pin, "0123456789*ABCD") != "" {
+		return fmt.Errorf("must only contain the keys 0-9, * and A-D, # ends the entry")
+	}
+	return nil
+}
+
+// HashPIN returns a salted hash of pin made with algorithm, for
+// security.dtmf_pin_hash
+func HashPIN(pin, algorithm string) (string, error) {
+	if err := ValidatePIN(pin); err != nil {
+		return "", fmt.Errorf("PIN %w", err)
+	}
+
+	switch algorithm {
+	case PinHashBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+		return string(hash), err
+	case PinHashArgon2id:
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(pin), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	default:
+		return "", fmt.Errorf("unknown PIN hash algorithm %q, must be bcrypt or argon2id", algorithm)
+	}
+}
+
+// VerifyPIN reports whether pin is the one hashed by HashPIN into hash
+func VerifyPIN(hash, pin string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		p, err := parseArgon2id(hash)
+		if err != nil {
+			return false
+		}
+		key := argon2.IDKey([]byte(pin), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+		return subtle.ConstantTimeCompare(key, p.key) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pin)) == nil
+}
+
+// hashAlgorithm returns the algorithm of a HashPIN hash, or an empty string
+// if it isn't one
+func hashAlgorithm(hash string) string {
+	if _, err := parseArgon2id(hash); err == nil {
+		return PinHashArgon2id
+	}
+	if _, err := bcrypt.Cost([]byte(hash)); err == nil {
+		return PinHashBcrypt
+	}
+	return ""
+}
+
+type argon2Params struct {
+	memory, time uint32
+	threads      uint8
+	salt, key    []byte
+}
+
+// parseArgon2id parses a hash in the PHC string format, e.g.
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+func parseArgon2id(hash string) (argon2Params, error) {
+	var p argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return p, fmt.Errorf("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(p.key) == 0 {
+		return p, fmt.Errorf("invalid argon2 key")
+	}
+	if p.time == 0 || p.threads == 0 || len(p.salt) == 0 {
+		return p, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+	return p, nil
+}
+
+// migratePIN replaces a plaintext dtmf_pin by its hash, so it's never kept
+// in memory. Invalid PINs and conflicts with dtmf_pin_hash are left for
+// Validate.
+func (s *SecurityConfig) migratePIN() error {
+	if s.DTMFPin == "" || s.DTMFPinHash != "" || ValidatePIN(s.DTMFPin) != nil {
+		return nil
+	}
+
+	hash, err := HashPIN(s.DTMFPin, s.algorithm())
+	if err != nil {
+		return fmt.Errorf("failed to hash security.dtmf_pin: %w", err)
+	}
+	s.DTMFPin, s.DTMFPinHash = "", hash
+	slog.Warn("security.dtmf_pin is in plaintext, replace it with dtmf_pin_hash, e.g. from golte security hash-pin")
+	return nil
+}
+
+// algorithm returns the PIN hash algorithm, bcrypt if not set
+func (s *SecurityConfig) algorithm() string {
+	if s.PinHashAlgorithm == "" {
+		return PinHashBcrypt
+	}
+	return s.PinHashAlgorithm
+}
+
+// validate checks the PIN settings agree with each other, and the access
+// lists
+func (s *SecurityConfig) validate(errs *ValidationErrors) {
+	switch s.algorithm() {
+	case PinHashBcrypt, PinHashArgon2id:
+	default:
+		errs.add("security.pin_hash_algorithm", "must be bcrypt or argon2id")
+	}
+
+	if s.DTMFPin != "" {
+		if s.DTMFPinHash != "" {
+			errs.add("security.dtmf_pin", "can't be set along with dtmf_pin_hash")
+		} else if err := ValidatePIN(s.DTMFPin); err != nil {
+			errs.add("security.dtmf_pin", "%v", err)
+		}
+	}
+	if s.DTMFPinHash != "" {
+		if algorithm := hashAlgorithm(s.DTMFPinHash); algorithm == "" {
+			errs.add("security.dtmf_pin_hash", "is not a bcrypt or argon2id hash, make one with golte security hash-pin")
+		} else if algorithm != s.algorithm() {
+			errs.add("security.dtmf_pin_hash", "is a %s hash but pin_hash_algorithm is %s", algorithm, s.algorithm())
+		}
+	}
+
+	if s.MaxPinAttempts < 0 {
+		errs.add("security.max_pin_attempts", "must not be negative")
+	}
+	if s.MaxPinAttempts > 0 && s.DTMFPinHash == "" && s.DTMFPin == "" {
+		errs.add("security.max_pin_attempts", "needs a PIN, set dtmf_pin_hash")
+	}
+	switch {
+	case s.LockoutDuration < 0:
+		errs.add("security.lockout_duration", "must not be negative")
+	case s.LockoutDuration > 0 && s.MaxPinAttempts == 0:
+		errs.add("security.lockout_duration", "needs max_pin_attempts, it's the number of wrong PINs locking out")
+	case s.LockoutDuration == 0 && s.MaxPinAttempts > 0:
+		errs.add("security.lockout_duration", "must be set when max_pin_attempts is")
+	}
+
+	for _, id := range s.AdminUserIDs {
+		if !isSnowflake(id) {
+			errs.add("security.admin_user_ids", "%q is not a Discord user ID", id)
+		}
+	}
+	for _, sender := range s.SMSSenderAllowlist {
+		if strings.TrimSpace(sender) == "" {
+			errs.add("security.sms_sender_allowlist", "must not contain empty senders")
+		}
+	}
+}
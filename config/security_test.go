@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestHashPIN(t *testing.T) {
+	for _, algorithm := range []string{PinHashBcrypt, PinHashArgon2id} {
+		hash, err := HashPIN("1234*", algorithm)
+		if err != nil {
+			t.Fatalf("HashPIN(%s) = %v", algorithm, err)
+		}
+		if got := hashAlgorithm(hash); got != algorithm {
+			t.Errorf("hashAlgorithm(%q) = %q, want %q", hash, got, algorithm)
+		}
+		if !VerifyPIN(hash, "1234*") {
+			t.Errorf("VerifyPIN(%s) rejected the PIN", algorithm)
+		}
+		if VerifyPIN(hash, "1235*") {
+			t.Errorf("VerifyPIN(%s) accepted a wrong PIN", algorithm)
+		}
+	}
+
+	for _, pin := range []string{"", "123", "12345678901234567", "12a4", "1 34", "123#"} {
+		if _, err := HashPIN(pin, PinHashBcrypt); err == nil {
+			t.Errorf("HashPIN(%q) should fail", pin)
+		}
+	}
+	if _, err := HashPIN("1234", "md5"); err == nil {
+		t.Error("HashPIN() with an unknown algorithm should fail")
+	}
+	if VerifyPIN("$argon2id$v=19$m=1,t=0,p=1$$", "1234") {
+		t.Error("VerifyPIN() accepted an invalid hash")
+	}
+}
+
+func TestMigratePIN(t *testing.T) {
+	s := SecurityConfig{DTMFPin: "2468", PinHashAlgorithm: PinHashArgon2id}
+	if err := s.migratePIN(); err != nil {
+		t.Fatal(err)
+	}
+	if s.DTMFPin != "" || !VerifyPIN(s.DTMFPinHash, "2468") || hashAlgorithm(s.DTMFPinHash) != PinHashArgon2id {
+		t.Errorf("migratePIN() = %+v", s)
+	}
+
+	// Left for Validate to report
+	s = SecurityConfig{DTMFPin: "12"}
+	if err := s.migratePIN(); err != nil || s.DTMFPinHash != "" {
+		t.Errorf("migratePIN() of an invalid PIN = %v, %+v", err, s)
+	}
+}
+
+func TestValidateSecurity(t *testing.T) {
+	hash, err := HashPIN("1234", PinHashBcrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		security SecurityConfig
+		want     []string
+	}{
+		{"none", SecurityConfig{}, nil},
+		{"lockout", SecurityConfig{DTMFPinHash: hash, MaxPinAttempts: 3, LockoutDuration: 5 * time.Minute}, nil},
+		{"lockout without attempts", SecurityConfig{DTMFPinHash: hash, LockoutDuration: time.Minute}, []string{"security.lockout_duration"}},
+		{"attempts without lockout", SecurityConfig{DTMFPinHash: hash, MaxPinAttempts: 3}, []string{"security.lockout_duration"}},
+		{"attempts without PIN", SecurityConfig{MaxPinAttempts: 3, LockoutDuration: time.Minute}, []string{"security.max_pin_attempts"}},
+		{"algorithm mismatch", SecurityConfig{DTMFPinHash: hash, PinHashAlgorithm: PinHashArgon2id}, []string{"security.dtmf_pin_hash"}},
+		{"plaintext hash", SecurityConfig{DTMFPinHash: "1234"}, []string{"security.dtmf_pin_hash"}},
+		{"both PINs", SecurityConfig{DTMFPin: "1234", DTMFPinHash: hash}, []string{"security.dtmf_pin"}},
+		{"unknown algorithm", SecurityConfig{PinHashAlgorithm: "md5"}, []string{"security.pin_hash_algorithm"}},
+		{"lists", SecurityConfig{AdminUserIDs: []string{"123456789012345678", "admin"}, SMSSenderAllowlist: []string{"+33612345678", " "}},
+			[]string{"security.admin_user_ids", "security.sms_sender_allowlist"}},
+	}
+	for _, tt := range tests {
+		var errs ValidationErrors
+		tt.security.validate(&errs)
+
+		var fields []string
+		for _, e := range errs {
+			fields = append(fields, e.Field)
+		}
+		if !slices.Equal(fields, tt.want) {
+			t.Errorf("%s: validate() fields = %q, want %q", tt.name, fields, tt.want)
+		}
+	}
+
+	var errs ValidationErrors
+	if err := (&Config{}).Validate(); errors.As(err, &errs) {
+		for _, e := range errs {
+			if e.Field == "security.pin_hash_algorithm" {
+				t.Errorf("Validate() of an empty algorithm = %v", e)
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"slices"
+	"strings"
+
+	"golte/storage"
+)
+
+// StorageConfig says where the bridge keeps the history of the SMS and
+// calls it saw, runtime state and counters, see the storage package
+type StorageConfig struct {
+	Driver string `mapstructure:"driver"` // one of storage.Drivers()
+	Path   string `mapstructure:"path"`   // file or database of the driver, empty keeps nothing
+
+	EncryptionKey     string `mapstructure:"encryption_key"`      // 64 hex characters encrypting message bodies, numbers and keys, empty stores them in the clear
+	EncryptionKeyFile string `mapstructure:"encryption_key_file"` // read the key from this file instead
+}
+
+// Key returns the encryption key, nil if the store isn't encrypted
+func (s *StorageConfig) Key() ([]byte, error) {
+	if s.EncryptionKey == "" {
+		return nil, nil
+	}
+	return storage.ParseKey(s.EncryptionKey)
+}
+
+// validate checks the encryption key is one, and the driver is built in
+// when a path is set
+func (s *StorageConfig) validate(errs *ValidationErrors) {
+	if _, err := s.Key(); err != nil {
+		errs.add("storage.encryption_key", "%v, e.g. from openssl rand -hex 32", err)
+	}
+	if s.Path == "" {
+		return
+	}
+	if !slices.Contains(storage.Drivers(), s.Driver) {
+		errs.add("storage.driver", "must be %s, %q isn't built in", strings.Join(storage.Drivers(), " or "), s.Driver)
+	}
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStorageValidate(t *testing.T) {
+	tests := []struct {
+		storage StorageConfig
+		field   string
+	}{
+		{StorageConfig{}, ""},
+		{StorageConfig{Driver: "sqlite"}, ""},
+		{StorageConfig{Driver: "sqlite", Path: "golte.db"}, ""},
+		{StorageConfig{Driver: "bolt", Path: "golte.bolt"}, ""},
+		{StorageConfig{Driver: "file", Path: "golte.store"}, ""},
+		{StorageConfig{Path: "golte.db"}, "storage.driver"},
+		{StorageConfig{Driver: "redis", Path: "golte.db"}, "storage.driver"},
+		{StorageConfig{Driver: "sqlite", Path: "golte.db", EncryptionKey: strings.Repeat("42", 32)}, ""},
+		{StorageConfig{Driver: "sqlite", Path: "golte.db", EncryptionKey: "hunter2"}, "storage.encryption_key"},
+	}
+	for _, tt := range tests {
+		var errs ValidationErrors
+		tt.storage.validate(&errs)
+		switch {
+		case tt.field == "" && len(errs) != 0:
+			t.Errorf("validate(%+v) = %v, want no error", tt.storage, errs)
+		case tt.field != "" && (len(errs) != 1 || errs[0].Field != tt.field):
+			t.Errorf("validate(%+v) = %v, want a %s error", tt.storage, errs, tt.field)
+		}
+	}
+}
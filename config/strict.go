@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// allowUnknownKeys makes LoadConfig only warn about unknown settings
+var allowUnknownKeys atomic.Bool
+
+// AllowUnknownKeys makes LoadConfig accept settings it doesn't know, e.g.
+// from a config file written for a newer version, with a warning instead of
+// failing
+func AllowUnknownKeys(allow bool) {
+	allowUnknownKeys.Store(allow)
+}
+
+// UnknownKey is a setting of the config file golte doesn't know, and the
+// known one it's closest to, if any
+type UnknownKey struct {
+	Key        string
+	Suggestion string
+}
+
+func (k UnknownKey) String() string {
+	if k.Suggestion == "" {
+		return k.Key
+	}
+	return fmt.Sprintf("%s (did you mean %s?)", k.Key, k.Suggestion)
+}
+
+// UnknownKeysError is returned by LoadConfig for settings it doesn't know,
+// usually typos that would otherwise silently leave the default in place
+type UnknownKeysError struct {
+	Keys []UnknownKey
+}
+
+func (e *UnknownKeysError) Error() string {
+	return fmt.Sprintf("unknown settings in the config file: %s (--allow-unknown-config ignores them)", e.list())
+}
+
+// list joins the keys with their suggestions
+func (e *UnknownKeysError) list() string {
+	keys := make([]string, len(e.Keys))
+	for i, k := range e.Keys {
+		keys[i] = k.String()
+	}
+	return strings.Join(keys, ", ")
+}
+
+// keyKind is what a dotted key of the schema holds
+type keyKind int
+
+const (
+	keySection keyKind = iota // a struct, its keys are settings
+	keySetting                // a value
+	keyMap                    // a map, any key is allowed under it
+)
+
+// schema returns every section and setting of Config by dotted key
+func schema() map[string]keyKind {
+	keys := make(map[string]keyKind)
+	var walk func(prefix string, t reflect.Type)
+	walk = func(prefix string, t reflect.Type) {
+		for i := range t.NumField() {
+			key := settingKey(prefix, t.Field(i))
+			switch field := t.Field(i).Type; field.Kind() {
+			case reflect.Struct:
+				keys[key] = keySection
+				walk(key, field)
+			case reflect.Map:
+				keys[key] = keyMap
+			default:
+				keys[key] = keySetting
+			}
+		}
+	}
+	walk("", reflect.TypeOf(Config{}))
+	return keys
+}
+
+// unknownKeys returns the keys of settings, nested as viper holds them,
+// that Config doesn't have. Each profile is checked like the file itself.
+func unknownKeys(settings map[string]any) []UnknownKey {
+	known := schema()
+	var unknown []UnknownKey
+
+	// prefix is the key of m in the schema, shown where it is in the file
+	var check func(prefix, shown string, m map[string]any)
+	check = func(prefix, shown string, m map[string]any) {
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			key := joinKey(prefix, k)
+			kind, ok := known[key]
+			switch {
+			case !ok:
+				u := UnknownKey{Key: joinKey(shown, k)}
+				if sibling := closestKey(known, prefix, k); sibling != "" {
+					u.Suggestion = joinKey(shown, sibling)
+				}
+				unknown = append(unknown, u)
+			case kind == keySection:
+				if sub, ok := m[k].(map[string]any); ok {
+					check(key, joinKey(shown, k), sub)
+				}
+			}
+		}
+	}
+
+	rest := maps.Clone(settings)
+	delete(rest, "profiles")
+	check("", "", rest)
+
+	profiles, _ := settings["profiles"].(map[string]any)
+	for _, name := range slices.Sorted(maps.Keys(profiles)) {
+		if overrides, ok := profiles[name].(map[string]any); ok {
+			check("", "profiles."+name, overrides)
+		}
+	}
+	return unknown
+}
+
+// closestKey returns the known key of the section prefix closest to name,
+// if it's close enough to be a typo of it
+func closestKey(known map[string]keyKind, prefix, name string) string {
+	best, bestDistance := "", max(2, len(name)/3)+1
+	for key := range known {
+		parent, sibling := "", key
+		if i := strings.LastIndexByte(key, '.'); i >= 0 {
+			parent, sibling = key[:i], key[i+1:]
+		}
+		if parent != prefix {
+			continue
+		}
+		if d := editDistance(name, sibling); d < bestDistance || (d == bestDistance && sibling < best) {
+			best, bestDistance = sibling, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// loadYAMLError loads yaml like loadYAML, returning the error
+func loadYAMLError(t *testing.T, yaml string) error {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigFile(path)
+
+	_, err := LoadConfig()
+	return err
+}
+
+const misspelledYAML = `
+disord:
+  token: "bot-token"
+modem:
+  device: "/dev/ttyUSB0"
+  bauds: 9600
+  smpp:
+    sytem_id: "golte"
+discord:
+  mentions:
+    "+33612345678": "123"
+profiles:
+  bench:
+    modem:
+      timout: "5s"
+    unrelated: true
+`
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	err := loadYAMLError(t, misspelledYAML)
+	var unknown *UnknownKeysError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("LoadConfig() = %v, want UnknownKeysError", err)
+	}
+
+	want := []UnknownKey{
+		{Key: "disord", Suggestion: "discord"},
+		{Key: "modem.bauds", Suggestion: "modem.baud"},
+		{Key: "modem.smpp.sytem_id", Suggestion: "modem.smpp.system_id"},
+		{Key: "profiles.bench.modem.timout", Suggestion: "profiles.bench.modem.timeout"},
+		{Key: "profiles.bench.unrelated"},
+	}
+	if !slices.Equal(unknown.Keys, want) {
+		t.Errorf("unknown keys = %v, want %v", unknown.Keys, want)
+	}
+}
+
+func TestLoadConfigAllowUnknownKeys(t *testing.T) {
+	AllowUnknownKeys(true)
+	defer AllowUnknownKeys(false)
+
+	if err := loadYAMLError(t, misspelledYAML); err != nil {
+		t.Errorf("LoadConfig() with unknown keys allowed = %v", err)
+	}
+}
+
+func TestExampleConfigHasNoUnknownKeys(t *testing.T) {
+	example, err := os.ReadFile("../config.yaml.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loadYAMLError(t, string(example)); err != nil {
+		t.Errorf("LoadConfig(config.yaml.example) = %v", err)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"discord", "discord", 0},
+		{"disord", "discord", 1},
+		{"bauds", "baud", 1},
+		{"timout", "timeout", 1},
+		{"", "abc", 3},
+		{"modem", "audio", 4},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"regexp"
+	"slices"
+)
+
+// TelegramConfig controls the Telegram bot notifications are posted with,
+// alongside Discord or instead of it
+type TelegramConfig struct {
+	Token     string `mapstructure:"token"`      // bot token from @BotFather, empty disables Telegram
+	TokenFile string `mapstructure:"token_file"` // read the token from this file instead
+	ChatID    int64  `mapstructure:"chat_id"`    // chat the SMS, calls and alerts are posted to
+	APIURL    string `mapstructure:"api_url"`    // Bot API server, empty for Telegram's
+
+	// AllowedChatIDs are the chats whose commands, like /send, are run.
+	// Empty makes the bot post only.
+	AllowedChatIDs []int64 `mapstructure:"allowed_chat_ids"`
+}
+
+// Enabled reports whether notifications are posted to Telegram
+func (t TelegramConfig) Enabled() bool {
+	return t.Token != ""
+}
+
+// Allows reports whether the commands of the chat are run
+func (t TelegramConfig) Allows(chatID int64) bool {
+	return slices.Contains(t.AllowedChatIDs, chatID)
+}
+
+// telegramToken matches the bot tokens of @BotFather, e.g.
+// 123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw
+var telegramToken = regexp.MustCompile(`^[0-9]+:[A-Za-z0-9_-]+$`)
+
+func (t *TelegramConfig) validate(errs *ValidationErrors) {
+	if !t.Enabled() {
+		return
+	}
+	if !telegramToken.MatchString(t.Token) {
+		errs.add("telegram.token", "is not a bot token like 123456789:AAH..., copy it from @BotFather")
+	}
+	if t.ChatID == 0 {
+		errs.add("telegram.chat_id", "is required with telegram.token")
+	}
+	if t.APIURL != "" && !isHTTPURL(t.APIURL) {
+		errs.add("telegram.api_url", "must be an http or https URL")
+	}
+	for _, id := range t.AllowedChatIDs {
+		if id == 0 {
+			errs.add("telegram.allowed_chat_ids", "0 is not a chat ID")
+		}
+	}
+}
+
+// DiscordEnabled reports whether the bridge connects to Discord. It always
+// does unless another notifier is set up and discord.token is left empty.
+func (c *Config) DiscordEnabled() bool {
+	return c.Discord.Token != "" || !c.Telegram.Enabled()
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTelegramValidate(t *testing.T) {
+	valid := TelegramConfig{Token: "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw", ChatID: -1001234567890}
+	with := func(change func(*TelegramConfig)) TelegramConfig {
+		c := valid
+		change(&c)
+		return c
+	}
+	tests := []struct {
+		telegram TelegramConfig
+		field    string
+	}{
+		{TelegramConfig{}, ""},
+		{valid, ""},
+		{with(func(c *TelegramConfig) { c.AllowedChatIDs = []int64{42, -1001234567890} }), ""},
+		{with(func(c *TelegramConfig) { c.APIURL = "http://localhost:8081" }), ""},
+		{with(func(c *TelegramConfig) { c.Token = "not a token" }), "telegram.token"},
+		{with(func(c *TelegramConfig) { c.ChatID = 0 }), "telegram.chat_id"},
+		{with(func(c *TelegramConfig) { c.APIURL = "localhost:8081" }), "telegram.api_url"},
+		{with(func(c *TelegramConfig) { c.AllowedChatIDs = []int64{0} }), "telegram.allowed_chat_ids"},
+	}
+	for _, tt := range tests {
+		var errs ValidationErrors
+		tt.telegram.validate(&errs)
+		switch {
+		case tt.field == "" && len(errs) != 0:
+			t.Errorf("validate(%+v) = %v, want no error", tt.telegram, errs)
+		case tt.field != "" && (len(errs) != 1 || errs[0].Field != tt.field):
+			t.Errorf("validate(%+v) = %v, want a %s error", tt.telegram, errs, tt.field)
+		}
+	}
+}
+
+func TestValidateTelegramWithoutDiscord(t *testing.T) {
+	cfg := &Config{
+		Modem:    ModemConfig{Device: os.DevNull, Baud: 115200, Timeout: 20 * time.Second},
+		Telegram: TelegramConfig{Token: "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw", ChatID: 42},
+	}
+	if err := cfg.Validate(); err != nil || cfg.DiscordEnabled() {
+		t.Errorf("Validate() with Telegram only = %v, Discord enabled %v", err, cfg.DiscordEnabled())
+	}
+
+	// A Discord token brings Discord back, with its settings
+	cfg.Discord.Token = "token"
+	if err := cfg.Validate(); err == nil || !cfg.DiscordEnabled() {
+		t.Errorf("Validate() with a Discord token and no channel = %v, want an error", err)
+	}
+
+	// Without any notifier Discord is required
+	cfg.Discord.Token, cfg.Telegram.Token = "", ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() without Discord nor Telegram succeeded")
+	}
+}
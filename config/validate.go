@@ -0,0 +1,426 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// standardBauds are the serial rates modems are configured with
+var standardBauds = []int{1200, 2400, 4800, 9600, 19200, 38400, 57600, 115200, 230400, 460800, 921600, 3000000}
+
+// Bounds of modem.timeout
+const (
+	minModemTimeout = time.Second
+	maxModemTimeout = 5 * time.Minute
+)
+
+// DiscordInteractionBudget is how long Discord keeps a deferred interaction
+// open for its reply. SMS are sent while the command waits, there is no
+// queue to send them after replying, so a send must fit in it.
+const DiscordInteractionBudget = 15 * time.Minute
+
+// maxCommandRetries caps modem.command_retries, each retry can wait for a
+// full reconnection
+const maxCommandRetries = 5
+
+// Bounds of discord.registration, the startup waits for every attempt
+const (
+	maxRegistrationAttempts = 10
+	maxRegistrationTimeout  = 2 * time.Minute
+)
+
+// maxLogBufferSize caps logging.buffer_size, as logger.MaxRecentSize does
+const maxLogBufferSize = 10000
+
+// ValidationErrors lists every problem Validate found
+type ValidationErrors []*ConfigError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// add records a problem with a key
+func (e *ValidationErrors) add(field, format string, args ...any) {
+	*e = append(*e, &ConfigError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// merge records the problems of a nested Validate
+func (e *ValidationErrors) merge(err error) {
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		*e = append(*e, errs...)
+	}
+}
+
+// err returns the problems as an error, nil if there are none
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ValidateModem checks only the modem settings, for commands that drive the
+// modem without the bridge and so don't need a Discord section
+func (c *Config) ValidateModem() error {
+	var errs ValidationErrors
+	c.Modem.validate(&errs)
+	return errs.err()
+}
+
+// Validate checks the configuration and returns ValidationErrors with every
+// problem found, not only the first one
+func (c *Config) Validate() error {
+	var errs ValidationErrors
+	c.General.validate(&errs)
+	c.Modem.validate(&errs)
+	c.Monitor.validate(&errs)
+	if c.DiscordEnabled() {
+		c.Discord.validate(&errs, c.Voice.Enabled)
+	}
+
+	switch c.Call.KeypressFeedback {
+	case "", "tones", "spoken", "silent":
+	default:
+		errs.add("call.keypress_feedback", "must be one of tones, spoken or silent")
+	}
+	if c.Call.MaxDuration < 0 {
+		errs.add("call.max_duration", "must not be negative")
+	}
+	if c.Call.DTMFTimeout < 0 {
+		errs.add("call.dtmf_timeout", "must not be negative")
+	}
+	switch c.Call.DTMFTimeoutAction {
+	case "", DTMFTimeoutReset, DTMFTimeoutEvaluate:
+	default:
+		errs.add("call.dtmf_timeout_action", "must be reset or evaluate")
+	}
+	validateCallRoutes(c.Call.Routes, &errs)
+	c.Broadcast.validate(&errs)
+
+	// Simulated calls carry no audio, ffmpeg may be missing on a laptop
+	if path := c.Audio.FFmpegPath; path != "" && c.Voice.Enabled && c.Modem.CarriesAudio() {
+		if _, err := exec.LookPath(path); err != nil {
+			errs.add("audio.ffmpeg_path", "%s not found or not executable", path)
+		}
+	}
+	if dir := c.Audio.AssetsDir; dir != "" && c.Voice.Enabled {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			errs.add("audio.assets_dir", "%s is not a directory", dir)
+		}
+	}
+	if c.Audio.DuckDepthDB < 0 || c.Audio.DuckDepthDB > 60 {
+		errs.add("audio.duck_depth_db", "must be between 0 and 60")
+	}
+	errs.merge(c.Audio.Opus.Validate())
+	errs.merge(c.Audio.Capture.Validate())
+	errs.merge(c.Audio.VAD.Validate())
+
+	for _, id := range c.Voice.TransmitUsers {
+		if !isSnowflake(id) {
+			errs.add("voice.transmit_users", "%q is not a Discord user ID", id)
+		}
+	}
+
+	switch strings.ToLower(c.Logging.Level) {
+	case "", "debug", "info", "warn", "warning", "error":
+	default:
+		errs.add("logging.level", "must be one of debug, info, warn or error")
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "", "text", "json":
+	default:
+		errs.add("logging.format", "must be text or json")
+	}
+	switch strings.ToLower(c.Logging.Redaction) {
+	case "", "none", "partial", "full":
+	default:
+		errs.add("logging.redaction", "must be none, partial or full")
+	}
+	switch strings.ToLower(c.Logging.Output) {
+	case "", LogOutputStdout:
+	case LogOutputFile, LogOutputBoth:
+		if c.Logging.File == "" {
+			errs.add("logging.file", "is required when logging.output is %s", c.Logging.Output)
+		}
+	default:
+		errs.add("logging.output", "must be stdout, file or both")
+	}
+	if r := c.Logging.Rotation; r.MaxSize < 0 || r.MaxBackups < 0 || r.MaxAge < 0 {
+		errs.add("logging.rotation", "must not be negative")
+	}
+	if c.Logging.BufferSize < 0 || c.Logging.BufferSize > maxLogBufferSize {
+		errs.add("logging.buffer_size", "must be between 0 and %d", maxLogBufferSize)
+	}
+
+	c.Security.validate(&errs)
+	c.API.validate(&errs)
+	c.MQTT.validate(&errs)
+	c.Telegram.validate(&errs)
+	validateWebhooks(c.Webhooks, &errs)
+	c.Storage.validate(&errs)
+
+	if c.Health.StateFile != "" && c.Health.Interval < time.Second {
+		errs.add("health.interval", "must be at least 1s")
+	}
+
+	return errs.err()
+}
+
+// validate checks the modem settings of the selected transport
+func (m *ModemConfig) validate(errs *ValidationErrors) {
+	switch m.Type {
+	case "", ModemTypeGSM:
+		if m.Device == "" {
+			errs.add("modem.device", "modem device is required")
+		} else if _, err := os.Stat(m.Device); err != nil {
+			errs.add("modem.device", "%s does not exist, is the modem plugged in?", m.Device)
+		}
+		if m.Baud != BaudAuto && !slices.Contains(standardBauds, m.Baud) {
+			errs.add("modem.baud", "%d is not a standard rate, e.g. 9600 or 115200, or auto", m.Baud)
+		}
+	case ModemTypeSMPP:
+		if m.SMPP.Addr == "" {
+			errs.add("modem.smpp.addr", "SMSC address is required for the smpp modem type")
+		}
+		if m.SMPP.SystemID == "" {
+			errs.add("modem.smpp.system_id", "SMPP system ID is required for the smpp modem type")
+		}
+	case ModemTypeMock:
+		if m.Mock.SendDelay < 0 {
+			errs.add("modem.mock.send_delay", "must not be negative")
+		}
+		if m.Mock.Listen != "" {
+			if _, _, err := net.SplitHostPort(m.Mock.Listen); err != nil {
+				errs.add("modem.mock.listen", "must be host:port, e.g. 127.0.0.1:8765")
+			}
+		}
+	default:
+		errs.add("modem.type", "must be gsm, smpp or mock")
+	}
+
+	if m.Timeout < minModemTimeout || m.Timeout > maxModemTimeout {
+		errs.add("modem.timeout", "must be between %s and %s", minModemTimeout, maxModemTimeout)
+	}
+	if m.SMSTimeout < 0 {
+		errs.add("modem.sms_timeout", "must not be negative")
+	} else if budget := m.SMSTimeout * time.Duration(max(m.MaxSMSSegments, 1)); budget > DiscordInteractionBudget {
+		errs.add("modem.sms_timeout", "a message of %d segments could take %s, longer than the %s Discord waits for the reply",
+			max(m.MaxSMSSegments, 1), budget, DiscordInteractionBudget)
+	}
+	if m.QueryTimeout < 0 || m.QueryTimeout > maxModemTimeout {
+		errs.add("modem.query_timeout", "must be between 0 and %s", maxModemTimeout)
+	}
+	if m.CommandRetries < 0 || m.CommandRetries > maxCommandRetries {
+		errs.add("modem.command_retries", "must be between 0 and %d", maxCommandRetries)
+	}
+	if m.MaxSMSSegments < 0 {
+		errs.add("modem.max_sms_segments", "must not be negative, 0 disables the confirmation")
+	}
+
+	switch m.Reregister.Method {
+	case "", ReregisterCOPS, ReregisterCFUN:
+	default:
+		errs.add("modem.reregister.method", "must be cops or cfun")
+	}
+	if m.Reregister.After < 0 || m.Reregister.Interval < 0 {
+		errs.add("modem.reregister", "durations must not be negative")
+	}
+
+	switch m.ClockSync {
+	case "", ClockSyncOff, ClockSyncTimestamps, ClockSyncSystem:
+	default:
+		errs.add("modem.clock_sync", "must be off, timestamps or system")
+	}
+
+	if m.OwnNumber != "" && strings.IndexFunc(m.OwnNumber, unicode.IsDigit) < 0 {
+		errs.add("modem.own_number", "must be a phone number")
+	}
+
+	if m.Trace.Enabled && m.Trace.Path == "" {
+		errs.add("modem.trace.path", "is required to trace the modem")
+	}
+	if m.Trace.MaxSize < 0 || m.Trace.MaxBackups < 0 {
+		errs.add("modem.trace", "sizes must not be negative")
+	}
+}
+
+// validate checks the Discord settings, IDs must be snowflakes. The voice
+// channel is only needed with voice.
+func (d *DiscordConfig) validate(errs *ValidationErrors, voice bool) {
+	if d.Token == "" {
+		errs.add("discord.token", "Discord token is required")
+	}
+	if len(d.ChannelIDs) == 0 {
+		errs.add("discord.channel_id", "is required")
+	}
+	for _, id := range d.ChannelIDs {
+		if !isSnowflake(id) {
+			errs.add("discord.channel_id", "%q is not a Discord ID, copy it with Developer Mode enabled", id)
+		}
+	}
+	for _, id := range []struct {
+		field, value string
+		required     bool
+	}{
+		{"discord.guild_id", d.GuildID, true},
+		{"discord.voice_channel_id", d.VoiceChannelID, voice},
+	} {
+		switch {
+		case id.value == "" && id.required:
+			errs.add(id.field, "is required")
+		case id.value != "" && !isSnowflake(id.value):
+			errs.add(id.field, "%q is not a Discord ID, copy it with Developer Mode enabled", id.value)
+		}
+	}
+
+	for _, id := range d.OwnerIDs {
+		if !isSnowflake(id) {
+			errs.add("discord.owner_ids", "%q is not a Discord user ID", id)
+		}
+	}
+	for number, value := range d.Mentions {
+		id, _ := strings.CutPrefix(strings.TrimSpace(value), "role:")
+		if !isSnowflake(id) {
+			errs.add("discord.mentions", "%q for %s is not a user ID or role:<id>", value, number)
+		}
+	}
+	for number, channelID := range d.NumberChannels {
+		if channelID == "" {
+			errs.add("discord.number_channels", "no channel ID for %s", number)
+		} else if !isSnowflake(channelID) {
+			errs.add("discord.number_channels", "%q for %s is not a channel ID", channelID, number)
+		}
+	}
+
+	switch d.ChannelReplyMode {
+	case "", ChannelReplyEmbed, ChannelReplyAny:
+	default:
+		errs.add("discord.channel_reply_mode", "must be embed or any")
+	}
+
+	for prefix := range d.CarrierPrefixes {
+		digits, ok := strings.CutPrefix(prefix, "+")
+		if !ok || digits == "" || strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+			errs.add("discord.carrier_prefixes", "%q is not an international prefix like +3366", prefix)
+		}
+	}
+
+	for _, u := range []struct{ key, value string }{
+		{"discord.shortener_url", d.ShortenerURL},
+		{"discord.sms_icon", d.SMSIcon},
+		{"discord.call_icon", d.CallIcon},
+	} {
+		if u.value != "" && !isHTTPURL(u.value) {
+			errs.add(u.key, "must be an http or https URL")
+		}
+	}
+	d.Access.validate(errs)
+
+	if d.Registration.Attempts < 0 || d.Registration.Attempts > maxRegistrationAttempts {
+		errs.add("discord.registration.attempts", "must be between 1 and %d", maxRegistrationAttempts)
+	}
+	if t := d.Registration.Timeout; t < 0 || (t > 0 && t < time.Second) || t > maxRegistrationTimeout {
+		errs.add("discord.registration.timeout", "must be between 1s and %s", maxRegistrationTimeout)
+	}
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Validate checks the opus settings are within the ranges libopus accepts
+func (o *OpusConfig) Validate() error {
+	var errs ValidationErrors
+	switch o.Application {
+	case "", "voip", "audio", "lowdelay":
+	default:
+		errs.add("audio.opus.application", "must be one of voip, audio or lowdelay")
+	}
+	if o.Bitrate != 0 && (o.Bitrate < 6000 || o.Bitrate > 510000) {
+		errs.add("audio.opus.bitrate", "must be 0 (auto) or between 6000 and 510000")
+	}
+	if o.Complexity < 0 || o.Complexity > 10 {
+		errs.add("audio.opus.complexity", "must be between 0 and 10")
+	}
+	return errs.err()
+}
+
+// Validate checks the capture settings
+func (c *CaptureConfig) Validate() error {
+	var errs ValidationErrors
+	switch c.Frame {
+	case 0, 10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 60 * time.Millisecond:
+	default:
+		errs.add("audio.capture.frame", "must be one of 10ms, 20ms, 40ms or 60ms")
+	}
+	if c.BufferSize < 0 {
+		errs.add("audio.capture.buffer_size", "must not be negative")
+	}
+	if c.UnderrunGrace < 0 {
+		errs.add("audio.capture.underrun_grace", "must not be negative")
+	}
+	return errs.err()
+}
+
+// Validate checks the VAD settings are usable
+func (c *VADConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var errs ValidationErrors
+	if c.ThresholdDB < -96 || c.ThresholdDB > 0 {
+		errs.add("audio.vad.threshold_db", "must be between -96 and 0")
+	}
+	if c.Hangover < 0 {
+		errs.add("audio.vad.hangover", "must not be negative")
+	}
+	if c.PreRoll < 0 || c.PreRoll > time.Second {
+		errs.add("audio.vad.pre_roll", "must be between 0 and 1s")
+	}
+	return errs.err()
+}
+
+// isSnowflake reports whether s is a Discord ID
+func isSnowflake(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
+
+// validate checks the broadcast limits and that groups hold phone numbers
+func (b *BroadcastConfig) validate(errs *ValidationErrors) {
+	if b.MaxRecipients < 0 || b.MaxRecipients > maxBroadcastRecipients {
+		errs.add("broadcast.max_recipients", "must be between 0 (disabled) and %d", maxBroadcastRecipients)
+	}
+	if b.Interval < 0 {
+		errs.add("broadcast.interval", "must not be negative")
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(b.Groups)) {
+		if name == "" || strings.ContainsAny(name[:1], "+0123456789") {
+			errs.add("broadcast.groups", "group name %q must start with a letter, it would be taken for a number", name)
+		}
+		for _, number := range b.Groups[name] {
+			if strings.IndexFunc(number, unicode.IsDigit) < 0 {
+				errs.add("broadcast.groups", "%q in group %s is not a phone number", number, name)
+			}
+		}
+	}
+}
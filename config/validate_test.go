@@ -0,0 +1,187 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := &Config{
+		Modem: ModemConfig{Device: "/dev/does-not-exist", Baud: 115201, Timeout: time.Hour},
+		Discord: DiscordConfig{
+			Token:          "token",
+			ChannelIDs:     []string{"123456789012345678"},
+			GuildID:        "my-server",
+			VoiceChannelID: "123456789012345679",
+			OwnerIDs:       []string{"123", "@admin"},
+			Mentions:       map[string]string{"+33612345678": "role:42"},
+			Access:         AccessConfig{DefaultRole: "moderators", Commands: map[string]string{"ussd": AccessEveryone}},
+		},
+		Logging: LoggingConfig{Level: "verbose", Format: "text"},
+		Health:  HealthConfig{StateFile: "golte-health.json", Interval: time.Millisecond},
+	}
+
+	err := cfg.Validate()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Validate() = %v, want ValidationErrors", err)
+	}
+
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	want := []string{"modem.device", "modem.baud", "modem.timeout", "discord.guild_id", "discord.owner_ids", "discord.access.default_role", "logging.level", "health.interval"}
+	if !slices.Equal(fields, want) {
+		t.Errorf("Validate() fields = %q, want %q", fields, want)
+	}
+}
+
+func TestValidateAcceptsValidConfig(t *testing.T) {
+	cfg := &Config{
+		Modem: ModemConfig{Device: os.DevNull, Baud: 115200, Timeout: 20 * time.Second},
+		Discord: DiscordConfig{
+			Token:          "token",
+			ChannelIDs:     []string{"123456789012345678"},
+			GuildID:        "123456789012345677",
+			VoiceChannelID: "123456789012345679",
+			NumberChannels: map[string]string{"+33612345678": "123456789012345670"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	// The SMPP transport doesn't need a serial device
+	cfg.Modem = ModemConfig{Type: ModemTypeSMPP, Timeout: 20 * time.Second, SMPP: SMPPConfig{Addr: "smsc:2775", SystemID: "golte"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with smpp = %v", err)
+	}
+}
+
+func TestValidateWithoutVoice(t *testing.T) {
+	cfg := &Config{
+		Modem: ModemConfig{Device: os.DevNull, Baud: 115200, Timeout: 20 * time.Second},
+		Discord: DiscordConfig{
+			Token:      "token",
+			ChannelIDs: []string{"123456789012345678"},
+			GuildID:    "123456789012345677",
+		},
+		Audio: AudioConfig{FFmpegPath: "/does/not/exist/ffmpeg", AssetsDir: "/does/not/exist"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() without voice = %v", err)
+	}
+
+	cfg.Voice.Enabled = true
+	want := []string{"discord.voice_channel_id", "audio.ffmpeg_path", "audio.assets_dir"}
+	if fields := fieldsOf(cfg.Validate()); !slices.Equal(fields, want) {
+		t.Errorf("Validate() with voice fields = %q, want %q", fields, want)
+	}
+
+	// SMPP has no call audio, ffmpeg isn't needed
+	cfg.Modem = ModemConfig{Type: ModemTypeSMPP, Timeout: 20 * time.Second, SMPP: SMPPConfig{Addr: "smsc:2775", SystemID: "golte"}}
+	want = []string{"discord.voice_channel_id", "audio.assets_dir"}
+	if fields := fieldsOf(cfg.Validate()); !slices.Equal(fields, want) {
+		t.Errorf("Validate() with smpp fields = %q, want %q", fields, want)
+	}
+}
+
+func TestValidateModemIgnoresDiscord(t *testing.T) {
+	cfg := &Config{Modem: ModemConfig{Device: os.DevNull, Baud: 115200, Timeout: 20 * time.Second}}
+	if err := cfg.ValidateModem(); err != nil {
+		t.Errorf("ValidateModem() = %v", err)
+	}
+
+	cfg.Modem.Baud = 115201
+	if fields := fieldsOf(cfg.ValidateModem()); !slices.Equal(fields, []string{"modem.baud"}) {
+		t.Errorf("ValidateModem() fields = %q, want modem.baud", fields)
+	}
+
+	cfg.Modem.Baud = 115200
+	cfg.Modem.MaxSMSSegments = -1
+	if fields := fieldsOf(cfg.ValidateModem()); !slices.Equal(fields, []string{"modem.max_sms_segments"}) {
+		t.Errorf("ValidateModem() fields = %q, want modem.max_sms_segments", fields)
+	}
+}
+
+func TestValidateSMSTimeout(t *testing.T) {
+	cfg := &Config{Modem: ModemConfig{Device: os.DevNull, Baud: 115200, Timeout: 20 * time.Second, SMSTimeout: 2 * time.Minute, MaxSMSSegments: 4}}
+	if err := cfg.ValidateModem(); err != nil {
+		t.Errorf("ValidateModem() = %v", err)
+	}
+
+	// 10 segments of 2 minutes are more than Discord waits
+	cfg.Modem.MaxSMSSegments = 10
+	if fields := fieldsOf(cfg.ValidateModem()); !slices.Equal(fields, []string{"modem.sms_timeout"}) {
+		t.Errorf("ValidateModem() fields = %q, want modem.sms_timeout", fields)
+	}
+
+	cfg.Modem.MaxSMSSegments = 4
+	cfg.Modem.SMSTimeout = -time.Second
+	cfg.Modem.QueryTimeout = time.Hour
+	if fields := fieldsOf(cfg.ValidateModem()); !slices.Equal(fields, []string{"modem.sms_timeout", "modem.query_timeout"}) {
+		t.Errorf("ValidateModem() fields = %q, want modem.sms_timeout and modem.query_timeout", fields)
+	}
+}
+
+func TestValidateIconURLs(t *testing.T) {
+	cfg := &Config{
+		Modem: ModemConfig{Device: os.DevNull, Baud: 115200, Timeout: 20 * time.Second},
+		Discord: DiscordConfig{
+			Token:          "token",
+			ChannelIDs:     []string{"123456789012345678"},
+			GuildID:        "123456789012345677",
+			VoiceChannelID: "123456789012345679",
+			SMSIcon:        "https://example.com/sms.png",
+			CallIcon:       "file:///opt/golte/call.png",
+		},
+	}
+	if got := fieldsOf(cfg.Validate()); !slices.Equal(got, []string{"discord.call_icon"}) {
+		t.Errorf("Validate() fields = %q, want discord.call_icon", got)
+	}
+}
+
+func TestValidateCarrierPrefixes(t *testing.T) {
+	cfg := &Config{
+		Modem: ModemConfig{Device: os.DevNull, Baud: 115200, Timeout: 20 * time.Second},
+		Discord: DiscordConfig{
+			Token:           "token",
+			ChannelIDs:      []string{"123456789012345678"},
+			GuildID:         "123456789012345677",
+			VoiceChannelID:  "123456789012345679",
+			CarrierPrefixes: map[string]string{"+3366": "Operator", "0033": "Operator"},
+		},
+	}
+	if got := fieldsOf(cfg.Validate()); !slices.Equal(got, []string{"discord.carrier_prefixes"}) {
+		t.Errorf("Validate() fields = %q, want discord.carrier_prefixes", got)
+	}
+}
+
+func TestValidateRegistration(t *testing.T) {
+	tests := []struct {
+		registration RegistrationConfig
+		field        string
+	}{
+		{RegistrationConfig{}, ""},
+		{RegistrationConfig{Attempts: 3, Timeout: 10 * time.Second}, ""},
+		{RegistrationConfig{Attempts: -1}, "discord.registration.attempts"},
+		{RegistrationConfig{Attempts: 11}, "discord.registration.attempts"},
+		{RegistrationConfig{Timeout: 100 * time.Millisecond}, "discord.registration.timeout"},
+		{RegistrationConfig{Timeout: time.Hour}, "discord.registration.timeout"},
+	}
+	for _, tt := range tests {
+		d := DiscordConfig{Token: "token", ChannelIDs: []string{"123456789012345678"}, GuildID: "123456789012345678", Registration: tt.registration}
+		var errs ValidationErrors
+		d.validate(&errs, false)
+		switch {
+		case tt.field == "" && len(errs) != 0:
+			t.Errorf("validate(%+v) = %v, want no error", tt.registration, errs)
+		case tt.field != "" && (len(errs) != 1 || errs[0].Field != tt.field):
+			t.Errorf("validate(%+v) = %v, want a %s error", tt.registration, errs, tt.field)
+		}
+	}
+}
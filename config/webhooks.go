@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Webhook events
+const (
+	WebhookSMSReceived  = "sms.received"
+	WebhookSMSSent      = "sms.sent"
+	WebhookCallIncoming = "call.incoming"
+	WebhookCallDialing  = "call.dialing"
+	WebhookCallHangup   = "call.hangup"
+	WebhookCallNotice   = "call.notice"
+)
+
+// WebhookEvents are the events a webhook can subscribe to
+var WebhookEvents = []string{WebhookSMSReceived, WebhookSMSSent, WebhookCallIncoming, WebhookCallDialing, WebhookCallHangup, WebhookCallNotice}
+
+// maxWebhookAttempts caps the attempts of a webhook delivery
+const maxWebhookAttempts = 10
+
+// WebhookConfig is an endpoint SMS and call events are POSTed to as JSON
+type WebhookConfig struct {
+	URL        string            `mapstructure:"url"`
+	Events     []string          `mapstructure:"events"`      // WebhookEvents sent, empty for all of them
+	Secret     string            `mapstructure:"secret"`      // signs the body with HMAC-SHA256, empty to not sign
+	SecretFile string            `mapstructure:"secret_file"` // read the secret from this file instead
+	Headers    map[string]string `mapstructure:"headers"`     // added to every request, e.g. Authorization
+	Attempts   int               `mapstructure:"attempts"`    // deliveries tried before giving up, 0 uses 5
+}
+
+// Subscribed reports whether the webhook is sent event
+func (w WebhookConfig) Subscribed(event string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+func validateWebhooks(webhooks []WebhookConfig, errs *ValidationErrors) {
+	for i, w := range webhooks {
+		field := fmt.Sprintf("webhooks[%d]", i)
+		if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add(field+".url", "must be an http or https URL")
+		}
+		for _, event := range w.Events {
+			if !slices.Contains(WebhookEvents, event) {
+				errs.add(field+".events", "unknown event %q, must be one of %s", event, strings.Join(WebhookEvents, ", "))
+			}
+		}
+		for name := range w.Headers {
+			if name == "" || strings.ContainsAny(name, " :\r\n") {
+				errs.add(field+".headers", "%q is not a header name", name)
+			} else if http.CanonicalHeaderKey(name) == "Content-Type" {
+				errs.add(field+".headers", "Content-Type is always application/json")
+			}
+		}
+		if w.Attempts < 0 || w.Attempts > maxWebhookAttempts {
+			errs.add(field+".attempts", "must be between 0 and %d", maxWebhookAttempts)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package config
+
+import "testing"
+
+func TestValidateWebhooks(t *testing.T) {
+	tests := []struct {
+		webhook WebhookConfig
+		field   string
+	}{
+		{WebhookConfig{URL: "https://example.com/golte", Events: []string{WebhookSMSReceived}, Headers: map[string]string{"Authorization": "Bearer x"}}, ""},
+		{WebhookConfig{URL: "example.com/golte"}, "webhooks[0].url"},
+		{WebhookConfig{URL: "ftp://example.com"}, "webhooks[0].url"},
+		{WebhookConfig{URL: "http://localhost", Events: []string{"sms"}}, "webhooks[0].events"},
+		{WebhookConfig{URL: "http://localhost", Headers: map[string]string{"X Bad": "1"}}, "webhooks[0].headers"},
+		{WebhookConfig{URL: "http://localhost", Headers: map[string]string{"content-type": "text/plain"}}, "webhooks[0].headers"},
+		{WebhookConfig{URL: "http://localhost", Attempts: 11}, "webhooks[0].attempts"},
+	}
+	for _, tt := range tests {
+		var errs ValidationErrors
+		validateWebhooks([]WebhookConfig{tt.webhook}, &errs)
+		switch {
+		case tt.field == "" && len(errs) != 0:
+			t.Errorf("validate(%+v) = %v, want no error", tt.webhook, errs)
+		case tt.field != "" && (len(errs) != 1 || errs[0].Field != tt.field):
+			t.Errorf("validate(%+v) = %v, want a %s error", tt.webhook, errs, tt.field)
+		}
+	}
+}
+
+func TestWebhookSubscribed(t *testing.T) {
+	all := WebhookConfig{}
+	some := WebhookConfig{Events: []string{WebhookCallIncoming}}
+	if !all.Subscribed(WebhookSMSSent) || !some.Subscribed(WebhookCallIncoming) || some.Subscribed(WebhookSMSSent) {
+		t.Error("Subscribed() doesn't follow webhooks[].events")
+	}
+}
@@ -0,0 +1,278 @@
+// Package doctor checks the environment of the bridge: configuration, modem,
+// SIM, audio and Discord access. Each check is independent and returns a
+// Result, the caller decides which ones to run.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golte/config"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/warthog618/modem/at"
+	"github.com/warthog618/modem/info"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn" // works, but likely not as intended
+	Fail Status = "fail"
+	Skip Status = "skip" // not applicable, or depends on a failed check
+)
+
+// Result is the outcome of a check with what was found
+type Result struct {
+	Check  string `json:"check"`
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func result(check string, status Status, format string, args ...any) Result {
+	return Result{Check: check, Status: status, Detail: fmt.Sprintf(format, args...)}
+}
+
+// Modem is the AT session the modem checks run on, *at.AT implements it
+type Modem interface {
+	Init(options ...at.InitOption) error
+	Command(cmd string, options ...at.CommandOption) ([]string, error)
+}
+
+// DiscordAPI is the part of the Discord REST API the Discord checks use,
+// rest.Rest implements it
+type DiscordAPI interface {
+	GetCurrentUser(bearerToken string, opts ...rest.RequestOpt) (*discord.OAuth2User, error)
+	GetGuild(guildID snowflake.ID, withCounts bool, opts ...rest.RequestOpt) (*discord.RestGuild, error)
+	GetChannel(channelID snowflake.ID, opts ...rest.RequestOpt) (discord.Channel, error)
+}
+
+// Config loads and validates the configuration. The configuration is nil
+// when it can't be used for the other checks.
+func Config(load func() (*config.Config, error)) (*config.Config, Result) {
+	const check = "config"
+	cfg, err := load()
+	if err != nil {
+		return nil, result(check, Fail, "%v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, result(check, Fail, "%v", err)
+	}
+	return cfg, result(check, Pass, "loaded and valid")
+}
+
+// SerialDevice checks that the modem's serial device exists and can be
+// opened for reading and writing
+func SerialDevice(path string) Result {
+	const check = "serial device"
+	fi, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return result(check, Fail, "%s does not exist, is the modem plugged in and modem.device right?", path)
+	}
+	if err != nil {
+		return result(check, Fail, "%v", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrPermission) {
+		return result(check, Fail, "no permission to open %s, add the user to the dialout group", path)
+	}
+	if err != nil {
+		return result(check, Fail, "%v", err)
+	}
+	f.Close()
+
+	if fi.Mode()&os.ModeCharDevice == 0 {
+		return result(check, Warn, "%s is not a character device", path)
+	}
+	return result(check, Pass, "%s", path)
+}
+
+// ModemAnswers checks that the modem responds to AT commands
+func ModemAnswers(m Modem) Result {
+	const check = "modem"
+	if err := m.Init(); err != nil {
+		return result(check, Fail, "the modem does not answer AT commands: %v, check modem.device and modem.baud", err)
+	}
+	response, err := m.Command("+CGMM")
+	if err != nil || len(response) == 0 {
+		return result(check, Pass, "answers AT commands")
+	}
+	return result(check, Pass, "%s answers AT commands", strings.TrimSpace(response[0]))
+}
+
+// SIM checks that a SIM is inserted and unlocked, from AT+CPIN?
+func SIM(m Modem) Result {
+	const check = "SIM"
+	response, err := m.Command("+CPIN?")
+	if err != nil {
+		var cme at.CMEError
+		if errors.As(err, &cme) && (cme == "10" || strings.EqualFold(string(cme), "SIM not inserted")) {
+			return result(check, Fail, "no SIM inserted")
+		}
+		return result(check, Fail, "%v", err)
+	}
+
+	for _, line := range response {
+		if !info.HasPrefix(line, "+CPIN") {
+			continue
+		}
+		switch state := strings.TrimSpace(info.TrimPrefix(line, "+CPIN")); state {
+		case "READY":
+			return result(check, Pass, "inserted and unlocked")
+		case "SIM PIN":
+			return result(check, Fail, "locked, the SIM needs its PIN")
+		case "SIM PUK":
+			return result(check, Fail, "blocked, the SIM needs its PUK")
+		default:
+			return result(check, Fail, "waiting for %s", state)
+		}
+	}
+	return result(check, Warn, "unexpected answer %q", response)
+}
+
+// Registration checks that the modem is registered on a network, from
+// AT+CREG?
+func Registration(m Modem) Result {
+	const check = "network registration"
+	response, err := m.Command("+CREG?")
+	if err != nil {
+		return result(check, Fail, "%v", err)
+	}
+	stat, ok := parseRegistration(response)
+	if !ok {
+		return result(check, Warn, "unexpected answer %q", response)
+	}
+
+	switch stat {
+	case 1:
+		return result(check, Pass, "registered on the home network")
+	case 5:
+		return result(check, Pass, "registered, roaming")
+	case 2:
+		return result(check, Warn, "searching for a network")
+	case 3:
+		return result(check, Fail, "registration denied by the network")
+	default:
+		return result(check, Fail, "not registered (status %d)", stat)
+	}
+}
+
+// parseRegistration returns the status of +CREG: <n>,<stat>[,...]
+func parseRegistration(response []string) (int, bool) {
+	for _, line := range response {
+		if !info.HasPrefix(line, "+CREG") {
+			continue
+		}
+		fields := strings.Split(info.TrimPrefix(line, "+CREG"), ",")
+		if len(fields) < 2 {
+			return 0, false
+		}
+		stat, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		return stat, err == nil
+	}
+	return 0, false
+}
+
+// FFmpeg checks that ffmpeg is installed with ALSA support. probe returns a
+// description of the binary, or why it can't be used.
+func FFmpeg(ctx context.Context, path string, probe func(context.Context, string) (string, error)) Result {
+	const check = "ffmpeg"
+	found, err := probe(ctx, path)
+	if err != nil {
+		return result(check, Fail, "%v", err)
+	}
+	return result(check, Pass, "%s", found)
+}
+
+// alsaHWPattern matches ALSA hardware device names like hw:2,0 or plughw:1
+var alsaHWPattern = regexp.MustCompile(`^(?:plug)?hw:(\d+)(?:,(\d+))?$`)
+
+// ALSADevice checks that the capture and playback nodes of an ALSA hardware
+// device exist under sndDir, usually /dev/snd
+func ALSADevice(device, sndDir string) Result {
+	const check = "ALSA device"
+	m := alsaHWPattern.FindStringSubmatch(device)
+	if m == nil {
+		return result(check, Warn, "%s is not a hw:card,device name, it can't be checked", device)
+	}
+	pcm := m[2]
+	if pcm == "" {
+		pcm = "0"
+	}
+
+	var missing []string
+	for _, direction := range []string{"c", "p"} {
+		node := filepath.Join(sndDir, fmt.Sprintf("pcmC%sD%s%s", m[1], pcm, direction))
+		if _, err := os.Stat(node); err != nil {
+			missing = append(missing, node)
+		}
+	}
+	if len(missing) > 0 {
+		return result(check, Fail, "%s not found (%s), check audio.device with arecord -l", device, strings.Join(missing, ", "))
+	}
+	return result(check, Pass, "%s", device)
+}
+
+// RNNoiseModel checks that the noise suppression model of the call capture
+// is installed
+func RNNoiseModel(path string) Result {
+	const check = "RNNoise model"
+	if _, err := os.Stat(path); err != nil {
+		return result(check, Fail, "%s is missing, call audio can't be captured without it", path)
+	}
+	return result(check, Pass, "%s", path)
+}
+
+// DiscordToken checks that the bot token authenticates
+func DiscordToken(api DiscordAPI) Result {
+	const check = "Discord token"
+	user, err := api.GetCurrentUser("")
+	if err != nil {
+		return result(check, Fail, "%v", err)
+	}
+	return result(check, Pass, "logged in as %s", user.Username)
+}
+
+// DiscordGuild checks that the bot is a member of the guild id
+func DiscordGuild(api DiscordAPI, id string) Result {
+	const check = "Discord guild"
+	guildID, err := snowflake.Parse(id)
+	if err != nil {
+		return result(check, Skip, "no guild configured")
+	}
+	guild, err := api.GetGuild(guildID, false)
+	if err != nil {
+		return result(check, Fail, "guild %s can't be reached, is the bot invited? %v", id, err)
+	}
+	return result(check, Pass, "%s", guild.Name)
+}
+
+// DiscordChannel checks that the bot can see the channel id, named by key in
+// the configuration, and that it belongs to the guild guildID
+func DiscordChannel(api DiscordAPI, key, id, guildID string) Result {
+	check := "Discord " + key
+	channelID, err := snowflake.Parse(id)
+	if err != nil {
+		return result(check, Skip, "not configured")
+	}
+	channel, err := api.GetChannel(channelID)
+	if err != nil {
+		return result(check, Fail, "channel %s can't be reached, check the bot's permissions: %v", id, err)
+	}
+
+	if guildChannel, ok := channel.(discord.GuildChannel); ok && guildID != "" && guildChannel.GuildID().String() != guildID {
+		return result(check, Warn, "#%s is not in guild %s", channel.Name(), guildID)
+	}
+	return result(check, Pass, "#%s", channel.Name())
+}
@@ -0,0 +1,227 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golte/config"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/warthog618/modem/at"
+)
+
+// fakeModem answers commands from a table, unknown commands fail
+type fakeModem struct {
+	initErr   error
+	responses map[string][]string
+	errors    map[string]error
+}
+
+func (m *fakeModem) Init(...at.InitOption) error {
+	return m.initErr
+}
+
+func (m *fakeModem) Command(cmd string, _ ...at.CommandOption) ([]string, error) {
+	if err, ok := m.errors[cmd]; ok {
+		return nil, err
+	}
+	if response, ok := m.responses[cmd]; ok {
+		return response, nil
+	}
+	return nil, at.ErrError
+}
+
+func TestConfig(t *testing.T) {
+	if cfg, r := Config(func() (*config.Config, error) { return nil, errors.New("no config.yaml") }); cfg != nil || r.Status != Fail {
+		t.Errorf("Config() with a load error = %v, %+v", cfg, r)
+	}
+	if cfg, r := Config(func() (*config.Config, error) { return &config.Config{}, nil }); cfg != nil || r.Status != Fail {
+		t.Errorf("Config() with an invalid config = %v, %+v", cfg, r)
+	}
+}
+
+func TestSerialDevice(t *testing.T) {
+	if r := SerialDevice(os.DevNull); r.Status != Pass {
+		t.Errorf("SerialDevice(%s) = %+v", os.DevNull, r)
+	}
+	if r := SerialDevice(filepath.Join(t.TempDir(), "ttyUSB2")); r.Status != Fail {
+		t.Errorf("SerialDevice() on a missing device = %+v", r)
+	}
+
+	file := filepath.Join(t.TempDir(), "ttyUSB2")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if r := SerialDevice(file); r.Status != Warn {
+		t.Errorf("SerialDevice() on a regular file = %+v", r)
+	}
+}
+
+func TestModemAnswers(t *testing.T) {
+	if r := ModemAnswers(&fakeModem{initErr: at.ErrDeadlineExceeded}); r.Status != Fail {
+		t.Errorf("ModemAnswers() on a silent modem = %+v", r)
+	}
+	r := ModemAnswers(&fakeModem{responses: map[string][]string{"+CGMM": {"EC25"}}})
+	if r.Status != Pass || r.Detail != "EC25 answers AT commands" {
+		t.Errorf("ModemAnswers() = %+v", r)
+	}
+}
+
+func TestSIM(t *testing.T) {
+	tests := []struct {
+		name   string
+		modem  *fakeModem
+		status Status
+		detail string
+	}{
+		{"ready", &fakeModem{responses: map[string][]string{"+CPIN?": {"+CPIN: READY"}}}, Pass, "inserted and unlocked"},
+		{"pin", &fakeModem{responses: map[string][]string{"+CPIN?": {"+CPIN: SIM PIN"}}}, Fail, "locked, the SIM needs its PIN"},
+		{"missing", &fakeModem{errors: map[string]error{"+CPIN?": at.CMEError("10")}}, Fail, "no SIM inserted"},
+		{"missing verbose", &fakeModem{errors: map[string]error{"+CPIN?": at.CMEError("SIM not inserted")}}, Fail, "no SIM inserted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if r := SIM(tt.modem); r.Status != tt.status || r.Detail != tt.detail {
+				t.Errorf("SIM() = %+v, want %s %q", r, tt.status, tt.detail)
+			}
+		})
+	}
+}
+
+func TestRegistration(t *testing.T) {
+	tests := []struct {
+		answer string
+		status Status
+	}{
+		{"+CREG: 0,1", Pass},
+		{"+CREG: 2,5,\"1A2B\",\"01C3D4E5\",7", Pass},
+		{"+CREG: 0,2", Warn},
+		{"+CREG: 0,3", Fail},
+		{"+CREG: 0,0", Fail},
+		{"+CREG: 0", Warn},
+	}
+	for _, tt := range tests {
+		m := &fakeModem{responses: map[string][]string{"+CREG?": {tt.answer}}}
+		if r := Registration(m); r.Status != tt.status {
+			t.Errorf("Registration(%q) = %+v, want %s", tt.answer, r, tt.status)
+		}
+	}
+}
+
+func TestFFmpeg(t *testing.T) {
+	ok := func(context.Context, string) (string, error) { return "ffmpeg 6.1.1 at /usr/bin/ffmpeg", nil }
+	if r := FFmpeg(context.Background(), "ffmpeg", ok); r.Status != Pass {
+		t.Errorf("FFmpeg() = %+v", r)
+	}
+	noALSA := func(context.Context, string) (string, error) { return "", errors.New("built without ALSA support") }
+	if r := FFmpeg(context.Background(), "ffmpeg", noALSA); r.Status != Fail {
+		t.Errorf("FFmpeg() without ALSA = %+v", r)
+	}
+}
+
+func TestALSADevice(t *testing.T) {
+	snd := t.TempDir()
+	for _, node := range []string{"pcmC2D0c", "pcmC2D0p", "pcmC3D0c"} {
+		if err := os.WriteFile(filepath.Join(snd, node), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		device string
+		status Status
+	}{
+		{"hw:2,0", Pass},
+		{"plughw:2", Pass},
+		{"hw:3,0", Fail}, // capture only
+		{"hw:4,0", Fail},
+		{"default", Warn},
+	}
+	for _, tt := range tests {
+		if r := ALSADevice(tt.device, snd); r.Status != tt.status {
+			t.Errorf("ALSADevice(%q) = %+v, want %s", tt.device, r, tt.status)
+		}
+	}
+}
+
+func TestRNNoiseModel(t *testing.T) {
+	model := filepath.Join(t.TempDir(), "std.rnnn")
+	if r := RNNoiseModel(model); r.Status != Fail {
+		t.Errorf("RNNoiseModel() on a missing file = %+v", r)
+	}
+	if err := os.WriteFile(model, []byte("model"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if r := RNNoiseModel(model); r.Status != Pass {
+		t.Errorf("RNNoiseModel() = %+v", r)
+	}
+}
+
+const (
+	testGuildID   = "123456789012345677"
+	testChannelID = "123456789012345678"
+)
+
+// fakeDiscord knows one guild with one text channel
+type fakeDiscord struct {
+	tokenErr error
+}
+
+func (d fakeDiscord) GetCurrentUser(string, ...rest.RequestOpt) (*discord.OAuth2User, error) {
+	if d.tokenErr != nil {
+		return nil, d.tokenErr
+	}
+	return &discord.OAuth2User{User: discord.User{Username: "golte"}}, nil
+}
+
+func (d fakeDiscord) GetGuild(id snowflake.ID, _ bool, _ ...rest.RequestOpt) (*discord.RestGuild, error) {
+	if id.String() != testGuildID {
+		return nil, errors.New("404 Unknown Guild")
+	}
+	return &discord.RestGuild{Guild: discord.Guild{Name: "Home"}}, nil
+}
+
+func (d fakeDiscord) GetChannel(id snowflake.ID, _ ...rest.RequestOpt) (discord.Channel, error) {
+	if id.String() != testChannelID {
+		return nil, errors.New("403 Missing Access")
+	}
+	var channel discord.GuildTextChannel
+	err := json.Unmarshal([]byte(`{"id":"`+testChannelID+`","guild_id":"`+testGuildID+`","type":0,"name":"sms"}`), &channel)
+	return channel, err
+}
+
+func TestDiscord(t *testing.T) {
+	if r := DiscordToken(fakeDiscord{tokenErr: errors.New("401 Unauthorized")}); r.Status != Fail {
+		t.Errorf("DiscordToken() with a bad token = %+v", r)
+	}
+	if r := DiscordToken(fakeDiscord{}); r.Status != Pass || !strings.Contains(r.Detail, "golte") {
+		t.Errorf("DiscordToken() = %+v", r)
+	}
+
+	if r := DiscordGuild(fakeDiscord{}, testGuildID); r.Status != Pass || r.Detail != "Home" {
+		t.Errorf("DiscordGuild() = %+v", r)
+	}
+	if r := DiscordGuild(fakeDiscord{}, "123456789012345600"); r.Status != Fail {
+		t.Errorf("DiscordGuild() on another guild = %+v", r)
+	}
+
+	if r := DiscordChannel(fakeDiscord{}, "channel", testChannelID, testGuildID); r.Status != Pass || r.Detail != "#sms" {
+		t.Errorf("DiscordChannel() = %+v", r)
+	}
+	if r := DiscordChannel(fakeDiscord{}, "channel", testChannelID, "123456789012345600"); r.Status != Warn {
+		t.Errorf("DiscordChannel() in another guild = %+v", r)
+	}
+	if r := DiscordChannel(fakeDiscord{}, "voice channel", "123456789012345600", testGuildID); r.Status != Fail {
+		t.Errorf("DiscordChannel() without access = %+v", r)
+	}
+	if r := DiscordChannel(fakeDiscord{}, "voice channel", "", testGuildID); r.Status != Skip {
+		t.Errorf("DiscordChannel() unset = %+v", r)
+	}
+}
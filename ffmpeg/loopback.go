@@ -0,0 +1,43 @@
+package ffmpeg
+
+import (
+	"math"
+	"time"
+)
+
+// TimedFrame is a captured frame with when it was read
+type TimedFrame struct {
+	At  time.Time
+	PCM []int16
+}
+
+// LoopbackResult is what MeasureLoopback heard of a test tone
+type LoopbackResult struct {
+	Detected bool
+	Latency  time.Duration // from the tone start to the first frame above the threshold
+	LevelDB  float64       // RMS level of the loudest frame after the tone start, in dBFS
+}
+
+// MeasureLoopback looks for a test tone played at start in frames captured
+// around it. The latency includes the capture buffering, as heard in calls.
+func MeasureLoopback(frames []TimedFrame, start time.Time, thresholdDB float64) LoopbackResult {
+	threshold := math.Pow(10, thresholdDB/20)
+	result := LoopbackResult{LevelDB: math.Inf(-1)}
+
+	var peak float64
+	for _, f := range frames {
+		if f.At.Before(start) {
+			continue
+		}
+		level := frameLevel(f.PCM)
+		peak = max(peak, level)
+		if !result.Detected && level >= threshold {
+			result.Detected = true
+			result.Latency = f.At.Sub(start)
+		}
+	}
+	if peak > 0 {
+		result.LevelDB = 20 * math.Log10(peak)
+	}
+	return result
+}
@@ -0,0 +1,33 @@
+package ffmpeg
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMeasureLoopback(t *testing.T) {
+	start := time.Date(2024, 1, 2, 13, 4, 5, 0, time.UTC)
+
+	// Silence, then the tone comes back 120ms after it started playing
+	var frames []TimedFrame
+	for i := -5; i < 20; i++ {
+		level := -90.0
+		if i >= 6 {
+			level = -12
+		}
+		frames = append(frames, TimedFrame{At: start.Add(time.Duration(i) * OutputFrame), PCM: sineFrame(level, 0)})
+	}
+
+	r := MeasureLoopback(frames, start, -40)
+	if !r.Detected || r.Latency != 120*time.Millisecond {
+		t.Errorf("MeasureLoopback() = %+v, want detected after 120ms", r)
+	}
+	if math.Abs(r.LevelDB+12) > 0.5 {
+		t.Errorf("LevelDB = %.1f, want about -12", r.LevelDB)
+	}
+
+	if r := MeasureLoopback(frames[:10], start, -40); r.Detected {
+		t.Errorf("MeasureLoopback() on silence = %+v", r)
+	}
+}
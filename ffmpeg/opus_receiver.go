@@ -1,6 +1,9 @@
 package ffmpeg
 
 import (
+	"slices"
+	"sync"
+
 	"golte/playback"
 
 	"github.com/disgoorg/audio/pcm"
@@ -18,13 +21,21 @@ type OpusFrameReceiver interface {
 	Close()
 }
 
+// OpusPCMReceiver forwards the PCM frames of Discord users to the call. When
+// the transmit list is non-empty only the listed users are forwarded.
 type OpusPCMReceiver struct {
 	Ch chan *pcm.Packet
+
+	mu       sync.Mutex
+	transmit map[snowflake.ID]struct{}
+	onAir    map[snowflake.ID]struct{}
 }
 
 func NewOpusPCMReceiver() (*OpusPCMReceiver, *playback.PCMStreamer, error) {
 	receiver := &OpusPCMReceiver{
-		Ch: make(chan *pcm.Packet, 10),
+		Ch:       make(chan *pcm.Packet, 10),
+		transmit: make(map[snowflake.ID]struct{}),
+		onAir:    make(map[snowflake.ID]struct{}),
 	}
 
 	streamer := playback.NewPCMStreamer(receiver.Ch)
@@ -32,12 +43,98 @@ func NewOpusPCMReceiver() (*OpusPCMReceiver, *playback.PCMStreamer, error) {
 }
 
 func (r *OpusPCMReceiver) ReceivePCMFrame(userID snowflake.ID, packet *pcm.Packet) error {
+	r.mu.Lock()
+	if !r.allowedLocked(userID) {
+		r.mu.Unlock()
+		return nil
+	}
+	r.onAir[userID] = struct{}{}
+	r.mu.Unlock()
+
 	r.Ch <- packet
 	return nil
 }
 
 func (r *OpusPCMReceiver) CleanupUser(userID snowflake.ID) {
-	// Cleanup any resources for the user
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.onAir, userID)
+}
+
+// allowedLocked reports whether the user's audio may be forwarded
+func (r *OpusPCMReceiver) allowedLocked(userID snowflake.ID) bool {
+	if len(r.transmit) == 0 {
+		return true
+	}
+	_, ok := r.transmit[userID]
+	return ok
+}
+
+// SetTransmitUsers replaces the transmit list. An empty list forwards everyone.
+func (r *OpusPCMReceiver) SetTransmitUsers(userIDs []snowflake.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.transmit = make(map[snowflake.ID]struct{}, len(userIDs))
+	for _, id := range userIDs {
+		r.transmit[id] = struct{}{}
+	}
+	r.pruneOnAirLocked()
+}
+
+// AddTransmitUser adds a user to the transmit list
+func (r *OpusPCMReceiver) AddTransmitUser(userID snowflake.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.transmit[userID] = struct{}{}
+	r.pruneOnAirLocked()
+}
+
+// RemoveTransmitUser removes a user from the transmit list and reports
+// whether they were on it
+func (r *OpusPCMReceiver) RemoveTransmitUser(userID snowflake.ID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.transmit[userID]; !ok {
+		return false
+	}
+	delete(r.transmit, userID)
+	r.pruneOnAirLocked()
+	return true
+}
+
+// TransmitUsers returns the transmit list, empty when everyone is forwarded
+func (r *OpusPCMReceiver) TransmitUsers() []snowflake.ID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return sortedIDs(r.transmit)
+}
+
+// OnAir returns the users whose audio is currently reaching the call
+func (r *OpusPCMReceiver) OnAir() []snowflake.ID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return sortedIDs(r.onAir)
+}
+
+// pruneOnAirLocked drops users no longer allowed to transmit
+func (r *OpusPCMReceiver) pruneOnAirLocked() {
+	for id := range r.onAir {
+		if !r.allowedLocked(id) {
+			delete(r.onAir, id)
+		}
+	}
+}
+
+func sortedIDs(set map[snowflake.ID]struct{}) []snowflake.ID {
+	ids := make([]snowflake.ID, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
 }
 
 func (r *OpusPCMReceiver) Close() {
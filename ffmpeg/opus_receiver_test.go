@@ -0,0 +1,65 @@
+package ffmpeg
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/disgoorg/audio/pcm"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+func TestOpusPCMReceiverTransmitList(t *testing.T) {
+	receiver, _, err := NewOpusPCMReceiver()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const host, observer = snowflake.ID(1), snowflake.ID(2)
+
+	received := func(userID snowflake.ID) bool {
+		if err := receiver.ReceivePCMFrame(userID, &pcm.Packet{}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-receiver.Ch:
+			return true
+		default:
+			return false
+		}
+	}
+
+	// An empty list forwards everyone
+	if !received(host) || !received(observer) {
+		t.Fatal("expected every user to be forwarded with an empty transmit list")
+	}
+
+	receiver.SetTransmitUsers([]snowflake.ID{host})
+	if !received(host) {
+		t.Error("expected listed user to be forwarded")
+	}
+	if received(observer) {
+		t.Error("expected unlisted user to be dropped")
+	}
+	if got := receiver.OnAir(); !slices.Equal(got, []snowflake.ID{host}) {
+		t.Errorf("OnAir() = %v, want [%d]", got, host)
+	}
+
+	receiver.AddTransmitUser(observer)
+	if !received(observer) {
+		t.Error("expected added user to be forwarded immediately")
+	}
+
+	if !receiver.RemoveTransmitUser(observer) {
+		t.Error("RemoveTransmitUser() = false for a listed user")
+	}
+	if received(observer) {
+		t.Error("expected removed user to be dropped immediately")
+	}
+	if got := receiver.OnAir(); !slices.Equal(got, []snowflake.ID{host}) {
+		t.Errorf("OnAir() after removal = %v, want [%d]", got, host)
+	}
+
+	receiver.CleanupUser(host)
+	if got := receiver.OnAir(); len(got) != 0 {
+		t.Errorf("OnAir() after CleanupUser = %v, want empty", got)
+	}
+}
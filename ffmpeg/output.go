@@ -0,0 +1,244 @@
+package ffmpeg
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"golte/playback"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// outputLead is the silence written ahead of the audio when an output
+// process starts, so ALSA doesn't under-run on the first late frame
+const outputLead = 100 * time.Millisecond
+
+// Output plays a beep streamer on an ALSA device through ffmpeg. Unlike the
+// beep speaker it can be moved to another device while playing: a new
+// ffmpeg is started on it and the streamer keeps being pulled, so the
+// playback queue and streams behind it are undisturbed.
+type Output struct {
+	exec       string
+	sampleRate beep.SampleRate
+
+	// streamMu is held while samples are pulled, see Lock
+	streamMu sync.Mutex
+
+	mu       sync.Mutex
+	device   string
+	proc     *outputProcess // nil while there's no ffmpeg to play to
+	streamer beep.Streamer  // set by Play
+	closed   bool
+	done     chan struct{}
+}
+
+// outputProcess is one ffmpeg playback, replaced when the device changes
+type outputProcess struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+var _ playback.DeviceOutput = (*Output)(nil)
+
+// NewOutput creates an output playing to the ALSA device, DefaultDevice if
+// empty, at sampleRate. Nothing is started before Play.
+func NewOutput(device string, sampleRate beep.SampleRate) *Output {
+	if device == "" {
+		device = DefaultDevice
+	}
+	return &Output{
+		exec:       ExecPath(),
+		sampleRate: sampleRate,
+		device:     device,
+		done:       make(chan struct{}),
+	}
+}
+
+// start launches an ffmpeg process playing raw PCM from its stdin to device
+func (o *Output) start(device string) (*outputProcess, error) {
+	cmd := exec.Command(o.exec,
+		"-f", "s16le",
+		"-ar", strconv.Itoa(int(o.sampleRate)),
+		"-ac", strconv.Itoa(Channels),
+		"-i", "pipe:0",
+		"-f", "alsa",
+		device,
+	)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go cmd.Wait()
+
+	// Fits in the pipe buffer, so it doesn't block on a stuck device
+	if _, err := stdin.Write(make([]byte, o.sampleRate.N(outputLead)*Channels*2)); err != nil {
+		stdin.Close()
+		cmd.Process.Kill()
+		return nil, err
+	}
+	return &outputProcess{cmd: cmd, stdin: stdin}, nil
+}
+
+// stop closes p's input and kills it, a process stuck on a dead device
+// wouldn't exit by itself
+func (p *outputProcess) stop() {
+	_ = p.stdin.Close()
+	if p.cmd.Process != nil {
+		_ = p.cmd.Process.Kill()
+	}
+}
+
+// Play starts ffmpeg on the device and plays s until Close
+func (o *Output) Play(s beep.Streamer) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return errors.New("output is closed")
+	}
+	if o.streamer != nil {
+		return errors.New("output is already playing")
+	}
+	proc, err := o.start(o.device)
+	if err != nil {
+		return fmt.Errorf("failed to start playback on %s: %w", o.device, err)
+	}
+	o.proc = proc
+	o.streamer = s
+
+	go o.pump(s)
+	return nil
+}
+
+// pump pulls s in real time and writes it to the current ffmpeg. While
+// there's none, e.g. ffmpeg exited as the device vanished, the audio is
+// pulled and dropped so the streams behind it keep flowing.
+func (o *Output) pump(s beep.Streamer) {
+	n := o.sampleRate.N(OutputFrame)
+	samples := make([][2]float64, n)
+	buf := make([]byte, n*Channels*2)
+
+	ticker := time.NewTicker(OutputFrame)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.done:
+			return
+		case <-ticker.C:
+		}
+
+		o.streamMu.Lock()
+		filled, _ := s.Stream(samples)
+		o.streamMu.Unlock()
+		clear(samples[filled:])
+		encodePCM(buf, samples)
+
+		o.mu.Lock()
+		proc := o.proc
+		o.mu.Unlock()
+		if proc == nil {
+			continue
+		}
+		if _, err := proc.stdin.Write(buf); err != nil {
+			o.dropProcess(proc)
+		}
+	}
+}
+
+// dropProcess forgets proc, which stopped taking audio, if it's still
+// current. ReconfigureDevice starts a new one.
+func (o *Output) dropProcess(proc *outputProcess) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.proc == proc {
+		o.proc = nil
+	}
+	proc.stop()
+}
+
+// encodePCM writes samples to buf as interleaved s16le
+func encodePCM(buf []byte, samples [][2]float64) {
+	for i, sample := range samples {
+		for c, v := range sample {
+			v = max(-1, min(1, v))
+			binary.LittleEndian.PutUint16(buf[(i*Channels+c)*2:], uint16(int16(v*32767)))
+		}
+	}
+}
+
+// Device returns the ALSA device played to
+func (o *Output) Device() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.device
+}
+
+// ReconfigureDevice relaunches the playback against device, e.g. after the
+// sound card re-enumerated. The streamer keeps being pulled throughout, a
+// playback whose ffmpeg already exited is relaunched too.
+func (o *Output) ReconfigureDevice(device string) error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return errors.New("output is closed")
+	}
+	if o.streamer == nil {
+		// Not playing yet, Play starts on device
+		o.device = device
+		o.mu.Unlock()
+		return nil
+	}
+
+	proc, err := o.start(device)
+	if err != nil {
+		o.mu.Unlock()
+		return fmt.Errorf("failed to start playback on %s: %w", device, err)
+	}
+	old := o.proc
+	o.proc = proc
+	o.device = device
+	o.mu.Unlock()
+
+	if old != nil {
+		old.stop()
+	}
+	return nil
+}
+
+// Lock keeps the output from pulling samples until Unlock, while the
+// streamers are changed
+func (o *Output) Lock() {
+	o.streamMu.Lock()
+}
+
+// Unlock lets the output pull samples again
+func (o *Output) Unlock() {
+	o.streamMu.Unlock()
+}
+
+// Close stops the playback, the output can't be used afterwards
+func (o *Output) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return
+	}
+	o.closed = true
+	close(o.done)
+	if o.proc != nil {
+		o.proc.stop()
+		o.proc = nil
+	}
+}
@@ -0,0 +1,89 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// countingStreamer streams a constant level and counts the samples pulled
+type countingStreamer struct {
+	pulled atomic.Int64
+}
+
+func (s *countingStreamer) Stream(samples [][2]float64) (int, bool) {
+	for i := range samples {
+		samples[i] = [2]float64{0.5, 0.5}
+	}
+	s.pulled.Add(int64(len(samples)))
+	return len(samples), true
+}
+
+func (s *countingStreamer) Err() error { return nil }
+
+// waitSize waits for the file at path to grow past size
+func waitSize(t *testing.T, path string, size int64) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		if info, err := os.Stat(path); err == nil && info.Size() > size {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s never grew past %d bytes", filepath.Base(path), size)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOutputReconfigureDevice(t *testing.T) {
+	dir := t.TempDir()
+	// The old device stops taking audio, as when the sound card goes away
+	exec := fakeFFmpeg(t, fmt.Sprintf(`case "$*" in *hw:old*) head -c 40000 > %[1]s/old; exit 1;; esac; cat > %[1]s/new`, dir))
+	o := NewOutput("hw:old", beep.SampleRate(SampleRate))
+	o.exec = exec
+	defer o.Close()
+
+	s := &countingStreamer{}
+	if err := o.Play(s); err != nil {
+		t.Fatal(err)
+	}
+	lead := int64(beep.SampleRate(SampleRate).N(outputLead) * Channels * 2)
+	waitSize(t, filepath.Join(dir, "old"), lead)
+
+	// The old ffmpeg exited, the output can still be moved
+	time.Sleep(100 * time.Millisecond)
+	if err := o.ReconfigureDevice("hw:new"); err != nil {
+		t.Fatalf("ReconfigureDevice() = %v", err)
+	}
+	if got := o.Device(); got != "hw:new" {
+		t.Errorf("Device() = %q, want hw:new", got)
+	}
+	pulled := s.pulled.Load()
+	waitSize(t, filepath.Join(dir, "new"), lead)
+
+	data, err := os.ReadFile(filepath.Join(dir, "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) <= int(lead) || data[len(data)-1] == 0 && data[len(data)-2] == 0 {
+		t.Error("the new device didn't get the streamer's audio")
+	}
+	if s.pulled.Load() <= pulled {
+		t.Error("the streamer stopped being pulled")
+	}
+}
+
+func TestEncodePCM(t *testing.T) {
+	buf := make([]byte, 8)
+	encodePCM(buf, [][2]float64{{1, -1}, {2, 0}})
+	want := []byte{0xff, 0x7f, 0x01, 0x80, 0xff, 0x7f, 0x00, 0x00}
+	if string(buf) != string(want) {
+		t.Errorf("encodePCM() = % x, want % x", buf, want)
+	}
+}
@@ -2,88 +2,108 @@ package ffmpeg
 
 import (
 	"context"
-	"embed"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"strconv"
 	"sync"
 )
 
-// Player plays MP3 audio files from an embedded filesystem through FFmpeg
+// Player plays MP3 audio files from an embedded filesystem through FFmpeg.
+// A Player can be used for any number of plays, one at a time, until Close.
 type Player struct {
-	mu     sync.RWMutex
+	mu     sync.Mutex
+	exec   string
+	device string
 	cmd    *exec.Cmd
 	stdin  io.WriteCloser
 	ctx    context.Context
 	cancel context.CancelFunc
 	done   chan struct{}
+	closed bool
 }
 
 // NewPlayer creates a new MP3 player that outputs to pipe:0 via FFmpeg
 func NewPlayer() (*Player, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Nothing is playing yet, so Wait must not block
+	done := make(chan struct{})
+	close(done)
+
 	player := &Player{
+		exec:   ExecPath(),
+		device: DefaultDevice,
 		ctx:    ctx,
 		cancel: cancel,
-		done:   make(chan struct{}),
+		done:   done,
 	}
 
 	return player, nil
 }
 
-// PlayMP3 plays an MP3 file from the embedded filesystem
-func (p *Player) PlayMP3(assets embed.FS, filename string) error {
+// PlayMP3 plays an MP3 file from the given filesystem (usually embedded)
+func (p *Player) PlayMP3(assets fs.FS, filename string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	if p.closed {
+		return fmt.Errorf("player is closed")
+	}
+
 	// Check if we're already playing something
 	if p.cmd != nil {
 		return fmt.Errorf("player is already active")
 	}
 
 	// Read the MP3 file from embedded filesystem
-	data, err := assets.ReadFile(filename)
+	data, err := fs.ReadFile(assets, filename)
 	if err != nil {
 		return fmt.Errorf("failed to read MP3 file %s: %w", filename, err)
 	}
 
+	// Each play gets its own context so stopping it leaves the player usable
+	playCtx, playCancel := context.WithCancel(p.ctx)
+
 	// Create FFmpeg command to decode MP3 and output to pipe:0
-	p.cmd = exec.CommandContext(p.ctx, Exec,
+	cmd := exec.CommandContext(playCtx, p.exec,
 		"-f", "mp3", // Input format is MP3
 		"-i", "pipe:0", // Read MP3 data from stdin
 		"-f", "alsa", // Output format: ALSA
 		"-ar", strconv.Itoa(SampleRate), // Output sample rate
 		"-ac", strconv.Itoa(Channels), // Output channels
-		"hw:2,0", // Output device (ALSA hardware device)
+		p.device, // Output device (ALSA hardware device)
 	)
 
 	// Get stdin pipe to send MP3 data
-	stdin, err := p.cmd.StdinPipe()
+	stdin, err := cmd.StdinPipe()
 	if err != nil {
-		p.cmd = nil
+		playCancel()
 		return fmt.Errorf("failed to create stdin pipe: %w", err)
 	}
-	p.stdin = stdin
 
 	// Set stderr to capture any FFmpeg errors
-	p.cmd.Stderr = os.Stderr
+	cmd.Stderr = os.Stderr
 
 	// Start the FFmpeg process
-	if err := p.cmd.Start(); err != nil {
-		p.stdin.Close()
-		p.stdin = nil
-		p.cmd = nil
+	if err := cmd.Start(); err != nil {
+		stdin.Close()
+		playCancel()
 		return fmt.Errorf("failed to start ffmpeg: %w", err)
 	}
 
+	done := make(chan struct{})
+	p.cmd = cmd
+	p.stdin = stdin
+	p.done = done
+
 	// Send MP3 data to FFmpeg in a goroutine
 	go func() {
-		defer p.stdin.Close()
+		defer stdin.Close()
 
-		if _, err := p.stdin.Write(data); err != nil {
+		if _, err := stdin.Write(data); err != nil {
 			fmt.Printf("Error writing MP3 data to ffmpeg: %v\n", err)
 		}
 	}()
@@ -92,13 +112,16 @@ func (p *Player) PlayMP3(assets embed.FS, filename string) error {
 	go func() {
 		defer func() {
 			p.mu.Lock()
-			p.cmd = nil
-			p.stdin = nil
+			if p.cmd == cmd {
+				p.cmd = nil
+				p.stdin = nil
+			}
 			p.mu.Unlock()
-			close(p.done)
+			playCancel()
+			close(done)
 		}()
 
-		if err := p.cmd.Wait(); err != nil {
+		if err := cmd.Wait(); err != nil {
 			fmt.Printf("ffmpeg process exited with error: %v\n", err)
 		}
 	}()
@@ -106,8 +129,15 @@ func (p *Player) PlayMP3(assets embed.FS, filename string) error {
 	return nil
 }
 
+// SetDevice changes the ALSA device used from the next play on
+func (p *Player) SetDevice(device string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.device = device
+}
+
 // PlayMP3Sync plays an MP3 file synchronously and waits for completion
-func (p *Player) PlayMP3Sync(assets embed.FS, filename string) error {
+func (p *Player) PlayMP3Sync(assets fs.FS, filename string) error {
 	if err := p.PlayMP3(assets, filename); err != nil {
 		return err
 	}
@@ -119,16 +149,15 @@ func (p *Player) PlayMP3Sync(assets embed.FS, filename string) error {
 
 // IsPlaying returns true if the player is currently playing
 func (p *Player) IsPlaying() bool {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.cmd != nil
 }
 
-// Stop stops the current playback
+// Stop stops the current playback and waits for FFmpeg to exit. The player
+// can be used again afterwards.
 func (p *Player) Stop() {
 	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	if p.cmd != nil {
 		// Close stdin to signal FFmpeg to stop
 		if p.stdin != nil {
@@ -140,20 +169,47 @@ func (p *Player) Stop() {
 		if p.cmd.Process != nil {
 			p.cmd.Process.Kill()
 		}
-
-		p.cmd = nil
 	}
+	done := p.done
+	p.mu.Unlock()
 
-	// Cancel the context
-	p.cancel()
+	<-done
 }
 
-// Wait blocks until playback is complete
+// Wait blocks until the current playback is complete
 func (p *Player) Wait() {
-	<-p.done
+	p.mu.Lock()
+	done := p.done
+	p.mu.Unlock()
+
+	<-done
+}
+
+// Drain stops accepting new plays and lets the current one finish, so ffmpeg
+// flushes what it buffered to ALSA instead of being cut off. Playback still
+// running when ctx is done is stopped. The player is closed afterwards.
+func (p *Player) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	done := p.done
+	p.mu.Unlock()
+
+	defer p.Close()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-// Close cleans up the player resources
+// Close stops any playback and permanently shuts the player down
 func (p *Player) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
 	p.Stop()
+	p.cancel()
 }
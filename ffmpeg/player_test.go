@@ -0,0 +1,141 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var fixtures = fstest.MapFS{
+	"audio/one.mp3": {Data: []byte("one")},
+	"audio/two.mp3": {Data: []byte("two")},
+}
+
+// fakeFFmpeg writes a shell script standing in for the ffmpeg binary and
+// returns its path
+func fakeFFmpeg(t *testing.T, script string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestPlayer returns a player running a fake ffmpeg made of the given
+// shell script instead of the real binary
+func newTestPlayer(t *testing.T, script string) *Player {
+	t.Helper()
+
+	p, err := NewPlayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.exec = fakeFFmpeg(t, script)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func waitOrFail(t *testing.T, p *Player) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		p.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return")
+	}
+}
+
+func TestPlayerBackToBack(t *testing.T) {
+	p := newTestPlayer(t, "cat > /dev/null")
+
+	for _, name := range []string{"audio/one.mp3", "audio/two.mp3"} {
+		if err := p.PlayMP3(fixtures, name); err != nil {
+			t.Fatalf("PlayMP3(%s) = %v", name, err)
+		}
+		waitOrFail(t, p)
+		if p.IsPlaying() {
+			t.Fatalf("player still active after %s finished", name)
+		}
+	}
+}
+
+func TestPlayerStopThenPlay(t *testing.T) {
+	p := newTestPlayer(t, "exec sleep 30")
+
+	if err := p.PlayMP3(fixtures, "audio/one.mp3"); err != nil {
+		t.Fatalf("PlayMP3() = %v", err)
+	}
+	if err := p.PlayMP3(fixtures, "audio/two.mp3"); err == nil {
+		t.Fatal("expected an error while another play is active")
+	}
+
+	p.Stop()
+	if p.IsPlaying() {
+		t.Fatal("player still active after Stop")
+	}
+
+	if err := p.PlayMP3(fixtures, "audio/two.mp3"); err != nil {
+		t.Fatalf("PlayMP3() after Stop = %v", err)
+	}
+	p.Stop()
+}
+
+func TestPlayerClosed(t *testing.T) {
+	p := newTestPlayer(t, "cat > /dev/null")
+	p.Close()
+
+	if err := p.PlayMP3(fixtures, "audio/one.mp3"); err == nil {
+		t.Fatal("expected an error playing on a closed player")
+	}
+	waitOrFail(t, p)
+}
+
+func TestPlayerDrainLetsPlayFinish(t *testing.T) {
+	p := newTestPlayer(t, "cat > /dev/null; sleep 0.2")
+
+	if err := p.PlayMP3(fixtures, "audio/one.mp3"); err != nil {
+		t.Fatalf("PlayMP3() = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := p.Drain(ctx); err != nil {
+		t.Fatalf("Drain() = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Drain returned after %s, before the play finished", elapsed)
+	}
+	if err := p.PlayMP3(fixtures, "audio/two.mp3"); err == nil {
+		t.Error("expected an error playing on a drained player")
+	}
+}
+
+func TestPlayerDrainDeadline(t *testing.T) {
+	p := newTestPlayer(t, "exec sleep 30")
+
+	if err := p.PlayMP3(fixtures, "audio/one.mp3"); err != nil {
+		t.Fatalf("PlayMP3() = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := p.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain() = %v, want DeadlineExceeded", err)
+	}
+	if p.IsPlaying() {
+		t.Error("player still active after Drain gave up")
+	}
+}
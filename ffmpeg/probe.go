@@ -0,0 +1,122 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProbeInfo describes the ffmpeg binary found by Probe
+type ProbeInfo struct {
+	Path    string // resolved path of the executable
+	Version string // e.g. "6.1.1-3ubuntu5"
+}
+
+var (
+	execMu   sync.RWMutex
+	execPath = Exec
+	detected *ProbeInfo
+)
+
+// SetExecPath sets the ffmpeg executable used by players and providers
+func SetExecPath(path string) {
+	execMu.Lock()
+	defer execMu.Unlock()
+	if path == "" {
+		path = Exec
+	}
+	execPath = path
+}
+
+// ExecPath returns the ffmpeg executable used by players and providers
+func ExecPath() string {
+	execMu.RLock()
+	defer execMu.RUnlock()
+	return execPath
+}
+
+// Detected returns the result of the last successful Probe
+func Detected() (ProbeInfo, bool) {
+	execMu.RLock()
+	defer execMu.RUnlock()
+	if detected == nil {
+		return ProbeInfo{}, false
+	}
+	return *detected, true
+}
+
+// Probe checks that path is an ffmpeg binary able to capture from and play
+// to ALSA devices. On success it becomes the executable used by the package.
+func Probe(ctx context.Context, path string) (ProbeInfo, error) {
+	if path == "" {
+		path = Exec
+	}
+
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return ProbeInfo{}, fmt.Errorf("ffmpeg not found at %q, install it or set audio.ffmpeg_path: %w", path, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, resolved, "-version").Output()
+	if err != nil {
+		return ProbeInfo{}, fmt.Errorf("failed to run %s -version: %w", resolved, err)
+	}
+	version, err := parseVersion(out)
+	if err != nil {
+		return ProbeInfo{}, fmt.Errorf("%s: %w", resolved, err)
+	}
+
+	out, err = exec.CommandContext(ctx, resolved, "-hide_banner", "-devices").Output()
+	if err != nil {
+		return ProbeInfo{}, fmt.Errorf("failed to list ffmpeg devices: %w", err)
+	}
+	if err := checkALSA(out); err != nil {
+		return ProbeInfo{}, fmt.Errorf("ffmpeg %s at %s: %w", version, resolved, err)
+	}
+
+	info := ProbeInfo{Path: resolved, Version: version}
+
+	execMu.Lock()
+	execPath = resolved
+	detected = &info
+	execMu.Unlock()
+
+	return info, nil
+}
+
+// parseVersion extracts the version from the output of ffmpeg -version
+func parseVersion(out []byte) (string, error) {
+	line, _, _ := bytes.Cut(out, []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) < 3 || fields[0] != "ffmpeg" || fields[1] != "version" {
+		return "", errors.New("not an ffmpeg binary, unexpected -version output")
+	}
+	return fields[2], nil
+}
+
+// checkALSA looks for an alsa device supporting both demuxing (capture) and
+// muxing (playback) in the output of ffmpeg -devices
+func checkALSA(out []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[1] != "alsa" {
+			continue
+		}
+		flags := fields[0]
+		if !strings.Contains(flags, "D") || !strings.Contains(flags, "E") {
+			return fmt.Errorf("alsa device is missing capture or playback support (flags %s)", flags)
+		}
+		return nil
+	}
+	return errors.New("built without ALSA support, the alsa device is missing")
+}
@@ -0,0 +1,59 @@
+package ffmpeg
+
+import (
+	"context"
+	"testing"
+)
+
+const devicesOutput = `Devices:
+ D. = Demuxing supported
+ .E = Muxing supported
+ ---
+ DE alsa            ALSA audio output
+  E fbdev           Linux framebuffer
+`
+
+func TestProbe(t *testing.T) {
+	script := `case "$1" in
+-version) echo "ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers" ;;
+*) cat <<'EOF'
+` + devicesOutput + `EOF
+;;
+esac`
+	info, err := Probe(context.Background(), fakeFFmpeg(t, script))
+	if err != nil {
+		t.Fatalf("Probe() = %v", err)
+	}
+	if info.Version != "6.1.1-3ubuntu5" {
+		t.Errorf("Version = %q", info.Version)
+	}
+	if detected, ok := Detected(); !ok || detected != info {
+		t.Errorf("Detected() = %+v, %v", detected, ok)
+	}
+	if ExecPath() != info.Path {
+		t.Errorf("ExecPath() = %q, want %q", ExecPath(), info.Path)
+	}
+	SetExecPath("")
+}
+
+func TestProbeFailures(t *testing.T) {
+	if _, err := Probe(context.Background(), "/nonexistent/ffmpeg"); err == nil {
+		t.Error("expected an error for a missing binary")
+	}
+
+	if _, err := Probe(context.Background(), fakeFFmpeg(t, "echo hello")); err == nil {
+		t.Error("expected an error for a binary that isn't ffmpeg")
+	}
+}
+
+func TestCheckALSA(t *testing.T) {
+	if err := checkALSA([]byte(devicesOutput)); err != nil {
+		t.Errorf("checkALSA() = %v", err)
+	}
+	if err := checkALSA([]byte(" D. = Demuxing supported\n  E fbdev  Linux framebuffer\n")); err == nil {
+		t.Error("expected an error without alsa")
+	}
+	if err := checkALSA([]byte(" D  alsa  ALSA audio input\n")); err == nil {
+		t.Error("expected an error for capture-only alsa")
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"os/exec"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/disgoorg/ffmpeg-audio"
 )
@@ -29,22 +31,122 @@ const (
 	Channels   = 2
 	SampleRate = 48000
 	BufferSize = 65307
+
+	// OutputFrame is the duration of the frames handed to the opus encoder,
+	// Discord's audio sender paces packets at this interval
+	OutputFrame = 20 * time.Millisecond
+
+	// RNNoiseModel is the noise suppression model applied to call audio
+	RNNoiseModel = "/opt/golte/std.rnnn"
+
+	// DefaultCaptureFrame is the default size of each read from ffmpeg
+	DefaultCaptureFrame = 20 * time.Millisecond
+
+	// DefaultUnderrunGrace is how long the capture may stall before the
+	// provider gives up and reports EOF
+	DefaultUnderrunGrace = 200 * time.Millisecond
+
+	// restartGrace is how long a relaunched capture may take to deliver
+	restartGrace = 2 * time.Second
+
+	// DefaultDevice is the ALSA device of the modem's sound card
+	DefaultDevice = "hw:2,0"
+
+	// frameQueue is how many output frames may wait for the encoder
+	frameQueue = 10
 )
 
+// ValidCaptureFrame reports whether d is a frame duration opus supports
+func ValidCaptureFrame(d time.Duration) bool {
+	switch d {
+	case 10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 60 * time.Millisecond:
+		return true
+	}
+	return false
+}
+
+// ProviderConfig tunes how the capture is read
+type ProviderConfig struct {
+	// CaptureFrame is the size of each read from ffmpeg, one of 10, 20, 40
+	// or 60ms. Frames are always re-cut to OutputFrame for the encoder.
+	CaptureFrame time.Duration
+
+	// UnderrunGrace is how long silence is sent while the capture stalls
+	// before the provider reports EOF
+	UnderrunGrace time.Duration
+
+	// Device is the ALSA capture device, DefaultDevice if empty
+	Device string
+}
+
+// ProviderStats counts the frames served by an AudioProvider
+type ProviderStats struct {
+	Frames    uint64 // frames read from the capture
+	Underruns uint64 // silent frames sent because the capture was late
+
+	// LastSound is when the capture last carried a non-zero sample, zero if
+	// it never did
+	LastSound time.Time
+}
+
 var _ FrameProvider = (*AudioProvider)(nil)
 
-func New(ctx context.Context, opts ...ffmpeg.ConfigOpt) (*AudioProvider, error) {
+func New(ctx context.Context, pcfg ProviderConfig, opts ...ffmpeg.ConfigOpt) (*AudioProvider, error) {
 	cfg := ffmpeg.DefaultConfig()
+	cfg.Exec = ExecPath()
 	cfg.Apply(opts)
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = BufferSize
+	}
 
-	cmd := exec.CommandContext(ctx, cfg.Exec,
+	if pcfg.CaptureFrame == 0 {
+		pcfg.CaptureFrame = DefaultCaptureFrame
+	}
+	if !ValidCaptureFrame(pcfg.CaptureFrame) {
+		return nil, fmt.Errorf("unsupported capture frame duration %s", pcfg.CaptureFrame)
+	}
+	if pcfg.UnderrunGrace == 0 {
+		pcfg.UnderrunGrace = DefaultUnderrunGrace
+	}
+	if pcfg.Device == "" {
+		pcfg.Device = DefaultDevice
+	}
+
+	done, doneFunc := context.WithCancel(context.Background())
+	p := &AudioProvider{
+		ctx:        ctx,
+		cfg:        cfg,
+		device:     pcfg.Device,
+		channels:   cfg.Channels,
+		captureLen: samplesIn(pcfg.CaptureFrame, cfg.SampleRate) * cfg.Channels,
+		frameLen:   samplesIn(OutputFrame, cfg.SampleRate) * cfg.Channels,
+		grace:      pcfg.UnderrunGrace,
+		frames:     make(chan []int16, frameQueue),
+		done:       done,
+		doneFunc:   doneFunc,
+	}
+
+	proc, err := p.start(pcfg.Device)
+	if err != nil {
+		doneFunc()
+		return nil, err
+	}
+	p.proc = proc
+	go p.readLoop(proc)
+
+	return p, nil
+}
+
+// start launches an ffmpeg process capturing from device
+func (p *AudioProvider) start(device string) (*captureProcess, error) {
+	cmd := exec.CommandContext(p.ctx, p.cfg.Exec,
 		"-thread_queue_size", "512",
 		"-f", "alsa",
-		"-channels", strconv.Itoa(cfg.Channels),
-		"-i", "hw:2,0",
-		"-ac", strconv.Itoa(cfg.Channels),
-		"-ar", strconv.Itoa(cfg.SampleRate),
-		"-af", "afftdn=nr=10,arnndn=m=/opt/golte/std.rnnn,lowpass=f=6000,highpass=f=150,volume=0.5",
+		"-channels", strconv.Itoa(p.cfg.Channels),
+		"-i", device,
+		"-ac", strconv.Itoa(p.cfg.Channels),
+		"-ar", strconv.Itoa(p.cfg.SampleRate),
+		"-af", "afftdn=nr=10,arnndn=m="+RNNoiseModel+",lowpass=f=6000,highpass=f=150,volume=0.5",
 		"-f", "s16le",
 		"-fflags", "+genpts+igndts",
 		"-avoid_negative_ts", "make_zero",
@@ -60,69 +162,220 @@ func New(ctx context.Context, opts ...ffmpeg.ConfigOpt) (*AudioProvider, error)
 		return nil, err
 	}
 
-	done, doneFunc := context.WithCancel(context.Background())
-	return &AudioProvider{
-		cmd:      cmd,
-		pipe:     pipe,
-		reader:   bufio.NewReaderSize(pipe, cfg.BufferSize),
-		channels: cfg.Channels,
-		done:     done,
-		doneFunc: doneFunc,
+	return &captureProcess{
+		cmd:    cmd,
+		pipe:   pipe,
+		reader: bufio.NewReaderSize(pipe, p.cfg.BufferSize),
+		exited: make(chan struct{}),
 	}, nil
 }
 
+// samplesIn returns the number of samples per channel in d
+func samplesIn(d time.Duration, sampleRate int) int {
+	return int(d * time.Duration(sampleRate) / time.Second)
+}
+
+// captureProcess is one ffmpeg capture, replaced when the device changes
+type captureProcess struct {
+	cmd    *exec.Cmd
+	pipe   io.Closer
+	reader *bufio.Reader
+	exited chan struct{}
+	err    error // exit status, set before exited is closed
+}
+
 type AudioProvider struct {
-	cmd      *exec.Cmd
-	pipe     io.Closer
-	reader   *bufio.Reader
-	channels int
-	done     context.Context
-	doneFunc context.CancelFunc
+	ctx        context.Context
+	cfg        *ffmpeg.Config
+	channels   int
+	captureLen int
+	frameLen   int
+	grace      time.Duration
+	frames     chan []int16
+	done       context.Context
+	doneFunc   context.CancelFunc
+
+	mu      sync.Mutex
+	proc    *captureProcess
+	device  string
+	readErr error // why the current capture stopped, nil while it runs
+
+	// stalledSince is when the capture started under-running, only touched
+	// by ProvidePCMFrame
+	stalledSince time.Time
+
+	framesRead atomic.Uint64
+	underruns  atomic.Uint64
+	lastSound  atomic.Int64
+	restarted  atomic.Int64
 }
 
-func (p *AudioProvider) ProvidePCMFrame() ([]int16, error) {
-	// Calculate frame size: 960 samples per frame * channels * 2 bytes per sample
-	frameSize := 960 * p.channels * 2
-	buf := make([]byte, frameSize)
+// endCapture records why proc, the capture that stopped with err, ended when
+// it's still current. The frames channel stays open: until the underrun
+// grace runs out the provider sends silence and ReconfigureDevice can
+// relaunch the capture, e.g. when ffmpeg exited as the sound card went away.
+func (p *AudioProvider) endCapture(proc *captureProcess, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.proc != proc {
+		return
+	}
+	if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, os.ErrClosed) {
+		p.readErr = fmt.Errorf("error reading PCM data: %w", err)
+	} else {
+		p.readErr = io.EOF
+	}
+}
+
+// captureErr returns why the current capture stopped, nil while it runs
+func (p *AudioProvider) captureErr() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.readErr
+}
+
+// readLoop reads the capture in CaptureFrame chunks and re-cuts it into
+// output frames until ffmpeg closes its output
+func (p *AudioProvider) readLoop(proc *captureProcess) {
+	defer func() {
+		proc.err = proc.cmd.Wait()
+		close(proc.exited)
+	}()
+
+	buf := make([]byte, p.captureLen*2)
+	var pending []int16
+	for {
+		if _, err := io.ReadFull(proc.reader, buf); err != nil {
+			p.endCapture(proc, err)
+			return
+		}
+
+		// Convert bytes to int16 samples
+		sound := false
+		for i := 0; i < len(buf); i += 2 {
+			sample := int16(binary.LittleEndian.Uint16(buf[i : i+2]))
+			sound = sound || sample != 0
+			pending = append(pending, sample)
+		}
+		if sound {
+			p.lastSound.Store(time.Now().UnixNano())
+		}
+
+		for len(pending) >= p.frameLen {
+			frame := make([]int16, p.frameLen)
+			copy(frame, pending)
+			pending = append(pending[:0], pending[p.frameLen:]...)
+
+			select {
+			case p.frames <- frame:
+			case <-p.done.Done():
+				return
+			}
+		}
+	}
+}
 
-	_, err := io.ReadFull(p.reader, buf)
+// ReconfigureDevice relaunches the capture against device, e.g. after the
+// sound card re-enumerated. The frames already read are still served and the
+// provider stays usable throughout, the gap is covered like any stall. A
+// capture whose ffmpeg already exited can be relaunched too, until the
+// provider gave up on it or was closed.
+func (p *AudioProvider) ReconfigureDevice(device string) error {
+	p.mu.Lock()
+	if p.done.Err() != nil {
+		p.mu.Unlock()
+		return errors.New("capture is closed")
+	}
+
+	proc, err := p.start(device)
 	if err != nil {
-		if errors.Is(err, io.EOF) || errors.Is(err, os.ErrClosed) {
+		p.mu.Unlock()
+		return fmt.Errorf("failed to start capture on %s: %w", device, err)
+	}
+	old := p.proc
+	p.proc = proc
+	p.device = device
+	p.readErr = nil
+	p.restarted.Store(time.Now().UnixNano())
+	p.mu.Unlock()
+
+	go p.readLoop(proc)
+
+	// The old process may be stuck on a dead device, don't wait for it
+	_ = old.pipe.Close()
+	if old.cmd.Process != nil {
+		_ = old.cmd.Process.Kill()
+	}
+	return nil
+}
+
+// Device returns the ALSA device currently captured
+func (p *AudioProvider) Device() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.device
+}
+
+func (p *AudioProvider) ProvidePCMFrame() ([]int16, error) {
+	// Wait up to half a frame so a slightly late read doesn't cost a packet
+	timer := time.NewTimer(OutputFrame / 2)
+	defer timer.Stop()
+
+	select {
+	case frame := <-p.frames:
+		p.stalledSince = time.Time{}
+		p.framesRead.Add(1)
+		return frame, nil
+
+	case <-timer.C:
+		// Tolerate a short ALSA hiccup, or ffmpeg exiting as the device
+		// goes away, by sending silence, but don't keep the call up forever
+		// on a capture that's gone or died without closing its pipe
+		now := time.Now()
+		if p.stalledSince.IsZero() {
+			p.stalledSince = now
+		} else if now.Sub(p.stalledSince) > p.grace && now.Sub(time.Unix(0, p.restarted.Load())) > restartGrace {
 			p.doneFunc()
+			if err := p.captureErr(); err != nil {
+				return nil, err
+			}
 			return nil, io.EOF
 		}
-		return nil, fmt.Errorf("error reading PCM data: %w", err)
+		p.underruns.Add(1)
+		return make([]int16, p.frameLen), nil
 	}
+}
 
-	// Convert bytes to int16 samples
-	samples := make([]int16, len(buf)/2)
-	for i := 0; i < len(samples); i++ {
-		samples[i] = int16(binary.LittleEndian.Uint16(buf[i*2 : i*2+2]))
+// Stats returns the frame counters of the provider
+func (p *AudioProvider) Stats() ProviderStats {
+	stats := ProviderStats{
+		Frames:    p.framesRead.Load(),
+		Underruns: p.underruns.Load(),
 	}
-
-	return samples, nil
+	if last := p.lastSound.Load(); last != 0 {
+		stats.LastSound = time.Unix(0, last)
+	}
+	return stats
 }
 
 func (p *AudioProvider) Close() {
-	_ = p.pipe.Close()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_ = p.proc.pipe.Close()
 	p.doneFunc()
 }
 
+// Wait blocks until the provider is done and returns the exit status of the
+// last capture process
 func (p *AudioProvider) Wait() error {
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		<-p.done.Done()
-	}()
+	<-p.done.Done()
 
-	var err error
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		err = p.cmd.Wait()
-	}()
+	p.mu.Lock()
+	proc := p.proc
+	p.mu.Unlock()
 
-	wg.Wait()
-	return err
+	<-proc.exited
+	return proc.err
 }
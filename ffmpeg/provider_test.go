@@ -0,0 +1,147 @@
+package ffmpeg
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/ffmpeg-audio"
+)
+
+func TestAudioProviderRecutsFrames(t *testing.T) {
+	// Three 40ms stereo reads make six 20ms frames
+	exec := fakeFFmpeg(t, "head -c 23040 /dev/zero")
+	p, err := New(context.Background(), ProviderConfig{CaptureFrame: 40 * time.Millisecond}, ffmpeg.WithExec(exec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	for i := 0; i < 6; i++ {
+		frame, err := p.ProvidePCMFrame()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if len(frame) != 960*Channels {
+			t.Fatalf("frame %d has %d samples, want %d", i, len(frame), 960*Channels)
+		}
+	}
+
+	// The capture ended, so after at most the grace period it's an EOF
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := p.ProvidePCMFrame(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("expected io.EOF, got %v", err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("provider never reported EOF")
+		}
+	}
+
+	if stats := p.Stats(); stats.Frames != 6 {
+		t.Errorf("Frames = %d, want 6", stats.Frames)
+	}
+}
+
+func TestAudioProviderToleratesStall(t *testing.T) {
+	// One frame, a 100ms stall, then another frame
+	exec := fakeFFmpeg(t, "head -c 3840 /dev/zero; sleep 0.1; head -c 3840 /dev/zero; exec sleep 30")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, err := New(ctx, ProviderConfig{UnderrunGrace: time.Second}, ffmpeg.WithExec(exec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for p.Stats().Frames < 2 {
+		frame, err := p.ProvidePCMFrame()
+		if err != nil {
+			t.Fatalf("stall within the grace period ended the stream: %v", err)
+		}
+		if len(frame) != 960*Channels {
+			t.Fatalf("got a %d sample frame", len(frame))
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("second frame never arrived")
+		}
+	}
+
+	if p.Stats().Underruns == 0 {
+		t.Error("expected the stall to be counted as underruns")
+	}
+}
+
+func TestAudioProviderRejectsFrameSize(t *testing.T) {
+	if _, err := New(context.Background(), ProviderConfig{CaptureFrame: 25 * time.Millisecond}); err == nil {
+		t.Error("expected an error for a 25ms capture frame")
+	}
+}
+
+func TestAudioProviderReconfigureDevice(t *testing.T) {
+	// The old device hangs without delivering, the new one has audio
+	exec := fakeFFmpeg(t, `case "$*" in *hw:old*) exec sleep 30;; esac; head -c 38400 /dev/urandom; exec sleep 30`)
+	p, err := New(context.Background(), ProviderConfig{Device: "hw:old"}, ffmpeg.WithExec(exec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if err := p.ReconfigureDevice("hw:new"); err != nil {
+		t.Fatalf("ReconfigureDevice() = %v", err)
+	}
+	if got := p.Device(); got != "hw:new" {
+		t.Errorf("Device() = %q, want hw:new", got)
+	}
+
+	// Frames from the new capture arrive without the stream ending
+	deadline := time.Now().Add(3 * time.Second)
+	for p.Stats().Frames < 5 {
+		if _, err := p.ProvidePCMFrame(); err != nil {
+			t.Fatalf("stream ended across the device change: %v", err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the new capture never delivered")
+		}
+	}
+	if p.Stats().LastSound.IsZero() {
+		t.Error("expected the new capture to be heard")
+	}
+}
+
+func TestAudioProviderReopensAfterExit(t *testing.T) {
+	// The old device's ffmpeg exits, as when the sound card goes away
+	exec := fakeFFmpeg(t, `case "$*" in *hw:old*) head -c 3840 /dev/zero; exit 1;; esac; head -c 38400 /dev/urandom; exec sleep 30`)
+	p, err := New(context.Background(), ProviderConfig{Device: "hw:old", UnderrunGrace: time.Second}, ffmpeg.WithExec(exec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// Wait for the capture to end
+	deadline := time.Now().Add(2 * time.Second)
+	for p.captureErr() == nil {
+		if _, err := p.ProvidePCMFrame(); err != nil {
+			t.Fatalf("stream ended within the grace period: %v", err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the old capture never ended")
+		}
+	}
+
+	if err := p.ReconfigureDevice("hw:new"); err != nil {
+		t.Fatalf("ReconfigureDevice() after ffmpeg exited = %v", err)
+	}
+	for p.Stats().LastSound.IsZero() {
+		if _, err := p.ProvidePCMFrame(); err != nil {
+			t.Fatalf("stream ended across the device change: %v", err)
+		}
+		if time.Now().After(deadline.Add(2 * time.Second)) {
+			t.Fatal("the new capture never delivered")
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/disgoorg/disgo/voice"
+)
+
+// VADConfig tunes the voice activity detection gating the call audio
+type VADConfig struct {
+	// ThresholdDB is the frame RMS level, in dBFS, counted as voice
+	ThresholdDB float64
+
+	// Hangover is how long transmission continues once the level drops
+	Hangover time.Duration
+
+	// PreRoll is how much audio from before the voice started is sent along,
+	// so word onsets aren't clipped. The call audio is delayed by as much.
+	PreRoll time.Duration
+}
+
+// VAD gates OutputFrame sized PCM frames on their energy. Frames go through a
+// delay line of PreRoll so the start of a word can still be sent once it's
+// detected.
+type VAD struct {
+	threshold  float64 // RMS amplitude as a fraction of full scale
+	preRoll    int     // in frames
+	hangover   int     // in frames
+	delay      [][]int16
+	sinceVoice int
+}
+
+// NewVAD creates a VAD, it starts out silent
+func NewVAD(cfg VADConfig) *VAD {
+	return &VAD{
+		threshold:  math.Pow(10, cfg.ThresholdDB/20),
+		preRoll:    int(cfg.PreRoll / OutputFrame),
+		hangover:   int((cfg.Hangover + OutputFrame - 1) / OutputFrame),
+		sinceVoice: math.MaxInt32,
+	}
+}
+
+// Process takes the newest captured frame and returns the frame to transmit,
+// PreRoll older, or nil while nobody speaks
+func (v *VAD) Process(frame []int16) []int16 {
+	if frameLevel(frame) >= v.threshold {
+		v.sinceVoice = 0
+	} else if v.sinceVoice < math.MaxInt32 {
+		v.sinceVoice++
+	}
+
+	v.delay = append(v.delay, slices.Clone(frame))
+	if len(v.delay) <= v.preRoll {
+		return nil
+	}
+	out := v.delay[0]
+	v.delay = v.delay[1:]
+
+	// The outgoing frame is voice, precedes voice by at most the pre-roll or
+	// follows it by at most the hangover
+	if v.sinceVoice > v.preRoll+v.hangover {
+		return nil
+	}
+	return out
+}
+
+// frameLevel returns the RMS level of a frame as a fraction of full scale
+func frameLevel(frame []int16) float64 {
+	if len(frame) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, s := range frame {
+		f := float64(s) / 32768
+		sum += f * f
+	}
+	return math.Sqrt(sum / float64(len(frame)))
+}
+
+// Encoder encodes PCM frames into opus, like *opus.Encoder. Providers
+// destroy their encoder when closed.
+type Encoder interface {
+	Encode(pcm []int16, data []byte) (int, error)
+	Destroy()
+}
+
+// VADOpusProvider encodes the capture for Discord while the VAD hears voice
+// and sends nothing otherwise, which lets the voice sender clear the
+// speaking flag
+type VADOpusProvider struct {
+	encoder  Encoder
+	provider FrameProvider
+	vad      *VAD
+	buf      []byte
+}
+
+var _ voice.OpusFrameProvider = (*VADOpusProvider)(nil)
+
+// NewVADOpusProvider gates the frames of provider with vad before encoding
+func NewVADOpusProvider(encoder Encoder, provider FrameProvider, vad *VAD) *VADOpusProvider {
+	return &VADOpusProvider{
+		encoder:  encoder,
+		provider: provider,
+		vad:      vad,
+		buf:      make([]byte, 2048),
+	}
+}
+
+func (p *VADOpusProvider) ProvideOpusFrame() ([]byte, error) {
+	frame, err := p.provider.ProvidePCMFrame()
+	if err != nil {
+		return nil, err
+	}
+	if len(frame) == 0 {
+		return nil, io.EOF
+	}
+
+	if p.vad != nil {
+		if frame = p.vad.Process(frame); frame == nil {
+			return nil, nil
+		}
+	}
+
+	n, err := p.encoder.Encode(frame, p.buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode opus frame: %w", err)
+	}
+	return p.buf[:n], nil
+}
+
+// NewOpusProvider encodes every frame of provider, without a VAD
+func NewOpusProvider(encoder Encoder, provider FrameProvider) *VADOpusProvider {
+	return NewVADOpusProvider(encoder, provider, nil)
+}
+
+func (p *VADOpusProvider) Close() {
+	p.encoder.Destroy()
+	p.provider.Close()
+}
@@ -0,0 +1,115 @@
+package ffmpeg
+
+import (
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// sineFrame returns an OutputFrame of stereo 440Hz at the given dBFS level,
+// marked with id in its first sample so frames can be told apart
+func sineFrame(levelDB float64, id int16) []int16 {
+	n := samplesIn(OutputFrame, SampleRate)
+	amplitude := math.Pow(10, levelDB/20) * math.Sqrt2 * 32767
+	frame := make([]int16, n*Channels)
+	for i := 0; i < n; i++ {
+		s := int16(amplitude * math.Sin(2*math.Pi*440*float64(i)/SampleRate))
+		frame[i*2], frame[i*2+1] = s, s
+	}
+	frame[0] = id
+	return frame
+}
+
+func TestVADGatesSpeech(t *testing.T) {
+	vad := NewVAD(VADConfig{
+		ThresholdDB: -40,
+		Hangover:    100 * time.Millisecond,
+		PreRoll:     40 * time.Millisecond,
+	})
+
+	// 10 frames of silence, 5 of speech, then silence again
+	var sent []int16
+	for i := 0; i < 40; i++ {
+		level := -90.0
+		if i >= 10 && i < 15 {
+			level = -20
+		}
+		if out := vad.Process(sineFrame(level, int16(i))); out != nil {
+			sent = append(sent, out[0])
+		}
+	}
+
+	// Two frames of pre-roll, the speech and five frames of hangover
+	if len(sent) != 12 {
+		t.Fatalf("sent %d frames (%v), want 12", len(sent), sent)
+	}
+	for i, id := range sent {
+		if id != int16(8+i) {
+			t.Fatalf("frame %d is input %d, want %d", i, id, 8+i)
+		}
+	}
+}
+
+func TestVADIgnoresNoiseBelowThreshold(t *testing.T) {
+	vad := NewVAD(VADConfig{ThresholdDB: -40, Hangover: 200 * time.Millisecond})
+
+	for i := 0; i < 50; i++ {
+		if out := vad.Process(sineFrame(-50, 0)); out != nil {
+			t.Fatalf("frame %d below the threshold was transmitted", i)
+		}
+	}
+
+	// Without pre-roll speech goes out as soon as it's heard
+	if vad.Process(sineFrame(-30, 0)) == nil {
+		t.Fatal("speech above the threshold was not transmitted")
+	}
+}
+
+// fakeEncoder "encodes" a frame into its first sample
+type fakeEncoder struct{ destroyed bool }
+
+func (e *fakeEncoder) Encode(pcm []int16, data []byte) (int, error) {
+	data[0] = byte(pcm[0])
+	return 1, nil
+}
+
+func (e *fakeEncoder) Destroy() { e.destroyed = true }
+
+// frames provides its frames then EOF
+type frames struct {
+	frames [][]int16
+	closed bool
+}
+
+func (f *frames) ProvidePCMFrame() ([]int16, error) {
+	if len(f.frames) == 0 {
+		return nil, nil
+	}
+	frame := f.frames[0]
+	f.frames = f.frames[1:]
+	return frame, nil
+}
+
+func (f *frames) Close() { f.closed = true }
+
+func TestOpusProvider(t *testing.T) {
+	encoder := &fakeEncoder{}
+	source := &frames{frames: [][]int16{sineFrame(-90, 1), sineFrame(-10, 2)}}
+	p := NewOpusProvider(encoder, source)
+
+	// Without a VAD silence is sent too
+	for _, want := range []byte{1, 2} {
+		if packet, err := p.ProvideOpusFrame(); err != nil || len(packet) != 1 || packet[0] != want {
+			t.Fatalf("ProvideOpusFrame() = %v, %v, want [%d]", packet, err, want)
+		}
+	}
+	if _, err := p.ProvideOpusFrame(); err != io.EOF {
+		t.Errorf("ProvideOpusFrame() at the end = %v, want EOF", err)
+	}
+
+	p.Close()
+	if !encoder.destroyed || !source.closed {
+		t.Error("Close() didn't destroy the encoder and close the source")
+	}
+}
@@ -0,0 +1,31 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestWriteWAV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteWAV(&buf, []int16{1, -1, 256}, 48000, 1); err != nil {
+		t.Fatalf("WriteWAV() = %v", err)
+	}
+
+	out := buf.Bytes()
+	if len(out) != 44+6 {
+		t.Fatalf("WriteWAV() wrote %d bytes, want 50", len(out))
+	}
+	if string(out[0:4]) != "RIFF" || string(out[8:16]) != "WAVEfmt " || string(out[36:40]) != "data" {
+		t.Errorf("WriteWAV() header = %q", out[:44])
+	}
+	if size := binary.LittleEndian.Uint32(out[4:8]); size != 42 {
+		t.Errorf("RIFF size = %d, want 42", size)
+	}
+	if rate := binary.LittleEndian.Uint32(out[28:32]); rate != 96000 {
+		t.Errorf("byte rate = %d, want 96000", rate)
+	}
+	if !bytes.Equal(out[44:], []byte{1, 0, 0xff, 0xff, 0, 1}) {
+		t.Errorf("samples = % x", out[44:])
+	}
+}
@@ -0,0 +1,173 @@
+// Package health records the health of a running bridge in a state file, so
+// another process, like golte healthcheck in a Docker HEALTHCHECK, can tell
+// whether it's okay without talking to it.
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State is what the bridge knew of its health when it last checked
+type State struct {
+	Checked time.Time `json:"checked"`
+	Modem   string    `json:"modem,omitempty"`   // why the modem isn't ok, empty if it is
+	Network string    `json:"network,omitempty"` // why the modem isn't registered, empty if it is
+	Discord string    `json:"discord,omitempty"` // why Discord isn't ok, empty if it is
+	LastSMS time.Time `json:"last_sms"`          // when the last SMS was received, zero if none was
+
+	Telegram string `json:"telegram,omitempty"` // why Telegram isn't ok, empty if it is or isn't used
+
+	Components []Component `json:"components,omitempty"` // the same problems, with since when
+}
+
+// Components of the bridge
+const (
+	ComponentModem    = "modem"
+	ComponentNetwork  = "network"
+	ComponentDiscord  = "discord"
+	ComponentTelegram = "telegram"
+)
+
+// Component is the health of one part of the bridge
+type Component struct {
+	Name    string    `json:"name"`
+	Problem string    `json:"problem,omitempty"` // empty when healthy
+	Since   time.Time `json:"since"`             // when it became healthy, or unhealthy
+}
+
+// Unhealthy returns the components with a problem
+func (s State) Unhealthy() []Component {
+	var unhealthy []Component
+	for _, c := range s.Components {
+		if c.Problem != "" {
+			unhealthy = append(unhealthy, c)
+		}
+	}
+	return unhealthy
+}
+
+// Err joins the problems of the state that mean the bridge is stuck, nil if
+// there are none. Being out of coverage isn't one, see Unhealthy.
+func (s State) Err() error {
+	var errs []error
+	if s.Modem != "" {
+		errs = append(errs, fmt.Errorf("modem: %s", s.Modem))
+	}
+	if s.Discord != "" {
+		errs = append(errs, fmt.Errorf("discord: %s", s.Discord))
+	}
+	if s.Telegram != "" {
+		errs = append(errs, fmt.Errorf("telegram: %s", s.Telegram))
+	}
+	return errors.Join(errs...)
+}
+
+// Summary describes the state on one line, e.g.
+// modem ok, discord ok, last SMS rx 2m ago
+func (s State) Summary(now time.Time) string {
+	parts := []string{describe("modem", s.Modem), describe("discord", s.Discord)}
+	if s.Telegram != "" {
+		parts = append(parts, describe("telegram", s.Telegram))
+	}
+	if s.LastSMS.IsZero() {
+		parts = append(parts, "no SMS rx yet")
+	} else {
+		parts = append(parts, "last SMS rx "+Ago(now.Sub(s.LastSMS)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describe(name, problem string) string {
+	if problem == "" {
+		return name + " ok"
+	}
+	return name + " " + problem
+}
+
+// Ago formats how long ago something happened with a single unit, e.g. 2m
+// ago
+func Ago(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+// Tracker remembers since when each component has been healthy or not
+type Tracker struct {
+	mu         sync.Mutex
+	components map[string]Component
+}
+
+// NewTracker returns a tracker knowing no component yet
+func NewTracker() *Tracker {
+	return &Tracker{components: make(map[string]Component)}
+}
+
+// Update records the problem of the component checked at now, empty if
+// it's healthy. Since only moves when it becomes healthy or unhealthy.
+func (t *Tracker) Update(name, problem string, now time.Time) Component {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.components[name]
+	if !ok || (c.Problem == "") != (problem == "") {
+		c.Since = now
+	}
+	c.Name, c.Problem = name, problem
+	t.components[name] = c
+	return c
+}
+
+// Write replaces the state file at path. The file is renamed into place so
+// readers never see it half written.
+func Write(path string, s State) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write the health state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write the health state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write the health state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write the health state: %w", err)
+	}
+	return nil
+}
+
+// Read returns the state in the file at path
+func Read(path string) (State, error) {
+	var s State
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("invalid health state in %s: %w", path, err)
+	}
+	return s, nil
+}
@@ -0,0 +1,87 @@
+package health
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golte-health.json")
+	if _, err := Read(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Read() of a missing file = %v", err)
+	}
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	want := State{Checked: now, Discord: "gateway reconnecting", LastSMS: now.Add(-2 * time.Minute)}
+	if err := Write(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Checked.Equal(want.Checked) || !got.LastSMS.Equal(want.LastSMS) || got.Modem != "" || got.Discord != want.Discord {
+		t.Errorf("Read() = %+v, want %+v", got, want)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Write() left %d files behind", len(entries))
+	}
+}
+
+func TestSummary(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		state State
+		want  string
+	}{
+		{State{LastSMS: now.Add(-2*time.Minute - 5*time.Second)}, "modem ok, discord ok, last SMS rx 2m ago"},
+		{State{Modem: "not responding"}, "modem not responding, discord ok, no SMS rx yet"},
+		{State{Discord: "gateway reconnecting", LastSMS: now.Add(-72 * time.Hour)}, "modem ok, discord gateway reconnecting, last SMS rx 3d ago"},
+		{State{Telegram: "Bot API unreachable"}, "modem ok, discord ok, telegram Bot API unreachable, no SMS rx yet"},
+	}
+	for _, tt := range tests {
+		if got := tt.state.Summary(now); got != tt.want {
+			t.Errorf("Summary() = %q, want %q", got, tt.want)
+		}
+	}
+
+	if err := (State{}).Err(); err != nil {
+		t.Errorf("Err() of a healthy state = %v", err)
+	}
+	if err := (State{Modem: "not responding"}).Err(); err == nil || err.Error() != "modem: not responding" {
+		t.Errorf("Err() = %v", err)
+	}
+	if err := (State{Telegram: "Bot API unreachable"}).Err(); err == nil || err.Error() != "telegram: Bot API unreachable" {
+		t.Errorf("Err() = %v", err)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker()
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	if c := tracker.Update(ComponentModem, "", start); !c.Since.Equal(start) {
+		t.Errorf("first update since %v, want %v", c.Since, start)
+	}
+	if c := tracker.Update(ComponentModem, "", start.Add(time.Minute)); !c.Since.Equal(start) {
+		t.Errorf("still healthy since %v, want %v", c.Since, start)
+	}
+	down := start.Add(2 * time.Minute)
+	tracker.Update(ComponentModem, "not responding", down)
+	if c := tracker.Update(ComponentModem, "not responding: timeout", down.Add(time.Minute)); !c.Since.Equal(down) || c.Problem != "not responding: timeout" {
+		t.Errorf("still unhealthy = %+v, want since %v", c, down)
+	}
+
+	s := State{Components: []Component{{Name: ComponentModem}, {Name: ComponentNetwork, Problem: "searching"}}}
+	if unhealthy := s.Unhealthy(); len(unhealthy) != 1 || unhealthy[0].Name != ComponentNetwork {
+		t.Errorf("Unhealthy() = %+v", unhealthy)
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("Err() of a state out of coverage = %v", err)
+	}
+}
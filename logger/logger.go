@@ -1,48 +1,137 @@
 package logger
 
 import (
+	"io"
 	"log/slog"
+	"math"
 	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
-// Setup configures the global logger based on the provided configuration
+// logLevel is shared by the handlers Setup creates, so SetLevel also affects
+// the loggers derived from them before the change
+var logLevel = new(slog.LevelVar)
+
+// Output is where the logs are written, besides the records kept in memory
+type Output struct {
+	Stdout bool
+	File   string // path of a log file, empty for none
+
+	// Rotation of File, see RotatingFile
+	MaxSize    int64 // in bytes, 0 doesn't rotate
+	MaxBackups int
+	MaxAge     time.Duration // 0 keeps the backups whatever their age
+}
+
+// logFile is the file of the Output last set up, nil if none
+var (
+	logFileMu sync.Mutex
+	logFile   *RotatingFile
+)
+
+// Setup configures the global logger based on the provided configuration,
+// writing to stdout
 func Setup(level, format string) error {
-	// Parse log level
-	var logLevel slog.Level
-	switch strings.ToLower(level) {
-	case "debug":
-		logLevel = slog.LevelDebug
-	case "info":
-		logLevel = slog.LevelInfo
-	case "warn", "warning":
-		logLevel = slog.LevelWarn
-	case "error":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
+	return SetupOutput(level, format, Output{Stdout: true})
+}
+
+// SetupOutput is Setup writing to output. A file that can't be opened is
+// left out with a warning, stdout taking its place.
+func SetupOutput(level, format string, output Output) error {
+	SetLevel(level)
+
+	var (
+		file    *RotatingFile
+		fileErr error
+	)
+	if output.File != "" {
+		maxSize := output.MaxSize
+		if maxSize <= 0 {
+			maxSize = math.MaxInt64
+		}
+		if file, fileErr = OpenRotatingFile(output.File, maxSize, output.MaxBackups); fileErr == nil {
+			file.SetMaxAge(output.MaxAge)
+		} else {
+			output.Stdout = true
+		}
+	}
+
+	logFileMu.Lock()
+	if logFile != nil {
+		logFile.Close()
+	}
+	logFile = file
+	logFileMu.Unlock()
+
+	// One handler per target, in the same format
+	var handlers teeHandler
+	if output.Stdout || file == nil {
+		handlers = append(handlers, newHandler(os.Stdout, format))
+	}
+	if file != nil {
+		handlers = append(handlers, newHandler(file, format))
+	}
+
+	// Also kept in memory for /logs, all redacted per logging.redaction
+	handlers = append(handlers, &ringHandler{ring: recent, level: logLevel})
+	handler := redactHandler{handlers}
+
+	// Set the global logger
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+
+	if fileErr != nil {
+		logger.Warn("Failed to open the log file, logging to stdout",
+			slog.String("path", output.File),
+			slog.Any("error", fileErr))
 	}
+	return nil
+}
 
-	// Create handler based on format
-	var handler slog.Handler
+// newHandler creates the handler of format writing to w
+func newHandler(w io.Writer, format string) slog.Handler {
 	opts := &slog.HandlerOptions{
 		Level: logLevel,
 	}
 
 	switch strings.ToLower(format) {
 	case "json":
-		handler = slog.NewJSONHandler(os.Stdout, opts)
+		return slog.NewJSONHandler(w, opts)
 	case "text":
-		handler = slog.NewTextHandler(os.Stdout, opts)
+		return slog.NewTextHandler(w, opts)
 	default:
-		handler = slog.NewTextHandler(os.Stdout, opts)
+		return slog.NewTextHandler(w, opts)
 	}
+}
 
-	// Set the global logger
-	logger := slog.New(handler)
-	slog.SetDefault(logger)
+// Reopen opens the log file again, once logrotate moved it away. It does
+// nothing without a log file.
+func Reopen() error {
+	logFileMu.Lock()
+	defer logFileMu.Unlock()
 
-	return nil
+	if logFile == nil {
+		return nil
+	}
+	return logFile.Reopen()
+}
+
+// SetLevel changes the level of the global logger in place
+func SetLevel(name string) {
+	switch strings.ToLower(name) {
+	case "debug":
+		logLevel.Set(slog.LevelDebug)
+	case "info":
+		logLevel.Set(slog.LevelInfo)
+	case "warn", "warning":
+		logLevel.Set(slog.LevelWarn)
+	case "error":
+		logLevel.Set(slog.LevelError)
+	default:
+		logLevel.Set(slog.LevelInfo)
+	}
 }
 
 // WithFields returns a logger with the given fields
@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetupOutputFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golte.log")
+	if err := SetupOutput("info", "json", Output{File: path, MaxSize: 1 << 20}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Setup("info", "text") })
+
+	slog.Info("before rotation")
+
+	// logrotate moves the file away, then golte gets a SIGHUP
+	if err := os.Rename(path, path+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	slog.Info("after rotation")
+
+	old, _ := os.ReadFile(path + ".old")
+	current, _ := os.ReadFile(path)
+	if !strings.Contains(string(old), `"msg":"before rotation"`) || strings.Contains(string(old), "after") {
+		t.Errorf("moved file = %q", old)
+	}
+	if !strings.Contains(string(current), `"msg":"after rotation"`) {
+		t.Errorf("reopened file = %q", current)
+	}
+}
+
+func TestSetupOutputFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "golte.log")
+	if err := SetupOutput("info", "text", Output{File: path}); err != nil {
+		t.Fatalf("SetupOutput() = %v, want a fallback to stdout", err)
+	}
+	t.Cleanup(func() { Setup("info", "text") })
+
+	warnings := Recent(1, slog.LevelWarn)
+	if len(warnings) != 1 || warnings[0].Message != "Failed to open the log file, logging to stdout" || !strings.Contains(warnings[0].Attrs, path) {
+		t.Errorf("Recent() = %+v, want the fallback warning", warnings)
+	}
+	if err := Reopen(); err != nil {
+		t.Errorf("Reopen() without a file = %v", err)
+	}
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golte.log")
+	r, err := OpenRotatingFile(path, 10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.SetMaxAge(time.Hour)
+
+	r.Write([]byte("first\n"))
+	r.Write([]byte("second\n"))
+	week := time.Now().Add(-7 * 24 * time.Hour)
+	if err := os.Chtimes(path+".1", week, week); err != nil {
+		t.Fatal(err)
+	}
+
+	// The week old backup is shifted to .2 and removed
+	r.Write([]byte("third\n"))
+	if _, err := os.Stat(path + ".2"); !os.IsNotExist(err) {
+		t.Errorf("kept a backup older than max_age: %v", err)
+	}
+	if got, _ := os.ReadFile(path + ".1"); string(got) != "second\n" {
+		t.Errorf("newest backup = %q", got)
+	}
+}
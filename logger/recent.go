@@ -0,0 +1,228 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Records kept in memory, see SetRecentSize
+const (
+	DefaultRecentSize = 500
+	MaxRecentSize     = 10000
+)
+
+// Record is a log record kept in memory, for /logs and the API
+type Record struct {
+	Time    time.Time  `json:"time"`
+	Level   slog.Level `json:"level"`
+	Message string     `json:"message"`
+	Attrs   string     `json:"attrs,omitempty"` // key=value pairs, space separated
+}
+
+// String formats the record on one line, like the text handler but shorter
+func (r Record) String() string {
+	line := r.Time.Format(time.TimeOnly) + " " + r.Level.String() + " " + r.Message
+	if r.Attrs != "" {
+		line += " " + r.Attrs
+	}
+	return line
+}
+
+// recent keeps the last records logged, oldest first once full
+var recent = newRing(DefaultRecentSize)
+
+// Recent returns up to the last n records at minLevel or above, oldest
+// first
+func Recent(n int, minLevel slog.Level) []Record {
+	return recent.last(n, minLevel, time.Time{})
+}
+
+// RecentSince is Recent without the records logged before since
+func RecentSince(since time.Time, n int, minLevel slog.Level) []Record {
+	return recent.last(n, minLevel, since)
+}
+
+// SetRecentSize changes how many records are kept in memory, capped at
+// MaxRecentSize. The newest are kept when shrinking, 0 keeps none.
+func SetRecentSize(n int) {
+	recent.resize(min(max(n, 0), MaxRecentSize))
+}
+
+// ring is a fixed size buffer of records, overwriting the oldest. Its
+// slice grows up to size as records come, the memory is only used once
+// that many were logged.
+type ring struct {
+	mu      sync.Mutex
+	size    int
+	records []Record
+	next    int // where the next record goes once full
+}
+
+func newRing(size int) *ring {
+	return &ring{size: size}
+}
+
+func (r *ring) add(record Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case r.size == 0:
+	case len(r.records) < r.size:
+		r.records = append(r.records, record)
+	default:
+		r.records[r.next] = record
+		r.next = (r.next + 1) % len(r.records)
+	}
+}
+
+// resize keeps the newest records that fit in size
+func (r *ring) resize(size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if size == r.size {
+		return
+	}
+	ordered := append(slices.Clone(r.records[r.next:]), r.records[:r.next]...)
+	r.records = slices.Clip(ordered[max(len(ordered)-size, 0):])
+	r.size, r.next = size, 0
+}
+
+// last returns up to n records at minLevel or above logged since, oldest
+// first
+func (r *ring) last(n int, minLevel slog.Level, since time.Time) []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var records []Record
+	for i := len(r.records) - 1; i >= 0 && len(records) < n; i-- {
+		record := r.records[(r.next+i)%len(r.records)]
+		if record.Time.Before(since) {
+			break
+		}
+		if record.Level >= minLevel {
+			records = append(records, record)
+		}
+	}
+	// Collected newest first
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records
+}
+
+// ringHandler is a slog.Handler adding records to a ring
+type ringHandler struct {
+	ring   *ring
+	level  slog.Leveler
+	attrs  string // formatted attributes of WithAttrs
+	prefix string // groups of WithGroup, dotted
+}
+
+func (h *ringHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *ringHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(&b, h.prefix, a)
+		return true
+	})
+	h.ring.add(Record{Time: r.Time, Level: r.Level, Message: r.Message, Attrs: b.String()})
+	return nil
+}
+
+func (h *ringHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, a := range attrs {
+		appendAttr(&b, h.prefix, a)
+	}
+	clone := *h
+	clone.attrs = b.String()
+	return &clone
+}
+
+func (h *ringHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix += name + "."
+	return &clone
+}
+
+// appendAttr writes a as key=value, groups flattened to dotted keys
+func appendAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			appendAttr(b, prefix, ga)
+		}
+		return
+	}
+
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	value := a.Value.String()
+	if strings.ContainsAny(value, " \"=") || value == "" {
+		value = strconv.Quote(value)
+	}
+	b.WriteString(prefix + a.Key + "=" + value)
+}
+
+// teeHandler sends records to every handler enabled for them
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRingHandler(t *testing.T) {
+	r := newRing(3)
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelDebug)
+	log := slog.New(&ringHandler{ring: r, level: level}).With("component", "modem")
+
+	log.Debug("first")
+	log.Info("Received SMS", slog.String("from", "+33612345678"), slog.String("message", "hello there"))
+	log.WithGroup("call").Warn("Dropped", slog.Int("digits", 4))
+	log.Error("last", slog.Group("caps", slog.Bool("voice", true)))
+
+	// The first record was overwritten
+	all := r.last(10, slog.LevelDebug, time.Time{})
+	if len(all) != 3 || all[0].Message != "Received SMS" || all[2].Message != "last" {
+		t.Fatalf("last() = %+v", all)
+	}
+	if want := `component=modem from=+33612345678 message="hello there"`; all[0].Attrs != want {
+		t.Errorf("attrs = %q, want %q", all[0].Attrs, want)
+	}
+	if want := "component=modem call.digits=4"; all[1].Attrs != want {
+		t.Errorf("group attrs = %q, want %q", all[1].Attrs, want)
+	}
+	if want := "component=modem caps.voice=true"; all[2].Attrs != want {
+		t.Errorf("group value attrs = %q, want %q", all[2].Attrs, want)
+	}
+
+	warnings := r.last(1, slog.LevelWarn, time.Time{})
+	if len(warnings) != 1 || warnings[0].Message != "last" {
+		t.Errorf("last(1, warn) = %+v", warnings)
+	}
+	if got := r.last(10, slog.LevelWarn, time.Time{}); len(got) != 2 || got[0].Message != "Dropped" {
+		t.Errorf("last(10, warn) = %+v", got)
+	}
+}
+
+func TestRingHandlerLevel(t *testing.T) {
+	r := newRing(3)
+	level := new(slog.LevelVar)
+	log := slog.New(teeHandler{slog.NewTextHandler(io.Discard, nil), &ringHandler{ring: r, level: level}})
+
+	log.Debug("hidden")
+	log.Info("kept")
+	if got := r.last(10, slog.LevelDebug, time.Time{}); len(got) != 1 || got[0].Message != "kept" {
+		t.Errorf("last() = %+v, want the info record only", got)
+	}
+}
+
+func TestRingResizeAndSince(t *testing.T) {
+	r := newRing(4)
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	for i := range 6 {
+		r.add(Record{Time: start.Add(time.Duration(i) * time.Minute), Level: slog.LevelInfo, Message: string(rune('a' + i))})
+	}
+
+	messages := func(records []Record) string {
+		var s string
+		for _, record := range records {
+			s += record.Message
+		}
+		return s
+	}
+	if got := messages(r.last(10, slog.LevelInfo, time.Time{})); got != "cdef" {
+		t.Errorf("last() = %q, want cdef", got)
+	}
+	if got := messages(r.last(10, slog.LevelInfo, start.Add(4*time.Minute))); got != "ef" {
+		t.Errorf("last() since e = %q, want ef", got)
+	}
+
+	// Shrinking keeps the newest, growing keeps them all
+	r.resize(2)
+	if got := messages(r.last(10, slog.LevelInfo, time.Time{})); got != "ef" {
+		t.Errorf("last() after shrinking = %q, want ef", got)
+	}
+	r.resize(3)
+	r.add(Record{Time: start.Add(time.Hour), Message: "g"})
+	r.add(Record{Time: start.Add(time.Hour), Message: "h"})
+	if got := messages(r.last(10, slog.LevelInfo, time.Time{})); got != "fgh" {
+		t.Errorf("last() after growing = %q, want fgh", got)
+	}
+
+	r.resize(0)
+	r.add(Record{Message: "i"})
+	if got := r.last(10, slog.LevelDebug, time.Time{}); len(got) != 0 {
+		t.Errorf("last() with size 0 = %+v", got)
+	}
+}
@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Redaction modes of logging.redaction
+const (
+	RedactNone    = "none"    // log numbers and messages as they are
+	RedactPartial = "partial" // +3361••••78, messages cut after a few characters
+	RedactFull    = "full"    // numbers masked, messages replaced by a keyed hash
+)
+
+// Attributes redacted, every component logs numbers and SMS bodies under
+// these keys
+var (
+	numberKeys = []string{"number", "from", "to"}
+	bodyKeys   = []string{"message", "transliterated", "pdus"}
+)
+
+// partialMessageLength is how many characters of a message partial keeps
+// at most, never more than a third of it
+const partialMessageLength = 12
+
+// messageKey keys the hashes of full, drawn for each run so a short message
+// like a code can't be found by hashing every candidate
+var messageKey = func() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}()
+
+// redaction is the mode the handlers Setup creates apply, like logLevel
+var redaction atomic.Value
+
+func init() {
+	redaction.Store(RedactNone)
+}
+
+// SetRedaction changes the redaction of the global logger in place, an
+// unknown mode logs everything
+func SetRedaction(mode string) {
+	switch mode = strings.ToLower(mode); mode {
+	case RedactPartial, RedactFull:
+	default:
+		mode = RedactNone
+	}
+	redaction.Store(mode)
+}
+
+// redactHandler masks phone numbers and SMS bodies before the records reach
+// the next handler
+type redactHandler struct {
+	next slog.Handler
+}
+
+func (h redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	mode := redaction.Load().(string)
+	if mode == RedactNone {
+		return h.next.Handle(ctx, r)
+	}
+	redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(a, mode))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+// WithAttrs redacts attrs with the mode of the time, the loggers made with
+// them are usually made at startup and don't carry numbers
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	mode := redaction.Load().(string)
+	if mode != RedactNone {
+		redacted := make([]slog.Attr, len(attrs))
+		for i, a := range attrs {
+			redacted[i] = redactAttr(a, mode)
+		}
+		attrs = redacted
+	}
+	return redactHandler{h.next.WithAttrs(attrs)}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{h.next.WithGroup(name)}
+}
+
+// redactAttr returns a with its value masked if its key holds a number or a
+// message
+func redactAttr(a slog.Attr, mode string) slog.Attr {
+	a.Value = a.Value.Resolve()
+	switch {
+	case a.Value.Kind() == slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redacted[i] = redactAttr(ga, mode)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	case slices.Contains(numberKeys, a.Key):
+		return slog.String(a.Key, RedactNumber(a.Value.String(), mode))
+	case slices.Contains(bodyKeys, a.Key):
+		return slog.String(a.Key, RedactMessage(a.Value.String(), mode))
+	}
+	return a
+}
+
+// RedactNumber masks a phone number: partial keeps the country and area
+// code and the last two digits, +3361••••78, full keeps nothing
+func RedactNumber(number, mode string) string {
+	if number == "" {
+		return number
+	}
+	switch mode {
+	case RedactPartial:
+		runes := []rune(number)
+		if len(runes) < 8 {
+			return strings.Repeat("•", max(len(runes)-2, 1)) + string(runes[max(len(runes)-2, 1):])
+		}
+		return string(runes[:5]) + "••••" + string(runes[len(runes)-2:])
+	case RedactFull:
+		return "••••"
+	}
+	return number
+}
+
+// RedactMessage hides an SMS body: partial keeps its first characters, up
+// to a third of them, full replaces it by a short HMAC, so the same message
+// can still be spotted within a run
+func RedactMessage(message, mode string) string {
+	if message == "" {
+		return message
+	}
+	switch mode {
+	case RedactPartial:
+		runes := []rune(message)
+		return string(runes[:min(partialMessageLength, len(runes)/3)]) + "…"
+	case RedactFull:
+		mac := hmac.New(sha256.New, messageKey)
+		mac.Write([]byte(message))
+		return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:4])
+	}
+	return message
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+// logRedacted logs an SMS with mode and returns the JSON record emitted
+func logRedacted(t *testing.T, mode string) map[string]any {
+	t.Helper()
+	SetRedaction(mode)
+	t.Cleanup(func() { SetRedaction(RedactNone) })
+
+	var buf bytes.Buffer
+	log := slog.New(redactHandler{slog.NewJSONHandler(&buf, nil)}).With("component", "machine")
+	log.Info("Received SMS",
+		slog.String("from", "+33612345678"),
+		slog.String("message", "Your code is 123456, don't share it"),
+		slog.Group("reply", slog.String("to", "+33687654321")),
+		slog.String("user", "alice"))
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+func TestRedaction(t *testing.T) {
+	tests := []struct {
+		mode, from, message, to string
+	}{
+		{RedactNone, "+33612345678", "Your code is 123456, don't share it", "+33687654321"},
+		{RedactPartial, "+3361••••78", "Your code i…", "+3368••••21"},
+		{RedactFull, "••••", RedactMessage("Your code is 123456, don't share it", RedactFull), "••••"},
+	}
+	for _, tt := range tests {
+		record := logRedacted(t, tt.mode)
+		reply, _ := record["reply"].(map[string]any)
+		if record["from"] != tt.from || record["message"] != tt.message || reply["to"] != tt.to {
+			t.Errorf("%s: from=%v message=%v to=%v, want %s, %s, %s", tt.mode, record["from"], record["message"], reply["to"], tt.from, tt.message, tt.to)
+		}
+		if record["user"] != "alice" || record["component"] != "machine" || record["msg"] != "Received SMS" {
+			t.Errorf("%s: other attributes changed: %v", tt.mode, record)
+		}
+	}
+}
+
+func TestRedactHelpers(t *testing.T) {
+	if got := RedactNumber("36665", RedactPartial); got != "•••65" {
+		t.Errorf("RedactNumber(short code) = %q", got)
+	}
+	if got := RedactMessage("Hi", RedactPartial); got != "…" {
+		t.Errorf("RedactMessage(short) = %q", got)
+	}
+	if got := RedactMessage("Code 493817", RedactPartial); got != "Cod…" {
+		t.Errorf("RedactMessage(code) = %q", got)
+	}
+	a, b := RedactMessage("same", RedactFull), RedactMessage("same", RedactFull)
+	if a != b || a == "same" || len(a) != len("hmac:")+8 {
+		t.Errorf("RedactMessage(full) = %q, %q", a, b)
+	}
+	// A plain hash of a short code could be reversed by trying them all
+	if sum := sha256.Sum256([]byte("same")); a == "hmac:"+hex.EncodeToString(sum[:4]) {
+		t.Error("RedactMessage(full) isn't keyed")
+	}
+	SetRedaction("bogus")
+	if mode := redaction.Load().(string); mode != RedactNone {
+		t.Errorf("SetRedaction(bogus) = %q, want none", mode)
+	}
+}
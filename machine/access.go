@@ -0,0 +1,157 @@
+package machine
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"golte/config"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// adminActions change the modem or the bridge, or show private data, they
+// are admin only unless discord.access.commands lists them
+var adminActions = []string{"ussd", "reload", "smsread", "clearsms", "broadcast", "audio device", "modem trace", "logs", "phonebook add", "rawpdu"}
+
+// Actions that aren't slash commands
+const (
+	actionReply  = "reply"  // an SMS sent by replying to its embed
+	actionRawPDU = "rawpdu" // the Raw PDU button of SMS embeds
+)
+
+// isAdmin reports whether the user may do everything
+func isAdmin(cfg *config.Config, userID snowflake.ID) bool {
+	id := userID.String()
+	return slices.Contains(cfg.Discord.OwnerIDs, id) ||
+		slices.Contains(cfg.Security.AdminUserIDs, id) ||
+		slices.Contains(cfg.Discord.Access.AdminUsers, id)
+}
+
+// requiredRole returns the role action needs: the one discord.access.commands
+// sets for it or its command, admin for adminActions, or the default role
+func requiredRole(access config.AccessConfig, action string) string {
+	if role, ok := access.Commands[action]; ok {
+		return role
+	}
+	if slices.Contains(adminActions, action) {
+		return config.AccessAdmin
+	}
+	if command, _, ok := strings.Cut(action, " "); ok {
+		if role, ok := access.Commands[command]; ok {
+			return role
+		}
+	}
+	return access.DefaultRole
+}
+
+// allowed evaluates the access policy for a user with the given Discord
+// roles doing action
+func allowed(cfg *config.Config, action string, userID snowflake.ID, roleIDs []snowflake.ID) bool {
+	if isAdmin(cfg, userID) {
+		return true
+	}
+
+	access := cfg.Discord.Access
+	switch role := requiredRole(access, action); role {
+	case "", config.AccessEveryone:
+		return true
+	case config.AccessAdmin:
+		return false
+	default:
+		return slices.Contains(access.Users, userID.String()) ||
+			slices.ContainsFunc(roleIDs, func(id snowflake.ID) bool { return id.String() == role })
+	}
+}
+
+// commandAction names a slash command with its subcommands, e.g. phonebook
+// add
+func commandAction(data discord.SlashCommandInteractionData) string {
+	parts := []string{data.CommandName()}
+	if data.SubCommandGroupName != nil {
+		parts = append(parts, *data.SubCommandGroupName)
+	}
+	if data.SubCommandName != nil {
+		parts = append(parts, *data.SubCommandName)
+	}
+	return strings.Join(parts, " ")
+}
+
+// authorize checks user, with the roles of member if in a guild, may do
+// action. Otherwise it tells them with deny, counts the attempt and reports
+// it to the audit channel.
+func (d *DiscordManager) authorize(action string, user discord.User, member *discord.Member, deny func(content string)) bool {
+	var roleIDs []snowflake.ID
+	if member != nil {
+		roleIDs = member.RoleIDs
+	}
+	if allowed(d.config(), action, user.ID, roleIDs) {
+		return true
+	}
+
+	denied := d.denied.Add(1)
+	d.logger.Warn("Refused a Discord user",
+		slog.String("action", action),
+		slog.String("user", user.Username),
+		slog.String("user_id", user.ID.String()),
+		slog.Int64("denied", denied))
+	deny(fmt.Sprintf("⛔ You're not allowed to use %s on this bridge", actionName(action)))
+
+	if channel := d.config().Discord.Access.AuditChannelID; channel != "" {
+		embed := discord.NewEmbedBuilder().
+			SetTitle("⛔ Access refused").
+			AddField("User", discord.UserMention(user.ID), true).
+			AddField("Action", actionName(action), true).
+			AddField("Refused so far", fmt.Sprint(denied), true).
+			SetColor(0xff0000).
+			SetTimestamp(d.modem.Now()).
+			Build()
+		// The user is named, not pinged
+		err := d.postToChannels([]string{channel}, discord.NewMessageCreateBuilder().
+			SetEmbeds(embed).
+			SetAllowedMentions(&discord.AllowedMentions{}).
+			Build())
+		if err != nil {
+			d.logger.Error("Failed to report a refused attempt", slog.String("channel", channel), slog.Any("error", err))
+		}
+	}
+	return false
+}
+
+// AuditAPI reports a request to the HTTP API and its outcome to the audit
+// channel, if there is one
+func (d *DiscordManager) AuditAPI(action, outcome string) {
+	channel := d.config().Discord.Access.AuditChannelID
+	if channel == "" {
+		return
+	}
+
+	embed := discord.NewEmbedBuilder().
+		SetTitle("🔌 API request").
+		AddField("Action", action, true).
+		AddField("Outcome", outcome, true).
+		SetColor(0x5865f2).
+		SetTimestamp(d.modem.Now()).
+		Build()
+	err := d.postToChannels([]string{channel}, discord.NewMessageCreateBuilder().
+		SetEmbeds(embed).
+		SetAllowedMentions(&discord.AllowedMentions{}).
+		Build())
+	if err != nil {
+		d.logger.Error("Failed to report an API request", slog.String("channel", channel), slog.Any("error", err))
+	}
+}
+
+// actionName shows an action as users know it
+func actionName(action string) string {
+	switch action {
+	case actionReply:
+		return "SMS replies"
+	case actionRawPDU:
+		return "raw PDUs"
+	default:
+		return "`/" + action + "`"
+	}
+}
@@ -0,0 +1,88 @@
+package machine
+
+import (
+	"testing"
+
+	"golte/config"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+func TestAllowed(t *testing.T) {
+	const (
+		owner     snowflake.ID = 100
+		admin     snowflake.ID = 101
+		member    snowflake.ID = 102
+		stranger  snowflake.ID = 103
+		allowedID snowflake.ID = 104
+		operators snowflake.ID = 200
+		billing   snowflake.ID = 201
+	)
+	cfg := &config.Config{
+		Discord: config.DiscordConfig{
+			OwnerIDs: []string{owner.String()},
+			Access: config.AccessConfig{
+				DefaultRole: operators.String(),
+				Commands: map[string]string{
+					"status":    config.AccessEveryone,
+					"ussd":      billing.String(),
+					"phonebook": config.AccessAdmin,
+				},
+				Users:      []string{allowedID.String()},
+				AdminUsers: []string{admin.String()},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		action string
+		user   snowflake.ID
+		roles  []snowflake.ID
+		want   bool
+	}{
+		{"member of the default role", "send", member, []snowflake.ID{operators}, true},
+		{"without the default role", "send", stranger, nil, false},
+		{"with another role", "send", stranger, []snowflake.ID{billing}, false},
+		{"reply with the default role", actionReply, member, []snowflake.ID{operators}, true},
+		{"reply without the default role", actionReply, stranger, nil, false},
+		{"explicitly allowed user", "send", allowedID, nil, true},
+		{"command open to everyone", "status", stranger, nil, true},
+		{"command overridden to a role", "ussd", member, []snowflake.ID{billing}, true},
+		{"command overridden to a role, without it", "ussd", member, []snowflake.ID{operators}, false},
+		{"admin only by default", "reload", member, []snowflake.ID{operators}, false},
+		{"admin only, explicitly allowed user", "reload", allowedID, nil, false},
+		{"admin only component", actionRawPDU, member, []snowflake.ID{operators}, false},
+		{"admin only through its command", "phonebook list", member, []snowflake.ID{operators}, false},
+		{"owner runs admin only", "reload", owner, nil, true},
+		{"admin user runs admin only", "clearsms", admin, nil, true},
+		{"admin user runs everything", "phonebook add", admin, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowed(cfg, tt.action, tt.user, tt.roles); got != tt.want {
+				t.Errorf("allowed(%q) = %v, want %v", tt.action, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequiredRoleDefaults(t *testing.T) {
+	tests := []struct {
+		access config.AccessConfig
+		action string
+		want   string
+	}{
+		{config.AccessConfig{}, "send", ""},
+		{config.AccessConfig{}, "phonebook add", config.AccessAdmin},
+		{config.AccessConfig{}, "phonebook list", ""},
+		{config.AccessConfig{DefaultRole: config.AccessAdmin}, "status", config.AccessAdmin},
+		{config.AccessConfig{Commands: map[string]string{"phonebook add": config.AccessEveryone}}, "phonebook add", config.AccessEveryone},
+		{config.AccessConfig{Commands: map[string]string{"phonebook": config.AccessEveryone}}, "phonebook add", config.AccessAdmin},
+	}
+	for _, tt := range tests {
+		if got := requiredRole(tt.access, tt.action); got != tt.want {
+			t.Errorf("requiredRole(%+v, %q) = %q, want %q", tt.access, tt.action, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package machine
+
+import (
+	"strings"
+	"unicode"
+)
+
+// senderAllowed reports whether SMS from sender are forwarded, everyone's
+// are when the allowlist is empty. Numbers match in any format, sender names
+// regardless of case.
+func senderAllowed(allowlist []string, sender string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+
+	number := isNumber(sender)
+	for _, allowed := range allowlist {
+		if strings.EqualFold(strings.TrimSpace(allowed), strings.TrimSpace(sender)) {
+			return true
+		}
+		if number && isNumber(allowed) && normalizeNumber(allowed) == normalizeNumber(sender) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNumber reports whether a sender is a phone number rather than a name
+func isNumber(sender string) bool {
+	return normalizeNumber(sender) != "" && strings.IndexFunc(sender, unicode.IsLetter) < 0
+}
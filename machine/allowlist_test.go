@@ -0,0 +1,27 @@
+package machine
+
+import "testing"
+
+func TestSenderAllowed(t *testing.T) {
+	allowlist := []string{"+33 6 12 34 56 78", "MyBank", "Bank 2"}
+	tests := []struct {
+		sender string
+		want   bool
+	}{
+		{"+33612345678", true},
+		{"0033612345678", true},
+		{"mybank", true},
+		{"+33698765432", false},
+		{"Amazon", false},
+		{"MyBank2", false},
+	}
+	for _, tt := range tests {
+		if got := senderAllowed(allowlist, tt.sender); got != tt.want {
+			t.Errorf("senderAllowed(%q) = %v, want %v", tt.sender, got, tt.want)
+		}
+	}
+
+	if !senderAllowed(nil, "Amazon") {
+		t.Error("senderAllowed() with no allowlist should allow everyone")
+	}
+}
@@ -0,0 +1,63 @@
+package machine
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+)
+
+// announceItems are the Info items shown when the bridge comes online
+var announceItems = []string{"Manufacturer", "Model", "Operator", "Signal"}
+
+// announceOnline posts that the bridge is online, with its version and the
+// state of the modem, to the notification channels
+func (d *DiscordManager) announceOnline() {
+	items, err := d.modem.query(announceItems, modemQueryTimeout(d.config().Modem))
+	embed := onlineEmbed(d.version, items, err).
+		SetTimestamp(d.modem.Now()).
+		Build()
+
+	channels := d.config().Discord.ChannelIDs
+	if err := d.postToChannels(channels, discord.NewMessageCreateBuilder().SetEmbeds(embed).Build()); err != nil {
+		d.logger.Error("Failed to announce the bridge is online", slog.Any("channels", channels), slog.Any("error", err))
+		return
+	}
+	d.logger.Info("Announced the bridge is online", slog.Any("channels", channels))
+}
+
+// onlineEmbed describes the bridge from its version and the modem's answers
+// to the announceItems queries, or the error querying it
+func onlineEmbed(version string, items []InfoItem, err error) *discord.EmbedBuilder {
+	if version == "" {
+		version = "dev"
+	}
+	embed := discord.NewEmbedBuilder().
+		SetTitle("🟢 Golte is online").
+		AddField("Version", version, true).
+		SetColor(0x00ff00)
+	if err != nil {
+		return embed.AddField("Modem", "⚠️ "+err.Error(), true)
+	}
+
+	values := make(map[string]string, len(items))
+	for _, item := range items {
+		if item.Error != "" {
+			values[item.Name] = "⚠️ " + item.Error
+		} else {
+			values[item.Name] = item.Value
+		}
+	}
+
+	model := strings.TrimSpace(values["Manufacturer"] + " " + values["Model"])
+	if model == "" {
+		model = "unknown"
+	}
+	embed.AddField("Modem", model, true)
+	for _, name := range []string{"Operator", "Signal"} {
+		if value, ok := values[name]; ok {
+			embed.AddField(name, value, true)
+		}
+	}
+	return embed
+}
@@ -0,0 +1,39 @@
+package machine
+
+import "testing"
+
+func TestOnlineEmbed(t *testing.T) {
+	items := []InfoItem{
+		{Name: "Manufacturer", Value: "SIMCOM INCORPORATED"},
+		{Name: "Model", Value: "SIM7600E-H"},
+		{Name: "Operator", Value: "Orange F"},
+		{Name: "Signal", Error: "timeout"},
+	}
+	embed := onlineEmbed("v1.4.0", items, nil).Build()
+
+	want := map[string]string{
+		"Version":  "v1.4.0",
+		"Modem":    "SIMCOM INCORPORATED SIM7600E-H",
+		"Operator": "Orange F",
+		"Signal":   "⚠️ timeout",
+	}
+	if len(embed.Fields) != len(want) {
+		t.Fatalf("onlineEmbed() has %d fields, want %d", len(embed.Fields), len(want))
+	}
+	for _, field := range embed.Fields {
+		if want[field.Name] != field.Value {
+			t.Errorf("field %s = %q, want %q", field.Name, field.Value, want[field.Name])
+		}
+	}
+
+	embed = onlineEmbed("", nil, ErrNoModem).Build()
+	if embed.Fields[0].Value != "dev" {
+		t.Errorf("version without one = %q", embed.Fields[0].Value)
+	}
+	if embed.Fields[1].Name != "Modem" || embed.Fields[1].Value != "⚠️ "+ErrNoModem.Error() {
+		t.Errorf("modem field on error = %+v", embed.Fields[1])
+	}
+	if len(embed.Fields) != 2 {
+		t.Errorf("onlineEmbed() on error has %d fields", len(embed.Fields))
+	}
+}
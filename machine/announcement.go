@@ -0,0 +1,101 @@
+package machine
+
+import (
+	"log/slog"
+	"time"
+
+	"golte/config"
+)
+
+// announcementPoll is how often the end of an announcement is checked for
+const announcementPoll = 200 * time.Millisecond
+
+// announcementTail lets the end of the announcement reach the caller before
+// hanging up, the speaker buffers a little audio
+const announcementTail = 500 * time.Millisecond
+
+// announceCall answers a call routed to the announce action, plays its
+// audio to the end then hangs up, and texts the route's message to the
+// caller if it has one. Without voice the call is rejected instead, still
+// followed by the message.
+func (m *ModemManager) announceCall(c callControl, number string, route config.CallRoute) {
+	if m.playback == nil {
+		if err := c.HangUp(); err != nil {
+			m.logger.Error("Failed to reject call", slog.Any("error", err))
+		}
+		m.callNotifyCallback(MQTTCallIncoming, number, "📞 Incoming voice call, rejected as voice is disabled for the announcement")
+		m.announcementSMS(number, route.Message)
+		return
+	}
+
+	m.callNotifyCallback(MQTTCallIncoming, number, "📞 Incoming voice call, answered with an announcement by call.routes")
+	c.PickUp()
+	m.state.Reset()
+	m.watchCall(number, m.config().Call.MaxDuration)
+
+	// Announcements can be long, the call handler mustn't wait for them
+	go func() {
+		defer recoverPanic(m.logger, "announcement", m.abortCall, m.panicFunc)
+
+		m.announcing.Store(true)
+		defer m.announcing.Store(false)
+
+		time.Sleep(1 * time.Second) // Wait for call to connect
+		left, err := m.playAnnouncement("audio/" + route.Audio)
+		if err != nil {
+			m.logger.Error("Failed to play announcement", slog.String("audio", route.Audio), slog.Any("error", err))
+		}
+
+		m.stopCallWatch()
+		if !left {
+			if err := c.HangUp(); err != nil {
+				m.logger.Error("Failed to hang up after the announcement", slog.Any("error", err))
+			}
+			m.logger.Info("Announcement played, call hung up", slog.String("number", number))
+		}
+		m.announcementSMS(number, route.Message)
+	}()
+}
+
+// playAnnouncement queues the prompt at filePath and waits until it played
+// out. left reports the caller hung up before.
+func (m *ModemManager) playAnnouncement(filePath string) (left bool, err error) {
+	handle, err := m.playback.EnqueuePredecoded(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	ticker := time.NewTicker(announcementPoll)
+	defer ticker.Stop()
+	lastCheck := time.Now()
+	for range ticker.C {
+		if _, queued := m.playback.Position(handle); !queued {
+			time.Sleep(announcementTail)
+			return false, nil
+		}
+		if time.Since(lastCheck) < callPollInterval {
+			continue
+		}
+		lastCheck = time.Now()
+		if !m.callActive() {
+			m.playback.Dequeue(handle)
+			m.logger.Info("Caller hung up during the announcement")
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// announcementSMS texts message to the caller of an announcement, in the
+// background as sending takes seconds. Withheld callers can't be texted.
+func (m *ModemManager) announcementSMS(number, message string) {
+	if message == "" || number == "" {
+		return
+	}
+	go func() {
+		defer recoverPanic(m.logger, "announcement SMS", nil, m.panicFunc)
+		if err := m.SendSMS(number, message); err != nil {
+			m.logger.Error("Failed to text the caller after the announcement", slog.String("number", number), slog.Any("error", err))
+		}
+	}()
+}
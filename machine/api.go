@@ -0,0 +1,473 @@
+package machine
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golte/config"
+	"golte/health"
+	"golte/logger"
+)
+
+// apiSendHistory is how many SMS sent through the API keep their status
+const apiSendHistory = 100
+
+// apiLogLines is how many records GET /v1/logs returns by default
+const apiLogLines = 100
+
+// apiMaxBody bounds the JSON bodies of requests, SMS are far smaller
+const apiMaxBody = 64 << 10
+
+// apiRefusedAuditInterval is how often refused requests are audited at
+// most, a scan of the API mustn't flood the audit channel
+const apiRefusedAuditInterval = time.Minute
+
+// Status of an SMS sent through the API
+const (
+	APISMSPending = "pending"
+	APISMSSent    = "sent"
+	APISMSFailed  = "failed"
+)
+
+// APISMS is an SMS sent through the API, as GET /v1/sms/{id} returns it
+type APISMS struct {
+	ID       string     `json:"id"`
+	To       string     `json:"to"`
+	Contact  string     `json:"contact,omitempty"` // SIM contact name to was given as
+	Status   string     `json:"status"`
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// APIServer serves the local HTTP API, for scripts sending SMS and placing
+// calls without Discord. Requests go through the same functions as the
+// Discord commands, see Handler.
+type APIServer struct {
+	config     func() *config.Config
+	logger     *slog.Logger
+	modem      *ModemManager
+	smsFunc    func(number, message string) error
+	callFunc   func(number string, maxDuration time.Duration) error
+	healthFunc func() health.State
+	auditFunc  func(action, outcome string)
+	wg         *sync.WaitGroup
+
+	mu    sync.Mutex
+	sends map[string]*APISMS
+	order []string // IDs of sends, oldest first
+
+	refusedMu      sync.Mutex
+	refused        int       // requests refused since the last audit
+	refusedAudited time.Time // when refused requests were last audited
+}
+
+// NewAPIServer creates the API server. SMS are sent in the background with
+// smsFunc, tracked by wg. auditFunc is told about every request, it may be
+// nil.
+func NewAPIServer(cfg func() *config.Config, modem *ModemManager, smsFunc func(number, message string) error, callFunc func(number string, maxDuration time.Duration) error, healthFunc func() health.State, auditFunc func(action, outcome string), wg *sync.WaitGroup) *APIServer {
+	return &APIServer{
+		config:     cfg,
+		logger:     slog.With("component", "api"),
+		modem:      modem,
+		smsFunc:    smsFunc,
+		callFunc:   callFunc,
+		healthFunc: healthFunc,
+		auditFunc:  auditFunc,
+		wg:         wg,
+		sends:      make(map[string]*APISMS),
+	}
+}
+
+// Serve starts the API on api.listen_addr until ctx is done
+func (s *APIServer) Serve(ctx context.Context) error {
+	addr := s.config().API.ListenAddr
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for API requests: %w", err)
+	}
+	server := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 5 * time.Second}
+
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("API stopped", slog.Any("error", err))
+		}
+	}()
+	go func() {
+		defer s.wg.Done()
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	s.logger.Info("API ready", slog.String("listen", listener.Addr().String()))
+	return nil
+}
+
+// Handler serves, with the bearer token of api.token:
+//
+//	POST /v1/sms {"to", "message"}  send an SMS, returns its ID
+//	GET  /v1/sms/{id}               status of a sent SMS
+//	POST /v1/call {"to"}            place a call
+//	GET  /v1/status                 health of the modem and Discord
+//	GET  /v1/signal                 signal quality
+//	GET  /v1/metrics                AT command latency percentiles
+//	GET  /v1/logs                   the last log records, see handleLogs
+//
+// to may be a number or a SIM contact name, like in /send and /call. The
+// probes of service managers and orchestrators need no token:
+//
+//	GET  /healthz                   the process is alive
+//	GET  /readyz                    the modem is registered and Discord connected
+func (s *APIServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/sms", s.handleSendSMS)
+	mux.HandleFunc("GET /v1/sms/{id}", s.handleGetSMS)
+	mux.HandleFunc("POST /v1/call", s.handleCall)
+	mux.HandleFunc("GET /v1/status", s.handleStatus)
+	mux.HandleFunc("GET /v1/signal", s.handleSignal)
+	mux.HandleFunc("GET /v1/metrics", s.handleMetrics)
+	mux.HandleFunc("GET /v1/logs", s.handleLogs)
+
+	root := http.NewServeMux()
+	root.HandleFunc("GET /healthz", s.handleHealthz)
+	root.HandleFunc("GET /readyz", s.handleReadyz)
+	root.Handle("/", s.authenticate(mux))
+	return root
+}
+
+// authenticate refuses requests without the bearer token
+func (s *APIServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		want := s.config().API.Token
+		if !ok || want == "" || subtle.ConstantTimeCompare([]byte(token), []byte(want)) != 1 {
+			s.logger.Warn("Refused an API request without a valid token",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("remote", r.RemoteAddr))
+			s.auditRefused(r)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// auditRefused audits a request refused for its token. Within
+// apiRefusedAuditInterval of the last audit it's only counted, and the
+// count goes with the next one.
+func (s *APIServer) auditRefused(r *http.Request) {
+	s.refusedMu.Lock()
+	s.refused++
+	now := time.Now()
+	if now.Sub(s.refusedAudited) < apiRefusedAuditInterval {
+		s.refusedMu.Unlock()
+		return
+	}
+	refused := s.refused
+	s.refused, s.refusedAudited = 0, now
+	s.refusedMu.Unlock()
+
+	outcome := "⛔ refused, invalid token from " + r.RemoteAddr
+	if refused > 1 {
+		outcome = fmt.Sprintf("⛔ refused %d requests with an invalid token, the last from %s", refused, r.RemoteAddr)
+	}
+	s.audit(r.Method+" "+r.URL.Path, outcome)
+}
+
+// readAPIBody decodes the JSON body of r into v, reading at most apiMaxBody
+// bytes of it
+func readAPIBody(w http.ResponseWriter, r *http.Request, v any) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBody)).Decode(v)
+}
+
+// handleSendSMS queues the SMS and replies right away, long messages can
+// take longer to send than clients wait
+func (s *APIServer) handleSendSMS(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		To      string `json:"to"`
+		Message string `json:"message"`
+	}
+	if err := readAPIBody(w, r, &body); err != nil || body.To == "" || body.Message == "" {
+		writeAPIError(w, http.StatusBadRequest, `expected {"to": "...", "message": "..."}`)
+		return
+	}
+	number, contact, err := resolveRecipient(s.modem.Contacts(), body.To)
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	sms := s.track(number, contact)
+	s.logger.Info("Received SMS request from the API", slog.String("id", sms.ID), slog.String("number", number))
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer recoverPanic(s.logger, "API SMS", nil, nil)
+
+		err := s.smsFunc(number, body.Message)
+		status := s.finish(sms.ID, err)
+		if err != nil {
+			s.logger.Error("Failed to send SMS via the API",
+				slog.String("id", sms.ID),
+				slog.String("number", number),
+				slog.Any("error", err))
+			s.audit("SMS to "+recipientLabel(number, contact), fmt.Sprintf("❌ %s (%s): %v", status.Status, sms.ID, err))
+			return
+		}
+		s.audit("SMS to "+recipientLabel(number, contact), fmt.Sprintf("✅ %s (%s)", status.Status, sms.ID))
+	}()
+
+	writeAPIJSON(w, http.StatusAccepted, sms)
+}
+
+// handleGetSMS reports the status of an SMS sent through the API
+func (s *APIServer) handleGetSMS(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	sms, ok := s.sends[r.PathValue("id")]
+	var status APISMS
+	if ok {
+		status = *sms
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "no SMS with this ID, only the last "+strconv.Itoa(apiSendHistory)+" are kept")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, status)
+}
+
+// handleCall places a call, like /call with call.max_duration
+func (s *APIServer) handleCall(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		To string `json:"to"`
+	}
+	if err := readAPIBody(w, r, &body); err != nil || body.To == "" {
+		writeAPIError(w, http.StatusBadRequest, `expected {"to": "..."}`)
+		return
+	}
+	number, contact, err := resolveRecipient(s.modem.Contacts(), body.To)
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	s.logger.Info("Received call request from the API", slog.String("number", number))
+	action := "Call to " + recipientLabel(number, contact)
+	if err := s.callFunc(number, 0); err != nil {
+		s.logger.Error("Failed to start call via the API", slog.String("number", number), slog.Any("error", err))
+		s.audit(action, fmt.Sprintf("❌ %v", err))
+		writeAPIError(w, http.StatusConflict, err.Error())
+		return
+	}
+	s.audit(action, "✅ calling")
+	writeAPIJSON(w, http.StatusAccepted, map[string]string{"to": number, "status": "calling"})
+}
+
+// handleStatus reports the health of the bridge, 503 when it isn't healthy
+func (s *APIServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	state := s.healthFunc()
+	soft, hard := s.modem.Recoveries()
+
+	code := http.StatusOK
+	if state.Err() != nil {
+		code = http.StatusServiceUnavailable
+	}
+	writeAPIJSON(w, code, struct {
+		health.State
+		Healthy        bool  `json:"healthy"`
+		SoftRecoveries int64 `json:"soft_recoveries"`
+		HardRecoveries int64 `json:"hard_recoveries"`
+	}{state, state.Err() == nil, soft, hard})
+}
+
+// handleHealthz answers as long as the process serves requests
+func (s *APIServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// APIReadiness is what GET /readyz returns
+type APIReadiness struct {
+	Ready      bool               `json:"ready"`
+	Unhealthy  []health.Component `json:"unhealthy,omitempty"` // the components keeping the bridge from being ready
+	Components []health.Component `json:"components"`
+}
+
+// handleReadyz reports whether every component is healthy, 503 listing
+// those that aren't otherwise
+func (s *APIServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	state := s.healthFunc()
+	readiness := APIReadiness{Unhealthy: state.Unhealthy(), Components: state.Components}
+	readiness.Ready = len(readiness.Unhealthy) == 0
+
+	code := http.StatusOK
+	if !readiness.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeAPIJSON(w, code, readiness)
+}
+
+// APIMetrics is what GET /v1/metrics returns
+type APIMetrics struct {
+	ATLatency []ATLatency `json:"at_latency"` // by command
+}
+
+// handleMetrics reports the latency of the AT commands, to tell a slow
+// serial link from a slow command
+func (s *APIServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, APIMetrics{ATLatency: s.modem.ATLatency()})
+}
+
+// handleLogs returns the last log records kept in memory, filtered by the
+// lines (100 by default), level and since (RFC 3339) query parameters
+func (s *APIServer) handleLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	lines := apiLogLines
+	if v := query.Get("lines"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeAPIError(w, http.StatusBadRequest, "lines must be a positive number")
+			return
+		}
+		lines = min(n, logger.MaxRecentSize)
+	}
+	level := slog.LevelDebug
+	if v := query.Get("level"); v != "" {
+		var ok bool
+		if level, ok = logLevels[v]; !ok {
+			writeAPIError(w, http.StatusBadRequest, "level must be debug, info, warn or error")
+			return
+		}
+	}
+	var since time.Time
+	if v := query.Get("since"); v != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, v); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "since must be an RFC 3339 time, e.g. 2024-01-02T15:04:05Z")
+			return
+		}
+	}
+
+	records := logger.RecentSince(since, lines, level)
+	if records == nil {
+		records = []logger.Record{}
+	}
+	writeAPIJSON(w, http.StatusOK, map[string][]logger.Record{"records": records})
+}
+
+// APISignal is the signal quality as GET /v1/signal returns it
+type APISignal struct {
+	CSQ  int  `json:"csq"`            // RSSI as reported by AT+CSQ, 0 to 31, 99 if unknown
+	DBm  *int `json:"dbm,omitempty"`  // absent without signal
+	BER  int  `json:"ber"`            // bit error rate, 99 if unknown
+	Lost bool `json:"lost,omitempty"` // no signal is detected
+
+	// LTE reference signal, on modems with AT+CESQ
+	RSRP *int     `json:"rsrp,omitempty"` // in dBm
+	RSRQ *float64 `json:"rsrq,omitempty"` // in dB
+}
+
+// handleSignal queries the signal quality
+func (s *APIServer) handleSignal(w http.ResponseWriter, r *http.Request) {
+	result, err := s.modem.GetSignalQuality()
+	if err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	lines, _ := result.([]string)
+	signal, ok := parseSignal(lines)
+	if !ok {
+		writeAPIError(w, http.StatusBadGateway, fmt.Sprintf("unexpected answer from the modem: %q", lines))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, signal)
+}
+
+// parseSignal reads an AT+CSQ response, e.g. +CSQ: 20,99, and the +CESQ
+// line following it if any
+func parseSignal(response []string) (APISignal, bool) {
+	q, ok := ParseSignalQuality(response)
+	if !ok {
+		return APISignal{}, false
+	}
+	signal := APISignal{CSQ: q.CSQ, BER: q.BER, Lost: q.Lost(), RSRP: q.RSRP, RSRQ: q.RSRQ}
+	if dbm, ok := q.DBm(); ok {
+		signal.DBm = &dbm
+	}
+	return signal, true
+}
+
+// track records a new pending SMS, forgetting the oldest past
+// apiSendHistory
+func (s *APIServer) track(number, contact string) APISMS {
+	sms := &APISMS{ID: newAPIID(), To: number, Contact: contact, Status: APISMSPending, Created: time.Now()}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sends[sms.ID] = sms
+	s.order = append(s.order, sms.ID)
+	if len(s.order) > apiSendHistory {
+		delete(s.sends, s.order[0])
+		s.order = s.order[1:]
+	}
+	return *sms
+}
+
+// finish records the outcome of the SMS with id
+func (s *APIServer) finish(id string, err error) APISMS {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sms, ok := s.sends[id]
+	if !ok {
+		return APISMS{ID: id}
+	}
+	now := time.Now()
+	sms.Status, sms.Finished = APISMSSent, &now
+	if err != nil {
+		sms.Status, sms.Error = APISMSFailed, err.Error()
+	}
+	return *sms
+}
+
+// audit reports a request and its outcome, see NewAPIServer
+func (s *APIServer) audit(action, outcome string) {
+	if s.auditFunc != nil {
+		s.auditFunc(action, outcome)
+	}
+}
+
+// newAPIID returns a random ID for an SMS sent through the API
+func newAPIID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeAPIJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, code int, message string) {
+	writeAPIJSON(w, code, map[string]string{"error": message})
+}
@@ -0,0 +1,267 @@
+package machine
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golte/config"
+	"golte/health"
+	"golte/logger"
+)
+
+const testAPIToken = "secret"
+
+// testAPISince is when the components of the test API last changed
+var testAPISince = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+// newTestAPI serves the API over a mock modem knowing one SIM contact.
+// smsErr makes every SMS fail with it. audits returns what was audited so
+// far.
+func newTestAPI(t *testing.T, smsErr error) (server *httptest.Server, audits func() []string) {
+	t.Helper()
+	cfg := &config.Config{
+		Modem: config.ModemConfig{Type: config.ModemTypeMock},
+		API:   config.APIConfig{ListenAddr: "127.0.0.1:0", Token: testAPIToken},
+	}
+	modem := NewModemManager(cfg, nil, nil, nil, nil)
+	modem.setPhonebook([]PhonebookEntry{{Index: 1, Name: "Alice", Number: "+33612345678"}})
+
+	var (
+		mu      sync.Mutex
+		audited []string
+		wg      sync.WaitGroup
+	)
+	sendSMS := func(number, message string) error {
+		if smsErr != nil {
+			return smsErr
+		}
+		return modem.SendSMS(number, message)
+	}
+	api := NewAPIServer(modem.config, modem, sendSMS, modem.StartCall,
+		func() health.State {
+			return health.State{Discord: "gateway reconnecting", Components: []health.Component{
+				{Name: health.ComponentModem, Since: testAPISince},
+				{Name: health.ComponentDiscord, Problem: "gateway reconnecting", Since: testAPISince},
+			}}
+		},
+		func(action, outcome string) {
+			mu.Lock()
+			defer mu.Unlock()
+			audited = append(audited, action+": "+outcome)
+		}, &wg)
+
+	server = httptest.NewServer(api.Handler())
+	t.Cleanup(func() {
+		server.Close()
+		wg.Wait()
+	})
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(audited)
+	}
+}
+
+func apiRequest(t *testing.T, server *httptest.Server, method, path, token, body string, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// waitForSMS polls GET /v1/sms/{id} until the SMS isn't pending
+func waitForSMS(t *testing.T, server *httptest.Server, id string) APISMS {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var sms APISMS
+		if code := apiRequest(t, server, "GET", "/v1/sms/"+id, testAPIToken, "", &sms); code != http.StatusOK {
+			t.Fatalf("GET /v1/sms/%s: status %d", id, code)
+		}
+		if sms.Status != APISMSPending || time.Now().After(deadline) {
+			return sms
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAPIRequiresToken(t *testing.T) {
+	server, audits := newTestAPI(t, nil)
+
+	for _, token := range []string{"", "wrong"} {
+		if code := apiRequest(t, server, "GET", "/v1/status", token, "", nil); code != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d, want 401", token, code)
+		}
+	}
+	// The second is only counted, a scan mustn't flood the audit channel
+	if audited := audits(); len(audited) != 1 || !strings.Contains(audited[0], "refused") {
+		t.Errorf("audited %q", audited)
+	}
+}
+
+func TestAPIBodyLimit(t *testing.T) {
+	server, _ := newTestAPI(t, nil)
+
+	body := `{"to": "Alice", "message": "` + strings.Repeat("a", apiMaxBody) + `"}`
+	if code := apiRequest(t, server, "POST", "/v1/sms", testAPIToken, body, nil); code != http.StatusBadRequest {
+		t.Errorf("POST /v1/sms over apiMaxBody: status %d, want 400", code)
+	}
+}
+
+func TestAPISendSMS(t *testing.T) {
+	server, audits := newTestAPI(t, nil)
+
+	var sms APISMS
+	code := apiRequest(t, server, "POST", "/v1/sms", testAPIToken, `{"to": "alice", "message": "hi"}`, &sms)
+	if code != http.StatusAccepted || sms.ID == "" || sms.To != "+33612345678" || sms.Contact != "Alice" {
+		t.Fatalf("POST /v1/sms: status %d, %+v", code, sms)
+	}
+
+	sms = waitForSMS(t, server, sms.ID)
+	if sms.Status != APISMSSent || sms.Finished == nil {
+		t.Errorf("SMS = %+v, want sent", sms)
+	}
+	// The outcome is audited after it's recorded
+	deadline := time.Now().Add(2 * time.Second)
+	for len(audits()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if audited := audits(); len(audited) != 1 || !strings.Contains(audited[0], "SMS to Alice") {
+		t.Errorf("audited %q", audited)
+	}
+
+	if code := apiRequest(t, server, "GET", "/v1/sms/unknown", testAPIToken, "", nil); code != http.StatusNotFound {
+		t.Errorf("unknown SMS: status %d, want 404", code)
+	}
+	if code := apiRequest(t, server, "POST", "/v1/sms", testAPIToken, `{"to": "+33612345678"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("SMS without message: status %d, want 400", code)
+	}
+	if code := apiRequest(t, server, "POST", "/v1/sms", testAPIToken, `{"to": "bob", "message": "hi"}`, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("unknown contact: status %d, want 422", code)
+	}
+}
+
+func TestAPISendSMSFailure(t *testing.T) {
+	server, _ := newTestAPI(t, errors.New("network refused"))
+
+	var sms APISMS
+	if code := apiRequest(t, server, "POST", "/v1/sms", testAPIToken, `{"to": "+33687654321", "message": "hi"}`, &sms); code != http.StatusAccepted {
+		t.Fatalf("POST /v1/sms: status %d", code)
+	}
+	sms = waitForSMS(t, server, sms.ID)
+	if sms.Status != APISMSFailed || sms.Error != "network refused" {
+		t.Errorf("SMS = %+v, want failed", sms)
+	}
+}
+
+func TestAPICall(t *testing.T) {
+	server, _ := newTestAPI(t, nil)
+
+	if code := apiRequest(t, server, "POST", "/v1/call", testAPIToken, `{"to": "Alice"}`, nil); code != http.StatusAccepted {
+		t.Errorf("POST /v1/call: status %d", code)
+	}
+	// The mock modem is still in the first call
+	if code := apiRequest(t, server, "POST", "/v1/call", testAPIToken, `{"to": "+33687654321"}`, nil); code != http.StatusConflict {
+		t.Errorf("second call: status %d, want 409", code)
+	}
+}
+
+func TestAPIStatusAndSignal(t *testing.T) {
+	server, _ := newTestAPI(t, nil)
+
+	var status struct {
+		Healthy bool   `json:"healthy"`
+		Discord string `json:"discord"`
+	}
+	if code := apiRequest(t, server, "GET", "/v1/status", testAPIToken, "", &status); code != http.StatusServiceUnavailable {
+		t.Errorf("GET /v1/status: status %d, want 503", code)
+	}
+	if status.Healthy || status.Discord != "gateway reconnecting" {
+		t.Errorf("status = %+v", status)
+	}
+
+	var signal APISignal
+	if code := apiRequest(t, server, "GET", "/v1/signal", testAPIToken, "", &signal); code != http.StatusOK {
+		t.Fatalf("GET /v1/signal: status %d", code)
+	}
+	if signal.CSQ < mockMinRSSI || signal.CSQ > mockMaxRSSI || signal.DBm == nil || *signal.DBm != -113+2*signal.CSQ {
+		t.Errorf("signal = %+v", signal)
+	}
+}
+
+func TestAPIProbes(t *testing.T) {
+	server, _ := newTestAPI(t, nil)
+
+	// Probes don't send the token
+	if code := apiRequest(t, server, "GET", "/healthz", "", "", nil); code != http.StatusOK {
+		t.Errorf("GET /healthz: status %d, want 200", code)
+	}
+
+	var readiness APIReadiness
+	if code := apiRequest(t, server, "GET", "/readyz", "", "", &readiness); code != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz: status %d, want 503", code)
+	}
+	if readiness.Ready || len(readiness.Components) != 2 || len(readiness.Unhealthy) != 1 {
+		t.Fatalf("readiness = %+v", readiness)
+	}
+	if c := readiness.Unhealthy[0]; c.Name != health.ComponentDiscord || c.Problem != "gateway reconnecting" || !c.Since.Equal(testAPISince) {
+		t.Errorf("unhealthy = %+v", c)
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	signal, ok := parseSignal([]string{"+CSQ: 99,99"})
+	if !ok || !signal.Lost || signal.DBm != nil {
+		t.Errorf("parseSignal(99) = %+v, %v", signal, ok)
+	}
+	signal, ok = parseSignal([]string{"+CSQ: 99,99", "+CESQ: 99,99,255,255,20,45"})
+	if !ok || signal.Lost || signal.RSRP == nil || *signal.RSRP != -96 {
+		t.Errorf("parseSignal(LTE) = %+v, %v", signal, ok)
+	}
+	if _, ok := parseSignal([]string{"OK"}); ok {
+		t.Error("parseSignal() read a response without +CSQ")
+	}
+}
+
+func TestAPILogs(t *testing.T) {
+	server, _ := newTestAPI(t, nil)
+
+	var logs struct {
+		Records []logger.Record `json:"records"`
+	}
+	if code := apiRequest(t, server, "GET", "/v1/logs?lines=5&level=error&since=2000-01-02T15:04:05Z", testAPIToken, "", &logs); code != http.StatusOK {
+		t.Fatalf("GET /v1/logs: status %d", code)
+	}
+	if len(logs.Records) > 5 || slices.ContainsFunc(logs.Records, func(r logger.Record) bool { return r.Level != slog.LevelError }) {
+		t.Errorf("records = %+v, want at most 5 errors", logs.Records)
+	}
+
+	for _, query := range []string{"lines=0", "level=trace", "since=yesterday"} {
+		if code := apiRequest(t, server, "GET", "/v1/logs?"+query, testAPIToken, "", nil); code != http.StatusBadRequest {
+			t.Errorf("GET /v1/logs?%s: status %d, want 400", query, code)
+		}
+	}
+}
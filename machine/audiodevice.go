@@ -0,0 +1,150 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"time"
+
+	"golte/ffmpeg"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+)
+
+const (
+	// deviceCheckInterval is how often the capture device node is checked
+	deviceCheckInterval = 2 * time.Second
+
+	// deviceSilence is how long the capture must have been all zeros before
+	// a reappeared device is taken as the cause
+	deviceSilence = 5 * time.Second
+)
+
+// alsaHWPattern matches ALSA hardware device names like hw:2,0 or plughw:1
+var alsaHWPattern = regexp.MustCompile(`^(?:plug)?hw:(\d+)(?:,(\d+))?$`)
+
+// alsaCaptureNode returns the /dev/snd node of an ALSA capture device, false
+// for names that don't map to a card number
+func alsaCaptureNode(device string) (string, bool) {
+	m := alsaHWPattern.FindStringSubmatch(device)
+	if m == nil {
+		return "", false
+	}
+	pcm := m[2]
+	if pcm == "" {
+		pcm = "0"
+	}
+	return fmt.Sprintf("/dev/snd/pcmC%sD%sc", m[1], pcm), true
+}
+
+// deviceWatch remembers whether the capture device node went away, so its
+// return can trigger a relaunch
+type deviceWatch struct {
+	vanished bool
+}
+
+// observe records a check of the device node and reports whether the
+// capture should be relaunched: the node came back after disappearing while
+// the capture has been silent
+func (w *deviceWatch) observe(present bool, lastSound, now time.Time) bool {
+	if !present {
+		w.vanished = true
+		return false
+	}
+	if !w.vanished || now.Sub(lastSound) < deviceSilence {
+		return false
+	}
+	w.vanished = false
+	return true
+}
+
+// watchCaptureDevice relaunches the capture when the sound card re-enumerates
+// under it, and the playback when it's on a device too, until ctx is done
+func (d *DiscordManager) watchCaptureDevice(ctx context.Context, capture *ffmpeg.AudioProvider) {
+	ticker := time.NewTicker(deviceCheckInterval)
+	defer ticker.Stop()
+
+	var watch deviceWatch
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			device := capture.Device()
+			node, ok := alsaCaptureNode(device)
+			if !ok {
+				continue
+			}
+			_, err := os.Stat(node)
+			if !watch.observe(err == nil, capture.Stats().LastSound, now) {
+				continue
+			}
+
+			d.logger.Warn("Capture device reappeared, relaunching capture", slog.String("device", device))
+			if err := capture.ReconfigureDevice(device); err != nil {
+				d.logger.Error("Failed to relaunch capture", slog.Any("error", err))
+			}
+			if output := d.playbackDevice(); output != "" {
+				d.logger.Warn("Relaunching playback", slog.String("device", output))
+				if err := d.playback.ReconfigureDevice(output); err != nil {
+					d.logger.Error("Failed to relaunch playback", slog.Any("error", err))
+				}
+			}
+		}
+	}
+}
+
+// playbackDevice returns the ALSA device the call audio is played to, empty
+// when it's the default output, which can't be switched
+func (d *DiscordManager) playbackDevice() string {
+	if d.playback == nil {
+		return ""
+	}
+	return d.playback.Device()
+}
+
+// handleAudioDevice shows or switches the ALSA device the call audio is
+// captured from, and played to when audio.playback_device is set
+func (d *DiscordManager) handleAudioDevice(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) {
+	if d.capture == nil {
+		d.respondEphemeral(event.CreateMessage, "The voice channel is not connected yet")
+		return
+	}
+
+	device, ok := data.OptString("name")
+	if !ok || device == "" {
+		status := "never heard audio"
+		if last := d.capture.Stats().LastSound; !last.IsZero() {
+			status = fmt.Sprintf("last heard audio %s ago", time.Since(last).Round(time.Second))
+		}
+		message := fmt.Sprintf("🎚️ Capturing from `%s`, %s", d.capture.Device(), status)
+		if output := d.playbackDevice(); output != "" {
+			message += fmt.Sprintf("\n🔊 Playing to `%s`", output)
+		}
+		d.respondEphemeral(event.CreateMessage, message)
+		return
+	}
+
+	d.logger.Info("Received audio device command from Discord",
+		slog.String("device", device),
+		slog.String("user", event.User().Username))
+
+	if err := d.capture.ReconfigureDevice(device); err != nil {
+		d.logger.Error("Failed to switch audio device", slog.String("device", device), slog.Any("error", err))
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("Audio device has **not** been changed: %v", err))
+		return
+	}
+	if d.playbackDevice() == "" {
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("🎚️ Now capturing from `%s`", device))
+		return
+	}
+	if err := d.playback.ReconfigureDevice(device); err != nil {
+		d.logger.Error("Failed to switch playback device", slog.String("device", device), slog.Any("error", err))
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("🎚️ Now capturing from `%s`, but playback has **not** been moved: %v", device, err))
+		return
+	}
+	d.respondEphemeral(event.CreateMessage, fmt.Sprintf("🎚️ Now capturing from and playing to `%s`", device))
+}
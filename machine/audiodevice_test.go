@@ -0,0 +1,44 @@
+package machine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestALSACaptureNode(t *testing.T) {
+	tests := map[string]string{
+		"hw:2,0":     "/dev/snd/pcmC2D0c",
+		"plughw:1,3": "/dev/snd/pcmC1D3c",
+		"hw:0":       "/dev/snd/pcmC0D0c",
+		"default":    "",
+		"hw:Device":  "",
+	}
+	for device, want := range tests {
+		got, ok := alsaCaptureNode(device)
+		if got != want || ok != (want != "") {
+			t.Errorf("alsaCaptureNode(%q) = %q, %v, want %q", device, got, ok, want)
+		}
+	}
+}
+
+func TestDeviceWatch(t *testing.T) {
+	now := time.Now()
+	silent := now.Add(-time.Minute)
+
+	var w deviceWatch
+	if w.observe(true, silent, now) {
+		t.Fatal("a device that never vanished must not be relaunched")
+	}
+	if w.observe(false, silent, now) {
+		t.Fatal("a missing device can't be relaunched")
+	}
+	if w.observe(true, now, now) {
+		t.Fatal("a capture still hearing audio must not be relaunched")
+	}
+	if !w.observe(true, silent, now) {
+		t.Fatal("a reappeared device with a silent capture should be relaunched")
+	}
+	if w.observe(true, silent, now) {
+		t.Fatal("the relaunch must only happen once")
+	}
+}
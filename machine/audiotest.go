@@ -0,0 +1,114 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golte/config"
+	"golte/ffmpeg"
+	"golte/playback"
+
+	disgoorgffmpeg "github.com/disgoorg/ffmpeg-audio"
+	"github.com/gopxl/beep/v2"
+)
+
+// NewPlayback creates the playback of prompts and tones to the modem, as
+// used during calls: on audio.playback_device through ffmpeg if set, else
+// on the default ALSA output
+func NewPlayback(cfg *config.Config) (*playback.Playback, error) {
+	sampleRate := beep.SampleRate(48000)
+	var (
+		pb  *playback.Playback
+		err error
+	)
+	if device := cfg.Audio.PlaybackDevice; device != "" {
+		pb, err = playback.NewPlaybackOutput(sampleRate, ffmpeg.NewOutput(device, sampleRate))
+	} else {
+		pb, err = playback.NewPlayback(sampleRate)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pb.SetDuckDepth(cfg.Audio.DuckDepthDB)
+	return pb, nil
+}
+
+// NewCaptureProvider starts capturing the modem audio with ffmpeg, as sent
+// to Discord during calls
+func NewCaptureProvider(ctx context.Context, cfg *config.Config) (*ffmpeg.AudioProvider, error) {
+	capture := cfg.Audio.Capture
+	return ffmpeg.New(ctx,
+		ffmpeg.ProviderConfig{CaptureFrame: capture.Frame, UnderrunGrace: capture.UnderrunGrace, Device: cfg.Audio.Device},
+		disgoorgffmpeg.WithChannels(voiceChannels),
+		disgoorgffmpeg.WithSampleRate(voiceSampleRate),
+		disgoorgffmpeg.WithBufferSize(capture.BufferSize),
+	)
+}
+
+// loopbackWarmup is how long the capture runs before the loopback tone, so
+// ffmpeg's startup isn't counted as latency
+const loopbackWarmup = 500 * time.Millisecond
+
+// PlayTestTone plays the 1kHz beep for duration and waits for it to end
+func PlayTestTone(ctx context.Context, pb *playback.Playback, duration time.Duration) error {
+	if err := pb.AddTone(playback.ToneBeep, duration); err != nil {
+		return err
+	}
+	return pb.Drain(ctx)
+}
+
+// RecordCapture captures duration of modem audio, returning mono samples at
+// the voice sample rate
+func RecordCapture(ctx context.Context, cfg *config.Config, duration time.Duration) ([]int16, error) {
+	provider, err := NewCaptureProvider(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the capture: %w", err)
+	}
+	defer provider.Close()
+
+	var samples []int16
+	for range int(duration / ffmpeg.OutputFrame) {
+		frame, err := provider.ProvidePCMFrame()
+		if err != nil {
+			return samples, fmt.Errorf("capture stopped: %w", err)
+		}
+		samples = append(samples, frame...)
+	}
+	return samples, nil
+}
+
+// MeasureLoopback plays the beep for duration while capturing, and reports
+// how long it took to be heard and how loud. The modem's audio output must be
+// looped back to its input, or the call audio must echo it.
+func MeasureLoopback(ctx context.Context, cfg *config.Config, pb *playback.Playback, duration time.Duration, thresholdDB float64) (ffmpeg.LoopbackResult, error) {
+	provider, err := NewCaptureProvider(ctx, cfg)
+	if err != nil {
+		return ffmpeg.LoopbackResult{}, fmt.Errorf("failed to start the capture: %w", err)
+	}
+	defer provider.Close()
+
+	var (
+		frames []ffmpeg.TimedFrame
+		start  time.Time
+	)
+	warmupEnd := time.Now().Add(loopbackWarmup)
+	for {
+		frame, err := provider.ProvidePCMFrame()
+		if err != nil {
+			return ffmpeg.LoopbackResult{}, fmt.Errorf("capture stopped: %w", err)
+		}
+		now := time.Now()
+		frames = append(frames, ffmpeg.TimedFrame{At: now, PCM: frame})
+
+		switch {
+		case start.IsZero() && now.After(warmupEnd):
+			start = now
+			if err := pb.AddTone(playback.ToneBeep, duration); err != nil {
+				return ffmpeg.LoopbackResult{}, err
+			}
+		case !start.IsZero() && now.Sub(start) > duration+time.Second:
+			return ffmpeg.MeasureLoopback(frames, start, thresholdDB), nil
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package machine
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"golte/config"
+
+	"github.com/warthog618/modem/at"
+	"github.com/warthog618/modem/serial"
+)
+
+// baudProbes is how many times AT is sent at each rate, the first command
+// after switching rates tends to be garbled
+const baudProbes = 2
+
+// DetectBaud returns the first of bauds the modem on device answers AT at
+func DetectBaud(device string, bauds []int) (int, error) {
+	return detectBaud(func(baud int) (io.ReadWriteCloser, error) {
+		return serial.New(serial.WithPort(device), serial.WithBaud(baud))
+	}, bauds, pingTimeout)
+}
+
+func detectBaud(open func(baud int) (io.ReadWriteCloser, error), bauds []int, timeout time.Duration) (int, error) {
+	tried := make([]string, 0, len(bauds))
+	for _, baud := range bauds {
+		port, err := open(baud)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create serial connection: %w", err)
+		}
+
+		a := at.New(port, at.WithTimeout(timeout))
+		answered := false
+		for range baudProbes {
+			if _, err := a.Command(""); err == nil {
+				answered = true
+				break
+			}
+		}
+		port.Close()
+
+		if answered {
+			return baud, nil
+		}
+		tried = append(tried, strconv.Itoa(baud))
+	}
+	return 0, fmt.Errorf("the modem didn't answer AT at %s baud", strings.Join(tried, ", "))
+}
+
+// baud returns the serial rate of the modem. With modem.baud auto it's
+// detected the first time, and kept for reconnections.
+func (m *ModemManager) baud() (int, error) {
+	if baud := m.config().Modem.Baud; baud != config.BaudAuto {
+		return baud, nil
+	}
+	if baud := m.detectedBaud.Load(); baud != 0 {
+		return int(baud), nil
+	}
+
+	baud, err := DetectBaud(m.config().Modem.Device, config.AutoBauds)
+	if err != nil {
+		return 0, err
+	}
+	m.detectedBaud.Store(int64(baud))
+	m.logger.Info("Detected modem baud rate", slog.Int("baud", baud))
+	return baud, nil
+}
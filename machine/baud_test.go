@@ -0,0 +1,63 @@
+package machine
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSerial is a serial port at some rate, the modem only makes sense of
+// commands at its own
+type fakeSerial struct {
+	r      *io.PipeReader
+	w      *io.PipeWriter
+	answer bool
+}
+
+func newFakeSerial(answer bool) *fakeSerial {
+	r, w := io.Pipe()
+	return &fakeSerial{r: r, w: w, answer: answer}
+}
+
+func (f *fakeSerial) Read(p []byte) (int, error) {
+	return f.r.Read(p)
+}
+
+func (f *fakeSerial) Write(p []byte) (int, error) {
+	if f.answer && strings.HasPrefix(string(p), "AT") {
+		go f.w.Write([]byte("\r\nOK\r\n"))
+	}
+	return len(p), nil
+}
+
+func (f *fakeSerial) Close() error {
+	return f.w.Close()
+}
+
+func TestDetectBaud(t *testing.T) {
+	var opened []int
+	open := func(baud int) (io.ReadWriteCloser, error) {
+		opened = append(opened, baud)
+		return newFakeSerial(baud == 57600), nil
+	}
+
+	baud, err := detectBaud(open, []int{9600, 19200, 57600, 115200}, 20*time.Millisecond)
+	if err != nil || baud != 57600 {
+		t.Errorf("detectBaud() = %d, %v, want 57600", baud, err)
+	}
+	if len(opened) != 3 {
+		t.Errorf("opened the port at %v, should stop at the first answer", opened)
+	}
+
+	_, err = detectBaud(open, []int{9600, 19200}, 20*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "9600, 19200") {
+		t.Errorf("detectBaud() without an answer = %v", err)
+	}
+
+	failing := func(int) (io.ReadWriteCloser, error) { return nil, errors.New("no such device") }
+	if _, err := detectBaud(failing, []int{9600}, 20*time.Millisecond); err == nil {
+		t.Error("detectBaud() should fail when the port can't be opened")
+	}
+}
@@ -0,0 +1,156 @@
+package machine
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+)
+
+// broadcastResult is the outcome of one SMS of a broadcast
+type broadcastResult struct {
+	number string
+	sent   bool
+	err    error
+}
+
+// resolveRecipients turns a comma separated list of numbers and group names
+// into the numbers to text, without duplicates. Entries starting with + or a
+// digit are numbers, the others name a group.
+func resolveRecipients(list string, groups map[string][]string, max int) ([]string, error) {
+	var (
+		numbers []string
+		seen    = make(map[string]bool)
+	)
+	add := func(number string) {
+		if key := normalizeNumber(number); key != "" && !seen[key] {
+			seen[key] = true
+			numbers = append(numbers, strings.TrimSpace(number))
+		}
+	}
+
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case strings.ContainsAny(entry[:1], "+0123456789"):
+			add(entry)
+		default:
+			// viper lowercases map keys
+			group, ok := groups[strings.ToLower(entry)]
+			if !ok {
+				return nil, fmt.Errorf("no group named %q", entry)
+			}
+			for _, number := range group {
+				add(number)
+			}
+		}
+	}
+
+	if len(numbers) == 0 {
+		return nil, fmt.Errorf("no recipients")
+	}
+	if len(numbers) > max {
+		return nil, fmt.Errorf("%d recipients, a broadcast can reach at most %d", len(numbers), max)
+	}
+	return numbers, nil
+}
+
+// broadcastEmbed lists the recipients of a broadcast and how sending to each
+// went
+func broadcastEmbed(results []broadcastResult, done bool) discord.Embed {
+	var (
+		list   strings.Builder
+		failed int
+	)
+	for _, r := range results {
+		switch {
+		case r.err != nil:
+			failed++
+			fmt.Fprintf(&list, "❌ `%s` %v\n", r.number, r.err)
+		case r.sent:
+			fmt.Fprintf(&list, "✅ `%s`\n", r.number)
+		default:
+			fmt.Fprintf(&list, "⏳ `%s`\n", r.number)
+		}
+	}
+
+	title := "📣 Broadcasting…"
+	color := 0x0099ff
+	if done {
+		title = fmt.Sprintf("📣 Broadcast sent to %d of %d", len(results)-failed, len(results))
+		color = 0x00ff00
+		if failed > 0 {
+			color = 0xff9900
+		}
+	}
+	return discord.NewEmbedBuilder().
+		SetTitle(title).
+		SetDescription(list.String()).
+		SetColor(color).
+		Build()
+}
+
+// handleBroadcast texts the same message to a few numbers or groups, one
+// after the other, and keeps the response updated with each outcome
+func (d *DiscordManager) handleBroadcast(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) {
+	cfg := d.config().Broadcast
+	if cfg.MaxRecipients == 0 {
+		d.respondEphemeral(event.CreateMessage, "Broadcasting is disabled, see `broadcast.max_recipients`")
+		return
+	}
+
+	message := data.String("message")
+	numbers, err := resolveRecipients(data.String("to"), cfg.Groups, cfg.MaxRecipients)
+	if err != nil {
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("Broadcast has **not** been sent: %v", err))
+		return
+	}
+
+	d.logger.Info("Received broadcast command from Discord",
+		slog.Int("recipients", len(numbers)),
+		slog.String("user", event.User().Username))
+
+	results := make([]broadcastResult, len(numbers))
+	for i, number := range numbers {
+		results[i].number = number
+	}
+	err = event.CreateMessage(discord.NewMessageCreateBuilder().
+		SetEmbeds(broadcastEmbed(results, false)).
+		SetEphemeral(true).
+		Build())
+	if err != nil {
+		d.logger.Error("Failed to send Discord response", slog.Any("error", err))
+		return
+	}
+
+	go func() {
+		for i, number := range numbers {
+			if i > 0 {
+				time.Sleep(cfg.Interval)
+			}
+
+			if err := d.smsFunc(number, message); err != nil {
+				d.logger.Error("Failed to send broadcast SMS",
+					slog.String("number", number),
+					slog.Any("error", err))
+				results[i].err = err
+			} else {
+				results[i].sent = true
+				d.logOutbound(event.User().ID, number, message)
+			}
+
+			_, err := event.Client().Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(),
+				discord.NewMessageUpdateBuilder().
+					SetEmbeds(broadcastEmbed(results, i == len(numbers)-1)).
+					Build())
+			if err != nil {
+				d.logger.Error("Failed to update broadcast response", slog.Any("error", err))
+			}
+		}
+		d.logger.Info("Broadcast done", slog.Int("recipients", len(numbers)))
+	}()
+}
@@ -0,0 +1,26 @@
+package machine
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestResolveRecipients(t *testing.T) {
+	groups := map[string][]string{
+		"oncall": {"+33612345678", "+33687654321"},
+	}
+
+	numbers, err := resolveRecipients("OnCall, 0033612345678, +33600000000,", groups, 3)
+	if err != nil {
+		t.Fatalf("resolveRecipients() = %v", err)
+	}
+	if want := []string{"+33612345678", "+33687654321", "+33600000000"}; !slices.Equal(numbers, want) {
+		t.Errorf("resolveRecipients() = %q, want %q", numbers, want)
+	}
+
+	for _, list := range []string{"", " , ", "friends", "oncall,+33600000000,+33600000001"} {
+		if _, err := resolveRecipients(list, groups, 3); err == nil {
+			t.Errorf("resolveRecipients(%q) should fail", list)
+		}
+	}
+}
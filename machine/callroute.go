@@ -0,0 +1,122 @@
+package machine
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"golte/config"
+
+	"github.com/warthog618/modem/at"
+)
+
+// callControl is what routing an incoming call does to it, see call.Call
+type callControl interface {
+	PickUp(options ...at.CommandOption) error
+	HangUp(options ...at.CommandOption) error
+}
+
+// ivrRoute is the route of callers matching no call.routes entry
+var ivrRoute = config.CallRoute{Action: config.CallActionIVR}
+
+// routeCall returns the first route matching the caller, ivrRoute if none
+// does. isContact reports whether a number is in the SIM phonebook.
+func routeCall(routes []config.CallRoute, number string, isContact func(string) bool) config.CallRoute {
+	normalized := normalizeNumber(number)
+	for _, route := range routes {
+		for _, pattern := range route.Numbers {
+			if callerMatches(pattern, normalized, isContact) {
+				return route
+			}
+		}
+	}
+	return ivrRoute
+}
+
+// callerMatches reports whether a normalized caller number matches a
+// pattern of call.routes
+func callerMatches(pattern, number string, isContact func(string) bool) bool {
+	switch pattern {
+	case config.CallPatternAny:
+		return true
+	case config.CallPatternWithheld:
+		return number == ""
+	case config.CallPatternContacts:
+		return number != "" && isContact(number)
+	case config.CallPatternUnknown:
+		return number == "" || !isContact(number)
+	}
+	if number == "" {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(number, normalizeNumber(prefix))
+	}
+	return number == normalizeNumber(pattern)
+}
+
+// routeIncomingCall does what call.routes says for a call ringing from
+// number, answering it with the password IVR by default
+func (m *ModemManager) routeIncomingCall(c callControl, number string) {
+	route := routeCall(m.config().Call.Routes, number, func(n string) bool {
+		_, ok := m.PhonebookName(n)
+		return ok
+	})
+	m.logger.Info("Routing incoming call", slog.String("number", number), slog.String("action", route.Action))
+
+	switch route.Action {
+	case config.CallActionIgnore:
+		m.callNotifyCallback(MQTTCallIncoming, number, "📞 Incoming voice call, left ringing by call.routes")
+		return
+	case config.CallActionReject, config.CallActionSMS:
+		if err := c.HangUp(); err != nil {
+			m.logger.Error("Failed to reject call", slog.Any("error", err))
+		}
+		if route.Action == config.CallActionReject || number == "" {
+			m.callNotifyCallback(MQTTCallIncoming, number, "📞 Incoming voice call, rejected by call.routes")
+			return
+		}
+		m.callNotifyCallback(MQTTCallIncoming, number, "📞 Incoming voice call, rejected by call.routes and answered by SMS")
+		// Sending takes seconds, the call handler mustn't wait for it
+		go func() {
+			defer recoverPanic(m.logger, "call route SMS", nil, m.panicFunc)
+			if err := m.SendSMS(number, route.Message); err != nil {
+				m.logger.Error("Failed to answer a rejected call by SMS", slog.String("number", number), slog.Any("error", err))
+			}
+		}()
+		return
+	case config.CallActionAnnounce:
+		m.announceCall(c, number, route)
+		return
+	}
+
+	// Nobody could hear the caller without voice
+	if m.playback == nil {
+		m.callNotifyCallback(MQTTCallIncoming, number, "📞 Incoming voice call, not answered as voice is disabled")
+		return
+	}
+	// Nor could they enter the password
+	if route.Action == config.CallActionIVR && !m.capabilities().DTMF {
+		m.callNotifyCallback(MQTTCallIncoming, number, "📞 Incoming voice call, not answered as the modem can't detect keypresses")
+		return
+	}
+
+	message := "📞 Incoming voice call"
+	if route.Action == config.CallActionVoice {
+		message = "📞 Incoming voice call, answered into the voice channel by call.routes"
+	}
+	m.callNotifyCallback(MQTTCallIncoming, number, message)
+	c.PickUp()
+	m.state.Reset()
+	m.watchCall(number, m.config().Call.MaxDuration)
+
+	if route.Action == config.CallActionIVR {
+		if m.config().Security.DTMFPinHash == "" {
+			m.logger.Warn("No security.dtmf_pin_hash is set, no PIN lets the caller through")
+		}
+		time.Sleep(1 * time.Second) // Wait for call to connect
+		if err := m.playPrompt(passwordPrompt); err != nil {
+			m.logger.Error("Failed to play prompt", slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package machine
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"golte/config"
+
+	"github.com/warthog618/modem/at"
+)
+
+func TestRouteCall(t *testing.T) {
+	routes := []config.CallRoute{
+		{Numbers: []string{"+33 6 12 34 56 78"}, Action: config.CallActionVoice},
+		{Numbers: []string{"+3389*", config.CallPatternWithheld}, Action: config.CallActionReject},
+		{Numbers: []string{config.CallPatternUnknown}, Action: config.CallActionSMS, Message: "Text me"},
+	}
+	isContact := func(number string) bool { return number == "+33687654321" }
+
+	tests := []struct {
+		number string
+		action string
+	}{
+		{"0033612345678", config.CallActionVoice},
+		{"+33892123456", config.CallActionReject},
+		{"", config.CallActionReject},
+		{"+33700000000", config.CallActionSMS},
+		{"+33687654321", config.CallActionIVR}, // a contact, matching no route
+	}
+	for _, tt := range tests {
+		if got := routeCall(routes, tt.number, isContact); got.Action != tt.action {
+			t.Errorf("routeCall(%q) = %s, want %s", tt.number, got.Action, tt.action)
+		}
+	}
+	if got := routeCall(nil, "+33612345678", isContact); got.Action != config.CallActionIVR {
+		t.Errorf("routeCall() without routes = %s, want ivr", got.Action)
+	}
+}
+
+// fakeCall records what routing did to a call
+type fakeCall struct {
+	pickedUp, hungUp bool
+}
+
+func (c *fakeCall) PickUp(...at.CommandOption) error {
+	c.pickedUp = true
+	return nil
+}
+
+func (c *fakeCall) HangUp(...at.CommandOption) error {
+	c.hungUp = true
+	return nil
+}
+
+// recordingTransport hands the SMS sent over a channel, they're sent in the
+// background
+type recordingTransport struct {
+	fakeSMS
+	sent chan string
+}
+
+func (r *recordingTransport) SendShortMessage(number, message string) (string, error) {
+	r.sent <- number + ": " + message
+	return "1", nil
+}
+
+func TestRouteIncomingCall(t *testing.T) {
+	sms := &recordingTransport{sent: make(chan string, 1)}
+	cfg := &config.Config{Call: config.CallConfig{Routes: []config.CallRoute{
+		{Numbers: []string{"+33612345678"}, Action: config.CallActionSMS, Message: "Text me"},
+		{Numbers: []string{"+33687654321"}, Action: config.CallActionIgnore},
+	}}}
+	var (
+		mu       sync.Mutex
+		notified []string
+	)
+	m := NewModemManager(cfg, nil, sms, func(event, from, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		// The event comes from the route, not from the wording
+		if event != MQTTCallIncoming {
+			t.Errorf("call notified as %q, want %q", event, MQTTCallIncoming)
+		}
+		notified = append(notified, message)
+	}, nil)
+
+	var rejected fakeCall
+	m.routeIncomingCall(&rejected, "+33612345678")
+	if !rejected.hungUp || rejected.pickedUp {
+		t.Errorf("sms route: %+v, want hung up", rejected)
+	}
+	select {
+	case got := <-sms.sent:
+		if got != "+33612345678: Text me" {
+			t.Errorf("replied %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no SMS reply sent")
+	}
+
+	var ignored fakeCall
+	m.routeIncomingCall(&ignored, "+33687654321")
+	if ignored.hungUp || ignored.pickedUp {
+		t.Errorf("ignore route: %+v, want left ringing", ignored)
+	}
+
+	// Without voice the IVR can't answer
+	var unanswered fakeCall
+	m.routeIncomingCall(&unanswered, "+33700000000")
+	if unanswered.pickedUp {
+		t.Error("answered without voice")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(notified) != 3 {
+		t.Errorf("notified %q, want every call", notified)
+	}
+}
+
+func TestAnnounceCallWithoutVoice(t *testing.T) {
+	sms := &recordingTransport{sent: make(chan string, 1)}
+	cfg := &config.Config{Call: config.CallConfig{Routes: []config.CallRoute{
+		{Numbers: []string{"*"}, Action: config.CallActionAnnounce, Audio: "annonce.mp3", Message: "This line is closed, text me"},
+	}}}
+	m := NewModemManager(cfg, nil, sms, func(event, from, message string) {}, nil)
+
+	// Nobody could hear the announcement, the caller still gets the message
+	var announced fakeCall
+	m.routeIncomingCall(&announced, "+33612345678")
+	if announced.pickedUp || !announced.hungUp {
+		t.Errorf("announce route without voice: %+v, want rejected", announced)
+	}
+	select {
+	case got := <-sms.sent:
+		if got != "+33612345678: This line is closed, text me" {
+			t.Errorf("texted %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no SMS sent after the announcement")
+	}
+}
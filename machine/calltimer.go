@@ -0,0 +1,129 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"golte/call"
+)
+
+// callPollInterval is how often the call status is polled to enforce the
+// maximum call duration
+const callPollInterval = 2 * time.Second
+
+// callMaxDuration returns the limit of a call asked to last at most
+// minutes, within call.max_duration when it sets one. 0 keeps the
+// configured limit.
+func callMaxDuration(minutes int, configured time.Duration) time.Duration {
+	if minutes <= 0 {
+		return 0
+	}
+	limit := time.Duration(minutes) * time.Minute
+	if configured > 0 && limit > configured {
+		return configured
+	}
+	return limit
+}
+
+// callTimer tracks how long a call has been answered
+type callTimer struct {
+	limit      time.Duration
+	answeredAt time.Time
+}
+
+// observe records a poll of the current calls. It reports whether the call
+// ended, and whether it was answered more than limit ago.
+func (t *callTimer) observe(calls []call.CallStatus, now time.Time) (overLimit, ended bool) {
+	if len(calls) == 0 {
+		return false, true
+	}
+
+	for _, c := range calls {
+		if c.Status == "ACTIVE" {
+			if t.answeredAt.IsZero() {
+				t.answeredAt = now
+			}
+			return now.Sub(t.answeredAt) >= t.limit, false
+		}
+	}
+
+	// Still ringing
+	return false, false
+}
+
+// watchCall hangs up the call with number once it has been answered for
+// limit, replacing the watch of the previous call. A limit of 0 doesn't watch
+// the call.
+func (m *ModemManager) watchCall(number string, limit time.Duration) {
+	m.stopCallWatch()
+	if limit <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.callWatchMu.Lock()
+	m.cancelCallWatch = cancel
+	m.callWatchMu.Unlock()
+
+	go func() {
+		defer cancel()
+		defer recoverPanic(m.logger, "call watch", nil, m.panicFunc)
+
+		ticker := time.NewTicker(callPollInterval)
+		defer ticker.Stop()
+
+		timer := callTimer{limit: limit}
+		for {
+			var now time.Time
+			select {
+			case <-ctx.Done():
+				return
+			case now = <-ticker.C:
+			}
+
+			c := m.callManager()
+			if c == nil {
+				return
+			}
+			calls, err := c.GetCallStatus()
+			if err != nil {
+				m.logger.Debug("Failed to poll call status", slog.Any("error", err))
+				continue
+			}
+
+			overLimit, ended := timer.observe(calls, now)
+			if ended {
+				return
+			}
+			if !overLimit {
+				continue
+			}
+
+			m.logger.Warn("Call reached its maximum duration, hanging up",
+				slog.String("number", number),
+				slog.Duration("max_duration", limit))
+			message := fmt.Sprintf("⏱️ Call hung up after reaching its maximum duration of %s", limit)
+			if err := c.HangUp(); err != nil {
+				m.logger.Error("Failed to hang up call", slog.Any("error", err))
+				message = fmt.Sprintf("⏱️ Call reached its maximum duration of %s but hanging up failed: %v", limit, err)
+			}
+			if m.callNotifyCallback != nil {
+				m.callNotifyCallback(MQTTCallNotice, number, message)
+			}
+			return
+		}
+	}()
+}
+
+// stopCallWatch stops enforcing the maximum duration of the current call
+func (m *ModemManager) stopCallWatch() {
+	m.callWatchMu.Lock()
+	defer m.callWatchMu.Unlock()
+
+	if m.cancelCallWatch != nil {
+		m.cancelCallWatch()
+		m.cancelCallWatch = nil
+	}
+}
@@ -0,0 +1,52 @@
+package machine
+
+import (
+	"testing"
+	"time"
+
+	"golte/call"
+)
+
+func TestCallTimer(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	timer := callTimer{limit: time.Minute}
+
+	ringing := []call.CallStatus{{Status: "ALERTING"}}
+	active := []call.CallStatus{{Status: "ACTIVE"}}
+
+	// Ringing doesn't count towards the limit
+	if over, ended := timer.observe(ringing, start.Add(5*time.Minute)); over || ended {
+		t.Fatalf("observe(ringing) = %v, %v", over, ended)
+	}
+	answered := start.Add(6 * time.Minute)
+	if over, _ := timer.observe(active, answered); over {
+		t.Fatal("call over the limit when answered")
+	}
+	if over, _ := timer.observe(active, answered.Add(59*time.Second)); over {
+		t.Fatal("call over the limit before it")
+	}
+	if over, _ := timer.observe(active, answered.Add(time.Minute)); !over {
+		t.Fatal("call not over the limit")
+	}
+	if _, ended := timer.observe(nil, answered.Add(2*time.Minute)); !ended {
+		t.Fatal("call not ended without calls")
+	}
+}
+
+func TestCallMaxDuration(t *testing.T) {
+	for _, tc := range []struct {
+		minutes    int
+		configured time.Duration
+		want       time.Duration
+	}{
+		{0, time.Hour, 0},
+		{-5, time.Hour, 0},
+		{15, time.Hour, 15 * time.Minute},
+		{120, time.Hour, time.Hour},
+		{120, 0, 2 * time.Hour},
+	} {
+		if got := callMaxDuration(tc.minutes, tc.configured); got != tc.want {
+			t.Errorf("callMaxDuration(%d, %v) = %v, want %v", tc.minutes, tc.configured, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"golte/call"
+
+	"github.com/warthog618/modem/gsm"
+)
+
+// CallOutcome is how a tracked call ended
+type CallOutcome string
+
+const (
+	CallCompleted CallOutcome = "completed" // answered, then hung up by either side
+	CallBusy      CallOutcome = "busy"
+	CallNoAnswer  CallOutcome = "no answer"
+	CallFailed    CallOutcome = "failed" // ended without being answered for another reason
+)
+
+const (
+	// callTrackInterval is how often TrackCall polls the call status
+	callTrackInterval = 500 * time.Millisecond
+
+	// callSetupTimeout is how long TrackCall waits for the call to show
+	// up in the call list
+	callSetupTimeout = 10 * time.Second
+)
+
+// InitializeDialer sets up the modem to place calls only, without the SMS,
+// incoming call, DTMF and SIM toolkit handlers of the bridge, for one-shot
+// commands
+func (m *ModemManager) InitializeDialer() error {
+	if m.mock != nil {
+		return nil
+	}
+	a, err := m.open()
+	if err != nil {
+		return err
+	}
+	if caps := m.probeCapabilities(a.Command); !caps.Voice {
+		m.closePort()
+		return notSupported("voice calls")
+	}
+
+	g := gsm.New(a)
+	if err := g.Init(); err != nil {
+		m.closePort()
+		return fmt.Errorf("failed to initialize modem: %w", err)
+	}
+	c := call.New(a)
+	if err := c.Init(); err != nil {
+		m.closePort()
+		return fmt.Errorf("failed to initialize call manager: %w", err)
+	}
+
+	m.connMu.Lock()
+	m.gsm, m.call = g, c
+	m.connMu.Unlock()
+	return nil
+}
+
+// TrackCall follows the current outgoing call until it ends, passing each
+// status it goes through, like DIALING, ALERTING or ACTIVE, to onChange
+func (m *ModemManager) TrackCall(ctx context.Context, onChange func(status string)) (CallOutcome, error) {
+	c := m.callManager()
+	if c == nil {
+		return "", ErrNoModem
+	}
+
+	codes := make(chan string, 1)
+	if err := c.WatchEnd(func(code string) {
+		select {
+		case codes <- code:
+		default:
+		}
+	}); err != nil {
+		return "", err
+	}
+	defer c.StopWatchingEnd()
+
+	ticker := time.NewTicker(callTrackInterval)
+	defer ticker.Stop()
+	setup := time.After(callSetupTimeout)
+
+	var (
+		status   string
+		seen     bool
+		answered bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case code := <-codes:
+			return callOutcome(answered, code), nil
+		case <-setup:
+			if !seen {
+				return CallFailed, fmt.Errorf("the call didn't start within %s", callSetupTimeout)
+			}
+		case <-ticker.C:
+		}
+
+		calls, err := c.GetCallStatus()
+		if err != nil {
+			m.logger.Debug("Failed to poll call status", slog.Any("error", err))
+			continue
+		}
+		if len(calls) == 0 {
+			if !seen {
+				continue
+			}
+			// The result code, if any, usually comes with the end of the call
+			select {
+			case code := <-codes:
+				return callOutcome(answered, code), nil
+			case <-time.After(callTrackInterval):
+				return callOutcome(answered, ""), nil
+			}
+		}
+
+		seen = true
+		if calls[0].Status != status {
+			status = calls[0].Status
+			answered = answered || status == "ACTIVE"
+			onChange(status)
+		}
+	}
+}
+
+// callOutcome tells how a call ended from whether it was answered and the
+// result code the modem sent, if any
+func callOutcome(answered bool, code string) CallOutcome {
+	switch {
+	case answered:
+		return CallCompleted
+	case code == call.EndBusy:
+		return CallBusy
+	case code == call.EndNoAnswer:
+		return CallNoAnswer
+	default:
+		return CallFailed
+	}
+}
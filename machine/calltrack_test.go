@@ -0,0 +1,27 @@
+package machine
+
+import (
+	"testing"
+
+	"golte/call"
+)
+
+func TestCallOutcome(t *testing.T) {
+	tests := []struct {
+		answered bool
+		code     string
+		want     CallOutcome
+	}{
+		{true, call.EndNoCarrier, CallCompleted},
+		{true, "", CallCompleted},
+		{false, call.EndBusy, CallBusy},
+		{false, call.EndNoAnswer, CallNoAnswer},
+		{false, call.EndNoCarrier, CallFailed},
+		{false, "", CallFailed},
+	}
+	for _, tt := range tests {
+		if got := callOutcome(tt.answered, tt.code); got != tt.want {
+			t.Errorf("callOutcome(%t, %q) = %q, want %q", tt.answered, tt.code, got, tt.want)
+		}
+	}
+}
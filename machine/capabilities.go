@@ -0,0 +1,149 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/warthog618/modem/at"
+	"github.com/warthog618/modem/info"
+)
+
+// ErrNotSupported is returned for features the modem said it lacks when
+// probed
+var ErrNotSupported = errors.New("not supported by the modem")
+
+// Capabilities are the optional features of a modem, found by sending the
+// test form (AT+CMD=?) of their commands
+type Capabilities struct {
+	Voice bool `json:"voice"` // AT+CLCC, voice calls
+	DTMF  bool `json:"dtmf"`  // AT+DDET, detecting the keypresses of callers
+	USSD  bool `json:"ussd"`  // AT+CUSD
+	PDU   bool `json:"pdu"`   // AT+CMGF=0, needed for long SMS
+	CESQ  bool `json:"cesq"`  // AT+CESQ, the LTE signal quality
+	QCSQ  bool `json:"qcsq"`  // AT+QCSQ of Quectel modems, the LTE signal with its SINR
+}
+
+// allCapabilities are assumed until the modem is probed, so a failed probe
+// doesn't turn anything off
+var allCapabilities = Capabilities{Voice: true, DTMF: true, USSD: true, PDU: true, CESQ: true, QCSQ: true}
+
+// Items lists the capabilities like Info items, for golte modem info
+func (c Capabilities) Items() []InfoItem {
+	items := make([]InfoItem, 0, 6)
+	for _, capability := range []struct {
+		name      string
+		supported bool
+	}{
+		{"Voice calls", c.Voice},
+		{"DTMF detection", c.DTMF},
+		{"USSD", c.USSD},
+		{"PDU mode", c.PDU},
+		{"LTE signal quality", c.CESQ},
+		{"LTE SINR", c.QCSQ},
+	} {
+		value := "supported"
+		if !capability.supported {
+			value = "not supported"
+		}
+		items = append(items, InfoItem{Name: capability.name, Value: value})
+	}
+	return items
+}
+
+// capabilityProbeTimeout bounds each test command, they answer right away
+const capabilityProbeTimeout = 3 * time.Second
+
+// Capabilities returns what the modem supports, probing it the first time
+func (m *ModemManager) Capabilities() (Capabilities, error) {
+	if caps := m.caps.Load(); caps != nil {
+		return *caps, nil
+	}
+	g := m.GSM()
+	if g == nil {
+		return Capabilities{}, ErrNoModem
+	}
+	return m.probeCapabilities(g.Command), nil
+}
+
+// capabilities returns what the modem supports as last probed, or everything
+// if it wasn't
+func (m *ModemManager) capabilities() Capabilities {
+	if caps := m.caps.Load(); caps != nil {
+		return *caps
+	}
+	return allCapabilities
+}
+
+// probeCapabilities sends the test commands through command and remembers
+// the result
+func (m *ModemManager) probeCapabilities(command func(cmd string, options ...at.CommandOption) ([]string, error)) Capabilities {
+	caps := probeCapabilities(command, capabilityProbeTimeout)
+	m.caps.Store(&caps)
+	m.logger.Info("Probed modem capabilities",
+		slog.Bool("voice", caps.Voice),
+		slog.Bool("dtmf", caps.DTMF),
+		slog.Bool("ussd", caps.USSD),
+		slog.Bool("pdu", caps.PDU),
+		slog.Bool("cesq", caps.CESQ),
+		slog.Bool("qcsq", caps.QCSQ))
+	return caps
+}
+
+// probeCapabilities asks the modem which optional commands it has, a
+// command it answers ERROR to is missing
+func probeCapabilities(command func(cmd string, options ...at.CommandOption) ([]string, error), timeout time.Duration) Capabilities {
+	test := func(cmd string) ([]string, bool) {
+		response, err := command(cmd+"=?", at.WithTimeout(timeout))
+		return response, err == nil
+	}
+
+	var caps Capabilities
+	_, caps.Voice = test("+CLCC")
+	_, caps.DTMF = test("+DDET")
+	_, caps.USSD = test("+CUSD")
+	_, caps.CESQ = test("+CESQ")
+	_, caps.QCSQ = test("+QCSQ")
+	if response, ok := test("+CMGF"); ok {
+		caps.PDU = supportsMode(response, "+CMGF", 0)
+	}
+	return caps
+}
+
+// supportsMode reports whether the answer to a test command lists mode,
+// e.g. +CMGF: (0,1) or +CMGF: (0-1). An answer it can't read counts as
+// supporting it.
+func supportsMode(response []string, prefix string, mode int) bool {
+	for _, line := range response {
+		if !info.HasPrefix(line, prefix) {
+			continue
+		}
+		values := strings.Trim(strings.TrimSpace(info.TrimPrefix(line, prefix)), "()")
+		for _, value := range strings.Split(values, ",") {
+			low, high, isRange := strings.Cut(strings.TrimSpace(value), "-")
+			from, err := strconv.Atoi(low)
+			if err != nil {
+				return true
+			}
+			to := from
+			if isRange {
+				if to, err = strconv.Atoi(high); err != nil {
+					return true
+				}
+			}
+			if mode >= from && mode <= to {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// notSupported returns the error of a feature the modem lacks
+func notSupported(feature string) error {
+	return fmt.Errorf("%s: %w", feature, ErrNotSupported)
+}
@@ -0,0 +1,88 @@
+package machine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/warthog618/modem/at"
+)
+
+// fakeCommands answers the test commands in responses, others with ERROR
+func fakeCommands(responses map[string][]string) func(string, ...at.CommandOption) ([]string, error) {
+	return func(cmd string, _ ...at.CommandOption) ([]string, error) {
+		response, ok := responses[cmd]
+		if !ok {
+			return nil, errors.New("ERROR")
+		}
+		return response, nil
+	}
+}
+
+func TestProbeCapabilities(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string][]string
+		want      Capabilities
+	}{
+		{
+			name: "everything",
+			responses: map[string][]string{
+				"+CLCC=?": nil,
+				"+DDET=?": {"+DDET: (0,1),(0,1)"},
+				"+CUSD=?": {"+CUSD: (0-2)"},
+				"+CMGF=?": {"+CMGF: (0-1)"},
+				"+CESQ=?": {"+CESQ: (0-63,99),(0-7,99),(0-96,255),(0-49,255),(0-34,255),(0-97,255)"},
+				"+QCSQ=?": {`+QCSQ: ("NOSERVICE","GSM","WCDMA","LTE")`},
+			},
+			want: allCapabilities,
+		},
+		{
+			name: "SMS only",
+			responses: map[string][]string{
+				"+CMGF=?": {"+CMGF: (1)"},
+			},
+			want: Capabilities{},
+		},
+		{
+			name: "no DTMF",
+			responses: map[string][]string{
+				"+CLCC=?": nil,
+				"+CUSD=?": nil,
+				"+CMGF=?": {"+CMGF: (0,1)"},
+			},
+			want: Capabilities{Voice: true, USSD: true, PDU: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := probeCapabilities(fakeCommands(tt.responses), time.Second)
+			if got != tt.want {
+				t.Errorf("probeCapabilities() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSupportsMode(t *testing.T) {
+	tests := []struct {
+		response []string
+		mode     int
+		want     bool
+	}{
+		{[]string{"+CMGF: (0,1)"}, 0, true},
+		{[]string{"+CMGF: (0-1)"}, 0, true},
+		{[]string{"+CMGF: (1)"}, 0, false},
+		{[]string{"+CMGF: (1)"}, 1, true},
+		{[]string{"+CMGF: (2-4)"}, 1, false},
+		{[]string{"+CMGF: garbled"}, 0, true},
+		{nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		if got := supportsMode(tt.response, "+CMGF", tt.mode); got != tt.want {
+			t.Errorf("supportsMode(%q, %d) = %v, want %v", tt.response, tt.mode, got, tt.want)
+		}
+	}
+}
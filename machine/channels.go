@@ -0,0 +1,65 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// smsChannels returns the channels SMS from number are posted to, its
+// dedicated channel if it has one
+func (d *DiscordManager) smsChannels(number string) []string {
+	number = normalizeNumber(number)
+	if number != "" {
+		for configured, channelID := range d.config().Discord.NumberChannels {
+			if normalizeNumber(configured) == number {
+				return []string{channelID}
+			}
+		}
+	}
+	return d.config().Discord.ChannelIDs
+}
+
+// postToChannels sends message to every channel, a failing channel doesn't
+// keep the others from getting it. The errors are joined.
+func (d *DiscordManager) postToChannels(channels []string, message discord.MessageCreate) error {
+	var errs []error
+	for _, channel := range channels {
+		if err := d.post(channel, message); err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: %w", channel, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d *DiscordManager) post(channel string, message discord.MessageCreate) error {
+	channelID, err := snowflake.Parse(channel)
+	if err != nil {
+		return fmt.Errorf("invalid channel ID: %w", err)
+	}
+	_, err = d.client.Rest().CreateMessage(channelID, message)
+	return err
+}
+
+// channelNumber returns the phone number a channel is dedicated to
+func (d *DiscordManager) channelNumber(channelID snowflake.ID) (string, bool) {
+	for number, configured := range d.config().Discord.NumberChannels {
+		if configured == channelID.String() {
+			return number, true
+		}
+	}
+	return "", false
+}
+
+// isSMSChannel reports whether SMS are posted to the channel, so replies
+// there are handled
+func (d *DiscordManager) isSMSChannel(channelID snowflake.ID) bool {
+	if slices.Contains(d.config().Discord.ChannelIDs, channelID.String()) {
+		return true
+	}
+	_, ok := d.channelNumber(channelID)
+	return ok
+}
@@ -0,0 +1,40 @@
+package machine
+
+import (
+	"slices"
+	"testing"
+
+	"golte/config"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+func TestNumberChannels(t *testing.T) {
+	d := &DiscordManager{}
+	d.cfg.Store(&config.Config{Discord: config.DiscordConfig{
+		ChannelIDs: []string{"100", "150"},
+		NumberChannels: map[string]string{
+			"+33 6 12 34 56 78": "200",
+		},
+	}})
+
+	if got := d.smsChannels("0033612345678"); !slices.Equal(got, []string{"200"}) {
+		t.Errorf("smsChannels() = %q, want the dedicated channel", got)
+	}
+	if got := d.smsChannels("+447700900123"); !slices.Equal(got, []string{"100", "150"}) {
+		t.Errorf("smsChannels() = %q, want the shared channels", got)
+	}
+
+	if number, ok := d.channelNumber(snowflake.ID(200)); !ok || number != "+33 6 12 34 56 78" {
+		t.Errorf("channelNumber(200) = %q, %v", number, ok)
+	}
+	if _, ok := d.channelNumber(snowflake.ID(100)); ok {
+		t.Error("the shared channel is not dedicated to a number")
+	}
+
+	for id, want := range map[snowflake.ID]bool{100: true, 150: true, 200: true, 300: false} {
+		if got := d.isSMSChannel(id); got != want {
+			t.Errorf("isSMSChannel(%d) = %v, want %v", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package machine
+
+import (
+	"fmt"
+	"log/slog"
+	"syscall"
+	"time"
+
+	"golte/atresp"
+	"golte/config"
+
+	"github.com/warthog618/modem/at"
+)
+
+// SyncTime turns on the network time zone update of the modem and reads its
+// clock. Depending on modem.clock_sync, notifications are then timestamped
+// with the modem clock, and the host clock is set to it.
+func (m *ModemManager) SyncTime() (time.Time, error) {
+	if m.GSM() == nil {
+		return time.Time{}, ErrNoModem
+	}
+
+	// Not every modem supports it, the clock may still be right
+	if _, err := m.GSM().Command("+CTZU=1"); err != nil {
+		m.logger.Debug("Failed to enable network time zone update", slog.Any("error", err))
+	}
+
+	clock, err := m.ModemClock()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	mode := m.config().Modem.ClockSync
+	if mode == config.ClockSyncTimestamps || mode == config.ClockSyncSystem {
+		m.clockOffset.Store(int64(time.Until(clock)))
+	}
+	if mode == config.ClockSyncSystem {
+		tv := syscall.NsecToTimeval(clock.UnixNano())
+		if err := syscall.Settimeofday(&tv); err != nil {
+			return clock, fmt.Errorf("failed to set the system clock: %w", err)
+		}
+		m.clockOffset.Store(0)
+		m.logger.Info("Set the system clock from the modem", slog.Time("time", clock))
+	}
+	return clock, nil
+}
+
+// ModemClock reads the modem's real time clock with AT+CCLK?
+func (m *ModemManager) ModemClock() (time.Time, error) {
+	if m.GSM() == nil {
+		return time.Time{}, ErrNoModem
+	}
+	response, err := m.GSM().Command("+CCLK?", at.WithTimeout(modemQueryTimeout(m.config().Modem)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read the modem clock: %w", err)
+	}
+	return parseModemClock(response)
+}
+
+// Now returns the current time, corrected by the modem clock when
+// modem.clock_sync is timestamps
+func (m *ModemManager) Now() time.Time {
+	return time.Now().Add(time.Duration(m.clockOffset.Load()))
+}
+
+// parseModemClock parses +CCLK: "24/01/02,13:04:05+04"
+func parseModemClock(response []string) (time.Time, error) {
+	fields, ok := atresp.Find(response, "+CCLK")
+	if !ok {
+		return time.Time{}, fmt.Errorf("no clock in the modem response")
+	}
+	value := fields.String(0)
+	clock := parseTextTimestamp(value)
+	if clock.IsZero() {
+		return time.Time{}, fmt.Errorf("invalid modem clock %q", value)
+	}
+	// Modems that never got network time start from their epoch
+	if clock.Year() < 2020 {
+		return time.Time{}, fmt.Errorf("the modem clock is not set (%s)", value)
+	}
+	return clock, nil
+}
@@ -0,0 +1,23 @@
+package machine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseModemClock(t *testing.T) {
+	clock, err := parseModemClock([]string{`+CCLK: "24/01/02,13:04:05+04"`})
+	if err != nil {
+		t.Fatalf("parseModemClock() = %v", err)
+	}
+	if want := time.Date(2024, 1, 2, 12, 4, 5, 0, time.UTC); !clock.Equal(want) {
+		t.Errorf("parseModemClock() = %s, want %s", clock, want)
+	}
+
+	// Without network time the clock starts from the modem's epoch
+	for _, response := range [][]string{{`+CCLK: "80/01/06,00:01:23+00"`}, {`+CCLK: "garbage"`}, {"OK"}} {
+		if _, err := parseModemClock(response); err == nil {
+			t.Errorf("parseModemClock(%q) should fail", response)
+		}
+	}
+}
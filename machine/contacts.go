@@ -0,0 +1,160 @@
+package machine
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// ErrUnknownContact is returned for a recipient that is neither a number nor
+// the name of a SIM contact
+var ErrUnknownContact = errors.New("no SIM contact has that name")
+
+// maxAutocompleteChoices is the most choices Discord shows
+const maxAutocompleteChoices = 25
+
+// AmbiguousContactError is returned for a name matching several contacts
+type AmbiguousContactError struct {
+	Name       string
+	Candidates []PhonebookEntry
+}
+
+func (e *AmbiguousContactError) Error() string {
+	candidates := make([]string, 0, len(e.Candidates))
+	for i, c := range e.Candidates {
+		if i == 5 {
+			candidates = append(candidates, fmt.Sprintf("and %d more", len(e.Candidates)-i))
+			break
+		}
+		candidates = append(candidates, contactLabel(c))
+	}
+	return fmt.Sprintf("%q matches %d contacts: %s", e.Name, len(e.Candidates), strings.Join(candidates, ", "))
+}
+
+// Contacts returns the SIM contacts as of the last ReadPhonebook, sorted by
+// name
+func (m *ModemManager) Contacts() []PhonebookEntry {
+	m.phonebookMu.RLock()
+	contacts := make([]PhonebookEntry, 0, len(m.phonebook))
+	for number, name := range m.phonebook {
+		contacts = append(contacts, PhonebookEntry{Number: number, Name: name})
+	}
+	m.phonebookMu.RUnlock()
+
+	slices.SortFunc(contacts, func(a, b PhonebookEntry) int {
+		return cmp.Or(cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)), cmp.Compare(a.Number, b.Number))
+	})
+	return contacts
+}
+
+// resolveRecipient returns the number to text or call for what was typed in
+// a number option: a number as is, or the number of the SIM contact it names
+func resolveRecipient(contacts []PhonebookEntry, recipient string) (number, name string, err error) {
+	recipient = strings.TrimSpace(recipient)
+	if isNumber(recipient) {
+		return recipient, "", nil
+	}
+
+	matches := matchContacts(contacts, recipient)
+	switch {
+	case len(matches) == 0:
+		return "", "", fmt.Errorf("%w: %q", ErrUnknownContact, recipient)
+	case len(matches) > 1 && !strings.EqualFold(matches[0].Name, recipient):
+		return "", "", &AmbiguousContactError{Name: recipient, Candidates: matches}
+	}
+
+	// Several contacts may share the exact name, e.g. a mobile and a landline
+	var exact []PhonebookEntry
+	for _, c := range matches {
+		if strings.EqualFold(c.Name, recipient) {
+			exact = append(exact, c)
+		}
+	}
+	if len(exact) > 1 {
+		return "", "", &AmbiguousContactError{Name: recipient, Candidates: exact}
+	}
+	return matches[0].Number, matches[0].Name, nil
+}
+
+// matchContacts returns the contacts whose name matches query, ignoring
+// case: exact matches first, then names starting with it, then names
+// containing it. A number matches the contacts whose number contains it.
+func matchContacts(contacts []PhonebookEntry, query string) []PhonebookEntry {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return contacts
+	}
+
+	var exact, prefix, contains []PhonebookEntry
+	if isNumber(query) {
+		digits := strings.TrimLeft(normalizeNumber(query), "+")
+		for _, c := range contacts {
+			if strings.Contains(normalizeNumber(c.Number), digits) {
+				contains = append(contains, c)
+			}
+		}
+		return contains
+	}
+	for _, c := range contacts {
+		name := strings.ToLower(c.Name)
+		switch {
+		case name == query:
+			exact = append(exact, c)
+		case strings.HasPrefix(name, query):
+			prefix = append(prefix, c)
+		case strings.Contains(name, query):
+			contains = append(contains, c)
+		}
+	}
+	return slices.Concat(exact, prefix, contains)
+}
+
+// contactLabel shows a contact with its number, e.g. Alice (+33612345678)
+func contactLabel(c PhonebookEntry) string {
+	return fmt.Sprintf("%s (%s)", c.Name, c.Number)
+}
+
+// recipientLabel shows who a command reached, the contact name if one was
+// given
+func recipientLabel(number, name string) string {
+	if name == "" {
+		return number
+	}
+	return contactLabel(PhonebookEntry{Number: number, Name: name})
+}
+
+// autocompleteListener suggests SIM contacts for the number option of /send
+// and /call, picking one fills in its number
+func (d *DiscordManager) autocompleteListener(event *events.AutocompleteInteractionCreate) {
+	data := event.Data
+	choices := make([]discord.AutocompleteChoice, 0, maxAutocompleteChoices)
+
+	// Contact names are only shown to those who may use the command,
+	// refusals aren't counted as every keystroke asks
+	var roleIDs []snowflake.ID
+	if member := event.Member(); member != nil {
+		roleIDs = member.RoleIDs
+	}
+	if data.Focused().Name == "number" && allowed(d.config(), data.CommandName, event.User().ID, roleIDs) {
+		for _, c := range matchContacts(d.modem.Contacts(), data.String("number")) {
+			if len(choices) == maxAutocompleteChoices {
+				break
+			}
+			choices = append(choices, discord.AutocompleteChoiceString{
+				Name:  contactLabel(c),
+				Value: c.Number,
+			})
+		}
+	}
+
+	if err := event.AutocompleteResult(choices); err != nil {
+		d.logger.Error("Failed to send autocomplete choices", slog.Any("error", err))
+	}
+}
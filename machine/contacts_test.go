@@ -0,0 +1,72 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestResolveRecipient(t *testing.T) {
+	contacts := []PhonebookEntry{
+		{Number: "+33612345678", Name: "Alice"},
+		{Number: "+33698765432", Name: "Alicia"},
+		{Number: "+33611111111", Name: "Bob"},
+		{Number: "+33122222222", Name: "Bob"},
+		{Number: "+33633333333", Name: "Zoé Martin"},
+	}
+
+	tests := []struct {
+		recipient string
+		number    string
+		name      string
+		ambiguous bool
+		unknown   bool
+	}{
+		{recipient: "+33 6 00 00 00 00", number: "+33 6 00 00 00 00"},
+		{recipient: "alice", number: "+33612345678", name: "Alice"},
+		{recipient: "alic", ambiguous: true},
+		{recipient: "zoé", number: "+33633333333", name: "Zoé Martin"},
+		{recipient: "martin", number: "+33633333333", name: "Zoé Martin"},
+		{recipient: "Bob", ambiguous: true},
+		{recipient: "Carol", unknown: true},
+	}
+	for _, tt := range tests {
+		number, name, err := resolveRecipient(contacts, tt.recipient)
+		var ambiguous *AmbiguousContactError
+		switch {
+		case tt.ambiguous:
+			if !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+				t.Errorf("resolveRecipient(%q) = %v, want 2 candidates", tt.recipient, err)
+			}
+		case tt.unknown:
+			if !errors.Is(err, ErrUnknownContact) {
+				t.Errorf("resolveRecipient(%q) = %v, want ErrUnknownContact", tt.recipient, err)
+			}
+		case err != nil || number != tt.number || name != tt.name:
+			t.Errorf("resolveRecipient(%q) = %q, %q, %v, want %q, %q", tt.recipient, number, name, err, tt.number, tt.name)
+		}
+	}
+}
+
+func TestMatchContactsOrder(t *testing.T) {
+	contacts := []PhonebookEntry{
+		{Number: "+33600000001", Name: "Annie"},
+		{Number: "+33600000002", Name: "Jeanne"},
+		{Number: "+33600000003", Name: "Ann"},
+	}
+
+	var names []string
+	for _, c := range matchContacts(contacts, "ANN") {
+		names = append(names, c.Name)
+	}
+	if got := fmt.Sprint(names); got != "[Ann Annie Jeanne]" {
+		t.Errorf("matchContacts() = %s, want exact, prefix then substring matches", got)
+	}
+
+	if got := matchContacts(contacts, "0002"); len(got) != 1 || got[0].Name != "Jeanne" {
+		t.Errorf("matchContacts() by number = %+v", got)
+	}
+	if got := matchContacts(contacts, ""); len(got) != 3 {
+		t.Errorf("matchContacts() without a query = %d contacts, want all", len(got))
+	}
+}
@@ -3,12 +3,15 @@ package machine
 import (
 	"context"
 	"fmt"
-	"log"
 	"log/slog"
-	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"golte/config"
+	"golte/ffmpeg"
 	"golte/playback"
 
 	"github.com/disgoorg/disgo"
@@ -16,46 +19,87 @@ import (
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
 	"github.com/disgoorg/disgo/gateway"
+	"github.com/disgoorg/disgo/rest"
 	"github.com/disgoorg/disgo/voice"
 	"github.com/disgoorg/snowflake/v2"
 )
 
 // DiscordManager handles all Discord bot operations
 type DiscordManager struct {
-	config     *config.Config
+	cfg        atomic.Pointer[config.Config]
 	client     bot.Client
 	logger     *slog.Logger
 	playback   *playback.Playback
+	modem      *ModemManager
 	streamer   *playback.PCMStreamer
+	receiver   atomic.Pointer[ffmpeg.OpusPCMReceiver] // set by the voice bridge, read by /voice transmit
+	capture    *ffmpeg.AudioProvider
 	conn       voice.Conn
 	smsFunc    func(number, message string) error
-	callFunc   func(number string) error
+	callFunc   func(number string, maxDuration time.Duration) error
 	hangupFunc func() error
+	reloadFunc func() (ReloadResult, error)
 	notifyFunc func(notificationType NotificationType, from, message string)
+	panicFunc  func(err error) // told of panics recovered in the voice bridge
+	ctx        context.Context // done when the bridge stops
+
+	version             string
+	started             time.Time
+	identityCache       identityCache
+	contentIntentWarned atomic.Bool
+	announced           atomic.Bool
+	unregistered        atomic.Bool  // the slash commands are retried in the background
+	denied              atomic.Int64 // interactions refused by authorize
+	rawPDUs             pduStore
+	pendingSends        pendingSends
 }
 
 // NewDiscordManager creates a new DiscordManager instance
-func NewDiscordManager(cfg *config.Config, playback *playback.Playback, smsFunc func(number, message string) error, callFunc func(number string) error, hangupFunc func() error, notifyFunc func(notificationType NotificationType, from, message string)) *DiscordManager {
-	return &DiscordManager{
-		config:     cfg,
+func NewDiscordManager(cfg *config.Config, playback *playback.Playback, modem *ModemManager, smsFunc func(number, message string) error, callFunc func(number string, maxDuration time.Duration) error, hangupFunc func() error, reloadFunc func() (ReloadResult, error), notifyFunc func(notificationType NotificationType, from, message string)) *DiscordManager {
+	d := &DiscordManager{
 		logger:     slog.With("component", "discord"),
 		playback:   playback,
+		modem:      modem,
 		smsFunc:    smsFunc,
 		callFunc:   callFunc,
 		hangupFunc: hangupFunc,
+		reloadFunc: reloadFunc,
 		notifyFunc: notifyFunc,
+		started:    time.Now(),
 	}
+	d.cfg.Store(cfg)
+	return d
+}
+
+// config returns the active configuration
+func (d *DiscordManager) config() *config.Config {
+	return d.cfg.Load()
+}
+
+var _ Notifier = (*DiscordManager)(nil)
+
+// Name names Discord in the logs and errors of the notifiers
+func (d *DiscordManager) Name() string {
+	return "Discord"
+}
+
+// Reconfigure switches to cfg. Settings read when a call starts apply from
+// the next call.
+func (d *DiscordManager) Reconfigure(cfg *config.Config) {
+	d.cfg.Store(cfg)
 }
 
 // Initialize sets up the Discord bot client
 func (d *DiscordManager) Initialize() error {
 	d.logger.Info("Initializing Discord client")
 
-	client, err := disgo.New(d.config.Discord.Token,
+	client, err := disgo.New(d.config().Discord.Token,
 		bot.WithGatewayConfigOpts(
 			gateway.WithIntents(gateway.IntentMessageContent|gateway.IntentGuilds|gateway.IntentGuildMessages|gateway.IntentDirectMessages|gateway.IntentGuildVoiceStates),
 		),
 		bot.WithEventListenerFunc(d.commandListener),
+		bot.WithEventListenerFunc(d.componentListener),
+		bot.WithEventListenerFunc(d.autocompleteListener),
 		bot.WithEventListenerFunc(d.messageListener),
 		bot.WithEventListenerFunc(d.readyListener),
 		bot.WithEventListenerFunc(d.voiceServerUpdate),
@@ -67,9 +111,11 @@ func (d *DiscordManager) Initialize() error {
 
 	d.client = client
 
-	// Register commands
-	if _, err = client.Rest().SetGlobalCommands(client.ApplicationID(), d.getCommands()); err != nil {
-		return fmt.Errorf("failed to register Discord commands: %w", err)
+	// SMS still reach Discord without the commands, they're retried once
+	// started
+	if err := d.registerCommands(context.Background()); err != nil {
+		d.logger.Error("Failed to register Discord commands, starting without them and retrying in the background", slog.Any("error", err))
+		d.unregistered.Store(true)
 	}
 
 	d.logger.Info("Discord client initialized successfully")
@@ -78,9 +124,13 @@ func (d *DiscordManager) Initialize() error {
 
 // Start opens the Discord gateway connection
 func (d *DiscordManager) Start(ctx context.Context) error {
+	d.ctx = ctx
 	if err := d.client.OpenGateway(ctx); err != nil {
 		return fmt.Errorf("failed to connect to Discord gateway: %w", err)
 	}
+	if d.unregistered.Load() {
+		go d.retryRegistration(ctx)
+	}
 	return nil
 }
 
@@ -93,19 +143,60 @@ func (d *DiscordManager) Stop() {
 
 // getCommands returns the Discord slash commands
 func (d *DiscordManager) getCommands() []discord.ApplicationCommandCreate {
-	return []discord.ApplicationCommandCreate{
+	commands := []discord.ApplicationCommandCreate{
 		discord.SlashCommandCreate{
 			Name:        "send",
 			Description: "sends a SMS",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:         "number",
+					Description:  "The phone number or SIM contact to send the message to",
+					Required:     true,
+					Autocomplete: true,
+				},
+				discord.ApplicationCommandOptionString{
+					Name:        "message",
+					Description: "What to say",
+					Required:    true,
+				},
+			},
+		},
+		discord.SlashCommandCreate{
+			Name:        "sendfile",
+			Description: "texts the link of an image, audio or video file",
 			Options: []discord.ApplicationCommandOption{
 				discord.ApplicationCommandOptionString{
 					Name:        "number",
-					Description: "The phone number to send the message to",
+					Description: "The phone number to send the link to",
+					Required:    true,
+				},
+				discord.ApplicationCommandOptionAttachment{
+					Name:        "file",
+					Description: "The file to share",
 					Required:    true,
 				},
 				discord.ApplicationCommandOptionString{
 					Name:        "message",
-					Description: "What to say",
+					Description: "Text to send before the link",
+				},
+				discord.ApplicationCommandOptionBool{
+					Name:        "shorten",
+					Description: "Shorten the link (defaults to on when a shortener is configured)",
+				},
+			},
+		},
+		discord.SlashCommandCreate{
+			Name:        "forward",
+			Description: "resends a received SMS to another number",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "message_id",
+					Description: "The ID of the SMS message in this channel",
+					Required:    true,
+				},
+				discord.ApplicationCommandOptionString{
+					Name:        "number",
+					Description: "The phone number to forward the SMS to",
 					Required:    true,
 				},
 			},
@@ -115,9 +206,15 @@ func (d *DiscordManager) getCommands() []discord.ApplicationCommandCreate {
 			Description: "makes a phone call",
 			Options: []discord.ApplicationCommandOption{
 				discord.ApplicationCommandOptionString{
-					Name:        "number",
-					Description: "The phone number to call",
-					Required:    true,
+					Name:         "number",
+					Description:  "The phone number or SIM contact to call",
+					Required:     true,
+					Autocomplete: true,
+				},
+				discord.ApplicationCommandOptionInt{
+					Name:        "max_minutes",
+					Description: "Hang up after this many minutes, at most the configured maximum",
+					Required:    false,
 				},
 			},
 		},
@@ -125,23 +222,253 @@ func (d *DiscordManager) getCommands() []discord.ApplicationCommandCreate {
 			Name:        "hangup",
 			Description: "hangs up the current phone call",
 		},
+		discord.SlashCommandCreate{
+			Name:        "transfer",
+			Description: "connects the active call to the held one and leaves both",
+		},
+		discord.SlashCommandCreate{
+			Name:        "clearsms",
+			Description: "deletes every SMS stored on the SIM (owner only)",
+		},
+		discord.SlashCommandCreate{
+			Name:        "smsread",
+			Description: "shows an SMS stored on the SIM in full (owner only)",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionInt{
+					Name:        "index",
+					Description: "Storage index of the SMS",
+					Required:    true,
+				},
+			},
+		},
+		discord.SlashCommandCreate{
+			Name:        "status",
+			Description: "shows the modem signal, network, storage and clock",
+		},
+		discord.SlashCommandCreate{
+			Name:        "whoami",
+			Description: "shows which bridge this is: its number, operator, version and uptime",
+		},
+		discord.SlashCommandCreate{
+			Name:        "ussd",
+			Description: "sends a USSD code, e.g. a balance check (owner only)",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "code",
+					Description: "The USSD code, e.g. *123#",
+					Required:    true,
+				},
+			},
+		},
+		discord.SlashCommandCreate{
+			Name:        "about",
+			Description: "shows information about the bridge",
+		},
+		discord.SlashCommandCreate{
+			Name:        "phonebook",
+			Description: "manages the contacts stored on the SIM",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionSubCommand{
+					Name:        "list",
+					Description: "lists the SIM contacts",
+				},
+				discord.ApplicationCommandOptionSubCommand{
+					Name:        "add",
+					Description: "stores a contact on the SIM (owner only)",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{
+							Name:        "name",
+							Description: "The contact name",
+							Required:    true,
+						},
+						discord.ApplicationCommandOptionString{
+							Name:        "number",
+							Description: "The phone number",
+							Required:    true,
+						},
+						discord.ApplicationCommandOptionInt{
+							Name:        "index",
+							Description: "The SIM location to write, the first free one if not set",
+							Required:    false,
+						},
+					},
+				},
+			},
+		},
+		discord.SlashCommandCreate{
+			Name:        "reload",
+			Description: "reloads the configuration file (owner only)",
+		},
+		discord.SlashCommandCreate{
+			Name:        "broadcast",
+			Description: "texts the same SMS to a few numbers (owner only)",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "to",
+					Description: "Comma separated phone numbers or group names",
+					Required:    true,
+				},
+				discord.ApplicationCommandOptionString{
+					Name:        "message",
+					Description: "The message to send",
+					Required:    true,
+				},
+			},
+		},
+		discord.SlashCommandCreate{
+			Name:        "modem",
+			Description: "debugs the modem",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionSubCommand{
+					Name:        "trace",
+					Description: "shows or switches the trace of AT commands to modem.trace.path (owner only)",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{
+							Name:        "state",
+							Description: "Whether to trace",
+							Required:    false,
+							Choices: []discord.ApplicationCommandOptionChoiceString{
+								{Name: "on", Value: "on"},
+								{Name: "off", Value: "off"},
+							},
+						},
+					},
+				},
+			},
+		},
+		discord.SlashCommandCreate{
+			Name:        "logs",
+			Description: "shows the last log lines of the bridge (owner only)",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionInt{
+					Name:        "lines",
+					Description: fmt.Sprintf("How many lines, %d by default and %d at most", defaultLogLines, maxLogLines),
+					Required:    false,
+				},
+				discord.ApplicationCommandOptionString{
+					Name:        "level",
+					Description: "Only lines at this level or above",
+					Required:    false,
+					Choices: []discord.ApplicationCommandOptionChoiceString{
+						{Name: "debug", Value: "debug"},
+						{Name: "info", Value: "info"},
+						{Name: "warn", Value: "warn"},
+						{Name: "error", Value: "error"},
+					},
+				},
+			},
+		},
+		discord.SlashCommandCreate{
+			Name:        "audio",
+			Description: "manages the call audio",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionSubCommand{
+					Name:        "device",
+					Description: "shows or switches the ALSA device of the call audio (owner only)",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{
+							Name:        "name",
+							Description: "The ALSA device to switch to, e.g. hw:2,0",
+							Required:    false,
+						},
+					},
+				},
+			},
+		},
+		discord.SlashCommandCreate{
+			Name:        "voice",
+			Description: "manages the voice channel bridge",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionSubCommandGroup{
+					Name:        "transmit",
+					Description: "chooses whose audio the caller hears",
+					Options: []discord.ApplicationCommandOptionSubCommand{
+						{
+							Name:        "add",
+							Description: "lets a user be heard by the caller",
+							Options: []discord.ApplicationCommandOption{
+								discord.ApplicationCommandOptionUser{
+									Name:        "user",
+									Description: "The user to put on air",
+									Required:    true,
+								},
+							},
+						},
+						{
+							Name:        "remove",
+							Description: "stops a user from being heard by the caller",
+							Options: []discord.ApplicationCommandOption{
+								discord.ApplicationCommandOptionUser{
+									Name:        "user",
+									Description: "The user to take off air",
+									Required:    true,
+								},
+							},
+						},
+						{
+							Name:        "list",
+							Description: "shows who the caller can hear",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// The call audio commands have nothing to act on without voice
+	if d.playback == nil {
+		commands = slices.DeleteFunc(commands, func(c discord.ApplicationCommandCreate) bool {
+			return c.CommandName() == "voice" || c.CommandName() == "audio"
+		})
+	}
+	// Only a serial modem has AT commands to trace
+	if d.config().Modem.Type != config.ModemTypeGSM {
+		commands = slices.DeleteFunc(commands, func(c discord.ApplicationCommandCreate) bool {
+			return c.CommandName() == "modem"
+		})
 	}
+	return commands
+}
+
+// isOwner reports whether the user may run owner-only commands
+func (d *DiscordManager) isOwner(userID snowflake.ID) bool {
+	return isAdmin(d.config(), userID)
+}
+
+// interactionMember returns the guild member of an interaction, nil in DMs
+func interactionMember(member *discord.ResolvedMember) *discord.Member {
+	if member == nil {
+		return nil
+	}
+	return &member.Member
 }
 
 // commandListener handles Discord slash commands
 func (d *DiscordManager) commandListener(event *events.ApplicationCommandInteractionCreate) {
 	data := event.SlashCommandInteractionData()
+	deny := func(content string) { d.respondEphemeral(event.CreateMessage, content) }
+	if !d.authorize(commandAction(data), event.User(), interactionMember(event.Member()), deny) {
+		return
+	}
 
 	switch data.CommandName() {
 	case "send":
-		phoneNumber := data.String("number")
+		phoneNumber, contact, err := resolveRecipient(d.modem.Contacts(), data.String("number"))
+		if err != nil {
+			d.respondEphemeral(event.CreateMessage, fmt.Sprintf("SMS has **not** been sent: %v", err))
+			return
+		}
 		message := data.String("message")
 
 		d.logger.Info("Received SMS command from Discord",
 			slog.String("number", phoneNumber),
 			slog.String("user", event.User().Username))
 
-		err := d.smsFunc(phoneNumber, message)
+		if d.confirmLongSMS(event, phoneNumber, message) {
+			return
+		}
+
+		err = d.smsFunc(phoneNumber, message)
 		if err != nil {
 			d.logger.Error("Failed to send SMS via Discord command",
 				slog.String("number", phoneNumber),
@@ -158,26 +485,39 @@ func (d *DiscordManager) commandListener(event *events.ApplicationCommandInterac
 		}
 
 		err = event.CreateMessage(discord.NewMessageCreateBuilder().
-			SetContent("SMS Sent!").
+			SetContentf("SMS Sent to %s!", recipientLabel(phoneNumber, contact)).
 			SetEphemeral(true).
 			Build())
 		if err != nil {
 			d.logger.Error("Failed to send Discord response", slog.Any("error", err))
 		}
 
-		// Notify about outgoing SMS
-		if d.notifyFunc != nil {
-			d.notifyFunc(NotificationTypeSMS, fmt.Sprintf("To %s", phoneNumber), message)
-		}
+		d.logOutbound(event.User().ID, phoneNumber, message)
+
+	case "sendfile":
+		d.handleSendFile(event, data)
+
+	case "forward":
+		d.handleForward(event, data)
 
 	case "call":
-		phoneNumber := data.String("number")
+		phoneNumber, contact, err := resolveRecipient(d.modem.Contacts(), data.String("number"))
+		if err != nil {
+			d.respondEphemeral(event.CreateMessage, fmt.Sprintf("Call has **not** been started: %v", err))
+			return
+		}
 
 		d.logger.Info("Received call command from Discord",
 			slog.String("number", phoneNumber),
 			slog.String("user", event.User().Username))
 
-		err := d.callFunc(phoneNumber)
+		// Overrides call.max_duration for this call, within it
+		var maxDuration time.Duration
+		if minutes, ok := data.OptInt("max_minutes"); ok {
+			maxDuration = callMaxDuration(minutes, d.config().Call.MaxDuration)
+		}
+
+		err = d.callFunc(phoneNumber, maxDuration)
 		if err != nil {
 			d.logger.Error("Failed to start call via Discord command",
 				slog.String("number", phoneNumber),
@@ -194,7 +534,7 @@ func (d *DiscordManager) commandListener(event *events.ApplicationCommandInterac
 		}
 
 		err = event.CreateMessage(discord.NewMessageCreateBuilder().
-			SetContentf("📞 Calling %s...", phoneNumber).
+			SetContentf("📞 Calling %s...", recipientLabel(phoneNumber, contact)).
 			SetEphemeral(true).
 			Build())
 		if err != nil {
@@ -237,17 +577,272 @@ func (d *DiscordManager) commandListener(event *events.ApplicationCommandInterac
 		if d.notifyFunc != nil {
 			d.notifyFunc(NotificationTypeCall, "Call ended", "📞 Call hung up")
 		}
+
+	case "transfer":
+		d.handleTransfer(event)
+
+	case "clearsms":
+		d.handleClearSMS(event)
+
+	case "smsread":
+		d.handleSMSRead(event, data)
+
+	case "voice":
+		d.handleVoiceTransmit(event, data)
+
+	case "audio":
+		d.handleAudioDevice(event, data)
+
+	case "modem":
+		d.handleModemTrace(event, data)
+
+	case "logs":
+		d.handleLogs(event, data)
+
+	case "about":
+		d.handleAbout(event)
+
+	case "status":
+		d.handleStatus(event)
+
+	case "whoami":
+		d.handleWhoami(event)
+
+	case "ussd":
+		d.handleUSSD(event, data)
+
+	case "phonebook":
+		d.handlePhonebook(event, data)
+
+	case "reload":
+		d.handleReload(event)
+
+	case "broadcast":
+		d.handleBroadcast(event, data)
+	}
+}
+
+// handleAbout describes the software the bridge runs on
+func (d *DiscordManager) handleAbout(event *events.ApplicationCommandInteractionCreate) {
+	ffmpegVersion := "not detected"
+	if info, ok := ffmpeg.Detected(); ok {
+		ffmpegVersion = fmt.Sprintf("%s (%s)", info.Version, info.Path)
+	}
+
+	embed := discord.NewEmbedBuilder().
+		SetTitle("ℹ️ Golte").
+		SetDescription("GSM/LTE modem to Discord bridge").
+		AddField("Go", runtime.Version(), true).
+		AddField("FFmpeg", ffmpegVersion, true).
+		SetColor(0x0099ff).
+		Build()
+
+	err := event.CreateMessage(discord.NewMessageCreateBuilder().
+		SetEmbeds(embed).
+		SetEphemeral(true).
+		Build())
+	if err != nil {
+		d.logger.Error("Failed to send Discord response", slog.Any("error", err))
+	}
+}
+
+// handleVoiceTransmit updates the transmit list of the running voice bridge
+func (d *DiscordManager) handleVoiceTransmit(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) {
+	if data.SubCommandName == nil {
+		return
+	}
+	action := *data.SubCommandName
+
+	d.logger.Info("Received voice transmit command from Discord",
+		slog.String("action", action),
+		slog.String("user", event.User().Username))
+
+	receiver := d.receiver.Load()
+	if receiver == nil {
+		d.respondEphemeral(event.CreateMessage, "The voice channel is not connected yet")
+		return
+	}
+
+	switch action {
+	case "add":
+		user := data.User("user")
+		receiver.AddTransmitUser(user.ID)
+		d.logger.Info("User added to voice transmit list", slog.String("target", user.Username))
+	case "remove":
+		user := data.User("user")
+		if !receiver.RemoveTransmitUser(user.ID) {
+			d.respondEphemeral(event.CreateMessage, fmt.Sprintf("%s is not on the transmit list", discord.UserMention(user.ID)))
+			return
+		}
+		d.logger.Info("User removed from voice transmit list", slog.String("target", user.Username))
+	}
+
+	content := fmt.Sprintf("🎙️ On air: %s", d.onAirSummary())
+	if speaking := receiver.OnAir(); len(speaking) > 0 {
+		mentions := make([]string, len(speaking))
+		for i, id := range speaking {
+			mentions[i] = discord.UserMention(id)
+		}
+		content += fmt.Sprintf("\nCurrently heard: %s", strings.Join(mentions, ", "))
+	}
+	d.respondEphemeral(event.CreateMessage, content)
+}
+
+// handleForward resends the body of a received SMS embed to another number
+func (d *DiscordManager) handleForward(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) {
+	phoneNumber := data.String("number")
+	rawID := data.String("message_id")
+
+	d.logger.Info("Received forward command from Discord",
+		slog.String("number", phoneNumber),
+		slog.String("message", rawID),
+		slog.String("user", event.User().Username))
+
+	messageID, err := snowflake.Parse(strings.TrimSpace(rawID))
+	if err != nil {
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("`%s` is not a message ID", rawID))
+		return
+	}
+
+	// The SMS may be in a number's own channel or a mirror when forwarded
+	// from there
+	channelID, err := snowflake.Parse(d.config().Discord.ChannelIDs[0])
+	if err != nil {
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("Invalid channel ID: %v", err))
+		return
+	}
+	if channel := event.Channel(); channel.MessageChannel != nil && d.isSMSChannel(channel.ID()) {
+		channelID = channel.ID()
+	}
+
+	message, err := d.client.Rest().GetMessage(channelID, messageID)
+	if err != nil {
+		d.logger.Debug("Failed to get message to forward", slog.Any("error", err))
+		d.respondEphemeral(event.CreateMessage, "SMS has **not** been forwarded: message not found in the SMS channel")
+		return
+	}
+
+	smsEmbed := findSMSEmbed(*message)
+	if message.Author.ID != d.client.ApplicationID() || smsEmbed == nil || smsEmbed.Description == "" {
+		d.respondEphemeral(event.CreateMessage, "SMS has **not** been forwarded: that message is not a received SMS")
+		return
+	}
+
+	if err := d.smsFunc(phoneNumber, smsEmbed.Description); err != nil {
+		d.logger.Error("Failed to forward SMS via Discord command",
+			slog.String("number", phoneNumber),
+			slog.Any("error", err))
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("SMS has **not** been forwarded: %v", err))
+		return
+	}
+
+	d.respondEphemeral(event.CreateMessage, fmt.Sprintf("↪️ SMS from %s forwarded to %s", smsEmbedNumber(*smsEmbed), phoneNumber))
+
+	d.logOutbound(event.User().ID, phoneNumber, smsEmbed.Description)
+}
+
+// componentListener handles Discord button interactions
+func (d *DiscordManager) componentListener(event *events.ComponentInteractionCreate) {
+	customID := event.Data.CustomID()
+
+	// Buttons do what their command did, /send for the long SMS confirmation
+	action, _, _ := strings.Cut(customID, ":")
+	if action == "sendlong" {
+		action = "send"
+	}
+	deny := func(content string) { d.respondEphemeral(event.CreateMessage, content) }
+	if !d.authorize(action, event.User(), interactionMember(event.Member()), deny) {
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(customID, "clearsms:"):
+		d.handleClearSMSConfirm(event, strings.TrimPrefix(customID, "clearsms:"))
+	case strings.HasPrefix(customID, "rawpdu:"):
+		d.handleRawPDU(event, strings.TrimPrefix(customID, "rawpdu:"))
+	case strings.HasPrefix(customID, "sendlong:"):
+		d.handleLongSMSConfirm(event, strings.TrimPrefix(customID, "sendlong:"))
+	}
+}
+
+// handleClearSMS asks the owner to confirm deleting all stored SMS
+func (d *DiscordManager) handleClearSMS(event *events.ApplicationCommandInteractionCreate) {
+	d.logger.Info("Received clearsms command from Discord",
+		slog.String("user", event.User().Username))
+
+	used, total, err := d.modem.MessageCount()
+	if err != nil {
+		d.logger.Error("Failed to read SMS storage", slog.Any("error", err))
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("Failed to read SMS storage: %v", err))
+		return
+	}
+
+	err = event.CreateMessage(discord.NewMessageCreateBuilder().
+		SetContentf("🗑️ Delete all **%d** stored SMS (%d slots)? This cannot be undone.", used, total).
+		AddActionRow(
+			discord.NewDangerButton("Delete all", "clearsms:confirm"),
+			discord.NewSecondaryButton("Cancel", "clearsms:cancel"),
+		).
+		SetEphemeral(true).
+		Build())
+	if err != nil {
+		d.logger.Error("Failed to send Discord response", slog.Any("error", err))
+	}
+}
+
+// handleClearSMSConfirm runs or cancels the deletion once confirmed
+func (d *DiscordManager) handleClearSMSConfirm(event *events.ComponentInteractionCreate, action string) {
+	update := discord.NewMessageUpdateBuilder().ClearContainerComponents()
+
+	switch {
+	case action != "confirm":
+		update.SetContent("Cancelled, no SMS were deleted.")
+	default:
+		used, _, err := d.modem.MessageCount()
+		if err != nil {
+			d.logger.Warn("Failed to count stored SMS before deletion", slog.Any("error", err))
+		}
+
+		if err := d.modem.ClearAllMessages(); err != nil {
+			update.SetContentf("Stored SMS have **not** been deleted: %v", err)
+		} else {
+			d.logger.Info("Stored SMS cleared from Discord",
+				slog.String("user", event.User().Username),
+				slog.Int("count", used))
+			update.SetContentf("🗑️ Deleted %d stored SMS.", used)
+		}
+	}
+
+	if err := event.UpdateMessage(update.Build()); err != nil {
+		d.logger.Error("Failed to send Discord response", slog.Any("error", err))
+	}
+}
+
+// respondEphemeral sends a plain ephemeral reply to an interaction
+func (d *DiscordManager) respondEphemeral(create func(discord.MessageCreate, ...rest.RequestOpt) error, content string) {
+	err := create(discord.NewMessageCreateBuilder().
+		SetContent(content).
+		SetEphemeral(true).
+		Build())
+	if err != nil {
+		d.logger.Error("Failed to send Discord response", slog.Any("error", err))
 	}
 }
 
 // readyListener handles Discord ready event
 func (d *DiscordManager) readyListener(event *events.Ready) {
-	d.logger.Info("Discord bot is ready, connecting to voice channel")
+	d.logger.Info("Discord bot is ready")
 
-	go func() {
-		var ch = make(chan os.Signal, 1)
-		d.ConnectAndPlay(ch)
-	}()
+	go d.checkMessageContentIntent()
+
+	// Ready comes again with every new gateway session, announce only the
+	// first one
+	if !d.announced.Swap(true) && d.config().Discord.AnnounceOnReady {
+		go d.announceOnline()
+	}
+	if d.playback != nil {
+		go d.runVoice(d.ctx)
+	}
 }
 
 // readyListener handles Discord ready event
@@ -260,8 +855,6 @@ func (d *DiscordManager) voiceStateUpdate(event *events.GuildVoiceStateUpdate) {
 		VoiceState: event.VoiceState,
 		Member:     event.Member,
 	})
-
-	fmt.Println(event)
 }
 
 // readyListener handles Discord ready event
@@ -279,14 +872,32 @@ func (d *DiscordManager) voiceServerUpdate(event *events.VoiceServerUpdate) {
 
 // messageListener handles Discord message events for replying to SMS embeds
 func (d *DiscordManager) messageListener(event *events.MessageCreate) {
-	log.Printf("Received message from Discord: %s", event.Message.Content)
-	// Ignore bot messages and messages not in the configured channel
-	if event.Message.Author.Bot || event.Message.ChannelID.String() != d.config.Discord.ChannelID {
+	// The body goes under message, redacted per logging.redaction
+	d.logger.Debug("Received message from Discord",
+		slog.String("channel_id", event.ChannelID.String()),
+		slog.String("message", event.Message.Content))
+	// Ignore bot, webhook and our own messages so nothing echoes back as SMS
+	if event.Message.Author.Bot || event.Message.WebhookID != nil || event.Message.Author.ID == d.client.ApplicationID() {
+		return
+	}
+
+	// Ignore messages outside the channels SMS are posted to
+	if !d.isSMSChannel(event.Message.ChannelID) {
+		return
+	}
+
+	deny := func(content string) { d.rejectReply(event, content) }
+	if !d.authorize(actionReply, event.Message.Author, event.Message.Member, deny) {
 		return
 	}
 
 	// Check if this message is a reply
 	if event.Message.MessageReference == nil {
+		// In a number's own channel any message can go to that number
+		if number, ok := d.channelNumber(event.Message.ChannelID); ok && d.config().Discord.ChannelReplyMode == config.ChannelReplyAny {
+			d.sendReply(event, number)
+			return
+		}
 		d.logger.Info("Message is not a reply, ignoring")
 		return
 	}
@@ -304,30 +915,35 @@ func (d *DiscordManager) messageListener(event *events.MessageCreate) {
 		return
 	}
 
-	// Find the SMS embed by checking for the SMS title
-	var smsEmbed *discord.Embed
-	for _, embed := range referencedMessage.Embeds {
-		if embed.Title == "📱 SMS Message" {
-			smsEmbed = &embed
-			break
-		}
-	}
-
+	smsEmbed := findSMSEmbed(*referencedMessage)
 	if smsEmbed == nil {
 		d.logger.Info("No SMS embed found in referenced message")
 		return
 	}
 
-	// Extract phone number from the embed's author field (which contains the phone number)
-	phoneNumber := smsEmbed.Author.Name
+	phoneNumber := smsEmbedNumber(*smsEmbed)
 	if phoneNumber == "" {
-		d.logger.Info("No phone number found in SMS embed")
+		d.logger.Warn("No phone number found in SMS embed, reply not sent",
+			slog.String("user", event.Message.Author.Username))
+		d.rejectReply(event, "⚠️ This reply was **not** sent: the phone number could not be found in that message. Use `/send` instead.")
 		return
 	}
 
+	d.sendReply(event, phoneNumber)
+}
+
+// sendReply sends the content of a channel message to phoneNumber and reacts
+// with the outcome
+func (d *DiscordManager) sendReply(event *events.MessageCreate, phoneNumber string) {
 	replyMessage := event.Message.Content
+	if contentHidden(event.Message) {
+		d.warnMessageContentIntent()
+		d.rejectReply(event, fmt.Sprintf("⚠️ This reply was **not** sent: the bot can't read messages. Use `/send number:%s` instead, or ask the bot owner to %s.", phoneNumber, messageContentHelp))
+		return
+	}
 	if replyMessage == "" {
 		d.logger.Info("Empty reply message")
+		d.rejectReply(event, "⚠️ This reply was **not** sent: only text can be sent by SMS.")
 		return
 	}
 
@@ -337,7 +953,7 @@ func (d *DiscordManager) messageListener(event *events.MessageCreate) {
 		slog.String("message", replyMessage))
 
 	// Send the SMS
-	err = d.smsFunc(phoneNumber, replyMessage)
+	err := d.smsFunc(phoneNumber, replyMessage)
 	if err != nil {
 		d.logger.Error("Failed to send SMS reply",
 			slog.String("number", phoneNumber),
@@ -358,52 +974,178 @@ func (d *DiscordManager) messageListener(event *events.MessageCreate) {
 	}
 }
 
+// smsEmbedTitle identifies the embeds posted for received SMS
+const smsEmbedTitle = "📱 SMS Message"
+
+// findSMSEmbed returns the SMS embed of a message, nil if it has none
+func findSMSEmbed(message discord.Message) *discord.Embed {
+	for _, embed := range message.Embeds {
+		if embed.Title == smsEmbedTitle {
+			return &embed
+		}
+	}
+	return nil
+}
+
+// smsNumberField is the embed field holding the sender of an SMS, kept
+// separate from the author so the layout can change without breaking replies
+const smsNumberField = "Number"
+
+// smsEmbedNumber returns the phone number an SMS embed came from, as stored
+// in its number field. Embeds without one, e.g. those posted by older
+// versions, return "" and aren't replied to: the author may be a contact
+// name and the text may hold any number.
+func smsEmbedNumber(embed discord.Embed) string {
+	for _, field := range embed.Fields {
+		if field.Name == smsNumberField {
+			return strings.Trim(field.Value, "`")
+		}
+	}
+	return ""
+}
+
+// rejectReply tells the author of a reply that it wasn't sent
+func (d *DiscordManager) rejectReply(event *events.MessageCreate, note string) {
+	if err := d.client.Rest().AddReaction(event.Message.ChannelID, event.Message.ID, "❌"); err != nil {
+		d.logger.Error("Failed to add error reaction", slog.Any("error", err))
+	}
+
+	_, err := d.client.Rest().CreateMessage(event.Message.ChannelID, discord.NewMessageCreateBuilder().
+		SetContent(note).
+		SetMessageReferenceByID(event.Message.ID).
+		SetAllowedMentions(&discord.AllowedMentions{RepliedUser: true}).
+		Build())
+	if err != nil {
+		d.logger.Error("Failed to send Discord response", slog.Any("error", err))
+	}
+}
+
+// outboundEmbedTitle identifies the embeds posted for sent SMS
+const outboundEmbedTitle = "📤 SMS Sent"
+
+// outboundEmbed records an SMS sent from Discord by sender
+func outboundEmbed(number, message string, sender snowflake.ID) discord.Embed {
+	return discord.NewEmbedBuilder().
+		SetTitle(outboundEmbedTitle).
+		SetDescription(message).
+		AddField(smsNumberField, "`"+number+"`", true).
+		AddField("Sent by", discord.UserMention(sender), true).
+		SetColor(0x5865f2).
+		SetTimestamp(time.Now()).
+		Build()
+}
+
+// logOutbound posts a sent SMS to the channel when discord.log_outbound is
+// enabled, so the team shares one record of the conversation
+func (d *DiscordManager) logOutbound(sender snowflake.ID, number, message string) {
+	if !d.config().Discord.LogOutbound {
+		return
+	}
+
+	// The sender is named, not pinged
+	err := d.postToChannels(d.smsChannels(number), discord.NewMessageCreateBuilder().
+		SetEmbeds(outboundEmbed(number, message, sender)).
+		SetAllowedMentions(&discord.AllowedMentions{}).
+		Build())
+	if err != nil {
+		d.logger.Error("Failed to log outbound SMS",
+			slog.String("number", number),
+			slog.Any("error", err))
+	}
+}
+
 // NotificationType represents the type of notification
 type NotificationType string
 
 const (
 	NotificationTypeSMS  NotificationType = "sms"
 	NotificationTypeCall NotificationType = "call"
+	NotificationTypeInfo NotificationType = "info"
 )
 
 // SendEmbed sends an embed message to the configured Discord channel
 func (d *DiscordManager) SendEmbed(notificationType NotificationType, from, message string) error {
-	channelID, err := snowflake.Parse(d.config.Discord.ChannelID)
-	if err != nil {
-		return fmt.Errorf("invalid channel ID: %w", err)
+	return d.sendEmbed(notificationType, from, message, nil)
+}
+
+// SendSMSEmbed posts a received SMS. With debug.raw_pdu_embed, owners can
+// see its PDUs with a button.
+func (d *DiscordManager) SendSMSEmbed(from, message string, pdus []string) error {
+	if !d.config().Debug.RawPDUEmbed {
+		pdus = nil
+	}
+	return d.sendEmbed(NotificationTypeSMS, from, message, pdus)
+}
+
+func (d *DiscordManager) sendEmbed(notificationType NotificationType, from, message string, pdus []string) error {
+	channels := d.config().Discord.ChannelIDs
+	if notificationType == NotificationTypeSMS {
+		channels = d.smsChannels(from)
 	}
 
 	var embed discord.Embed
 	switch notificationType {
 	case NotificationTypeSMS:
-		embed = discord.NewEmbedBuilder().
-			SetTitle("📱 SMS Message").
+		// The number field is what replies go to, the author can be a name
+		author := from
+		if name, ok := d.modem.PhonebookName(from); ok && name != "" {
+			author = name
+		}
+		builder := discord.NewEmbedBuilder().
+			SetTitle(smsEmbedTitle).
 			SetDescription(message).
-			SetAuthor(from, "", "").
+			SetAuthor(author, "", d.config().Discord.SMSIcon).
+			AddField(smsNumberField, "`"+from+"`", true).
 			SetColor(0x00ff00).
-			SetTimestamp(time.Now()).
-			Build()
+			SetTimestamp(d.modem.Now())
+		if origin, ok := d.numberOrigin(from); ok {
+			builder.AddField(originField, origin, true)
+		}
+		embed = builder.Build()
 	case NotificationTypeCall:
-		embed = discord.NewEmbedBuilder().
+		builder := discord.NewEmbedBuilder().
 			SetTitle("📞 Call").
 			SetDescription(message).
-			SetAuthor(from, "", "").
+			SetAuthor(from, "", d.config().Discord.CallIcon)
+		if origin, ok := d.numberOrigin(from); ok {
+			builder.AddField(originField, origin, true)
+		}
+		embed = builder.
+			AddField("🎙️ On air", d.onAirSummary(), false).
 			SetColor(0x0099ff).
-			SetTimestamp(time.Now()).
+			SetTimestamp(d.modem.Now()).
+			Build()
+	case NotificationTypeInfo:
+		embed = discord.NewEmbedBuilder().
+			SetTitle("ℹ️ Info").
+			SetDescription(message).
+			SetAuthor(from, "", "").
+			SetColor(0x95a5a6).
+			SetTimestamp(d.modem.Now()).
 			Build()
 	default:
 		return fmt.Errorf("unsupported notification type: %s", notificationType)
 	}
 
-	_, err = d.client.Rest().CreateMessage(channelID, discord.NewMessageCreateBuilder().
-		SetEmbeds(embed).
-		Build())
+	builder := discord.NewMessageCreateBuilder().
+		SetEmbeds(embed)
 
-	if err != nil {
+	// Only the configured user or role may be pinged
+	if notificationType == NotificationTypeSMS {
+		if mention, allowed, ok := d.mentionFor(from); ok {
+			builder.SetContent(mention).SetAllowedMentions(&allowed)
+		}
+	}
+	if len(pdus) > 0 {
+		builder.AddContainerComponents(d.rawPDUButton(pdus))
+	}
+
+	// Mirrors get the embed even if another channel fails
+	if err := d.postToChannels(channels, builder.Build()); err != nil {
 		d.logger.Error("Failed to send embed to Discord",
 			slog.String("type", string(notificationType)),
 			slog.String("from", from),
-			slog.String("channel", d.config.Discord.ChannelID),
+			slog.Any("channels", channels),
 			slog.Any("error", err))
 		return fmt.Errorf("failed to send Discord message: %w", err)
 	}
@@ -411,7 +1153,7 @@ func (d *DiscordManager) SendEmbed(notificationType NotificationType, from, mess
 	d.logger.Debug("Sent embed to Discord",
 		slog.String("type", string(notificationType)),
 		slog.String("from", from),
-		slog.String("channel", d.config.Discord.ChannelID))
+		slog.Any("channels", channels))
 
 	return nil
 }
@@ -0,0 +1,119 @@
+package machine
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golte/logger"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+func TestSMSEmbedNumber(t *testing.T) {
+	tests := []struct {
+		name  string
+		embed discord.Embed
+		want  string
+	}{
+		{"number field", discord.Embed{Fields: []discord.EmbedField{{Name: smsNumberField, Value: "`+33612345678`"}}}, "+33612345678"},
+		{"contact name", discord.Embed{Author: &discord.EmbedAuthor{Name: "Alice"}, Fields: []discord.EmbedField{{Name: smsNumberField, Value: "`+33612345678`"}}}, "+33612345678"},
+		{"author only", discord.Embed{Author: &discord.EmbedAuthor{Name: "+33612345678"}}, ""},
+		{"number in the text", discord.Embed{Description: "From +33 6 12 34 56 78: see you"}, ""},
+		{"nothing", discord.Embed{Description: "see you at 5"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := smsEmbedNumber(tt.embed); got != tt.want {
+				t.Errorf("smsEmbedNumber() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindSMSEmbed(t *testing.T) {
+	message := discord.Message{Embeds: []discord.Embed{
+		{Title: "📞 Call"},
+		{Title: smsEmbedTitle, Description: "code 1234"},
+	}}
+	if embed := findSMSEmbed(message); embed == nil || embed.Description != "code 1234" {
+		t.Errorf("findSMSEmbed() = %+v", embed)
+	}
+
+	if embed := findSMSEmbed(discord.Message{Embeds: []discord.Embed{{Title: "📞 Call"}}}); embed != nil {
+		t.Errorf("findSMSEmbed() on a call embed = %+v, want nil", embed)
+	}
+}
+
+func TestOutboundEmbed(t *testing.T) {
+	embed := outboundEmbed("+33612345678", "on my way", snowflake.ID(42))
+
+	if embed.Title != outboundEmbedTitle || embed.Description != "on my way" {
+		t.Fatalf("unexpected embed %q: %q", embed.Title, embed.Description)
+	}
+	if got := smsEmbedNumber(embed); got != "+33612345678" {
+		t.Errorf("smsEmbedNumber() = %q, want +33612345678", got)
+	}
+	if embed.Fields[1].Value != "<@42>" {
+		t.Errorf("sender field = %q, want <@42>", embed.Fields[1].Value)
+	}
+
+	// Replies go to received SMS, not to the log of sent ones
+	if findSMSEmbed(discord.Message{Embeds: []discord.Embed{embed}}) != nil {
+		t.Error("findSMSEmbed matched an outbound embed")
+	}
+}
+
+func TestContentHidden(t *testing.T) {
+	if !contentHidden(discord.Message{}) {
+		t.Error("a message without anything should have its content hidden")
+	}
+	if contentHidden(discord.Message{Content: "see you"}) {
+		t.Error("a message with text doesn't have its content hidden")
+	}
+	if contentHidden(discord.Message{Attachments: []discord.Attachment{{Filename: "photo.jpg"}}}) {
+		t.Error("an attachment only message doesn't have its content hidden")
+	}
+}
+
+func TestMessageListenerRedacted(t *testing.T) {
+	previous := slog.Default()
+	path := filepath.Join(t.TempDir(), "golte.log")
+	if err := logger.SetupOutput("debug", "text", logger.Output{File: path}); err != nil {
+		t.Fatal(err)
+	}
+	logger.SetRedaction(logger.RedactFull)
+	t.Cleanup(func() {
+		logger.SetRedaction(logger.RedactNone)
+		logger.SetupOutput("info", "text", logger.Output{Stdout: true})
+		slog.SetDefault(previous)
+	})
+
+	const body = "my code is 483920"
+	d := &DiscordManager{logger: slog.With("component", "discord")}
+	// From a bot, so it's dropped right after being logged
+	d.messageListener(&events.MessageCreate{GenericMessage: &events.GenericMessage{
+		Message: discord.Message{Content: body, Author: discord.User{Bot: true}},
+	}})
+
+	logged, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(logged), "Received message from Discord") {
+		t.Fatalf("the message wasn't logged: %s", logged)
+	}
+	if strings.Contains(string(logged), body) {
+		t.Errorf("the log file has the message body: %s", logged)
+	}
+	for _, record := range logger.Recent(logger.MaxRecentSize, slog.LevelDebug) {
+		if strings.Contains(record.String(), body) {
+			t.Errorf("the recent logs have the message body: %s", record)
+		}
+	}
+}
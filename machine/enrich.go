@@ -0,0 +1,22 @@
+package machine
+
+import (
+	"golte/numinfo"
+)
+
+// originField is the embed field naming the country and carrier of a number
+const originField = "🌍 Origin"
+
+// numberOrigin describes where a number is from when discord.enrich_numbers
+// is set, false for national numbers and alphanumeric senders
+func (d *DiscordManager) numberOrigin(number string) (string, bool) {
+	cfg := d.config()
+	if !cfg.Discord.EnrichNumbers {
+		return "", false
+	}
+	info, ok := numinfo.Lookup(number, cfg.Discord.CarrierPrefixes)
+	if !ok {
+		return "", false
+	}
+	return info.String(), true
+}
@@ -0,0 +1,114 @@
+package machine
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultErrorHistory is the number of errors kept by the machine's reporter
+const DefaultErrorHistory = 50
+
+// Severity classifies a reported error
+type Severity int
+
+const (
+	// SeverityTransient errors are logged and kept, the bridge keeps running
+	SeverityTransient Severity = iota
+	// SeverityFatal errors mean the bridge can't recover and must stop
+	SeverityFatal
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityTransient:
+		return "transient"
+	case SeverityFatal:
+		return "fatal"
+	default:
+		return "unknown"
+	}
+}
+
+// ReportedError is an error recorded by the ErrorReporter
+type ReportedError struct {
+	Time     time.Time
+	Err      error
+	Severity Severity
+}
+
+// ErrorReporter keeps the most recent errors queryable and signals fatal
+// ones. Unlike a plain buffered channel it never loses an error to a full
+// buffer: the history is a ring and fatal signals are coalesced.
+type ErrorReporter struct {
+	mu     sync.Mutex
+	recent []ReportedError
+	next   int
+	counts map[Severity]int
+	fatal  chan error
+}
+
+// NewErrorReporter creates a reporter keeping the last size errors
+func NewErrorReporter(size int) *ErrorReporter {
+	if size <= 0 {
+		size = DefaultErrorHistory
+	}
+	return &ErrorReporter{
+		recent: make([]ReportedError, 0, size),
+		counts: make(map[Severity]int),
+		fatal:  make(chan error, 1),
+	}
+}
+
+// Report records an error. Fatal errors are also delivered on Fatal; if one
+// is already pending the new one is only kept in the history.
+func (r *ErrorReporter) Report(err error, severity Severity) {
+	if err == nil {
+		return
+	}
+
+	r.mu.Lock()
+	entry := ReportedError{Time: time.Now(), Err: err, Severity: severity}
+	if len(r.recent) < cap(r.recent) {
+		r.recent = append(r.recent, entry)
+	} else {
+		r.recent[r.next] = entry
+	}
+	r.next = (r.next + 1) % cap(r.recent)
+	r.counts[severity]++
+	r.mu.Unlock()
+
+	if severity == SeverityFatal {
+		select {
+		case r.fatal <- err:
+		default:
+		}
+	}
+}
+
+// Recent returns the recorded errors, oldest first
+func (r *ErrorReporter) Recent() []ReportedError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]ReportedError, 0, len(r.recent))
+	if len(r.recent) == cap(r.recent) {
+		out = append(out, r.recent[r.next:]...)
+		out = append(out, r.recent[:r.next]...)
+	} else {
+		out = append(out, r.recent...)
+	}
+	return out
+}
+
+// Count returns the number of errors of the given severity reported since
+// creation
+func (r *ErrorReporter) Count(severity Severity) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counts[severity]
+}
+
+// Fatal returns a channel receiving fatal errors
+func (r *ErrorReporter) Fatal() <-chan error {
+	return r.fatal
+}
@@ -0,0 +1,52 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorReporterKeepsLastN(t *testing.T) {
+	r := NewErrorReporter(3)
+	for i := 0; i < 5; i++ {
+		r.Report(fmt.Errorf("error %d", i), SeverityTransient)
+	}
+
+	recent := r.Recent()
+	if len(recent) != 3 {
+		t.Fatalf("expected 3 errors, got %d", len(recent))
+	}
+	for i, want := range []string{"error 2", "error 3", "error 4"} {
+		if recent[i].Err.Error() != want {
+			t.Errorf("recent[%d] = %q, want %q", i, recent[i].Err, want)
+		}
+	}
+	if got := r.Count(SeverityTransient); got != 5 {
+		t.Errorf("Count(SeverityTransient) = %d, want 5", got)
+	}
+}
+
+func TestErrorReporterOnlySignalsFatal(t *testing.T) {
+	r := NewErrorReporter(10)
+
+	r.Report(errors.New("transient"), SeverityTransient)
+	select {
+	case err := <-r.Fatal():
+		t.Fatalf("unexpected fatal signal for %v", err)
+	default:
+	}
+
+	first := errors.New("first fatal")
+	r.Report(first, SeverityFatal)
+	r.Report(errors.New("second fatal"), SeverityFatal)
+
+	if err := <-r.Fatal(); err != first {
+		t.Fatalf("Fatal() = %v, want %v", err, first)
+	}
+	if got := r.Count(SeverityFatal); got != 2 {
+		t.Errorf("Count(SeverityFatal) = %d, want 2", got)
+	}
+	if len(r.Recent()) != 3 {
+		t.Fatalf("expected every error in the history, got %d", len(r.Recent()))
+	}
+}
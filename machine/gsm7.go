@@ -0,0 +1,79 @@
+package machine
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/warthog618/sms/encoding/gsm7/charset"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// The charset tables are generated lazily and without locking, so build them
+// once up front rather than from concurrent SendSMS calls
+var (
+	gsm7Encoder    = charset.DefaultEncoder()
+	gsm7ExtEncoder = charset.DefaultExtEncoder()
+)
+
+// gsm7Replacements covers common characters that have no accent to strip
+// but a close GSM-7 equivalent
+var gsm7Replacements = map[rune]string{
+	'‘': "'", '’': "'", '‚': "'", '‛': "'",
+	'“': "\"", '”': "\"", '„': "\"", '‟': "\"",
+	'«': "\"", '»': "\"",
+	'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '―': "-",
+	'…':      "...",
+	'\u00a0': " ", '\u2009': " ", '\u202f': " ",
+	'•': "-",
+}
+
+// isGSM7 reports whether r can be sent in the GSM-7 default alphabet or its
+// extension table
+func isGSM7(r rune) bool {
+	if _, ok := gsm7Encoder[r]; ok {
+		return true
+	}
+	_, ok := gsm7ExtEncoder[r]
+	return ok
+}
+
+func notGSM7(r rune) bool {
+	return !isGSM7(r)
+}
+
+// transliterateGSM7 replaces the characters of s that GSM-7 can't represent
+// with a close equivalent, leaving representable ones (é, ñ, ü...) untouched.
+// Characters without an equivalent are kept, so the message falls back to
+// UCS2 as before. It reports whether anything was replaced.
+func transliterateGSM7(s string) (string, bool) {
+	stripMarks := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+	var b strings.Builder
+	changed := false
+	for _, r := range s {
+		if isGSM7(r) {
+			b.WriteRune(r)
+			continue
+		}
+
+		if repl, ok := gsm7Replacements[r]; ok {
+			b.WriteString(repl)
+			changed = true
+			continue
+		}
+
+		stripMarks.Reset()
+		stripped, _, err := transform.String(stripMarks, string(r))
+		if err == nil && stripped != "" && strings.IndexFunc(stripped, notGSM7) < 0 {
+			b.WriteString(stripped)
+			changed = true
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String(), changed
+}
@@ -0,0 +1,28 @@
+package machine
+
+import "testing"
+
+func TestTransliterateGSM7(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		changed bool
+	}{
+		{"plain ascii", "Hello world", "Hello world", false},
+		{"gsm7 accents kept", "café à Köln ñ ü", "café à Köln ñ ü", false},
+		{"gsm7 extension kept", "100€ {ok}", "100€ {ok}", false},
+		{"smart quotes", "“it’s” – fine…", "\"it's\" - fine...", true},
+		{"stripped accents", "fête à Málaga, ça", "fete à Malaga, ca", true},
+		{"no equivalent", "hi 😀", "hi 😀", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := transliterateGSM7(tt.in)
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("transliterateGSM7(%q) = %q, %v, want %q, %v", tt.in, got, changed, tt.want, tt.changed)
+			}
+		})
+	}
+}
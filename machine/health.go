@@ -0,0 +1,112 @@
+package machine
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"golte/config"
+	"golte/health"
+
+	"github.com/disgoorg/disgo/gateway"
+)
+
+// StatusRunning is the status once the bridge is up, and after it recovered
+const StatusRunning = "Running"
+
+// Health checks that the modem, or the SMSC bind replacing it, answers and
+// that the Discord gateway is connected and the Telegram Bot API reachable,
+// when they're used. The components are recorded with since when they've
+// been healthy or not.
+func (m *Machine) Health() health.State {
+	now := time.Now()
+	s := health.State{Checked: now}
+	if received := m.lastSMS.Load(); received != 0 {
+		s.LastSMS = time.Unix(0, received)
+	}
+
+	cfg := m.config()
+	switch {
+	case !m.initialized.Load():
+		s.Modem = "not initialized"
+	case cfg.Modem.Type == config.ModemTypeGSM:
+		if err := m.modem.probe(); err != nil {
+			s.Modem = "not responding: " + err.Error()
+			break
+		}
+		registration, err := m.modem.Registration(modemQueryTimeout(cfg.Modem))
+		if err != nil {
+			s.Network = "registration unknown: " + err.Error()
+		} else if !strings.HasPrefix(registration, "registered") {
+			s.Network = registration
+		}
+	case cfg.Modem.Type == config.ModemTypeSMPP:
+		select {
+		case <-m.modem.Closed():
+			s.Modem = "SMSC connection closed"
+		default:
+		}
+	}
+
+	s.Components = []health.Component{
+		m.components.Update(health.ComponentModem, s.Modem, now),
+		m.components.Update(health.ComponentNetwork, s.Network, now),
+	}
+	if m.discord != nil {
+		if !m.discord.Connected() {
+			s.Discord = "gateway reconnecting"
+		}
+		s.Components = append(s.Components, m.components.Update(health.ComponentDiscord, s.Discord, now))
+	}
+	if m.telegram != nil {
+		if !m.telegram.Connected() {
+			s.Telegram = "Bot API unreachable"
+		}
+		s.Components = append(s.Components, m.components.Update(health.ComponentTelegram, s.Telegram, now))
+	}
+	return s
+}
+
+// Healthy returns an error unless every check of Health passes
+func (m *Machine) Healthy() error {
+	return m.Health().Err()
+}
+
+// recordHealth writes the Health of the bridge to health.state_file every
+// health.interval, for golte healthcheck. The file is removed on Stop.
+func (m *Machine) recordHealth() {
+	path := m.config().Health.StateFile
+	if path == "" {
+		return
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer os.Remove(path)
+
+		for {
+			if err := health.Write(path, m.Health()); err != nil {
+				m.logger.Warn("Failed to record health", slog.String("path", path), slog.Any("error", err))
+			}
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-time.After(m.config().Health.Interval):
+			}
+		}
+	}()
+}
+
+// setStatus reports a major state change, see WithStatusFunc
+func (m *Machine) setStatus(status string) {
+	if m.statusFunc != nil {
+		m.statusFunc(status)
+	}
+}
+
+// Connected reports whether the Discord gateway is connected and ready
+func (d *DiscordManager) Connected() bool {
+	return d.client != nil && d.client.HasGateway() && d.client.Gateway().Status() == gateway.StatusReady
+}
@@ -0,0 +1,60 @@
+package machine
+
+import (
+	"log/slog"
+	"time"
+
+	"golte/config"
+	"golte/storage"
+)
+
+// Statuses of the calls in the history
+const (
+	callStatusDialed   = "dialed"   // placed by the bridge, answered or not
+	callStatusReceived = "received" // rang the modem, whatever the bridge did with it
+)
+
+// openHistory opens the store of storage.path the SMS and calls are
+// recorded in, nil when it's empty
+func openHistory(cfg *config.Config) (storage.Store, error) {
+	if cfg.Storage.Path == "" {
+		return nil, nil
+	}
+	key, err := cfg.Storage.Key()
+	if err != nil {
+		return nil, err
+	}
+	return storage.OpenWithKey(cfg.Storage.Driver, cfg.Storage.Path, key)
+}
+
+// recordMessage adds an SMS to the history. Failing to is only logged, the
+// SMS was already handled.
+func (m *Machine) recordMessage(direction, number, text string) {
+	if m.history == nil {
+		return
+	}
+	msg := storage.Message{Time: time.Now(), Direction: direction, Number: number, Text: text}
+	if _, err := m.history.AddMessage(msg); err != nil {
+		m.logger.Error("Failed to record SMS in the history",
+			slog.String("direction", direction),
+			slog.Any("error", err))
+	}
+}
+
+// recordCall adds a call to the history
+func (m *Machine) recordCall(direction, number, status string) {
+	if m.history == nil {
+		return
+	}
+	c := storage.Call{Time: time.Now(), Direction: direction, Number: number, Status: status}
+	if _, err := m.history.AddCall(c); err != nil {
+		m.logger.Error("Failed to record call in the history",
+			slog.String("direction", direction),
+			slog.Any("error", err))
+	}
+}
+
+// recordIncomingCall adds a call ringing the modem to the history
+func (m *Machine) recordIncomingCall(number string) {
+	m.recordCall(storage.DirectionIn, number, callStatusReceived)
+}
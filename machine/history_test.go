@@ -0,0 +1,52 @@
+package machine
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"golte/config"
+	"golte/storage"
+)
+
+func TestHistory(t *testing.T) {
+	cfg := &config.Config{
+		Modem:   config.ModemConfig{Type: config.ModemTypeMock},
+		Storage: config.StorageConfig{Driver: storage.DriverSQLite, Path: filepath.Join(t.TempDir(), "golte.db")},
+	}
+	history, err := openHistory(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+
+	modem := NewModemManager(cfg, nil, nil, nil, nil)
+	m := &Machine{modem: modem, logger: slog.Default(), history: history}
+	m.cfg.Store(cfg)
+	m.webhooks = NewWebhookEmitter(context.Background(), m.config, modem, &m.wg)
+	modem.OnIncomingCall(m.recordIncomingCall)
+
+	if err := m.SendSMS("+33612345678", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := modem.mock.InjectCall("+33687654321"); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := history.Messages("", 0)
+	if err != nil || len(messages) != 1 || messages[0].Direction != storage.DirectionOut || messages[0].Text != "hello" {
+		t.Errorf("Messages() = %+v, %v", messages, err)
+	}
+	calls, err := history.Calls("", 0)
+	if err != nil || len(calls) != 1 || calls[0].Direction != storage.DirectionIn || calls[0].Number != "+33687654321" || calls[0].Status != callStatusReceived {
+		t.Errorf("Calls() = %+v, %v", calls, err)
+	}
+}
+
+func TestHistoryDisabled(t *testing.T) {
+	history, err := openHistory(&config.Config{})
+	if history != nil || err != nil {
+		t.Errorf("openHistory() without storage.path = %v, %v", history, err)
+	}
+}
@@ -0,0 +1,42 @@
+package machine
+
+import (
+	"log/slog"
+
+	"github.com/disgoorg/disgo/discord"
+)
+
+// messageContentHelp tells how to let the bot read replies
+const messageContentHelp = "enable the Message Content intent of the bot in the Discord developer portal (Bot, Privileged Gateway Intents)"
+
+// checkMessageContentIntent warns when the application isn't allowed the
+// Message Content intent, without it replies to SMS arrive empty
+func (d *DiscordManager) checkMessageContentIntent() {
+	app, err := d.client.Rest().GetCurrentApplication()
+	if err != nil {
+		d.logger.Debug("Failed to read the application flags", slog.Any("error", err))
+		return
+	}
+	if app.Flags.Missing(discord.ApplicationFlagGatewayMessageContent) && app.Flags.Missing(discord.ApplicationFlagGatewayMessageContentLimited) {
+		d.warnMessageContentIntent()
+	}
+}
+
+// warnMessageContentIntent logs, once, that replies can't be read
+func (d *DiscordManager) warnMessageContentIntent() {
+	if d.contentIntentWarned.Swap(true) {
+		return
+	}
+	d.logger.Warn("The bot can't read message content, replies to SMS won't be sent and /send has to be used instead. To fix it, " + messageContentHelp)
+}
+
+// contentHidden reports whether Discord left out the content of a message,
+// which happens for messages not mentioning the bot when it lacks the
+// Message Content intent
+func contentHidden(message discord.Message) bool {
+	return message.Content == "" &&
+		len(message.Attachments) == 0 &&
+		len(message.Embeds) == 0 &&
+		len(message.StickerItems) == 0 &&
+		len(message.Components) == 0
+}
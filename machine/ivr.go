@@ -0,0 +1,161 @@
+package machine
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"golte/config"
+)
+
+// IVR prompts
+const (
+	passwordPrompt        = "audio/bonjour_veuillez_entrez_votre_mot_de_passe.mp3"
+	passwordCorrectPrompt = "audio/mot_de_passe_correct.mp3"
+)
+
+// pinAttempts counts the wrong PINs in a row, across calls, to lock PIN
+// entry out for security.lockout_duration after security.max_pin_attempts
+type pinAttempts struct {
+	mu          sync.Mutex
+	failures    int
+	lockedUntil time.Time
+}
+
+// locked reports whether PINs are refused at now
+func (p *pinAttempts) locked(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return now.Before(p.lockedUntil)
+}
+
+// fail counts a wrong PIN and reports whether it locks PINs out, max 0
+// never does
+func (p *pinAttempts) fail(now time.Time, max int, lockout time.Duration) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures++
+	if max == 0 || p.failures < max {
+		return false
+	}
+	p.failures = 0
+	p.lockedUntil = now.Add(lockout)
+	return true
+}
+
+// succeed clears the wrong PINs
+func (p *pinAttempts) succeed() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures = 0
+}
+
+// checkPassword checks an entry the caller ended, and confirms to them if
+// it's the PIN of security.dtmf_pin_hash. Every entry checked counts toward
+// security.max_pin_attempts until the right one, none is while locked out.
+func (m *ModemManager) checkPassword(input string) bool {
+	security := m.config().Security
+	if input == "" || security.DTMFPinHash == "" {
+		return false
+	}
+	if m.pins.locked(time.Now()) {
+		m.logger.Info("PIN refused, entry is locked out")
+		return false
+	}
+	if len(input) < config.MinPINLength || !config.VerifyPIN(security.DTMFPinHash, input) {
+		m.wrongPassword(input)
+		return false
+	}
+
+	m.pins.succeed()
+	m.logger.Info("Password entered correctly")
+	m.state.Reset()
+	if err := m.playPrompt(passwordCorrectPrompt); err != nil {
+		m.logger.Error("Failed to play prompt", slog.Any("error", err))
+	}
+	return true
+}
+
+// wrongPassword counts an entry that ended without the PIN, locking PINs
+// out once security.max_pin_attempts are wrong in a row. An entry ended
+// while locked out isn't counted.
+func (m *ModemManager) wrongPassword(input string) {
+	if input == "" {
+		return
+	}
+	now := time.Now()
+	if m.pins.locked(now) {
+		m.logger.Info("PIN refused, entry is locked out")
+		return
+	}
+
+	security := m.config().Security
+	m.logger.Info("Wrong password entered")
+	if m.pins.fail(now, security.MaxPinAttempts, security.LockoutDuration) {
+		m.logger.Warn("Too many wrong PINs, PIN entry locked out",
+			slog.Int("attempts", security.MaxPinAttempts),
+			slog.Duration("lockout", security.LockoutDuration))
+	}
+}
+
+// dtmfDigit handles a key pressed by the caller. # ends the entry, which is
+// then checked. An entry longer than the longest PIN is wrong without
+// checking it.
+func (m *ModemManager) dtmfDigit(digit string) {
+	if digit == "#" {
+		input := m.state.Password()
+		m.state.Reset()
+		m.checkPassword(input)
+		return
+	}
+
+	m.keypressFeedback(digit)
+
+	cfg := m.config().Call
+	input := m.state.AddDigitWithTimeout(digit, cfg.DTMFTimeout, m.dtmfTimeout)
+	if len(input) > config.MaxPINLength {
+		m.state.Reset()
+		m.wrongPassword(input)
+	}
+}
+
+// dtmfTimeout handles the caller pausing for call.dtmf_timeout in the middle
+// of an entry, which is already cleared. The evaluate action checks the
+// entry as # would, reset discards it. The password prompt is then replayed
+// if call.dtmf_timeout_replay is set.
+func (m *ModemManager) dtmfTimeout(input string) {
+	defer recoverPanic(m.logger, "DTMF timeout", m.abortCall, m.panicFunc)
+
+	// The caller may have hung up without finishing
+	if !m.callActive() {
+		return
+	}
+
+	cfg := m.config().Call
+	m.logger.Info("DTMF entry timed out",
+		slog.Int("digits", len(input)),
+		slog.String("action", cfg.DTMFTimeoutAction))
+	if cfg.DTMFTimeoutAction == config.DTMFTimeoutEvaluate {
+		m.checkPassword(input)
+	} else {
+		// An abandoned entry counts as a wrong PIN too, or the lockout
+		// could be dodged by pausing
+		m.wrongPassword(input)
+	}
+
+	if cfg.DTMFTimeoutReplay {
+		if err := m.playPrompt(passwordPrompt); err != nil {
+			m.logger.Error("Failed to play prompt", slog.Any("error", err))
+		}
+	}
+}
+
+// callActive reports whether the modem has a call in progress
+func (m *ModemManager) callActive() bool {
+	c := m.callManager()
+	if c == nil {
+		return false
+	}
+	calls, err := c.GetCallStatus()
+	return err == nil && len(calls) > 0
+}
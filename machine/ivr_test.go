@@ -0,0 +1,121 @@
+package machine
+
+import (
+	"testing"
+	"time"
+
+	"golte/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestIVR returns a modem whose IVR checks the PIN 2468, locking out
+// after maxAttempts wrong ones
+func newTestIVR(t *testing.T, maxAttempts int, lockout time.Duration) *ModemManager {
+	t.Helper()
+	// The lowest cost keeps checking every key fast
+	hash, err := bcrypt.GenerateFromPassword([]byte("2468"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{Security: config.SecurityConfig{
+		DTMFPinHash:     string(hash),
+		MaxPinAttempts:  maxAttempts,
+		LockoutDuration: lockout,
+	}}
+	return NewModemManager(cfg, nil, &fakeSMS{}, func(event, from, message string) {}, nil)
+}
+
+// enter presses keys as a caller would
+func enter(m *ModemManager, keys string) {
+	for _, key := range keys {
+		m.dtmfDigit(string(key))
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	m := newTestIVR(t, 0, 0)
+
+	if !m.checkPassword("2468") {
+		t.Error("checkPassword() rejected the PIN")
+	}
+	for _, input := range []string{"1357", "246", "24680", ""} {
+		if m.checkPassword(input) {
+			t.Errorf("checkPassword(%q) accepted a wrong PIN", input)
+		}
+	}
+
+	// No PIN lets anyone through
+	m.Reconfigure(&config.Config{})
+	if m.checkPassword("2468") || m.checkPassword("52226636") {
+		t.Error("checkPassword() without security.dtmf_pin_hash accepted a PIN")
+	}
+}
+
+func TestWrongPIN(t *testing.T) {
+	m := newTestIVR(t, 3, time.Hour)
+
+	enter(m, "1357#")
+	if m.pins.failures != 1 || m.state.Password() != "" {
+		t.Errorf("after a wrong PIN: %d failures, entry %q", m.pins.failures, m.state.Password())
+	}
+	// A # alone isn't a try
+	enter(m, "#")
+	if m.pins.failures != 1 {
+		t.Errorf("# counted as a wrong PIN, %d failures", m.pins.failures)
+	}
+	// Nor is a caller keying in too long an entry left to go on forever
+	enter(m, "11111111111111111")
+	if m.pins.failures != 2 || m.state.Password() != "" {
+		t.Errorf("after 17 wrong keys: %d failures, entry %q", m.pins.failures, m.state.Password())
+	}
+
+	// The PIN is only checked once the entry ends
+	enter(m, "2468")
+	if m.pins.failures != 2 || m.state.Password() != "2468" {
+		t.Errorf("the PIN was checked before #: %d failures, entry %q", m.pins.failures, m.state.Password())
+	}
+	enter(m, "#")
+	if m.pins.failures != 0 {
+		t.Errorf("the right PIN left %d failures", m.pins.failures)
+	}
+}
+
+func TestPINLockout(t *testing.T) {
+	m := newTestIVR(t, 2, time.Hour)
+
+	enter(m, "1357#")
+	enter(m, "9999#")
+	if !m.pins.locked(time.Now()) {
+		t.Fatal("max_pin_attempts wrong PINs didn't lock out")
+	}
+	if m.checkPassword("2468") {
+		t.Error("checkPassword() accepted the PIN while locked out")
+	}
+	enter(m, "2468#")
+	if m.state.Password() != "" || !m.pins.locked(time.Now()) {
+		t.Error("the PIN got through the lockout")
+	}
+}
+
+func TestPINLockoutExpires(t *testing.T) {
+	m := newTestIVR(t, 1, 50*time.Millisecond)
+
+	enter(m, "1357#")
+	if m.checkPassword("2468") {
+		t.Fatal("checkPassword() accepted the PIN while locked out")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if !m.checkPassword("2468") {
+		t.Error("checkPassword() refused the PIN after the lockout")
+	}
+
+	var p pinAttempts
+	now := time.Now()
+	if !p.fail(now, 1, time.Minute) || !p.locked(now.Add(59*time.Second)) || p.locked(now.Add(time.Minute)) {
+		t.Error("the lockout doesn't last lockout_duration")
+	}
+	if p.fail(now, 0, time.Minute) {
+		t.Error("max_pin_attempts 0 locked out")
+	}
+}
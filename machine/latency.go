@@ -0,0 +1,237 @@
+package machine
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AT command latency recording
+const (
+	latencySamples     = 256              // kept per command, the percentiles are over those
+	latencyCommands    = 64               // distinct commands tracked, the rest count as other
+	latencyLogInterval = 10 * time.Minute // between debug log summaries
+)
+
+// latencyOther is the command name of commands past latencyCommands
+const latencyOther = "other"
+
+// atFinalResults end the response to a command
+var atFinalResults = []string{"OK", "ERROR", "+CME ERROR", "+CMS ERROR", "NO CARRIER", "NO ANSWER", "BUSY", "NO DIALTONE", "CONNECT"}
+
+// ATLatency is the latency distribution of an AT command
+type ATLatency struct {
+	Command string  `json:"command"` // e.g. +CSQ, +COPS=? or D, without arguments
+	Count   int64   `json:"count"`   // commands timed since startup
+	P50     float64 `json:"p50_ms"`
+	P90     float64 `json:"p90_ms"`
+	P99     float64 `json:"p99_ms"`
+	Max     float64 `json:"max_ms"` // of the recent samples the percentiles are over
+}
+
+// latencyRecorder times AT commands from being written to the modem to
+// their final result being read back. The AT session sends one command at
+// a time, so there's at most one to time.
+type latencyRecorder struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	commands map[string]*latencyRing
+	pending  string // command written and not answered yet, empty if none
+	started  time.Time
+	line     []byte // partial line read so far
+}
+
+// latencyRing holds the last latencySamples of a command
+type latencyRing struct {
+	count   int64
+	samples []time.Duration
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{now: time.Now, commands: make(map[string]*latencyRing)}
+}
+
+// Wrap returns rw timing the commands going through it
+func (l *latencyRecorder) Wrap(rw io.ReadWriter) io.ReadWriter {
+	return &latencyReadWriter{rw: rw, recorder: l}
+}
+
+// written notes a command line sent to the modem. SMS bodies following a
+// > prompt aren't commands, they're timed as part of +CMGS.
+func (l *latencyRecorder) written(p []byte) {
+	if len(p) < 2 || !strings.EqualFold(string(p[:2]), "AT") {
+		return
+	}
+	name := atCommandName(strings.TrimRight(string(p[2:]), "\r\n"))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending, l.started = name, l.now()
+}
+
+// read looks for the final result of the pending command in p
+func (l *latencyRecorder) read(p []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			// Bounded, a line longer than a response is noise
+			if len(l.line)+len(p) <= 512 {
+				l.line = append(l.line, p...)
+			}
+			return
+		}
+		line := strings.TrimSpace(string(append(l.line, p[:i]...)))
+		l.line, p = l.line[:0], p[i+1:]
+
+		if l.pending != "" && isFinalResult(line) {
+			l.record(l.pending, l.now().Sub(l.started))
+			l.pending = ""
+		}
+	}
+}
+
+// record adds a sample of command, with l.mu held
+func (l *latencyRecorder) record(command string, d time.Duration) {
+	ring, ok := l.commands[command]
+	if !ok {
+		if len(l.commands) >= latencyCommands {
+			command = latencyOther
+			ring = l.commands[command]
+		}
+		if ring == nil {
+			ring = &latencyRing{}
+			l.commands[command] = ring
+		}
+	}
+	if len(ring.samples) < latencySamples {
+		ring.samples = append(ring.samples, d)
+	} else {
+		ring.samples[ring.count%latencySamples] = d
+	}
+	ring.count++
+}
+
+// Summary returns the latency of every command timed, by command
+func (l *latencyRecorder) Summary() []ATLatency {
+	l.mu.Lock()
+	summary := make([]ATLatency, 0, len(l.commands))
+	for command, ring := range l.commands {
+		samples := slices.Clone(ring.samples)
+		slices.Sort(samples)
+		summary = append(summary, ATLatency{
+			Command: command,
+			Count:   ring.count,
+			P50:     milliseconds(percentile(samples, 50)),
+			P90:     milliseconds(percentile(samples, 90)),
+			P99:     milliseconds(percentile(samples, 99)),
+			Max:     milliseconds(samples[len(samples)-1]),
+		})
+	}
+	l.mu.Unlock()
+
+	slices.SortFunc(summary, func(a, b ATLatency) int { return strings.Compare(a.Command, b.Command) })
+	return summary
+}
+
+// logSummary logs the latency of every command at debug level every
+// latencyLogInterval until ctx is done
+func (l *latencyRecorder) logSummary(ctx context.Context, logger *slog.Logger) {
+	ticker := time.NewTicker(latencyLogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !logger.Enabled(ctx, slog.LevelDebug) {
+			continue
+		}
+		for _, latency := range l.Summary() {
+			logger.Debug("AT command latency",
+				slog.String("command", latency.Command),
+				slog.Int64("count", latency.Count),
+				slog.Float64("p50_ms", latency.P50),
+				slog.Float64("p90_ms", latency.P90),
+				slog.Float64("p99_ms", latency.P99),
+				slog.Float64("max_ms", latency.Max))
+		}
+	}
+}
+
+// ATLatency returns the latency of every AT command sent to the modem since
+// startup, by command
+func (m *ModemManager) ATLatency() []ATLatency {
+	return m.latency.Summary()
+}
+
+// latencyReadWriter is the serial link as seen by the AT session
+type latencyReadWriter struct {
+	rw       io.ReadWriter
+	recorder *latencyRecorder
+}
+
+func (l *latencyReadWriter) Write(p []byte) (int, error) {
+	l.recorder.written(p)
+	return l.rw.Write(p)
+}
+
+func (l *latencyReadWriter) Read(p []byte) (int, error) {
+	n, err := l.rw.Read(p)
+	if n > 0 {
+		l.recorder.read(p[:n])
+	}
+	return n, err
+}
+
+// atCommandName names a command line without its AT prefix and arguments,
+// keeping test (=?) and read (?) commands apart: +CSQ, +COPS=?, +CREG?,
+// +CMGS= or D for a dial, whose number mustn't end up in metrics
+func atCommandName(cmd string) string {
+	if cmd == "" {
+		return "AT"
+	}
+	if !strings.ContainsAny(cmd[:1], "+&^$%#*") {
+		// Basic commands are a letter, like D, A, H or E0
+		return strings.ToUpper(cmd[:1])
+	}
+	name, args, found := strings.Cut(cmd, "=")
+	switch {
+	case !found:
+		return strings.ToUpper(name)
+	case args == "?":
+		return strings.ToUpper(name) + "=?"
+	default:
+		return strings.ToUpper(name) + "="
+	}
+}
+
+// isFinalResult reports whether a response line ends a command
+func isFinalResult(line string) bool {
+	for _, result := range atFinalResults {
+		if strings.HasPrefix(line, result) {
+			return true
+		}
+	}
+	return false
+}
+
+// percentile returns the p-th percentile of sorted samples, by the nearest
+// rank
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
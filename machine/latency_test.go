@@ -0,0 +1,93 @@
+package machine
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+// scriptedSerial answers reads from a script, one chunk per read
+type scriptedSerial struct {
+	written bytes.Buffer
+	chunks  []string
+}
+
+func (f *scriptedSerial) Write(p []byte) (int, error) { return f.written.Write(p) }
+
+func (f *scriptedSerial) Read(p []byte) (int, error) {
+	if len(f.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, f.chunks[0])
+	f.chunks = f.chunks[1:]
+	return n, nil
+}
+
+func TestLatencyRecorder(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	l := newLatencyRecorder()
+	l.now = func() time.Time { return now }
+
+	serial := &scriptedSerial{chunks: []string{"\r\n+CSQ: 20", ",99\r\n", "\r\nO", "K\r\n"}}
+	rw := l.Wrap(serial)
+	rw.Write([]byte("AT+CSQ\r"))
+	buf := make([]byte, 64)
+	rw.Read(buf)
+	now = now.Add(40 * time.Millisecond)
+	for range 3 {
+		rw.Read(buf)
+	}
+
+	// An unsolicited result without a pending command isn't timed
+	serial.chunks = []string{"\r\nNO CARRIER\r\n"}
+	rw.Read(buf)
+
+	summary := l.Summary()
+	if len(summary) != 1 {
+		t.Fatalf("Summary() = %+v, want +CSQ only", summary)
+	}
+	if s := summary[0]; s.Command != "+CSQ" || s.Count != 1 || s.P50 != 40 || s.Max != 40 {
+		t.Errorf("Summary() = %+v", s)
+	}
+	if serial.written.String() != "AT+CSQ\r" {
+		t.Errorf("written %q", serial.written.String())
+	}
+}
+
+func TestLatencyPercentiles(t *testing.T) {
+	l := newLatencyRecorder()
+	for i := 1; i <= 100; i++ {
+		l.record("+COPS=?", time.Duration(i)*time.Millisecond)
+	}
+	s := l.Summary()[0]
+	if s.Count != 100 || s.P50 != 50 || s.P90 != 90 || s.P99 != 99 || s.Max != 100 {
+		t.Errorf("Summary() = %+v", s)
+	}
+
+	// Only the last latencySamples count
+	for range latencySamples {
+		l.record("+COPS=?", time.Millisecond)
+	}
+	if s := l.Summary()[0]; s.Count != 100+latencySamples || s.Max != 1 {
+		t.Errorf("Summary() after wrapping = %+v", s)
+	}
+}
+
+func TestATCommandName(t *testing.T) {
+	tests := map[string]string{
+		"":                   "AT",
+		"+CSQ":               "+CSQ",
+		"+creg?":             "+CREG?",
+		"+COPS=?":            "+COPS=?",
+		"+CMGS=23":           "+CMGS=",
+		"D+33612345678;":     "D",
+		"E0":                 "E",
+		`+CPBW=1,"0612",129`: "+CPBW=",
+	}
+	for cmd, want := range tests {
+		if got := atCommandName(cmd); got != want {
+			t.Errorf("atCommandName(%q) = %q, want %q", cmd, got, want)
+		}
+	}
+}
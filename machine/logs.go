@@ -0,0 +1,84 @@
+package machine
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golte/logger"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+)
+
+// Lines /logs shows
+const (
+	defaultLogLines = 20
+	maxLogLines     = 100
+)
+
+// maxMessageLength is the most characters a Discord message holds
+const maxMessageLength = 2000
+
+// logLevels are the level choices of /logs
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// handleLogs shows the last log lines to an owner
+func (d *DiscordManager) handleLogs(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) {
+	lines := defaultLogLines
+	if n, ok := data.OptInt("lines"); ok {
+		lines = min(max(n, 1), maxLogLines)
+	}
+	level := slog.LevelDebug
+	if name, ok := data.OptString("level"); ok {
+		level = logLevels[name]
+	}
+
+	records := logger.Recent(lines, level)
+	if len(records) == 0 {
+		d.respondEphemeral(event.CreateMessage, "📜 Nothing logged at that level yet")
+		return
+	}
+	d.respondEphemeral(event.CreateMessage, formatLogs(records, d.config().General.Location(), maxMessageLength))
+}
+
+// formatLogs puts records, timed in loc, in a code block of at most limit
+// characters, dropping the oldest lines and cutting long ones to fit
+func formatLogs(records []logger.Record, loc *time.Location, limit int) string {
+	const fence = "```\n"
+	const maxLine = 300
+	const note = "\n%d older lines left out"
+	budget := limit - 2*len(fence) - len(note)
+
+	var lines []string
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		record.Time = record.Time.In(loc)
+		line := strings.ReplaceAll(record.String(), "```", "'''")
+		if runes := []rune(line); len(runes) > maxLine {
+			line = string(runes[:maxLine-1]) + "…"
+		}
+		if len(line)+1 > budget {
+			break
+		}
+		budget -= len(line) + 1
+		lines = append(lines, line)
+	}
+
+	var b strings.Builder
+	b.WriteString(fence)
+	for i := len(lines) - 1; i >= 0; i-- {
+		b.WriteString(lines[i] + "\n")
+	}
+	b.WriteString(strings.TrimSuffix(fence, "\n"))
+	if dropped := len(records) - len(lines); dropped > 0 {
+		return b.String() + fmt.Sprintf(note, dropped)
+	}
+	return b.String()
+}
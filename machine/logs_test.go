@@ -0,0 +1,39 @@
+package machine
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"golte/logger"
+)
+
+func TestFormatLogs(t *testing.T) {
+	at := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	records := []logger.Record{
+		{Time: at, Level: slog.LevelInfo, Message: "oldest"},
+		{Time: at, Level: slog.LevelWarn, Message: "Failed", Attrs: "component=modem error=timeout"},
+		{Time: at, Level: slog.LevelInfo, Message: "newest " + strings.Repeat("x", 500)},
+	}
+
+	got := formatLogs(records, time.UTC, maxMessageLength)
+	if !strings.HasPrefix(got, "```\n12:00:00 INFO oldest\n12:00:00 WARN Failed component=modem error=timeout\n") || !strings.HasSuffix(got, "…\n```") {
+		t.Errorf("formatLogs() = %q", got)
+	}
+
+	// Only the newest lines fit
+	got = formatLogs(records, time.UTC, 380)
+	if len(got) > 380 || strings.Contains(got, "oldest") || !strings.Contains(got, "newest") || !strings.HasSuffix(got, "2 older lines left out") {
+		t.Errorf("formatLogs() within 380 = %q (%d)", got, len(got))
+	}
+
+	// Shown in general.timezone
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("no timezone database:", err)
+	}
+	if got := formatLogs(records[:1], paris, maxMessageLength); !strings.Contains(got, "14:00:00 INFO oldest") {
+		t.Errorf("formatLogs() in Europe/Paris = %q", got)
+	}
+}
@@ -6,69 +6,156 @@ import (
 	"log"
 	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"golte/config"
+	"golte/health"
 	"golte/playback"
+	"golte/storage"
 
-	"github.com/gopxl/beep/v2"
 	"github.com/warthog618/modem/gsm"
 )
 
 // Machine represents the main application state
 type Machine struct {
-	config        *config.Config
+	cfg           atomic.Pointer[config.Config]
+	reloadMu      sync.Mutex
 	modem         *ModemManager
-	discord       *DiscordManager
+	discord       *DiscordManager // nil when Telegram replaces it
+	telegram      *TelegramNotifier
+	notifiers     []Notifier // posted the SMS, calls and alerts
 	signalMonitor *SignalMonitor
+	api           *APIServer
+	mqtt          *MQTTBridge
+	webhooks      *WebhookEmitter
 	logger        *slog.Logger
 	playback      *playback.Playback
+	history       storage.Store // SMS and calls seen, nil without storage.path
 	ctx           context.Context
 	cancel        context.CancelFunc
 	wg            sync.WaitGroup
 	stopChan      chan struct{}
-	errorChan     chan error
+	errors        *ErrorReporter
+	statusFunc    func(status string)
+	lastSMS       atomic.Int64    // when the last SMS was received, in Unix nanoseconds
+	initialized   atomic.Bool     // the transport and Discord are initialized
+	components    *health.Tracker // since when each part has been healthy, see Health
+}
+
+// Option configures a Machine
+type Option func(*options)
+
+type options struct {
+	statusFunc func(status string)
+	version    string
+}
+
+// WithStatusFunc makes the machine describe its major state changes, like
+// losing the modem, to f
+func WithStatusFunc(f func(status string)) Option {
+	return func(o *options) {
+		o.statusFunc = f
+	}
+}
+
+// WithVersion sets the version of the bridge shown in Discord
+func WithVersion(version string) Option {
+	return func(o *options) {
+		o.version = version
+	}
 }
 
 // New creates a new Machine instance
-func New(cfg *config.Config) *Machine {
+func New(cfg *config.Config, opts ...Option) *Machine {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	m := &Machine{
-		config:    cfg,
-		logger:    slog.With("component", "machine"),
-		ctx:       ctx,
-		cancel:    cancel,
-		stopChan:  make(chan struct{}),
-		errorChan: make(chan error, 10),
+		logger:     slog.With("component", "machine"),
+		ctx:        ctx,
+		cancel:     cancel,
+		stopChan:   make(chan struct{}),
+		errors:     NewErrorReporter(DefaultErrorHistory),
+		statusFunc: o.statusFunc,
+		components: health.NewTracker(),
 	}
-
-	pb, err := playback.NewPlayback(beep.SampleRate(48000))
-	if err != nil {
-		log.Fatal(err)
+	m.cfg.Store(cfg)
+
+	// Without voice nothing is played, the speaker isn't opened
+	var pb *playback.Playback
+	if cfg.Voice.Enabled {
+		var err error
+		if pb, err = NewPlayback(cfg); err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	// Initialize components
-	m.modem = NewModemManager(cfg, pb, m.sendCallNotification)
-	m.signalMonitor = NewSignalMonitor(cfg, m.modem, &m.wg)
-	m.discord = NewDiscordManager(cfg, pb, m.SendSMS, m.StartCall, m.HangUpCall, m.sendDiscordEmbed)
+	m.modem = NewModemManager(cfg, pb, nil, m.sendCallNotification, m.sendSIMNotification)
+	m.modem.OnIncomingCall(m.recordIncomingCall)
+	if cfg.Modem.Type != config.ModemTypeSMPP {
+		// No radio to poll behind an SMSC account
+		m.signalMonitor = NewSignalMonitor(ctx, cfg, m.modem, m.notify, &m.wg)
+	}
+	if cfg.DiscordEnabled() {
+		m.discord = NewDiscordManager(cfg, pb, m.modem, m.SendSMS, m.StartCall, m.HangUpCall, m.Reload, m.notify)
+		m.discord.version = o.version
+		m.discord.panicFunc = m.recovered
+		m.notifiers = append(m.notifiers, m.discord)
+	}
+	if cfg.Telegram.Enabled() {
+		m.telegram = NewTelegramNotifier(m.config, m.modem, m.SendSMS, m.audit, &m.wg)
+		m.notifiers = append(m.notifiers, m.telegram)
+	}
+	m.modem.panicFunc = m.recovered
 	m.playback = pb
+	if cfg.API.Enabled() {
+		m.api = NewAPIServer(m.config, m.modem, m.SendSMS, m.StartCall, m.Health, m.audit, &m.wg)
+	}
+	// Always created, webhooks can be added by a reload
+	m.webhooks = NewWebhookEmitter(ctx, m.config, m.modem, &m.wg)
+	if cfg.MQTT.Enabled() {
+		m.mqtt = NewMQTTBridge(m.config, m.modem, m.SendSMS, m.StartCall, m.HangUpCall, m.audit, &m.wg)
+		if m.signalMonitor != nil {
+			m.signalMonitor.sampleFunc = m.mqtt.PublishSample
+		}
+	}
 	return m
 }
 
+// config returns the active configuration
+func (m *Machine) config() *config.Config {
+	return m.cfg.Load()
+}
+
 // Initialize sets up the machine components
 func (m *Machine) Initialize() error {
 	m.logger.Info("Initializing machine...")
 
-	// Initialize modem
+	history, err := openHistory(m.config())
+	if err != nil {
+		return fmt.Errorf("failed to open the history of storage.path: %w", err)
+	}
+	m.history = history
+
+	// Initialize modem, or the SMPP bind when it replaces the modem for SMS
 	if err := m.modem.Initialize(); err != nil {
-		return fmt.Errorf("failed to initialize modem: %w", err)
+		return fmt.Errorf("failed to initialize %s transport: %w", m.config().Modem.Type, err)
 	}
 
-	// Initialize Discord client
-	if err := m.discord.Initialize(); err != nil {
-		return fmt.Errorf("failed to initialize Discord: %w", err)
+	// Initialize the Discord client and the other notifiers
+	for _, n := range m.notifiers {
+		if err := n.Initialize(); err != nil {
+			return fmt.Errorf("failed to initialize %s: %w", n.Name(), err)
+		}
 	}
 
+	m.initialized.Store(true)
 	m.logger.Info("Machine initialized successfully")
 	return nil
 }
@@ -83,22 +170,62 @@ func (m *Machine) Start() error {
 	}
 
 	// Start signal quality polling
-	m.signalMonitor.SetContext(m.ctx)
-	m.signalMonitor.Start()
+	if m.signalMonitor != nil {
+		m.signalMonitor.Start()
+	}
+
+	// Losing the modem is the one condition the bridge can't recover from
+	m.watchModem()
 
-	// Start Discord gateway
-	if err := m.discord.Start(m.ctx); err != nil {
-		return fmt.Errorf("failed to connect to Discord gateway: %w", err)
+	// For tuning a slow serial link, with debug logging
+	if m.config().Modem.Type == config.ModemTypeGSM {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.modem.latency.logSummary(m.ctx, m.modem.logger)
+		}()
+	}
+
+	// Start the Discord gateway and the other notifiers
+	for _, n := range m.notifiers {
+		if err := n.Start(m.ctx); err != nil {
+			return err
+		}
+	}
+
+	// For golte healthcheck
+	m.recordHealth()
+
+	// Stopped with the context
+	if m.api != nil {
+		if err := m.api.Serve(m.ctx); err != nil {
+			return err
+		}
+	}
+	if m.mqtt != nil {
+		m.mqtt.Run(m.ctx)
 	}
 
 	m.logger.Info("Machine started successfully")
 	return nil
 }
 
+// drainTimeout bounds how long Stop waits for queued prompts to play out
+const drainTimeout = 2 * time.Second
+
 // Stop gracefully shuts down the machine
 func (m *Machine) Stop() error {
 	m.logger.Info("Stopping machine...")
 
+	// Let the prompt being played reach the caller before tearing down
+	if m.playback != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		if err := m.playback.Drain(ctx); err != nil {
+			m.logger.Warn("Playback cut off before it drained", slog.Any("error", err))
+		}
+		cancel()
+	}
+
 	// Cancel context to stop all operations
 	m.cancel()
 
@@ -107,53 +234,120 @@ func (m *Machine) Stop() error {
 		m.signalMonitor.Stop()
 	}
 
-	// Close Discord connection
-	if m.discord != nil {
-		m.discord.Stop()
+	// Close the Discord connection and the other notifiers
+	for _, n := range m.notifiers {
+		n.Stop()
 	}
 
 	// Stop SMS reception
-	if m.modem != nil {
-		m.modem.StopMessageReception()
-	}
+	m.modem.StopMessageReception()
 
 	// Wait for all goroutines to finish
 	m.wg.Wait()
+	m.modem.tracer.Stop()
+
+	if m.history != nil {
+		if err := m.history.Close(); err != nil {
+			m.logger.Error("Failed to close the history", slog.Any("error", err))
+		}
+	}
 
 	m.logger.Info("Machine stopped")
 	return nil
 }
 
-// Wait blocks until the machine is stopped
+// Wait blocks until the machine is stopped or a fatal error occurs.
+// Transient errors are only recorded, see RecentErrors.
 func (m *Machine) Wait() error {
 	select {
 	case <-m.ctx.Done():
 		return m.ctx.Err()
-	case err := <-m.errorChan:
+	case err := <-m.errors.Fatal():
 		return err
-	case <-m.modem.Closed():
-		return fmt.Errorf("modem connection closed")
 	}
 }
 
-// SendSMS sends an SMS message through the modem
+// watchModem restores the modem connection when it's lost, and reports a
+// fatal error when that fails or the SMSC connection is lost
+func (m *Machine) watchModem() {
+	m.modem.WatchLink()
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-m.modem.Closed():
+			}
+
+			m.logger.Error("SMS transport connection closed", slog.String("type", m.config().Modem.Type))
+			if m.config().Modem.Type == config.ModemTypeSMPP {
+				m.errors.Report(fmt.Errorf("%s connection closed", m.config().Modem.Type), SeverityFatal)
+				return
+			}
+
+			m.setStatus("Modem connection lost, reconnecting")
+			kind, err := m.modem.Reconnect()
+			if err != nil {
+				m.errors.Report(fmt.Errorf("modem connection lost: %w", err), SeverityFatal)
+				return
+			}
+			if err := m.startMessageReception(); err != nil {
+				m.errors.Report(fmt.Errorf("failed to restart message reception: %w", err), SeverityFatal)
+				return
+			}
+
+			m.setStatus(StatusRunning)
+			soft, hard := m.modem.Recoveries()
+			m.notify(NotificationTypeInfo, "Modem",
+				fmt.Sprintf("🔌 Modem connection restored (%s recovery, %d soft and %d hard so far)", kind, soft, hard))
+		}
+	}()
+}
+
+// SendSMS sends an SMS message through the configured transport
 func (m *Machine) SendSMS(number, message string) error {
-	return m.modem.SendSMS(number, message)
+	err := m.modem.SendSMS(number, message)
+	event := WebhookEvent{Event: config.WebhookSMSSent, Number: number, Message: message, Status: "sent"}
+	if err != nil {
+		event.Status, event.Error = "failed", err.Error()
+	} else {
+		m.recordMessage(storage.DirectionOut, number, message)
+	}
+	m.webhooks.Emit(event)
+	return err
 }
 
-// StartCall initiates a call through the modem
-func (m *Machine) StartCall(number string) error {
-	return m.modem.StartCall(number)
+// StartCall initiates a call through the modem, hung up once answered for
+// maxDuration, or call.max_duration if 0
+func (m *Machine) StartCall(number string, maxDuration time.Duration) error {
+	if err := m.modem.StartCall(number, maxDuration); err != nil {
+		return err
+	}
+	m.recordCall(storage.DirectionOut, number, callStatusDialed)
+	m.publishCall(MQTTCallDialing, number, "")
+	return nil
 }
 
 // HangUpCall hangs up the current call
 func (m *Machine) HangUpCall() error {
-	return m.modem.HangUpCall()
+	if err := m.modem.HangUpCall(); err != nil {
+		return err
+	}
+	m.publishCall(MQTTCallHangup, "", "")
+	return nil
 }
 
-// Error returns the error channel for monitoring errors
+// Error returns the channel fatal errors are delivered on
 func (m *Machine) Error() <-chan error {
-	return m.errorChan
+	return m.errors.Fatal()
+}
+
+// RecentErrors returns the most recent errors, fatal or not, oldest first
+func (m *Machine) RecentErrors() []ReportedError {
+	return m.errors.Recent()
 }
 
 // startMessageReception begins listening for incoming SMS messages
@@ -165,37 +359,87 @@ func (m *Machine) startMessageReception() error {
 			m.logger.Info("Received SMS",
 				slog.String("from", msg.Number),
 				slog.String("message", msg.Message))
+			m.lastSMS.Store(time.Now().UnixNano())
+			m.recordMessage(storage.DirectionIn, msg.Number, msg.Message)
+			if !senderAllowed(m.config().Security.SMSSenderAllowlist, msg.Number) {
+				m.logger.Info("Dropped SMS from a sender not in security.sms_sender_allowlist", slog.String("from", msg.Number))
+				return
+			}
+			if m.mqtt != nil {
+				m.mqtt.PublishSMS(msg.Number, msg.Message)
+			}
+			m.webhooks.Emit(WebhookEvent{Event: config.WebhookSMSReceived, Number: msg.Number, Message: msg.Message})
 
-			if err := m.discord.SendEmbed(NotificationTypeSMS, msg.Number, msg.Message); err != nil {
-				m.logger.Error("Failed to forward SMS to Discord",
+			// The PDUs are what a decoding bug report needs
+			pdus := rawPDUs(msg)
+			if m.config().Debug.IncludeRawPDU {
+				m.logger.Debug("Raw SMS PDU",
 					slog.String("from", msg.Number),
-					slog.Any("error", err))
+					slog.Any("pdus", pdus))
+			}
+
+			for _, n := range m.notifiers {
+				if err := n.SendSMSEmbed(msg.Number, msg.Message, pdus); err != nil {
+					m.logger.Error("Failed to forward SMS",
+						slog.String("notifier", n.Name()),
+						slog.String("from", msg.Number),
+						slog.Any("error", err))
+				}
 			}
 		},
 		func(err error) {
-			m.logger.Error("SMS reception error", slog.Any("error", err))
-			select {
-			case m.errorChan <- err:
-			default:
-			}
+			m.logger.Warn("SMS reception error", slog.Any("error", err))
+			m.errors.Report(err, SeverityTransient)
 		})
 }
 
-// sendDiscordEmbed sends a formatted embed to Discord
-func (m *Machine) sendDiscordEmbed(notificationType NotificationType, from, message string) {
-	if err := m.discord.SendEmbed(notificationType, from, message); err != nil {
-		m.logger.Error("Failed to send Discord embed",
-			slog.String("type", string(notificationType)),
-			slog.String("from", from),
-			slog.Any("error", err))
+// notify posts a notification to Discord and the other notifiers
+func (m *Machine) notify(notificationType NotificationType, from, message string) {
+	for _, n := range m.notifiers {
+		if err := n.SendEmbed(notificationType, from, message); err != nil {
+			m.logger.Error("Failed to send notification",
+				slog.String("notifier", n.Name()),
+				slog.String("type", string(notificationType)),
+				slog.String("from", from),
+				slog.Any("error", err))
+		}
 	}
 }
 
-// sendCallNotification sends a call notification to Discord
-func (m *Machine) sendCallNotification(from, message string) {
-	if err := m.discord.SendEmbed(NotificationTypeCall, from, message); err != nil {
-		m.logger.Error("Failed to send call notification to Discord",
-			slog.String("from", from),
-			slog.Any("error", err))
+// audit reports a command of the API, MQTT or Telegram to the audit channel
+// of Discord, when Discord is used
+func (m *Machine) audit(action, outcome string) {
+	if m.discord != nil {
+		m.discord.AuditAPI(action, outcome)
 	}
 }
+
+// webhookCallEvents are the webhook events of the MQTTCall events
+var webhookCallEvents = map[string]string{
+	MQTTCallIncoming: config.WebhookCallIncoming,
+	MQTTCallDialing:  config.WebhookCallDialing,
+	MQTTCallHangup:   config.WebhookCallHangup,
+	MQTTCallNotice:   config.WebhookCallNotice,
+}
+
+// publishCall tells MQTT and the webhooks about a call event, one of the
+// MQTTCall constants
+func (m *Machine) publishCall(event, number, message string) {
+	if m.mqtt != nil {
+		m.mqtt.PublishCall(event, number, message)
+	}
+	m.webhooks.Emit(WebhookEvent{Event: webhookCallEvents[event], Number: number, Message: message})
+}
+
+// sendCallNotification sends a call event, one of the MQTTCall constants,
+// to MQTT, the webhooks and the notifiers, which show message
+func (m *Machine) sendCallNotification(event, from, message string) {
+	m.publishCall(event, from, message)
+	m.notify(NotificationTypeCall, from, message)
+}
+
+// sendSIMNotification tells the notifiers about something the SIM did on
+// its own
+func (m *Machine) sendSIMNotification(message string) {
+	m.notify(NotificationTypeInfo, "SIM", message)
+}
@@ -0,0 +1,71 @@
+package machine
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// normalizeNumber strips the formatting of a phone number so numbers written
+// differently compare equal, e.g. "+33 6 12-34-56-78" and "0033612345678"
+func normalizeNumber(number string) string {
+	normalized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == '+':
+			return r
+		default:
+			return -1
+		}
+	}, number)
+
+	if strings.HasPrefix(normalized, "00") {
+		normalized = "+" + normalized[2:]
+	}
+	return normalized
+}
+
+// parseMention parses a Discord.Mentions value, a user ID or "role:<id>"
+func parseMention(value string) (string, discord.AllowedMentions, error) {
+	value = strings.TrimSpace(value)
+
+	if rawID, ok := strings.CutPrefix(value, "role:"); ok {
+		id, err := snowflake.Parse(rawID)
+		if err != nil {
+			return "", discord.AllowedMentions{}, fmt.Errorf("invalid role ID %q: %w", rawID, err)
+		}
+		return discord.RoleMention(id), discord.AllowedMentions{Roles: []snowflake.ID{id}}, nil
+	}
+
+	id, err := snowflake.Parse(value)
+	if err != nil {
+		return "", discord.AllowedMentions{}, fmt.Errorf("invalid user ID %q: %w", value, err)
+	}
+	return discord.UserMention(id), discord.AllowedMentions{Users: []snowflake.ID{id}}, nil
+}
+
+// mentionFor returns the mention configured for a phone number, if any
+func (d *DiscordManager) mentionFor(number string) (string, discord.AllowedMentions, bool) {
+	number = normalizeNumber(number)
+	if number == "" {
+		return "", discord.AllowedMentions{}, false
+	}
+
+	for configured, value := range d.config().Discord.Mentions {
+		if normalizeNumber(configured) != number {
+			continue
+		}
+
+		mention, allowed, err := parseMention(value)
+		if err != nil {
+			d.logger.Warn("Ignoring invalid discord.mentions entry",
+				slog.String("number", configured),
+				slog.Any("error", err))
+			return "", discord.AllowedMentions{}, false
+		}
+		return mention, allowed, true
+	}
+	return "", discord.AllowedMentions{}, false
+}
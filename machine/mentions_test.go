@@ -0,0 +1,44 @@
+package machine
+
+import (
+	"testing"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+func TestNormalizeNumber(t *testing.T) {
+	tests := map[string]string{
+		"+33612345678":      "+33612345678",
+		"+33 6 12-34-56-78": "+33612345678",
+		"0033612345678":     "+33612345678",
+		"(555) 123.4567":    "5551234567",
+		"":                  "",
+	}
+	for in, want := range tests {
+		if got := normalizeNumber(in); got != want {
+			t.Errorf("normalizeNumber(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseMention(t *testing.T) {
+	mention, allowed, err := parseMention("123456789012345678")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mention != "<@123456789012345678>" || len(allowed.Users) != 1 || allowed.Users[0] != snowflake.ID(123456789012345678) || len(allowed.Roles) != 0 {
+		t.Errorf("user mention = %q, %+v", mention, allowed)
+	}
+
+	mention, allowed, err = parseMention("role:123456789012345678")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mention != "<@&123456789012345678>" || len(allowed.Roles) != 1 || len(allowed.Users) != 0 {
+		t.Errorf("role mention = %q, %+v", mention, allowed)
+	}
+
+	if _, _, err := parseMention("role:abc"); err == nil {
+		t.Error("expected an error for an invalid role ID")
+	}
+}
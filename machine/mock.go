@@ -0,0 +1,259 @@
+package machine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golte/config"
+
+	"github.com/warthog618/modem/gsm"
+)
+
+// Bounds of the simulated signal, as reported by AT+CSQ
+const (
+	mockMinRSSI = 2
+	mockMaxRSSI = 31
+)
+
+// MockModem simulates the modem for --dry-run and modem.type mock. Sent SMS
+// are logged, and SMS and calls are injected over HTTP, see Handler.
+type MockModem struct {
+	config     func() *config.Config
+	logger     *slog.Logger
+	callNotify func(event, from, message string)
+
+	onIncomingCall func(from string) // see ModemManager.OnIncomingCall
+
+	mu        sync.Mutex
+	onMessage func(gsm.Message)
+	nextRef   int
+	rssi      int
+	call      string // number of the simulated call, empty if none
+	server    *http.Server
+}
+
+var _ Transport = (*MockModem)(nil)
+
+// NewMockModem creates a simulated modem reading its settings from cfg.
+// callNotify is told about injected incoming calls.
+func NewMockModem(cfg func() *config.Config, callNotify func(event, from, message string)) *MockModem {
+	return &MockModem{
+		config:     cfg,
+		logger:     slog.With("component", "mock-modem"),
+		callNotify: callNotify,
+		rssi:       20,
+	}
+}
+
+// Serve starts the injection endpoint on modem.mock.listen, if set
+func (m *MockModem) Serve() error {
+	addr := m.config().Modem.Mock.Listen
+	if addr == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for injected SMS and calls: %w", err)
+	}
+	server := &http.Server{Handler: m.Handler(), ReadHeaderTimeout: 5 * time.Second}
+
+	m.mu.Lock()
+	m.server = server
+	m.mu.Unlock()
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			m.logger.Error("Injection endpoint stopped", slog.Any("error", err))
+		}
+	}()
+	m.logger.Info("Simulated modem ready, inject SMS and calls with golte mock", slog.String("listen", listener.Addr().String()))
+	return nil
+}
+
+// Handler serves POST /sms {"from", "message"}, POST /call {"from"} and
+// POST /hangup
+func (m *MockModem) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /sms", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			From    string `json:"from"`
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.From == "" {
+			http.Error(w, `expected {"from": "...", "message": "..."}`, http.StatusBadRequest)
+			return
+		}
+		if err := m.InjectSMS(body.From, body.Message); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /call", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			From string `json:"from"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.From == "" {
+			http.Error(w, `expected {"from": "..."}`, http.StatusBadRequest)
+			return
+		}
+		if err := m.InjectCall(body.From); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /hangup", func(w http.ResponseWriter, r *http.Request) {
+		if err := m.HangUp(); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// InjectSMS delivers an SMS as if the modem received it
+func (m *MockModem) InjectSMS(from, message string) error {
+	m.mu.Lock()
+	onMessage := m.onMessage
+	m.mu.Unlock()
+
+	if onMessage == nil {
+		return errors.New("SMS reception is not started")
+	}
+	m.logger.Info("Injected SMS", slog.String("from", from))
+	onMessage(gsm.Message{Number: from, Message: message})
+	return nil
+}
+
+// InjectCall rings as if from called, the call is answered right away like
+// the bridge does
+func (m *MockModem) InjectCall(from string) error {
+	m.mu.Lock()
+	if m.call != "" {
+		m.mu.Unlock()
+		return fmt.Errorf("already in a call with %s", m.call)
+	}
+	m.call = from
+	m.mu.Unlock()
+
+	m.logger.Info("Injected incoming call", slog.String("from", from))
+	if m.onIncomingCall != nil {
+		m.onIncomingCall(from)
+	}
+	if m.callNotify != nil {
+		m.callNotify(MQTTCallIncoming, from, "📞 Incoming voice call")
+	}
+	return nil
+}
+
+// Dial starts a simulated outgoing call
+func (m *MockModem) Dial(number string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.call != "" {
+		return fmt.Errorf("already in a call with %s", m.call)
+	}
+	m.call = number
+	m.logger.Info("Simulated call started", slog.String("number", number))
+	return nil
+}
+
+// HangUp ends the simulated call
+func (m *MockModem) HangUp() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.call == "" {
+		return errors.New("no call in progress")
+	}
+	m.logger.Info("Simulated call ended", slog.String("number", m.call))
+	m.call = ""
+	return nil
+}
+
+// SendDTMF logs DTMF tones sent in the simulated call
+func (m *MockModem) SendDTMF(digits string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.call == "" {
+		return errors.New("no call in progress")
+	}
+	m.logger.Info("Simulated DTMF sent", slog.String("number", m.call), slog.String("digits", digits))
+	return nil
+}
+
+func (m *MockModem) SendShortMessage(number, message string) (string, error) {
+	refs := m.send(number, message, 1)
+	return refs[0], nil
+}
+
+func (m *MockModem) SendLongMessage(number, message string) ([]string, error) {
+	return m.send(number, message, smsSegments(message, false)), nil
+}
+
+// send logs an SMS after modem.mock.send_delay and returns a message
+// reference per segment
+func (m *MockModem) send(number, message string, segments int) []string {
+	time.Sleep(m.config().Modem.Mock.SendDelay)
+
+	m.mu.Lock()
+	refs := make([]string, segments)
+	for i := range refs {
+		m.nextRef = (m.nextRef + 1) % 256
+		refs[i] = strconv.Itoa(m.nextRef)
+	}
+	m.mu.Unlock()
+
+	m.logger.Info("Simulated SMS sent",
+		slog.String("number", number),
+		slog.String("message", message),
+		slog.Int("segments", segments))
+	return refs
+}
+
+func (m *MockModem) StartMessageRx(onMessage func(gsm.Message), _ func(error)) error {
+	m.mu.Lock()
+	m.onMessage = onMessage
+	m.mu.Unlock()
+	return nil
+}
+
+// StopMessageRx stops delivering injected SMS and closes the injection
+// endpoint
+func (m *MockModem) StopMessageRx() {
+	m.mu.Lock()
+	m.onMessage = nil
+	server := m.server
+	m.server = nil
+	m.mu.Unlock()
+
+	if server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}
+}
+
+// SignalQuality returns a signal wandering a step at a time, like a modem
+// on a windowsill
+func (m *MockModem) SignalQuality() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rssi = min(mockMaxRSSI, max(mockMinRSSI, m.rssi+rand.IntN(5)-2))
+	return []string{fmt.Sprintf("+CSQ: %d,99", m.rssi)}, nil
+}
@@ -0,0 +1,97 @@
+package machine
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golte/config"
+
+	"github.com/warthog618/modem/gsm"
+)
+
+func newTestMock(t *testing.T) (*MockModem, *[]string) {
+	t.Helper()
+	cfg := &config.Config{Modem: config.ModemConfig{Type: config.ModemTypeMock}}
+	var calls []string
+	m := NewMockModem(func() *config.Config { return cfg }, func(_, from, _ string) {
+		calls = append(calls, from)
+	})
+	return m, &calls
+}
+
+func TestMockModemSends(t *testing.T) {
+	m, _ := newTestMock(t)
+
+	ref, err := m.SendShortMessage("+33612345678", "hi")
+	if err != nil || ref != "1" {
+		t.Errorf("SendShortMessage() = %q, %v", ref, err)
+	}
+	refs, err := m.SendLongMessage("+33612345678", strings.Repeat("a", 200))
+	if err != nil || len(refs) != 2 || refs[0] != "2" {
+		t.Errorf("SendLongMessage() = %q, %v", refs, err)
+	}
+}
+
+func TestMockModemInjects(t *testing.T) {
+	m, calls := newTestMock(t)
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	post := func(path, body string) int {
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("/sms", `{"from": "+33612345678", "message": "hi"}`); code != http.StatusConflict {
+		t.Errorf("SMS before reception started: status %d, want 409", code)
+	}
+
+	var received []gsm.Message
+	m.StartMessageRx(func(msg gsm.Message) { received = append(received, msg) }, nil)
+	if code := post("/sms", `{"from": "+33612345678", "message": "hi"}`); code != http.StatusNoContent {
+		t.Errorf("POST /sms: status %d", code)
+	}
+	if len(received) != 1 || received[0].Number != "+33612345678" || received[0].Message != "hi" {
+		t.Errorf("received %+v", received)
+	}
+	if code := post("/sms", `{"message": "no sender"}`); code != http.StatusBadRequest {
+		t.Errorf("SMS without sender: status %d, want 400", code)
+	}
+
+	if code := post("/call", `{"from": "+33687654321"}`); code != http.StatusNoContent {
+		t.Errorf("POST /call: status %d", code)
+	}
+	if len(*calls) != 1 || (*calls)[0] != "+33687654321" {
+		t.Errorf("call notifications = %q", *calls)
+	}
+	if code := post("/call", `{"from": "+33687654321"}`); code != http.StatusConflict {
+		t.Errorf("second call: status %d, want 409", code)
+	}
+	if code := post("/hangup", ``); code != http.StatusNoContent {
+		t.Errorf("POST /hangup: status %d", code)
+	}
+}
+
+func TestMockModemSignalWanders(t *testing.T) {
+	m, _ := newTestMock(t)
+
+	seen := make(map[string]bool)
+	for range 1000 {
+		lines, _ := m.SignalQuality()
+		var rssi int
+		if _, err := fmt.Sscanf(lines[0], "+CSQ: %d,99", &rssi); err != nil || rssi < mockMinRSSI || rssi > mockMaxRSSI || signalLost(lines) {
+			t.Fatalf("SignalQuality() = %q", lines)
+		}
+		seen[lines[0]] = true
+	}
+	if len(seen) < 2 {
+		t.Error("the signal should wander")
+	}
+}
@@ -1,146 +1,534 @@
 package machine
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"golte/atresp"
 	"golte/call"
 	"golte/config"
 	"golte/playback"
+	"golte/stk"
 
 	"github.com/warthog618/modem/at"
 	"github.com/warthog618/modem/gsm"
 	"github.com/warthog618/modem/serial"
 )
 
+// ErrNoModem is returned by modem operations when SMS go through another
+// transport and no GSM modem was initialized
+var ErrNoModem = errors.New("no GSM modem is configured")
+
+// ErrVoiceDisabled is returned by audio operations when voice.enabled is off
+var ErrVoiceDisabled = errors.New("voice is disabled")
+
 // ModemManager handles all GSM modem operations
 type ModemManager struct {
-	config             *config.Config
+	cfg                atomic.Pointer[config.Config]
+	connMu             sync.RWMutex // guards the session fields, replaced by Reconnect
+	port               io.Closer
 	gsm                *gsm.GSM
+	sms                Transport
+	mock               *MockModem     // replaces the serial modem when modem.type is mock
+	smpp               *SMPPTransport // carries the SMS when modem.type is smpp
 	call               *call.Call
 	playback           *playback.Playback
 	logger             *slog.Logger
-	callNotifyCallback func(from, message string)
+	callNotifyCallback func(event, from, message string)
+	simNotifyCallback  func(message string)
+	incomingCallback   func(number string) // told of every incoming call as it rings
+	panicFunc          func(err error)     // told of panics recovered in the call handlers
+	stk                *stk.STK
+
+	callWatchMu     sync.Mutex
+	cancelCallWatch context.CancelFunc
+	announcing      atomic.Bool // an announce route is playing, keypresses are ignored
+
+	clockOffset  atomic.Int64 // modem clock minus host clock, in nanoseconds
+	detectedBaud atomic.Int64 // serial rate found when modem.baud is auto
+
+	softRecoveries atomic.Int64
+	hardRecoveries atomic.Int64
+	linkWatched    atomic.Bool   // a watcher reconnects when Closed fires
+	recoveredCh    chan struct{} // closed by the next Reconnect, guarded by connMu
+
+	phonebookMu sync.RWMutex
+	phonebook   map[string]string // normalized number to SIM contact name
+
+	caps atomic.Pointer[Capabilities] // probed when the modem is initialized
+
+	signalReporting atomic.Bool   // the modem of the session reports signal changes
+	signalReports   chan struct{} // told of each report, see SignalReports
+	signals         signalHistory // samples of the monitor, for /status
+
+	latency *latencyRecorder // times the AT commands of every session
+	tracer  *atTracer        // writes them to modem.trace.path while started
 
 	state *ModemState
+	pins  pinAttempts // wrong PINs in a row, see security.max_pin_attempts
 }
 
+// ModemState holds the IVR state of the current call. It is shared between
+// the CLIP and DTMF indication handlers and the inter-digit timer, so all
+// access goes through its methods.
 type ModemState struct {
+	mu       sync.Mutex
 	password string
+	seq      uint64      // bumped by every change, a timer only fires for the input it was armed for
+	timer    *time.Timer // inter-digit timeout, nil if not armed
 }
 
 func NewState() *ModemState {
 	return &ModemState{}
 }
 
-// NewModemManager creates a new ModemManager instance
-func NewModemManager(cfg *config.Config, playback *playback.Playback, callNotifyCallback func(from, message string)) *ModemManager {
-	return &ModemManager{
-		config:             cfg,
+// Reset clears the accumulated password and disarms its timeout
+func (s *ModemState) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resetLocked()
+}
+
+func (s *ModemState) resetLocked() {
+	s.password = ""
+	s.seq++
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// AddDigit appends a DTMF digit to the password and returns the result
+func (s *ModemState) AddDigit(digit string) string {
+	return s.AddDigitWithTimeout(digit, 0, nil)
+}
+
+// AddDigitWithTimeout appends a DTMF digit to the password and returns the
+// result. If no other digit comes within timeout, and the state isn't reset
+// meanwhile, the password is cleared and passed to onTimeout. A timeout of 0
+// waits forever.
+func (s *ModemState) AddDigitWithTimeout(digit string, timeout time.Duration, onTimeout func(input string)) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.password += digit
+	s.seq++
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if timeout > 0 && onTimeout != nil {
+		seq := s.seq
+		s.timer = time.AfterFunc(timeout, func() { s.expire(seq, onTimeout) })
+	}
+	return s.password
+}
+
+// expire clears the password for the timer armed at seq, unless a digit or
+// a reset came since
+func (s *ModemState) expire(seq uint64, onTimeout func(input string)) {
+	s.mu.Lock()
+	if seq != s.seq {
+		s.mu.Unlock()
+		return
+	}
+	input := s.password
+	s.resetLocked()
+	s.mu.Unlock()
+
+	onTimeout(input)
+}
+
+// Password returns the accumulated password
+func (s *ModemState) Password() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.password
+}
+
+// NewModemManager creates a new ModemManager instance. SMS go through sms,
+// or when it's nil the transport of modem.type: the modem's own AT
+// commands, an SMSC account bound over SMPP, or with mock a MockModem
+// standing in for the modem, nothing is opened. callNotifyCallback is told
+// about calls with their MQTTCall event and the text to show.
+func NewModemManager(cfg *config.Config, playback *playback.Playback, sms Transport, callNotifyCallback func(event, from, message string), simNotifyCallback func(message string)) *ModemManager {
+	m := &ModemManager{
 		logger:             slog.With("component", "modem"),
+		sms:                sms,
 		callNotifyCallback: callNotifyCallback,
+		simNotifyCallback:  simNotifyCallback,
 		playback:           playback,
 		state:              NewState(),
+		latency:            newLatencyRecorder(),
+		tracer:             newATTracer(),
+		signalReports:      make(chan struct{}, 1),
+	}
+	m.cfg.Store(cfg)
+
+	if sms == nil {
+		switch cfg.Modem.Type {
+		case config.ModemTypeMock:
+			m.mock = NewMockModem(m.config, callNotifyCallback)
+			m.sms = m.mock
+		case config.ModemTypeSMPP:
+			// No radio, calls report ErrNoModem
+			m.smpp = NewSMPPTransport(cfg)
+			m.sms = m.smpp
+		}
+	}
+	return m
+}
+
+// OnIncomingCall makes the modem tell f about every incoming call as it
+// rings, before it's answered. Set it before Initialize.
+func (m *ModemManager) OnIncomingCall(f func(number string)) {
+	m.incomingCallback = f
+	if m.mock != nil {
+		m.mock.onIncomingCall = f
 	}
 }
 
-// Initialize sets up the GSM modem connection
+// config returns the active configuration
+func (m *ModemManager) config() *config.Config {
+	return m.cfg.Load()
+}
+
+// Reconfigure switches to cfg, the serial port settings keep their values
+// until the modem is reopened
+func (m *ModemManager) Reconfigure(cfg *config.Config) {
+	previous := m.cfg.Swap(cfg)
+	if previous.Modem.Trace != cfg.Modem.Trace && m.mock == nil {
+		m.applyTrace()
+	}
+}
+
+// applyTrace starts or stops the AT trace as modem.trace says. A trace file
+// that can't be opened is only warned about, the modem works without it.
+func (m *ModemManager) applyTrace() {
+	cfg := m.config().Modem.Trace
+	if !cfg.Enabled {
+		m.tracer.Stop()
+		return
+	}
+	if err := m.tracer.Start(cfg); err != nil {
+		m.logger.Warn("Failed to trace the modem", slog.Any("error", err))
+	}
+}
+
+// Initialize sets up the GSM modem connection, or the SMPP bind
 func (m *ModemManager) Initialize() error {
+	if m.mock != nil {
+		m.logger.Warn("Using a simulated modem, nothing is sent to the network")
+		return m.mock.Serve()
+	}
+	if m.smpp != nil {
+		return m.smpp.Initialize()
+	}
+
+	baud := strconv.Itoa(m.config().Modem.Baud)
+	if m.config().Modem.Baud == config.BaudAuto {
+		baud = "auto"
+	}
 	m.logger.Info("Initializing modem",
-		slog.String("device", m.config.Modem.Device),
-		slog.Int("baud", m.config.Modem.Baud))
+		slog.String("device", m.config().Modem.Device),
+		slog.String("baud", baud))
+	if m.sms == nil {
+		m.sms = gsmTransport{modem: m}
+	}
+	if m.config().Modem.Trace.Enabled {
+		m.applyTrace()
+	}
 
+	a, err := m.open()
+	if err != nil {
+		return err
+	}
+	if err := m.attach(a, true); err != nil {
+		m.closePort()
+		return err
+	}
+
+	m.logger.Info("Modem initialized successfully")
+	return nil
+}
+
+// open opens the serial port and returns an AT session on it
+func (m *ModemManager) open() (*at.AT, error) {
+	baud, err := m.baud()
+	if err != nil {
+		return nil, err
+	}
 	serialModem, err := serial.New(
-		serial.WithPort(m.config.Modem.Device),
-		serial.WithBaud(m.config.Modem.Baud),
+		serial.WithPort(m.config().Modem.Device),
+		serial.WithBaud(baud),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create serial connection: %w", err)
+		return nil, fmt.Errorf("failed to create serial connection: %w", err)
 	}
 
-	var mio io.ReadWriter = serialModem
+	m.connMu.Lock()
+	m.port = serialModem
+	m.connMu.Unlock()
 
-	at := at.New(mio,
-		at.WithTimeout(m.config.Modem.Timeout),
-		at.WithCmds("I"))
+	var mio io.ReadWriter = serialModem
+	mio = m.latency.Wrap(mio)
+	mio = m.tracer.Wrap(mio)
+	return at.New(mio,
+		at.WithTimeout(m.config().Modem.Timeout),
+		at.WithCmds("I")), nil
+}
 
-	m.gsm = gsm.New(at)
-	m.call = call.New(at)
+// closePort closes the serial port, if open
+func (m *ModemManager) closePort() {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
 
-	if err := m.gsm.Init(); err != nil {
-		serialModem.Close()
-		return fmt.Errorf("failed to initialize modem: %w", err)
+	if m.port != nil {
+		m.port.Close()
+		m.port = nil
 	}
+}
 
-	if err := m.call.Init(); err != nil {
-		serialModem.Close()
-		return fmt.Errorf("failed to initialize call manager: %w", err)
+// attach switches the modem to the AT session a, registering the call, DTMF
+// and SIM toolkit handlers on it. full runs the modem initialization too,
+// otherwise the modem is expected to have kept its configuration.
+func (m *ModemManager) attach(a *at.AT, full bool) error {
+	// Features the modem lacks are left off rather than failing the
+	// initialization
+	caps := m.capabilities()
+	if full {
+		caps = m.probeCapabilities(a.Command)
 	}
 
-	m.call.StartListening(func(call string) {
-		message := fmt.Sprintf("📞 Incoming voice call")
-		m.callNotifyCallback(call, message)
-		m.call.PickUp()
-		m.state = NewState()
+	var options []gsm.Option
+	if !caps.PDU {
+		m.logger.Warn("The modem has no PDU mode, SMS longer than 160 characters can't be sent")
+		options = append(options, gsm.WithTextMode)
+	}
+	g := gsm.New(a, options...)
+	c := call.New(a)
 
-		time.Sleep(1 * time.Second) // Wait for call to connect
-		m.playback.AddPredecoded("audio/bonjour_veuillez_entrez_votre_mot_de_passe.mp3")
-	})
+	if full {
+		if err := g.Init(); err != nil {
+			return fmt.Errorf("failed to initialize modem: %w", err)
+		}
+		if err := c.Init(); err != nil {
+			return fmt.Errorf("failed to initialize call manager: %w", err)
+		}
+	}
 
-	m.call.SetDTMFHandler(func(digit string) {
-		m.logger.Info("DTMF digit received", slog.String("digit", digit))
+	if caps.Voice {
+		err := c.StartListening(func(number string) {
+			defer recoverPanic(m.logger, "incoming call", m.abortCall, m.panicFunc)
+
+			if m.incomingCallback != nil {
+				m.incomingCallback(number)
+			}
+			m.routeIncomingCall(c, number)
+		})
+		if err != nil {
+			m.logger.Warn("Failed to listen for incoming calls, continuing with SMS only", slog.Any("error", err))
+			caps.Voice = false
+		}
+	} else {
+		m.logger.Warn("The modem doesn't support voice calls, incoming calls won't be answered")
+	}
 
-		if digit == "#" {
-			m.state = NewState()
-			return
+	if caps.DTMF {
+		c.SetDTMFHandler(func(digit string) {
+			defer recoverPanic(m.logger, "DTMF handler", m.abortCall, m.panicFunc)
+
+			// The digits are the PIN, keep them out of the logs
+			m.logger.Debug("DTMF digit received")
+			if m.announcing.Load() {
+				return
+			}
+			m.dtmfDigit(digit)
+		})
+		if err := c.EnableDTMFDetection(); err != nil {
+			m.logger.Warn("Failed to enable DTMF detection, only the voice route of call.routes answers incoming calls", slog.Any("error", err))
+			caps.DTMF = false
 		}
+	} else {
+		m.logger.Warn("The modem doesn't detect DTMF, only the voice route of call.routes answers incoming calls")
+	}
+	// What failed to set up counts as missing
+	m.caps.Store(&caps)
+
+	m.enableSignalReports(a)
+
+	// Unanswered SIM toolkit commands can block the SIM, dismiss them all
+	s := stk.New(a)
+	if err := s.Start(m.handleProactiveCommand); err != nil {
+		m.logger.Warn("Failed to register SIM toolkit handler", slog.Any("error", err))
+	}
 
-		m.playback.AddPredecoded("audio/" + digit + ".mp3")
-		m.state.password += digit
+	m.connMu.Lock()
+	m.gsm, m.call, m.stk = g, c, s
+	m.connMu.Unlock()
 
-		if m.state.password == "52226636" {
-			m.logger.Info("Password entered correctly")
-			m.playback.AddPredecoded("audio/mot_de_passe_correct.mp3")
+	// Contacts stored on the SIM name the senders of SMS
+	if full {
+		if _, err := m.ReadPhonebook(); err != nil {
+			m.logger.Warn("Failed to read SIM phonebook", slog.Any("error", err))
 		}
-	})
-	m.call.EnableDTMFDetection()
+	}
 
-	m.logger.Info("Modem initialized successfully")
+	if full && m.config().Modem.ClockSync != config.ClockSyncOff && m.config().Modem.ClockSync != "" {
+		if clock, err := m.SyncTime(); err != nil {
+			m.logger.Warn("Failed to sync time with the modem", slog.Any("error", err))
+		} else {
+			m.logger.Info("Read the modem clock", slog.Time("time", clock))
+		}
+	}
 	return nil
 }
 
+// handleProactiveCommand reports a SIM toolkit command once it's dismissed
+func (m *ModemManager) handleProactiveCommand(cmd stk.Command, err error) {
+	if err != nil {
+		m.logger.Error("Failed to dismiss SIM toolkit command",
+			slog.String("command", cmd.Name()),
+			slog.Any("error", err))
+	} else {
+		m.logger.Info("Dismissed SIM toolkit command",
+			slog.String("command", cmd.Name()),
+			slog.String("text", cmd.Text))
+	}
+
+	if m.simNotifyCallback == nil {
+		return
+	}
+	message := fmt.Sprintf("SIM requested %s", cmd.Name())
+	if cmd.Text != "" {
+		message += fmt.Sprintf(": %q", cmd.Text)
+	}
+	if err != nil {
+		message += fmt.Sprintf("\n⚠️ It could not be dismissed: %v", err)
+	}
+	m.simNotifyCallback(message)
+}
+
+// maxPromptDelay is how late a prompt may start before it's dropped, a
+// greeting heard seconds after picking up only confuses the caller
+const maxPromptDelay = 3 * time.Second
+
+// playPrompt queues an IVR prompt, unless what's already queued would delay
+// it by more than maxPromptDelay
+func (m *ModemManager) playPrompt(filePath string) error {
+	if m.playback == nil {
+		return ErrVoiceDisabled
+	}
+	handle, err := m.playback.EnqueuePredecoded(filePath)
+	if err != nil {
+		return err
+	}
+
+	if wait, ok := m.playback.EstimatedStart(handle); ok && wait > maxPromptDelay {
+		position, _ := m.playback.Position(handle)
+		m.playback.Dequeue(handle)
+		m.logger.Warn("Dropping stale prompt",
+			slog.String("prompt", filePath),
+			slog.Int("position", position),
+			slog.Duration("wait", wait))
+	}
+	return nil
+}
+
+// keypressDuration is how long the DTMF feedback tone of a keypress lasts
+const keypressDuration = 120 * time.Millisecond
+
+// keypressFeedback lets the caller hear that a digit was received
+func (m *ModemManager) keypressFeedback(digit string) {
+	if m.playback == nil {
+		return
+	}
+
+	var err error
+	switch m.config().Call.KeypressFeedback {
+	case "silent":
+		return
+	case "spoken":
+		err = m.playPrompt("audio/" + digit + ".mp3")
+	default:
+		err = m.playback.AddTone(digit, keypressDuration)
+	}
+	if err != nil {
+		m.logger.Warn("Failed to play keypress feedback", slog.Any("error", err))
+	}
+}
+
 // SendSMS sends an SMS message through the modem
 func (m *ModemManager) SendSMS(number, message string) error {
+	_, err := m.SubmitSMS(number, message)
+	return err
+}
+
+// SubmitSMS sends an SMS message through the modem and returns the message
+// references of its segments, which status reports refer to
+func (m *ModemManager) SubmitSMS(number, message string) ([]int, error) {
+	if m.config().Modem.TransliterateOutbound {
+		if folded, changed := transliterateGSM7(message); changed {
+			m.logger.Info("Transliterated SMS to GSM-7",
+				slog.String("number", number),
+				slog.String("message", message),
+				slog.String("transliterated", folded))
+			message = folded
+		}
+	}
+
+	segments := smsSegments(message, false)
 	m.logger.Info("Sending SMS",
 		slog.String("number", number),
-		slog.Int("length", len(message)))
+		slog.Int("length", len(message)),
+		slog.Int("segments", segments),
+		slog.Duration("timeout", smsTimeout(m.config().Modem)*time.Duration(segments)))
 
-	var err error
-	if len(message) > 160 {
-		// Long SMS, split into multiple messages
-		_, err = m.gsm.SendLongMessage(number, message, at.WithTimeout(5*time.Second))
-	} else {
-		_, err = m.gsm.SendShortMessage(number, message, at.WithTimeout(5*time.Second))
+	if m.sms == nil {
+		return nil, ErrNoModem
 	}
 
+	var refs []string
+	err := m.withRecovery("send SMS", func() error {
+		var err error
+		if len(message) > 160 {
+			// Long SMS, split into multiple messages
+			refs, err = m.sms.SendLongMessage(number, message)
+		} else {
+			var ref string
+			ref, err = m.sms.SendShortMessage(number, message)
+			refs = []string{ref}
+		}
+		return err
+	})
+
 	if err != nil {
 		m.logger.Error("Failed to send SMS",
 			slog.String("number", number),
 			slog.Any("error", err))
-		return err
+		return nil, err
 	}
 
 	m.logger.Info("SMS sent successfully", slog.String("number", number))
-	return nil
+	return parseMessageRefs(refs), nil
 }
 
 // StartMessageReception begins listening for incoming SMS messages
 func (m *ModemManager) StartMessageReception(onMessage func(gsm.Message), onError func(error)) error {
 	m.logger.Info("Starting SMS message reception")
+	if m.sms == nil {
+		return ErrNoModem
+	}
 
-	err := m.gsm.StartMessageRx(onMessage, onError)
+	err := m.sms.StartMessageRx(onMessage, onError)
 	if err != nil {
 		return fmt.Errorf("failed to start message reception: %w", err)
 	}
@@ -151,22 +539,108 @@ func (m *ModemManager) StartMessageReception(onMessage func(gsm.Message), onErro
 
 // StopMessageReception stops SMS message reception
 func (m *ModemManager) StopMessageReception() {
-	if m.gsm != nil {
-		m.gsm.StopMessageRx()
+	if m.sms != nil {
+		m.sms.StopMessageRx()
+	}
+}
+
+// MessageCount returns the number of SMS stored in the modem's read storage
+// and its capacity, using AT+CPMS?
+func (m *ModemManager) MessageCount() (used, total int, err error) {
+	if m.GSM() == nil {
+		return 0, 0, ErrNoModem
+	}
+	response, err := m.GSM().Command("+CPMS?", at.WithTimeout(modemQueryTimeout(m.config().Modem)))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Format: +CPMS: <mem1>,<used1>,<total1>,<mem2>,<used2>,<total2>,...
+	fields, ok := atresp.Find(response, "+CPMS")
+	if !ok || len(fields) < 3 {
+		return 0, 0, fmt.Errorf("no storage status found in response")
+	}
+	used, ok = fields.Int(1)
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid CPMS used count %q", fields[1])
+	}
+	total, ok = fields.Int(2)
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid CPMS total count %q", fields[2])
+	}
+	return used, total, nil
+}
+
+// ClearAllMessages deletes every SMS from the modem's storage
+func (m *ModemManager) ClearAllMessages() error {
+	if m.GSM() == nil {
+		return ErrNoModem
 	}
+	m.logger.Info("Deleting all stored SMS")
+
+	// AT+CMGD=<index>,<delflag> with delflag 4 ignores the index and
+	// deletes all messages
+	if _, err := m.GSM().Command("+CMGD=1,4"); err != nil {
+		m.logger.Error("Failed to delete stored SMS", slog.Any("error", err))
+		return err
+	}
+
+	m.logger.Info("All stored SMS deleted")
+	return nil
 }
 
 // GetSignalQuality retrieves the current signal quality
 func (m *ModemManager) GetSignalQuality() (interface{}, error) {
-	return m.gsm.Command("+CSQ")
+	if m.sms == nil {
+		return nil, ErrNoModem
+	}
+	return m.sms.SignalQuality()
 }
 
-// StartCall initiates a call to the specified number
-func (m *ModemManager) StartCall(number string) error {
+// Reregister makes the modem register with the network again, either by
+// restarting automatic operator selection or by turning the radio off and on
+func (m *ModemManager) Reregister(method string) error {
+	if m.GSM() == nil {
+		return ErrNoModem
+	}
+	m.logger.Warn("Re-registering with the network", slog.String("method", method))
+
+	if method == config.ReregisterCFUN {
+		if _, err := m.GSM().Command("+CFUN=0"); err != nil {
+			return fmt.Errorf("failed to turn the radio off: %w", err)
+		}
+		if _, err := m.GSM().Command("+CFUN=1"); err != nil {
+			return fmt.Errorf("failed to turn the radio on: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := m.GSM().Command("+COPS=0"); err != nil {
+		return fmt.Errorf("failed to restart operator selection: %w", err)
+	}
+	return nil
+}
+
+// StartCall initiates a call to the specified number. It's hung up once
+// answered for maxDuration, or call.max_duration if 0. A negative
+// maxDuration doesn't limit the call.
+func (m *ModemManager) StartCall(number string, maxDuration time.Duration) error {
+	if m.mock != nil {
+		return m.mock.Dial(number)
+	}
+	if m.callManager() == nil {
+		return ErrNoModem
+	}
+	if !m.capabilities().Voice {
+		return notSupported("voice calls")
+	}
 	m.logger.Info("Starting call",
 		slog.String("number", number))
 
-	err := m.call.StartCall(number)
+	// The call manager is replaced when the link is recovered
+	err := m.withRecovery("dial", func() error {
+		return m.callManager().StartCall(number)
+	})
 	if err != nil {
 		m.logger.Error("Failed to start call",
 			slog.String("number", number),
@@ -174,15 +648,29 @@ func (m *ModemManager) StartCall(number string) error {
 		return err
 	}
 
+	if maxDuration == 0 {
+		maxDuration = m.config().Call.MaxDuration
+	}
+	m.watchCall(number, maxDuration)
+
 	m.logger.Info("Call initiated successfully", slog.String("number", number))
 	return nil
 }
 
 // HangUpCall hangs up the current call
 func (m *ModemManager) HangUpCall() error {
+	if m.mock != nil {
+		return m.mock.HangUp()
+	}
+	if m.callManager() == nil {
+		return ErrNoModem
+	}
 	m.logger.Info("Hanging up call")
+	m.stopCallWatch()
 
-	err := m.call.HangUp()
+	err := m.withRecovery("hang up", func() error {
+		return m.callManager().HangUp()
+	})
 	if err != nil {
 		m.logger.Error("Failed to hang up call",
 			slog.Any("error", err))
@@ -193,18 +681,70 @@ func (m *ModemManager) HangUpCall() error {
 	return nil
 }
 
-// Closed returns a channel that's closed when the modem connection is lost
+// maxDTMFDigits bounds the digits of a single SendDTMF
+const maxDTMFDigits = 32
+
+// SendDTMF plays DTMF tones in the current call, e.g. to drive a voice menu
+// at the other end. digits may hold 0-9, *, # and A-D.
+func (m *ModemManager) SendDTMF(digits string) error {
+	if err := validDTMF(digits); err != nil {
+		return err
+	}
+	if m.mock != nil {
+		return m.mock.SendDTMF(digits)
+	}
+	if m.callManager() == nil {
+		return ErrNoModem
+	}
+	if !m.capabilities().Voice {
+		return notSupported("voice calls")
+	}
+	m.logger.Info("Sending DTMF", slog.Int("digits", len(digits)))
+
+	err := m.withRecovery("send DTMF", func() error {
+		return m.callManager().SendDTMF(digits)
+	})
+	if err != nil {
+		m.logger.Error("Failed to send DTMF", slog.Any("error", err))
+	}
+	return err
+}
+
+// validDTMF checks digits holds only DTMF tones, and not too many
+func validDTMF(digits string) error {
+	if digits == "" || len(digits) > maxDTMFDigits {
+		return fmt.Errorf("expected 1 to %d DTMF digits", maxDTMFDigits)
+	}
+	for _, r := range digits {
+		if !strings.ContainsRune("0123456789*#ABCD", r) {
+			return fmt.Errorf("%q is not a DTMF digit, use 0-9, *, # or A-D", r)
+		}
+	}
+	return nil
+}
+
+// Closed returns a channel that's closed when the modem connection, or the
+// SMSC's, is lost. A new channel is returned once Reconnect restored the connection.
 func (m *ModemManager) Closed() <-chan struct{} {
-	if m.gsm != nil {
-		return m.gsm.Closed()
+	if m.smpp != nil {
+		return m.smpp.Closed()
 	}
-	if m.call != nil {
-		return m.call.Closed()
+	if g := m.GSM(); g != nil {
+		return g.Closed()
 	}
 	return nil
 }
 
 // GSM returns the underlying GSM instance
 func (m *ModemManager) GSM() *gsm.GSM {
+	m.connMu.RLock()
+	defer m.connMu.RUnlock()
 	return m.gsm
 }
+
+// callManager returns the call manager of the current modem session
+func (m *ModemManager) callManager() *call.Call {
+	m.connMu.RLock()
+	defer m.connMu.RUnlock()
+	return m.call
+}
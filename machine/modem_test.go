@@ -0,0 +1,160 @@
+package machine
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golte/config"
+
+	"github.com/warthog618/modem/gsm"
+)
+
+// Run with -race: the CLIP and DTMF handlers touch the state concurrently
+func TestModemStateConcurrentAccess(t *testing.T) {
+	state := NewState()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				state.AddDigit("1")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := state.Password(); got != strings.Repeat("1", 800) {
+		t.Fatalf("expected 800 digits, got %d", len(got))
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		state.Reset()
+	}()
+	go func() {
+		defer wg.Done()
+		state.AddDigit("2")
+	}()
+	wg.Wait()
+
+	if got := state.Password(); got != "" && got != "2" {
+		t.Fatalf("unexpected password after concurrent reset: %q", got)
+	}
+}
+
+// fakeSMS records what the ModemManager sends through it
+type fakeSMS struct {
+	short, long []string
+}
+
+func (f *fakeSMS) SendShortMessage(number, message string) (string, error) {
+	f.short = append(f.short, number+":"+message)
+	return "1", nil
+}
+
+func (f *fakeSMS) SendLongMessage(number, message string) ([]string, error) {
+	f.long = append(f.long, number+":"+message)
+	return []string{"2", "3"}, nil
+}
+
+func (f *fakeSMS) StartMessageRx(func(gsm.Message), func(error)) error { return nil }
+func (f *fakeSMS) StopMessageRx()                                      {}
+func (f *fakeSMS) SignalQuality() ([]string, error)                    { return []string{"+CSQ: 20,99"}, nil }
+
+func TestModemManagerUsesTransport(t *testing.T) {
+	sms := &fakeSMS{}
+	m := NewModemManager(&config.Config{}, nil, sms, nil, nil)
+
+	if err := m.SendSMS("+33612345678", "hello"); err != nil {
+		t.Fatalf("SendSMS() = %v", err)
+	}
+	refs, err := m.SubmitSMS("+33612345678", strings.Repeat("a", 161))
+	if err != nil {
+		t.Fatalf("SubmitSMS() = %v", err)
+	}
+	if len(refs) != 2 || refs[0] != 2 || refs[1] != 3 {
+		t.Errorf("SubmitSMS() refs = %v, want [2 3]", refs)
+	}
+	if len(sms.short) != 1 || len(sms.long) != 1 {
+		t.Fatalf("expected one short and one long message, got %d and %d", len(sms.short), len(sms.long))
+	}
+
+	quality, err := m.GetSignalQuality()
+	if err != nil || quality.([]string)[0] != "+CSQ: 20,99" {
+		t.Errorf("GetSignalQuality() = %v, %v", quality, err)
+	}
+
+	// Calls still need the modem
+	if err := m.StartCall("+33612345678", 0); err != ErrNoModem {
+		t.Errorf("StartCall() = %v, want ErrNoModem", err)
+	}
+}
+
+func TestModemManagerSMPP(t *testing.T) {
+	m := NewModemManager(&config.Config{Modem: config.ModemConfig{Type: config.ModemTypeSMPP}}, nil, nil, nil, nil)
+	if m.smpp == nil || m.sms != Transport(m.smpp) {
+		t.Fatal("modem.type smpp doesn't send through SMPP")
+	}
+
+	// Not bound yet
+	if err := m.SendSMS("+33612345678", "hello"); err == nil {
+		t.Error("SendSMS() before Initialize() succeeded")
+	}
+	if _, err := m.GetSignalQuality(); err != ErrNoModem {
+		t.Errorf("GetSignalQuality() = %v, want ErrNoModem", err)
+	}
+	if m.Closed() == nil {
+		t.Error("Closed() = nil")
+	}
+}
+
+func TestModemStateTimeout(t *testing.T) {
+	state := NewState()
+	timedOut := make(chan string, 1)
+	onTimeout := func(input string) { timedOut <- input }
+
+	state.AddDigitWithTimeout("1", 20*time.Millisecond, onTimeout)
+	state.AddDigitWithTimeout("2", 20*time.Millisecond, onTimeout)
+	select {
+	case input := <-timedOut:
+		if input != "12" {
+			t.Errorf("timed out with %q, want 12", input)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the entry didn't time out")
+	}
+	if got := state.Password(); got != "" {
+		t.Errorf("password after the timeout = %q, want it cleared", got)
+	}
+
+	// A reset, like a new call, disarms the timer
+	state.AddDigitWithTimeout("3", 20*time.Millisecond, onTimeout)
+	state.Reset()
+	state.AddDigit("4")
+	select {
+	case input := <-timedOut:
+		t.Errorf("timed out with %q after a reset", input)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if got := state.Password(); got != "4" {
+		t.Errorf("password = %q, want 4", got)
+	}
+}
+
+func TestValidDTMF(t *testing.T) {
+	for _, digits := range []string{"1", "0123456789*#ABCD"} {
+		if err := validDTMF(digits); err != nil {
+			t.Errorf("validDTMF(%q) = %v", digits, err)
+		}
+	}
+	for _, digits := range []string{"", "12x", "1a", strings.Repeat("1", maxDTMFDigits+1)} {
+		if err := validDTMF(digits); err == nil {
+			t.Errorf("validDTMF(%q) accepted", digits)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golte/atresp"
+
+	"github.com/warthog618/modem/at"
+	"github.com/warthog618/modem/info"
+)
+
+// InfoItem is one answer of the modem to the Info queries
+type InfoItem struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// infoQueries are the AT commands Info runs, with how to present the answer
+var infoQueries = []struct {
+	name   string
+	cmd    string
+	format func(string) string
+}{
+	{"Manufacturer", "+CGMI", nil},
+	{"Model", "+CGMM", nil},
+	{"Firmware", "+CGMR", nil},
+	{"IMEI", "+CGSN", nil},
+	{"ICCID", "+CCID", nil},
+	{"IMSI", "+CIMI", nil},
+	{"SMSC", "+CSCA?", formatSMSC},
+	{"Registration", "+CREG?", formatRegistration},
+	{"Operator", "+COPS?", formatOperator},
+	{"Signal", "+CSQ", formatSignal},
+}
+
+// firmwareItems are the Info items identifying the modem hardware
+var firmwareItems = []string{"Manufacturer", "Model", "Firmware"}
+
+// Info queries the modem identity, SIM and network state. Each query is
+// bounded by timeout, failed queries are reported in their item.
+func (m *ModemManager) Info(timeout time.Duration) ([]InfoItem, error) {
+	return m.query(nil, timeout)
+}
+
+// Firmware queries only the manufacturer, model and firmware revision, see
+// Info
+func (m *ModemManager) Firmware(timeout time.Duration) ([]InfoItem, error) {
+	return m.query(firmwareItems, timeout)
+}
+
+// Registration queries the network registration state, as described by
+// formatRegistration
+func (m *ModemManager) Registration(timeout time.Duration) (string, error) {
+	items, err := m.query([]string{"Registration"}, timeout)
+	if err != nil {
+		return "", err
+	}
+	if items[0].Error != "" {
+		return "", errors.New(items[0].Error)
+	}
+	return items[0].Value, nil
+}
+
+// query runs the info queries with the given names, or all of them
+func (m *ModemManager) query(names []string, timeout time.Duration) ([]InfoItem, error) {
+	g := m.GSM()
+	if g == nil {
+		return nil, ErrNoModem
+	}
+
+	items := make([]InfoItem, 0, len(infoQueries))
+	for _, q := range infoQueries {
+		if names != nil && !slices.Contains(names, q.name) {
+			continue
+		}
+		item := InfoItem{Name: q.name}
+		response, err := g.Command(q.cmd, at.WithTimeout(timeout))
+		if err != nil {
+			item.Error = err.Error()
+		} else {
+			item.Value = infoValue(q.cmd, response)
+			if q.format != nil {
+				item.Value = q.format(item.Value)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// infoValue joins the lines of an answer, without the +CMD: prefix
+func infoValue(cmd string, response []string) string {
+	prefix := strings.TrimSuffix(cmd, "?")
+	values := make([]string, 0, len(response))
+	for _, line := range response {
+		if info.HasPrefix(line, prefix) {
+			line = info.TrimPrefix(line, prefix)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			values = append(values, line)
+		}
+	}
+	return strings.Join(values, " ")
+}
+
+// formatSMSC keeps the number of +CSCA: "+33609001390",145
+func formatSMSC(value string) string {
+	if fields := atresp.Split(value); fields[0] != "" {
+		return fields[0]
+	}
+	return value
+}
+
+// registrationStates are the <stat> values of +CREG
+var registrationStates = []string{"not registered", "registered, home", "searching", "denied", "unknown", "registered, roaming"}
+
+// formatRegistration describes +CREG: <n>,<stat>
+func formatRegistration(value string) string {
+	stat, ok := atresp.Split(value).Int(1)
+	if !ok || stat < 0 || stat >= len(registrationStates) {
+		return value
+	}
+	return registrationStates[stat]
+}
+
+// accessTechnologies are the <AcT> values of +COPS
+var accessTechnologies = map[string]string{"0": "GSM", "2": "UTRAN", "3": "EDGE", "4": "HSDPA", "5": "HSUPA", "6": "HSPA", "7": "LTE", "9": "NB-IoT", "10": "LTE", "13": "LTE+NR"}
+
+// formatOperator describes +COPS: <mode>,<format>,"<operator>",<AcT>
+func formatOperator(value string) string {
+	fields := atresp.Split(value)
+	if len(fields) < 3 {
+		return "none"
+	}
+	if len(fields) >= 4 {
+		if act, ok := accessTechnologies[fields[3]]; ok {
+			return fmt.Sprintf("%s (%s)", fields[2], act)
+		}
+	}
+	return fields[2]
+}
+
+// formatSignal converts +CSQ: <rssi>,<ber> to dBm
+func formatSignal(value string) string {
+	rssi, _, _ := strings.Cut(value, ",")
+	n, err := strconv.Atoi(strings.TrimSpace(rssi))
+	if err != nil {
+		return value
+	}
+	if n == 99 || n < 0 || n > 31 {
+		return "no signal"
+	}
+	return fmt.Sprintf("%d dBm (CSQ %d)", -113+2*n, n)
+}
@@ -0,0 +1,30 @@
+package machine
+
+import "testing"
+
+func TestInfoFormat(t *testing.T) {
+	tests := []struct {
+		cmd      string
+		response []string
+		format   func(string) string
+		want     string
+	}{
+		{"+CGMI", []string{"SIMCOM INCORPORATED"}, nil, "SIMCOM INCORPORATED"},
+		{"+CCID", []string{"+CCID: 89330123456789012345"}, nil, "89330123456789012345"},
+		{"+CSCA?", []string{`+CSCA: "+33609001390",145`}, formatSMSC, "+33609001390"},
+		{"+CREG?", []string{"+CREG: 0,5"}, formatRegistration, "registered, roaming"},
+		{"+COPS?", []string{`+COPS: 0,0,"Orange F",7`}, formatOperator, "Orange F (LTE)"},
+		{"+COPS?", []string{"+COPS: 0"}, formatOperator, "none"},
+		{"+CSQ", []string{"+CSQ: 20,99"}, formatSignal, "-73 dBm (CSQ 20)"},
+		{"+CSQ", []string{"+CSQ: 99,99"}, formatSignal, "no signal"},
+	}
+	for _, tt := range tests {
+		got := infoValue(tt.cmd, tt.response)
+		if tt.format != nil {
+			got = tt.format(got)
+		}
+		if got != tt.want {
+			t.Errorf("%s %q = %q, want %q", tt.cmd, tt.response, got, tt.want)
+		}
+	}
+}
@@ -2,8 +2,11 @@ package machine
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golte/config"
@@ -11,72 +14,228 @@ import (
 
 // SignalMonitor handles signal quality monitoring
 type SignalMonitor struct {
-	config      *config.Config
+	cfg         atomic.Pointer[config.Config]
 	logger      *slog.Logger
 	modem       *ModemManager
+	notifyFunc  func(notificationType NotificationType, from, message string)
 	ctx         context.Context
 	cancel      context.CancelFunc
 	wg          *sync.WaitGroup
 	stopChannel chan struct{}
+	stopOnce    sync.Once
+
+	// sampleFunc, if set, is given every signal sample with the network
+	// registration state queried along, empty if that failed
+	sampleFunc func(signal []string, registration string)
+
+	mu       sync.Mutex
+	started  bool
+	interval time.Duration      // of the running loop, 0 if none runs
+	stopLoop context.CancelFunc // stops the running loop
 }
 
-// NewSignalMonitor creates a new SignalMonitor instance
-func NewSignalMonitor(cfg *config.Config, modem *ModemManager, wg *sync.WaitGroup) *SignalMonitor {
-	ctx, cancel := context.WithCancel(context.Background())
+// NewSignalMonitor creates a new SignalMonitor instance whose lifetime is
+// bound to the parent context. Re-registrations are reported to notifyFunc.
+func NewSignalMonitor(parent context.Context, cfg *config.Config, modem *ModemManager, notifyFunc func(notificationType NotificationType, from, message string), wg *sync.WaitGroup) *SignalMonitor {
+	ctx, cancel := context.WithCancel(parent)
 
-	return &SignalMonitor{
-		config:      cfg,
+	s := &SignalMonitor{
 		logger:      slog.With("component", "signal-monitor"),
 		modem:       modem,
+		notifyFunc:  notifyFunc,
 		ctx:         ctx,
 		cancel:      cancel,
 		wg:          wg,
 		stopChannel: make(chan struct{}),
 	}
+	s.cfg.Store(cfg)
+	return s
+}
+
+// Reconfigure switches to cfg from the next signal check, restarting the
+// polling when monitor.signal_interval changed
+func (s *SignalMonitor) Reconfigure(cfg *config.Config) {
+	s.cfg.Store(cfg)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started && cfg.Monitor.SignalInterval != s.interval {
+		s.restartLocked()
+	}
 }
 
-// Start begins signal quality monitoring
+// Start begins signal quality monitoring, unless monitor.signal_interval is
+// 0
 func (s *SignalMonitor) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started = true
+	s.restartLocked()
+}
+
+// restartLocked stops the polling loop and starts one at the configured
+// interval, if any. s.mu must be held.
+func (s *SignalMonitor) restartLocked() {
+	if s.stopLoop != nil {
+		s.stopLoop()
+		s.stopLoop = nil
+	}
+	s.interval = 0
+
+	interval := s.cfg.Load().Monitor.SignalInterval
+	if s.ctx.Err() != nil {
+		return
+	}
+	if interval <= 0 {
+		s.logger.Info("Signal quality monitoring disabled")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.stopLoop = cancel
+	s.interval = interval
 	s.wg.Add(1)
-	go func() {
-		defer s.wg.Done()
-
-		s.logger.Info("Starting signal quality monitoring")
-
-		ticker := time.NewTicker(time.Minute)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				result, err := s.modem.GetSignalQuality()
-				if err != nil {
-					s.logger.Error("Failed to get signal quality", slog.Any("error", err))
-				} else {
-					s.logger.Debug("Signal quality", slog.Any("result", result))
-				}
-			case <-s.ctx.Done():
-				s.logger.Info("Signal quality monitoring stopped")
-				return
-			case <-s.modem.Closed():
-				s.logger.Warn("Modem closed, stopping signal quality monitoring")
-				return
-			case <-s.stopChannel:
-				s.logger.Info("Signal quality monitoring stopped via stop channel")
-				return
+	go s.run(ctx, interval)
+}
+
+// signalReportGap is the least time between two samples taken on signal
+// reports, a fringe signal can change every few seconds
+const signalReportGap = 10 * time.Second
+
+// run polls the signal every interval until ctx is done or the monitor
+// stops. While the modem reports signal changes, it's only sampled on
+// reports, the ticks then check the outage with the last sample.
+func (s *SignalMonitor) run(ctx context.Context, interval time.Duration) {
+	defer s.wg.Done()
+
+	s.logger.Info("Starting signal quality monitoring",
+		slog.Duration("interval", interval),
+		slog.Bool("reports", s.modem.SignalReporting()))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var (
+		watch   signalWatch
+		lost    bool
+		sampled time.Time
+		pending bool // a report came too soon after the last sample
+	)
+	sample := func(now time.Time) {
+		if l, ok := s.sample(ctx); ok {
+			lost, sampled, pending = l, now, false
+		}
+	}
+	for {
+		select {
+		case now := <-ticker.C:
+			if !s.modem.SignalReporting() || pending || sampled.IsZero() {
+				sample(now)
+			}
+			cfg := s.cfg.Load()
+			if !sampled.IsZero() && watch.observe(lost, now, cfg.Modem.Reregister) {
+				s.reregister(cfg.Modem.Reregister, now.Sub(watch.lostSince))
 			}
+		case <-s.modem.SignalReports():
+			now := time.Now()
+			if now.Sub(sampled) < signalReportGap {
+				pending = true
+				continue
+			}
+			sample(now)
+		case <-ctx.Done():
+			s.logger.Info("Signal quality monitoring stopped")
+			return
+		case <-s.stopChannel:
+			s.logger.Info("Signal quality monitoring stopped via stop channel")
+			return
 		}
-	}()
+	}
 }
 
-// Stop stops signal quality monitoring
+// sample queries the signal and hands it to sampleFunc, reporting whether
+// it was lost. ok is false if the modem didn't answer.
+func (s *SignalMonitor) sample(ctx context.Context) (lost, ok bool) {
+	result, err := s.modem.GetSignalQuality()
+	if err != nil {
+		s.logger.Error("Failed to get signal quality", slog.Any("error", err))
+		return false, false
+	}
+	cfg := s.cfg.Load()
+	s.logger.Log(ctx, cfg.Monitor.SampleLevel(), "Signal quality", slog.Any("result", result))
+
+	lines, _ := result.([]string)
+	if q, ok := ParseSignalQuality(lines); ok {
+		s.modem.signals.add(q)
+	}
+	if s.sampleFunc != nil {
+		registration, err := s.modem.Registration(modemQueryTimeout(cfg.Modem))
+		if err != nil && !errors.Is(err, ErrNoModem) {
+			s.logger.Debug("Failed to query the network registration", slog.Any("error", err))
+		}
+		s.sampleFunc(lines, registration)
+	}
+	return signalLost(lines), true
+}
+
+// Stop stops signal quality monitoring, it is safe to call more than once
 func (s *SignalMonitor) Stop() {
-	s.cancel()
-	close(s.stopChannel)
+	s.stopOnce.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.cancel()
+		close(s.stopChannel)
+	})
+}
+
+// reregister nudges the modem back onto the network and tells Discord
+func (s *SignalMonitor) reregister(cfg config.ReregisterConfig, lost time.Duration) {
+	s.logger.Warn("Signal lost, re-registering with the network",
+		slog.Duration("lost_for", lost),
+		slog.String("method", cfg.Method))
+
+	message := fmt.Sprintf("📡 No signal for %s, asked the modem to register with the network again", lost.Round(time.Minute))
+	if err := s.modem.Reregister(cfg.Method); err != nil {
+		s.logger.Error("Failed to re-register", slog.Any("error", err))
+		message = fmt.Sprintf("📡 No signal for %s, re-registering with the network failed: %v", lost.Round(time.Minute), err)
+	}
+	if s.notifyFunc != nil {
+		s.notifyFunc(NotificationTypeInfo, "Modem", message)
+	}
+}
+
+// signalWatch tracks how long the signal has been lost, to decide when the
+// modem should re-register
+type signalWatch struct {
+	lostSince time.Time
+	lastNudge time.Time
+}
+
+// observe records a signal check and reports whether to re-register: the
+// signal has been lost for cfg.After and the last attempt is older than
+// cfg.Interval
+func (w *signalWatch) observe(lost bool, now time.Time, cfg config.ReregisterConfig) bool {
+	if !lost {
+		w.lostSince = time.Time{}
+		return false
+	}
+	if w.lostSince.IsZero() {
+		w.lostSince = now
+	}
+	if cfg.After <= 0 || now.Sub(w.lostSince) < cfg.After {
+		return false
+	}
+	if !w.lastNudge.IsZero() && now.Sub(w.lastNudge) < cfg.Interval {
+		return false
+	}
+	w.lastNudge = now
+	return true
 }
 
-// SetContext updates the context for cancellation
-func (s *SignalMonitor) SetContext(ctx context.Context) {
-	s.cancel() // Cancel the old context
-	s.ctx = ctx
+// signalLost reports whether an AT+CSQ response says there is no signal,
+// an RSSI of 99 meaning unknown or not detectable, unless +CESQ found an LTE
+// reference signal
+func signalLost(response []string) bool {
+	q, ok := ParseSignalQuality(response)
+	return ok && q.Lost()
 }
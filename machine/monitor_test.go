@@ -0,0 +1,172 @@
+package machine
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"golte/config"
+)
+
+func monitorConfig(interval time.Duration) *config.Config {
+	return &config.Config{Monitor: config.MonitorConfig{SignalInterval: interval}}
+}
+
+// waitGroupDone fails the test if wg doesn't finish within a second
+func waitGroupDone(t *testing.T, wg *sync.WaitGroup, what string) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal(what)
+	}
+}
+
+func TestSignalMonitorStopTwice(t *testing.T) {
+	var wg sync.WaitGroup
+	s := NewSignalMonitor(context.Background(), monitorConfig(time.Hour), &ModemManager{}, nil, &wg)
+
+	s.Start()
+	s.Stop()
+	s.Stop()
+	wg.Wait()
+}
+
+func TestSignalMonitorStopsWithParentContext(t *testing.T) {
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewSignalMonitor(ctx, monitorConfig(time.Hour), &ModemManager{}, nil, &wg)
+
+	s.Start()
+	cancel()
+	waitGroupDone(t, &wg, "signal monitor did not stop after the parent context was cancelled")
+
+	// Stopping after the parent is gone must still be safe
+	s.Stop()
+}
+
+func TestSignalWatch(t *testing.T) {
+	cfg := config.ReregisterConfig{After: 5 * time.Minute, Interval: 30 * time.Minute}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	var w signalWatch
+	for minute := range 5 {
+		if w.observe(true, at(minute), cfg) {
+			t.Fatalf("re-registered after %d minutes", minute)
+		}
+	}
+	if !w.observe(true, at(5), cfg) {
+		t.Fatal("expected a re-registration after 5 minutes without signal")
+	}
+	if w.observe(true, at(6), cfg) || w.observe(true, at(34), cfg) {
+		t.Error("re-registrations should be rate limited")
+	}
+	if !w.observe(true, at(35), cfg) {
+		t.Error("expected another re-registration once the interval passed")
+	}
+
+	// Signal coming back resets the outage but not the rate limit
+	w.observe(false, at(36), cfg)
+	if w.observe(true, at(42), cfg) {
+		t.Error("re-registered within the interval of the previous one")
+	}
+
+	var disabled signalWatch
+	if disabled.observe(true, at(0), config.ReregisterConfig{}) || disabled.observe(true, at(600), config.ReregisterConfig{}) {
+		t.Error("re-registration should be disabled when after is 0")
+	}
+}
+
+func TestSignalLost(t *testing.T) {
+	for response, want := range map[string]bool{
+		"+CSQ: 99,99": true,
+		"+CSQ: 18,99": false,
+		"+CSQ:99,0":   true,
+		"ERROR":       false,
+	} {
+		if got := signalLost([]string{response}); got != want {
+			t.Errorf("signalLost(%q) = %v, want %v", response, got, want)
+		}
+	}
+}
+
+func TestSignalMonitorDisabled(t *testing.T) {
+	var wg sync.WaitGroup
+	s := NewSignalMonitor(context.Background(), monitorConfig(0), &ModemManager{}, nil, &wg)
+	defer s.Stop()
+
+	s.Start()
+	waitGroupDone(t, &wg, "a disabled signal monitor left its loop running")
+}
+
+func TestSignalMonitorReconfigureInterval(t *testing.T) {
+	var wg sync.WaitGroup
+	s := NewSignalMonitor(context.Background(), monitorConfig(time.Hour), &ModemManager{}, nil, &wg)
+	defer s.Stop()
+
+	// Reconfiguring before Start doesn't start polling
+	s.Reconfigure(monitorConfig(time.Minute))
+	waitGroupDone(t, &wg, "Reconfigure started the monitor before Start")
+
+	s.Start()
+	s.Reconfigure(monitorConfig(time.Second))
+	if s.interval != time.Second {
+		t.Errorf("interval after a reload = %v, want 1s", s.interval)
+	}
+
+	s.Reconfigure(monitorConfig(0))
+	waitGroupDone(t, &wg, "disabling the monitor on reload left its loop running")
+
+	s.Reconfigure(monitorConfig(time.Minute))
+	if s.interval != time.Minute {
+		t.Errorf("interval after enabling on reload = %v, want 1m", s.interval)
+	}
+	s.Stop()
+	waitGroupDone(t, &wg, "the signal monitor did not stop")
+
+	// Nothing starts once stopped
+	s.Reconfigure(monitorConfig(time.Second))
+	waitGroupDone(t, &wg, "Reconfigure restarted a stopped monitor")
+}
+
+func TestSignalMonitorReports(t *testing.T) {
+	var wg sync.WaitGroup
+	modem := NewModemManager(&config.Config{}, nil, &fakeSMS{}, nil, nil)
+	modem.signalReporting.Store(true)
+	s := NewSignalMonitor(context.Background(), monitorConfig(time.Hour), modem, nil, &wg)
+	samples := make(chan []string, 4)
+	s.sampleFunc = func(signal []string, _ string) { samples <- signal }
+	defer func() {
+		s.Stop()
+		wg.Wait()
+	}()
+	s.Start()
+
+	modem.signalReports <- struct{}{}
+	select {
+	case signal := <-samples:
+		if len(signal) != 1 || signal[0] != "+CSQ: 20,99" {
+			t.Errorf("sample = %q", signal)
+		}
+		if spark := modem.SignalSparkline(); spark != "▆" {
+			t.Errorf("SignalSparkline() = %q", spark)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a signal report wasn't sampled")
+	}
+
+	// Reports right after a sample wait for the next tick
+	modem.signalReports <- struct{}{}
+	select {
+	case <-samples:
+		t.Error("sampled twice within signalReportGap")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
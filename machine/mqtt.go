@@ -0,0 +1,361 @@
+package machine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"golte/config"
+	"golte/mqtt"
+)
+
+// mqttRetryDelay is the first delay before reconnecting to the broker, it
+// doubles up to maxRegisterRetryDelay
+const mqttRetryDelay = time.Second
+
+// Payloads of the <topic_prefix>/status topic
+const (
+	MQTTOnline  = "online"
+	MQTTOffline = "offline"
+)
+
+// Events of the <topic_prefix>/call topic
+const (
+	MQTTCallIncoming = "incoming"
+	MQTTCallDialing  = "dialing"
+	MQTTCallHangup   = "hangup"
+	MQTTCallNotice   = "notice" // anything else the modem reported, see Message
+)
+
+// MQTTSMS is an SMS received, published on <topic_prefix>/sms/received
+type MQTTSMS struct {
+	From     string    `json:"from"`
+	Contact  string    `json:"contact,omitempty"` // SIM contact name of from
+	Message  string    `json:"message"`
+	Received time.Time `json:"received"`
+}
+
+// MQTTCall is a call event, published on <topic_prefix>/call
+type MQTTCall struct {
+	Event   string    `json:"event"`
+	Number  string    `json:"number,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// MQTTSignal is a signal sample, retained on <topic_prefix>/signal
+type MQTTSignal struct {
+	APISignal
+	Time time.Time `json:"time"`
+}
+
+// MQTTRegistration is the network registration state, retained on
+// <topic_prefix>/registration when it changes
+type MQTTRegistration struct {
+	State string    `json:"state"`
+	Time  time.Time `json:"time"`
+}
+
+// MQTTCommand is the payload of a message on <topic_prefix>/cmd/<command>,
+// each command reading the fields it needs
+type MQTTCommand struct {
+	ID      string `json:"id,omitempty"` // echoed in the result
+	To      string `json:"to,omitempty"` // sms and call, a number or SIM contact name
+	Message string `json:"message,omitempty"`
+	Digits  string `json:"digits,omitempty"` // dtmf
+}
+
+// MQTTResult is the outcome of a command, published on
+// <topic_prefix>/cmd/<command>/result
+type MQTTResult struct {
+	ID      string `json:"id,omitempty"`
+	Command string `json:"command"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+}
+
+// MQTTBridge publishes SMS, call events, signal samples and registration
+// changes to an MQTT broker, and takes the commands mqtt.commands allows
+// through the same functions as the Discord commands. The connection is
+// retried with backoff for as long as the bridge runs.
+type MQTTBridge struct {
+	config     func() *config.Config
+	logger     *slog.Logger
+	modem      *ModemManager
+	smsFunc    func(number, message string) error
+	callFunc   func(number string, maxDuration time.Duration) error
+	hangupFunc func() error
+	auditFunc  func(action, outcome string)
+	wg         *sync.WaitGroup
+
+	mu           sync.Mutex
+	client       *mqtt.Client // nil while disconnected
+	registration string       // last published
+}
+
+// NewMQTTBridge creates the bridge, connected once Run is called. Commands
+// and publishing run in the background, tracked by wg. auditFunc is told
+// about every command, it may be nil.
+func NewMQTTBridge(cfg func() *config.Config, modem *ModemManager, smsFunc func(number, message string) error, callFunc func(number string, maxDuration time.Duration) error, hangupFunc func() error, auditFunc func(action, outcome string), wg *sync.WaitGroup) *MQTTBridge {
+	return &MQTTBridge{
+		config:     cfg,
+		logger:     slog.With("component", "mqtt"),
+		modem:      modem,
+		smsFunc:    smsFunc,
+		callFunc:   callFunc,
+		hangupFunc: hangupFunc,
+		auditFunc:  auditFunc,
+		wg:         wg,
+	}
+}
+
+// Run keeps the bridge connected to mqtt.broker until ctx is done, then
+// marks it offline
+func (b *MQTTBridge) Run(ctx context.Context) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer recoverPanic(b.logger, "MQTT bridge", nil, nil)
+
+		for {
+			err := retryWithBackoff(ctx, 0, mqttRetryDelay, b.connect, func(attempt int, delay time.Duration, err error) {
+				b.logger.Warn("Failed to connect to the MQTT broker, retrying",
+					slog.Int("attempt", attempt),
+					slog.Duration("delay", delay),
+					slog.Any("error", err))
+			})
+			if err != nil {
+				return
+			}
+
+			client := b.current()
+			select {
+			case <-client.Closed():
+				b.setClient(nil)
+				b.logger.Warn("Lost the MQTT broker, reconnecting")
+			case <-ctx.Done():
+				b.disconnect(client)
+				return
+			}
+
+			// A broker dropping every connection right away isn't hammered
+			select {
+			case <-time.After(mqttRetryDelay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// connect dials the broker with an offline last will, marks the bridge
+// online and subscribes to the command topics
+func (b *MQTTBridge) connect(ctx context.Context) error {
+	cfg := b.config().MQTT
+	client, err := mqtt.Dial(ctx, mqtt.Config{
+		Broker:    cfg.Broker,
+		ClientID:  cfg.ClientID,
+		Username:  cfg.Username,
+		Password:  cfg.Password,
+		KeepAlive: cfg.KeepAlive,
+		Will:      &mqtt.Message{Topic: b.topic("status"), Payload: []byte(MQTTOffline), QoS: 1, Retain: true},
+	}, b.onMessage)
+	if err != nil {
+		return err
+	}
+
+	err = client.Publish(ctx, mqtt.Message{Topic: b.topic("status"), Payload: []byte(MQTTOnline), QoS: 1, Retain: true})
+	if err == nil {
+		err = client.Subscribe(ctx, map[string]byte{b.topic("cmd/+"): 1})
+	}
+	if err != nil {
+		client.Close()
+		return err
+	}
+
+	b.setClient(client)
+	b.logger.Info("Connected to the MQTT broker",
+		slog.String("broker", cfg.Broker),
+		slog.String("topic_prefix", cfg.TopicPrefix),
+		slog.Any("commands", cfg.Commands))
+	return nil
+}
+
+// disconnect marks the bridge offline, the broker doesn't publish the last
+// will of a client disconnecting cleanly
+func (b *MQTTBridge) disconnect(client *mqtt.Client) {
+	b.setClient(nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Publish(ctx, mqtt.Message{Topic: b.topic("status"), Payload: []byte(MQTTOffline), QoS: 1, Retain: true}); err != nil {
+		b.logger.Warn("Failed to publish the offline status", slog.Any("error", err))
+	}
+	client.Close()
+}
+
+func (b *MQTTBridge) current() *mqtt.Client {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.client
+}
+
+func (b *MQTTBridge) setClient(client *mqtt.Client) {
+	b.mu.Lock()
+	b.client = client
+	b.mu.Unlock()
+}
+
+// topic returns name under mqtt.topic_prefix
+func (b *MQTTBridge) topic(name string) string {
+	return b.config().MQTT.TopicPrefix + "/" + name
+}
+
+// PublishSMS publishes an SMS received
+func (b *MQTTBridge) PublishSMS(from, message string) {
+	contact, _ := b.modem.PhonebookName(from)
+	b.publish("sms/received", MQTTSMS{From: from, Contact: contact, Message: message, Received: b.modem.Now()}, false)
+}
+
+// PublishCall publishes a call event, message describes it for
+// MQTTCallNotice
+func (b *MQTTBridge) PublishCall(event, number, message string) {
+	b.publish("call", MQTTCall{Event: event, Number: number, Message: message, Time: b.modem.Now()}, false)
+}
+
+// PublishSample publishes a signal sample of the monitor, and the
+// registration state when it changed
+func (b *MQTTBridge) PublishSample(lines []string, registration string) {
+	now := b.modem.Now()
+	if signal, ok := parseSignal(lines); ok {
+		b.publish("signal", MQTTSignal{APISignal: signal, Time: now}, true)
+	}
+
+	b.mu.Lock()
+	changed := registration != "" && registration != b.registration
+	if changed {
+		b.registration = registration
+	}
+	b.mu.Unlock()
+	if changed {
+		b.publish("registration", MQTTRegistration{State: registration, Time: now}, true)
+	}
+}
+
+// publish sends v as JSON on the topic name at QoS 1 in the background.
+// Events while disconnected are dropped.
+func (b *MQTTBridge) publish(name string, v any, retain bool) {
+	client := b.current()
+	if client == nil {
+		b.logger.Debug("Not connected to the MQTT broker, dropped an event", slog.String("topic", name))
+		return
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		b.logger.Error("Failed to encode an MQTT event", slog.String("topic", name), slog.Any("error", err))
+		return
+	}
+	m := mqtt.Message{Topic: b.topic(name), Payload: payload, QoS: 1, Retain: retain}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		if err := client.Publish(context.Background(), m); err != nil {
+			b.logger.Warn("Failed to publish to the MQTT broker", slog.String("topic", m.Topic), slog.Any("error", err))
+		}
+	}()
+}
+
+// onMessage runs a command in the background, the client acknowledges the
+// message once it returns
+func (b *MQTTBridge) onMessage(m mqtt.Message) {
+	// Results are published a level below, out of the subscription
+	command, ok := strings.CutPrefix(m.Topic, b.topic("cmd/"))
+	if !ok || strings.Contains(command, "/") {
+		return
+	}
+	if m.Retain {
+		b.logger.Warn("Ignored a retained MQTT command, it would run again on every reconnection", slog.String("topic", m.Topic))
+		return
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer recoverPanic(b.logger, "MQTT command", nil, nil)
+
+		result := b.runCommand(command, m.Payload)
+		b.publish("cmd/"+command+"/result", result, false)
+	}()
+}
+
+// runCommand runs command if mqtt.commands allows it
+func (b *MQTTBridge) runCommand(command string, payload []byte) MQTTResult {
+	var cmd MQTTCommand
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &cmd); err != nil {
+			return MQTTResult{Command: command, Error: "payload is not a JSON object"}
+		}
+	}
+	result := MQTTResult{ID: cmd.ID, Command: command}
+
+	if !b.config().MQTT.Allows(command) {
+		b.logger.Warn("Refused an MQTT command not in mqtt.commands", slog.String("command", command))
+		b.audit("MQTT "+command, "⛔ refused, not in mqtt.commands")
+		result.Error = "command not allowed by mqtt.commands"
+		return result
+	}
+
+	b.logger.Info("Received MQTT command", slog.String("command", command), slog.String("id", cmd.ID))
+	action, err := b.execute(command, cmd)
+	if action == "" {
+		action = "MQTT " + command
+	}
+	if err != nil {
+		b.logger.Error("MQTT command failed", slog.String("command", command), slog.Any("error", err))
+		b.audit(action, fmt.Sprintf("❌ %v", err))
+		result.Error = err.Error()
+		return result
+	}
+	b.audit(action, "✅ done")
+	result.OK = true
+	return result
+}
+
+// execute runs an allowed command, returning the action to audit it as
+func (b *MQTTBridge) execute(command string, cmd MQTTCommand) (string, error) {
+	switch command {
+	case config.MQTTCommandSMS, config.MQTTCommandCall:
+		if command == config.MQTTCommandSMS && (cmd.To == "" || cmd.Message == "") {
+			return "", errors.New(`expected {"to": "...", "message": "..."}`)
+		}
+		if cmd.To == "" {
+			return "", errors.New(`expected {"to": "..."}`)
+		}
+		number, contact, err := resolveRecipient(b.modem.Contacts(), cmd.To)
+		if err != nil {
+			return "", err
+		}
+		if command == config.MQTTCommandSMS {
+			return "MQTT SMS to " + recipientLabel(number, contact), b.smsFunc(number, cmd.Message)
+		}
+		return "MQTT call to " + recipientLabel(number, contact), b.callFunc(number, 0)
+	case config.MQTTCommandHangup:
+		return "MQTT hang up", b.hangupFunc()
+	case config.MQTTCommandDTMF:
+		return "MQTT DTMF", b.modem.SendDTMF(cmd.Digits)
+	default:
+		return "", fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// audit reports a command and its outcome, see NewMQTTBridge
+func (b *MQTTBridge) audit(action, outcome string) {
+	if b.auditFunc != nil {
+		b.auditFunc(action, outcome)
+	}
+}
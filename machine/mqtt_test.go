@@ -0,0 +1,126 @@
+package machine
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"golte/config"
+)
+
+// newTestMQTT returns a disconnected bridge over a mock modem knowing one
+// SIM contact, allowing the given commands. sent returns the SMS sent as
+// "number: message", audits what was audited.
+func newTestMQTT(t *testing.T, commands ...string) (b *MQTTBridge, sent, audits func() []string) {
+	t.Helper()
+	cfg := &config.Config{
+		Modem: config.ModemConfig{Type: config.ModemTypeMock},
+		MQTT:  config.MQTTConfig{Broker: "tcp://localhost", TopicPrefix: "golte", Commands: commands},
+	}
+	modem := NewModemManager(cfg, nil, nil, nil, nil)
+	modem.setPhonebook([]PhonebookEntry{{Index: 1, Name: "Alice", Number: "+33612345678"}})
+
+	var (
+		mu               sync.Mutex
+		smsSent, audited []string
+		wg               sync.WaitGroup
+	)
+	t.Cleanup(wg.Wait)
+	record := func(list *[]string, entry string) {
+		mu.Lock()
+		defer mu.Unlock()
+		*list = append(*list, entry)
+	}
+	get := func(list *[]string) func() []string {
+		return func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return slices.Clone(*list)
+		}
+	}
+
+	b = NewMQTTBridge(modem.config, modem,
+		func(number, message string) error {
+			record(&smsSent, number+": "+message)
+			return nil
+		},
+		modem.StartCall, modem.HangUpCall,
+		func(action, outcome string) { record(&audited, action+": "+outcome) },
+		&wg)
+	return b, get(&smsSent), get(&audited)
+}
+
+func TestMQTTCommandNotAllowed(t *testing.T) {
+	b, _, audits := newTestMQTT(t, config.MQTTCommandSMS)
+
+	result := b.runCommand(config.MQTTCommandCall, []byte(`{"id": "42", "to": "Alice"}`))
+	if result.OK || result.ID != "42" || !strings.Contains(result.Error, "mqtt.commands") {
+		t.Errorf("call result = %+v, want refused", result)
+	}
+	if audited := audits(); len(audited) != 1 || !strings.Contains(audited[0], "refused") {
+		t.Errorf("audited %q", audited)
+	}
+	if b.modem.mock.call != "" {
+		t.Error("a refused call was placed")
+	}
+}
+
+func TestMQTTSendSMS(t *testing.T) {
+	b, sent, audits := newTestMQTT(t, config.MQTTCommandSMS)
+
+	result := b.runCommand(config.MQTTCommandSMS, []byte(`{"id": "1", "to": "alice", "message": "hi"}`))
+	if !result.OK || result.ID != "1" || result.Command != config.MQTTCommandSMS {
+		t.Fatalf("result = %+v", result)
+	}
+	if got := sent(); len(got) != 1 || got[0] != "+33612345678: hi" {
+		t.Errorf("sent %q", got)
+	}
+	if audited := audits(); len(audited) != 1 || !strings.Contains(audited[0], "SMS to Alice") {
+		t.Errorf("audited %q", audited)
+	}
+
+	for _, payload := range []string{`not json`, `{"to": "alice"}`, `{"to": "bob", "message": "hi"}`} {
+		if result := b.runCommand(config.MQTTCommandSMS, []byte(payload)); result.OK || result.Error == "" {
+			t.Errorf("payload %s: result %+v, want an error", payload, result)
+		}
+	}
+	if got := sent(); len(got) != 1 {
+		t.Errorf("sent %q, want only the valid SMS", got)
+	}
+}
+
+func TestMQTTCallAndDTMF(t *testing.T) {
+	b, _, _ := newTestMQTT(t, config.MQTTCommandCall, config.MQTTCommandDTMF, config.MQTTCommandHangup)
+
+	if result := b.runCommand(config.MQTTCommandDTMF, []byte(`{"digits": "1#"}`)); result.OK {
+		t.Error("DTMF sent without a call")
+	}
+	if result := b.runCommand(config.MQTTCommandCall, []byte(`{"to": "+33687654321"}`)); !result.OK {
+		t.Fatalf("call result = %+v", result)
+	}
+	if result := b.runCommand(config.MQTTCommandDTMF, []byte(`{"digits": "12x"}`)); result.OK {
+		t.Error("invalid DTMF digits sent")
+	}
+	if result := b.runCommand(config.MQTTCommandDTMF, []byte(`{"digits": "1#"}`)); !result.OK {
+		t.Errorf("DTMF result = %+v", result)
+	}
+	if result := b.runCommand(config.MQTTCommandHangup, nil); !result.OK {
+		t.Errorf("hang up result = %+v", result)
+	}
+}
+
+func TestMQTTPublishWhileDisconnected(t *testing.T) {
+	b, _, _ := newTestMQTT(t)
+
+	// Dropped, but the registration state is still tracked
+	b.PublishSMS("+33612345678", "hi")
+	b.PublishSample([]string{"+CSQ: 20,99"}, "registered, home")
+	b.PublishSample([]string{"+CSQ: 20,99"}, "")
+	if b.registration != "registered, home" {
+		t.Errorf("registration = %q", b.registration)
+	}
+	if got := b.topic("cmd/sms/result"); got != "golte/cmd/sms/result" {
+		t.Errorf("topic() = %q", got)
+	}
+}
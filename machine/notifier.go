@@ -0,0 +1,17 @@
+package machine
+
+import "context"
+
+// Notifier is a chat service the bridge posts received SMS, calls and alerts
+// to, and may take commands from. Discord and Telegram are notifiers, the
+// machine tells every one configured.
+type Notifier interface {
+	Name() string
+	Initialize() error
+	Start(ctx context.Context) error
+	Stop()
+	Connected() bool
+
+	SendEmbed(notificationType NotificationType, from, message string) error
+	SendSMSEmbed(from, message string, pdus []string) error
+}
@@ -0,0 +1,125 @@
+package machine
+
+/*
+#cgo pkg-config: opus
+#include <opus/opus.h>
+
+static int golte_opus_set_bitrate(OpusEncoder *st, opus_int32 bitrate) {
+	return opus_encoder_ctl(st, OPUS_SET_BITRATE(bitrate));
+}
+static int golte_opus_set_complexity(OpusEncoder *st, opus_int32 complexity) {
+	return opus_encoder_ctl(st, OPUS_SET_COMPLEXITY(complexity));
+}
+static int golte_opus_set_inband_fec(OpusEncoder *st, opus_int32 fec) {
+	return opus_encoder_ctl(st, OPUS_SET_INBAND_FEC(fec));
+}
+static int golte_opus_set_packet_loss_perc(OpusEncoder *st, opus_int32 perc) {
+	return opus_encoder_ctl(st, OPUS_SET_PACKET_LOSS_PERC(perc));
+}
+static int golte_opus_set_dtx(OpusEncoder *st, opus_int32 dtx) {
+	return opus_encoder_ctl(st, OPUS_SET_DTX(dtx));
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"log/slog"
+
+	"golte/config"
+	"golte/ffmpeg"
+
+	"github.com/disgoorg/audio/opus"
+)
+
+// fecExpectedLoss is the packet loss percentage announced to the encoder when
+// in-band FEC is enabled, opus only spends bits on FEC when it expects loss
+const fecExpectedLoss = 10
+
+// opusEncoder is an opus encoder created and owned here, disgoorg/audio's
+// doesn't expose the FEC and DTX ctls nor its handle
+type opusEncoder struct {
+	st       *C.OpusEncoder
+	channels int
+}
+
+var _ ffmpeg.Encoder = (*opusEncoder)(nil)
+
+// Encode encodes a frame of interleaved PCM into data, returning the length
+// of the packet
+func (e *opusEncoder) Encode(pcm []int16, data []byte) (int, error) {
+	if e.st == nil {
+		return 0, opus.ErrEncoderNotInitialized
+	}
+	n := C.opus_encode(e.st, (*C.opus_int16)(&pcm[0]), C.int(len(pcm)/e.channels), (*C.uchar)(&data[0]), C.opus_int32(cap(data)))
+	if n < 0 {
+		return 0, opus.Error(n)
+	}
+	return int(n), nil
+}
+
+// Destroy frees the encoder, it can't be used afterwards
+func (e *opusEncoder) Destroy() {
+	if e.st != nil {
+		C.opus_encoder_destroy(e.st)
+		e.st = nil
+	}
+}
+
+// newOpusEncoder creates the encoder for the audio sent to Discord and applies
+// the configured settings
+func newOpusEncoder(cfg config.OpusConfig, sampleRate, channels int, logger *slog.Logger) (*opusEncoder, error) {
+	application := C.int(C.OPUS_APPLICATION_VOIP)
+	switch cfg.Application {
+	case "audio":
+		application = C.OPUS_APPLICATION_AUDIO
+	case "lowdelay":
+		application = C.OPUS_APPLICATION_RESTRICTED_LOWDELAY
+	}
+
+	var rc C.int
+	st := C.opus_encoder_create(C.opus_int32(sampleRate), C.int(channels), application, &rc)
+	if rc != C.OPUS_OK {
+		return nil, fmt.Errorf("failed to create opus encoder: %w", opus.Error(rc))
+	}
+	encoder := &opusEncoder{st: st, channels: channels}
+	if err := encoder.configure(cfg); err != nil {
+		encoder.Destroy()
+		return nil, err
+	}
+
+	logger.Info("Opus encoder configured",
+		slog.String("application", cfg.Application),
+		slog.Int("bitrate", cfg.Bitrate),
+		slog.Int("complexity", cfg.Complexity),
+		slog.Bool("inband_fec", cfg.InbandFEC),
+		slog.Bool("dtx", cfg.DTX))
+
+	return encoder, nil
+}
+
+// configure applies the settings of cfg to the encoder
+func (e *opusEncoder) configure(cfg config.OpusConfig) error {
+	if cfg.Bitrate != 0 {
+		if rc := C.golte_opus_set_bitrate(e.st, C.opus_int32(cfg.Bitrate)); rc != C.OPUS_OK {
+			return fmt.Errorf("failed to set opus bitrate: %w", opus.Error(rc))
+		}
+	}
+	if rc := C.golte_opus_set_complexity(e.st, C.opus_int32(cfg.Complexity)); rc != C.OPUS_OK {
+		return fmt.Errorf("failed to set opus complexity: %w", opus.Error(rc))
+	}
+	if cfg.InbandFEC {
+		if rc := C.golte_opus_set_inband_fec(e.st, 1); rc != C.OPUS_OK {
+			return fmt.Errorf("failed to enable opus in-band FEC: %w", opus.Error(rc))
+		}
+		if rc := C.golte_opus_set_packet_loss_perc(e.st, fecExpectedLoss); rc != C.OPUS_OK {
+			return fmt.Errorf("failed to set opus expected packet loss: %w", opus.Error(rc))
+		}
+	}
+	if cfg.DTX {
+		if rc := C.golte_opus_set_dtx(e.st, 1); rc != C.OPUS_OK {
+			return fmt.Errorf("failed to enable opus DTX: %w", opus.Error(rc))
+		}
+	}
+	return nil
+}
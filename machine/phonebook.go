@@ -0,0 +1,272 @@
+package machine
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"golte/atresp"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/warthog618/sms/encoding/ucs2"
+)
+
+// PhonebookEntry is a contact stored on the SIM
+type PhonebookEntry struct {
+	Index  int
+	Number string
+	Name   string
+}
+
+// defaultPhonebookSize is read when the modem doesn't report the size of the
+// SIM phonebook
+const defaultPhonebookSize = 250
+
+// ReadPhonebook returns the contacts stored on the SIM, and remembers them to
+// name the senders of incoming SMS
+func (m *ModemManager) ReadPhonebook() ([]PhonebookEntry, error) {
+	if m.GSM() == nil {
+		return nil, ErrNoModem
+	}
+	if _, err := m.GSM().Command(`+CPBS="SM"`); err != nil {
+		return nil, fmt.Errorf("failed to select the SIM phonebook: %w", err)
+	}
+
+	size := defaultPhonebookSize
+	if response, err := m.GSM().Command("+CPBR=?"); err == nil {
+		if n, ok := parsePhonebookSize(response); ok {
+			size = n
+		}
+	}
+
+	response, err := m.GSM().Command(fmt.Sprintf("+CPBR=1,%d", size))
+	if err != nil {
+		// An empty phonebook is reported as an error by some modems
+		if strings.Contains(err.Error(), "not found") {
+			m.setPhonebook(nil)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read the SIM phonebook: %w", err)
+	}
+
+	entries := parsePhonebook(response, m.charset() == "UCS2")
+	m.setPhonebook(entries)
+	m.logger.Info("Read SIM phonebook", slog.Int("entries", len(entries)))
+	return entries, nil
+}
+
+// WritePhonebookEntry stores a contact on the SIM at index, or at the first
+// free location if index is 0. Names outside ASCII are written in UCS2.
+func (m *ModemManager) WritePhonebookEntry(index int, name, number string) error {
+	if m.GSM() == nil {
+		return ErrNoModem
+	}
+	if strings.ContainsRune(name, '"') || strings.ContainsRune(number, '"') {
+		return fmt.Errorf("names and numbers can't contain quotes")
+	}
+	if _, err := m.GSM().Command(`+CPBS="SM"`); err != nil {
+		return fmt.Errorf("failed to select the SIM phonebook: %w", err)
+	}
+
+	// Strings are sent in the TE character set, switch to UCS2 for the
+	// write if the name needs it
+	charset := m.charset()
+	encodedName, encodedNumber := name, number
+	if !isASCII(name) || charset == "UCS2" {
+		if charset != "UCS2" {
+			if _, err := m.GSM().Command(`+CSCS="UCS2"`); err != nil {
+				return fmt.Errorf("failed to switch to the UCS2 character set: %w", err)
+			}
+			defer func() {
+				if _, err := m.GSM().Command(fmt.Sprintf(`+CSCS="%s"`, charset)); err != nil {
+					m.logger.Warn("Failed to restore the character set", slog.String("charset", charset), slog.Any("error", err))
+				}
+			}()
+		}
+		encodedName = encodeUCS2Hex(name)
+		encodedNumber = encodeUCS2Hex(number)
+	}
+
+	numberType := 129 // national or unknown
+	if strings.HasPrefix(number, "+") {
+		numberType = 145 // international
+	}
+	location := ""
+	if index > 0 {
+		location = strconv.Itoa(index)
+	}
+	if _, err := m.GSM().Command(fmt.Sprintf(`+CPBW=%s,"%s",%d,"%s"`, location, encodedNumber, numberType, encodedName)); err != nil {
+		return fmt.Errorf("failed to write the phonebook entry: %w", err)
+	}
+
+	m.phonebookMu.Lock()
+	if m.phonebook == nil {
+		m.phonebook = make(map[string]string)
+	}
+	m.phonebook[normalizeNumber(number)] = name
+	m.phonebookMu.Unlock()
+
+	m.logger.Info("Wrote SIM phonebook entry", slog.Int("index", index), slog.String("number", number))
+	return nil
+}
+
+// PhonebookName returns the name stored on the SIM for a number, as of the
+// last ReadPhonebook
+func (m *ModemManager) PhonebookName(number string) (string, bool) {
+	m.phonebookMu.RLock()
+	defer m.phonebookMu.RUnlock()
+
+	name, ok := m.phonebook[normalizeNumber(number)]
+	return name, ok
+}
+
+// handlePhonebook lists the SIM contacts or stores a new one
+func (d *DiscordManager) handlePhonebook(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) {
+	if data.SubCommandName == nil {
+		return
+	}
+
+	switch *data.SubCommandName {
+	case "list":
+		entries, err := d.modem.ReadPhonebook()
+		if err != nil {
+			d.logger.Error("Failed to read SIM phonebook", slog.Any("error", err))
+			d.respondEphemeral(event.CreateMessage, fmt.Sprintf("Failed to read the SIM phonebook: %v", err))
+			return
+		}
+		if len(entries) == 0 {
+			d.respondEphemeral(event.CreateMessage, "📒 The SIM phonebook is empty")
+			return
+		}
+
+		var list strings.Builder
+		for i, e := range entries {
+			line := fmt.Sprintf("`%d` **%s** `%s`\n", e.Index, e.Name, e.Number)
+			if list.Len()+len(line) > 4000 {
+				fmt.Fprintf(&list, "… and %d more", len(entries)-i)
+				break
+			}
+			list.WriteString(line)
+		}
+
+		err = event.CreateMessage(discord.NewMessageCreateBuilder().
+			SetEmbeds(discord.NewEmbedBuilder().
+				SetTitle("📒 SIM Phonebook").
+				SetDescription(list.String()).
+				SetColor(0x0099ff).
+				Build()).
+			SetEphemeral(true).
+			Build())
+		if err != nil {
+			d.logger.Error("Failed to send Discord response", slog.Any("error", err))
+		}
+
+	case "add":
+		name := data.String("name")
+		number := data.String("number")
+		index, _ := data.OptInt("index")
+		if err := d.modem.WritePhonebookEntry(index, name, number); err != nil {
+			d.logger.Error("Failed to write SIM phonebook entry", slog.Any("error", err))
+			d.respondEphemeral(event.CreateMessage, fmt.Sprintf("Contact has **not** been stored: %v", err))
+			return
+		}
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("📒 Stored **%s** `%s` on the SIM", name, number))
+	}
+}
+
+// setPhonebook replaces the cached contacts
+func (m *ModemManager) setPhonebook(entries []PhonebookEntry) {
+	phonebook := make(map[string]string, len(entries))
+	for _, e := range entries {
+		phonebook[normalizeNumber(e.Number)] = e.Name
+	}
+
+	m.phonebookMu.Lock()
+	m.phonebook = phonebook
+	m.phonebookMu.Unlock()
+}
+
+// charset returns the TE character set selected with AT+CSCS, or an empty
+// string if the modem doesn't say
+func (m *ModemManager) charset() string {
+	response, err := m.GSM().Command("+CSCS?")
+	if err != nil {
+		return ""
+	}
+	fields, _ := atresp.Find(response, "+CSCS")
+	return fields.String(0)
+}
+
+// parsePhonebookSize parses the answer to AT+CPBR=?, e.g.
+// +CPBR: (1-250),40,18, into the last index
+func parsePhonebookSize(response []string) (int, bool) {
+	fields, ok := atresp.Find(response, "+CPBR")
+	if !ok {
+		return 0, false
+	}
+	indexes := strings.Trim(fields.String(0), "()")
+	_, last, ok := strings.Cut(indexes, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(last))
+	return n, err == nil && n > 0
+}
+
+// parsePhonebook parses AT+CPBR entries, e.g.
+// +CPBR: 1,"+33612345678",145,"Alice". Numbers and names are hex encoded
+// UCS2 when the character set is UCS2.
+func parsePhonebook(response []string, ucs2Strings bool) []PhonebookEntry {
+	var entries []PhonebookEntry
+	for _, fields := range atresp.FindAll(response, "+CPBR") {
+		if len(fields) < 4 {
+			continue
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+
+		number, name := fields[1], fields[3]
+		if ucs2Strings {
+			if decoded, ok := decodeUCS2Hex(number); ok {
+				number = decoded
+			}
+			if decoded, ok := decodeUCS2Hex(name); ok {
+				name = decoded
+			}
+		}
+		entries = append(entries, PhonebookEntry{Index: index, Number: number, Name: name})
+	}
+	return entries
+}
+
+// decodeUCS2Hex decodes a UCS2 string written as hex digits
+func decodeUCS2Hex(s string) (string, bool) {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		return "", false
+	}
+	runes, err := ucs2.Decode(raw)
+	if err != nil {
+		return "", false
+	}
+	return string(runes), true
+}
+
+// encodeUCS2Hex encodes a string as UCS2 written as hex digits
+func encodeUCS2Hex(s string) string {
+	return strings.ToUpper(hex.EncodeToString(ucs2.Encode([]rune(s))))
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package machine
+
+import (
+	"testing"
+)
+
+func TestParsePhonebook(t *testing.T) {
+	entries := parsePhonebook([]string{
+		`+CPBR: 1,"+33612345678",145,"Alice"`,
+		`+CPBR: 2,"0612345678",129,"Smith, Bob"`,
+		`OK`,
+	}, false)
+	if len(entries) != 2 {
+		t.Fatalf("parsePhonebook() = %+v", entries)
+	}
+	if e := entries[1]; e.Index != 2 || e.Number != "0612345678" || e.Name != "Smith, Bob" {
+		t.Errorf("entries[1] = %+v", e)
+	}
+
+	// "Zoé" and its number in UCS2
+	entries = parsePhonebook([]string{
+		`+CPBR: 3,"002B00330033003600310032003300340035003600370038",145,"005A006F00E9"`,
+	}, true)
+	if len(entries) != 1 || entries[0].Name != "Zoé" || entries[0].Number != "+33612345678" {
+		t.Errorf("parsePhonebook() with UCS2 = %+v", entries)
+	}
+	if got := encodeUCS2Hex("Zoé"); got != "005A006F00E9" {
+		t.Errorf("encodeUCS2Hex() = %q", got)
+	}
+}
+
+func TestParsePhonebookSize(t *testing.T) {
+	if n, ok := parsePhonebookSize([]string{"+CPBR: (1-250),40,18"}); !ok || n != 250 {
+		t.Errorf("parsePhonebookSize() = %d, %v, want 250", n, ok)
+	}
+	if _, ok := parsePhonebookSize([]string{"+CPBR: 40,18"}); ok {
+		t.Error("parsePhonebookSize() without a range should fail")
+	}
+}
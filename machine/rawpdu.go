@@ -0,0 +1,85 @@
+package machine
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/warthog618/modem/gsm"
+)
+
+// rawPDUHistory is how many SMS keep their PDUs for the Raw PDU button
+const rawPDUHistory = 100
+
+// rawPDUs returns the TPDUs of a received SMS in hex, one per segment, as
+// received before decoding. SMS from SMPP have none.
+func rawPDUs(msg gsm.Message) []string {
+	pdus := make([]string, 0, len(msg.TPDUs))
+	for _, tp := range msg.TPDUs {
+		b, err := tp.MarshalBinary()
+		if err != nil {
+			continue
+		}
+		pdus = append(pdus, strings.ToUpper(hex.EncodeToString(b)))
+	}
+	return pdus
+}
+
+// pduStore keeps the PDUs of the latest SMS posted with a Raw PDU button,
+// the button's custom ID is too short to carry them
+type pduStore struct {
+	mu    sync.Mutex
+	next  int
+	order []int
+	pdus  map[int][]string
+}
+
+// add stores pdus and returns the ID to look them up with
+func (s *pduStore) add(pdus []string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pdus == nil {
+		s.pdus = make(map[int][]string)
+	}
+	s.next++
+	s.pdus[s.next] = pdus
+	s.order = append(s.order, s.next)
+	if len(s.order) > rawPDUHistory {
+		delete(s.pdus, s.order[0])
+		s.order = s.order[1:]
+	}
+	return s.next
+}
+
+// get returns the PDUs stored under id, false once they're evicted
+func (s *pduStore) get(id int) ([]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pdus, ok := s.pdus[id]
+	return pdus, ok
+}
+
+// rawPDUButton returns the action row of an SMS embed showing pdus
+func (d *DiscordManager) rawPDUButton(pdus []string) discord.ActionRowComponent {
+	id := d.rawPDUs.add(pdus)
+	return discord.NewActionRow(discord.NewSecondaryButton("Raw PDU", "rawpdu:"+strconv.Itoa(id)))
+}
+
+// handleRawPDU shows the PDUs of an SMS embed to an owner
+func (d *DiscordManager) handleRawPDU(event *events.ComponentInteractionCreate, rawID string) {
+	id, _ := strconv.Atoi(rawID)
+	pdus, ok := d.rawPDUs.get(id)
+	if !ok {
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("The PDU is no longer kept, only the last %d SMS are", rawPDUHistory))
+		return
+	}
+
+	d.logger.Info("Raw PDU shown on Discord", slog.String("user", event.User().Username))
+	d.respondEphemeral(event.CreateMessage, "```\n"+strings.Join(pdus, "\n")+"\n```")
+}
@@ -0,0 +1,42 @@
+package machine
+
+import (
+	"encoding/hex"
+	"slices"
+	"testing"
+
+	"github.com/warthog618/modem/gsm"
+	"github.com/warthog618/sms/encoding/tpdu"
+)
+
+func TestRawPDUs(t *testing.T) {
+	// SMS-DELIVER from +33612345678, "hi" in GSM-7
+	const pdu = "040B913316325476F800004210203104008002E834"
+	b, _ := hex.DecodeString(pdu)
+	var tp tpdu.TPDU
+	if err := tp.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	got := rawPDUs(gsm.Message{TPDUs: []*tpdu.TPDU{&tp}})
+	if !slices.Equal(got, []string{pdu}) {
+		t.Errorf("rawPDUs() = %q, want %q", got, pdu)
+	}
+	if got := rawPDUs(gsm.Message{}); len(got) != 0 {
+		t.Errorf("rawPDUs() without TPDUs = %q", got)
+	}
+}
+
+func TestPDUStoreEvicts(t *testing.T) {
+	var s pduStore
+	first := s.add([]string{"00"})
+	for range rawPDUHistory {
+		s.add([]string{"01"})
+	}
+	if _, ok := s.get(first); ok {
+		t.Error("the oldest PDUs should be evicted")
+	}
+	if pdus, ok := s.get(first + rawPDUHistory); !ok || pdus[0] != "01" {
+		t.Errorf("get(latest) = %q, %v", pdus, ok)
+	}
+}
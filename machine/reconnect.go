@@ -0,0 +1,98 @@
+package machine
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/warthog618/modem/at"
+)
+
+// RecoveryKind tells how the modem connection was restored
+type RecoveryKind string
+
+const (
+	// RecoverySoft reopened the serial port, the modem kept its
+	// configuration and any call in progress
+	RecoverySoft RecoveryKind = "soft"
+
+	// RecoveryHard reopened the serial port and initialized the modem again
+	RecoveryHard RecoveryKind = "hard"
+)
+
+const (
+	reconnectAttempts = 5               // tries at opening the port again
+	reconnectDelay    = 2 * time.Second // between tries, the USB device may re-enumerate
+	reconnectPings    = 3               // AT commands that must succeed for a soft recovery
+	pingTimeout       = time.Second
+)
+
+// Reconnect restores the modem connection after the serial link dropped. It
+// first reopens the port and pings the modem, only re-arming the handlers if
+// the session survived, and falls back to the full initialization
+// otherwise.
+func (m *ModemManager) Reconnect() (RecoveryKind, error) {
+	m.closePort()
+
+	a, err := m.reopen()
+	if err != nil {
+		return "", err
+	}
+	if err = ping(a); err == nil {
+		if err = m.attach(a, false); err == nil {
+			m.softRecoveries.Add(1)
+			m.signalRecovered()
+			m.logger.Info("Modem connection restored", slog.String("recovery", string(RecoverySoft)))
+			return RecoverySoft, nil
+		}
+	}
+	m.logger.Warn("Soft recovery failed, reinitializing the modem", slog.Any("error", err))
+
+	m.closePort()
+	if a, err = m.reopen(); err != nil {
+		return "", err
+	}
+	if err := m.attach(a, true); err != nil {
+		m.closePort()
+		return "", err
+	}
+	m.hardRecoveries.Add(1)
+	m.signalRecovered()
+	m.logger.Info("Modem connection restored", slog.String("recovery", string(RecoveryHard)))
+	return RecoveryHard, nil
+}
+
+// Recoveries returns how many times Reconnect restored the connection each
+// way
+func (m *ModemManager) Recoveries() (soft, hard int64) {
+	return m.softRecoveries.Load(), m.hardRecoveries.Load()
+}
+
+// reopen opens the serial port, waiting for the device to come back
+func (m *ModemManager) reopen() (*at.AT, error) {
+	var err error
+	for attempt := range reconnectAttempts {
+		if attempt > 0 {
+			time.Sleep(reconnectDelay)
+		}
+
+		var a *at.AT
+		if a, err = m.open(); err == nil {
+			return a, nil
+		}
+		m.logger.Warn("Failed to reopen the serial port",
+			slog.Int("attempt", attempt+1),
+			slog.Any("error", err))
+	}
+	return nil, fmt.Errorf("modem did not come back: %w", err)
+}
+
+// ping checks the modem answers plain AT commands
+func ping(a *at.AT) error {
+	for range reconnectPings {
+		if _, err := a.Command("", at.WithTimeout(pingTimeout)); err != nil {
+			return fmt.Errorf("modem did not answer AT: %w", err)
+		}
+	}
+	return nil
+}
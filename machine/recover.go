@@ -0,0 +1,66 @@
+package machine
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+)
+
+// PanicError is a panic recovered in one of the bridge's goroutines
+type PanicError struct {
+	Goroutine string
+	Value     any
+	Stack     []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", e.Goroutine, e.Value)
+}
+
+// recoverPanic stops a panic in the goroutine it's deferred in from crashing
+// the bridge. It logs the panic with its stack, calls release to free the
+// audio the goroutine held, then report with the panic. Both may be nil. It
+// must be deferred directly, recover only sees the panic then.
+func recoverPanic(logger *slog.Logger, goroutine string, release func(), report func(error)) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	err := &PanicError{Goroutine: goroutine, Value: v, Stack: debug.Stack()}
+	logger.Error("Recovered from a panic",
+		slog.String("goroutine", goroutine),
+		slog.Any("panic", v),
+		slog.String("stack", string(err.Stack)))
+
+	if release != nil {
+		release()
+	}
+	if report != nil {
+		report(err)
+	}
+}
+
+// recovered records a panic recovered in the call or voice path, which the
+// bridge survives, and tells Discord
+func (m *Machine) recovered(err error) {
+	m.errors.Report(err, SeverityTransient)
+	m.notify(NotificationTypeInfo, "Golte", fmt.Sprintf("⚠️ Recovered from an internal error, the call audio was reset: %v", err))
+}
+
+// abortCall hangs up and resets the IVR after a call handler panicked, so the
+// next call starts with the audio free
+func (m *ModemManager) abortCall() {
+	m.stopCallWatch()
+	m.state.Reset()
+	if m.playback != nil {
+		if n := m.playback.ClearQueue(); n > 0 {
+			m.logger.Info("Dropped queued prompts", slog.Int("count", n))
+		}
+	}
+	if c := m.callManager(); c != nil {
+		if err := c.HangUp(); err != nil {
+			m.logger.Error("Failed to hang up call", slog.Any("error", err))
+		}
+	}
+}
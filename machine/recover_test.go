@@ -0,0 +1,55 @@
+package machine
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRecoverPanicReleasesResources(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	released := false
+	reported := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer recoverPanic(logger, "voice", func() { released = true }, func(err error) { reported <- err })
+
+		panic("error connecting to voice channel")
+	}()
+	<-done
+
+	if !released {
+		t.Error("release wasn't called after the panic")
+	}
+	select {
+	case err := <-reported:
+		var p *PanicError
+		if !errors.As(err, &p) || p.Goroutine != "voice" {
+			t.Fatalf("reported %v, want a PanicError of voice", err)
+		}
+		if !strings.Contains(err.Error(), "error connecting to voice channel") {
+			t.Errorf("Error() = %q, want the panic value", err.Error())
+		}
+		if len(p.Stack) == 0 {
+			t.Error("PanicError has no stack")
+		}
+	default:
+		t.Error("the panic wasn't reported")
+	}
+}
+
+func TestRecoverPanicWithoutPanic(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	called := false
+	func() {
+		defer recoverPanic(logger, "voice", func() { called = true }, func(error) { called = true })
+	}()
+	if called {
+		t.Error("release or report called without a panic")
+	}
+}
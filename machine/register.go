@@ -0,0 +1,94 @@
+package machine
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/disgoorg/disgo/rest"
+)
+
+// Command registration defaults, for a discord.registration left at 0
+const (
+	defaultRegisterAttempts = 3
+	defaultRegisterTimeout  = 10 * time.Second
+)
+
+// Delays between registration attempts, doubling from the first
+const (
+	registerRetryDelay    = 5 * time.Second
+	maxRegisterRetryDelay = 5 * time.Minute
+)
+
+// registerCommands sets the slash commands, trying up to
+// discord.registration.attempts times
+func (d *DiscordManager) registerCommands(ctx context.Context) error {
+	cfg := d.config().Discord.Registration
+	attempts := cmp.Or(cfg.Attempts, defaultRegisterAttempts)
+	return retryWithBackoff(ctx, attempts, registerRetryDelay, d.setCommands, func(attempt int, delay time.Duration, err error) {
+		d.logger.Warn("Failed to register Discord commands, retrying",
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.Any("error", err))
+	})
+}
+
+// retryRegistration keeps registering the slash commands in the background
+// until it works or ctx is done, then tells the channel
+func (d *DiscordManager) retryRegistration(ctx context.Context) {
+	defer recoverPanic(d.logger, "command registration", nil, d.panicFunc)
+
+	tries := 0
+	err := retryWithBackoff(ctx, 0, registerRetryDelay, func(ctx context.Context) error {
+		tries++
+		return d.setCommands(ctx)
+	}, func(attempt int, delay time.Duration, err error) {
+		d.logger.Debug("Discord commands still not registered",
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.Any("error", err))
+	})
+	if err != nil {
+		return
+	}
+
+	d.logger.Info("Discord commands registered", slog.Int("attempts", tries))
+	if d.notifyFunc != nil {
+		d.notifyFunc(NotificationTypeInfo, "Discord", fmt.Sprintf("✅ Slash commands registered after %d more attempts, they're usable now", tries))
+	}
+}
+
+// setCommands sets the slash commands once, within
+// discord.registration.timeout
+func (d *DiscordManager) setCommands(ctx context.Context) error {
+	timeout := cmp.Or(d.config().Discord.Registration.Timeout, defaultRegisterTimeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, err := d.client.Rest().SetGlobalCommands(d.client.ApplicationID(), d.getCommands(), rest.WithCtx(ctx))
+	return err
+}
+
+// retryWithBackoff calls fn until it succeeds, attempts times or forever if
+// attempts is 0. The delay between attempts doubles from first up to
+// maxRegisterRetryDelay, onRetry is told before each wait. It returns the
+// last error of fn, or the context's once ctx is done.
+func retryWithBackoff(ctx context.Context, attempts int, first time.Duration, fn func(context.Context) error, onRetry func(attempt int, delay time.Duration, err error)) error {
+	delay := first
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || (attempts > 0 && attempt >= attempts) {
+			return err
+		}
+
+		onRetry(attempt, delay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxRegisterRetryDelay)
+	}
+}
@@ -0,0 +1,52 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryWithBackoff(t *testing.T) {
+	failing := errors.New("503 Service Unavailable")
+
+	t.Run("bounded", func(t *testing.T) {
+		calls := 0
+		var delays []time.Duration
+		err := retryWithBackoff(context.Background(), 3, time.Millisecond, func(context.Context) error {
+			calls++
+			return failing
+		}, func(_ int, delay time.Duration, _ error) {
+			delays = append(delays, delay)
+		})
+		if !errors.Is(err, failing) || calls != 3 {
+			t.Errorf("retryWithBackoff() = %v after %d calls, want the error after 3", err, calls)
+		}
+		if len(delays) != 2 || delays[1] != 2*delays[0] {
+			t.Errorf("delays = %v, want 2 doubling", delays)
+		}
+	})
+
+	t.Run("until it works", func(t *testing.T) {
+		calls := 0
+		err := retryWithBackoff(context.Background(), 0, time.Millisecond, func(context.Context) error {
+			if calls++; calls < 5 {
+				return failing
+			}
+			return nil
+		}, func(int, time.Duration, error) {})
+		if err != nil || calls != 5 {
+			t.Errorf("retryWithBackoff() = %v after %d calls, want success after 5", err, calls)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		err := retryWithBackoff(ctx, 0, time.Hour, func(context.Context) error {
+			return failing
+		}, func(int, time.Duration, error) { cancel() })
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("retryWithBackoff() = %v, want context.Canceled", err)
+		}
+	})
+}
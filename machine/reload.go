@@ -0,0 +1,101 @@
+package machine
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"golte/config"
+	"golte/logger"
+
+	"github.com/disgoorg/disgo/events"
+)
+
+// ReloadResult lists the configuration keys changed by a reload
+type ReloadResult struct {
+	Applied []string // in effect right away, or from the next call
+	Restart []string // kept at their old value until the bridge restarts
+}
+
+// Reload reads the configuration again and applies it, see Reconfigure
+func (m *Machine) Reload() (ReloadResult, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return ReloadResult{}, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return m.Reconfigure(cfg)
+}
+
+// Reconfigure applies the settings of cfg that can change at runtime, like
+// logging, contacts, routing and thresholds. Changes to settings only read at
+// startup, like the modem device or the Discord token, are logged and left
+// out.
+func (m *Machine) Reconfigure(cfg *config.Config) (ReloadResult, error) {
+	if err := cfg.Validate(); err != nil {
+		return ReloadResult{}, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	active := m.config()
+	var result ReloadResult
+	for _, key := range config.Diff(active, cfg) {
+		if config.RequiresRestart(key) {
+			result.Restart = append(result.Restart, key)
+		} else {
+			result.Applied = append(result.Applied, key)
+		}
+	}
+
+	for _, key := range result.Restart {
+		m.logger.Warn("Setting changed but requires a restart", slog.String("key", key))
+	}
+	if len(result.Applied) == 0 {
+		m.logger.Info("Configuration reloaded, nothing to apply")
+		return result, nil
+	}
+
+	next := config.Reloadable(active, cfg)
+	logger.SetLevel(next.Logging.Level)
+	logger.SetRecentSize(next.Logging.BufferSize)
+	logger.SetRedaction(next.Logging.Redaction)
+	if m.playback != nil {
+		m.playback.SetDuckDepth(next.Audio.DuckDepthDB)
+	}
+	m.modem.Reconfigure(next)
+	if m.discord != nil {
+		m.discord.Reconfigure(next)
+	}
+	if m.signalMonitor != nil {
+		m.signalMonitor.Reconfigure(next)
+	}
+	m.cfg.Store(next)
+
+	m.logger.Info("Configuration reloaded", slog.Any("applied", result.Applied))
+	return result, nil
+}
+
+// handleReload reloads the configuration file on an owner's request
+func (d *DiscordManager) handleReload(event *events.ApplicationCommandInteractionCreate) {
+	d.logger.Info("Received reload command from Discord", slog.String("user", event.User().Username))
+
+	result, err := d.reloadFunc()
+	if err != nil {
+		d.logger.Error("Failed to reload configuration", slog.Any("error", err))
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("Configuration has **not** been reloaded: %v", err))
+		return
+	}
+
+	var content strings.Builder
+	content.WriteString("🔄 Configuration reloaded")
+	if len(result.Applied) == 0 {
+		content.WriteString(", nothing changed")
+	} else {
+		fmt.Fprintf(&content, "\nApplied: `%s`", strings.Join(result.Applied, "`, `"))
+	}
+	if len(result.Restart) > 0 {
+		fmt.Fprintf(&content, "\nNeeds a restart: `%s`", strings.Join(result.Restart, "`, `"))
+	}
+	d.respondEphemeral(event.CreateMessage, content.String())
+}
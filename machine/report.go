@@ -0,0 +1,169 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/warthog618/modem/at"
+	"github.com/warthog618/modem/gsm"
+	"github.com/warthog618/sms"
+	"github.com/warthog618/sms/encoding/pdumode"
+	"github.com/warthog618/sms/encoding/tpdu"
+)
+
+// StatusReport is the delivery outcome of a sent SMS, matched to it by its
+// message reference
+type StatusReport struct {
+	Ref    int
+	Status byte // TP-ST, see 3GPP TS 23.040 9.2.3.15
+}
+
+// Delivered reports whether the SMS reached the recipient
+func (r StatusReport) Delivered() bool {
+	return r.Status < 0x20
+}
+
+// Final reports whether the SMSC gave up or completed, other statuses mean
+// it's still trying
+func (r StatusReport) Final() bool {
+	return r.Status < 0x20 || r.Status >= 0x40
+}
+
+func (r StatusReport) String() string {
+	switch {
+	case r.Delivered():
+		return "delivered"
+	case !r.Final():
+		return fmt.Sprintf("pending (status 0x%02x)", r.Status)
+	default:
+		return fmt.Sprintf("failed (status 0x%02x)", r.Status)
+	}
+}
+
+// statusReportRequest sets TP-SRR on the SMS-SUBMIT TPDUs, asking the SMSC
+// for a status report
+type statusReportRequest struct{}
+
+func (statusReportRequest) ApplyTPDUOption(t *tpdu.TPDU) error {
+	t.FirstOctet |= tpdu.FoSRR
+	return nil
+}
+
+// InitializeSender sets up the modem, or the SMPP bind, to send SMS only, without the call,
+// DTMF and SIM toolkit handlers of the bridge, for one-shot commands.
+// statusReports asks the SMSC for a status report of every SMS sent.
+func (m *ModemManager) InitializeSender(statusReports bool) error {
+	if m.mock != nil {
+		return nil
+	}
+	if m.smpp != nil {
+		if statusReports {
+			return errors.New("status reports need a GSM modem")
+		}
+		return m.smpp.Initialize()
+	}
+	if m.sms == nil {
+		m.sms = gsmTransport{modem: m}
+	}
+
+	a, err := m.open()
+	if err != nil {
+		return err
+	}
+
+	var options []gsm.Option
+	if statusReports {
+		options = append(options, gsm.WithEncoderOption(sms.WithTemplateOption(statusReportRequest{})))
+	}
+	g := gsm.New(a, options...)
+	if err := g.Init(); err != nil {
+		m.closePort()
+		return fmt.Errorf("failed to initialize modem: %w", err)
+	}
+
+	m.connMu.Lock()
+	m.gsm = g
+	m.connMu.Unlock()
+	return nil
+}
+
+// Close closes the modem's serial port, or unbinds from the SMSC
+func (m *ModemManager) Close() {
+	if m.smpp != nil {
+		m.smpp.StopMessageRx()
+	}
+	m.closePort()
+}
+
+// WatchStatusReports passes the status reports the modem receives to
+// handler. Incoming SMS are left in the modem's storage.
+func (m *ModemManager) WatchStatusReports(handler func(StatusReport)) error {
+	g := m.GSM()
+	if g == nil {
+		return ErrNoModem
+	}
+
+	err := g.AddIndication("+CDS:", func(info []string) {
+		report, err := parseStatusReport(info)
+		g.Command("+CNMA")
+		if err != nil {
+			m.logger.Warn("Failed to parse status report", slog.Any("error", err))
+			return
+		}
+		handler(report)
+	}, at.WithTrailingLine)
+	if err != nil {
+		return err
+	}
+
+	// Route status reports to +CDS indications, and only them
+	for _, cmd := range []string{"+CSMS=1", "+CNMI=1,0,0,1,0"} {
+		if _, err := g.Command(cmd); err != nil {
+			g.CancelIndication("+CDS:")
+			return fmt.Errorf("failed to enable status reports: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseStatusReport parses a +CDS indication, e.g. +CDS: 25 followed by the
+// PDU in hex
+func parseStatusReport(info []string) (StatusReport, error) {
+	if len(info) < 2 {
+		return StatusReport{}, errors.New("status report without a PDU")
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(info[0], "+CDS:")))
+	if err != nil {
+		return StatusReport{}, fmt.Errorf("invalid status report length: %w", err)
+	}
+	pdu, err := pdumode.UnmarshalHexString(strings.TrimSpace(info[1]))
+	if err != nil {
+		return StatusReport{}, err
+	}
+	if length != len(pdu.TPDU) {
+		return StatusReport{}, fmt.Errorf("status report length mismatch, expected %d, got %d", length, len(pdu.TPDU))
+	}
+
+	var tp tpdu.TPDU
+	if err := tp.UnmarshalBinary(pdu.TPDU); err != nil {
+		return StatusReport{}, err
+	}
+	if tp.SmsType() != tpdu.SmsStatusReport {
+		return StatusReport{}, fmt.Errorf("expected a status report, got %s", tp.SmsType())
+	}
+	return StatusReport{Ref: int(tp.MR), Status: tp.ST}, nil
+}
+
+// parseMessageRefs parses the message references returned when sending
+func parseMessageRefs(raw []string) []int {
+	refs := make([]int, 0, len(raw))
+	for _, r := range raw {
+		if ref, err := strconv.Atoi(strings.TrimSpace(r)); err == nil {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
@@ -0,0 +1,55 @@
+package machine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/warthog618/sms/encoding/pdumode"
+	"github.com/warthog618/sms/encoding/tpdu"
+)
+
+func statusReportIndication(t *testing.T, mr, st byte) []string {
+	t.Helper()
+
+	tp := tpdu.TPDU{MR: mr, ST: st, RA: tpdu.NewAddress(tpdu.FromNumber("+33612345678"))}
+	if err := tpdu.SmsStatusReport.ApplyTPDUOption(&tp); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := tp.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdu := pdumode.PDU{TPDU: raw}
+	hex, err := pdu.MarshalHexString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []string{fmt.Sprintf("+CDS: %d", len(raw)), hex}
+}
+
+func TestParseStatusReport(t *testing.T) {
+	for _, tc := range []struct {
+		st               byte
+		delivered, final bool
+	}{
+		{0x00, true, true},
+		{0x30, false, false}, // SME busy, still trying
+		{0x41, false, true},  // incompatible destination
+		{0x62, false, true},  // no response from SME, gave up
+	} {
+		report, err := parseStatusReport(statusReportIndication(t, 42, tc.st))
+		if err != nil {
+			t.Fatalf("parseStatusReport(0x%02x) = %v", tc.st, err)
+		}
+		if report.Ref != 42 || report.Status != tc.st {
+			t.Errorf("parseStatusReport() = %+v", report)
+		}
+		if report.Delivered() != tc.delivered || report.Final() != tc.final {
+			t.Errorf("status 0x%02x: delivered %t, final %t", tc.st, report.Delivered(), report.Final())
+		}
+	}
+
+	if _, err := parseStatusReport([]string{"+CDS: 3", "00"}); err == nil {
+		t.Error("parseStatusReport() should fail on a length mismatch")
+	}
+}
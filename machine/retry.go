@@ -0,0 +1,103 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/warthog618/modem/at"
+)
+
+// recoveryWait bounds how long a command waits for the watcher to restore
+// the link, Reconnect may retry opening the port for a while
+const recoveryWait = reconnectAttempts*reconnectDelay + 30*time.Second
+
+// isDeadLink reports whether err means the modem didn't answer at all, as
+// opposed to answering ERROR
+func isDeadLink(err error) bool {
+	return errors.Is(err, at.ErrDeadlineExceeded)
+}
+
+// withRecovery runs a critical command. When the modem doesn't answer it and
+// doesn't answer a bare AT either, the link is recovered and the command run
+// again, up to modem.command_retries times. Commands the modem rejects are
+// not retried, nor those timing out while the modem still answers AT, as
+// they may have taken effect.
+func (m *ModemManager) withRecovery(command string, fn func() error) error {
+	err := fn()
+	for attempt := 1; attempt <= m.config().Modem.CommandRetries && isDeadLink(err); attempt++ {
+		if probeErr := m.probe(); probeErr == nil {
+			m.logger.Warn("Modem timed out on a command but still answers, not retrying",
+				slog.String("command", command),
+				slog.Any("error", err))
+			return err
+		}
+
+		m.logger.Warn("Modem not responding, recovering the link",
+			slog.String("command", command),
+			slog.Int("attempt", attempt))
+		if recoverErr := m.recoverLink(); recoverErr != nil {
+			return fmt.Errorf("%w, and the modem did not recover: %v", err, recoverErr)
+		}
+		err = fn()
+	}
+	return err
+}
+
+// probe checks the modem answers a bare AT
+func (m *ModemManager) probe() error {
+	g := m.GSM()
+	if g == nil {
+		return ErrNoModem
+	}
+	_, err := g.Command("", at.WithTimeout(pingTimeout))
+	return err
+}
+
+// recoverLink drops the serial link and waits until it's restored. The
+// machine's watcher reconnects when the link drops, without one Reconnect
+// is called here.
+func (m *ModemManager) recoverLink() error {
+	if !m.linkWatched.Load() {
+		_, err := m.Reconnect()
+		return err
+	}
+
+	done := m.recovered()
+	m.closePort()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(recoveryWait):
+		return fmt.Errorf("link not restored within %s", recoveryWait)
+	}
+}
+
+// WatchLink tells the manager that the caller reconnects whenever Closed
+// fires, so recoverLink leaves it to them
+func (m *ModemManager) WatchLink() {
+	m.linkWatched.Store(true)
+}
+
+// recovered returns a channel closed by the next successful Reconnect
+func (m *ModemManager) recovered() <-chan struct{} {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+
+	if m.recoveredCh == nil {
+		m.recoveredCh = make(chan struct{})
+	}
+	return m.recoveredCh
+}
+
+// signalRecovered wakes the commands waiting in recoverLink
+func (m *ModemManager) signalRecovered() {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+
+	if m.recoveredCh != nil {
+		close(m.recoveredCh)
+		m.recoveredCh = nil
+	}
+}
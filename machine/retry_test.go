@@ -0,0 +1,60 @@
+package machine
+
+import (
+	"errors"
+	"testing"
+
+	"golte/config"
+
+	"github.com/warthog618/modem/at"
+)
+
+// recoveringPort stands for the serial port, closing it acts as the watcher
+// restoring the link
+type recoveringPort struct {
+	m *ModemManager
+}
+
+func (p recoveringPort) Close() error {
+	go p.m.signalRecovered()
+	return nil
+}
+
+func TestWithRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		retries int
+		errs    []error
+		calls   int
+		wantErr error
+	}{
+		{"success", 1, []error{nil}, 1, nil},
+		{"modem answered ERROR", 1, []error{at.ErrError, nil}, 1, at.ErrError},
+		{"dead link recovered", 1, []error{at.ErrDeadlineExceeded, nil}, 2, nil},
+		{"still dead after the retry", 1, []error{at.ErrDeadlineExceeded, at.ErrDeadlineExceeded, nil}, 2, at.ErrDeadlineExceeded},
+		{"retries disabled", 0, []error{at.ErrDeadlineExceeded, nil}, 1, at.ErrDeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Modem: config.ModemConfig{CommandRetries: tt.retries}}
+			m := NewModemManager(cfg, nil, nil, nil, nil)
+			m.WatchLink()
+
+			calls := 0
+			err := m.withRecovery("test", func() error {
+				// The probe fails without a modem, so every timeout drops
+				// the link
+				m.port = recoveringPort{m}
+				calls++
+				return tt.errs[calls-1]
+			})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("withRecovery() = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.calls {
+				t.Errorf("command ran %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package machine
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golte/atresp"
+
+	"github.com/warthog618/modem/gsm"
+)
+
+// ErrOwnNumberUnknown is returned by OwnNumber when neither the
+// configuration nor the SIM know the number
+var ErrOwnNumberUnknown = errors.New("the SIM's own number is unknown, set modem.own_number")
+
+// ErrLoopbackTimeout is returned by LoopbackSMS when the SMS didn't come back
+var ErrLoopbackTimeout = errors.New("the SMS did not come back in time")
+
+// loopbackPrefix starts the text of loopback SMS, followed by a random token
+const loopbackPrefix = "golte selftest "
+
+// OwnNumber returns the SIM's phone number, modem.own_number if set or the
+// first number from AT+CNUM
+func (m *ModemManager) OwnNumber() (string, error) {
+	if number := m.config().Modem.OwnNumber; number != "" {
+		return number, nil
+	}
+	if m.GSM() == nil {
+		return "", ErrNoModem
+	}
+
+	response, err := m.GSM().Command("+CNUM")
+	if err != nil {
+		// Most SIMs don't store their number, modems answer with an error
+		return "", ErrOwnNumberUnknown
+	}
+	number, ok := parseOwnNumber(response, m.charset() == "UCS2")
+	if !ok {
+		return "", ErrOwnNumberUnknown
+	}
+	return number, nil
+}
+
+// LoopbackSMS sends an SMS to number, the SIM's own, and waits for it to come
+// back, returning the round trip time. The modem must be set up with
+// InitializeSender, other SMS received meanwhile are logged.
+func (m *ModemManager) LoopbackSMS(number string, timeout time.Duration) (time.Duration, error) {
+	token, err := loopbackToken()
+	if err != nil {
+		return 0, err
+	}
+	text := loopbackPrefix + token
+
+	received := make(chan struct{}, 1)
+	rxErrors := make(chan error, 1)
+	err = m.StartMessageReception(func(msg gsm.Message) {
+		if isLoopback(msg.Message, token) {
+			select {
+			case received <- struct{}{}:
+			default:
+			}
+			return
+		}
+		m.logger.Warn("Received another SMS during the selftest",
+			slog.String("from", msg.Number),
+			slog.String("message", msg.Message))
+	}, func(err error) {
+		select {
+		case rxErrors <- err:
+		default:
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer m.StopMessageReception()
+
+	start := time.Now()
+	if _, err := m.SubmitSMS(number, text); err != nil {
+		return 0, fmt.Errorf("failed to send the SMS: %w", err)
+	}
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case <-received:
+			return time.Since(start), nil
+		case err := <-rxErrors:
+			// Errors on unrelated SMS don't fail the test, the loopback
+			// one may still come
+			m.logger.Warn("Failed to receive an SMS", slog.Any("error", err))
+		case <-deadline:
+			return 0, ErrLoopbackTimeout
+		}
+	}
+}
+
+// loopbackToken returns a random token identifying a loopback SMS
+func loopbackToken() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isLoopback reports whether an SMS text is the loopback SMS with token.
+// Some networks append a signature, so only the start is compared.
+func isLoopback(text, token string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), loopbackPrefix+token)
+}
+
+// parseOwnNumber parses the answer to AT+CNUM, e.g.
+// +CNUM: "Mine","+33612345678",145, into its first number. The number is
+// hex encoded UCS2 when the character set is UCS2.
+func parseOwnNumber(response []string, ucs2Strings bool) (string, bool) {
+	for _, fields := range atresp.FindAll(response, "+CNUM") {
+		if len(fields) < 2 || fields[1] == "" {
+			continue
+		}
+		number := fields[1]
+		if ucs2Strings {
+			if decoded, ok := decodeUCS2Hex(number); ok {
+				number = decoded
+			}
+		}
+		return number, true
+	}
+	return "", false
+}
@@ -0,0 +1,39 @@
+package machine
+
+import "testing"
+
+func TestParseOwnNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		response []string
+		ucs2     bool
+		want     string
+		ok       bool
+	}{
+		{"named", []string{`+CNUM: "Mine","+33612345678",145`}, false, "+33612345678", true},
+		{"unnamed", []string{`+CNUM: ,"0612345678",129`}, false, "0612345678", true},
+		{"ucs2", []string{`+CNUM: "","002B00330033003600310032",145`}, true, "+33612", true},
+		{"empty first", []string{`+CNUM: "","",129`, `+CNUM: "","+33612345678",145`}, false, "+33612345678", true},
+		{"none", []string{}, false, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseOwnNumber(tt.response, tt.ucs2)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseOwnNumber() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	if !isLoopback("golte selftest 0a1b2c3d", "0a1b2c3d") {
+		t.Error("the loopback SMS was not recognized")
+	}
+	if !isLoopback("golte selftest 0a1b2c3d\n-- Sent by MyOperator", "0a1b2c3d") {
+		t.Error("a network signature should be ignored")
+	}
+	if isLoopback("golte selftest 00000000", "0a1b2c3d") {
+		t.Error("an earlier selftest SMS was taken for this one")
+	}
+}
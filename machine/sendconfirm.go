@@ -0,0 +1,141 @@
+package machine
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/warthog618/sms"
+)
+
+// pendingSendLimit is how many oversized /send messages wait for
+// confirmation at once, the oldest is dropped beyond it
+const pendingSendLimit = 20
+
+// smsSegments returns how many SMS message is sent as, after the
+// transliteration SubmitSMS applies when transliterate is set
+func smsSegments(message string, transliterate bool) int {
+	if transliterate {
+		message, _ = transliterateGSM7(message)
+	}
+	tpdus, err := sms.Encode([]byte(message))
+	if err != nil || len(tpdus) == 0 {
+		return 1
+	}
+	return len(tpdus)
+}
+
+// pendingSend is an oversized /send message waiting for confirmation, the
+// prompt is ephemeral so only its author can confirm it
+type pendingSend struct {
+	number  string
+	message string
+}
+
+// pendingSends keeps the messages behind confirmation buttons, the button's
+// custom ID is too short to carry them
+type pendingSends struct {
+	mu    sync.Mutex
+	next  int
+	order []int
+	sends map[int]pendingSend
+}
+
+// add stores p and returns the ID to take it back with
+func (s *pendingSends) add(p pendingSend) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sends == nil {
+		s.sends = make(map[int]pendingSend)
+	}
+	s.next++
+	s.sends[s.next] = p
+	s.order = append(s.order, s.next)
+	if len(s.order) > pendingSendLimit {
+		delete(s.sends, s.order[0])
+		s.order = s.order[1:]
+	}
+	return s.next
+}
+
+// take removes and returns the message stored under id, so it's sent at
+// most once
+func (s *pendingSends) take(id int) (pendingSend, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.sends[id]
+	delete(s.sends, id)
+	return p, ok
+}
+
+// confirmLongSMS asks for confirmation instead of sending a message needing
+// more than modem.max_sms_segments SMS. It reports whether it did.
+func (d *DiscordManager) confirmLongSMS(event *events.ApplicationCommandInteractionCreate, number, message string) bool {
+	limit := d.config().Modem.MaxSMSSegments
+	if limit <= 0 {
+		return false
+	}
+	segments := smsSegments(message, d.config().Modem.TransliterateOutbound)
+	if segments <= limit {
+		return false
+	}
+
+	d.logger.Info("SMS above the segment limit, asking for confirmation",
+		slog.String("number", number),
+		slog.Int("segments", segments),
+		slog.Int("limit", limit))
+
+	id := strconv.Itoa(d.pendingSends.add(pendingSend{number: number, message: message}))
+	err := event.CreateMessage(discord.NewMessageCreateBuilder().
+		SetContentf("✉️ This message is %d characters long and will be sent as **%d SMS** to %s, each billed as one (the limit is %d). Send it anyway?",
+			len([]rune(message)), segments, number, limit).
+		AddActionRow(
+			discord.NewPrimaryButton(fmt.Sprintf("Send %d SMS", segments), "sendlong:confirm:"+id),
+			discord.NewSecondaryButton("Cancel", "sendlong:cancel:"+id),
+		).
+		SetEphemeral(true).
+		Build())
+	if err != nil {
+		d.logger.Error("Failed to send Discord response", slog.Any("error", err))
+	}
+	return true
+}
+
+// handleLongSMSConfirm sends or drops an oversized message once confirmed
+func (d *DiscordManager) handleLongSMSConfirm(event *events.ComponentInteractionCreate, data string) {
+	action, rawID, _ := strings.Cut(data, ":")
+	id, _ := strconv.Atoi(rawID)
+	update := discord.NewMessageUpdateBuilder().ClearContainerComponents()
+
+	p, ok := d.pendingSends.take(id)
+	sent := false
+	switch {
+	case !ok:
+		update.SetContent("This message is no longer waiting, send it again with /send.")
+	case action != "confirm":
+		update.SetContent("Cancelled, no SMS were sent.")
+	default:
+		if err := d.smsFunc(p.number, p.message); err != nil {
+			d.logger.Error("Failed to send SMS via Discord command",
+				slog.String("number", p.number),
+				slog.Any("error", err))
+			update.SetContentf("SMS has **not** been sent: %v", err)
+		} else {
+			update.SetContent("SMS Sent!")
+			sent = true
+		}
+	}
+
+	if err := event.UpdateMessage(update.Build()); err != nil {
+		d.logger.Error("Failed to send Discord response", slog.Any("error", err))
+	}
+	if sent {
+		d.logOutbound(event.User().ID, p.number, p.message)
+	}
+}
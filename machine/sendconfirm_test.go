@@ -0,0 +1,41 @@
+package machine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSMSSegments(t *testing.T) {
+	tests := []struct {
+		name          string
+		message       string
+		transliterate bool
+		want          int
+	}{
+		{"single gsm7", strings.Repeat("a", 160), false, 1},
+		{"long gsm7", strings.Repeat("a", 161), false, 2},
+		{"single ucs2", strings.Repeat("ж", 70), false, 1},
+		{"long ucs2", strings.Repeat("ж", 71), false, 2},
+		{"smart quotes as ucs2", strings.Repeat("’", 160), false, 3},
+		{"smart quotes transliterated", strings.Repeat("’", 160), true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := smsSegments(tt.message, tt.transliterate); got != tt.want {
+				t.Errorf("smsSegments() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPendingSendsTakeOnce(t *testing.T) {
+	var s pendingSends
+	id := s.add(pendingSend{number: "+33612345678", message: "hi"})
+	if p, ok := s.take(id); !ok || p.message != "hi" {
+		t.Fatalf("take() = %+v, %v", p, ok)
+	}
+	if _, ok := s.take(id); ok {
+		t.Error("a pending send should only be taken once")
+	}
+}
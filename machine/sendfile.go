@@ -0,0 +1,125 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+)
+
+// maxSendFileSize is the largest attachment /sendfile accepts. The file itself
+// is never sent over the air, this keeps recipients on metered data in mind.
+const maxSendFileSize = 25 << 20
+
+// sendFileTypes are the attachment content types /sendfile accepts
+var sendFileTypes = []string{"image/", "audio/", "video/"}
+
+// validateAttachment checks an attachment is small enough and a media file
+func validateAttachment(attachment discord.Attachment) error {
+	if attachment.Size > maxSendFileSize {
+		return fmt.Errorf("%s is too large (%d MB max)", attachment.Filename, maxSendFileSize>>20)
+	}
+
+	if attachment.ContentType == nil {
+		return fmt.Errorf("%s has an unknown type", attachment.Filename)
+	}
+	for _, prefix := range sendFileTypes {
+		if strings.HasPrefix(*attachment.ContentType, prefix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is a %s file, only images, audio and video can be sent", attachment.Filename, *attachment.ContentType)
+}
+
+// shortenURL shortens link with a plain-text shortener service whose URL
+// template contains a single %s for the escaped link
+func shortenURL(ctx context.Context, template, link string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(template, url.QueryEscape(link)), nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid shortener URL: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("shortener request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 2048))
+	if err != nil {
+		return "", fmt.Errorf("failed to read shortener response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("shortener returned %s", resp.Status)
+	}
+
+	short := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(short, "http://") && !strings.HasPrefix(short, "https://") {
+		return "", fmt.Errorf("shortener returned an unexpected response: %q", short)
+	}
+	return short, nil
+}
+
+// handleSendFile texts the link of a Discord attachment to a phone number.
+// The file itself isn't sent: MMS needs the vendor commands' binary upload,
+// which the AT layer can't stream, and is left out until it does.
+func (d *DiscordManager) handleSendFile(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) {
+	phoneNumber := data.String("number")
+	attachment := data.Attachment("file")
+
+	d.logger.Info("Received sendfile command from Discord",
+		slog.String("number", phoneNumber),
+		slog.String("file", attachment.Filename),
+		slog.Int("size", attachment.Size),
+		slog.String("user", event.User().Username))
+
+	if err := validateAttachment(attachment); err != nil {
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("File has **not** been sent: %v", err))
+		return
+	}
+
+	link := attachment.URL
+	shorten, ok := data.OptBool("shorten")
+	if !ok {
+		shorten = d.config().Discord.ShortenerURL != ""
+	}
+	if shorten {
+		if d.config().Discord.ShortenerURL == "" {
+			d.respondEphemeral(event.CreateMessage, "File has **not** been sent: no link shortener is configured")
+			return
+		}
+		short, err := shortenURL(context.Background(), d.config().Discord.ShortenerURL, link)
+		if err != nil {
+			d.logger.Warn("Failed to shorten attachment link, sending it in full", slog.Any("error", err))
+		} else {
+			link = short
+		}
+	}
+
+	message := link
+	if text, ok := data.OptString("message"); ok && text != "" {
+		message = text + "\n" + link
+	}
+
+	if err := d.smsFunc(phoneNumber, message); err != nil {
+		d.logger.Error("Failed to send file link via Discord command",
+			slog.String("number", phoneNumber),
+			slog.Any("error", err))
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("File has **not** been sent: %v", err))
+		return
+	}
+
+	d.respondEphemeral(event.CreateMessage, fmt.Sprintf("📎 Link to %s sent!", attachment.Filename))
+
+	d.logOutbound(event.User().ID, phoneNumber, message)
+}
@@ -0,0 +1,58 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+)
+
+func TestValidateAttachment(t *testing.T) {
+	contentType := func(s string) *string { return &s }
+
+	tests := []struct {
+		name       string
+		attachment discord.Attachment
+		wantErr    bool
+	}{
+		{"image", discord.Attachment{Filename: "a.png", ContentType: contentType("image/png"), Size: 1 << 20}, false},
+		{"audio", discord.Attachment{Filename: "a.ogg", ContentType: contentType("audio/ogg"), Size: 1 << 20}, false},
+		{"too large", discord.Attachment{Filename: "a.png", ContentType: contentType("image/png"), Size: maxSendFileSize + 1}, true},
+		{"document", discord.Attachment{Filename: "a.pdf", ContentType: contentType("application/pdf"), Size: 1 << 10}, true},
+		{"unknown type", discord.Attachment{Filename: "a", Size: 1 << 10}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateAttachment(tt.attachment); (err != nil) != tt.wantErr {
+				t.Errorf("validateAttachment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestShortenURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("url") != "https://cdn.example/a.png?ex=1&hm=2" {
+			http.Error(w, "bad url", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, "https://short.example/x")
+	}))
+	defer server.Close()
+
+	short, err := shortenURL(context.Background(), server.URL+"/?url=%s", "https://cdn.example/a.png?ex=1&hm=2")
+	if err != nil {
+		t.Fatalf("shortenURL() error = %v", err)
+	}
+	if short != "https://short.example/x" {
+		t.Errorf("shortenURL() = %q", short)
+	}
+
+	if _, err := shortenURL(context.Background(), server.URL+"/?url=%s", "https://other.example"); err == nil {
+		t.Error("expected an error when the shortener rejects the link")
+	}
+}
@@ -0,0 +1,280 @@
+package machine
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golte/atresp"
+
+	"github.com/warthog618/modem/at"
+	"github.com/warthog618/modem/info"
+)
+
+// SignalQuality is the signal as the modem reports it, AT+CSQ on every modem
+// and AT+CESQ or AT+QCSQ on LTE modems having them
+type SignalQuality struct {
+	CSQ  int    // RSSI as reported by AT+CSQ, 0 to 31, 99 if unknown
+	BER  int    // bit error rate, 0 to 7, 99 if unknown
+	Tech string // access technology of AT+COPS?, e.g. LTE, empty if not queried
+
+	// From AT+CESQ or AT+QCSQ, nil unless the modem has them and is on LTE
+	RSRP *int     // reference signal received power, in dBm
+	RSRQ *float64 // reference signal received quality, in dB
+	SINR *float64 // signal to interference plus noise ratio, in dB, AT+QCSQ only
+}
+
+// DBm returns the RSSI in dBm, false if it's unknown
+func (q SignalQuality) DBm() (int, bool) {
+	if q.CSQ < 0 || q.CSQ > 31 {
+		return 0, false
+	}
+	return -113 + 2*q.CSQ, true
+}
+
+// Lost reports whether no signal is detected. LTE modems may leave the RSSI
+// unknown, a reference signal power still counts as signal.
+func (q SignalQuality) Lost() bool {
+	_, ok := q.DBm()
+	return !ok && q.RSRP == nil
+}
+
+// cesqUnknown is the value of the AT+CESQ fields the modem doesn't know
+const cesqUnknown = 255
+
+// ParseSignalQuality reads the +CSQ and +CESQ lines of a response, e.g.
+// +CSQ: 20,99 and +CESQ: 99,99,255,255,20,45. It reports false if it has
+// neither.
+func ParseSignalQuality(response []string) (SignalQuality, bool) {
+	q := SignalQuality{CSQ: 99, BER: 99}
+	found := false
+	if fields, ok := atresp.Find(response, "+CSQ"); ok {
+		csq, ok := fields.Int(0)
+		if !ok {
+			return SignalQuality{}, false
+		}
+		q.CSQ, found = csq, true
+		if n, ok := fields.Int(1); ok {
+			q.BER = n
+		}
+	}
+	// <rxlev>,<ber>,<rscp>,<ecno>,<rsrq>,<rsrp>
+	if fields, ok := atresp.Find(response, "+CESQ"); ok && len(fields) == 6 {
+		values, ok := fields.Ints()
+		if !ok {
+			return SignalQuality{}, false
+		}
+		found = true
+		if rsrq := values[4]; rsrq != cesqUnknown && rsrq >= 0 && rsrq <= 34 {
+			db := -20 + float64(rsrq)/2
+			q.RSRQ = &db
+		}
+		if rsrp := values[5]; rsrp != cesqUnknown && rsrp >= 0 && rsrp <= 97 {
+			dbm := -141 + rsrp
+			q.RSRP = &dbm
+		}
+	}
+	return q, found
+}
+
+// lteTechnologies are the access technologies with LTE metrics
+var lteTechnologies = []string{"LTE", "NB-IoT", "LTE+NR"}
+
+// LTE reports whether the modem is on an LTE network, as AT+COPS? said
+func (q SignalQuality) LTE() bool {
+	return slices.Contains(lteTechnologies, q.Tech)
+}
+
+// String describes the LTE metrics, or the RSSI without them, e.g.
+// RSRP -96 dBm, RSRQ -10 dB, SINR 13 dB
+func (q SignalQuality) String() string {
+	if q.RSRP == nil {
+		return formatSignal(strconv.Itoa(q.CSQ))
+	}
+	s := fmt.Sprintf("RSRP %d dBm", *q.RSRP)
+	if q.RSRQ != nil {
+		s += fmt.Sprintf(", RSRQ %s dB", strconv.FormatFloat(*q.RSRQ, 'f', -1, 64))
+	}
+	if q.SINR != nil {
+		s += fmt.Sprintf(", SINR %s dB", strconv.FormatFloat(*q.SINR, 'f', -1, 64))
+	}
+	return s
+}
+
+// qcsqModes are the system modes of AT+QCSQ answering with LTE metrics
+var qcsqModes = []string{"LTE", "CAT-M1", "CAT-NB1"}
+
+// parseQCSQ reads the LTE metrics of an AT+QCSQ answer into q, e.g.
+// +QCSQ: "LTE",-52,-81,195,-10 for the RSSI, RSRP, SINR and RSRQ. The SINR
+// is in fifths of a dB from -20 dB, as EC2x modems give it. It reports
+// false on other system modes.
+func parseQCSQ(response []string, q *SignalQuality) bool {
+	fields, ok := atresp.Find(response, "+QCSQ")
+	if !ok || len(fields) != 5 || !slices.Contains(qcsqModes, fields[0]) {
+		return false
+	}
+	values, ok := atresp.Fields(fields[1:]).Ints()
+	if !ok {
+		return false
+	}
+	rsrp := values[1]
+	db, quality := float64(values[2])/5-20, float64(values[3])
+	q.RSRP, q.SINR, q.RSRQ = &rsrp, &db, &quality
+	return true
+}
+
+// accessTechnology returns the access technology of an AT+COPS? answer,
+// empty if it has none
+func accessTechnology(response []string) string {
+	fields, _ := atresp.Find(response, "+COPS?")
+	return accessTechnologies[fields.String(3)]
+}
+
+// GetLTESignal queries the signal with the LTE metrics when AT+COPS? says
+// the modem is on LTE: RSRP and RSRQ from AT+CESQ, or along the SINR from
+// AT+QCSQ on Quectel modems. Other networks and modems lacking both get the
+// AT+CSQ signal only.
+func (m *ModemManager) GetLTESignal() (SignalQuality, error) {
+	if m.mock != nil {
+		lines, _ := m.mock.SignalQuality()
+		q, _ := ParseSignalQuality(lines)
+		return q, nil
+	}
+	g := m.GSM()
+	if g == nil {
+		return SignalQuality{}, ErrNoModem
+	}
+	timeout := at.WithTimeout(modemQueryTimeout(m.config().Modem))
+
+	response, err := g.Command("+CSQ", timeout)
+	if err != nil {
+		return SignalQuality{}, err
+	}
+	q, ok := ParseSignalQuality(response)
+	if !ok {
+		return SignalQuality{}, fmt.Errorf("unexpected answer to AT+CSQ: %q", response)
+	}
+	if response, err := g.Command("+COPS?", timeout); err == nil {
+		q.Tech = accessTechnology(response)
+	}
+	if !q.LTE() {
+		return q, nil
+	}
+
+	caps := m.capabilities()
+	if caps.QCSQ {
+		if response, err := g.Command("+QCSQ", timeout); err == nil && parseQCSQ(response, &q) {
+			return q, nil
+		}
+	}
+	if caps.CESQ {
+		if response, err := g.Command("+CESQ", timeout); err == nil {
+			lte, _ := ParseSignalQuality(response)
+			q.RSRP, q.RSRQ = lte.RSRP, lte.RSRQ
+		}
+	}
+	return q, nil
+}
+
+// signalIndicators are the names AT+CIND gives the signal strength
+// indicator
+var signalIndicators = []string{"signal", "rssi"}
+
+// indicatorName matches the names in an AT+CIND=? answer, e.g.
+// +CIND: ("battchg",(0-5)),("signal",(0-5)),("service",(0,1))
+var indicatorName = regexp.MustCompile(`\(\s*"([^"]+)"`)
+
+// signalIndicator returns the 1-based index AT+CIND=? gives the signal
+// strength, 0 if it has none
+func signalIndicator(response []string) int {
+	for _, line := range response {
+		if !info.HasPrefix(line, "+CIND") {
+			continue
+		}
+		for i, match := range indicatorName.FindAllStringSubmatch(line, -1) {
+			if slices.Contains(signalIndicators, strings.ToLower(match[1])) {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// isSignalEvent reports whether a +CIEV indication is about the indicator
+// at index, e.g. +CIEV: 2,4. Some modems name the indicator instead.
+func isSignalEvent(line string, index int) bool {
+	indicator, _, _ := strings.Cut(info.TrimPrefix(line, "+CIEV"), ",")
+	indicator = strings.TrimSpace(indicator)
+	if name, err := strconv.Unquote(indicator); err == nil {
+		return slices.Contains(signalIndicators, strings.ToLower(name))
+	}
+	n, err := strconv.Atoi(indicator)
+	return err == nil && n == index
+}
+
+// enableSignalReports asks the modem of the session a to tell when its
+// signal changes, when monitor.signal_reports is on. Quectel modems report
+// the CSQ with +QIND, others the signal indicator of AT+CMER with +CIEV.
+// The monitor keeps polling modems having neither.
+func (m *ModemManager) enableSignalReports(a *at.AT) {
+	m.signalReporting.Store(false)
+	if !m.config().Monitor.SignalReports {
+		return
+	}
+	report := func() {
+		select {
+		case m.signalReports <- struct{}{}:
+		default:
+		}
+	}
+	timeout := at.WithTimeout(capabilityProbeTimeout)
+
+	err := a.AddIndication("+QIND:", func(info []string) {
+		if strings.Contains(info[0], `"csq"`) {
+			report()
+		}
+	})
+	if err == nil {
+		if _, err = a.Command(`+QINDCFG="csq",1,0`, timeout); err == nil {
+			m.signalReporting.Store(true)
+			m.logger.Info("The modem reports signal changes", slog.String("indication", "+QIND"))
+			return
+		}
+		a.CancelIndication("+QIND:")
+	}
+
+	response, err := a.Command("+CIND=?", timeout)
+	index := signalIndicator(response)
+	if err == nil && index > 0 {
+		err = a.AddIndication("+CIEV:", func(info []string) {
+			if isSignalEvent(info[0], index) {
+				report()
+			}
+		})
+		if err == nil {
+			// Indicator events only, buffered while the link is busy
+			if _, err = a.Command("+CMER=3,0,0,1", timeout); err == nil {
+				m.signalReporting.Store(true)
+				m.logger.Info("The modem reports signal changes", slog.String("indication", "+CIEV"))
+				return
+			}
+			a.CancelIndication("+CIEV:")
+		}
+	}
+	m.logger.Warn("The modem doesn't report signal changes, polling it instead")
+}
+
+// SignalReporting reports whether the modem tells when its signal changes,
+// sparing the monitor from polling it
+func (m *ModemManager) SignalReporting() bool {
+	return m.signalReporting.Load()
+}
+
+// SignalReports returns a channel receiving a value when the modem reported
+// a signal change. Reports coming while one is pending are merged.
+func (m *ModemManager) SignalReports() <-chan struct{} {
+	return m.signalReports
+}
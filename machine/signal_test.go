@@ -0,0 +1,108 @@
+package machine
+
+import "testing"
+
+func TestParseSignalQuality(t *testing.T) {
+	q, ok := ParseSignalQuality([]string{"+CSQ: 20,99", "+CESQ: 99,99,255,255,20,45"})
+	if !ok || q.CSQ != 20 || q.BER != 99 || q.RSRP == nil || *q.RSRP != -96 || q.RSRQ == nil || *q.RSRQ != -10 {
+		t.Fatalf("ParseSignalQuality() = %+v, %v", q, ok)
+	}
+	if dbm, ok := q.DBm(); !ok || dbm != -73 || q.Lost() {
+		t.Errorf("DBm() = %d, %v, lost %v", dbm, ok, q.Lost())
+	}
+
+	// An LTE modem leaving the RSSI unknown still has signal
+	q, _ = ParseSignalQuality([]string{"+CSQ: 99,99", "+CESQ: 99,99,255,255,18,40"})
+	if q.Lost() {
+		t.Errorf("Lost() = true with RSRP %v", q.RSRP)
+	}
+	q, _ = ParseSignalQuality([]string{"+CSQ: 99,99", "+CESQ: 99,99,255,255,255,255"})
+	if !q.Lost() || q.RSRP != nil || q.RSRQ != nil {
+		t.Errorf("unknown CESQ = %+v", q)
+	}
+
+	if _, ok := ParseSignalQuality([]string{"OK"}); ok {
+		t.Error("ParseSignalQuality() read a response without signal")
+	}
+	if _, ok := ParseSignalQuality([]string{"+CSQ: x,99"}); ok {
+		t.Error("ParseSignalQuality() read an invalid RSSI")
+	}
+}
+
+func TestSignalIndicator(t *testing.T) {
+	response := []string{`+CIND: ("battchg",(0-5)),("signal",(0-5)),("service",(0,1)),("call",(0,1))`}
+	index := signalIndicator(response)
+	if index != 2 {
+		t.Fatalf("signalIndicator() = %d, want 2", index)
+	}
+	if got := signalIndicator([]string{`+CIND: ("service",(0,1))`}); got != 0 {
+		t.Errorf("signalIndicator() without signal = %d", got)
+	}
+
+	for line, want := range map[string]bool{
+		"+CIEV: 2,4":        true,
+		"+CIEV: 3,1":        false,
+		`+CIEV: "SIGNAL",3`: true,
+		`+CIEV: "call",1`:   false,
+	} {
+		if got := isSignalEvent(line, index); got != want {
+			t.Errorf("isSignalEvent(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func TestParseQCSQ(t *testing.T) {
+	var q SignalQuality
+	if !parseQCSQ([]string{`+QCSQ: "LTE",-52,-81,195,-10`}, &q) {
+		t.Fatal("parseQCSQ() = false")
+	}
+	if *q.RSRP != -81 || *q.SINR != 19 || *q.RSRQ != -10 {
+		t.Errorf("parseQCSQ() = %+v", q)
+	}
+	if got, want := q.String(), "RSRP -81 dBm, RSRQ -10 dB, SINR 19 dB"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	for _, response := range []string{`+QCSQ: "GSM",-69`, `+QCSQ: "NOSERVICE"`, `+QCSQ: "LTE",-52,x,195,-10`} {
+		if parseQCSQ([]string{response}, &SignalQuality{}) {
+			t.Errorf("parseQCSQ(%q) = true", response)
+		}
+	}
+}
+
+func TestAccessTechnology(t *testing.T) {
+	for response, want := range map[string]string{
+		`+COPS: 0,0,"Orange F",7`: "LTE",
+		`+COPS: 0,0,"Orange F",0`: "GSM",
+		`+COPS: 0,0,"Orange F"`:   "",
+		`+COPS: 0`:                "",
+	} {
+		if got := accessTechnology([]string{response}); got != want {
+			t.Errorf("accessTechnology(%q) = %q, want %q", response, got, want)
+		}
+	}
+	if !(SignalQuality{Tech: "LTE"}).LTE() || (SignalQuality{Tech: "UTRAN"}).LTE() {
+		t.Error("LTE() doesn't follow the access technology")
+	}
+}
+
+func TestSignalSparkline(t *testing.T) {
+	var h signalHistory
+	if h.sparkline() != "" {
+		t.Fatal("sparkline() without samples isn't empty")
+	}
+	rsrp := func(dbm int) SignalQuality { return SignalQuality{CSQ: 99, RSRP: &dbm} }
+	for _, q := range []SignalQuality{{CSQ: 0}, {CSQ: 31}, {CSQ: 99}, rsrp(-130), rsrp(-100), rsrp(-60)} {
+		h.add(q)
+	}
+	if got, want := h.sparkline(), "▁█·▁▅█"; got != want {
+		t.Errorf("sparkline() = %q, want %q", got, want)
+	}
+
+	for range signalHistorySize {
+		h.add(SignalQuality{CSQ: 15})
+	}
+	if got := []rune(h.sparkline()); len(got) != signalHistorySize || got[0] != '▄' {
+		t.Errorf("sparkline() = %q, want %d samples", string(got), signalHistorySize)
+	}
+}
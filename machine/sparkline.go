@@ -0,0 +1,73 @@
+package machine
+
+import (
+	"strings"
+	"sync"
+)
+
+// signalHistorySize is how many samples of the monitor /status draws
+const signalHistorySize = 24
+
+// sparkBars draw the signal levels, weakest first
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkLost draws a sample without signal
+const sparkLost = '·'
+
+// signalHistory keeps the levels of the last signal samples
+type signalHistory struct {
+	mu     sync.Mutex
+	levels []float64 // see signalLevel, oldest first
+}
+
+// add records a sample, forgetting the oldest past signalHistorySize
+func (h *signalHistory) add(q SignalQuality) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.levels = append(h.levels, signalLevel(q))
+	if len(h.levels) > signalHistorySize {
+		h.levels = h.levels[len(h.levels)-signalHistorySize:]
+	}
+}
+
+// sparkline draws the samples, oldest first, empty if there are none
+func (h *signalHistory) sparkline() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var b strings.Builder
+	for _, level := range h.levels {
+		if level < 0 {
+			b.WriteRune(sparkLost)
+			continue
+		}
+		b.WriteRune(sparkBars[min(int(level*float64(len(sparkBars))), len(sparkBars)-1)])
+	}
+	return b.String()
+}
+
+// Ranges of the sparkline, below is the lowest bar and above the highest
+const (
+	sparkMinRSRP = -125
+	sparkMaxRSRP = -75
+)
+
+// signalLevel rates q from 0 to 1, by its RSRP on LTE and its CSQ
+// otherwise, or -1 if the signal is lost
+func signalLevel(q SignalQuality) float64 {
+	if q.Lost() {
+		return -1
+	}
+	if q.RSRP != nil {
+		level := float64(*q.RSRP-sparkMinRSRP) / (sparkMaxRSRP - sparkMinRSRP)
+		return min(max(level, 0), 1)
+	}
+	return float64(q.CSQ) / 31
+}
+
+// SignalSparkline draws the last signal samples of the monitor, e.g.
+// ▃▄▄▅▇▇·▂, empty if it took none
+func (m *ModemManager) SignalSparkline() string {
+	return m.signals.sparkline()
+}
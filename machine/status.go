@@ -0,0 +1,82 @@
+package machine
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+)
+
+// localTime shows t to people, in general.timezone. Embed timestamps don't
+// need it, Discord shows them in the reader's timezone.
+func (d *DiscordManager) localTime(t time.Time) string {
+	return formatLocalTime(t, d.config().General.Location())
+}
+
+// formatLocalTime formats t in loc with the zone, e.g. 2026-10-16 14:05:00 CEST
+func formatLocalTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(time.DateTime + " MST")
+}
+
+// handleStatus reports the health of the modem: signal, network, storage,
+// reconnections and clock
+func (d *DiscordManager) handleStatus(event *events.ApplicationCommandInteractionCreate) {
+	// The modem queries can take longer than Discord waits for a response
+	if err := event.DeferCreateMessage(true); err != nil {
+		d.logger.Error("Failed to defer Discord response", slog.Any("error", err))
+		return
+	}
+
+	embed := discord.NewEmbedBuilder().
+		SetTitle("📶 Status").
+		SetColor(0x0099ff).
+		SetTimestamp(d.modem.Now())
+
+	items, err := d.modem.Info(modemQueryTimeout(d.config().Modem))
+	if err != nil {
+		embed.SetDescription(fmt.Sprintf("The modem can't be queried: %v", err))
+	}
+	for _, item := range items {
+		switch item.Name {
+		case "Signal", "Operator", "Registration":
+			value := item.Value
+			if item.Error != "" {
+				value = "⚠️ " + item.Error
+			}
+			embed.AddField(item.Name, value, true)
+		}
+	}
+
+	// On LTE the CSQ says little, the reference signal tells more
+	if q, err := d.modem.GetLTESignal(); err == nil && q.RSRP != nil {
+		embed.AddField("LTE signal", q.String(), true)
+	}
+	if spark := d.modem.SignalSparkline(); spark != "" {
+		embed.AddField("Signal history", spark, false)
+	}
+
+	if used, total, err := d.modem.MessageCount(); err == nil {
+		embed.AddField("SMS storage", fmt.Sprintf("%d / %d", used, total), true)
+	}
+
+	soft, hard := d.modem.Recoveries()
+	embed.AddField("Reconnections", fmt.Sprintf("%d soft, %d full", soft, hard), true)
+	embed.AddField("Refused attempts", fmt.Sprint(d.denied.Load()), true)
+
+	if clock, err := d.modem.ModemClock(); err != nil {
+		embed.AddField("Modem clock", "⚠️ "+err.Error(), true)
+	} else {
+		drift := time.Until(clock).Round(time.Second)
+		embed.AddField("Modem clock", fmt.Sprintf("%s (host %+.0fs)", d.localTime(clock), -drift.Seconds()), true)
+	}
+
+	_, err = event.Client().Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(),
+		discord.NewMessageUpdateBuilder().
+			SetEmbeds(embed.Build()).
+			Build())
+	if err != nil {
+		d.logger.Error("Failed to send Discord response", slog.Any("error", err))
+	}
+}
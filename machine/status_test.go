@@ -0,0 +1,22 @@
+package machine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatLocalTime(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("no timezone database:", err)
+	}
+
+	// A modem clock is in the network's zone, here UTC
+	clock := time.Date(2026, 10, 16, 12, 5, 0, 0, time.UTC)
+	if got, want := formatLocalTime(clock, paris), "2026-10-16 14:05:00 CEST"; got != want {
+		t.Errorf("formatLocalTime() = %q, want %q", got, want)
+	}
+	if got, want := formatLocalTime(clock, time.UTC), "2026-10-16 12:05:00 UTC"; got != want {
+		t.Errorf("formatLocalTime() = %q, want %q", got, want)
+	}
+}
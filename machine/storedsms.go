@@ -0,0 +1,197 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"golte/atresp"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/warthog618/sms"
+	"github.com/warthog618/sms/encoding/pdumode"
+	"github.com/warthog618/sms/encoding/tpdu"
+)
+
+// StoredSMS is an SMS read from the modem's storage
+type StoredSMS struct {
+	Index  int
+	Status string    // REC UNREAD, REC READ, STO UNSENT or STO SENT
+	Number string    // sender, or recipient of a stored outgoing SMS
+	Time   time.Time // service centre timestamp, zero for outgoing SMS
+	Text   string
+}
+
+// pduStatuses are the text mode names of the PDU mode <stat> values
+var pduStatuses = []string{"REC UNREAD", "REC READ", "STO UNSENT", "STO SENT"}
+
+// ErrNoStoredSMS is returned when a storage slot is empty
+var ErrNoStoredSMS = errors.New("no SMS stored at that index")
+
+// ReadStoredSMS reads the SMS stored at index with AT+CMGR, in the text or
+// PDU mode the modem is in
+func (m *ModemManager) ReadStoredSMS(index int) (StoredSMS, error) {
+	if m.GSM() == nil {
+		return StoredSMS{}, ErrNoModem
+	}
+
+	// Indexes start at 0 or 1 depending on the modem
+	_, total, err := m.MessageCount()
+	if err == nil && (index < 0 || index > total) {
+		return StoredSMS{}, fmt.Errorf("index %d is out of range, the storage has %d slots", index, total)
+	}
+
+	response, err := m.GSM().Command(fmt.Sprintf("+CMGR=%d", index))
+	if err != nil {
+		// 321 is an invalid memory index
+		if strings.Contains(err.Error(), "321") {
+			return StoredSMS{}, ErrNoStoredSMS
+		}
+		return StoredSMS{}, fmt.Errorf("failed to read SMS %d: %w", index, err)
+	}
+
+	msg, err := parseStoredSMS(response)
+	if err != nil {
+		return StoredSMS{}, err
+	}
+	msg.Index = index
+	return msg, nil
+}
+
+// parseStoredSMS parses the answer to AT+CMGR. In text mode the header is
+// +CMGR: "REC READ","+33612345678",,"24/01/01,12:00:00+04" followed by the
+// text, in PDU mode it's +CMGR: 1,,25 followed by the PDU in hex.
+func parseStoredSMS(response []string) (StoredSMS, error) {
+	for i, line := range response {
+		header, ok := atresp.Payload(line, "+CMGR")
+		if !ok {
+			continue
+		}
+		body := response[i+1:]
+		if strings.HasPrefix(header, `"`) {
+			return parseStoredText(header, body)
+		}
+		return parseStoredPDU(header, body)
+	}
+	return StoredSMS{}, ErrNoStoredSMS
+}
+
+func parseStoredText(header string, body []string) (StoredSMS, error) {
+	fields := atresp.Split(header)
+	if len(fields) < 2 {
+		return StoredSMS{}, fmt.Errorf("malformed +CMGR header %q", header)
+	}
+
+	msg := StoredSMS{Status: fields[0], Number: fields[1], Text: strings.Join(body, "\n")}
+	if len(fields) >= 4 {
+		msg.Time = parseTextTimestamp(fields[3])
+	}
+	return msg, nil
+}
+
+// parseTextTimestamp parses a text mode timestamp, e.g. 24/01/01,12:00:00+04
+// where the zone is in quarters of an hour, or returns the zero time
+func parseTextTimestamp(s string) time.Time {
+	if len(s) < 17 {
+		return time.Time{}
+	}
+	zone := time.UTC
+	if quarters, err := strconv.Atoi(s[17:]); err == nil {
+		zone = time.FixedZone("", quarters*15*60)
+	}
+	t, err := time.ParseInLocation("06/01/02,15:04:05", s[:17], zone)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func parseStoredPDU(header string, body []string) (StoredSMS, error) {
+	stat, ok := atresp.Split(header).Int(0)
+	if !ok || stat < 0 || stat >= len(pduStatuses) {
+		return StoredSMS{}, fmt.Errorf("malformed +CMGR header %q", header)
+	}
+	if len(body) == 0 {
+		return StoredSMS{}, errors.New("stored SMS without a PDU")
+	}
+
+	pdu, err := pdumode.UnmarshalHexString(strings.TrimSpace(body[0]))
+	if err != nil {
+		return StoredSMS{}, err
+	}
+	var tp tpdu.TPDU
+	if stat >= 2 {
+		// Stored outgoing SMS are SMS-SUBMIT
+		tp.Direction = tpdu.MO
+	}
+	if err := tp.UnmarshalBinary(pdu.TPDU); err != nil {
+		return StoredSMS{}, fmt.Errorf("failed to decode stored SMS: %w", err)
+	}
+	text, err := sms.Decode([]*tpdu.TPDU{&tp})
+	if err != nil {
+		return StoredSMS{}, fmt.Errorf("failed to decode stored SMS: %w", err)
+	}
+
+	msg := StoredSMS{Status: pduStatuses[stat], Text: string(text)}
+	if tp.SmsType() == tpdu.SmsSubmit {
+		msg.Number = tp.DA.Number()
+	} else {
+		msg.Number = tp.OA.Number()
+		msg.Time = tp.SCTS.Time
+	}
+	return msg, nil
+}
+
+// maxEmbedDescription is the most characters an embed description holds
+const maxEmbedDescription = 4096
+
+// cutEmbedDescription cuts text to fit an embed description, on a character
+// boundary so no multi-byte character is split
+func cutEmbedDescription(text string) string {
+	if runes := []rune(text); len(runes) > maxEmbedDescription {
+		return string(runes[:maxEmbedDescription-1]) + "…"
+	}
+	return text
+}
+
+// handleSMSRead shows one SMS from the modem's storage in full
+func (d *DiscordManager) handleSMSRead(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) {
+	index := data.Int("index")
+	d.logger.Info("Received smsread command from Discord",
+		slog.Int("index", index),
+		slog.String("user", event.User().Username))
+
+	msg, err := d.modem.ReadStoredSMS(index)
+	if err != nil {
+		d.logger.Warn("Failed to read stored SMS", slog.Int("index", index), slog.Any("error", err))
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("SMS %d could not be read: %v", index, err))
+		return
+	}
+
+	author := msg.Number
+	if name, ok := d.modem.PhonebookName(msg.Number); ok && name != "" {
+		author = name
+	}
+	embed := discord.NewEmbedBuilder().
+		SetTitle(fmt.Sprintf("📨 Stored SMS #%d", msg.Index)).
+		SetDescription(cutEmbedDescription(msg.Text)).
+		SetAuthor(author, "", "").
+		AddField(smsNumberField, "`"+msg.Number+"`", true).
+		AddField("Status", msg.Status, true).
+		SetColor(0x0099ff)
+	if !msg.Time.IsZero() {
+		embed.SetTimestamp(msg.Time)
+	}
+
+	err = event.CreateMessage(discord.NewMessageCreateBuilder().
+		SetEmbeds(embed.Build()).
+		SetEphemeral(true).
+		Build())
+	if err != nil {
+		d.logger.Error("Failed to send Discord response", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,73 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+	"time"
+
+	"github.com/warthog618/sms"
+	"github.com/warthog618/sms/encoding/pdumode"
+)
+
+func TestParseStoredSMSText(t *testing.T) {
+	msg, err := parseStoredSMS([]string{
+		`+CMGR: "REC READ","+33612345678","","24/01/02,13:04:05+04"`,
+		"first line",
+		"second line",
+	})
+	if err != nil {
+		t.Fatalf("parseStoredSMS() = %v", err)
+	}
+	if msg.Status != "REC READ" || msg.Number != "+33612345678" || msg.Text != "first line\nsecond line" {
+		t.Errorf("parseStoredSMS() = %+v", msg)
+	}
+	want := time.Date(2024, 1, 2, 12, 4, 5, 0, time.UTC)
+	if !msg.Time.Equal(want) {
+		t.Errorf("time = %s, want %s", msg.Time, want)
+	}
+}
+
+func TestParseStoredSMSPDU(t *testing.T) {
+	tpdus, err := sms.Encode([]byte("hello from the SIM"), sms.AsDeliver, sms.From("+33612345678"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := tpdus[0].MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdu := pdumode.PDU{TPDU: raw}
+	hex, err := pdu.MarshalHexString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := parseStoredSMS([]string{fmt.Sprintf("+CMGR: 1,,%d", len(raw)), hex})
+	if err != nil {
+		t.Fatalf("parseStoredSMS() = %v", err)
+	}
+	if msg.Status != "REC READ" || msg.Number != "+33612345678" || msg.Text != "hello from the SIM" {
+		t.Errorf("parseStoredSMS() = %+v", msg)
+	}
+
+	if _, err := parseStoredSMS(nil); err != ErrNoStoredSMS {
+		t.Errorf("parseStoredSMS(nil) = %v, want ErrNoStoredSMS", err)
+	}
+}
+
+func TestCutEmbedDescription(t *testing.T) {
+	if got := cutEmbedDescription("hello"); got != "hello" {
+		t.Errorf("cutEmbedDescription() = %q", got)
+	}
+
+	// 4100 characters of 3 bytes each, a byte cut would split one
+	got := cutEmbedDescription(strings.Repeat("€", 4100))
+	if !utf8.ValidString(got) || utf8.RuneCountInString(got) != maxEmbedDescription || !strings.HasSuffix(got, "€…") {
+		t.Errorf("cutEmbedDescription() = %d characters, valid %v", utf8.RuneCountInString(got), utf8.ValidString(got))
+	}
+	if text := strings.Repeat("€", maxEmbedDescription); cutEmbedDescription(text) != text {
+		t.Error("cutEmbedDescription() cut a description that fits")
+	}
+}
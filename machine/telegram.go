@@ -0,0 +1,309 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golte/config"
+	"golte/telegram"
+)
+
+// telegramPollTimeout is how long a request for the bot's messages waits
+// for one to come
+const telegramPollTimeout = 30 * time.Second
+
+// telegramRetryDelay is the first delay before polling again after a
+// failure, it doubles up to maxRegisterRetryDelay
+const telegramRetryDelay = time.Second
+
+// telegramSendTimeout bounds posting a message to Telegram
+const telegramSendTimeout = 15 * time.Second
+
+// telegramMaxText is the longest text of a Telegram message, in characters
+const telegramMaxText = 4096
+
+// telegramUsage answers /help and /start
+const telegramUsage = "Commands:\n/send <number or contact> <message> sends an SMS"
+
+// TelegramNotifier posts the notifications to telegram.chat_id with a
+// Telegram bot, and runs the commands sent to the bot from the chats of
+// telegram.allowed_chat_ids. The bot's messages are long polled for as long
+// as the bridge runs.
+type TelegramNotifier struct {
+	config    func() *config.Config
+	logger    *slog.Logger
+	modem     *ModemManager
+	smsFunc   func(number, message string) error
+	auditFunc func(action, outcome string)
+	wg        *sync.WaitGroup
+
+	client    *telegram.Client
+	connected atomic.Bool // the last Bot API request went through
+	started   time.Time   // commands sent before are not run
+	cancel    context.CancelFunc
+}
+
+var _ Notifier = (*TelegramNotifier)(nil)
+
+// NewTelegramNotifier creates the notifier of the bot of telegram.token,
+// polling once Start is called. Commands run in the background, tracked by
+// wg. auditFunc is told about every command, it may be nil.
+func NewTelegramNotifier(cfg func() *config.Config, modem *ModemManager, smsFunc func(number, message string) error, auditFunc func(action, outcome string), wg *sync.WaitGroup) *TelegramNotifier {
+	t := cfg().Telegram
+	return &TelegramNotifier{
+		config:    cfg,
+		logger:    slog.With("component", "telegram"),
+		modem:     modem,
+		smsFunc:   smsFunc,
+		auditFunc: auditFunc,
+		wg:        wg,
+		client:    telegram.NewClient(t.Token, t.APIURL),
+	}
+}
+
+// Name names Telegram in the logs and errors of the notifiers
+func (t *TelegramNotifier) Name() string {
+	return "Telegram"
+}
+
+// Initialize checks the bot token with the Bot API
+func (t *TelegramNotifier) Initialize() error {
+	ctx, cancel := context.WithTimeout(context.Background(), telegramSendTimeout)
+	defer cancel()
+
+	me, err := t.client.GetMe(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reach the Telegram bot: %w", err)
+	}
+	t.connected.Store(true)
+	t.logger.Info("Telegram bot initialized",
+		slog.String("bot", me.Username),
+		slog.Int64("chat_id", t.config().Telegram.ChatID))
+	return nil
+}
+
+// Start polls the messages sent to the bot until ctx is done or Stop is
+// called
+func (t *TelegramNotifier) Start(ctx context.Context) error {
+	ctx, t.cancel = context.WithCancel(ctx)
+	t.started = time.Now()
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer recoverPanic(t.logger, "Telegram bot", nil, nil)
+		t.poll(ctx)
+	}()
+	return nil
+}
+
+// Stop stops polling
+func (t *TelegramNotifier) Stop() {
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+// Connected reports whether the Bot API answered the last request
+func (t *TelegramNotifier) Connected() bool {
+	return t.connected.Load()
+}
+
+// poll reads the messages sent to the bot, backing off while the Bot API
+// fails or rate limits the bot
+func (t *TelegramNotifier) poll(ctx context.Context) {
+	var offset int64
+	delay := telegramRetryDelay
+	for ctx.Err() == nil {
+		updates, err := t.client.GetUpdates(ctx, offset, telegramPollTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			t.connected.Store(false)
+			var apiErr *telegram.APIError
+			if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+				delay = apiErr.RetryAfter
+			}
+			t.logger.Warn("Failed to read the Telegram bot's messages, retrying",
+				slog.Duration("delay", delay),
+				slog.Any("error", err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = min(2*delay, maxRegisterRetryDelay)
+			continue
+		}
+
+		t.connected.Store(true)
+		delay = telegramRetryDelay
+		for _, u := range updates {
+			offset = max(offset, u.UpdateID+1)
+			if u.Message != nil {
+				t.onMessage(ctx, u.Message)
+			}
+		}
+	}
+}
+
+// onMessage runs the command of a message in the background, messages that
+// aren't commands are ignored
+func (t *TelegramNotifier) onMessage(ctx context.Context, m *telegram.Message) {
+	command, args := parseTelegramCommand(m.Text)
+	if command == "" {
+		return
+	}
+	// Telegram keeps the messages sent while the bridge was down
+	if time.Unix(m.Date, 0).Before(t.started.Truncate(time.Second)) {
+		t.logger.Info("Ignored a Telegram command sent before the bridge started",
+			slog.String("command", command),
+			slog.Int64("chat_id", m.Chat.ID))
+		return
+	}
+
+	chatID := m.Chat.ID
+	if !t.config().Telegram.Allows(chatID) {
+		t.logger.Warn("Refused a Telegram command from a chat not in telegram.allowed_chat_ids",
+			slog.String("command", command),
+			slog.Int64("chat_id", chatID))
+		t.audit("Telegram /"+command, "⛔ refused, chat not in telegram.allowed_chat_ids")
+		t.reply(ctx, chatID, "⛔ This chat isn't allowed to run commands")
+		return
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer recoverPanic(t.logger, "Telegram command", nil, nil)
+
+		t.logger.Info("Received Telegram command", slog.String("command", command), slog.Int64("chat_id", chatID))
+		t.reply(ctx, chatID, t.runCommand(command, args))
+	}()
+}
+
+// runCommand runs an allowed command, returning the reply
+func (t *TelegramNotifier) runCommand(command, args string) string {
+	switch command {
+	case "send":
+		recipient, message, _ := strings.Cut(args, " ")
+		message = strings.TrimSpace(message)
+		if recipient == "" || message == "" {
+			return "Usage: /send <number or contact> <message>"
+		}
+		number, contact, err := resolveRecipient(t.modem.Contacts(), recipient)
+		if err != nil {
+			return fmt.Sprintf("❌ %v", err)
+		}
+		action := "Telegram SMS to " + recipientLabel(number, contact)
+		if err := t.smsFunc(number, message); err != nil {
+			t.logger.Error("Telegram command failed", slog.String("command", command), slog.Any("error", err))
+			t.audit(action, fmt.Sprintf("❌ %v", err))
+			return fmt.Sprintf("❌ Failed to send the SMS: %v", err)
+		}
+		t.audit(action, "✅ done")
+		return "✅ SMS sent to " + recipientLabel(number, contact)
+	case "start", "help":
+		return telegramUsage
+	default:
+		return fmt.Sprintf("Unknown command /%s\n\n%s", command, telegramUsage)
+	}
+}
+
+// parseTelegramCommand splits a message like /send@golte_bot +336... hi into
+// the command, send, and its arguments. command is empty if the message
+// isn't a command.
+func parseTelegramCommand(text string) (command, args string) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", ""
+	}
+	command, args, _ = strings.Cut(text[1:], " ")
+	// Groups address commands to a bot by its name
+	command, _, _ = strings.Cut(command, "@")
+	return strings.ToLower(command), strings.TrimSpace(args)
+}
+
+// SendEmbed posts a notification to telegram.chat_id
+func (t *TelegramNotifier) SendEmbed(notificationType NotificationType, from, message string) error {
+	contact := ""
+	if notificationType == NotificationTypeSMS {
+		contact, _ = t.modem.PhonebookName(from)
+	}
+	text, err := telegramText(notificationType, from, contact, message)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), telegramSendTimeout)
+	defer cancel()
+	if _, err := t.client.SendMessage(ctx, t.config().Telegram.ChatID, text, telegram.ParseModeHTML); err != nil {
+		t.logger.Error("Failed to send message to Telegram",
+			slog.String("type", string(notificationType)),
+			slog.String("from", from),
+			slog.Any("error", err))
+		return fmt.Errorf("failed to send Telegram message: %w", err)
+	}
+
+	t.logger.Debug("Sent message to Telegram",
+		slog.String("type", string(notificationType)),
+		slog.String("from", from))
+	return nil
+}
+
+// SendSMSEmbed posts a received SMS, Telegram has no use for its PDUs
+func (t *TelegramNotifier) SendSMSEmbed(from, message string, pdus []string) error {
+	return t.SendEmbed(NotificationTypeSMS, from, message)
+}
+
+// telegramText formats a notification as the HTML of a Telegram message,
+// e.g. 💬 SMS from Alice +33612345678 then the SMS. contact is the SIM name
+// of from, if any. Long messages are cut to fit in one Telegram message.
+func telegramText(notificationType NotificationType, from, contact, message string) (string, error) {
+	var header string
+	switch notificationType {
+	case NotificationTypeSMS:
+		sender := "<code>" + html.EscapeString(from) + "</code>"
+		if contact != "" {
+			sender = html.EscapeString(contact) + " " + sender
+		}
+		header = "💬 <b>SMS from</b> " + sender
+	case NotificationTypeCall:
+		header = "📞 <b>" + html.EscapeString(from) + "</b>"
+	case NotificationTypeInfo:
+		header = "ℹ️ <b>" + html.EscapeString(from) + "</b>"
+	default:
+		return "", fmt.Errorf("unsupported notification type: %s", notificationType)
+	}
+
+	// Cut before escaping, so no entity is split
+	room := telegramMaxText - len([]rune(header)) - 2
+	if runes := []rune(message); len(runes) > room {
+		message = string(runes[:room-1]) + "…"
+	}
+	return header + "\n\n" + html.EscapeString(message), nil
+}
+
+// reply answers a command in its chat
+func (t *TelegramNotifier) reply(ctx context.Context, chatID int64, text string) {
+	ctx, cancel := context.WithTimeout(ctx, telegramSendTimeout)
+	defer cancel()
+	if _, err := t.client.SendMessage(ctx, chatID, text, ""); err != nil {
+		t.logger.Warn("Failed to answer a Telegram command", slog.Int64("chat_id", chatID), slog.Any("error", err))
+	}
+}
+
+// audit reports a command and its outcome, see NewTelegramNotifier
+func (t *TelegramNotifier) audit(action, outcome string) {
+	if t.auditFunc != nil {
+		t.auditFunc(action, outcome)
+	}
+}
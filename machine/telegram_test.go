@@ -0,0 +1,154 @@
+package machine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golte/config"
+	"golte/telegram"
+)
+
+func TestParseTelegramCommand(t *testing.T) {
+	tests := []struct {
+		text, command, args string
+	}{
+		{"/send +33612345678 see you at 8", "send", "+33612345678 see you at 8"},
+		{"/Send@golte_bot Alice hi", "send", "Alice hi"},
+		{"  /help  ", "help", ""},
+		{"hello /send", "", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if command, args := parseTelegramCommand(tt.text); command != tt.command || args != tt.args {
+			t.Errorf("parseTelegramCommand(%q) = %q, %q, want %q, %q", tt.text, command, args, tt.command, tt.args)
+		}
+	}
+}
+
+func TestTelegramText(t *testing.T) {
+	tests := []struct {
+		notificationType NotificationType
+		from, contact    string
+		message, want    string
+	}{
+		{NotificationTypeSMS, "+33612345678", "", "1 < 2 & 3", "💬 <b>SMS from</b> <code>+33612345678</code>\n\n1 &lt; 2 &amp; 3"},
+		{NotificationTypeSMS, "+33612345678", "Alice <3", "hi", "💬 <b>SMS from</b> Alice &lt;3 <code>+33612345678</code>\n\nhi"},
+		{NotificationTypeCall, "+33612345678", "", "📞 Incoming voice call", "📞 <b>+33612345678</b>\n\n📞 Incoming voice call"},
+		{NotificationTypeInfo, "Modem", "", "🔌 Modem connection restored", "ℹ️ <b>Modem</b>\n\n🔌 Modem connection restored"},
+	}
+	for _, tt := range tests {
+		if got, err := telegramText(tt.notificationType, tt.from, tt.contact, tt.message); err != nil || got != tt.want {
+			t.Errorf("telegramText(%s, %q) = %q, %v, want %q", tt.notificationType, tt.message, got, err, tt.want)
+		}
+	}
+
+	long, _ := telegramText(NotificationTypeSMS, "+33612345678", "", strings.Repeat("é", 2*telegramMaxText))
+	if n := len([]rune(long)); n > telegramMaxText || !strings.HasSuffix(long, "…") {
+		t.Errorf("telegramText() of a long SMS is %d characters, want it cut to %d", n, telegramMaxText)
+	}
+	if _, err := telegramText("fax", "", "", ""); err == nil {
+		t.Error("telegramText() of an unknown type succeeded")
+	}
+}
+
+// newTestTelegram returns a notifier over a mock modem knowing one SIM
+// contact, talking to a fake Bot API and allowing the commands of chat 7.
+// messages returns the Bot API requests as "method chat_id: text", sent the
+// SMS sent as "number: message".
+func newTestTelegram(t *testing.T) (n *TelegramNotifier, messages, sent func() []string) {
+	t.Helper()
+	var (
+		mu             sync.Mutex
+		posted, smsOut []string
+		wg             sync.WaitGroup
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params struct {
+			ChatID int64  `json:"chat_id"`
+			Text   string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&params)
+		mu.Lock()
+		posted = append(posted, fmt.Sprintf("%s %d: %s", strings.TrimPrefix(r.URL.Path, "/bot123:abc/"), params.ChatID, params.Text))
+		mu.Unlock()
+		io.WriteString(w, `{"ok": true, "result": {"message_id": 1, "chat": {"id": 42}}}`)
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(wg.Wait)
+
+	cfg := &config.Config{
+		Modem:    config.ModemConfig{Type: config.ModemTypeMock},
+		Telegram: config.TelegramConfig{Token: "123:abc", ChatID: 42, APIURL: server.URL, AllowedChatIDs: []int64{7}},
+	}
+	modem := NewModemManager(cfg, nil, nil, nil, nil)
+	modem.setPhonebook([]PhonebookEntry{{Index: 1, Name: "Alice", Number: "+33612345678"}})
+
+	n = NewTelegramNotifier(modem.config, modem, func(number, message string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		smsOut = append(smsOut, number+": "+message)
+		return nil
+	}, nil, &wg)
+	get := func(list *[]string) func() []string {
+		return func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), *list...)
+		}
+	}
+	return n, get(&posted), get(&smsOut)
+}
+
+func TestTelegramNotifier(t *testing.T) {
+	n, messages, sent := newTestTelegram(t)
+
+	if err := n.SendSMSEmbed("+33612345678", "hello", []string{"0791"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := messages(); len(got) != 1 || got[0] != "sendMessage 42: 💬 <b>SMS from</b> Alice <code>+33612345678</code>\n\nhello" {
+		t.Errorf("posted %q", got)
+	}
+
+	if reply := n.runCommand("send", "Alice see you at 8"); !strings.HasPrefix(reply, "✅") {
+		t.Errorf("/send reply = %q", reply)
+	}
+	if got := sent(); len(got) != 1 || got[0] != "+33612345678: see you at 8" {
+		t.Errorf("sent %q", got)
+	}
+	if reply := n.runCommand("send", "Alice"); !strings.HasPrefix(reply, "Usage") {
+		t.Errorf("/send without a message replied %q", reply)
+	}
+	if reply := n.runCommand("send", "Bob hi"); !strings.HasPrefix(reply, "❌") {
+		t.Errorf("/send to an unknown contact replied %q", reply)
+	}
+}
+
+func TestTelegramCommandChats(t *testing.T) {
+	n, messages, sent := newTestTelegram(t)
+	n.started = time.Now()
+	now := time.Now().Unix()
+
+	n.onMessage(context.Background(), &telegram.Message{Chat: telegram.Chat{ID: 99}, Date: now, Text: "/send Alice hi"})
+	if got := messages(); len(got) != 1 || !strings.HasPrefix(got[0], "sendMessage 99: ⛔") {
+		t.Errorf("a chat not allowed got %q, want refused", got)
+	}
+
+	// Commands queued while the bridge was down aren't run
+	n.onMessage(context.Background(), &telegram.Message{Chat: telegram.Chat{ID: 7}, Date: now - 3600, Text: "/send Alice old"})
+	n.onMessage(context.Background(), &telegram.Message{Chat: telegram.Chat{ID: 7}, Date: now, Text: "/send Alice hi"})
+	n.wg.Wait()
+	if got := sent(); len(got) != 1 || got[0] != "+33612345678: hi" {
+		t.Errorf("sent %q, want the allowed command only", got)
+	}
+	if got := messages(); len(got) != 2 || !strings.HasPrefix(got[1], "sendMessage 7: ✅") {
+		t.Errorf("replies %q", got)
+	}
+}
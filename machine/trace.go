@@ -0,0 +1,216 @@
+package machine
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"golte/config"
+	"golte/logger"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/warthog618/modem/trace"
+)
+
+// defaultTraceMaxSize is the size of a trace file for modem.trace.max_size 0
+const defaultTraceMaxSize = 10
+
+// Formats of the trace lines, which the tracer tells apart
+const (
+	traceTX = "TX %q"
+	traceRX = "RX %q"
+)
+
+// smsBodyHeaders are followed by a line holding an SMS body, PDU or text
+var smsBodyHeaders = []string{"+CMT:", "+CMGR:", "+CMGL:", "+CDS:"}
+
+// atTracer writes the bytes exchanged with the modem to modem.trace.path
+// while it's started. Every AT session is traced through it, so starting
+// and stopping doesn't reopen the serial port.
+type atTracer struct {
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	file   *logger.RotatingFile // nil while stopped
+	path   string
+	redact bool
+	body   int // bytes of the SMS body line being read, -1 if not in one
+	line   []byte
+}
+
+func newATTracer() *atTracer {
+	return &atTracer{logger: slog.With("component", "trace"), body: -1}
+}
+
+// Wrap returns rw traced while the tracer is started
+func (t *atTracer) Wrap(rw io.ReadWriter) io.ReadWriter {
+	t.mu.Lock()
+	t.body, t.line = -1, t.line[:0]
+	t.mu.Unlock()
+	return trace.New(rw, trace.WithLogger(t), trace.WithWriteFormat(traceTX), trace.WithReadFormat(traceRX))
+}
+
+// Start traces to cfg.Path, replacing the file traced to so far
+func (t *atTracer) Start(cfg config.TraceConfig) error {
+	if cfg.Path == "" {
+		return errors.New("modem.trace.path is not set")
+	}
+	maxSize := int64(cmp.Or(cfg.MaxSize, defaultTraceMaxSize)) << 20
+	file, err := logger.OpenRotatingFile(cfg.Path, maxSize, cfg.MaxBackups)
+	if err != nil {
+		return fmt.Errorf("failed to open the trace file: %w", err)
+	}
+
+	t.mu.Lock()
+	previous := t.file
+	t.file, t.path, t.redact = file, cfg.Path, cfg.Redact
+	t.mu.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+	t.logger.Info("Tracing the modem", slog.String("path", cfg.Path), slog.Bool("redact", cfg.Redact))
+	return nil
+}
+
+// Stop stops tracing, the file is left as is
+func (t *atTracer) Stop() {
+	t.mu.Lock()
+	file := t.file
+	t.file = nil
+	t.mu.Unlock()
+
+	if file != nil {
+		file.Close()
+		t.logger.Info("Stopped tracing the modem")
+	}
+}
+
+// Path returns the file traced to, empty while stopped
+func (t *atTracer) Path() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return ""
+	}
+	return t.path
+}
+
+// Printf writes a timestamped trace line, see trace.Logger
+func (t *atTracer) Printf(format string, v ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(v) != 1 {
+		return
+	}
+	data, ok := v[0].([]byte)
+	if !ok {
+		return
+	}
+	// Redaction follows the SMS headers read whether tracing or not
+	if format == traceRX {
+		data = t.redactRX(data)
+	}
+	if t.file == nil {
+		return
+	}
+	if format == traceTX && t.redact && !isCommandLine(data) {
+		data = fmt.Appendf(nil, "[%d bytes redacted]", len(data))
+	}
+	line := fmt.Sprintf("%s "+format+"\n", time.Now().Format("2006-01-02T15:04:05.000000Z07:00"), data)
+	if _, err := io.WriteString(t.file, line); err != nil {
+		t.logger.Warn("Failed to write the trace, stopping it", slog.Any("error", err))
+		t.file.Close()
+		t.file = nil
+	}
+}
+
+// redactRX replaces the SMS body lines of data when modem.trace.redact is
+// set. Bodies split across reads are replaced piece by piece.
+func (t *atTracer) redactRX(data []byte) []byte {
+	var out []byte
+	for _, b := range data {
+		if t.body >= 0 {
+			if b != '\n' && b != '\r' {
+				t.body++
+				continue
+			}
+			if t.body > 0 {
+				out = fmt.Appendf(out, "[%d bytes redacted]", t.body)
+			}
+			if b == '\n' {
+				t.body = -1
+			} else {
+				t.body = 0
+			}
+			out = append(out, b)
+			continue
+		}
+
+		out = append(out, b)
+		if b != '\n' {
+			if len(t.line) < 16 {
+				t.line = append(t.line, b)
+			}
+			continue
+		}
+		header := strings.TrimSpace(string(t.line))
+		t.line = t.line[:0]
+		for _, prefix := range smsBodyHeaders {
+			if strings.HasPrefix(header, prefix) {
+				t.body = 0
+			}
+		}
+	}
+	if t.body > 0 {
+		out = fmt.Appendf(out, "[%d bytes redacted]", t.body)
+		t.body = 0
+	}
+	if !t.redact {
+		return data
+	}
+	return out
+}
+
+// isCommandLine reports whether data written to the modem is a command, not
+// the body of an SMS following the > prompt
+func isCommandLine(data []byte) bool {
+	return len(data) >= 2 && strings.EqualFold(string(data[:2]), "AT")
+}
+
+// handleModemTrace switches the AT trace on or off, or shows whether it's on
+func (d *DiscordManager) handleModemTrace(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) {
+	state, ok := data.OptString("state")
+	if !ok {
+		if path := d.modem.tracer.Path(); path != "" {
+			d.respondEphemeral(event.CreateMessage, fmt.Sprintf("📝 Tracing the modem to `%s`", path))
+		} else {
+			d.respondEphemeral(event.CreateMessage, "📝 The modem isn't traced")
+		}
+		return
+	}
+
+	d.logger.Info("Received modem trace command from Discord",
+		slog.String("state", state),
+		slog.String("user", event.User().Username))
+
+	if state == "off" {
+		d.modem.tracer.Stop()
+		d.respondEphemeral(event.CreateMessage, "📝 Stopped tracing the modem")
+		return
+	}
+	cfg := d.config().Modem.Trace
+	if err := d.modem.tracer.Start(cfg); err != nil {
+		d.logger.Error("Failed to start the modem trace", slog.Any("error", err))
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("The trace has **not** been started: %v", err))
+		return
+	}
+	d.respondEphemeral(event.CreateMessage, fmt.Sprintf("📝 Tracing the modem to `%s`", cfg.Path))
+}
@@ -0,0 +1,82 @@
+package machine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golte/config"
+)
+
+// traceSession writes an SMS and reads one back through a tracer
+func traceSession(t *testing.T, tracer *atTracer) {
+	t.Helper()
+	serial := &scriptedSerial{chunks: []string{"\r\n> ", "\r\n+CMGS: 4\r\n\r\nOK\r\n", "\r\n+CMT: ,23\r\n0791334", "4560100F0\r\n"}}
+	rw := tracer.Wrap(serial)
+	buf := make([]byte, 64)
+	rw.Write([]byte("AT+CMGS=23\r"))
+	rw.Read(buf)
+	rw.Write([]byte("0011000B913366\x1a"))
+	for range 3 {
+		rw.Read(buf)
+	}
+}
+
+func TestATTrace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.log")
+	tracer := newATTracer()
+
+	// Nothing is written while stopped
+	traceSession(t, tracer)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("trace file created while stopped: %v", err)
+	}
+
+	if err := tracer.Start(config.TraceConfig{Path: path, Redact: true}); err != nil {
+		t.Fatal(err)
+	}
+	if tracer.Path() != path {
+		t.Errorf("Path() = %q", tracer.Path())
+	}
+	traceSession(t, tracer)
+	tracer.Stop()
+	if tracer.Path() != "" {
+		t.Errorf("Path() = %q once stopped", tracer.Path())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := string(data)
+	for _, want := range []string{`TX "AT+CMGS=23\r"`, `TX "[15 bytes redacted]"`, `RX "\r\n+CMT: ,23\r\n[7 bytes redacted]"`, `RX "[9 bytes redacted]\r\n"`} {
+		if !strings.Contains(trace, want) {
+			t.Errorf("trace is missing %s:\n%s", want, trace)
+		}
+	}
+	if strings.Contains(trace, "0011000B") || strings.Contains(trace, "0791334") {
+		t.Errorf("trace holds an SMS body:\n%s", trace)
+	}
+}
+
+func TestATTraceUnredacted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.log")
+	tracer := newATTracer()
+	if err := tracer.Start(config.TraceConfig{Path: path}); err != nil {
+		t.Fatal(err)
+	}
+	traceSession(t, tracer)
+	tracer.Stop()
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `TX "0011000B913366\x1a"`) || !strings.Contains(string(data), "0791334") {
+		t.Errorf("trace without redaction:\n%s", data)
+	}
+}
+
+func TestATTraceNeedsPath(t *testing.T) {
+	if err := newATTracer().Start(config.TraceConfig{}); err == nil {
+		t.Error("Start() without a path succeeded")
+	}
+}
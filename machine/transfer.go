@@ -0,0 +1,82 @@
+package machine
+
+import (
+	"fmt"
+	"log/slog"
+
+	"golte/call"
+
+	"github.com/disgoorg/disgo/events"
+)
+
+// TransferCall connects the active call to the held one and leaves both
+// (explicit call transfer). It fails unless there is exactly one active and
+// one held call.
+func (m *ModemManager) TransferCall() error {
+	c := m.callManager()
+	if m.mock != nil || c == nil {
+		return ErrNoModem
+	}
+
+	calls, err := c.GetCallStatus()
+	if err != nil {
+		return fmt.Errorf("failed to list the current calls: %w", err)
+	}
+	active, held, err := transferableCalls(calls)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("Transferring call",
+		slog.Group("active", slog.String("number", active.Number)),
+		slog.Group("held", slog.String("number", held.Number)))
+	if err := c.Transfer(); err != nil {
+		m.logger.Error("Failed to transfer call", slog.Any("error", err))
+		return fmt.Errorf("the network refused the transfer: %w", err)
+	}
+
+	// The bridge isn't part of a call anymore
+	m.stopCallWatch()
+	m.logger.Info("Call transferred successfully")
+	return nil
+}
+
+// transferableCalls returns the active and the held call to connect, or an
+// error unless those are the only two calls
+func transferableCalls(calls []call.CallStatus) (active, held call.CallStatus, err error) {
+	var nActive, nHeld int
+	for _, c := range calls {
+		switch c.Status {
+		case "ACTIVE":
+			active = c
+			nActive++
+		case "HELD":
+			held = c
+			nHeld++
+		default:
+			return active, held, fmt.Errorf("call %d is %s, wait until it's answered or hung up", c.Index, c.Status)
+		}
+	}
+	if nActive != 1 || nHeld != 1 {
+		return active, held, fmt.Errorf("a transfer needs one active and one held call, there are %d active and %d held", nActive, nHeld)
+	}
+	return active, held, nil
+}
+
+// handleTransfer connects the active and the held call and drops the bridge
+// out of them
+func (d *DiscordManager) handleTransfer(event *events.ApplicationCommandInteractionCreate) {
+	d.logger.Info("Received transfer command from Discord",
+		slog.String("user", event.User().Username))
+
+	if err := d.modem.TransferCall(); err != nil {
+		d.logger.Error("Failed to transfer call via Discord command", slog.Any("error", err))
+		d.respondEphemeral(event.CreateMessage, fmt.Sprintf("Call has **not** been transferred: %v", err))
+		return
+	}
+	d.respondEphemeral(event.CreateMessage, "🔀 Calls connected, the bridge left them")
+
+	if d.notifyFunc != nil {
+		d.notifyFunc(NotificationTypeCall, "Call transferred", "🔀 Call transferred")
+	}
+}
@@ -0,0 +1,34 @@
+package machine
+
+import (
+	"testing"
+
+	"golte/call"
+)
+
+func TestTransferableCalls(t *testing.T) {
+	active := call.CallStatus{Index: 1, Status: "ACTIVE", Number: "+33612345678"}
+	held := call.CallStatus{Index: 2, Status: "HELD", Number: "+33698765432"}
+
+	gotActive, gotHeld, err := transferableCalls([]call.CallStatus{held, active})
+	if err != nil {
+		t.Fatalf("transferableCalls() = %v", err)
+	}
+	if gotActive != active || gotHeld != held {
+		t.Errorf("transferableCalls() = %+v, %+v", gotActive, gotHeld)
+	}
+
+	invalid := [][]call.CallStatus{
+		nil,
+		{active},
+		{held},
+		{active, active},
+		{held, held, active},
+		{active, {Index: 2, Status: "DIALING"}},
+	}
+	for _, calls := range invalid {
+		if _, _, err := transferableCalls(calls); err == nil {
+			t.Errorf("transferableCalls(%+v) should fail", calls)
+		}
+	}
+}
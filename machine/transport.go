@@ -0,0 +1,244 @@
+package machine
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golte/config"
+	"golte/smpp"
+
+	"github.com/warthog618/modem/at"
+	"github.com/warthog618/modem/gsm"
+)
+
+// Transport carries the SMS of a ModemManager. The default drives the GSM
+// modem with AT commands, with modem.type smpp an SMSC account takes its
+// place and with modem.type mock the simulated modem.
+type Transport interface {
+	SendShortMessage(number, message string) (ref string, err error)
+	SendLongMessage(number, message string) (refs []string, err error)
+	StartMessageRx(onMessage func(gsm.Message), onError func(error)) error
+	StopMessageRx()
+	SignalQuality() ([]string, error)
+}
+
+var (
+	_ Transport = (*gsmTransport)(nil)
+	_ Transport = (*SMPPTransport)(nil)
+)
+
+// Timeouts used when neither the specific setting nor modem.timeout is set
+const (
+	smsSendTimeout = 5 * time.Second
+	queryTimeout   = 3 * time.Second
+)
+
+// smsTimeout returns how long the modem may take to accept each segment of
+// an SMS
+func smsTimeout(cfg config.ModemConfig) time.Duration {
+	return cmp.Or(cfg.SMSTimeout, cfg.Timeout, smsSendTimeout)
+}
+
+// modemQueryTimeout returns how long the quick queries of the signal monitor
+// and /status may take
+func modemQueryTimeout(cfg config.ModemConfig) time.Duration {
+	return cmp.Or(cfg.QueryTimeout, cfg.Timeout, queryTimeout)
+}
+
+// gsmTransport is the Transport of the AT driven GSM modem, it follows
+// the modem across reconnections
+type gsmTransport struct {
+	modem *ModemManager
+}
+
+// sendOptions are the options of each SMS segment sent, the library bounds
+// every segment of a long message separately
+func (t gsmTransport) sendOptions() []at.CommandOption {
+	return []at.CommandOption{at.WithTimeout(smsTimeout(t.modem.config().Modem))}
+}
+
+// queryOptions are the options of the signal quality query
+func (t gsmTransport) queryOptions() []at.CommandOption {
+	return []at.CommandOption{at.WithTimeout(modemQueryTimeout(t.modem.config().Modem))}
+}
+
+func (t gsmTransport) SendShortMessage(number, message string) (string, error) {
+	return t.modem.GSM().SendShortMessage(number, message, t.sendOptions()...)
+}
+
+func (t gsmTransport) SendLongMessage(number, message string) ([]string, error) {
+	return t.modem.GSM().SendLongMessage(number, message, t.sendOptions()...)
+}
+
+func (t gsmTransport) StartMessageRx(onMessage func(gsm.Message), onError func(error)) error {
+	return t.modem.GSM().StartMessageRx(onMessage, onError)
+}
+
+func (t gsmTransport) StopMessageRx() {
+	t.modem.GSM().StopMessageRx()
+}
+
+// SignalQuality queries AT+CSQ, followed by AT+CESQ on modems having it
+// for the LTE reference signal
+func (t gsmTransport) SignalQuality() ([]string, error) {
+	response, err := t.modem.GSM().Command("+CSQ", t.queryOptions()...)
+	if err != nil || !t.modem.capabilities().CESQ {
+		return response, err
+	}
+	if extended, err := t.modem.GSM().Command("+CESQ", t.queryOptions()...); err == nil {
+		response = append(response, extended...)
+	} else {
+		t.modem.logger.Debug("Failed to query the extended signal quality", slog.Any("error", err))
+	}
+	return response, nil
+}
+
+// SMPPTransport exchanges SMS with an SMSC over SMPP
+type SMPPTransport struct {
+	config *config.Config
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	client    *smpp.Client
+	onMessage func(gsm.Message)
+	onError   func(error)
+	closed    chan struct{}
+}
+
+// NewSMPPTransport creates a new SMPPTransport instance
+func NewSMPPTransport(cfg *config.Config) *SMPPTransport {
+	return &SMPPTransport{
+		config: cfg,
+		logger: slog.With("component", "smpp"),
+		closed: make(chan struct{}),
+	}
+}
+
+// Initialize binds to the SMSC as a transceiver
+func (t *SMPPTransport) Initialize() error {
+	cfg := t.config.Modem.SMPP
+
+	t.logger.Info("Binding to SMSC",
+		slog.String("addr", cfg.Addr),
+		slog.String("system_id", cfg.SystemID))
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.config.Modem.Timeout)
+	defer cancel()
+
+	client, err := smpp.Dial(ctx, smpp.Config{
+		Addr:            cfg.Addr,
+		TLS:             cfg.TLS,
+		SystemID:        cfg.SystemID,
+		Password:        cfg.Password,
+		SystemType:      cfg.SystemType,
+		SourceAddr:      cfg.SourceAddr,
+		SourceTON:       cfg.SourceTON,
+		SourceNPI:       cfg.SourceNPI,
+		EnquireLink:     cfg.EnquireLink,
+		ResponseTimeout: t.config.Modem.Timeout,
+	}, t.deliver, t.reportError)
+	if err != nil {
+		return fmt.Errorf("failed to bind to SMSC: %w", err)
+	}
+
+	t.client = client
+
+	t.logger.Info("Bound to SMSC successfully")
+	return nil
+}
+
+// SendShortMessage submits an SMS to the SMSC and returns its message ID
+func (t *SMPPTransport) SendShortMessage(number, message string) (string, error) {
+	if t.client == nil {
+		return "", errors.New("SMPP transport is not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.config.Modem.Timeout)
+	defer cancel()
+
+	id, err := t.client.Submit(ctx, number, message)
+	if err != nil {
+		return "", err
+	}
+	t.logger.Debug("SMSC accepted SMS",
+		slog.String("number", number),
+		slog.String("message_id", id))
+	return id, nil
+}
+
+// SendLongMessage submits a long SMS to the SMSC in one piece, in the
+// message_payload, and the SMSC splits it
+func (t *SMPPTransport) SendLongMessage(number, message string) ([]string, error) {
+	id, err := t.SendShortMessage(number, message)
+	if err != nil {
+		return nil, err
+	}
+	return []string{id}, nil
+}
+
+// StartMessageRx begins passing delivered SMS to onMessage. The SMSC
+// delivers as soon as the bind is up, messages arriving before this are
+// dropped with a warning.
+func (t *SMPPTransport) StartMessageRx(onMessage func(gsm.Message), onError func(error)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.onMessage = onMessage
+	t.onError = onError
+	return nil
+}
+
+// StopMessageRx stops passing delivered SMS on and unbinds
+func (t *SMPPTransport) StopMessageRx() {
+	t.mu.Lock()
+	t.onMessage = nil
+	t.onError = nil
+	t.mu.Unlock()
+
+	if t.client != nil {
+		t.client.Close()
+	}
+}
+
+// SignalQuality returns ErrNoModem, there's no radio behind an SMSC account
+func (t *SMPPTransport) SignalQuality() ([]string, error) {
+	return nil, ErrNoModem
+}
+
+// Closed returns a channel that's closed when the SMSC connection is lost
+func (t *SMPPTransport) Closed() <-chan struct{} {
+	if t.client != nil {
+		return t.client.Closed()
+	}
+	return t.closed
+}
+
+func (t *SMPPTransport) deliver(message smpp.Message) {
+	t.mu.Lock()
+	onMessage := t.onMessage
+	t.mu.Unlock()
+
+	if onMessage == nil {
+		t.logger.Warn("Dropping SMS delivered before reception started",
+			slog.String("from", message.Source))
+		return
+	}
+	onMessage(gsm.Message{Number: message.Source, Message: message.Text})
+}
+
+func (t *SMPPTransport) reportError(err error) {
+	t.mu.Lock()
+	onError := t.onError
+	t.mu.Unlock()
+
+	if onError != nil {
+		onError(err)
+	} else {
+		t.logger.Warn("SMPP error", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,42 @@
+package machine
+
+import (
+	"testing"
+	"time"
+
+	"golte/config"
+
+	"github.com/warthog618/modem/at"
+)
+
+func TestGSMTransportTimeouts(t *testing.T) {
+	tests := []struct {
+		name         string
+		modem        config.ModemConfig
+		send, signal time.Duration
+	}{
+		{"configured", config.ModemConfig{Timeout: 20 * time.Second, SMSTimeout: time.Minute, QueryTimeout: 2 * time.Second}, time.Minute, 2 * time.Second},
+		{"modem timeout", config.ModemConfig{Timeout: 20 * time.Second}, 20 * time.Second, 20 * time.Second},
+		{"nothing set", config.ModemConfig{}, smsSendTimeout, queryTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModemManager(&config.Config{Modem: tt.modem}, nil, nil, nil, nil)
+			transport := gsmTransport{modem: m}
+
+			if got := transport.sendOptions(); len(got) != 1 || got[0] != at.WithTimeout(tt.send) {
+				t.Errorf("sendOptions() = %v, want a %s timeout", got, tt.send)
+			}
+			if got := transport.queryOptions(); len(got) != 1 || got[0] != at.WithTimeout(tt.signal) {
+				t.Errorf("queryOptions() = %v, want a %s timeout", got, tt.signal)
+			}
+		})
+	}
+
+	// Reloads apply to the next message
+	m := NewModemManager(&config.Config{Modem: config.ModemConfig{SMSTimeout: time.Minute}}, nil, nil, nil, nil)
+	m.Reconfigure(&config.Config{Modem: config.ModemConfig{SMSTimeout: 2 * time.Minute}})
+	if got := (gsmTransport{modem: m}).sendOptions()[0]; got != at.WithTimeout(2*time.Minute) {
+		t.Errorf("sendOptions() after a reload = %v, want 2m", got)
+	}
+}
@@ -0,0 +1,205 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golte/atresp"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/warthog618/modem/info"
+)
+
+// USSD result codes, the <m> of +CUSD
+const (
+	USSDDone           = 0 // no further action required
+	USSDReplyExpected  = 1 // the network expects a reply, see USSDSession.Reply
+	USSDTerminated     = 2
+	USSDOtherClient    = 3
+	USSDNotSupported   = 4
+	USSDNetworkTimeout = 5
+)
+
+// ErrUSSDTimeout is returned when the network doesn't answer a USSD request
+// in time
+var ErrUSSDTimeout = errors.New("no USSD response from the network")
+
+// ussdTimeout bounds how long the network may take to answer
+const ussdTimeout = 30 * time.Second
+
+// USSDResponse is a message of the network in a USSD session
+type USSDResponse struct {
+	Status int
+	Text   string
+}
+
+// ReplyExpected reports whether the network waits for a reply, e.g. a menu
+// choice
+func (r USSDResponse) ReplyExpected() bool {
+	return r.Status == USSDReplyExpected
+}
+
+// USSDSession is a USSD dialogue with the network. The modem's character
+// set is UCS2 for its duration, so responses of any language and length
+// come on a single line.
+type USSDSession struct {
+	modem     *ModemManager
+	charset   string // restored by Close
+	responses chan USSDResponse
+
+	closeOnce sync.Once
+}
+
+// StartUSSD sends a USSD code, e.g. *123#, and returns the network's
+// response. The session must be closed, the network may expect replies
+// first.
+func (m *ModemManager) StartUSSD(code string, timeout time.Duration) (*USSDSession, USSDResponse, error) {
+	g := m.GSM()
+	if g == nil {
+		return nil, USSDResponse{}, ErrNoModem
+	}
+	if !m.capabilities().USSD {
+		return nil, USSDResponse{}, notSupported("USSD")
+	}
+
+	s := &USSDSession{modem: m, charset: m.charset(), responses: make(chan USSDResponse, 1)}
+	if s.charset != "UCS2" {
+		if _, err := g.Command(`+CSCS="UCS2"`); err != nil {
+			return nil, USSDResponse{}, fmt.Errorf("failed to switch to the UCS2 character set: %w", err)
+		}
+	}
+
+	err := g.AddIndication("+CUSD:", func(info []string) {
+		response, err := parseUSSD(info[0])
+		if err != nil {
+			m.logger.Warn("Failed to parse USSD response", slog.Any("error", err))
+			return
+		}
+		select {
+		case s.responses <- response:
+		default:
+		}
+	})
+	if err != nil {
+		s.restoreCharset()
+		return nil, USSDResponse{}, fmt.Errorf("failed to watch for USSD responses: %w", err)
+	}
+
+	response, err := s.send(code, timeout)
+	if err != nil {
+		s.Close()
+		return nil, USSDResponse{}, err
+	}
+	return s, response, nil
+}
+
+// Reply answers the network, e.g. with a menu choice
+func (s *USSDSession) Reply(text string, timeout time.Duration) (USSDResponse, error) {
+	return s.send(text, timeout)
+}
+
+// Close ends the session, cancelling it on the network side if it was still
+// open, and restores the character set
+func (s *USSDSession) Close() {
+	s.closeOnce.Do(func() {
+		g := s.modem.GSM()
+		if g == nil {
+			return
+		}
+		g.Command("+CUSD=2")
+		g.CancelIndication("+CUSD:")
+		s.restoreCharset()
+	})
+}
+
+func (s *USSDSession) send(text string, timeout time.Duration) (USSDResponse, error) {
+	g := s.modem.GSM()
+	if g == nil {
+		return USSDResponse{}, ErrNoModem
+	}
+	if _, err := g.Command(fmt.Sprintf(`+CUSD=1,"%s",15`, encodeUCS2Hex(text))); err != nil {
+		return USSDResponse{}, fmt.Errorf("failed to send USSD: %w", err)
+	}
+
+	select {
+	case response := <-s.responses:
+		switch response.Status {
+		case USSDNotSupported:
+			return response, errors.New("the network doesn't support this USSD code")
+		case USSDNetworkTimeout:
+			return response, errors.New("the USSD session timed out on the network side")
+		}
+		return response, nil
+	case <-time.After(timeout):
+		return USSDResponse{}, ErrUSSDTimeout
+	}
+}
+
+func (s *USSDSession) restoreCharset() {
+	if s.charset == "" || s.charset == "UCS2" {
+		return
+	}
+	if _, err := s.modem.GSM().Command(fmt.Sprintf(`+CSCS="%s"`, s.charset)); err != nil {
+		s.modem.logger.Warn("Failed to restore the character set", slog.String("charset", s.charset), slog.Any("error", err))
+	}
+}
+
+// parseUSSD parses +CUSD: <m>[,"<str>",<dcs>]. The text is hex encoded UCS2
+// as the session switches to that character set, modems that ignore it
+// send it as is.
+func parseUSSD(line string) (USSDResponse, error) {
+	fields := atresp.Split(info.TrimPrefix(line, "+CUSD"))
+	status, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return USSDResponse{}, fmt.Errorf("invalid USSD status %q", fields[0])
+	}
+
+	response := USSDResponse{Status: status}
+	if len(fields) > 1 {
+		response.Text = fields[1]
+		if text, ok := decodeUCS2Hex(fields[1]); ok && fields[1] != "" {
+			response.Text = text
+		}
+	}
+	return response, nil
+}
+
+// handleUSSD runs a single step USSD request, e.g. a balance check
+func (d *DiscordManager) handleUSSD(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) {
+	code := strings.TrimSpace(data.String("code"))
+	d.logger.Info("Received USSD command from Discord",
+		slog.String("code", code),
+		slog.String("user", event.User().Username))
+
+	// The network can take a while to answer
+	if err := event.DeferCreateMessage(true); err != nil {
+		d.logger.Error("Failed to defer Discord response", slog.Any("error", err))
+		return
+	}
+
+	var content string
+	session, response, err := d.modem.StartUSSD(code, ussdTimeout)
+	if err != nil {
+		content = fmt.Sprintf("USSD `%s` failed: %v", code, err)
+	} else {
+		session.Close()
+		content = fmt.Sprintf("📟 `%s`\n```\n%s\n```", code, response.Text)
+		if response.ReplyExpected() {
+			content += "\nThe network expected a reply, menus can be browsed with `golte ussd --reply`."
+		}
+	}
+
+	_, err = event.Client().Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(),
+		discord.NewMessageUpdateBuilder().
+			SetContent(content).
+			Build())
+	if err != nil {
+		d.logger.Error("Failed to send Discord response", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,29 @@
+package machine
+
+import "testing"
+
+func TestParseUSSD(t *testing.T) {
+	tests := []struct {
+		line string
+		want USSDResponse
+	}{
+		{`+CUSD: 0,"` + encodeUCS2Hex("Solde : 12,50 €") + `",15`, USSDResponse{USSDDone, "Solde : 12,50 €"}},
+		{`+CUSD: 1,"` + encodeUCS2Hex("1. Forfait\n2. Options") + `",72`, USSDResponse{USSDReplyExpected, "1. Forfait\n2. Options"}},
+		{`+CUSD: 0,"Balance is 5 EUR",15`, USSDResponse{USSDDone, "Balance is 5 EUR"}},
+		{`+CUSD: 4`, USSDResponse{Status: USSDNotSupported}},
+	}
+	for _, tt := range tests {
+		got, err := parseUSSD(tt.line)
+		if err != nil {
+			t.Errorf("parseUSSD(%q) = %v", tt.line, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseUSSD(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+
+	if _, err := parseUSSD("+CUSD: x"); err == nil {
+		t.Error("parseUSSD() should fail on an invalid status")
+	}
+}
@@ -2,58 +2,132 @@ package machine
 
 import (
 	"context"
+	"fmt"
 	"golte/ffmpeg"
 	"log"
-	"os"
-	"syscall"
+	"log/slog"
+	"strings"
+	"time"
 
-	"github.com/disgoorg/audio/opus"
 	"github.com/disgoorg/audio/pcm"
+	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/voice"
-	disgoorgffmpeg "github.com/disgoorg/ffmpeg-audio"
 	"github.com/disgoorg/snowflake/v2"
 )
 
-func (d *DiscordManager) ConnectAndPlay(closeChan chan os.Signal) {
-	guild_id := snowflake.MustParse(d.config.Discord.GuildID)
-	vc_id := snowflake.MustParse(d.config.Discord.VoiceChannelID)
+// The modem audio captured by ffmpeg is mono, at the rate Discord expects
+const (
+	voiceSampleRate = 48000
+	voiceChannels   = 1
+)
+
+// voiceRetryDelay is how long the voice bridge waits before connecting again
+// after it failed
+const voiceRetryDelay = 10 * time.Second
+
+// runVoice runs the voice bridge until ctx is done, connecting again when it
+// fails or panics
+func (d *DiscordManager) runVoice(ctx context.Context) {
+	for {
+		err := d.playVoice()
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+
+		d.logger.Error("Voice bridge failed, connecting again",
+			slog.Any("error", err),
+			slog.Duration("delay", voiceRetryDelay))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(voiceRetryDelay):
+		}
+	}
+}
+
+// playVoice runs ConnectAndPlay, turning a panic into an error so the call
+// audio failing doesn't take the bridge down
+func (d *DiscordManager) playVoice() (err error) {
+	defer recoverPanic(d.logger, "voice", d.closeVoice, func(p error) {
+		err = p
+		if d.panicFunc != nil {
+			d.panicFunc(p)
+		}
+	})
+
+	if err := d.ConnectAndPlay(); err != nil {
+		d.closeVoice()
+		return err
+	}
+	return nil
+}
+
+// ConnectAndPlay joins the voice channel and bridges it with the call audio
+// until the capture ends
+func (d *DiscordManager) ConnectAndPlay() error {
+	guild_id := snowflake.MustParse(d.config().Discord.GuildID)
+	vc_id := snowflake.MustParse(d.config().Discord.VoiceChannelID)
 
 	conn := d.client.VoiceManager().CreateConn(guild_id)
 	d.conn = conn
 
 	if err := conn.Open(context.Background(), vc_id, false, false); err != nil {
-		panic("error connecting to voice channel: " + err.Error())
+		return fmt.Errorf("error connecting to voice channel: %w", err)
 	}
 
 	if err := conn.SetSpeaking(context.Background(), voice.SpeakingFlagMicrophone); err != nil {
-		panic("error setting speaking flag: " + err.Error())
+		return fmt.Errorf("error setting speaking flag: %w", err)
 	}
 
-	pcmProvider, err := ffmpeg.New(context.Background(), disgoorgffmpeg.WithChannels(1), disgoorgffmpeg.WithSampleRate(48000))
+	pcmProvider, err := NewCaptureProvider(context.Background(), d.config())
 	if err != nil {
-		panic("error creating pcm provider: " + err.Error())
+		return fmt.Errorf("error creating pcm provider: %w", err)
 	}
 	defer pcmProvider.Close()
+	d.capture = pcmProvider
 
-	opusEncoder, _ := opus.NewEncoder(48000, 1, opus.ApplicationVoip)
-	opusProvider, err := pcm.NewOpusProvider(opusEncoder, pcmProvider)
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	go d.watchCaptureDevice(watchCtx, pcmProvider)
+
+	opusEncoder, err := newOpusEncoder(d.config().Audio.Opus, voiceSampleRate, voiceChannels, d.logger)
 	if err != nil {
-		panic("error creating opus provider: " + err.Error())
+		return fmt.Errorf("error creating opus encoder: %w", err)
+	}
+
+	// With VAD nothing is sent while the caller is silent, which also clears
+	// the bot's speaking indicator
+	var opusProvider voice.OpusFrameProvider
+	if vad := d.config().Audio.VAD; vad.Enabled {
+		opusProvider = ffmpeg.NewVADOpusProvider(opusEncoder, pcmProvider, ffmpeg.NewVAD(ffmpeg.VADConfig{
+			ThresholdDB: vad.ThresholdDB,
+			Hangover:    vad.Hangover,
+			PreRoll:     vad.PreRoll,
+		}))
+	} else {
+		opusProvider = ffmpeg.NewOpusProvider(opusEncoder, pcmProvider)
 	}
 
 	receiver, streamer, err := ffmpeg.NewOpusPCMReceiver()
 	if err != nil {
-		panic("error creating opus pcm receiver: " + err.Error())
+		return fmt.Errorf("error creating opus pcm receiver: %w", err)
 	}
 	defer receiver.Close()
 
+	receiver.SetTransmitUsers(d.configTransmitUsers())
+	d.receiver.Store(receiver)
 	d.streamer = streamer
 
 	conn.SetEventHandlerFunc(func(opCode voice.Opcode, data voice.GatewayMessageData) {
 		switch opCode {
 		case 11:
 			d.streamer.Close()
-			d.streamer = d.streamer.Reopen()
+			streamer, err := d.streamer.Reopen()
+			if err != nil {
+				log.Println("Failed to reopen streamer:", err)
+				return
+			}
+			d.streamer = streamer
 			d.playback.AddStream(d.streamer)
 			log.Println("Reopened streamer")
 		case voice.OpcodeClientDisconnect:
@@ -65,8 +139,81 @@ func (d *DiscordManager) ConnectAndPlay(closeChan chan os.Signal) {
 	conn.SetOpusFrameReceiver(pcm.NewPCMOpusReceiver(nil, receiver, nil))
 	conn.SetOpusFrameProvider(opusProvider)
 	if err = pcmProvider.Wait(); err != nil {
-		panic("error waiting for opus provider: " + err.Error())
+		return fmt.Errorf("error waiting for opus provider: %w", err)
 	}
 
-	closeChan <- syscall.SIGTERM
+	stats := pcmProvider.Stats()
+	d.logger.Info("Call audio capture ended",
+		slog.Uint64("frames", stats.Frames),
+		slog.Uint64("underruns", stats.Underruns))
+	return nil
+}
+
+// voiceCloseTimeout bounds how long leaving the voice channel may take
+const voiceCloseTimeout = 5 * time.Second
+
+// closeVoice leaves the voice channel and drops the call audio from the
+// playback, so the next connection finds the audio free. The capture and
+// receiver are closed by ConnectAndPlay as it returns or unwinds.
+func (d *DiscordManager) closeVoice() {
+	if d.streamer != nil {
+		d.streamer.Close()
+	}
+	d.capture = nil
+	d.receiver.Store(nil)
+
+	if d.conn != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), voiceCloseTimeout)
+		d.conn.Close(ctx)
+		cancel()
+		d.conn = nil
+	}
+}
+
+// configTransmitUsers parses the configured transmit list, skipping invalid IDs
+func (d *DiscordManager) configTransmitUsers() []snowflake.ID {
+	var ids []snowflake.ID
+	for _, raw := range d.config().Voice.TransmitUsers {
+		id, err := snowflake.Parse(raw)
+		if err != nil {
+			d.logger.Warn("Ignoring invalid voice.transmit_users entry",
+				slog.String("id", raw),
+				slog.Any("error", err))
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// onAirSummary describes whose audio currently reaches the phone call
+func (d *DiscordManager) onAirSummary() string {
+	receiver := d.receiver.Load()
+	if receiver == nil {
+		return "Voice channel not connected"
+	}
+
+	users := receiver.TransmitUsers()
+	if len(users) == 0 {
+		channelID, err := snowflake.Parse(d.config().Discord.VoiceChannelID)
+		if err != nil {
+			return "Everyone in the voice channel"
+		}
+		return fmt.Sprintf("Everyone in %s", discord.ChannelMention(channelID))
+	}
+
+	mentions := make([]string, len(users))
+	for i, id := range users {
+		mentions[i] = discord.UserMention(id)
+	}
+	return strings.Join(mentions, ", ")
+}
+
+// CaptureStats returns the counters of the call audio capture, false when the
+// voice bridge isn't running
+func (d *DiscordManager) CaptureStats() (ffmpeg.ProviderStats, bool) {
+	if d.capture == nil {
+		return ffmpeg.ProviderStats{}, false
+	}
+	return d.capture.Stats(), true
 }
@@ -0,0 +1,194 @@
+package machine
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golte/config"
+)
+
+// Webhook deliveries
+const (
+	defaultWebhookAttempts = 5
+	webhookTimeout         = 10 * time.Second
+	webhookRetryDelay      = 2 * time.Second // doubled after every failed attempt
+)
+
+// Headers of a webhook request besides webhooks[].headers
+const (
+	WebhookEventHeader     = "X-Golte-Event"
+	WebhookSignatureHeader = "X-Golte-Signature" // sha256=<hex HMAC-SHA256 of the body>
+)
+
+// WebhookEvent is the JSON body POSTed to webhooks
+type WebhookEvent struct {
+	Event   string    `json:"event"` // one of config.WebhookEvents
+	Number  string    `json:"number,omitempty"`
+	Contact string    `json:"contact,omitempty"` // SIM contact name of number
+	Message string    `json:"message,omitempty"`
+	Status  string    `json:"status,omitempty"` // sms.sent: sent or failed
+	Error   string    `json:"error,omitempty"`
+	Time    time.Time `json:"time"`  // when it happened
+	Modem   string    `json:"modem"` // modem.label
+}
+
+// WebhookEmitter POSTs events to the webhooks they're subscribed to, in the
+// background so SMS and calls never wait for them
+type WebhookEmitter struct {
+	ctx    context.Context
+	config func() *config.Config
+	logger *slog.Logger
+	modem  *ModemManager
+	client *http.Client
+	wg     *sync.WaitGroup
+
+	retryDelay time.Duration // before the second attempt
+}
+
+// NewWebhookEmitter creates an emitter whose deliveries, tracked by wg, are
+// given up once ctx is done
+func NewWebhookEmitter(ctx context.Context, cfg func() *config.Config, modem *ModemManager, wg *sync.WaitGroup) *WebhookEmitter {
+	return &WebhookEmitter{
+		ctx:    ctx,
+		config: cfg,
+		logger: slog.With("component", "webhook"),
+		modem:  modem,
+		client: &http.Client{Timeout: webhookTimeout},
+		wg:     wg,
+
+		retryDelay: webhookRetryDelay,
+	}
+}
+
+// Emit sends e to every webhook subscribed to it, filling in the time,
+// contact and modem
+func (w *WebhookEmitter) Emit(e WebhookEvent) {
+	cfg := w.config()
+	if len(cfg.Webhooks) == 0 {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = w.modem.Now()
+	}
+	if e.Contact == "" && e.Number != "" {
+		e.Contact, _ = w.modem.PhonebookName(e.Number)
+	}
+	e.Modem = cfg.Modem.Name()
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		w.logger.Error("Failed to encode a webhook event", slog.String("event", e.Event), slog.Any("error", err))
+		return
+	}
+	for _, webhook := range cfg.Webhooks {
+		if !webhook.Subscribed(e.Event) {
+			continue
+		}
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			defer recoverPanic(w.logger, "webhook", nil, nil)
+			w.deliver(webhook, e.Event, body)
+		}()
+	}
+}
+
+// deliver POSTs body, retrying network errors and 5xx answers with a
+// doubling delay up to webhooks[].attempts times
+func (w *WebhookEmitter) deliver(webhook config.WebhookConfig, event string, body []byte) {
+	logger := w.logger.With(slog.String("host", webhookHost(webhook.URL)), slog.String("event", event))
+	attempts := cmp.Or(webhook.Attempts, defaultWebhookAttempts)
+	delay := w.retryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := w.post(webhook, event, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= attempts {
+			logger.Error("Gave up delivering a webhook", slog.Int("attempts", attempt), slog.Any("error", err))
+			return
+		}
+
+		logger.Warn("Webhook delivery failed, retrying",
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.Any("error", err))
+		select {
+		case <-w.ctx.Done():
+			logger.Error("Gave up delivering a webhook on shutdown", slog.Int("attempts", attempt), slog.Any("error", err))
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post makes one delivery, reporting whether a failure is worth retrying
+func (w *WebhookEmitter) post(webhook config.WebhookConfig, event string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, withoutURL(err)
+	}
+	for name, value := range webhook.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, event)
+	if webhook.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, signWebhook(webhook.Secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, withoutURL(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("webhook answered %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return false, nil
+}
+
+// withoutURL strips the URL a *url.Error wraps err with, it may hold a token
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// webhookHost returns the host of a webhook URL, logged instead of the URL
+// as its path or query often holds a token
+func webhookHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// signWebhook returns the X-Golte-Signature of body
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,164 @@
+package machine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"golte/config"
+)
+
+// webhookRequest is what the test server received
+type webhookRequest struct {
+	event     WebhookEvent
+	body      []byte
+	header    http.Header
+	attempted int
+}
+
+// newTestWebhooks returns an emitter over a mock modem knowing one SIM
+// contact and a server failing the first fails requests with a 500. wait
+// waits for the deliveries and returns the requests answered 200.
+func newTestWebhooks(t *testing.T, fails int, webhooks func(url string) []config.WebhookConfig) (w *WebhookEmitter, wait func() []webhookRequest) {
+	t.Helper()
+	var (
+		mu        sync.Mutex
+		attempted int
+		received  []webhookRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempted++
+		if attempted <= fails {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		req := webhookRequest{body: body, header: r.Header, attempted: attempted}
+		if err := json.Unmarshal(body, &req.event); err != nil {
+			t.Errorf("body %s: %v", body, err)
+		}
+		received = append(received, req)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		Modem:    config.ModemConfig{Type: config.ModemTypeMock, Label: "home"},
+		Webhooks: webhooks(server.URL),
+	}
+	modem := NewModemManager(cfg, nil, nil, nil, nil)
+	modem.setPhonebook([]PhonebookEntry{{Index: 1, Name: "Alice", Number: "+33612345678"}})
+
+	var wg sync.WaitGroup
+	w = NewWebhookEmitter(context.Background(), modem.config, modem, &wg)
+	w.retryDelay = 0
+	return w, func() []webhookRequest {
+		wg.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		return received
+	}
+}
+
+func TestWebhookPayloadAndSignature(t *testing.T) {
+	w, wait := newTestWebhooks(t, 0, func(url string) []config.WebhookConfig {
+		return []config.WebhookConfig{{URL: url, Secret: "s3cret", Headers: map[string]string{"Authorization": "Bearer abc"}}}
+	})
+
+	w.Emit(WebhookEvent{Event: config.WebhookSMSReceived, Number: "+33612345678", Message: "hello"})
+	received := wait()
+	if len(received) != 1 {
+		t.Fatalf("received %d requests, want 1", len(received))
+	}
+	req := received[0]
+	e := req.event
+	if e.Event != config.WebhookSMSReceived || e.Number != "+33612345678" || e.Contact != "Alice" || e.Message != "hello" || e.Modem != "home" || e.Time.IsZero() {
+		t.Errorf("event = %+v", e)
+	}
+	if got := req.header.Get(WebhookEventHeader); got != config.WebhookSMSReceived {
+		t.Errorf("%s = %q", WebhookEventHeader, got)
+	}
+	if got, want := req.header.Get(WebhookSignatureHeader), signWebhook("s3cret", req.body); got != want {
+		t.Errorf("%s = %q, want %q", WebhookSignatureHeader, got, want)
+	}
+	if got := req.header.Get("Authorization"); got != "Bearer abc" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := req.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	// echo -n '{"event":"sms.sent"}' | openssl dgst -sha256 -hmac key
+	want := "sha256=6d001ef3ebe4125f6a2c99145a78c1e3dec500aa3f54e895ec8278f33808778e"
+	if got := signWebhook("key", []byte(`{"event":"sms.sent"}`)); got != want {
+		t.Errorf("signWebhook = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	w, wait := newTestWebhooks(t, 2, func(url string) []config.WebhookConfig {
+		return []config.WebhookConfig{{URL: url, Attempts: 3}}
+	})
+
+	w.Emit(WebhookEvent{Event: config.WebhookCallHangup})
+	received := wait()
+	if len(received) != 1 || received[0].attempted != 3 {
+		t.Fatalf("received %+v, want one request on the third attempt", received)
+	}
+	if received[0].header.Get(WebhookSignatureHeader) != "" {
+		t.Error("signed without a secret")
+	}
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	w, wait := newTestWebhooks(t, 5, func(url string) []config.WebhookConfig {
+		return []config.WebhookConfig{{URL: url, Attempts: 2}}
+	})
+
+	w.Emit(WebhookEvent{Event: config.WebhookCallHangup})
+	if received := wait(); len(received) != 0 {
+		t.Fatalf("received %+v after giving up", received)
+	}
+}
+
+func TestWebhookEvents(t *testing.T) {
+	w, wait := newTestWebhooks(t, 0, func(url string) []config.WebhookConfig {
+		return []config.WebhookConfig{
+			{URL: url, Events: []string{config.WebhookCallIncoming}},
+			{URL: url + "/all"},
+		}
+	})
+
+	w.Emit(WebhookEvent{Event: config.WebhookSMSSent, Number: "+33600000000", Status: "sent"})
+	w.Emit(WebhookEvent{Event: config.WebhookCallIncoming, Number: "+33600000000"})
+	counts := map[string]int{}
+	for _, req := range wait() {
+		counts[req.event.Event]++
+	}
+	if counts[config.WebhookSMSSent] != 1 || counts[config.WebhookCallIncoming] != 2 {
+		t.Errorf("events received = %v, want sms.sent once and call.incoming twice", counts)
+	}
+}
+
+func TestWebhookErrorHidesURL(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	cfg := &config.Config{Modem: config.ModemConfig{Type: config.ModemTypeMock}}
+	modem := NewModemManager(cfg, nil, nil, nil, nil)
+	var wg sync.WaitGroup
+	w := NewWebhookEmitter(context.Background(), modem.config, modem, &wg)
+
+	retry, err := w.post(config.WebhookConfig{URL: server.URL + "/hook?token=s3cret"}, config.WebhookSMSSent, nil)
+	if !retry || err == nil || strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("post() to a closed server = %v, %v, want a retried error without the URL", retry, err)
+	}
+}
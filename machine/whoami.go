@@ -0,0 +1,155 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+)
+
+// identityItems are the Info items that don't change while the bridge runs
+var identityItems = []string{"Manufacturer", "Model", "IMEI", "IMSI"}
+
+// identity is what tells a bridge apart from another
+type identity struct {
+	Number string
+	Model  string
+	IMEI   string
+	IMSI   string
+}
+
+// identityCache keeps the identity once it's fully known, the modem and SIM
+// don't change without a restart
+type identityCache struct {
+	mu sync.Mutex
+	id *identity
+}
+
+// identity returns the bridge identity, querying the modem until it answers
+// every item
+func (d *DiscordManager) identity() (identity, error) {
+	d.identityCache.mu.Lock()
+	defer d.identityCache.mu.Unlock()
+	if d.identityCache.id != nil {
+		return *d.identityCache.id, nil
+	}
+
+	var id identity
+	complete := true
+	number, err := d.modem.OwnNumber()
+	switch {
+	case err == nil:
+		id.Number = number
+	case errors.Is(err, ErrOwnNumberUnknown):
+		id.Number = "unknown"
+	default:
+		id.Number = "⚠️ " + err.Error()
+		complete = false
+	}
+
+	items, err := d.modem.query(identityItems, modemQueryTimeout(d.config().Modem))
+	if err != nil {
+		return id, err
+	}
+	values := make(map[string]string, len(items))
+	for _, item := range items {
+		if item.Error != "" {
+			values[item.Name] = "⚠️ " + item.Error
+			complete = false
+		} else {
+			values[item.Name] = item.Value
+		}
+	}
+	id.Model = strings.TrimSpace(values["Manufacturer"] + " " + values["Model"])
+	id.IMEI, id.IMSI = values["IMEI"], values["IMSI"]
+
+	if complete {
+		d.identityCache.id = &id
+	}
+	return id, nil
+}
+
+// handleWhoami describes which bridge this is: the SIM's number, operator,
+// version and uptime, and to owners the IMEI and IMSI
+func (d *DiscordManager) handleWhoami(event *events.ApplicationCommandInteractionCreate) {
+	// The modem queries can take longer than Discord waits for a response
+	if err := event.DeferCreateMessage(true); err != nil {
+		d.logger.Error("Failed to defer Discord response", slog.Any("error", err))
+		return
+	}
+
+	id, err := d.identity()
+	operator := ""
+	if err == nil {
+		if items, err := d.modem.query([]string{"Operator"}, modemQueryTimeout(d.config().Modem)); err == nil && len(items) == 1 {
+			operator = items[0].Value
+			if items[0].Error != "" {
+				operator = "⚠️ " + items[0].Error
+			}
+		}
+	}
+
+	embed := whoamiEmbed(id, operator, d.version, time.Since(d.started), d.isOwner(event.User().ID))
+	if err != nil {
+		embed.SetDescription(fmt.Sprintf("The modem can't be queried: %v", err))
+	}
+
+	_, err = event.Client().Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(),
+		discord.NewMessageUpdateBuilder().
+			SetEmbeds(embed.Build()).
+			Build())
+	if err != nil {
+		d.logger.Error("Failed to send Discord response", slog.Any("error", err))
+	}
+}
+
+// whoamiEmbed lays out the identity card, with the IMEI and IMSI only for
+// owners
+func whoamiEmbed(id identity, operator, version string, uptime time.Duration, owner bool) *discord.EmbedBuilder {
+	if version == "" {
+		version = "dev"
+	}
+	embed := discord.NewEmbedBuilder().
+		SetTitle("🪪 Who am I").
+		SetColor(0x0099ff)
+
+	for _, field := range []struct{ name, value string }{
+		{"Number", id.Number},
+		{"Operator", operator},
+		{"Modem", id.Model},
+	} {
+		if field.value != "" {
+			embed.AddField(field.name, field.value, true)
+		}
+	}
+	embed.AddField("Version", version, true)
+	embed.AddField("Uptime", formatUptime(uptime), true)
+
+	if owner {
+		for _, field := range []struct{ name, value string }{{"IMEI", id.IMEI}, {"IMSI", id.IMSI}} {
+			if field.value != "" {
+				embed.AddField(field.name, field.value, true)
+			}
+		}
+	}
+	return embed
+}
+
+// formatUptime shows d with its two largest units, e.g. 3d 4h or 12m
+func formatUptime(d time.Duration) string {
+	d = d.Truncate(time.Minute)
+	days, hours, minutes := int(d/(24*time.Hour)), int(d/time.Hour)%24, int(d/time.Minute)%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
@@ -0,0 +1,61 @@
+package machine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWhoamiEmbed(t *testing.T) {
+	id := identity{Number: "+33612345678", Model: "SIMCOM INCORPORATED SIM7600E-H", IMEI: "861234567890123", IMSI: "208011234567890"}
+	uptime := 26*time.Hour + 5*time.Minute
+
+	fields := func(owner bool) map[string]string {
+		embed := whoamiEmbed(id, "Orange F (LTE)", "v1.4.0", uptime, owner).Build()
+		m := make(map[string]string, len(embed.Fields))
+		for _, field := range embed.Fields {
+			m[field.Name] = field.Value
+		}
+		return m
+	}
+
+	public := fields(false)
+	for name, want := range map[string]string{
+		"Number":   "+33612345678",
+		"Operator": "Orange F (LTE)",
+		"Modem":    "SIMCOM INCORPORATED SIM7600E-H",
+		"Version":  "v1.4.0",
+		"Uptime":   "1d 2h",
+	} {
+		if public[name] != want {
+			t.Errorf("field %s = %q, want %q", name, public[name], want)
+		}
+	}
+	if _, ok := public["IMEI"]; ok {
+		t.Error("IMEI shown to a non-owner")
+	}
+	if _, ok := public["IMSI"]; ok {
+		t.Error("IMSI shown to a non-owner")
+	}
+
+	owner := fields(true)
+	if owner["IMEI"] != id.IMEI || owner["IMSI"] != id.IMSI {
+		t.Errorf("owner fields IMEI = %q, IMSI = %q", owner["IMEI"], owner["IMSI"])
+	}
+}
+
+func TestFormatUptime(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{40 * time.Second, "0m"},
+		{12*time.Minute + 30*time.Second, "12m"},
+		{2*time.Hour + 5*time.Minute, "2h 5m"},
+		{76 * time.Hour, "3d 4h"},
+	}
+	for _, tt := range tests {
+		if got := formatUptime(tt.d); got != tt.want {
+			t.Errorf("formatUptime(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
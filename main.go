@@ -1,11 +1,17 @@
 package main
 
 import (
+	_ "embed"
+
 	"golte/cmd"
 )
 
 //go:generate go run tools/generate.go
 
+//go:embed config.yaml.example
+var sampleConfig []byte
+
 func main() {
+	cmd.SampleConfig = sampleConfig
 	cmd.Execute()
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"os"
 	"testing"
 	"time"
 
@@ -17,11 +18,13 @@ func TestConfigValidation(t *testing.T) {
 			name: "valid config",
 			config: &config.Config{
 				Discord: config.DiscordConfig{
-					Token:     "test-token",
-					ChannelID: "123456789012345678",
+					Token:          "test-token",
+					ChannelIDs:     []string{"123456789012345678"},
+					GuildID:        "123456789012345677",
+					VoiceChannelID: "123456789012345679",
 				},
 				Modem: config.ModemConfig{
-					Device:  "/dev/ttyUSB0",
+					Device:  os.DevNull, // must exist
 					Baud:    115200,
 					Timeout: 20 * time.Second,
 				},
@@ -36,7 +39,7 @@ func TestConfigValidation(t *testing.T) {
 			name: "missing discord token",
 			config: &config.Config{
 				Discord: config.DiscordConfig{
-					ChannelID: "123456789012345678",
+					ChannelIDs: []string{"123456789012345678"},
 				},
 			},
 			wantErr: true,
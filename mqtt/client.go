@@ -0,0 +1,367 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by requests made after the connection was lost
+var ErrClosed = errors.New("mqtt: connection closed")
+
+// Config holds the settings of a connection to a broker
+type Config struct {
+	Broker          string // tcp://host:port, or ssl://host:port for TLS
+	ClientID        string
+	Username        string
+	Password        string
+	KeepAlive       time.Duration // ping interval, 0 uses 30s
+	Will            *Message      // published by the broker if the connection is lost
+	ResponseTimeout time.Duration // how long a request waits for its acknowledgement, 0 uses 10s
+}
+
+// Client is a connection to an MQTT broker with a clean session
+type Client struct {
+	cfg       Config
+	conn      net.Conn
+	logger    *slog.Logger
+	onMessage func(Message)
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  uint16
+	waiting map[uint16]chan *Packet
+	pong    chan struct{}
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// Dial connects to the broker. Messages of the topics subscribed to are
+// passed to onMessage from the connection's reader goroutine, so it must not
+// block. A QoS 1 message is acknowledged once onMessage returns.
+func Dial(ctx context.Context, cfg Config, onMessage func(Message)) (*Client, error) {
+	if cfg.KeepAlive == 0 {
+		cfg.KeepAlive = 30 * time.Second
+	}
+	if cfg.ResponseTimeout == 0 {
+		cfg.ResponseTimeout = 10 * time.Second
+	}
+
+	addr, useTLS, err := brokerAddr(cfg.Broker)
+	if err != nil {
+		return nil, err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the MQTT broker: %w", err)
+	}
+	if useTLS {
+		host, _, _ := net.SplitHostPort(addr)
+		conn = tls.Client(conn, &tls.Config{ServerName: host})
+	}
+
+	c := &Client{
+		cfg:       cfg,
+		conn:      conn,
+		logger:    slog.With("component", "mqtt"),
+		onMessage: onMessage,
+		waiting:   make(map[uint16]chan *Packet),
+		pong:      make(chan struct{}, 1),
+		closed:    make(chan struct{}),
+	}
+	if err := c.connect(ctx); err != nil {
+		c.shutdown()
+		return nil, err
+	}
+
+	go c.readLoop()
+	go c.keepAlive()
+	return c, nil
+}
+
+// brokerAddr splits a broker URL into host:port and whether it uses TLS
+func brokerAddr(broker string) (string, bool, error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		return "", false, fmt.Errorf("mqtt: broker %q is not a URL like tcp://host:1883", broker)
+	}
+
+	var useTLS bool
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS, port = true, "8883"
+	default:
+		return "", false, fmt.Errorf("mqtt: unsupported scheme %q, use tcp or ssl", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+// connect sends CONNECT and waits for the CONNACK, before the reader runs
+func (c *Client) connect(ctx context.Context) error {
+	flags := connectCleanSession
+	if c.cfg.Username != "" {
+		flags |= connectUsername
+	}
+	if c.cfg.Password != "" {
+		flags |= connectPassword
+	}
+	if will := c.cfg.Will; will != nil {
+		flags |= connectWill | will.QoS<<3
+		if will.Retain {
+			flags |= connectWillRetain
+		}
+	}
+
+	var body bodyWriter
+	body.string("MQTT")
+	body.WriteByte(protocolLevel)
+	body.WriteByte(flags)
+	body.uint16(uint16(c.cfg.KeepAlive / time.Second))
+	body.string(c.cfg.ClientID)
+	if will := c.cfg.Will; will != nil {
+		body.string(will.Topic)
+		body.uint16(uint16(len(will.Payload)))
+		body.Write(will.Payload)
+	}
+	if c.cfg.Username != "" {
+		body.string(c.cfg.Username)
+	}
+	if c.cfg.Password != "" {
+		body.string(c.cfg.Password)
+	}
+
+	deadline := time.Now().Add(c.cfg.ResponseTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+	defer c.conn.SetDeadline(time.Time{})
+
+	if err := c.write(&Packet{Type: Connect, Body: body.Bytes()}); err != nil {
+		return err
+	}
+	p, err := ReadPacket(c.conn)
+	if err != nil {
+		return fmt.Errorf("mqtt: no CONNACK: %w", err)
+	}
+	if p.Type != Connack || len(p.Body) != 2 {
+		return fmt.Errorf("mqtt: expected CONNACK, got packet type %d", p.Type)
+	}
+	if code := p.Body[1]; code != 0 {
+		return ConnectError(code)
+	}
+	return nil
+}
+
+// Publish sends m. At QoS 1 it waits for the broker's PUBACK.
+func (c *Client) Publish(ctx context.Context, m Message) error {
+	if m.QoS > 1 {
+		return fmt.Errorf("mqtt: QoS %d is not supported", m.QoS)
+	}
+	if m.QoS == 0 {
+		return c.write(publishPacket(m, 0))
+	}
+
+	id, ch := c.register()
+	defer c.unregister(id)
+	if err := c.write(publishPacket(m, id)); err != nil {
+		return err
+	}
+	_, err := c.await(ctx, ch, Puback)
+	return err
+}
+
+// Subscribe subscribes to topic filters, each with the maximum QoS it is
+// delivered at
+func (c *Client) Subscribe(ctx context.Context, filters map[string]byte) error {
+	id, ch := c.register()
+	defer c.unregister(id)
+
+	var body bodyWriter
+	body.uint16(id)
+	for filter, qos := range filters {
+		body.string(filter)
+		body.WriteByte(qos)
+	}
+	if err := c.write(&Packet{Type: Subscribe, Flags: 0x02, Body: body.Bytes()}); err != nil {
+		return err
+	}
+
+	p, err := c.await(ctx, ch, Suback)
+	if err != nil {
+		return err
+	}
+	for _, code := range p.Body[2:] {
+		if code == 0x80 {
+			return errors.New("mqtt: the broker refused a subscription")
+		}
+	}
+	return nil
+}
+
+// Closed returns a channel that's closed when the connection is lost
+func (c *Client) Closed() <-chan struct{} {
+	return c.closed
+}
+
+// Close disconnects from the broker, which then drops the last will
+func (c *Client) Close() error {
+	select {
+	case <-c.closed:
+		return nil
+	default:
+	}
+	if err := c.write(&Packet{Type: Disconnect}); err != nil {
+		c.logger.Debug("DISCONNECT failed", slog.Any("error", err))
+	}
+	c.shutdown()
+	return nil
+}
+
+// register allocates a packet identifier to wait for its acknowledgement on
+func (c *Client) register() (uint16, chan *Packet) {
+	ch := make(chan *Packet, 1)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		c.nextID = c.nextID%maxPacketID + 1 // 0 is not a valid identifier
+		if _, used := c.waiting[c.nextID]; !used {
+			break
+		}
+	}
+	c.waiting[c.nextID] = ch
+	return c.nextID, ch
+}
+
+func (c *Client) unregister(id uint16) {
+	c.mu.Lock()
+	delete(c.waiting, id)
+	c.mu.Unlock()
+}
+
+// await waits for the acknowledgement of a request
+func (c *Client) await(ctx context.Context, ch chan *Packet, want byte) (*Packet, error) {
+	timer := time.NewTimer(c.cfg.ResponseTimeout)
+	defer timer.Stop()
+
+	select {
+	case p := <-ch:
+		if p.Type != want {
+			return nil, fmt.Errorf("mqtt: expected packet type %d, got %d", want, p.Type)
+		}
+		return p, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("mqtt: no acknowledgement (packet type %d)", want)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.closed:
+		return nil, ErrClosed
+	}
+}
+
+func (c *Client) write(p *Packet) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if _, err := c.conn.Write(p.Bytes()); err != nil {
+		c.shutdown()
+		return fmt.Errorf("mqtt: write failed: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) readLoop() {
+	defer c.shutdown()
+
+	for {
+		p, err := ReadPacket(c.conn)
+		if err != nil {
+			select {
+			case <-c.closed:
+			default:
+				c.logger.Warn("Connection to the broker lost", slog.Any("error", err))
+			}
+			return
+		}
+
+		switch p.Type {
+		case Publish:
+			m, id, err := parsePublish(p)
+			if err != nil {
+				c.logger.Warn("Dropped an invalid message", slog.Any("error", err))
+				continue
+			}
+			if c.onMessage != nil {
+				c.onMessage(m)
+			}
+			if m.QoS > 0 {
+				var body bodyWriter
+				body.uint16(id)
+				c.write(&Packet{Type: Puback, Body: body.Bytes()})
+			}
+		case Puback, Suback:
+			id, err := packetID(p)
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch, ok := c.waiting[id]
+			c.mu.Unlock()
+			if ok {
+				ch <- p
+			}
+		case Pingresp:
+			select {
+			case c.pong <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// keepAlive pings the broker, a broker that doesn't answer is given up on
+func (c *Client) keepAlive() {
+	ticker := time.NewTicker(c.cfg.KeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.write(&Packet{Type: Pingreq}); err != nil {
+				return
+			}
+			select {
+			case <-c.pong:
+			case <-time.After(c.cfg.ResponseTimeout):
+				c.logger.Warn("The broker doesn't answer pings, disconnecting")
+				c.shutdown()
+				return
+			case <-c.closed:
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+func (c *Client) shutdown() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.conn.Close()
+	})
+}
@@ -0,0 +1,214 @@
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeBroker accepts one client, acknowledges its packets and records them.
+// connack is the CONNACK return code.
+type fakeBroker struct {
+	t        *testing.T
+	listener net.Listener
+	conns    chan net.Conn
+	packets  chan *Packet
+}
+
+func newFakeBroker(t *testing.T, connack byte) *fakeBroker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{t: t, listener: listener, conns: make(chan net.Conn, 1), packets: make(chan *Packet, 64)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { conn.Close() })
+		b.conns <- conn
+
+		for {
+			p, err := ReadPacket(conn)
+			if err != nil {
+				return
+			}
+			switch p.Type {
+			case Connect:
+				conn.Write((&Packet{Type: Connack, Body: []byte{0, connack}}).Bytes())
+			case Publish:
+				if p.Flags>>1&0x03 == 1 {
+					_, id, _ := parsePublish(p)
+					var body bodyWriter
+					body.uint16(id)
+					conn.Write((&Packet{Type: Puback, Body: body.Bytes()}).Bytes())
+				}
+			case Subscribe:
+				conn.Write((&Packet{Type: Suback, Body: append(p.Body[:2:2], 1)}).Bytes())
+			case Pingreq:
+				conn.Write((&Packet{Type: Pingresp}).Bytes())
+			}
+			b.packets <- p
+		}
+	}()
+	return b
+}
+
+func (b *fakeBroker) url() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+func (b *fakeBroker) next(packetType byte) *Packet {
+	b.t.Helper()
+	for {
+		select {
+		case p := <-b.packets:
+			if p.Type == packetType {
+				return p
+			}
+		case <-time.After(2 * time.Second):
+			b.t.Fatalf("no packet of type %d received", packetType)
+			return nil
+		}
+	}
+}
+
+func TestConnectWithWill(t *testing.T) {
+	b := newFakeBroker(t, 0)
+	c, err := Dial(context.Background(), Config{
+		Broker:   b.url(),
+		ClientID: "golte",
+		Username: "user",
+		Password: "pass",
+		Will:     &Message{Topic: "golte/status", Payload: []byte("offline"), QoS: 1, Retain: true},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	connect := b.next(Connect)
+	r := bodyReader{buf: connect.Body}
+	if name := r.string(); name != "MQTT" {
+		t.Errorf("protocol name %q", name)
+	}
+	level, flags := r.buf[0], r.buf[1]
+	r.buf = r.buf[4:]
+	want := connectCleanSession | connectWill | 1<<3 | connectWillRetain | connectUsername | connectPassword
+	if level != protocolLevel || flags != want {
+		t.Errorf("level %d flags %08b, want %d %08b", level, flags, protocolLevel, want)
+	}
+	for _, field := range []string{"golte", "golte/status", "offline", "user", "pass"} {
+		if got := r.string(); got != field {
+			t.Errorf("CONNECT payload field %q, want %q", got, field)
+		}
+	}
+}
+
+func TestConnectRefused(t *testing.T) {
+	b := newFakeBroker(t, 5)
+	_, err := Dial(context.Background(), Config{Broker: b.url(), ClientID: "golte"}, nil)
+	var refused ConnectError
+	if !errors.As(err, &refused) || refused != 5 {
+		t.Errorf("Dial() = %v, want ConnectError 5", err)
+	}
+}
+
+func TestPublishAndSubscribe(t *testing.T) {
+	b := newFakeBroker(t, 0)
+	received := make(chan Message, 1)
+	c, err := Dial(context.Background(), Config{Broker: b.url(), ClientID: "golte"}, func(m Message) {
+		received <- m
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Publish(context.Background(), Message{Topic: "golte/sms", Payload: []byte(`{"from":"+33612345678"}`), QoS: 1}); err != nil {
+		t.Fatalf("Publish() = %v", err)
+	}
+	m, _, err := parsePublish(b.next(Publish))
+	if err != nil || m.Topic != "golte/sms" || m.QoS != 1 || string(m.Payload) != `{"from":"+33612345678"}` {
+		t.Errorf("published %+v, %v", m, err)
+	}
+
+	if err := c.Subscribe(context.Background(), map[string]byte{"golte/cmd/+": 1}); err != nil {
+		t.Fatalf("Subscribe() = %v", err)
+	}
+	if sub := b.next(Subscribe); sub.Flags != 0x02 {
+		t.Errorf("SUBSCRIBE flags %x, want 2", sub.Flags)
+	}
+
+	// A command delivered at QoS 1 is acknowledged after the handler
+	conn := <-b.conns
+	conn.Write(publishPacket(Message{Topic: "golte/cmd/sms", Payload: []byte("hi"), QoS: 1}, 7).Bytes())
+	select {
+	case m := <-received:
+		if m.Topic != "golte/cmd/sms" || !bytes.Equal(m.Payload, []byte("hi")) {
+			t.Errorf("received %+v", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received")
+	}
+	if id, _ := packetID(b.next(Puback)); id != 7 {
+		t.Errorf("PUBACK for packet %d, want 7", id)
+	}
+}
+
+func TestClosedWhenBrokerDrops(t *testing.T) {
+	b := newFakeBroker(t, 0)
+	c, err := Dial(context.Background(), Config{Broker: b.url(), ClientID: "golte"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	(<-b.conns).Close()
+	select {
+	case <-c.Closed():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Closed() not closed after the broker dropped the connection")
+	}
+	if err := c.Publish(context.Background(), Message{Topic: "golte/sms", QoS: 1}); err == nil {
+		t.Error("Publish() succeeded on a closed connection")
+	}
+}
+
+func TestPacketLength(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384, 200000} {
+		p := &Packet{Type: Publish, Body: make([]byte, n)}
+		got, err := ReadPacket(bytes.NewReader(p.Bytes()))
+		if err != nil || len(got.Body) != n {
+			t.Errorf("length %d: read %v, %v", n, len(got.Body), err)
+		}
+	}
+}
+
+func TestBrokerAddr(t *testing.T) {
+	tests := []struct {
+		broker string
+		addr   string
+		tls    bool
+		err    bool
+	}{
+		{"tcp://localhost", "localhost:1883", false, false},
+		{"mqtt://10.0.0.2:1884", "10.0.0.2:1884", false, false},
+		{"ssl://broker.example.com", "broker.example.com:8883", true, false},
+		{"http://broker", "", false, true},
+		{"localhost:1883", "", false, true},
+	}
+	for _, tt := range tests {
+		addr, useTLS, err := brokerAddr(tt.broker)
+		if addr != tt.addr || useTLS != tt.tls || (err != nil) != tt.err {
+			t.Errorf("brokerAddr(%q) = %q, %v, %v", tt.broker, addr, useTLS, err)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Package mqtt implements the subset of MQTT 3.1.1 a bridge needs: connecting
+// with credentials and a last will, publishing at QoS 0 and 1, and
+// subscribing to topics delivered at QoS 0 or 1.
+package mqtt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Packet types
+const (
+	Connect    byte = 1
+	Connack    byte = 2
+	Publish    byte = 3
+	Puback     byte = 4
+	Subscribe  byte = 8
+	Suback     byte = 9
+	Pingreq    byte = 12
+	Pingresp   byte = 13
+	Disconnect byte = 14
+)
+
+const (
+	protocolLevel = 4       // MQTT 3.1.1
+	maxPacketLen  = 1 << 20 // far more than an SMS or a command needs
+	maxPacketID   = 0xffff
+)
+
+// flagRetain is the retain flag of a PUBLISH packet
+const flagRetain byte = 0x01
+
+// Flags of a CONNECT packet
+const (
+	connectCleanSession byte = 0x02
+	connectWill         byte = 0x04
+	connectWillRetain   byte = 0x20
+	connectPassword     byte = 0x40
+	connectUsername     byte = 0x80
+)
+
+// ConnectError is a refusal of the broker in its CONNACK
+type ConnectError byte
+
+func (e ConnectError) Error() string {
+	switch e {
+	case 1:
+		return "mqtt: connection refused, unacceptable protocol version"
+	case 2:
+		return "mqtt: connection refused, client identifier rejected"
+	case 3:
+		return "mqtt: connection refused, server unavailable"
+	case 4:
+		return "mqtt: connection refused, bad user name or password"
+	case 5:
+		return "mqtt: connection refused, not authorized"
+	default:
+		return fmt.Sprintf("mqtt: connection refused, code %d", byte(e))
+	}
+}
+
+// Packet is a single MQTT control packet
+type Packet struct {
+	Type  byte
+	Flags byte // low nibble of the first byte
+	Body  []byte
+}
+
+// ReadPacket reads one packet from r
+func ReadPacket(r io.Reader) (*Packet, error) {
+	var first [1]byte
+	if _, err := io.ReadFull(r, first[:]); err != nil {
+		return nil, err
+	}
+
+	// The remaining length takes 7 bits per byte, up to 4 bytes
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return nil, errors.New("mqtt: malformed remaining length")
+		}
+		var b [1]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		length += int(b[0]&0x7f) * multiplier
+		if b[0]&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	if length > maxPacketLen {
+		return nil, fmt.Errorf("mqtt: packet of %d bytes is too large", length)
+	}
+
+	p := &Packet{Type: first[0] >> 4, Flags: first[0] & 0x0f, Body: make([]byte, length)}
+	if _, err := io.ReadFull(r, p.Body); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Bytes encodes the packet for the wire
+func (p *Packet) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(p.Type<<4 | p.Flags&0x0f)
+	length := len(p.Body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		buf.WriteByte(b)
+		if length == 0 {
+			break
+		}
+	}
+	buf.Write(p.Body)
+	return buf.Bytes()
+}
+
+// Message is an application message published on a topic
+type Message struct {
+	Topic   string
+	Payload []byte
+	QoS     byte
+	Retain  bool
+}
+
+// publishPacket encodes a PUBLISH of m, id is only sent for QoS 1
+func publishPacket(m Message, id uint16) *Packet {
+	var body bodyWriter
+	body.string(m.Topic)
+	if m.QoS > 0 {
+		body.uint16(id)
+	}
+	body.Write(m.Payload)
+
+	flags := m.QoS << 1
+	if m.Retain {
+		flags |= flagRetain
+	}
+	return &Packet{Type: Publish, Flags: flags, Body: body.Bytes()}
+}
+
+// parsePublish decodes a PUBLISH, returning its packet ID for QoS 1
+func parsePublish(p *Packet) (Message, uint16, error) {
+	r := bodyReader{buf: p.Body}
+	m := Message{Topic: r.string(), QoS: p.Flags >> 1 & 0x03, Retain: p.Flags&flagRetain != 0}
+	var id uint16
+	if m.QoS > 0 {
+		id = r.uint16()
+	}
+	if r.err != nil {
+		return Message{}, 0, fmt.Errorf("mqtt: invalid PUBLISH: %w", r.err)
+	}
+	m.Payload = r.rest()
+	return m, id, nil
+}
+
+// packetID returns the packet identifier starting the body of p, as in
+// PUBACK and SUBACK
+func packetID(p *Packet) (uint16, error) {
+	if len(p.Body) < 2 {
+		return 0, fmt.Errorf("mqtt: packet type %d without identifier", p.Type)
+	}
+	return binary.BigEndian.Uint16(p.Body), nil
+}
+
+// bodyWriter builds a packet body
+type bodyWriter struct {
+	bytes.Buffer
+}
+
+func (w *bodyWriter) uint16(v uint16) {
+	w.Write(binary.BigEndian.AppendUint16(nil, v))
+}
+
+// string writes a length-prefixed UTF-8 string
+func (w *bodyWriter) string(s string) {
+	w.uint16(uint16(len(s)))
+	w.WriteString(s)
+}
+
+// bodyReader reads a packet body, remembering the first error
+type bodyReader struct {
+	buf []byte
+	err error
+}
+
+func (r *bodyReader) uint16() uint16 {
+	if r.err != nil || len(r.buf) < 2 {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	v := binary.BigEndian.Uint16(r.buf)
+	r.buf = r.buf[2:]
+	return v
+}
+
+func (r *bodyReader) string() string {
+	n := int(r.uint16())
+	if r.err != nil || len(r.buf) < n {
+		r.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
+
+func (r *bodyReader) rest() []byte {
+	rest := r.buf
+	r.buf = nil
+	return rest
+}
@@ -0,0 +1,232 @@
+package numinfo
+
+// callingCode is a country calling code, ITU-T E.164 assignments
+type callingCode struct {
+	name   string
+	region string
+	shared string // calling code the entry is a range of, e.g. 7 for Kazakhstan
+}
+
+// callingCodes maps calling codes to countries. NANP countries share +1 and
+// are not told apart.
+var callingCodes = map[string]callingCode{
+	"1":  {name: "North America (NANP)"},
+	"7":  {name: "Russia", region: "RU"},
+	"76": {name: "Kazakhstan", region: "KZ", shared: "7"},
+	"77": {name: "Kazakhstan", region: "KZ", shared: "7"},
+
+	"20": {name: "Egypt", region: "EG"},
+	"27": {name: "South Africa", region: "ZA"},
+	"30": {name: "Greece", region: "GR"},
+	"31": {name: "Netherlands", region: "NL"},
+	"32": {name: "Belgium", region: "BE"},
+	"33": {name: "France", region: "FR"},
+	"34": {name: "Spain", region: "ES"},
+	"36": {name: "Hungary", region: "HU"},
+	"39": {name: "Italy", region: "IT"},
+	"40": {name: "Romania", region: "RO"},
+	"41": {name: "Switzerland", region: "CH"},
+	"43": {name: "Austria", region: "AT"},
+	"44": {name: "United Kingdom", region: "GB"},
+	"45": {name: "Denmark", region: "DK"},
+	"46": {name: "Sweden", region: "SE"},
+	"47": {name: "Norway", region: "NO"},
+	"48": {name: "Poland", region: "PL"},
+	"49": {name: "Germany", region: "DE"},
+	"51": {name: "Peru", region: "PE"},
+	"52": {name: "Mexico", region: "MX"},
+	"53": {name: "Cuba", region: "CU"},
+	"54": {name: "Argentina", region: "AR"},
+	"55": {name: "Brazil", region: "BR"},
+	"56": {name: "Chile", region: "CL"},
+	"57": {name: "Colombia", region: "CO"},
+	"58": {name: "Venezuela", region: "VE"},
+	"60": {name: "Malaysia", region: "MY"},
+	"61": {name: "Australia", region: "AU"},
+	"62": {name: "Indonesia", region: "ID"},
+	"63": {name: "Philippines", region: "PH"},
+	"64": {name: "New Zealand", region: "NZ"},
+	"65": {name: "Singapore", region: "SG"},
+	"66": {name: "Thailand", region: "TH"},
+	"81": {name: "Japan", region: "JP"},
+	"82": {name: "South Korea", region: "KR"},
+	"84": {name: "Vietnam", region: "VN"},
+	"86": {name: "China", region: "CN"},
+	"90": {name: "Turkey", region: "TR"},
+	"91": {name: "India", region: "IN"},
+	"92": {name: "Pakistan", region: "PK"},
+	"93": {name: "Afghanistan", region: "AF"},
+	"94": {name: "Sri Lanka", region: "LK"},
+	"95": {name: "Myanmar", region: "MM"},
+	"98": {name: "Iran", region: "IR"},
+
+	"211": {name: "South Sudan", region: "SS"},
+	"212": {name: "Morocco", region: "MA"},
+	"213": {name: "Algeria", region: "DZ"},
+	"216": {name: "Tunisia", region: "TN"},
+	"218": {name: "Libya", region: "LY"},
+	"220": {name: "Gambia", region: "GM"},
+	"221": {name: "Senegal", region: "SN"},
+	"222": {name: "Mauritania", region: "MR"},
+	"223": {name: "Mali", region: "ML"},
+	"224": {name: "Guinea", region: "GN"},
+	"225": {name: "Côte d'Ivoire", region: "CI"},
+	"226": {name: "Burkina Faso", region: "BF"},
+	"227": {name: "Niger", region: "NE"},
+	"228": {name: "Togo", region: "TG"},
+	"229": {name: "Benin", region: "BJ"},
+	"230": {name: "Mauritius", region: "MU"},
+	"231": {name: "Liberia", region: "LR"},
+	"232": {name: "Sierra Leone", region: "SL"},
+	"233": {name: "Ghana", region: "GH"},
+	"234": {name: "Nigeria", region: "NG"},
+	"235": {name: "Chad", region: "TD"},
+	"236": {name: "Central African Republic", region: "CF"},
+	"237": {name: "Cameroon", region: "CM"},
+	"238": {name: "Cape Verde", region: "CV"},
+	"239": {name: "São Tomé and Príncipe", region: "ST"},
+	"240": {name: "Equatorial Guinea", region: "GQ"},
+	"241": {name: "Gabon", region: "GA"},
+	"242": {name: "Congo", region: "CG"},
+	"243": {name: "DR Congo", region: "CD"},
+	"244": {name: "Angola", region: "AO"},
+	"245": {name: "Guinea-Bissau", region: "GW"},
+	"246": {name: "Diego Garcia", region: "IO"},
+	"248": {name: "Seychelles", region: "SC"},
+	"249": {name: "Sudan", region: "SD"},
+	"250": {name: "Rwanda", region: "RW"},
+	"251": {name: "Ethiopia", region: "ET"},
+	"252": {name: "Somalia", region: "SO"},
+	"253": {name: "Djibouti", region: "DJ"},
+	"254": {name: "Kenya", region: "KE"},
+	"255": {name: "Tanzania", region: "TZ"},
+	"256": {name: "Uganda", region: "UG"},
+	"257": {name: "Burundi", region: "BI"},
+	"258": {name: "Mozambique", region: "MZ"},
+	"260": {name: "Zambia", region: "ZM"},
+	"261": {name: "Madagascar", region: "MG"},
+	"262": {name: "Réunion and Mayotte", region: "RE"},
+	"263": {name: "Zimbabwe", region: "ZW"},
+	"264": {name: "Namibia", region: "NA"},
+	"265": {name: "Malawi", region: "MW"},
+	"266": {name: "Lesotho", region: "LS"},
+	"267": {name: "Botswana", region: "BW"},
+	"268": {name: "Eswatini", region: "SZ"},
+	"269": {name: "Comoros", region: "KM"},
+	"290": {name: "Saint Helena", region: "SH"},
+	"291": {name: "Eritrea", region: "ER"},
+	"297": {name: "Aruba", region: "AW"},
+	"298": {name: "Faroe Islands", region: "FO"},
+	"299": {name: "Greenland", region: "GL"},
+
+	"350": {name: "Gibraltar", region: "GI"},
+	"351": {name: "Portugal", region: "PT"},
+	"352": {name: "Luxembourg", region: "LU"},
+	"353": {name: "Ireland", region: "IE"},
+	"354": {name: "Iceland", region: "IS"},
+	"355": {name: "Albania", region: "AL"},
+	"356": {name: "Malta", region: "MT"},
+	"357": {name: "Cyprus", region: "CY"},
+	"358": {name: "Finland", region: "FI"},
+	"359": {name: "Bulgaria", region: "BG"},
+	"370": {name: "Lithuania", region: "LT"},
+	"371": {name: "Latvia", region: "LV"},
+	"372": {name: "Estonia", region: "EE"},
+	"373": {name: "Moldova", region: "MD"},
+	"374": {name: "Armenia", region: "AM"},
+	"375": {name: "Belarus", region: "BY"},
+	"376": {name: "Andorra", region: "AD"},
+	"377": {name: "Monaco", region: "MC"},
+	"378": {name: "San Marino", region: "SM"},
+	"380": {name: "Ukraine", region: "UA"},
+	"381": {name: "Serbia", region: "RS"},
+	"382": {name: "Montenegro", region: "ME"},
+	"383": {name: "Kosovo", region: "XK"},
+	"385": {name: "Croatia", region: "HR"},
+	"386": {name: "Slovenia", region: "SI"},
+	"387": {name: "Bosnia and Herzegovina", region: "BA"},
+	"389": {name: "North Macedonia", region: "MK"},
+	"420": {name: "Czechia", region: "CZ"},
+	"421": {name: "Slovakia", region: "SK"},
+	"423": {name: "Liechtenstein", region: "LI"},
+
+	"500": {name: "Falkland Islands", region: "FK"},
+	"501": {name: "Belize", region: "BZ"},
+	"502": {name: "Guatemala", region: "GT"},
+	"503": {name: "El Salvador", region: "SV"},
+	"504": {name: "Honduras", region: "HN"},
+	"505": {name: "Nicaragua", region: "NI"},
+	"506": {name: "Costa Rica", region: "CR"},
+	"507": {name: "Panama", region: "PA"},
+	"508": {name: "Saint Pierre and Miquelon", region: "PM"},
+	"509": {name: "Haiti", region: "HT"},
+	"590": {name: "Guadeloupe", region: "GP"},
+	"591": {name: "Bolivia", region: "BO"},
+	"592": {name: "Guyana", region: "GY"},
+	"593": {name: "Ecuador", region: "EC"},
+	"594": {name: "French Guiana", region: "GF"},
+	"595": {name: "Paraguay", region: "PY"},
+	"596": {name: "Martinique", region: "MQ"},
+	"597": {name: "Suriname", region: "SR"},
+	"598": {name: "Uruguay", region: "UY"},
+	"599": {name: "Curaçao and Caribbean Netherlands", region: "CW"},
+
+	"670": {name: "Timor-Leste", region: "TL"},
+	"672": {name: "Norfolk Island", region: "NF"},
+	"673": {name: "Brunei", region: "BN"},
+	"674": {name: "Nauru", region: "NR"},
+	"675": {name: "Papua New Guinea", region: "PG"},
+	"676": {name: "Tonga", region: "TO"},
+	"677": {name: "Solomon Islands", region: "SB"},
+	"678": {name: "Vanuatu", region: "VU"},
+	"679": {name: "Fiji", region: "FJ"},
+	"680": {name: "Palau", region: "PW"},
+	"681": {name: "Wallis and Futuna", region: "WF"},
+	"682": {name: "Cook Islands", region: "CK"},
+	"683": {name: "Niue", region: "NU"},
+	"685": {name: "Samoa", region: "WS"},
+	"686": {name: "Kiribati", region: "KI"},
+	"687": {name: "New Caledonia", region: "NC"},
+	"688": {name: "Tuvalu", region: "TV"},
+	"689": {name: "French Polynesia", region: "PF"},
+	"690": {name: "Tokelau", region: "TK"},
+	"691": {name: "Micronesia", region: "FM"},
+	"692": {name: "Marshall Islands", region: "MH"},
+
+	"800": {name: "International Freephone"},
+	"850": {name: "North Korea", region: "KP"},
+	"852": {name: "Hong Kong", region: "HK"},
+	"853": {name: "Macau", region: "MO"},
+	"855": {name: "Cambodia", region: "KH"},
+	"856": {name: "Laos", region: "LA"},
+	"870": {name: "Inmarsat"},
+	"880": {name: "Bangladesh", region: "BD"},
+	"881": {name: "Global Mobile Satellite System"},
+	"882": {name: "International Networks"},
+	"883": {name: "International Networks"},
+	"886": {name: "Taiwan", region: "TW"},
+
+	"960": {name: "Maldives", region: "MV"},
+	"961": {name: "Lebanon", region: "LB"},
+	"962": {name: "Jordan", region: "JO"},
+	"963": {name: "Syria", region: "SY"},
+	"964": {name: "Iraq", region: "IQ"},
+	"965": {name: "Kuwait", region: "KW"},
+	"966": {name: "Saudi Arabia", region: "SA"},
+	"967": {name: "Yemen", region: "YE"},
+	"968": {name: "Oman", region: "OM"},
+	"970": {name: "Palestine", region: "PS"},
+	"971": {name: "United Arab Emirates", region: "AE"},
+	"972": {name: "Israel", region: "IL"},
+	"973": {name: "Bahrain", region: "BH"},
+	"974": {name: "Qatar", region: "QA"},
+	"975": {name: "Bhutan", region: "BT"},
+	"976": {name: "Mongolia", region: "MN"},
+	"977": {name: "Nepal", region: "NP"},
+	"992": {name: "Tajikistan", region: "TJ"},
+	"993": {name: "Turkmenistan", region: "TM"},
+	"994": {name: "Azerbaijan", region: "AZ"},
+	"995": {name: "Georgia", region: "GE"},
+	"996": {name: "Kyrgyzstan", region: "KG"},
+	"998": {name: "Uzbekistan", region: "UZ"},
+}
@@ -0,0 +1,114 @@
+package numinfo
+
+import (
+	"strings"
+)
+
+// Info is what the prefix of a phone number tells about it
+type Info struct {
+	E164        string // normalized number, e.g. +33612345678
+	CallingCode string // country calling code without the +, e.g. 33
+	Country     string
+	Region      string // ISO 3166-1 alpha-2 code, empty for codes shared by several countries
+	Carrier     string // operator the number range was allocated to, empty if unknown
+}
+
+// Flag returns the flag emoji of the region, or an empty string
+func (i Info) Flag() string {
+	if len(i.Region) != 2 {
+		return ""
+	}
+	var flag strings.Builder
+	for _, r := range strings.ToUpper(i.Region) {
+		if r < 'A' || r > 'Z' {
+			return ""
+		}
+		flag.WriteRune(0x1F1E6 + r - 'A')
+	}
+	return flag.String()
+}
+
+// String describes the number's origin, e.g. "🇫🇷 France · Orange"
+func (i Info) String() string {
+	s := i.Country
+	if flag := i.Flag(); flag != "" {
+		s = flag + " " + s
+	}
+	if i.Carrier != "" {
+		s += " · " + i.Carrier
+	}
+	return s
+}
+
+// E164 normalizes an international number to E.164, stripping spaces,
+// dashes and brackets and turning a 00 prefix into +. National numbers and
+// alphanumeric senders can't be placed in a country and are rejected.
+func E164(number string) (string, bool) {
+	var digits strings.Builder
+	international := false
+	for i, r := range strings.TrimSpace(number) {
+		switch {
+		case r == '+' && i == 0:
+			international = true
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(" -.()/", r):
+		default:
+			return "", false
+		}
+	}
+
+	d := digits.String()
+	if !international {
+		var ok bool
+		if d, ok = strings.CutPrefix(d, "00"); !ok {
+			return "", false
+		}
+	}
+	// E.164 numbers have at most 15 digits, and no country code starts
+	// with 0
+	if len(d) < 7 || len(d) > 15 || d[0] == '0' {
+		return "", false
+	}
+	return "+" + d, true
+}
+
+// Lookup places an international number in its country. carriers maps E.164
+// prefixes, e.g. +3366, to the operator they were allocated to; the longest
+// matching prefix wins. Ported numbers keep the prefix of their first
+// operator, so the carrier is only a hint.
+func Lookup(number string, carriers map[string]string) (Info, bool) {
+	e164, ok := E164(number)
+	if !ok {
+		return Info{}, false
+	}
+
+	// Calling codes are one to three digits and prefix free, apart from
+	// the Kazakh ranges within +7
+	digits := e164[1:]
+	var (
+		code    string
+		country callingCode
+	)
+	for n := min(3, len(digits)); n > 0; n-- {
+		if c, ok := callingCodes[digits[:n]]; ok {
+			code, country = digits[:n], c
+			break
+		}
+	}
+	if code == "" {
+		return Info{}, false
+	}
+	if country.shared != "" {
+		code = country.shared
+	}
+
+	info := Info{E164: e164, CallingCode: code, Country: country.name, Region: country.region}
+	best := 0
+	for prefix, carrier := range carriers {
+		if len(prefix) > best && strings.HasPrefix(e164, prefix) {
+			info.Carrier, best = carrier, len(prefix)
+		}
+	}
+	return info, true
+}
@@ -0,0 +1,76 @@
+package numinfo
+
+import (
+	"testing"
+)
+
+func TestE164(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"+33 6 12-34-56-78", "+33612345678", true},
+		{"0033612345678", "+33612345678", true},
+		{"+1 (415) 555-0100", "+14155550100", true},
+		{"0612345678", "", false},
+		{"Amazon", "", false},
+		{"+0123456789", "", false},
+		{"+1234", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := E164(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("E164(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	carriers := map[string]string{"+336": "Mobile", "+3366": "Operator B"}
+
+	tests := []struct {
+		number string
+		want   string
+		code   string
+	}{
+		{"+33612345678", "🇫🇷 France · Mobile", "33"},
+		{"+33661234567", "🇫🇷 France · Operator B", "33"},
+		{"+33123456789", "🇫🇷 France", "33"},
+		{"+3521234567", "🇱🇺 Luxembourg", "352"},
+		{"+77012345678", "🇰🇿 Kazakhstan", "7"},
+		{"+74951234567", "🇷🇺 Russia", "7"},
+		{"+14155550100", "North America (NANP)", "1"},
+	}
+
+	for _, tt := range tests {
+		info, ok := Lookup(tt.number, carriers)
+		if !ok || info.String() != tt.want || info.CallingCode != tt.code {
+			t.Errorf("Lookup(%q) = %q (+%s), %v, want %q (+%s)", tt.number, info, info.CallingCode, ok, tt.want, tt.code)
+		}
+	}
+
+	if _, ok := Lookup("0612345678", nil); ok {
+		t.Error("Lookup() should reject national numbers")
+	}
+}
+
+func TestCallingCodesPrefixFree(t *testing.T) {
+	for code, c := range callingCodes {
+		for n := 1; n < len(code); n++ {
+			if _, ok := callingCodes[code[:n]]; ok && c.shared != code[:n] {
+				t.Errorf("calling code %s is shadowed by %s", code, code[:n])
+			}
+		}
+	}
+}
+
+func TestFlag(t *testing.T) {
+	if got := (Info{Region: "fr"}).Flag(); got != "🇫🇷" {
+		t.Errorf("Flag() = %q", got)
+	}
+	if got := (Info{}).Flag(); got != "" {
+		t.Errorf("Flag() without region = %q", got)
+	}
+}
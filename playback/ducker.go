@@ -0,0 +1,70 @@
+package playback
+
+import (
+	"math"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// Ramps used when ducking starts and ends. The attack is short so a prompt
+// is heard clearly from its first syllable, the release is slower so speech
+// doesn't jump back in.
+const (
+	duckAttack  = 20 * time.Millisecond
+	duckRelease = 200 * time.Millisecond
+)
+
+// Ducker attenuates a streamer while active reports true, ramping the gain
+// to avoid clicks
+type Ducker struct {
+	Streamer beep.Streamer
+
+	active      func() bool
+	duckedGain  float64
+	attackStep  float64
+	releaseStep float64
+	gain        float64
+}
+
+var _ beep.Streamer = (*Ducker)(nil)
+
+// NewDucker wraps streamer so it is attenuated by depthDB decibels whenever
+// active returns true
+func NewDucker(streamer beep.Streamer, sampleRate beep.SampleRate, depthDB float64, active func() bool) *Ducker {
+	duckedGain := math.Pow(10, -math.Abs(depthDB)/20)
+	return &Ducker{
+		Streamer:    streamer,
+		active:      active,
+		duckedGain:  duckedGain,
+		attackStep:  (1 - duckedGain) / float64(max(sampleRate.N(duckAttack), 1)),
+		releaseStep: (1 - duckedGain) / float64(max(sampleRate.N(duckRelease), 1)),
+		gain:        1,
+	}
+}
+
+func (d *Ducker) Stream(samples [][2]float64) (n int, ok bool) {
+	n, ok = d.Streamer.Stream(samples)
+
+	// The activity is sampled once per buffer, the ramp smooths the rest
+	target := 1.0
+	if d.active() {
+		target = d.duckedGain
+	}
+
+	for i := range samples[:n] {
+		switch {
+		case d.gain > target:
+			d.gain = math.Max(d.gain-d.attackStep, target)
+		case d.gain < target:
+			d.gain = math.Min(d.gain+d.releaseStep, target)
+		}
+		samples[i][0] *= d.gain
+		samples[i][1] *= d.gain
+	}
+	return n, ok
+}
+
+func (d *Ducker) Err() error {
+	return d.Streamer.Err()
+}
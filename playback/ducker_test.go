@@ -0,0 +1,59 @@
+package playback
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/generators"
+)
+
+func constant(value float64) beep.Streamer {
+	return beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		for i := range samples {
+			samples[i] = [2]float64{value, value}
+		}
+		return len(samples), true
+	})
+}
+
+func TestDuckerFollowsQueue(t *testing.T) {
+	queue := &Queue{}
+	ducker := NewDucker(constant(1), SampleRate, 20, queue.Active)
+	ducked := math.Pow(10, -20.0/20)
+
+	// Mimic the mixer: the queue is streamed before the live path
+	tick := func(d time.Duration) [][2]float64 {
+		buf := make([][2]float64, SampleRate.N(d))
+		queue.Stream(make([][2]float64, len(buf)))
+		ducker.Stream(buf)
+		return buf
+	}
+
+	if got := tick(10 * time.Millisecond); got[len(got)-1][0] != 1 {
+		t.Fatalf("expected full level with an idle queue, got %f", got[len(got)-1][0])
+	}
+
+	queue.Add(generators.Silence(SampleRate.N(100 * time.Millisecond)))
+	out := tick(50 * time.Millisecond)
+	if out[0][0] >= 1 || out[0][0] < ducked {
+		t.Errorf("expected the attack to ramp, first sample %f", out[0][0])
+	}
+	if got := out[len(out)-1][0]; math.Abs(got-ducked) > 1e-9 {
+		t.Errorf("expected ducked level %f after the attack, got %f", ducked, got)
+	}
+
+	// Drain the prompt, then give the release time to finish
+	tick(50 * time.Millisecond)
+	tick(10 * time.Millisecond)
+	out = tick(duckRelease)
+	if got := out[len(out)-1][0]; math.Abs(got-1) > 1e-9 {
+		t.Errorf("expected full level after the release, got %f", got)
+	}
+	for i := 1; i < len(out); i++ {
+		if out[i][0] < out[i-1][0] {
+			t.Fatalf("release is not monotonic at sample %d", i)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package playback
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// ErrFixedDevice is returned by ReconfigureDevice when the output can't be
+// moved to another device
+var ErrFixedDevice = errors.New("the default ALSA output can't change device")
+
+// Output plays the mix of a Playback. Lock keeps it from pulling samples
+// while the streamers are changed.
+type Output interface {
+	Play(s beep.Streamer) error
+	Lock()
+	Unlock()
+	Close()
+}
+
+// DeviceOutput is an Output on a named ALSA device that can be moved to
+// another one while playing, e.g. after the sound card re-enumerated
+type DeviceOutput interface {
+	Output
+	Device() string
+	ReconfigureDevice(device string) error
+}
+
+// speakerOutput plays through the beep speaker on the default ALSA output.
+// The speaker is opened once per process and can't be reopened.
+type speakerOutput struct {
+	sampleRate beep.SampleRate
+}
+
+var _ Output = speakerOutput{}
+
+func (o speakerOutput) Play(s beep.Streamer) error {
+	if err := speaker.Init(o.sampleRate, o.sampleRate.N(speakerBuffer)); err != nil {
+		return fmt.Errorf("failed to initialize speaker: %w", err)
+	}
+	speaker.Play(s)
+	return nil
+}
+
+func (speakerOutput) Lock()   { speaker.Lock() }
+func (speakerOutput) Unlock() { speaker.Unlock() }
+func (speakerOutput) Close()  { speaker.Close() }
@@ -2,6 +2,7 @@ package playback
 
 import (
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/disgoorg/audio/pcm"
@@ -10,55 +11,79 @@ import (
 )
 
 var ErrAlreadyClosed = errors.New("already closed")
+var ErrNotClosed = errors.New("not closed")
 var SampleRate = beep.SampleRate(48000)
 
+// DefaultMaxConcealment is how long a lost packet is concealed before the
+// streamer falls back to silence
+const DefaultMaxConcealment = 60 * time.Millisecond
+
 type PCMStreamer struct {
 	silence   *pcm.Packet
 	pcm       []int16
 	pcmIdx    int
 	lastFrame [2]float64
 	fadeLevel float64
+	fadeStep  float64
 
-	packets  chan *pcm.Packet
-	closedCh chan struct{}
-	closed   bool
+	packets   chan *pcm.Packet
+	closedCh  chan struct{}
+	closeOnce sync.Once
 }
 
 var _ beep.Streamer = (*PCMStreamer)(nil)
 var _ StreamSource = (*PCMStreamer)(nil)
 
 func NewPCMStreamer(packets chan *pcm.Packet) *PCMStreamer {
-	return &PCMStreamer{
-		silence:  &pcm.Packet{PCM: make([]int16, SampleRate.N(20*time.Millisecond)*2)},
-		packets:  packets,
-		closedCh: make(chan struct{}),
+	s := &PCMStreamer{
+		silence:   &pcm.Packet{PCM: make([]int16, SampleRate.N(20*time.Millisecond)*2)},
+		fadeLevel: 1.0, // start full volume
+		packets:   packets,
+		closedCh:  make(chan struct{}),
+	}
+	s.SetMaxConcealment(DefaultMaxConcealment)
+	return s
+}
+
+// SetMaxConcealment sets how long the last received frame is faded out
+// when the packet channel under-runs. A zero duration disables concealment.
+func (s *PCMStreamer) SetMaxConcealment(d time.Duration) {
+	n := SampleRate.N(d)
+	if n <= 0 {
+		s.fadeStep = 1
+		return
 	}
+	s.fadeStep = 1 / float64(n)
 }
 
 func (s *PCMStreamer) Err() error {
 	return nil
 }
 
+// Close stops the streamer. It is safe to call from any goroutine; calls
+// after the first return ErrAlreadyClosed.
 func (s *PCMStreamer) Close() error {
-	if s.closed {
-		return ErrAlreadyClosed
-	}
-	s.closed = true
-	go func() {
-		s.closedCh <- struct{}{}
+	err := ErrAlreadyClosed
+	s.closeOnce.Do(func() {
 		close(s.closedCh)
-	}()
-	return nil
+		err = nil
+	})
+	return err
 }
 
-func (s *PCMStreamer) Stream(samples [][2]float64) (n int, ok bool) {
-	if s.closed {
-		return 0, false
+// isClosed reports whether Close has been called
+func (s *PCMStreamer) isClosed() bool {
+	select {
+	case <-s.closedCh:
+		return true
+	default:
+		return false
 	}
+}
 
-	// State for concealment
-	if s.fadeLevel == 0 {
-		s.fadeLevel = 1.0 // start full volume
+func (s *PCMStreamer) Stream(samples [][2]float64) (n int, ok bool) {
+	if s.isClosed() {
+		return 0, false
 	}
 
 	for n < len(samples) {
@@ -67,18 +92,36 @@ func (s *PCMStreamer) Stream(samples [][2]float64) (n int, ok bool) {
 			case packet := <-s.packets:
 				s.pcm = packet.PCM
 				s.pcmIdx = 0
-				s.fadeLevel = 1.0 // reset fade on new data
 			case <-s.closedCh:
 				return 0, false
+			default:
+				// Under-run: fade out the last frame instead of leaving a
+				// hard gap, then stay silent until data resumes
+				for ; n < len(samples); n++ {
+					s.fadeLevel -= s.fadeStep
+					if s.fadeLevel < 0 {
+						s.fadeLevel = 0
+					}
+					samples[n][0] = s.lastFrame[0] * s.fadeLevel
+					samples[n][1] = s.lastFrame[1] * s.fadeLevel
+				}
+				return n, true
 			}
 		}
 
 		for ; n < len(samples) && s.pcmIdx < len(s.pcm); n++ {
-			left := float64(s.pcm[s.pcmIdx]) / 32767
-			right := float64(s.pcm[s.pcmIdx+1]) / 32767
-			samples[n][0] = left
-			samples[n][1] = right
-			s.lastFrame = samples[n] // store for concealment
+			// Ramp back up from wherever the concealment left off so a
+			// resumed stream doesn't click
+			if s.fadeLevel < 1 {
+				s.fadeLevel += s.fadeStep
+				if s.fadeLevel > 1 {
+					s.fadeLevel = 1
+				}
+			}
+			s.lastFrame[0] = float64(s.pcm[s.pcmIdx]) / 32767
+			s.lastFrame[1] = float64(s.pcm[s.pcmIdx+1]) / 32767
+			samples[n][0] = s.lastFrame[0] * s.fadeLevel
+			samples[n][1] = s.lastFrame[1] * s.fadeLevel
 			s.pcmIdx += 2
 		}
 	}
@@ -95,11 +138,17 @@ func (m *PCMStreamer) GetStreamer() (beep.Streamer, beep.Format, error) {
 	}, beep.Format{SampleRate: SampleRate, NumChannels: 2}, nil
 }
 
-// Reopen copies the streamer's state but resets the PCM buffer
-func (s *PCMStreamer) Reopen() *PCMStreamer {
-	return &PCMStreamer{
-		silence:  s.silence,
-		packets:  s.packets,
-		closedCh: make(chan struct{}),
+// Reopen copies the streamer's state but resets the PCM buffer. The streamer
+// must be closed first, otherwise both would read from the same packets.
+func (s *PCMStreamer) Reopen() (*PCMStreamer, error) {
+	if !s.isClosed() {
+		return nil, ErrNotClosed
 	}
+	return &PCMStreamer{
+		silence:   s.silence,
+		fadeLevel: 1.0,
+		fadeStep:  s.fadeStep,
+		packets:   s.packets,
+		closedCh:  make(chan struct{}),
+	}, nil
 }
@@ -0,0 +1,140 @@
+package playback
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/audio/pcm"
+)
+
+func constantPacket(value int16, frames int) *pcm.Packet {
+	p := &pcm.Packet{PCM: make([]int16, frames*2)}
+	for i := range p.PCM {
+		p.PCM[i] = value
+	}
+	return p
+}
+
+func TestPCMStreamerConcealsGap(t *testing.T) {
+	packets := make(chan *pcm.Packet, 8)
+	s := NewPCMStreamer(packets)
+	s.SetMaxConcealment(DefaultMaxConcealment)
+
+	frame := SampleRate.N(20 * time.Millisecond)
+	var out [][2]float64
+	stream := func(n int) {
+		buf := make([][2]float64, n)
+		got, ok := s.Stream(buf)
+		if !ok || got != n {
+			t.Fatalf("Stream() = %d, %v, want %d, true", got, ok, n)
+		}
+		out = append(out, buf...)
+	}
+
+	// Two packets, a gap longer than the concealment window, then data again
+	packets <- constantPacket(16384, frame)
+	packets <- constantPacket(16384, frame)
+	stream(frame * 2)
+	stream(SampleRate.N(100 * time.Millisecond))
+	for i := 0; i < 4; i++ {
+		packets <- constantPacket(16384, frame)
+	}
+	stream(frame * 4)
+
+	maxStep := s.fadeStep + 1e-9
+	for i := 1; i < len(out); i++ {
+		if d := math.Abs(out[i][0] - out[i-1][0]); d > maxStep {
+			t.Fatalf("discontinuity of %f at sample %d exceeds step %f", d, i, maxStep)
+		}
+	}
+
+	// The gap is longer than the concealment window so it must reach silence
+	gapEnd := frame*2 + SampleRate.N(100*time.Millisecond) - 1
+	if out[gapEnd][0] != 0 {
+		t.Errorf("expected silence after concealment window, got %f", out[gapEnd][0])
+	}
+
+	// And the resumed stream must be back at full level
+	if got := out[len(out)-1][0]; math.Abs(got-16384.0/32767) > 1e-9 {
+		t.Errorf("expected full level after resume, got %f", got)
+	}
+}
+
+func TestPCMStreamerZeroConcealment(t *testing.T) {
+	packets := make(chan *pcm.Packet, 1)
+	s := NewPCMStreamer(packets)
+	s.SetMaxConcealment(0)
+
+	packets <- constantPacket(16384, 10)
+	buf := make([][2]float64, 20)
+	if _, ok := s.Stream(buf); !ok {
+		t.Fatal("Stream() returned !ok")
+	}
+	if buf[10][0] != 0 {
+		t.Errorf("expected immediate silence with concealment disabled, got %f", buf[10][0])
+	}
+}
+
+// Run with -race: Close is called from the Discord event goroutine while the
+// speaker goroutine is streaming
+func TestPCMStreamerCloseConcurrent(t *testing.T) {
+	packets := make(chan *pcm.Packet, 1)
+	s := NewPCMStreamer(packets)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([][2]float64, 64)
+		for {
+			if _, ok := s.Stream(buf); !ok {
+				return
+			}
+		}
+	}()
+
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() { errs <- s.Close() }()
+	}
+
+	var nilCount int
+	for i := 0; i < cap(errs); i++ {
+		switch err := <-errs; err {
+		case nil:
+			nilCount++
+		case ErrAlreadyClosed:
+		default:
+			t.Fatalf("unexpected error from Close: %v", err)
+		}
+	}
+	if nilCount != 1 {
+		t.Fatalf("expected exactly one successful Close, got %d", nilCount)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stream did not stop after Close")
+	}
+}
+
+func TestPCMStreamerReopen(t *testing.T) {
+	s := NewPCMStreamer(make(chan *pcm.Packet))
+
+	if _, err := s.Reopen(); err != ErrNotClosed {
+		t.Fatalf("Reopen() on open streamer = %v, want ErrNotClosed", err)
+	}
+
+	s.Close()
+	reopened, err := s.Reopen()
+	if err != nil {
+		t.Fatalf("Reopen() = %v", err)
+	}
+	if _, ok := reopened.Stream(make([][2]float64, 1)); !ok {
+		t.Fatal("reopened streamer should stream")
+	}
+	if err := reopened.Close(); err != nil {
+		t.Fatalf("Close() on reopened streamer = %v", err)
+	}
+}
@@ -1,40 +1,67 @@
 package playback
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/gopxl/beep/v2"
 	"github.com/gopxl/beep/v2/effects"
-	"github.com/gopxl/beep/v2/speaker"
 )
 
-// NewPlayback creates a new Playback instance
+// speakerBuffer is how much audio the speaker holds ahead of ALSA
+const speakerBuffer = 100 * time.Millisecond
+
+// errDraining is returned when audio is added while the playback drains
+var errDraining = errors.New("playback is draining")
+
+// NewPlayback creates a new Playback instance on the default ALSA output
 func NewPlayback(sampleRate beep.SampleRate) (*Playback, error) {
-	// Initialize the speaker with the given sample rate
-	err := speaker.Init(sampleRate, sampleRate.N(100*time.Millisecond))
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize speaker: %w", err)
-	}
+	return NewPlaybackOutput(sampleRate, speakerOutput{sampleRate: sampleRate})
+}
 
+// NewPlaybackOutput creates a new Playback instance playing through out
+func NewPlaybackOutput(sampleRate beep.SampleRate, out Output) (*Playback, error) {
 	mixer := &beep.Mixer{}
 	ctrl := &beep.Ctrl{Streamer: mixer}
 
 	playback := &Playback{
+		out:        out,
 		mixer:      mixer,
 		ctrl:       ctrl,
 		sampleRate: sampleRate,
-		queue:      &Queue{},
+		queue:      NewQueue(sampleRate),
 	}
 
 	mixer.Add(playback.queue)
 
 	// Start playing the mixer
-	speaker.Play(ctrl)
+	if err := out.Play(ctrl); err != nil {
+		return nil, err
+	}
 
 	return playback, nil
 }
 
+// Device returns the ALSA device played to, empty for the default output
+func (p *Playback) Device() string {
+	if out, ok := p.out.(DeviceOutput); ok {
+		return out.Device()
+	}
+	return ""
+}
+
+// ReconfigureDevice moves the playback to device, the queue and the streams
+// keep playing. It returns ErrFixedDevice on the default output.
+func (p *Playback) ReconfigureDevice(device string) error {
+	out, ok := p.out.(DeviceOutput)
+	if !ok {
+		return ErrFixedDevice
+	}
+	return out.ReconfigureDevice(device)
+}
+
 // AddStream adds a new audio stream to the playback mixer
 func (p *Playback) AddStream(source StreamSource) error {
 	p.mu.Lock()
@@ -43,6 +70,9 @@ func (p *Playback) AddStream(source StreamSource) error {
 	if p.closed {
 		return fmt.Errorf("playback is closed")
 	}
+	if p.draining {
+		return errDraining
+	}
 
 	streamer, format, err := source.GetStreamer()
 	if err != nil {
@@ -51,40 +81,106 @@ func (p *Playback) AddStream(source StreamSource) error {
 
 	// Resample if necessary to match the speaker's sample rate
 	if format.SampleRate != p.sampleRate {
-		resampled := beep.Resample(4, format.SampleRate, p.sampleRate, streamer)
-		// Add to mixer
-		p.mixer.Add(resampled)
-		p.streamers = append(p.streamers, resampled)
-	} else {
-		// Add to mixer
-		p.mixer.Add(streamer)
-		p.streamers = append(p.streamers, streamer)
+		streamer = beep.Resample(4, format.SampleRate, p.sampleRate, streamer)
+	}
+
+	// Live streams are turned down while a prompt plays
+	if p.duckDepth != 0 {
+		streamer = NewDucker(streamer, p.sampleRate, p.duckDepth, p.queue.Active)
 	}
 
+	// Add to mixer
+	p.mixer.Add(streamer)
+	p.streamers = append(p.streamers, streamer)
+
 	return nil
 }
 
+// SetDuckDepth sets by how many decibels streams added with AddStream are
+// attenuated while queued prompts play. Zero disables ducking. It applies to
+// streams added afterwards.
+func (p *Playback) SetDuckDepth(depthDB float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.duckDepth = depthDB
+}
+
 // AddPredecoded is a convenience method to add a predecoded audio file
 func (p *Playback) AddPredecoded(filePath string) error {
+	_, err := p.EnqueuePredecoded(filePath)
+	return err
+}
+
+// EnqueuePredecoded queues a predecoded audio file and returns its handle in
+// the queue
+func (p *Playback) EnqueuePredecoded(filePath string) (Handle, error) {
+	if p.isDraining() {
+		return 0, errDraining
+	}
+
 	src := &PredecodedSource{FilePath: filePath}
-	streamer, format, err := src.GetStreamer()
+	streamer, format, length, err := src.stream()
 	if err != nil {
-		return fmt.Errorf("failed to get streamer: %w", err)
+		return 0, fmt.Errorf("failed to get streamer: %w", err)
 	}
 
+	// The queue counts samples at the speaker rate
+	length = int(int64(length) * int64(p.sampleRate) / int64(format.SampleRate))
+
 	resampled := beep.Resample(4, format.SampleRate, p.sampleRate, streamer)
 
-	p.queue.Add(resampled)
+	return p.queue.Enqueue(filePath, length, resampled), nil
+}
+
+// AddTone queues the DTMF tone of a keypad digit, or ToneBeep/ToneError,
+// synthesized at the speaker sample rate
+func (p *Playback) AddTone(digit string, duration time.Duration) error {
+	if p.isDraining() {
+		return errDraining
+	}
+
+	tone, err := NewNamedTone(p.sampleRate, digit, duration)
+	if err != nil {
+		return err
+	}
+
+	p.queue.Enqueue("tone:"+digit, tone.Len(), tone)
 	return nil
 }
 
+// Position returns how many queued items are ahead of h, see Queue.Position
+func (p *Playback) Position(h Handle) (int, bool) {
+	return p.queue.Position(h)
+}
+
+// EstimatedStart returns how long until h starts playing, see
+// Queue.EstimatedStart
+func (p *Playback) EstimatedStart(h Handle) (time.Duration, bool) {
+	return p.queue.EstimatedStart(h)
+}
+
+// Dequeue removes a queued item, see Queue.Remove
+func (p *Playback) Dequeue(h Handle) bool {
+	return p.queue.Remove(h)
+}
+
+// ClearQueue drops every queued prompt and tone, see Queue.Clear
+func (p *Playback) ClearQueue() int {
+	return p.queue.Clear()
+}
+
+// Queued returns the names of the queued items, the playing one first
+func (p *Playback) Queued() []string {
+	return p.queue.Names()
+}
+
 // SetVolume sets the volume for the entire playback (0.0 to 1.0)
 func (p *Playback) SetVolume(volume float64) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	if !p.closed {
-		speaker.Lock()
+		p.out.Lock()
 		p.mixer.Clear()
 		// Re-add all streamers with volume control
 		for _, s := range p.streamers {
@@ -96,7 +192,7 @@ func (p *Playback) SetVolume(volume float64) {
 			}
 			p.mixer.Add(volumeStreamer)
 		}
-		speaker.Unlock()
+		p.out.Unlock()
 	}
 }
 
@@ -106,9 +202,9 @@ func (p *Playback) Pause() {
 	defer p.mu.Unlock()
 
 	if !p.closed {
-		speaker.Lock()
+		p.out.Lock()
 		p.ctrl.Paused = true
-		speaker.Unlock()
+		p.out.Unlock()
 	}
 }
 
@@ -118,9 +214,9 @@ func (p *Playback) Resume() {
 	defer p.mu.Unlock()
 
 	if !p.closed {
-		speaker.Lock()
+		p.out.Lock()
 		p.ctrl.Paused = false
-		speaker.Unlock()
+		p.out.Unlock()
 	}
 }
 
@@ -130,13 +226,49 @@ func (p *Playback) Stop() {
 	defer p.mu.Unlock()
 
 	if !p.closed {
-		speaker.Lock()
+		p.out.Lock()
 		p.mixer.Clear()
 		p.streamers = nil
-		speaker.Unlock()
+		p.out.Unlock()
 	}
 }
 
+// Drain stops accepting new audio and waits for the queued prompts to play
+// out and the speaker buffer to reach ALSA, so the caller hears the end of
+// the last prompt. Whatever is left when ctx is done is cut off. The playback
+// is closed afterwards.
+func (p *Playback) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	p.draining = true
+	p.mu.Unlock()
+
+	defer p.Close()
+
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+
+	for p.queue.Len() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	select {
+	case <-time.After(speakerBuffer):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Playback) isDraining() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.draining
+}
+
 // Close closes the playback and releases resources
 func (p *Playback) Close() error {
 	p.mu.Lock()
@@ -148,13 +280,13 @@ func (p *Playback) Close() error {
 
 	p.closed = true
 
-	speaker.Lock()
+	p.out.Lock()
 	p.mixer.Clear()
 	p.streamers = nil
-	speaker.Unlock()
+	p.out.Unlock()
 
-	// Close the speaker
-	speaker.Close()
+	// Close the output
+	p.out.Close()
 
 	return nil
 }
@@ -168,9 +300,9 @@ func (p *Playback) IsPlaying() bool {
 		return false
 	}
 
-	speaker.Lock()
+	p.out.Lock()
 	playing := !p.ctrl.Paused
-	speaker.Unlock()
+	p.out.Unlock()
 
 	return playing
 }
@@ -1,35 +1,180 @@
 package playback
 
-import "github.com/gopxl/beep/v2"
+import (
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/gopxl/beep/v2"
+)
+
+// Handle identifies an item added to a Queue
+type Handle uint64
+
+// queueItem is a queued streamer with what's known about its length
+type queueItem struct {
+	handle   Handle
+	name     string
+	streamer beep.Streamer
+	length   int // in samples at the queue sample rate, 0 if unknown
+	played   int
+}
+
+// remaining returns how many samples of the item are left to play
+func (i *queueItem) remaining() int {
+	return max(i.length-i.played, 0)
+}
+
+// Queue plays streamers one after the other, and silence when it's empty
 type Queue struct {
-	streamers []beep.Streamer
+	mu         sync.Mutex
+	items      []*queueItem
+	next       Handle
+	sampleRate beep.SampleRate
+	active     atomic.Bool
 }
 
+// NewQueue creates a queue whose wait estimates use the given sample rate
+func NewQueue(sampleRate beep.SampleRate) *Queue {
+	return &Queue{sampleRate: sampleRate}
+}
+
+// Active reports whether the queue played something during its last Stream
+// call, as opposed to filling with silence
+func (q *Queue) Active() bool {
+	return q.active.Load()
+}
+
+// Add queues streamers without a name. Their length is known only if they
+// implement Len, like beep.StreamSeeker.
 func (q *Queue) Add(streamers ...beep.Streamer) {
-	q.streamers = append(q.streamers, streamers...)
+	for _, s := range streamers {
+		length := 0
+		if l, ok := s.(interface{ Len() int }); ok {
+			length = l.Len()
+		}
+		q.Enqueue("", length, s)
+	}
+}
+
+// Enqueue queues a streamer lasting length samples at the queue sample rate
+// and returns a handle to follow it with Position and EstimatedStart
+func (q *Queue) Enqueue(name string, length int, streamer beep.Streamer) Handle {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.next++
+	q.items = append(q.items, &queueItem{
+		handle:   q.next,
+		name:     name,
+		streamer: streamer,
+		length:   length,
+	})
+	return q.next
+}
+
+// Remove drops an item from the queue, cutting it off if it's playing. It
+// returns false if the item already finished.
+func (q *Queue) Remove(h Handle) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, item := range q.items {
+		if item.handle == h {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Clear drops every item, cutting off the playing one, and returns how many
+// there were
+func (q *Queue) Clear() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := len(q.items)
+	q.items = nil
+	return n
+}
+
+// Position returns how many items are ahead of h, 0 meaning it's playing.
+// ok is false once the item finished or was removed.
+func (q *Queue) Position(h Handle) (position int, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, item := range q.items {
+		if item.handle == h {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// EstimatedStart returns how long until h starts playing, from what's left of
+// the items ahead. Items of unknown length count as already finished. ok is
+// false once the item finished or was removed.
+func (q *Queue) EstimatedStart(h Handle) (wait time.Duration, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	samples := 0
+	for _, item := range q.items {
+		if item.handle == h {
+			if q.sampleRate == 0 {
+				return 0, true
+			}
+			return q.sampleRate.D(samples), true
+		}
+		samples += item.remaining()
+	}
+	return 0, false
+}
+
+// Names returns the names of the queued items, the playing one first
+func (q *Queue) Names() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	names := make([]string, len(q.items))
+	for i, item := range q.items {
+		names[i] = item.name
+	}
+	return names
+}
+
+// Len returns the number of items waiting or playing
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
 }
 
 func (q *Queue) Stream(samples [][2]float64) (n int, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
 	// We use the filled variable to track how many samples we've
 	// successfully filled already. We loop until all samples are filled.
 	filled := 0
+	q.active.Store(len(q.items) > 0)
 	for filled < len(samples) {
 		// There are no streamers in the queue, so we stream silence.
-		if len(q.streamers) == 0 {
-			for i := range samples[filled:] {
-				samples[i][0] = 0
-				samples[i][1] = 0
-			}
+		if len(q.items) == 0 {
+			clear(samples[filled:])
 			break
 		}
 
 		// We stream from the first streamer in the queue.
-		n, ok := q.streamers[0].Stream(samples[filled:])
+		item := q.items[0]
+		n, ok := item.streamer.Stream(samples[filled:])
+		item.played += n
 		// If it's drained, we pop it from the queue, thus continuing with
 		// the next streamer.
 		if !ok {
-			q.streamers = q.streamers[1:]
+			q.items = q.items[1:]
 		}
 		// We update the number of filled samples.
 		filled += n
@@ -0,0 +1,92 @@
+package playback
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep/v2/generators"
+)
+
+func TestQueueEstimates(t *testing.T) {
+	q := NewQueue(SampleRate)
+
+	second := SampleRate.N(time.Second)
+	a := q.Enqueue("a", second, generators.Silence(second))
+	b := q.Enqueue("b", 2*second, generators.Silence(2*second))
+	c := q.Enqueue("c", second/2, generators.Silence(second/2))
+
+	check := func(h Handle, wantPos int, wantWait time.Duration) {
+		t.Helper()
+		pos, ok := q.Position(h)
+		if !ok || pos != wantPos {
+			t.Errorf("Position(%d) = %d, %v, want %d", h, pos, ok, wantPos)
+		}
+		wait, ok := q.EstimatedStart(h)
+		if !ok || wait != wantWait {
+			t.Errorf("EstimatedStart(%d) = %s, %v, want %s", h, wait, ok, wantWait)
+		}
+	}
+
+	check(a, 0, 0)
+	check(b, 1, time.Second)
+	check(c, 2, 3*time.Second)
+
+	// Half of a played
+	q.Stream(make([][2]float64, second/2))
+	check(a, 0, 0)
+	check(b, 1, 500*time.Millisecond)
+	check(c, 2, 2500*time.Millisecond)
+
+	// Past the end of a and into b
+	q.Stream(make([][2]float64, second))
+	if _, ok := q.Position(a); ok {
+		t.Error("finished item should no longer be queued")
+	}
+	check(b, 0, 0)
+	check(c, 1, 1500*time.Millisecond)
+
+	if !q.Remove(b) {
+		t.Fatal("Remove(b) = false")
+	}
+	check(c, 0, 0)
+	if q.Remove(b) {
+		t.Error("Remove of a removed item should return false")
+	}
+}
+
+func TestQueueAddUsesStreamerLength(t *testing.T) {
+	q := NewQueue(SampleRate)
+
+	tone := NewTone(SampleRate, 200*time.Millisecond, 440)
+	q.Add(tone, generators.Silence(-1))
+	q.Enqueue("last", 0, generators.Silence(10))
+
+	names := q.Names()
+	if len(names) != 3 || names[2] != "last" {
+		t.Fatalf("Names() = %q", names)
+	}
+
+	// The tone knows its length, the endless silence counts as nothing
+	wait, ok := q.EstimatedStart(3)
+	if !ok || wait != 200*time.Millisecond {
+		t.Errorf("EstimatedStart() = %s, %v, want 200ms", wait, ok)
+	}
+}
+
+func TestQueueClear(t *testing.T) {
+	q := NewQueue(SampleRate)
+
+	second := SampleRate.N(time.Second)
+	a := q.Enqueue("a", second, generators.Silence(second))
+	q.Enqueue("b", second, generators.Silence(second))
+
+	if n := q.Clear(); n != 2 {
+		t.Errorf("Clear() = %d, want 2", n)
+	}
+	if q.Len() != 0 {
+		t.Errorf("Len() after Clear() = %d", q.Len())
+	}
+	if _, ok := q.Position(a); ok {
+		t.Error("cleared item still has a position")
+	}
+}
@@ -9,19 +9,30 @@ import (
 	"github.com/gopxl/beep/v2/effects"
 )
 
+// Samples trimmed from the start and end of prompts, the generated TTS clips
+// have silence on both sides
+const (
+	promptTrimStart = 3000
+	promptTrimEnd   = 7000
+)
+
 // GetStreamer implements StreamSource for PredecodedSource
 func (p *PredecodedSource) GetStreamer() (beep.Streamer, beep.Format, error) {
-	cache := assets.GetPredecodedCache()
-	audio, exists := cache.GetAudio(p.FilePath)
-	if !exists {
-		return nil, beep.Format{}, fmt.Errorf("predecoded audio not found: %s", p.FilePath)
+	streamer, format, _, err := p.stream()
+	return streamer, format, err
+}
+
+// stream returns the prompt streamer along with its length in samples
+func (p *PredecodedSource) stream() (beep.Streamer, beep.Format, int, error) {
+	// Create a new streamer from the buffer, without the TTS padding
+	streamer, format, err := assets.GetPredecodedCache().Streamer(p.FilePath, promptTrimStart, promptTrimEnd)
+	if err != nil {
+		return nil, beep.Format{}, 0, fmt.Errorf("predecoded audio not available: %w", err)
 	}
 
-	// Create a new streamer from the buffer
-	streamer := audio.Buffer.Streamer(3000, audio.Buffer.Len()-7000)
 	return &effects.Volume{
 		Streamer: streamer,
 		Base:     2,
 		Volume:   -0.5,
-	}, audio.Format, nil
+	}, format, streamer.Len(), nil
 }
@@ -0,0 +1,112 @@
+package playback
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// Named tones accepted by NewNamedTone besides the DTMF digits
+const (
+	ToneBeep  = "beep"  // confirmation, also used before recording
+	ToneError = "error" // rejected input
+)
+
+// dtmfFrequencies maps each keypad key to its low and high frequency in Hz
+var dtmfFrequencies = map[string][2]float64{
+	"1": {697, 1209}, "2": {697, 1336}, "3": {697, 1477}, "A": {697, 1633},
+	"4": {770, 1209}, "5": {770, 1336}, "6": {770, 1477}, "B": {770, 1633},
+	"7": {852, 1209}, "8": {852, 1336}, "9": {852, 1477}, "C": {852, 1633},
+	"*": {941, 1209}, "0": {941, 1336}, "#": {941, 1477}, "D": {941, 1633},
+}
+
+// namedTones are the non-DTMF feedback tones
+var namedTones = map[string][]float64{
+	ToneBeep:  {1000},
+	ToneError: {480, 620},
+}
+
+// toneRamp is the fade applied to both ends of a tone to avoid clicks
+const toneRamp = 5 * time.Millisecond
+
+// ToneStreamer synthesizes a sum of sine waves for a fixed duration
+type ToneStreamer struct {
+	freqs      []float64
+	sampleRate beep.SampleRate
+	pos        int
+	total      int
+	ramp       int
+}
+
+var _ beep.Streamer = (*ToneStreamer)(nil)
+
+// NewTone returns a streamer playing the given frequencies together
+func NewTone(sampleRate beep.SampleRate, duration time.Duration, freqs ...float64) *ToneStreamer {
+	total := sampleRate.N(duration)
+	return &ToneStreamer{
+		freqs:      freqs,
+		sampleRate: sampleRate,
+		total:      total,
+		ramp:       min(sampleRate.N(toneRamp), total/2),
+	}
+}
+
+// NewNamedTone returns the DTMF tone of a keypad digit, or one of ToneBeep
+// and ToneError
+func NewNamedTone(sampleRate beep.SampleRate, name string, duration time.Duration) (*ToneStreamer, error) {
+	if pair, ok := dtmfFrequencies[name]; ok {
+		return NewTone(sampleRate, duration, pair[0], pair[1]), nil
+	}
+	if freqs, ok := namedTones[name]; ok {
+		return NewTone(sampleRate, duration, freqs...), nil
+	}
+	return nil, fmt.Errorf("unknown tone %q", name)
+}
+
+func (t *ToneStreamer) Stream(samples [][2]float64) (n int, ok bool) {
+	if t.pos >= t.total {
+		return 0, false
+	}
+
+	// Split the headroom between the frequencies so the sum never clips
+	amplitude := 0.5 / float64(len(t.freqs))
+	rate := float64(t.sampleRate)
+
+	for ; n < len(samples) && t.pos < t.total; n++ {
+		var v float64
+		for _, f := range t.freqs {
+			v += math.Sin(2 * math.Pi * f * float64(t.pos) / rate)
+		}
+		v *= amplitude * t.envelope()
+
+		samples[n][0] = v
+		samples[n][1] = v
+		t.pos++
+	}
+	return n, true
+}
+
+// envelope returns the fade in/out gain at the current position
+func (t *ToneStreamer) envelope() float64 {
+	if t.ramp <= 0 {
+		return 1
+	}
+	if t.pos < t.ramp {
+		return float64(t.pos) / float64(t.ramp)
+	}
+	if remaining := t.total - t.pos; remaining < t.ramp {
+		return float64(remaining) / float64(t.ramp)
+	}
+	return 1
+}
+
+// Len returns the length of the tone in samples
+func (t *ToneStreamer) Len() int {
+	return t.total
+}
+
+func (t *ToneStreamer) Err() error {
+	return nil
+}
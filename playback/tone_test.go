@@ -0,0 +1,86 @@
+package playback
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// goertzel returns the power of freq in the left channel of samples
+func goertzel(samples [][2]float64, freq float64, rate float64) float64 {
+	coeff := 2 * math.Cos(2*math.Pi*freq/rate)
+	var s1, s2 float64
+	for _, sample := range samples {
+		s0 := sample[0] + coeff*s1 - s2
+		s2, s1 = s1, s0
+	}
+	return s1*s1 + s2*s2 - coeff*s1*s2
+}
+
+func render(t *testing.T, tone *ToneStreamer) [][2]float64 {
+	t.Helper()
+	var out [][2]float64
+	buf := make([][2]float64, 512)
+	for {
+		n, ok := tone.Stream(buf)
+		if !ok {
+			return out
+		}
+		out = append(out, buf[:n]...)
+	}
+}
+
+func TestDTMFToneFrequencies(t *testing.T) {
+	rows := []float64{697, 770, 852, 941}
+	cols := []float64{1209, 1336, 1477, 1633}
+
+	for digit, pair := range dtmfFrequencies {
+		tone, err := NewNamedTone(SampleRate, digit, 100*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples := render(t, tone)
+		if len(samples) != SampleRate.N(100*time.Millisecond) {
+			t.Fatalf("digit %s: got %d samples", digit, len(samples))
+		}
+
+		// The expected pair must dominate every other row and column frequency
+		for _, group := range [][]float64{rows, cols} {
+			var want float64
+			var strongest, strongestPower float64
+			for _, f := range group {
+				p := goertzel(samples, f, float64(SampleRate))
+				if f == pair[0] || f == pair[1] {
+					want = f
+				}
+				if p > strongestPower {
+					strongest, strongestPower = f, p
+				}
+			}
+			if strongest != want {
+				t.Errorf("digit %s: strongest frequency %v Hz, want %v Hz", digit, strongest, want)
+			}
+		}
+	}
+}
+
+func TestNamedTones(t *testing.T) {
+	tone, err := NewNamedTone(SampleRate, ToneBeep, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := render(t, tone)
+	if on, off := goertzel(samples, 1000, float64(SampleRate)), goertzel(samples, 1500, float64(SampleRate)); on < 100*off {
+		t.Errorf("beep: 1000 Hz power %f not dominant over %f", on, off)
+	}
+
+	for _, s := range samples {
+		if math.Abs(s[0]) > 1 {
+			t.Fatalf("sample %f clips", s[0])
+		}
+	}
+
+	if _, err := NewNamedTone(SampleRate, "x", time.Second); err == nil {
+		t.Error("expected an error for an unknown tone")
+	}
+}
@@ -9,13 +9,16 @@ import (
 
 // Playback represents a single playback instance that can mix multiple audio streams
 type Playback struct {
+	out        Output
 	mixer      *beep.Mixer
 	ctrl       *beep.Ctrl
 	mu         sync.RWMutex
 	streamers  []beep.Streamer
 	closed     bool
+	draining   bool
 	sampleRate beep.SampleRate
 	queue      *Queue
+	duckDepth  float64
 }
 
 // StreamSource represents different types of audio input sources
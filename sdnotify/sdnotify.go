@@ -0,0 +1,65 @@
+// Package sdnotify implements the systemd notify protocol, telling the
+// service manager when the bridge is ready, what it's doing and that it's
+// still alive. Every function does nothing when the process wasn't started
+// by systemd with Type=notify.
+package sdnotify
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// States understood by systemd, see sd_notify(3)
+const (
+	Ready    = "READY=1"
+	Reload   = "RELOADING=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Enabled reports whether systemd listens to notifications
+func Enabled() bool {
+	return os.Getenv("NOTIFY_SOCKET") != ""
+}
+
+// Notify sends state, e.g. Ready, to the socket in NOTIFY_SOCKET. A socket
+// name starting with @ is in the abstract namespace.
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("failed to connect to the notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("failed to notify systemd: %w", err)
+	}
+	return nil
+}
+
+// Status sets the status shown by systemctl status
+func Status(status string) error {
+	return Notify("STATUS=" + status)
+}
+
+// WatchdogInterval returns how often systemd expects Watchdog, from
+// WATCHDOG_USEC, or 0 if the watchdog is disabled or meant for another
+// process
+func WatchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
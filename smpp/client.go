@@ -0,0 +1,358 @@
+package smpp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by requests made after the connection was lost
+var ErrClosed = errors.New("smpp: connection closed")
+
+// Config holds the settings of an SMPP transceiver bind
+type Config struct {
+	Addr            string // host:port of the SMSC
+	TLS             bool
+	SystemID        string
+	Password        string
+	SystemType      string
+	SourceAddr      string        // sender shown to recipients
+	SourceTON       byte          // type of number of SourceAddr
+	SourceNPI       byte          // numbering plan of SourceAddr
+	EnquireLink     time.Duration // keepalive interval, 0 uses 30s
+	ResponseTimeout time.Duration // how long a request waits for its response, 0 uses 10s
+}
+
+// Client is an ESME bound as a transceiver
+type Client struct {
+	cfg       Config
+	conn      net.Conn
+	logger    *slog.Logger
+	onMessage func(Message)
+	onError   func(error)
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	seq     uint32
+	waiting map[uint32]chan *PDU
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	parts     reassembler
+}
+
+// Dial connects to the SMSC and binds as a transceiver. Received messages
+// are passed to onMessage and asynchronous errors to onError, both are
+// called from the connection's reader goroutine.
+func Dial(ctx context.Context, cfg Config, onMessage func(Message), onError func(error)) (*Client, error) {
+	if cfg.EnquireLink == 0 {
+		cfg.EnquireLink = 30 * time.Second
+	}
+	if cfg.ResponseTimeout == 0 {
+		cfg.ResponseTimeout = 10 * time.Second
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMSC: %w", err)
+	}
+	if cfg.TLS {
+		host, _, _ := net.SplitHostPort(cfg.Addr)
+		conn = tls.Client(conn, &tls.Config{ServerName: host})
+	}
+
+	c := &Client{
+		cfg:       cfg,
+		conn:      conn,
+		logger:    slog.With("component", "smpp"),
+		onMessage: onMessage,
+		onError:   onError,
+		waiting:   make(map[uint32]chan *PDU),
+		closed:    make(chan struct{}),
+	}
+	go c.readLoop()
+
+	var body bodyWriter
+	body.cstring(cfg.SystemID)
+	body.cstring(cfg.Password)
+	body.cstring(cfg.SystemType)
+	body.byte(interfaceVersion)
+	body.byte(0) // addr_ton
+	body.byte(0) // addr_npi
+	body.cstring("")
+
+	if _, err := c.request(ctx, BindTransceiver, body.Bytes()); err != nil {
+		c.shutdown()
+		return nil, fmt.Errorf("bind failed: %w", err)
+	}
+
+	go c.keepAlive()
+	return c, nil
+}
+
+// Submit sends text to number with submit_sm and returns the message ID the
+// SMSC assigned. Texts too long for one short message are sent in the
+// message_payload parameter and split by the SMSC.
+func (c *Client) Submit(ctx context.Context, number, text string) (string, error) {
+	coding, data := encodeText(text)
+
+	var body bodyWriter
+	body.cstring("") // service_type
+	body.byte(c.cfg.SourceTON)
+	body.byte(c.cfg.SourceNPI)
+	body.cstring(c.cfg.SourceAddr)
+	// SMPP addresses carry the type of number instead of a leading +
+	destTON := byte(0) // unknown
+	if international, ok := strings.CutPrefix(number, "+"); ok {
+		destTON, number = 1, international
+	}
+	body.byte(destTON)
+	body.byte(1) // dest_addr_npi: ISDN
+	body.cstring(number)
+	body.byte(0)     // esm_class
+	body.byte(0)     // protocol_id
+	body.byte(0)     // priority_flag
+	body.cstring("") // schedule_delivery_time
+	body.cstring("") // validity_period
+	body.byte(0)     // registered_delivery
+	body.byte(0)     // replace_if_present_flag
+	body.byte(coding)
+	body.byte(0) // sm_default_msg_id
+	if len(data) <= maxShortMessageOctets {
+		body.byte(byte(len(data)))
+		body.Write(data)
+	} else {
+		body.byte(0)
+		body.tlv(tagMessagePayload, data)
+	}
+
+	resp, err := c.request(ctx, SubmitSM, body.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	r := bodyReader{buf: resp.Body}
+	return r.cstring(), nil
+}
+
+// Closed returns a channel that's closed when the connection is lost
+func (c *Client) Closed() <-chan struct{} {
+	return c.closed
+}
+
+// Close unbinds from the SMSC and closes the connection
+func (c *Client) Close() error {
+	select {
+	case <-c.closed:
+		return nil
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := c.request(ctx, Unbind, nil); err != nil {
+		c.logger.Debug("Unbind failed", slog.Any("error", err))
+	}
+
+	c.shutdown()
+	return nil
+}
+
+// request sends a PDU and waits for its response
+func (c *Client) request(ctx context.Context, commandID uint32, body []byte) (*PDU, error) {
+	ch := make(chan *PDU, 1)
+
+	c.mu.Lock()
+	c.seq++
+	seq := c.seq
+	c.waiting[seq] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.waiting, seq)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(&PDU{CommandID: commandID, Sequence: seq, Body: body}); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(c.cfg.ResponseTimeout)
+	defer timer.Stop()
+
+	select {
+	case resp := <-ch:
+		if resp.Status != StatusOK {
+			return nil, StatusError(resp.Status)
+		}
+		return resp, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("smpp: no response to command 0x%08x", commandID)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.closed:
+		return nil, ErrClosed
+	}
+}
+
+func (c *Client) write(p *PDU) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if _, err := c.conn.Write(p.Bytes()); err != nil {
+		c.shutdown()
+		return fmt.Errorf("smpp: write failed: %w", err)
+	}
+	return nil
+}
+
+// respond answers a request from the SMSC
+func (c *Client) respond(req *PDU, commandID, status uint32, body []byte) {
+	if err := c.write(&PDU{CommandID: commandID, Status: status, Sequence: req.Sequence, Body: body}); err != nil {
+		c.logger.Debug("Failed to respond to SMSC", slog.Any("error", err))
+	}
+}
+
+func (c *Client) readLoop() {
+	defer c.shutdown()
+
+	for {
+		p, err := ReadPDU(c.conn)
+		if err != nil {
+			select {
+			case <-c.closed:
+			default:
+				c.reportError(fmt.Errorf("smpp: read failed: %w", err))
+			}
+			return
+		}
+
+		if p.CommandID&respBit != 0 {
+			c.mu.Lock()
+			ch, ok := c.waiting[p.Sequence]
+			c.mu.Unlock()
+			if ok {
+				ch <- p
+			}
+			continue
+		}
+
+		switch p.CommandID {
+		case DeliverSM:
+			c.respond(p, DeliverSMResp, StatusOK, []byte{0})
+			c.handleDeliver(p)
+		case EnquireLink:
+			c.respond(p, EnquireLinkResp, StatusOK, nil)
+		case Unbind:
+			c.respond(p, UnbindResp, StatusOK, nil)
+			c.logger.Info("SMSC unbound")
+			return
+		default:
+			c.respond(p, GenericNack, StatusInvalidCmd, nil)
+		}
+	}
+}
+
+// handleDeliver decodes a deliver_sm and passes complete messages on
+func (c *Client) handleDeliver(p *PDU) {
+	r := bodyReader{buf: p.Body}
+	r.cstring() // service_type
+	sourceTON := r.byte()
+	r.byte() // source_addr_npi
+	source := r.cstring()
+	r.byte() // dest_addr_ton
+	r.byte() // dest_addr_npi
+	r.cstring()
+	esmClass := r.byte()
+	r.byte()    // protocol_id
+	r.byte()    // priority_flag
+	r.cstring() // schedule_delivery_time
+	r.cstring() // validity_period
+	r.byte()    // registered_delivery
+	r.byte()    // replace_if_present_flag
+	coding := r.byte()
+	r.byte() // sm_default_msg_id
+	data := r.bytes(int(r.byte()))
+	tlvs := r.tlvs()
+	if r.err != nil {
+		c.reportError(fmt.Errorf("smpp: invalid deliver_sm: %w", r.err))
+		return
+	}
+
+	if sourceTON == 1 && !strings.HasPrefix(source, "+") {
+		source = "+" + source
+	}
+
+	// Delivery receipts are not messages
+	if esmClass&esmClassReceiptMask != 0 {
+		return
+	}
+
+	if payload, ok := tlvs[tagMessagePayload]; ok && len(data) == 0 {
+		data = payload
+	}
+
+	concat := &concatInfo{total: 1}
+	if esmClass&esmClassUDHI != 0 {
+		var err error
+		if data, concat, err = splitUDH(data); err != nil {
+			c.reportError(err)
+			return
+		}
+		if concat == nil {
+			concat = &concatInfo{total: 1}
+		}
+	}
+
+	text, err := decodeText(coding, data)
+	if err != nil {
+		c.reportError(fmt.Errorf("smpp: failed to decode message from %s: %w", source, err))
+		return
+	}
+
+	if full, ok := c.parts.add(source, *concat, text, time.Now()); ok && c.onMessage != nil {
+		c.onMessage(Message{Source: source, Text: full})
+	}
+}
+
+func (c *Client) keepAlive() {
+	ticker := time.NewTicker(c.cfg.EnquireLink)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), c.cfg.ResponseTimeout)
+			_, err := c.request(ctx, EnquireLink, nil)
+			cancel()
+			if err != nil && !errors.Is(err, ErrClosed) {
+				c.reportError(fmt.Errorf("smpp: keepalive failed: %w", err))
+				c.shutdown()
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+func (c *Client) reportError(err error) {
+	if c.onError != nil {
+		c.onError(err)
+	}
+}
+
+func (c *Client) shutdown() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.conn.Close()
+	})
+}
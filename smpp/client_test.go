@@ -0,0 +1,220 @@
+package smpp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/warthog618/sms/encoding/ucs2"
+)
+
+// fakeSMSC accepts one ESME, answers its requests and records every PDU it
+// receives
+type fakeSMSC struct {
+	t        *testing.T
+	listener net.Listener
+	conns    chan net.Conn
+	requests chan *PDU
+}
+
+func newFakeSMSC(t *testing.T) *fakeSMSC {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMSC{t: t, listener: listener, conns: make(chan net.Conn, 1), requests: make(chan *PDU, 64)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { conn.Close() })
+		s.conns <- conn
+
+		for {
+			p, err := ReadPDU(conn)
+			if err != nil {
+				return
+			}
+			switch p.CommandID {
+			case BindTransceiver, EnquireLink, Unbind:
+				conn.Write((&PDU{CommandID: p.CommandID | respBit, Sequence: p.Sequence}).Bytes())
+			case SubmitSM:
+				conn.Write((&PDU{CommandID: SubmitSMResp, Sequence: p.Sequence, Body: []byte("msg-1\x00")}).Bytes())
+			}
+			s.requests <- p
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMSC) next(commandID uint32) *PDU {
+	s.t.Helper()
+	for {
+		select {
+		case p := <-s.requests:
+			if p.CommandID == commandID {
+				return p
+			}
+		case <-time.After(2 * time.Second):
+			s.t.Fatalf("no PDU 0x%08x received", commandID)
+			return nil
+		}
+	}
+}
+
+func deliverSM(source string, esmClass, coding byte, data []byte) []byte {
+	var body bodyWriter
+	body.cstring("")
+	body.byte(1)
+	body.byte(1)
+	body.cstring(source)
+	body.byte(0)
+	body.byte(0)
+	body.cstring("1234")
+	body.byte(esmClass)
+	body.byte(0)
+	body.byte(0)
+	body.cstring("")
+	body.cstring("")
+	body.byte(0)
+	body.byte(0)
+	body.byte(coding)
+	body.byte(0)
+	body.byte(byte(len(data)))
+	body.Write(data)
+	return body.Bytes()
+}
+
+func TestClientBindAndSubmit(t *testing.T) {
+	smsc := newFakeSMSC(t)
+
+	client, err := Dial(context.Background(), Config{Addr: smsc.listener.Addr().String(), SystemID: "golte", Password: "secret", SourceAddr: "GOLTE"}, nil, nil)
+	if err != nil {
+		t.Fatalf("Dial() = %v", err)
+	}
+	defer client.Close()
+
+	bind := bodyReader{buf: smsc.next(BindTransceiver).Body}
+	if id, pw := bind.cstring(), bind.cstring(); id != "golte" || pw != "secret" {
+		t.Errorf("bound as %q/%q", id, pw)
+	}
+
+	id, err := client.Submit(context.Background(), "+33612345678", "héllo")
+	if err != nil {
+		t.Fatalf("Submit() = %v", err)
+	}
+	if id != "msg-1" {
+		t.Errorf("message ID = %q", id)
+	}
+
+	r := bodyReader{buf: smsc.next(SubmitSM).Body}
+	r.cstring()
+	r.byte()
+	r.byte()
+	if src := r.cstring(); src != "GOLTE" {
+		t.Errorf("source_addr = %q", src)
+	}
+	ton, _ := r.byte(), r.byte()
+	if dst := r.cstring(); dst != "33612345678" || ton != 1 {
+		t.Errorf("destination = %q (ton %d), want 33612345678 (ton 1)", dst, ton)
+	}
+	for i := 0; i < 3; i++ {
+		r.byte()
+	}
+	r.cstring()
+	r.cstring()
+	r.byte()
+	r.byte()
+	coding := r.byte()
+	r.byte()
+	data := r.bytes(int(r.byte()))
+	if text, err := decodeText(coding, data); err != nil || text != "héllo" {
+		t.Errorf("short_message = %q, %v (coding %d)", text, err, coding)
+	}
+}
+
+func TestClientDeliver(t *testing.T) {
+	smsc := newFakeSMSC(t)
+
+	messages := make(chan Message, 4)
+	client, err := Dial(context.Background(), Config{Addr: smsc.listener.Addr().String()}, func(m Message) { messages <- m }, nil)
+	if err != nil {
+		t.Fatalf("Dial() = %v", err)
+	}
+	defer client.Close()
+	conn := <-smsc.conns
+
+	// A receipt, then a two part UCS2 message delivered out of order
+	conn.Write((&PDU{CommandID: DeliverSM, Sequence: 1, Body: deliverSM("33612345678", 0x04, 0, []byte("id:1 stat:DELIVRD"))}).Bytes())
+	udh := func(seq byte) []byte { return []byte{5, 0x00, 3, 42, 2, seq} }
+	conn.Write((&PDU{CommandID: DeliverSM, Sequence: 2, Body: deliverSM("33612345678", esmClassUDHI, dataCodingUCS2, append(udh(2), ucs2.Encode([]rune(" ça va"))...))}).Bytes())
+	conn.Write((&PDU{CommandID: DeliverSM, Sequence: 3, Body: deliverSM("33612345678", esmClassUDHI, dataCodingUCS2, append(udh(1), ucs2.Encode([]rune("salut,"))...))}).Bytes())
+
+	for i := 0; i < 3; i++ {
+		smsc.next(DeliverSMResp)
+	}
+
+	select {
+	case m := <-messages:
+		if m.Source != "+33612345678" || m.Text != "salut, ça va" {
+			t.Errorf("received %+v", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not delivered")
+	}
+
+	select {
+	case m := <-messages:
+		t.Errorf("unexpected extra message %+v", m)
+	default:
+	}
+}
+
+func TestClientClosed(t *testing.T) {
+	smsc := newFakeSMSC(t)
+
+	client, err := Dial(context.Background(), Config{Addr: smsc.listener.Addr().String()}, nil, func(error) {})
+	if err != nil {
+		t.Fatalf("Dial() = %v", err)
+	}
+	(<-smsc.conns).Close()
+
+	select {
+	case <-client.Closed():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Closed() not signalled after the SMSC hung up")
+	}
+	if _, err := client.Submit(context.Background(), "123", "hi"); err == nil {
+		t.Error("expected Submit to fail on a closed connection")
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	tests := []struct {
+		text   string
+		coding byte
+		data   []byte
+	}{
+		{"hi", dataCodingDefault, []byte{0x68, 0x69}},
+		// Characters ASCII and GSM 03.38 place differently, and the
+		// extension table
+		{"a_b@", dataCodingDefault, []byte{0x61, 0x11, 0x62, 0x00}},
+		{"{€}", dataCodingDefault, []byte{0x1b, 0x28, 0x1b, 0x65, 0x1b, 0x29}},
+		{"日本", dataCodingUCS2, ucs2.Encode([]rune("日本"))},
+	}
+	for _, tt := range tests {
+		coding, data := encodeText(tt.text)
+		if coding != tt.coding || string(data) != string(tt.data) {
+			t.Errorf("encodeText(%q) = %d % x, want %d % x", tt.text, coding, data, tt.coding, tt.data)
+		}
+		if text, err := decodeText(coding, data); err != nil || text != tt.text {
+			t.Errorf("decodeText(encodeText(%q)) = %q, %v", tt.text, text, err)
+		}
+	}
+}
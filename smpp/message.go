@@ -0,0 +1,144 @@
+package smpp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/warthog618/sms/encoding/gsm7"
+	"github.com/warthog618/sms/encoding/ucs2"
+)
+
+// Message is a mobile originated SMS received through deliver_sm
+type Message struct {
+	Source string
+	Text   string
+}
+
+// encodeText picks the data coding for text. Text the GSM 03.38 alphabet
+// holds goes out in the SMSC default alphabet, one septet per octet as
+// decodeText reads it, anything else as UCS2.
+func encodeText(text string) (coding byte, data []byte) {
+	if septets, err := gsm7.Encode([]byte(text)); err == nil {
+		return dataCodingDefault, septets
+	}
+	return dataCodingUCS2, ucs2.Encode([]rune(text))
+}
+
+// decodeText decodes a short message according to its data coding
+func decodeText(coding byte, data []byte) (string, error) {
+	switch coding {
+	case dataCodingUCS2:
+		runes, err := ucs2.Decode(data)
+		if err != nil {
+			return "", err
+		}
+		return string(runes), nil
+	case dataCodingLatin1:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes), nil
+	default:
+		// The SMSC default alphabet is GSM 03.38, one septet per octet
+		text, err := gsm7.Decode(data)
+		if err != nil {
+			return "", err
+		}
+		return string(text), nil
+	}
+}
+
+// concatInfo is the concatenated SMS information element of a UDH
+type concatInfo struct {
+	ref   uint16
+	total byte
+	seq   byte
+}
+
+// splitUDH strips the user data header from a short message and returns the
+// concatenation element if it has one
+func splitUDH(data []byte) ([]byte, *concatInfo, error) {
+	if len(data) < 1 || int(data[0])+1 > len(data) {
+		return nil, nil, fmt.Errorf("smpp: invalid user data header")
+	}
+	udh, payload := data[1:data[0]+1], data[data[0]+1:]
+
+	var concat *concatInfo
+	for len(udh) >= 2 {
+		id, length := udh[0], int(udh[1])
+		if len(udh) < 2+length {
+			break
+		}
+		ie := udh[2 : 2+length]
+		switch {
+		case id == 0x00 && length == 3:
+			concat = &concatInfo{ref: uint16(ie[0]), total: ie[1], seq: ie[2]}
+		case id == 0x08 && length == 4:
+			concat = &concatInfo{ref: uint16(ie[0])<<8 | uint16(ie[1]), total: ie[2], seq: ie[3]}
+		}
+		udh = udh[2+length:]
+	}
+	return payload, concat, nil
+}
+
+// reassemblyTimeout is how long the parts of a concatenated SMS are kept
+// waiting for the missing ones
+const reassemblyTimeout = 5 * time.Minute
+
+type partialKey struct {
+	source string
+	ref    uint16
+}
+
+type partial struct {
+	parts   []string
+	got     int
+	started time.Time
+}
+
+// reassembler joins the parts of concatenated messages
+type reassembler struct {
+	pending map[partialKey]*partial
+}
+
+// add records a part and returns the full text once every part arrived
+func (r *reassembler) add(source string, info concatInfo, text string, now time.Time) (string, bool) {
+	if info.total <= 1 {
+		return text, true
+	}
+	if info.seq < 1 || info.seq > info.total {
+		return "", false
+	}
+
+	if r.pending == nil {
+		r.pending = make(map[partialKey]*partial)
+	}
+	for key, p := range r.pending {
+		if now.Sub(p.started) > reassemblyTimeout {
+			delete(r.pending, key)
+		}
+	}
+
+	key := partialKey{source: source, ref: info.ref}
+	p, ok := r.pending[key]
+	if !ok || len(p.parts) != int(info.total) {
+		p = &partial{parts: make([]string, info.total), started: now}
+		r.pending[key] = p
+	}
+	if p.parts[info.seq-1] == "" {
+		p.got++
+	}
+	p.parts[info.seq-1] = text
+
+	if p.got < int(info.total) {
+		return "", false
+	}
+	delete(r.pending, key)
+
+	var full string
+	for _, part := range p.parts {
+		full += part
+	}
+	return full, true
+}
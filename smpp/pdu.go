@@ -0,0 +1,182 @@
+// Package smpp implements the subset of SMPP 3.4 needed to send and receive
+// SMS through an SMSC as an ESME bound as a transceiver.
+package smpp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Command IDs
+const (
+	GenericNack         uint32 = 0x80000000
+	BindTransceiver     uint32 = 0x00000009
+	BindTransceiverResp uint32 = 0x80000009
+	SubmitSM            uint32 = 0x00000004
+	SubmitSMResp        uint32 = 0x80000004
+	DeliverSM           uint32 = 0x00000005
+	DeliverSMResp       uint32 = 0x80000005
+	Unbind              uint32 = 0x00000006
+	UnbindResp          uint32 = 0x80000006
+	EnquireLink         uint32 = 0x00000015
+	EnquireLinkResp     uint32 = 0x80000015
+
+	respBit uint32 = 0x80000000
+)
+
+const (
+	headerLen        = 16
+	maxPDULen        = 64 << 10
+	interfaceVersion = 0x34
+
+	tagMessagePayload uint16 = 0x0424
+
+	esmClassUDHI        = 0x40
+	esmClassReceiptMask = 0x3c
+
+	dataCodingDefault = 0x00
+	dataCodingLatin1  = 0x03
+	dataCodingUCS2    = 0x08
+
+	maxShortMessageOctets = 254
+)
+
+// Status codes used by this package
+const (
+	StatusOK         uint32 = 0x00000000
+	StatusInvalidCmd uint32 = 0x00000003
+)
+
+// StatusError is a non-zero command_status returned by the SMSC
+type StatusError uint32
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("smpp: command status 0x%08x", uint32(e))
+}
+
+// PDU is a single SMPP protocol data unit
+type PDU struct {
+	CommandID uint32
+	Status    uint32
+	Sequence  uint32
+	Body      []byte
+}
+
+// ReadPDU reads one PDU from r
+func ReadPDU(r io.Reader) (*PDU, error) {
+	var header [headerLen]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length < headerLen || length > maxPDULen {
+		return nil, fmt.Errorf("smpp: invalid PDU length %d", length)
+	}
+
+	p := &PDU{
+		CommandID: binary.BigEndian.Uint32(header[4:8]),
+		Status:    binary.BigEndian.Uint32(header[8:12]),
+		Sequence:  binary.BigEndian.Uint32(header[12:16]),
+		Body:      make([]byte, length-headerLen),
+	}
+	if _, err := io.ReadFull(r, p.Body); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Bytes encodes the PDU with its header
+func (p *PDU) Bytes() []byte {
+	buf := make([]byte, headerLen, headerLen+len(p.Body))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(headerLen+len(p.Body)))
+	binary.BigEndian.PutUint32(buf[4:8], p.CommandID)
+	binary.BigEndian.PutUint32(buf[8:12], p.Status)
+	binary.BigEndian.PutUint32(buf[12:16], p.Sequence)
+	return append(buf, p.Body...)
+}
+
+// bodyWriter builds PDU bodies
+type bodyWriter struct {
+	bytes.Buffer
+}
+
+func (w *bodyWriter) cstring(s string) {
+	w.WriteString(s)
+	w.WriteByte(0)
+}
+
+func (w *bodyWriter) byte(b byte) {
+	w.WriteByte(b)
+}
+
+func (w *bodyWriter) tlv(tag uint16, value []byte) {
+	var header [4]byte
+	binary.BigEndian.PutUint16(header[0:2], tag)
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(value)))
+	w.Write(header[:])
+	w.Write(value)
+}
+
+var errShortBody = errors.New("smpp: truncated PDU body")
+
+// bodyReader parses PDU bodies, the first error sticks
+type bodyReader struct {
+	buf []byte
+	err error
+}
+
+func (r *bodyReader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(r.buf, 0)
+	if i < 0 {
+		r.err = errShortBody
+		return ""
+	}
+	s := string(r.buf[:i])
+	r.buf = r.buf[i+1:]
+	return s
+}
+
+func (r *bodyReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) < 1 {
+		r.err = errShortBody
+		return 0
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *bodyReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.buf) < n {
+		r.err = errShortBody
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+// tlvs parses the optional parameters left in the body
+func (r *bodyReader) tlvs() map[uint16][]byte {
+	tlvs := make(map[uint16][]byte)
+	for r.err == nil && len(r.buf) >= 4 {
+		tag := binary.BigEndian.Uint16(r.buf[0:2])
+		length := int(binary.BigEndian.Uint16(r.buf[2:4]))
+		r.buf = r.buf[4:]
+		tlvs[tag] = r.bytes(length)
+	}
+	return tlvs
+}
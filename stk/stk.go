@@ -0,0 +1,305 @@
+package stk
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/warthog618/modem/at"
+	"github.com/warthog618/sms/encoding/gsm7"
+	"github.com/warthog618/sms/encoding/ucs2"
+)
+
+// ProactiveHandler is a callback for the proactive commands issued by the
+// SIM, err is set when the terminal response dismissing it failed
+type ProactiveHandler func(cmd Command, err error)
+
+// Proactive command types, ETSI TS 102 223 section 9.4
+const (
+	TypeRefresh         = 0x01
+	TypeSetUpEventList  = 0x05
+	TypeSetUpCall       = 0x10
+	TypeSendSS          = 0x11
+	TypeSendUSSD        = 0x12
+	TypeSendSMS         = 0x13
+	TypeLaunchBrowser   = 0x15
+	TypePlayTone        = 0x20
+	TypeDisplayText     = 0x21
+	TypeGetInkey        = 0x22
+	TypeGetInput        = 0x23
+	TypeSelectItem      = 0x24
+	TypeSetUpMenu       = 0x25
+	TypeProvideLocal    = 0x26
+	TypeSetUpIdleText   = 0x28
+	TypeLanguageSetting = 0x35
+	TypeOpenChannel     = 0x40
+)
+
+var typeNames = map[byte]string{
+	TypeRefresh:         "refresh",
+	TypeSetUpEventList:  "set up event list",
+	TypeSetUpCall:       "set up call",
+	TypeSendSS:          "send SS",
+	TypeSendUSSD:        "send USSD",
+	TypeSendSMS:         "send SMS",
+	TypePlayTone:        "play tone",
+	TypeDisplayText:     "display text",
+	TypeGetInkey:        "get inkey",
+	TypeGetInput:        "get input",
+	TypeSelectItem:      "select item",
+	TypeSetUpMenu:       "set up menu",
+	TypeProvideLocal:    "provide local information",
+	TypeSetUpIdleText:   "set up idle mode text",
+	TypeLaunchBrowser:   "launch browser",
+	TypeOpenChannel:     "open channel",
+	TypeLanguageSetting: "language notification",
+}
+
+// Result codes sent in terminal responses
+const (
+	ResultOK             = 0x00
+	ResultUserTerminated = 0x10
+	ResultBeyondTerminal = 0x30
+)
+
+// BER-TLV tags, without the comprehension required bit
+const (
+	proactiveCommandTag   = 0xd0
+	commandDetailsTag     = 0x01
+	deviceIdentitiesTag   = 0x02
+	resultTag             = 0x03
+	alphaIdentifierTag    = 0x05
+	textStringTag         = 0x0d
+	comprehensionRequired = 0x80
+)
+
+// Command is a decoded proactive command
+type Command struct {
+	Number    byte
+	Type      byte
+	Qualifier byte
+	Text      string // text string or alpha identifier, if any
+}
+
+// Name returns a readable name for the command type
+func (c Command) Name() string {
+	if name, ok := typeNames[c.Type]; ok {
+		return name
+	}
+	return fmt.Sprintf("command 0x%02X", c.Type)
+}
+
+// ParseProactive decodes a hex encoded proactive command
+func ParseProactive(data string) (Command, error) {
+	raw, err := hex.DecodeString(strings.Trim(strings.TrimSpace(data), `"`))
+	if err != nil {
+		return Command{}, fmt.Errorf("invalid proactive command hex: %w", err)
+	}
+	if len(raw) < 2 || raw[0] != proactiveCommandTag {
+		return Command{}, errors.New("not a proactive command")
+	}
+
+	body, _, err := readValue(raw[1:])
+	if err != nil {
+		return Command{}, err
+	}
+
+	var cmd Command
+	found := false
+	for len(body) > 0 {
+		tag := body[0] &^ comprehensionRequired
+		value, rest, err := readValue(body[1:])
+		if err != nil {
+			return Command{}, err
+		}
+		body = rest
+
+		switch tag {
+		case commandDetailsTag:
+			if len(value) < 3 {
+				return Command{}, errors.New("short command details")
+			}
+			cmd.Number, cmd.Type, cmd.Qualifier = value[0], value[1], value[2]
+			found = true
+		case textStringTag:
+			cmd.Text = decodeText(value)
+		case alphaIdentifierTag:
+			if cmd.Text == "" {
+				cmd.Text = decodeAlpha(value)
+			}
+		}
+	}
+	if !found {
+		return Command{}, errors.New("proactive command without command details")
+	}
+	return cmd, nil
+}
+
+// readValue reads a BER-TLV length and returns the value and what follows
+func readValue(b []byte) (value, rest []byte, err error) {
+	if len(b) == 0 {
+		return nil, nil, errors.New("truncated TLV")
+	}
+	length, n := int(b[0]), 1
+	if b[0] == 0x81 {
+		if len(b) < 2 {
+			return nil, nil, errors.New("truncated TLV length")
+		}
+		length, n = int(b[1]), 2
+	}
+	if len(b) < n+length {
+		return nil, nil, errors.New("truncated TLV value")
+	}
+	return b[n : n+length], b[n+length:], nil
+}
+
+// decodeText decodes a text string, whose first byte is the data coding scheme
+func decodeText(value []byte) string {
+	if len(value) == 0 {
+		return ""
+	}
+	dcs, text := value[0], value[1:]
+	switch dcs & 0x0c {
+	case 0x00:
+		return decodeGSM7(gsm7.Unpack7Bit(text, 0))
+	case 0x08:
+		runes, err := ucs2.Decode(text)
+		if err != nil {
+			return ""
+		}
+		return string(runes)
+	default:
+		return decodeGSM7(text)
+	}
+}
+
+// decodeAlpha decodes an alpha identifier, UCS2 when it starts with 0x80
+func decodeAlpha(value []byte) string {
+	if len(value) > 0 && value[0] == 0x80 {
+		runes, err := ucs2.Decode(value[1:])
+		if err != nil {
+			return ""
+		}
+		return string(runes)
+	}
+	return decodeGSM7(value)
+}
+
+func decodeGSM7(septets []byte) string {
+	// Unused bytes of alpha identifiers are 0xFF
+	septets = []byte(strings.TrimRight(string(septets), "\xff"))
+	text, err := gsm7.Decode(septets)
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// ResultFor returns the result that dismisses a command without user
+// interaction: informational commands are acknowledged, questions are
+// answered as if the user backed out, the rest isn't supported
+func ResultFor(cmd Command) byte {
+	switch cmd.Type {
+	case TypeDisplayText, TypeSetUpMenu, TypeSetUpIdleText, TypeSetUpEventList,
+		TypeLanguageSetting, TypeRefresh, TypePlayTone:
+		return ResultOK
+	case TypeGetInkey, TypeGetInput, TypeSelectItem:
+		return ResultUserTerminated
+	default:
+		return ResultBeyondTerminal
+	}
+}
+
+// TerminalResponse encodes the terminal response to cmd with the given result
+func TerminalResponse(cmd Command, result byte) string {
+	return fmt.Sprintf("%02X03%02X%02X%02X%02X028281%02X01%02X",
+		comprehensionRequired|commandDetailsTag, cmd.Number, cmd.Type, cmd.Qualifier,
+		comprehensionRequired|deviceIdentitiesTag,
+		comprehensionRequired|resultTag, result)
+}
+
+// STK answers SIM toolkit proactive commands so they don't wedge the SIM.
+// It uses the SIMCom +STKPCI indication and +STKTR terminal response.
+type STK struct {
+	*at.AT
+	logger  *slog.Logger
+	mu      sync.Mutex
+	handler ProactiveHandler
+}
+
+// New creates a new STK handler on the provided AT modem
+func New(a *at.AT) *STK {
+	return &STK{AT: a, logger: slog.With("component", "stk")}
+}
+
+// stkpciPattern matches +STKPCI: <type>,"<hex>", type 0 being a proactive command
+var stkpciPattern = regexp.MustCompile(`\+STKPCI:\s*(\d+)\s*,\s*"?([0-9A-Fa-f]+)"?`)
+
+// Start registers for proactive command indications. Every command is
+// dismissed with a terminal response, then passed to handler.
+func (s *STK) Start(handler ProactiveHandler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handler = handler
+	return s.AddIndication("+STKPCI", func(info []string) {
+		if len(info) == 0 {
+			return
+		}
+		m := stkpciPattern.FindStringSubmatch(info[0])
+		if m == nil {
+			return
+		}
+		if kind, _ := strconv.Atoi(m[1]); kind != 0 {
+			return
+		}
+
+		// Answered off the indication goroutine, it can't issue commands
+		go s.dismiss(m[2])
+	})
+}
+
+// dismiss answers a proactive command and reports it to the handler. One
+// that can't be parsed is refused as beyond the terminal's capabilities, the
+// SIM waits for a terminal response either way.
+func (s *STK) dismiss(data string) {
+	cmd, err := ParseProactive(data)
+	if err != nil {
+		s.logger.Warn("Failed to parse proactive command, refusing it",
+			slog.String("data", data),
+			slog.Any("error", err))
+		if err := s.Respond(Command{}, ResultBeyondTerminal); err != nil {
+			s.logger.Error("Failed to refuse proactive command", slog.Any("error", err))
+		}
+		return
+	}
+
+	err = s.Respond(cmd, ResultFor(cmd))
+
+	s.mu.Lock()
+	handler := s.handler
+	s.mu.Unlock()
+	if handler != nil {
+		handler(cmd, err)
+	}
+}
+
+// Respond sends the terminal response to cmd
+func (s *STK) Respond(cmd Command, result byte, options ...at.CommandOption) error {
+	_, err := s.Command(fmt.Sprintf(`+STKTR="%s"`, TerminalResponse(cmd, result)), options...)
+	return err
+}
+
+// Stop stops handling proactive commands
+func (s *STK) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.CancelIndication("+STKPCI")
+	s.handler = nil
+}
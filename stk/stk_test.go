@@ -0,0 +1,144 @@
+package stk
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/warthog618/modem/at"
+)
+
+// displayText is a DISPLAY TEXT command showing "Hello from SIM" in 8-bit
+const displayText = "D01A8103012180820281028D0F0448656C6C6F2066726F6D2053494D"
+
+func TestParseProactive(t *testing.T) {
+	cmd, err := ParseProactive(`"` + displayText + `"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Number != 1 || cmd.Type != TypeDisplayText || cmd.Qualifier != 0x80 {
+		t.Errorf("command details = %d, %#x, %#x", cmd.Number, cmd.Type, cmd.Qualifier)
+	}
+	if cmd.Text != "Hello from SIM" || cmd.Name() != "display text" {
+		t.Errorf("command = %q %q", cmd.Name(), cmd.Text)
+	}
+
+	// SELECT ITEM titled "Menu" by its alpha identifier
+	cmd, err = ParseProactive("D00F810302240082028182" + "85044D656E75")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Type != TypeSelectItem || cmd.Text != "Menu" {
+		t.Errorf("select item = %#x %q", cmd.Type, cmd.Text)
+	}
+
+	for _, bad := range []string{"", "zz", "D0", "D00581030121", "2201AA"} {
+		if _, err := ParseProactive(bad); err == nil {
+			t.Errorf("ParseProactive(%q) should fail", bad)
+		}
+	}
+}
+
+func TestTerminalResponse(t *testing.T) {
+	cmd := Command{Number: 1, Type: TypeDisplayText, Qualifier: 0x80}
+	if got := TerminalResponse(cmd, ResultFor(cmd)); got != "810301218082028281830100" {
+		t.Errorf("TerminalResponse() = %s", got)
+	}
+
+	if got := ResultFor(Command{Type: TypeGetInput}); got != ResultUserTerminated {
+		t.Errorf("questions should be dismissed as backed out, got %#x", got)
+	}
+	if got := ResultFor(Command{Type: TypeOpenChannel}); got != ResultBeyondTerminal {
+		t.Errorf("unsupported commands should be refused, got %#x", got)
+	}
+}
+
+// fakeModem returns the modem end of a pipe that acknowledges every command
+// and hands it over commands, and the host end
+func fakeModem(t *testing.T) (modem, host net.Conn, commands chan string) {
+	t.Helper()
+	modem, host = net.Pipe()
+	t.Cleanup(func() {
+		modem.Close()
+		host.Close()
+	})
+
+	commands = make(chan string, 4)
+	go func() {
+		r := bufio.NewReader(modem)
+		for {
+			line, err := r.ReadString('\r')
+			if err != nil {
+				return
+			}
+			commands <- strings.TrimSpace(line)
+			modem.Write([]byte("\r\nOK\r\n"))
+		}
+	}()
+	return modem, host, commands
+}
+
+func TestSTKDismissesProactiveCommand(t *testing.T) {
+	// A modem that raises a proactive command and acknowledges every command
+	modem, host, commands := fakeModem(t)
+
+	s := New(at.New(host, at.WithTimeout(time.Second)))
+	got := make(chan Command, 1)
+	if err := s.Start(func(cmd Command, err error) {
+		if err != nil {
+			t.Errorf("terminal response failed: %v", err)
+		}
+		got <- cmd
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	modem.Write([]byte("\r\n+STKPCI: 0,\"" + displayText + "\"\r\n"))
+
+	select {
+	case cmd := <-commands:
+		if cmd != `AT+STKTR="810301218082028281830100"` {
+			t.Errorf("sent %q", cmd)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no terminal response sent")
+	}
+
+	select {
+	case cmd := <-got:
+		if cmd.Text != "Hello from SIM" {
+			t.Errorf("handler got %q", cmd.Text)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler not called")
+	}
+}
+
+func TestSTKRefusesUnparsedCommand(t *testing.T) {
+	modem, host, commands := fakeModem(t)
+
+	s := New(at.New(host, at.WithTimeout(time.Second)))
+	called := make(chan struct{}, 1)
+	if err := s.Start(func(Command, error) { called <- struct{}{} }); err != nil {
+		t.Fatal(err)
+	}
+
+	// A proactive command without its command details
+	modem.Write([]byte("\r\n+STKPCI: 0,\"D0058D03044869\"\r\n"))
+
+	select {
+	case cmd := <-commands:
+		if cmd != `AT+STKTR="810300000082028281830130"` {
+			t.Errorf("sent %q", cmd)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no terminal response sent")
+	}
+	select {
+	case <-called:
+		t.Error("handler called for a command that wasn't parsed")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
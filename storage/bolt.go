@@ -0,0 +1,344 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DriverBolt keeps the data in a bbolt database, a single file with
+// transactions
+const DriverBolt = "bolt"
+
+func init() {
+	Register(DriverBolt, OpenBolt)
+}
+
+// boltOpenTimeout bounds waiting for another process holding the database,
+// e.g. golte storage stats while the bridge runs
+const boltOpenTimeout = 5 * time.Second
+
+// Buckets of the bbolt database. Messages and calls are keyed by their ID,
+// big endian so the cursor walks them in order.
+var (
+	boltMeta     = []byte("meta")
+	boltMessages = []byte("messages")
+	boltCalls    = []byte("calls")
+	boltKeys     = []byte("keys")
+	boltCounters = []byte("counters")
+
+	boltVersionKey = []byte("version")
+)
+
+// boltInitialPages are the freelist and root pages bbolt creates a database
+// with, the first write replaces them and they stay free even once compacted
+const boltInitialPages = 2
+
+// boltMigrations upgrade a database of the version they're indexed by to
+// the next one, within the transaction opening it
+var boltMigrations = map[int]func(tx *bolt.Tx) error{}
+
+// boltStore is a Store in a bbolt database
+type boltStore struct {
+	path string
+	db   *bolt.DB
+}
+
+// OpenBolt opens the bbolt database at path, creating it if needed
+func OpenBolt(path string) (Store, error) {
+	db, err := openBoltDB(path)
+	if err != nil {
+		return nil, err
+	}
+	return &boltStore{path: path, db: db}, nil
+}
+
+// openBoltDB opens the database at path and brings its schema to
+// SchemaVersion
+func openBoltDB(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o640, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	// A write would leave the pages it replaced free, even after Vacuum
+	var current bool
+	db.View(func(tx *bolt.Tx) error {
+		if meta := tx.Bucket(boltMeta); meta != nil {
+			current = string(meta.Get(boltVersionKey)) == strconv.Itoa(SchemaVersion)
+		}
+		return nil
+	})
+	if current {
+		return db, nil
+	}
+	if err := db.Update(func(tx *bolt.Tx) error { return boltMigrate(tx, path) }); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// boltMigrate creates the buckets and upgrades the data to SchemaVersion
+func boltMigrate(tx *bolt.Tx, path string) error {
+	meta, err := tx.CreateBucketIfNotExists(boltMeta)
+	if err != nil {
+		return err
+	}
+	version := 0
+	if v := meta.Get(boltVersionKey); v != nil {
+		if version, err = strconv.Atoi(string(v)); err != nil {
+			return fmt.Errorf("invalid schema version %q in %s", v, path)
+		}
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("%w: %s has schema %d, this version knows %d", ErrNewerSchema, path, version, SchemaVersion)
+	}
+
+	for _, name := range [][]byte{boltMessages, boltCalls, boltKeys, boltCounters} {
+		if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+			return err
+		}
+	}
+	// A new database has nothing to migrate
+	if version > 0 {
+		for v := version; v < SchemaVersion; v++ {
+			if migration, ok := boltMigrations[v]; ok {
+				if err := migration(tx); err != nil {
+					return fmt.Errorf("failed to migrate %s from schema %d: %w", path, v, err)
+				}
+			}
+		}
+	}
+	return meta.Put(boltVersionKey, []byte(strconv.Itoa(SchemaVersion)))
+}
+
+// boltID is the key of an ID
+func boltID(id int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(id))
+}
+
+// boltAdd stores v as JSON in bucket under the next ID, which setID sets
+func boltAdd(db *bolt.DB, bucket []byte, v any, setID func(int64)) (int64, error) {
+	var id int64
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		id = int64(seq)
+		setID(id)
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return b.Put(boltID(id), value)
+	})
+	return id, err
+}
+
+// boltNewest returns up to limit items of bucket matching number, newest
+// first. A limit of 0 or less returns them all.
+func boltNewest[T any](db *bolt.DB, bucket []byte, number string, numberOf func(T) string, limit int) ([]T, error) {
+	var matched []T
+	err := db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var item T
+			if err := json.Unmarshal(v, &item); err != nil {
+				return fmt.Errorf("invalid %s %d: %w", bucket, binary.BigEndian.Uint64(k), err)
+			}
+			if number != "" && numberOf(item) != number {
+				continue
+			}
+			matched = append(matched, item)
+			if limit > 0 && len(matched) == limit {
+				break
+			}
+		}
+		return nil
+	})
+	return matched, err
+}
+
+func (s *boltStore) AddMessage(m Message) (int64, error) {
+	return boltAdd(s.db, boltMessages, &m, func(id int64) { m.ID = id })
+}
+
+func (s *boltStore) Messages(number string, limit int) ([]Message, error) {
+	return boltNewest(s.db, boltMessages, number, func(m Message) string { return m.Number }, limit)
+}
+
+func (s *boltStore) AddCall(c Call) (int64, error) {
+	return boltAdd(s.db, boltCalls, &c, func(id int64) { c.ID = id })
+}
+
+func (s *boltStore) Calls(number string, limit int) ([]Call, error) {
+	return boltNewest(s.db, boltCalls, number, func(c Call) string { return c.Number }, limit)
+}
+
+func (s *boltStore) Get(key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(boltKeys).Get([]byte(key))
+		if v == nil {
+			return ErrNotFound
+		}
+		// v is only valid during the transaction
+		value = slices.Clone(v)
+		return nil
+	})
+	return value, err
+}
+
+func (s *boltStore) Set(key string, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		// A nil value can't be told from a missing key
+		return tx.Bucket(boltKeys).Put([]byte(key), append([]byte{}, value...))
+	})
+}
+
+func (s *boltStore) Delete(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltKeys).Delete([]byte(key))
+	})
+}
+
+func (s *boltStore) Keys() ([]string, error) {
+	var keys []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// The cursor walks them sorted
+		return tx.Bucket(boltKeys).ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	return keys, err
+}
+
+func (s *boltStore) Incr(name string, delta int64) (int64, error) {
+	var n int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltCounters)
+		n = boltCounter(b.Get([]byte(name))) + delta
+		return b.Put([]byte(name), binary.BigEndian.AppendUint64(nil, uint64(n)))
+	})
+	return n, err
+}
+
+func (s *boltStore) Counter(name string) (int64, error) {
+	var n int64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		n = boltCounter(tx.Bucket(boltCounters).Get([]byte(name)))
+		return nil
+	})
+	return n, err
+}
+
+func (s *boltStore) Counters() (map[string]int64, error) {
+	counters := make(map[string]int64)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltCounters).ForEach(func(k, v []byte) error {
+			counters[string(k)] = boltCounter(v)
+			return nil
+		})
+	})
+	return counters, err
+}
+
+// boltCounter decodes a counter, 0 if it was never incremented
+func boltCounter(v []byte) int64 {
+	if len(v) != 8 {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(v))
+}
+
+func (s *boltStore) Stats() (Stats, error) {
+	stats := Stats{Driver: DriverBolt, Path: s.path}
+	var used int64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		version, err := strconv.Atoi(string(tx.Bucket(boltMeta).Get(boltVersionKey)))
+		if err != nil {
+			return fmt.Errorf("invalid schema version in %s", s.path)
+		}
+		stats.Version = version
+		stats.Messages = tx.Bucket(boltMessages).Stats().KeyN
+		stats.Calls = tx.Bucket(boltCalls).Stats().KeyN
+		stats.Keys = tx.Bucket(boltKeys).Stats().KeyN
+		stats.Counters = tx.Bucket(boltCounters).Stats().KeyN
+		used = tx.Size()
+		return nil
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+
+	// Free pages within the data, and the file bbolt grew ahead of it
+	stats.Reclaimable = max(int64(s.db.Stats().FreePageN)-boltInitialPages, 0)
+	if info, err := os.Stat(s.path); err == nil {
+		stats.Size = info.Size()
+		stats.Reclaimable += max(stats.Size-used, 0) / int64(s.db.Info().PageSize)
+	}
+	return stats, nil
+}
+
+// Vacuum copies the data to a new database, as bbolt never shrinks its
+// file, and swaps it in
+func (s *boltStore) Vacuum() error {
+	tmp := filepath.Join(filepath.Dir(s.path), "."+filepath.Base(s.path)+".vacuum")
+	os.Remove(tmp)
+	dst, err := bolt.Open(tmp, 0o640, nil)
+	if err != nil {
+		return fmt.Errorf("failed to vacuum %s: %w", s.path, err)
+	}
+	defer os.Remove(tmp)
+
+	if err := bolt.Compact(dst, s.db, 0); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to vacuum %s: %w", s.path, err)
+	}
+	var used int64
+	dst.View(func(tx *bolt.Tx) error {
+		used = tx.Size()
+		return nil
+	})
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	// bbolt grows the file ahead of the data, the next write grows it again
+	if err := os.Truncate(tmp, used); err != nil {
+		return fmt.Errorf("failed to vacuum %s: %w", s.path, err)
+	}
+
+	if err := s.db.Close(); err != nil {
+		return err
+	}
+	renameErr := os.Rename(tmp, s.path)
+	// The store keeps working on either file
+	db, err := openBoltDB(s.path)
+	if err != nil {
+		return err
+	}
+	s.db = db
+	if renameErr != nil {
+		return fmt.Errorf("failed to vacuum %s: %w", s.path, renameErr)
+	}
+	return nil
+}
+
+func (s *boltStore) Close() error {
+	err := s.db.Close()
+	if errors.Is(err, bolt.ErrDatabaseNotOpen) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,36 @@
+package storage_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"golte/storage"
+	"golte/storage/storagetest"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltConformance(t *testing.T) {
+	storagetest.Run(t, storage.OpenBolt)
+}
+
+func TestBoltNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golte.db")
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucket([]byte("meta"))
+		if err != nil {
+			return err
+		}
+		return meta.Put([]byte("version"), []byte("99"))
+	})
+	db.Close()
+
+	if _, err := storage.OpenBolt(path); !errors.Is(err, storage.ErrNewerSchema) {
+		t.Errorf("OpenBolt() = %v, want ErrNewerSchema", err)
+	}
+}
@@ -0,0 +1,303 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// KeySize is the length of the keys of Encrypt, for AES-256
+const KeySize = 32
+
+var (
+	// ErrWrongKey is returned by Encrypt for a store encrypted with
+	// another key
+	ErrWrongKey = errors.New("the store is encrypted with another key")
+
+	// ErrPlaintext is returned by Encrypt for a store holding data that
+	// isn't encrypted, Migrate it to an encrypted store
+	ErrPlaintext = errors.New("the store isn't encrypted")
+)
+
+// encryptedKey marks an encrypted store, its value checks the key
+const encryptedKey = "storage.encrypted"
+
+// encryptedCheck is the value of encryptedKey, sealed
+const encryptedCheck = "golte"
+
+// Labels of the fields, bound to their ciphertext so a value can't be
+// moved to another field
+const (
+	labelText   = "message.text"
+	labelNumber = "number"
+	labelKey    = "key:"
+)
+
+// encryptedStore encrypts the message bodies, numbers and key values of the
+// store it wraps. Numbers are encrypted deterministically, the same number
+// always gives the same ciphertext so Messages and Calls can filter on it.
+// Times, directions, durations, statuses, key names and counters stay in
+// the clear.
+type encryptedStore struct {
+	Store
+	aead cipher.AEAD
+	// nonces derives the nonces of numbers from them
+	nonces []byte
+}
+
+var _ Store = (*encryptedStore)(nil)
+
+// ParseKey decodes a key given as 64 hex characters, e.g. the output of
+// openssl rand -hex 32
+func ParseKey(s string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != KeySize {
+		return nil, fmt.Errorf("the key must be %d hex characters", KeySize*2)
+	}
+	return key, nil
+}
+
+// Encrypt returns store with its messages, calls and keys encrypted with
+// key. A new store is marked encrypted, a store already holding plaintext
+// data is refused with ErrPlaintext, and one encrypted with another key
+// with ErrWrongKey.
+func Encrypt(store Store, key []byte) (Store, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("the key must be %d bytes, not %d", KeySize, len(key))
+	}
+	// Separate keys for the data and the nonces of numbers
+	kdf := hkdf.New(sha256.New, key, nil, []byte("golte storage"))
+	dataKey := make([]byte, KeySize)
+	nonceKey := make([]byte, KeySize)
+	if _, err := io.ReadFull(kdf, dataKey); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(kdf, nonceKey); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	s := &encryptedStore{Store: store, aead: aead, nonces: nonceKey}
+
+	check, err := store.Get(encryptedKey)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		if err := s.markEncrypted(); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if plain, err := s.open(check, encryptedKey); err != nil || string(plain) != encryptedCheck {
+			return nil, ErrWrongKey
+		}
+	}
+	return s, nil
+}
+
+// IsEncrypted reports whether store, opened without Encrypt, holds
+// encrypted data
+func IsEncrypted(store Store) (bool, error) {
+	_, err := store.Get(encryptedKey)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// OpenWithKey opens the store of driver at path encrypted with key, or in
+// the clear if key is nil. An encrypted store is refused without a key.
+func OpenWithKey(driver, path string, key []byte) (Store, error) {
+	s, err := Open(driver, path)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		encrypted, err := IsEncrypted(s)
+		if err == nil && encrypted {
+			err = fmt.Errorf("%s is encrypted, set storage.encryption_key", path)
+		}
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		return s, nil
+	}
+	encrypted, err := Encrypt(s, key)
+	if errors.Is(err, ErrPlaintext) {
+		err = fmt.Errorf("%w, encrypt it with golte storage encrypt", err)
+	}
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return encrypted, nil
+}
+
+// markEncrypted marks a new store encrypted, refusing one with plaintext
+// data. Counters aren't encrypted, a store with only those is taken.
+func (s *encryptedStore) markEncrypted() error {
+	stats, err := s.Store.Stats()
+	if err != nil {
+		return err
+	}
+	if stats.Messages != 0 || stats.Calls != 0 || stats.Keys != 0 {
+		return fmt.Errorf("%w: %s holds %d messages, %d calls and %d keys", ErrPlaintext, stats.Path, stats.Messages, stats.Calls, stats.Keys)
+	}
+	return s.Store.Set(encryptedKey, s.seal([]byte(encryptedCheck), encryptedKey, nil))
+}
+
+// seal encrypts plaintext for the field label with nonce, a random one if
+// nil, and returns the nonce followed by the ciphertext
+func (s *encryptedStore) seal(plaintext []byte, label string, nonce []byte) []byte {
+	if nonce == nil {
+		nonce = make([]byte, s.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			panic("storage: no randomness for a nonce: " + err.Error())
+		}
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, []byte(label))
+}
+
+// open decrypts the output of seal for the field label
+func (s *encryptedStore) open(sealed []byte, label string) ([]byte, error) {
+	size := s.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, errors.New("truncated ciphertext")
+	}
+	return s.aead.Open(nil, sealed[:size], sealed[size:], []byte(label))
+}
+
+// sealString encrypts a text field, base64 encoded to fit the drivers'
+// text columns
+func (s *encryptedStore) sealString(plaintext, label string, nonce []byte) string {
+	return base64.StdEncoding.EncodeToString(s.seal([]byte(plaintext), label, nonce))
+}
+
+func (s *encryptedStore) openString(sealed, label string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", label, err)
+	}
+	plain, err := s.open(data, label)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", label, err)
+	}
+	return string(plain), nil
+}
+
+// sealNumber encrypts a number with a nonce derived from it, the same
+// number always giving the same ciphertext
+func (s *encryptedStore) sealNumber(number string) string {
+	mac := hmac.New(sha256.New, s.nonces)
+	mac.Write([]byte(number))
+	return s.sealString(number, labelNumber, mac.Sum(nil)[:s.aead.NonceSize()])
+}
+
+// filterNumber encrypts the number Messages and Calls filter on, empty
+// for every number
+func (s *encryptedStore) filterNumber(number string) string {
+	if number == "" {
+		return ""
+	}
+	return s.sealNumber(number)
+}
+
+func (s *encryptedStore) AddMessage(m Message) (int64, error) {
+	m.Number = s.sealNumber(m.Number)
+	m.Text = s.sealString(m.Text, labelText, nil)
+	return s.Store.AddMessage(m)
+}
+
+func (s *encryptedStore) Messages(number string, limit int) ([]Message, error) {
+	messages, err := s.Store.Messages(s.filterNumber(number), limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range messages {
+		m := &messages[i]
+		if m.Number, err = s.openString(m.Number, labelNumber); err != nil {
+			return nil, fmt.Errorf("message %d: %w", m.ID, err)
+		}
+		if m.Text, err = s.openString(m.Text, labelText); err != nil {
+			return nil, fmt.Errorf("message %d: %w", m.ID, err)
+		}
+	}
+	return messages, nil
+}
+
+func (s *encryptedStore) AddCall(c Call) (int64, error) {
+	c.Number = s.sealNumber(c.Number)
+	return s.Store.AddCall(c)
+}
+
+func (s *encryptedStore) Calls(number string, limit int) ([]Call, error) {
+	calls, err := s.Store.Calls(s.filterNumber(number), limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range calls {
+		if calls[i].Number, err = s.openString(calls[i].Number, labelNumber); err != nil {
+			return nil, fmt.Errorf("call %d: %w", calls[i].ID, err)
+		}
+	}
+	return calls, nil
+}
+
+func (s *encryptedStore) Get(key string) ([]byte, error) {
+	if key == encryptedKey {
+		return nil, ErrNotFound
+	}
+	sealed, err := s.Store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	value, err := s.open(sealed, labelKey+key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt key %s: %w", key, err)
+	}
+	return value, nil
+}
+
+func (s *encryptedStore) Set(key string, value []byte) error {
+	if key == encryptedKey {
+		return fmt.Errorf("key %s is reserved", key)
+	}
+	return s.Store.Set(key, s.seal(value, labelKey+key, nil))
+}
+
+func (s *encryptedStore) Delete(key string) error {
+	if key == encryptedKey {
+		return nil
+	}
+	return s.Store.Delete(key)
+}
+
+func (s *encryptedStore) Keys() ([]string, error) {
+	keys, err := s.Store.Keys()
+	return slices.DeleteFunc(keys, func(key string) bool { return key == encryptedKey }), err
+}
+
+func (s *encryptedStore) Stats() (Stats, error) {
+	stats, err := s.Store.Stats()
+	// The marker isn't one of the caller's keys
+	stats.Keys = max(stats.Keys-1, 0)
+	return stats, err
+}
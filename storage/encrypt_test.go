@@ -0,0 +1,194 @@
+package storage_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golte/storage"
+	"golte/storage/storagetest"
+)
+
+var testKey = bytes.Repeat([]byte{0x42}, storage.KeySize)
+
+// openEncrypted opens the file store at path encrypted with key
+func openEncrypted(key []byte) storagetest.Opener {
+	return func(path string) (storage.Store, error) {
+		s, err := storage.OpenFile(path)
+		if err != nil {
+			return nil, err
+		}
+		encrypted, err := storage.Encrypt(s, key)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		return encrypted, nil
+	}
+}
+
+func TestEncryptConformance(t *testing.T) {
+	storagetest.Run(t, openEncrypted(testKey))
+}
+
+func TestEncryptHidesData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golte.store")
+	s, err := openEncrypted(testKey)(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage(storage.Message{Time: time.Now(), Direction: storage.DirectionIn, Number: "+33612345678", Text: "Your code is 493817"})
+	s.AddCall(storage.Call{Time: time.Now(), Direction: storage.DirectionIn, Number: "+33687654321", Status: "missed"})
+	s.Set("last_sender", []byte("+33611111111"))
+	s.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"493817", "33612345678", "33687654321", "33611111111"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("the store holds %s in the clear", secret)
+		}
+	}
+
+	// The same number filters across restarts
+	s, err = openEncrypted(testKey)(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.AddMessage(storage.Message{Time: time.Now(), Direction: storage.DirectionOut, Number: "+33612345678", Text: "thanks"})
+	messages, err := s.Messages("+33612345678", 0)
+	if err != nil || len(messages) != 2 || messages[1].Text != "Your code is 493817" || messages[1].Number != "+33612345678" {
+		t.Errorf("Messages(number) = %+v, %v", messages, err)
+	}
+	if calls, err := s.Calls("+33687654321", 0); err != nil || len(calls) != 1 || calls[0].Number != "+33687654321" {
+		t.Errorf("Calls(number) = %+v, %v", calls, err)
+	}
+}
+
+func TestEncryptWrongKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golte.store")
+	s, err := openEncrypted(testKey)(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	other := bytes.Repeat([]byte{0x24}, storage.KeySize)
+	if _, err := openEncrypted(other)(path); !errors.Is(err, storage.ErrWrongKey) {
+		t.Errorf("Encrypt() with another key = %v, want ErrWrongKey", err)
+	}
+
+	plain, err := storage.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	if encrypted, err := storage.IsEncrypted(plain); err != nil || !encrypted {
+		t.Errorf("IsEncrypted() = %v, %v", encrypted, err)
+	}
+}
+
+func TestEncryptPlaintext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golte.store")
+	s, err := storage.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.AddMessage(storage.Message{Time: time.Now(), Number: "+33612345678", Text: "hello"})
+
+	if encrypted, err := storage.IsEncrypted(s); err != nil || encrypted {
+		t.Errorf("IsEncrypted() = %v, %v", encrypted, err)
+	}
+	if _, err := storage.Encrypt(s, testKey); !errors.Is(err, storage.ErrPlaintext) {
+		t.Errorf("Encrypt() of a plaintext store = %v, want ErrPlaintext", err)
+	}
+	if _, err := storage.Encrypt(s, testKey[:16]); err == nil {
+		t.Error("Encrypt() took a 16 byte key")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	key, err := storage.ParseKey("4242424242424242424242424242424242424242424242424242424242424242\n")
+	if err != nil || !bytes.Equal(key, testKey) {
+		t.Errorf("ParseKey() = %x, %v", key, err)
+	}
+	for _, s := range []string{"", "42", "not hex at all, not hex at all, not hex at all, not hex at all!"} {
+		if _, err := storage.ParseKey(s); err == nil {
+			t.Errorf("ParseKey(%q) took an invalid key", s)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	plain, err := storage.OpenFile(filepath.Join(dir, "plain.store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	plain.AddMessage(storage.Message{Time: start, Direction: storage.DirectionIn, Number: "+33612345678", Text: "first"})
+	plain.AddMessage(storage.Message{Time: start.Add(time.Minute), Direction: storage.DirectionOut, Number: "+33612345678", Text: "second"})
+	plain.AddCall(storage.Call{Time: start, Direction: storage.DirectionIn, Number: "+33687654321", Duration: time.Minute, Status: "answered"})
+	plain.Set("state", []byte("on"))
+	plain.Incr("sms.received", 3)
+
+	to, err := openEncrypted(testKey)(filepath.Join(dir, "encrypted.store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer to.Close()
+	if err := storage.Migrate(plain, to); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, _ := to.Messages("+33612345678", 0)
+	if len(messages) != 2 || messages[0].Text != "second" || messages[1].Text != "first" || !messages[1].Time.Equal(start) {
+		t.Errorf("Messages() after Migrate() = %+v", messages)
+	}
+	if calls, _ := to.Calls("", 0); len(calls) != 1 || calls[0].Number != "+33687654321" || calls[0].Duration != time.Minute {
+		t.Errorf("Calls() after Migrate() = %+v", calls)
+	}
+	if value, _ := to.Get("state"); string(value) != "on" {
+		t.Errorf("Get() after Migrate() = %q", value)
+	}
+	if n, _ := to.Counter("sms.received"); n != 3 {
+		t.Errorf("Counter() after Migrate() = %d", n)
+	}
+
+	// Migrating twice would duplicate everything
+	if err := storage.Migrate(plain, to); err == nil {
+		t.Error("Migrate() to a store holding data succeeded")
+	}
+}
+
+func TestOpenWithKey(t *testing.T) {
+	dir := t.TempDir()
+	encryptedPath := filepath.Join(dir, "encrypted.db")
+	s, err := storage.OpenWithKey(storage.DriverSQLite, encryptedPath, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if _, err := storage.OpenWithKey(storage.DriverSQLite, encryptedPath, nil); err == nil {
+		t.Error("OpenWithKey() without a key opened an encrypted store")
+	}
+
+	plainPath := filepath.Join(dir, "plain.db")
+	s, err = storage.OpenWithKey(storage.DriverSQLite, plainPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage(storage.Message{Time: time.Now(), Number: "+33612345678", Text: "hello"})
+	s.Close()
+	if _, err := storage.OpenWithKey(storage.DriverSQLite, plainPath, testKey); !errors.Is(err, storage.ErrPlaintext) {
+		t.Errorf("OpenWithKey() of a plaintext store = %v, want ErrPlaintext", err)
+	}
+}
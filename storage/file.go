@@ -0,0 +1,378 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// DriverFile is the built-in driver, a journal of JSON lines needing
+// nothing but the file system
+const DriverFile = "file"
+
+func init() {
+	Register(DriverFile, OpenFile)
+}
+
+// Operations of the journal
+const (
+	opSchema  = "schema"
+	opMessage = "message"
+	opCall    = "call"
+	opSet     = "set"
+	opDelete  = "delete"
+	opIncr    = "incr"
+)
+
+// entry is a line of the journal
+type entry struct {
+	Op      string   `json:"op"`
+	Version int      `json:"version,omitempty"`
+	Message *Message `json:"message,omitempty"`
+	Call    *Call    `json:"call,omitempty"`
+	Key     string   `json:"key,omitempty"`
+	Value   []byte   `json:"value,omitempty"`
+	Delta   int64    `json:"delta,omitempty"`
+}
+
+// fileMigrations upgrade the data loaded from a journal of the version
+// they're indexed by to the next one. The journal is rewritten once
+// migrated.
+var fileMigrations = map[int]func(*fileData) error{}
+
+// fileData is the state replayed from a journal
+type fileData struct {
+	version  int
+	nextID   int64
+	messages []Message
+	calls    []Call
+	keys     map[string][]byte
+	counters map[string]int64
+}
+
+// fileStore is a Store appending each change to a journal, replayed when
+// opened. Vacuum rewrites the journal with only the current state.
+type fileStore struct {
+	path string
+
+	mu      sync.Mutex
+	file    *os.File
+	data    fileData
+	entries int64 // lines in the journal
+}
+
+// OpenFile opens the journal at path, creating it if needed
+func OpenFile(path string) (Store, error) {
+	s := &fileStore{path: path}
+	torn, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case s.data.version < SchemaVersion:
+		if err := s.migrate(); err != nil {
+			return nil, err
+		}
+	case torn:
+		// Appending after the torn line would leave it in the middle
+		if err := s.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+	return s, nil
+}
+
+// load replays the journal, a missing one is empty. torn reports a last
+// line left half written by a crash, which is skipped.
+func (s *fileStore) load() (torn bool, err error) {
+	s.data = fileData{nextID: 1, keys: make(map[string][]byte), counters: make(map[string]int64)}
+
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			if !scanner.Scan() {
+				return true, nil
+			}
+			return false, fmt.Errorf("invalid entry at %s:%d: %w", s.path, line, err)
+		}
+		if e.Op == opSchema && e.Version > SchemaVersion {
+			return false, fmt.Errorf("%w: %s has schema %d, this version knows %d", ErrNewerSchema, s.path, e.Version, SchemaVersion)
+		}
+		s.data.apply(e)
+		s.entries++
+	}
+	return false, scanner.Err()
+}
+
+// migrate upgrades the data to SchemaVersion and rewrites the journal
+func (s *fileStore) migrate() error {
+	// A new store has nothing to migrate
+	if s.entries > 0 {
+		for v := s.data.version; v < SchemaVersion; v++ {
+			if migration, ok := fileMigrations[v]; ok {
+				if err := migration(&s.data); err != nil {
+					return fmt.Errorf("failed to migrate %s from schema %d: %w", s.path, v, err)
+				}
+			}
+		}
+	}
+	s.data.version = SchemaVersion
+	return s.rewrite()
+}
+
+// apply changes the data as the journal entry says
+func (d *fileData) apply(e entry) {
+	switch e.Op {
+	case opSchema:
+		d.version = e.Version
+	case opMessage:
+		if e.Message != nil {
+			d.messages = append(d.messages, *e.Message)
+			d.nextID = max(d.nextID, e.Message.ID+1)
+		}
+	case opCall:
+		if e.Call != nil {
+			d.calls = append(d.calls, *e.Call)
+			d.nextID = max(d.nextID, e.Call.ID+1)
+		}
+	case opSet:
+		d.keys[e.Key] = e.Value
+	case opDelete:
+		delete(d.keys, e.Key)
+	case opIncr:
+		d.counters[e.Key] += e.Delta
+	}
+}
+
+// snapshot returns the entries recreating the data
+func (d *fileData) snapshot() []entry {
+	entries := []entry{{Op: opSchema, Version: d.version}}
+	for i := range d.messages {
+		entries = append(entries, entry{Op: opMessage, Message: &d.messages[i]})
+	}
+	for i := range d.calls {
+		entries = append(entries, entry{Op: opCall, Call: &d.calls[i]})
+	}
+	for _, key := range slices.Sorted(maps.Keys(d.keys)) {
+		entries = append(entries, entry{Op: opSet, Key: key, Value: d.keys[key]})
+	}
+	for _, name := range slices.Sorted(maps.Keys(d.counters)) {
+		if d.counters[name] != 0 {
+			entries = append(entries, entry{Op: opIncr, Key: name, Delta: d.counters[name]})
+		}
+	}
+	return entries
+}
+
+// append writes e to the journal and applies it, with s.mu held
+func (s *fileStore) append(e entry) error {
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.data.apply(e)
+	s.entries++
+	return nil
+}
+
+// rewrite replaces the journal by a snapshot of the data, renamed into
+// place so a crash leaves either journal whole. s.mu must be held.
+func (s *fileStore) rewrite() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	entries := s.data.snapshot()
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	s.entries = int64(len(entries))
+	return nil
+}
+
+func (s *fileStore) AddMessage(m Message) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m.ID = s.data.nextID
+	return m.ID, s.append(entry{Op: opMessage, Message: &m})
+}
+
+func (s *fileStore) Messages(number string, limit int) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return newest(s.data.messages, number, func(m Message) string { return m.Number }, limit), nil
+}
+
+func (s *fileStore) AddCall(c Call) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c.ID = s.data.nextID
+	return c.ID, s.append(entry{Op: opCall, Call: &c})
+}
+
+func (s *fileStore) Calls(number string, limit int) ([]Call, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return newest(s.data.calls, number, func(c Call) string { return c.Number }, limit), nil
+}
+
+func (s *fileStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.data.keys[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return slices.Clone(value), nil
+}
+
+func (s *fileStore) Set(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.append(entry{Op: opSet, Key: key, Value: slices.Clone(value)})
+}
+
+func (s *fileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.keys[key]; !ok {
+		return nil
+	}
+	return s.append(entry{Op: opDelete, Key: key})
+}
+
+func (s *fileStore) Keys() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Sorted(maps.Keys(s.data.keys)), nil
+}
+
+func (s *fileStore) Incr(name string, delta int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.append(entry{Op: opIncr, Key: name, Delta: delta}); err != nil {
+		return 0, err
+	}
+	return s.data.counters[name], nil
+}
+
+func (s *fileStore) Counter(name string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.counters[name], nil
+}
+
+func (s *fileStore) Counters() (map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.data.counters), nil
+}
+
+func (s *fileStore) Stats() (Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := Stats{
+		Driver:   DriverFile,
+		Path:     s.path,
+		Version:  s.data.version,
+		Messages: len(s.data.messages),
+		Calls:    len(s.data.calls),
+		Keys:     len(s.data.keys),
+		Counters: len(s.data.counters),
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		stats.Size = info.Size()
+	}
+	stats.Reclaimable = s.entries - int64(len(s.data.snapshot()))
+	return stats, nil
+}
+
+func (s *fileStore) Vacuum() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	if err := s.rewrite(); err != nil {
+		return fmt.Errorf("failed to vacuum %s: %w", s.path, err)
+	}
+
+	// Appends go to the new journal from now on
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	s.file.Close()
+	s.file = file
+	return nil
+}
+
+func (s *fileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,54 @@
+package storage_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golte/storage"
+	"golte/storage/storagetest"
+)
+
+func TestFileConformance(t *testing.T) {
+	storagetest.Run(t, storage.OpenFile)
+}
+
+func TestOpenUnknownDriver(t *testing.T) {
+	_, err := storage.Open("redis", filepath.Join(t.TempDir(), "golte.db"))
+	if !errors.Is(err, storage.ErrUnknownDriver) || !strings.Contains(err.Error(), "file") {
+		t.Errorf("Open(redis) = %v, want ErrUnknownDriver listing the built-in drivers", err)
+	}
+}
+
+func TestFileNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golte.store")
+	os.WriteFile(path, []byte(`{"op":"schema","version":99}`+"\n"), 0o600)
+	if _, err := storage.OpenFile(path); !errors.Is(err, storage.ErrNewerSchema) {
+		t.Errorf("OpenFile() = %v, want ErrNewerSchema", err)
+	}
+}
+
+func TestFileTornEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golte.store")
+	journal := `{"op":"schema","version":1}` + "\n" + `{"op":"incr","key":"n","delta":2}` + "\n" + `{"op":"incr","ke`
+	os.WriteFile(path, []byte(journal), 0o600)
+
+	// The half written entry of a crash is dropped
+	s, err := storage.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Incr("n", 1)
+	s.Close()
+
+	s, err = storage.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if n, _ := s.Counter("n"); n != 3 {
+		t.Errorf("Counter() = %d, want 3", n)
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Migrate copies everything in from to to, which must be empty: messages
+// and calls oldest first, keys and counters. Messages and calls get new IDs
+// in to. Migrating from a plaintext store to one returned by Encrypt
+// encrypts it, the other way round decrypts it, and between two drivers
+// converts it.
+func Migrate(from, to Store) error {
+	stats, err := to.Stats()
+	if err != nil {
+		return err
+	}
+	if stats.Messages != 0 || stats.Calls != 0 || stats.Keys != 0 || stats.Counters != 0 {
+		return fmt.Errorf("%s isn't empty, migrating would mix its data with the copy", stats.Path)
+	}
+
+	messages, err := from.Messages("", 0)
+	if err != nil {
+		return err
+	}
+	for _, m := range slices.Backward(messages) {
+		if _, err := to.AddMessage(m); err != nil {
+			return fmt.Errorf("failed to copy message %d: %w", m.ID, err)
+		}
+	}
+
+	calls, err := from.Calls("", 0)
+	if err != nil {
+		return err
+	}
+	for _, c := range slices.Backward(calls) {
+		if _, err := to.AddCall(c); err != nil {
+			return fmt.Errorf("failed to copy call %d: %w", c.ID, err)
+		}
+	}
+
+	keys, err := from.Keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		value, err := from.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to copy key %s: %w", key, err)
+		}
+		if err := to.Set(key, value); err != nil {
+			return fmt.Errorf("failed to copy key %s: %w", key, err)
+		}
+	}
+
+	counters, err := from.Counters()
+	if err != nil {
+		return err
+	}
+	for name, n := range counters {
+		if _, err := to.Incr(name, n); err != nil {
+			return fmt.Errorf("failed to copy counter %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// DriverSQLite keeps the data in a SQLite database, the default
+const DriverSQLite = "sqlite"
+
+func init() {
+	Register(DriverSQLite, OpenSQLite)
+}
+
+// sqliteSchema creates the tables of a new database. A database of schema
+// 0 may predate the counters, so the tables that exist are kept.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS messages (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	time      TEXT NOT NULL,
+	direction TEXT NOT NULL,
+	number    TEXT NOT NULL,
+	text      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS messages_number ON messages (number, id);
+CREATE TABLE IF NOT EXISTS calls (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	time      TEXT NOT NULL,
+	direction TEXT NOT NULL,
+	number    TEXT NOT NULL,
+	duration  INTEGER NOT NULL,
+	status    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS calls_number ON calls (number, id);
+CREATE TABLE IF NOT EXISTS keys (
+	key   TEXT PRIMARY KEY,
+	value BLOB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS counters (
+	name  TEXT PRIMARY KEY,
+	value INTEGER NOT NULL
+);
+`
+
+// sqliteMigrations upgrade a database of the version they're indexed by to
+// the next one, within the transaction opening it
+var sqliteMigrations = map[int]func(tx *sql.Tx) error{}
+
+// sqliteStore is a Store in a SQLite database
+type sqliteStore struct {
+	path string
+	db   *sql.DB
+}
+
+// OpenSQLite opens the SQLite database at path, creating it if needed
+func OpenSQLite(path string) (Store, error) {
+	// The busy timeout lets golte storage stats wait for the bridge's writes
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if err := sqliteMigrate(db, path); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqliteStore{path: path, db: db}, nil
+}
+
+// sqliteMigrate creates the tables and upgrades the data to SchemaVersion,
+// the version is kept in user_version
+func sqliteMigrate(db *sql.DB, path string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer tx.Rollback()
+
+	var version int
+	if err := tx.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	switch {
+	case version > SchemaVersion:
+		return fmt.Errorf("%w: %s has schema %d, this version knows %d", ErrNewerSchema, path, version, SchemaVersion)
+	case version == SchemaVersion:
+		return nil
+	case version == 0:
+		if _, err := tx.Exec(sqliteSchema); err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
+		}
+	default:
+		for v := version; v < SchemaVersion; v++ {
+			if migration, ok := sqliteMigrations[v]; ok {
+				if err := migration(tx); err != nil {
+					return fmt.Errorf("failed to migrate %s from schema %d: %w", path, v, err)
+				}
+			}
+		}
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) AddMessage(m Message) (int64, error) {
+	res, err := s.db.Exec("INSERT INTO messages (time, direction, number, text) VALUES (?, ?, ?, ?)",
+		m.Time.Format(time.RFC3339Nano), m.Direction, m.Number, m.Text)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (s *sqliteStore) Messages(number string, limit int) ([]Message, error) {
+	rows, err := s.db.Query(`SELECT id, time, direction, number, text FROM messages
+		WHERE ? = '' OR number = ? ORDER BY id DESC LIMIT ?`, number, number, sqliteLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var (
+			m  Message
+			at string
+		)
+		if err := rows.Scan(&m.ID, &at, &m.Direction, &m.Number, &m.Text); err != nil {
+			return nil, err
+		}
+		if m.Time, err = time.Parse(time.RFC3339Nano, at); err != nil {
+			return nil, fmt.Errorf("invalid time of message %d: %w", m.ID, err)
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+func (s *sqliteStore) AddCall(c Call) (int64, error) {
+	res, err := s.db.Exec("INSERT INTO calls (time, direction, number, duration, status) VALUES (?, ?, ?, ?, ?)",
+		c.Time.Format(time.RFC3339Nano), c.Direction, c.Number, int64(c.Duration), c.Status)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (s *sqliteStore) Calls(number string, limit int) ([]Call, error) {
+	rows, err := s.db.Query(`SELECT id, time, direction, number, duration, status FROM calls
+		WHERE ? = '' OR number = ? ORDER BY id DESC LIMIT ?`, number, number, sqliteLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calls []Call
+	for rows.Next() {
+		var (
+			c        Call
+			at       string
+			duration int64
+		)
+		if err := rows.Scan(&c.ID, &at, &c.Direction, &c.Number, &duration, &c.Status); err != nil {
+			return nil, err
+		}
+		if c.Time, err = time.Parse(time.RFC3339Nano, at); err != nil {
+			return nil, fmt.Errorf("invalid time of call %d: %w", c.ID, err)
+		}
+		c.Duration = time.Duration(duration)
+		calls = append(calls, c)
+	}
+	return calls, rows.Err()
+}
+
+// sqliteLimit maps a limit of 0 or less, meaning all, to SQLite's no limit
+func sqliteLimit(limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
+
+func (s *sqliteStore) Get(key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRow("SELECT value FROM keys WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (s *sqliteStore) Set(key string, value []byte) error {
+	// A nil value would be stored as NULL
+	_, err := s.db.Exec("INSERT INTO keys (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value",
+		key, append([]byte{}, value...))
+	return err
+}
+
+func (s *sqliteStore) Delete(key string) error {
+	_, err := s.db.Exec("DELETE FROM keys WHERE key = ?", key)
+	return err
+}
+
+func (s *sqliteStore) Keys() ([]string, error) {
+	rows, err := s.db.Query("SELECT key FROM keys ORDER BY key")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *sqliteStore) Incr(name string, delta int64) (int64, error) {
+	var n int64
+	err := s.db.QueryRow(`INSERT INTO counters (name, value) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET value = value + excluded.value RETURNING value`, name, delta).Scan(&n)
+	return n, err
+}
+
+func (s *sqliteStore) Counter(name string) (int64, error) {
+	var n int64
+	err := s.db.QueryRow("SELECT value FROM counters WHERE name = ?", name).Scan(&n)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return n, err
+}
+
+func (s *sqliteStore) Counters() (map[string]int64, error) {
+	rows, err := s.db.Query("SELECT name, value FROM counters")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counters := make(map[string]int64)
+	for rows.Next() {
+		var (
+			name string
+			n    int64
+		)
+		if err := rows.Scan(&name, &n); err != nil {
+			return nil, err
+		}
+		counters[name] = n
+	}
+	return counters, rows.Err()
+}
+
+func (s *sqliteStore) Stats() (Stats, error) {
+	stats := Stats{Driver: DriverSQLite, Path: s.path}
+	err := s.db.QueryRow(`SELECT
+		(SELECT count(*) FROM messages),
+		(SELECT count(*) FROM calls),
+		(SELECT count(*) FROM keys),
+		(SELECT count(*) FROM counters)`).Scan(&stats.Messages, &stats.Calls, &stats.Keys, &stats.Counters)
+	if err != nil {
+		return Stats{}, err
+	}
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&stats.Version); err != nil {
+		return Stats{}, err
+	}
+	if err := s.db.QueryRow("PRAGMA freelist_count").Scan(&stats.Reclaimable); err != nil {
+		return Stats{}, err
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		stats.Size = info.Size()
+	}
+	return stats, nil
+}
+
+func (s *sqliteStore) Vacuum() error {
+	if _, err := s.db.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum %s: %w", s.path, err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) Close() error {
+	// Closing twice is harmless for database/sql
+	return s.db.Close()
+}
@@ -0,0 +1,103 @@
+package storage_test
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golte/storage"
+	"golte/storage/storagetest"
+)
+
+func TestSQLiteConformance(t *testing.T) {
+	storagetest.Run(t, storage.OpenSQLite)
+}
+
+func TestSQLiteReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golte.db")
+	s, err := storage.OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	s.AddMessage(storage.Message{Time: start, Direction: storage.DirectionIn, Number: "+33612345678", Text: "first"})
+	s.AddMessage(storage.Message{Time: start.Add(time.Minute), Direction: storage.DirectionIn, Number: "+33687654321", Text: "other"})
+	s.AddMessage(storage.Message{Time: start.Add(2 * time.Minute), Direction: storage.DirectionOut, Number: "+33612345678", Text: "second"})
+	s.AddCall(storage.Call{Time: start, Direction: storage.DirectionIn, Number: "+33612345678", Duration: time.Minute, Status: "answered"})
+	if _, err := s.Get("missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get() of a missing key = %v, want ErrNotFound", err)
+	}
+	if err := s.Set("state", nil); err != nil {
+		t.Errorf("Set() of an empty value = %v", err)
+	}
+	s.Close()
+
+	// Everything survives reopening
+	s, err = storage.OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	messages, err := s.Messages("+33612345678", 0)
+	if err != nil || len(messages) != 2 || messages[0].Text != "second" || messages[1].Text != "first" || !messages[1].Time.Equal(start) {
+		t.Errorf("Messages(number) = %+v, %v", messages, err)
+	}
+	if messages, _ := s.Messages("", 1); len(messages) != 1 || messages[0].Text != "second" {
+		t.Errorf("Messages(\"\", 1) = %+v", messages)
+	}
+	calls, err := s.Calls("", 0)
+	if err != nil || len(calls) != 1 || calls[0].Duration != time.Minute || calls[0].Status != "answered" {
+		t.Errorf("Calls() = %+v, %v", calls, err)
+	}
+	if value, err := s.Get("state"); err != nil || len(value) != 0 {
+		t.Errorf("Get() = %q, %v", value, err)
+	}
+	if stats, err := s.Stats(); err != nil || stats.Messages != 3 || stats.Calls != 1 || stats.Keys != 1 || stats.Size == 0 || stats.Version != storage.SchemaVersion {
+		t.Errorf("Stats() = %+v, %v", stats, err)
+	}
+}
+
+func TestSQLiteSchemaZero(t *testing.T) {
+	// The history before counters and schema versions
+	path := filepath.Join(t.TempDir(), "golte.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE messages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, time TEXT NOT NULL, direction TEXT NOT NULL, number TEXT NOT NULL, text TEXT NOT NULL);
+	INSERT INTO messages (time, direction, number, text) VALUES ('2026-10-16T12:00:00Z', 'in', '+33612345678', 'kept')`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := storage.OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if messages, err := s.Messages("", 0); err != nil || len(messages) != 1 || messages[0].Text != "kept" {
+		t.Errorf("Messages() = %+v, %v", messages, err)
+	}
+	if n, err := s.Incr("sms.received", 1); err != nil || n != 1 {
+		t.Errorf("Incr() = %d, %v", n, err)
+	}
+}
+
+func TestSQLiteNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golte.sqlite")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("PRAGMA user_version = 99")
+	db.Close()
+
+	if _, err := storage.OpenSQLite(path); !errors.Is(err, storage.ErrNewerSchema) {
+		t.Errorf("OpenSQLite() = %v, want ErrNewerSchema", err)
+	}
+}
@@ -0,0 +1,163 @@
+// Package storage keeps what the bridge remembers across restarts: the SMS
+// and calls it saw, runtime state and counters. Drivers register under a
+// name selected by storage.driver: file, bolt and sqlite are built in.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SchemaVersion is the version of the data the drivers write. A store with
+// an older version is migrated when opened, a newer one is refused.
+const SchemaVersion = 1
+
+var (
+	// ErrNotFound is returned by Get for keys that aren't set
+	ErrNotFound = errors.New("not found")
+
+	// ErrUnknownDriver is returned by Open for drivers not built in
+	ErrUnknownDriver = errors.New("unknown storage driver")
+
+	// ErrNewerSchema is returned by Open for a store written by a newer
+	// golte
+	ErrNewerSchema = errors.New("the store was written by a newer version")
+)
+
+// Directions of messages and calls
+const (
+	DirectionIn  = "in"
+	DirectionOut = "out"
+)
+
+// Message is an SMS received or sent
+type Message struct {
+	ID        int64     `json:"id"` // set by AddMessage
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	Number    string    `json:"number"`
+	Text      string    `json:"text"`
+}
+
+// Call is a call received or placed
+type Call struct {
+	ID        int64         `json:"id"` // set by AddCall
+	Time      time.Time     `json:"time"`
+	Direction string        `json:"direction"`
+	Number    string        `json:"number"`
+	Duration  time.Duration `json:"duration"`
+	Status    string        `json:"status"` // e.g. answered, missed, busy
+}
+
+// Stats describe a store, for golte storage stats
+type Stats struct {
+	Driver   string
+	Path     string
+	Version  int   // schema version
+	Size     int64 // bytes on disk
+	Messages int
+	Calls    int
+	Keys     int
+	Counters int
+
+	// Reclaimable is what Vacuum would free, in the driver's own unit:
+	// superseded journal entries for the file driver, free pages for bolt
+	// and sqlite
+	Reclaimable int64
+}
+
+// Store keeps messages, calls, key-value state and counters. It's safe for
+// concurrent use.
+type Store interface {
+	// AddMessage records m and returns its ID
+	AddMessage(m Message) (int64, error)
+	// Messages returns up to limit messages of number, or of every number
+	// if empty, newest first
+	Messages(number string, limit int) ([]Message, error)
+
+	// AddCall records c and returns its ID
+	AddCall(c Call) (int64, error)
+	// Calls returns up to limit calls of number, or of every number if
+	// empty, newest first
+	Calls(number string, limit int) ([]Call, error)
+
+	// Get returns the value of key, ErrNotFound if it isn't set
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte) error
+	// Delete removes key, deleting a key that isn't set isn't an error
+	Delete(key string) error
+	// Keys returns the keys set, sorted
+	Keys() ([]string, error)
+
+	// Incr adds delta to the counter name and returns its new value
+	Incr(name string, delta int64) (int64, error)
+	// Counter returns the value of the counter name, 0 if it was never
+	// incremented
+	Counter(name string) (int64, error)
+	// Counters returns the value of every counter incremented, by name
+	Counters() (map[string]int64, error)
+
+	Stats() (Stats, error)
+	// Vacuum reclaims the space of deleted and superseded data
+	Vacuum() error
+	Close() error
+}
+
+// Opener opens the store of a driver at path, creating it if needed
+type Opener func(path string) (Store, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Opener)
+)
+
+// Register makes a driver available to Open under name, it panics if the
+// name is taken
+func Register(name string, open Opener) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if _, ok := drivers[name]; ok {
+		panic("storage: driver " + name + " registered twice")
+	}
+	drivers[name] = open
+}
+
+// Drivers returns the names of the registered drivers, sorted
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	return slices.Sorted(maps.Keys(drivers))
+}
+
+// Open opens the store at path with driver
+func Open(driver, path string) (Store, error) {
+	driversMu.RLock()
+	open, ok := drivers[driver]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q, this build has %s", ErrUnknownDriver, driver, strings.Join(Drivers(), ", "))
+	}
+	return open(path)
+}
+
+// newest returns up to limit items matching number, newest first, from
+// items oldest first. A limit of 0 or less returns them all.
+func newest[T any](items []T, number string, numberOf func(T) string, limit int) []T {
+	var matched []T
+	for i := len(items) - 1; i >= 0; i-- {
+		if number != "" && numberOf(items[i]) != number {
+			continue
+		}
+		matched = append(matched, items[i])
+		if limit > 0 && len(matched) == limit {
+			break
+		}
+	}
+	return matched
+}
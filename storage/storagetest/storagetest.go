@@ -0,0 +1,277 @@
+// Package storagetest is the conformance suite of the storage drivers, each
+// driver's tests run it against their store.
+package storagetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"golte/storage"
+)
+
+// Opener opens the store of the driver under test at path
+type Opener func(path string) (storage.Store, error)
+
+// Run checks the store of open behaves as storage.Store says, each test in
+// a store of its own
+func Run(t *testing.T, open Opener) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, Opener, string)
+	}{
+		{"Messages", testMessages},
+		{"Calls", testCalls},
+		{"Keys", testKeys},
+		{"Counters", testCounters},
+		{"Persistence", testPersistence},
+		{"Vacuum", testVacuum},
+		{"Concurrency", testConcurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open, filepath.Join(t.TempDir(), "golte.store"))
+		})
+	}
+}
+
+// mustOpen opens the store at path, closed when the test ends
+func mustOpen(t *testing.T, open Opener, path string) storage.Store {
+	t.Helper()
+	s, err := open(path)
+	if err != nil {
+		t.Fatalf("open(%s) = %v", path, err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+var start = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+func testMessages(t *testing.T, open Opener, path string) {
+	s := mustOpen(t, open, path)
+
+	if messages, err := s.Messages("", 10); err != nil || len(messages) != 0 {
+		t.Fatalf("Messages() of an empty store = %v, %v", messages, err)
+	}
+
+	var ids []int64
+	for i, number := range []string{"+33612345678", "+33687654321", "+33612345678"} {
+		id, err := s.AddMessage(storage.Message{
+			Time:      start.Add(time.Duration(i) * time.Minute),
+			Direction: storage.DirectionIn,
+			Number:    number,
+			Text:      fmt.Sprintf("message %d", i),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if ids[0] == ids[1] || ids[1] == ids[2] || ids[0] == ids[2] {
+		t.Errorf("AddMessage() IDs = %v, want them unique", ids)
+	}
+
+	all, err := s.Messages("", 0)
+	if err != nil || len(all) != 3 || all[0].Text != "message 2" || all[2].Text != "message 0" {
+		t.Fatalf("Messages() = %+v, %v, want the 3 newest first", all, err)
+	}
+	if all[0].ID != ids[2] || !all[0].Time.Equal(start.Add(2*time.Minute)) || all[0].Direction != storage.DirectionIn {
+		t.Errorf("Messages()[0] = %+v", all[0])
+	}
+
+	mine, _ := s.Messages("+33612345678", 1)
+	if len(mine) != 1 || mine[0].Text != "message 2" {
+		t.Errorf("Messages(number, 1) = %+v", mine)
+	}
+}
+
+func testCalls(t *testing.T, open Opener, path string) {
+	s := mustOpen(t, open, path)
+
+	s.AddCall(storage.Call{Time: start, Direction: storage.DirectionIn, Number: "+33612345678", Status: "missed"})
+	id, err := s.AddCall(storage.Call{Time: start.Add(time.Hour), Direction: storage.DirectionOut, Number: "+33687654321", Duration: 90 * time.Second, Status: "answered"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls, err := s.Calls("", 10)
+	if err != nil || len(calls) != 2 {
+		t.Fatalf("Calls() = %+v, %v", calls, err)
+	}
+	if c := calls[0]; c.ID != id || c.Duration != 90*time.Second || c.Status != "answered" || c.Direction != storage.DirectionOut {
+		t.Errorf("Calls()[0] = %+v", c)
+	}
+	if calls, _ := s.Calls("+33612345678", 10); len(calls) != 1 || calls[0].Status != "missed" {
+		t.Errorf("Calls(number) = %+v", calls)
+	}
+}
+
+func testKeys(t *testing.T, open Opener, path string) {
+	s := mustOpen(t, open, path)
+
+	if _, err := s.Get("missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get(missing) = %v, want ErrNotFound", err)
+	}
+	if err := s.Set("state", []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	value := []byte("two")
+	s.Set("state", value)
+	value[0] = 'x'
+	if got, err := s.Get("state"); err != nil || string(got) != "two" {
+		t.Errorf("Get() = %q, %v, want the last value set, unaffected by the caller", got, err)
+	}
+
+	s.Set("config", nil)
+	if keys, err := s.Keys(); err != nil || !slices.Equal(keys, []string{"config", "state"}) {
+		t.Errorf("Keys() = %q, %v, want them sorted", keys, err)
+	}
+
+	if err := s.Delete("state"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("state"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get() after Delete = %v", err)
+	}
+	if err := s.Delete("state"); err != nil {
+		t.Errorf("Delete() of a missing key = %v", err)
+	}
+}
+
+func testCounters(t *testing.T, open Opener, path string) {
+	s := mustOpen(t, open, path)
+
+	if n, err := s.Counter("sms.received"); err != nil || n != 0 {
+		t.Errorf("Counter() never incremented = %d, %v", n, err)
+	}
+	s.Incr("sms.received", 1)
+	if n, err := s.Incr("sms.received", 2); err != nil || n != 3 {
+		t.Errorf("Incr() = %d, %v, want 3", n, err)
+	}
+	if n, _ := s.Incr("sms.received", -1); n != 2 {
+		t.Errorf("Incr(-1) = %d, want 2", n)
+	}
+	if n, _ := s.Counter("sms.received"); n != 2 {
+		t.Errorf("Counter() = %d, want 2", n)
+	}
+	s.Incr("calls.missed", 5)
+	if counters, err := s.Counters(); err != nil || !maps.Equal(counters, map[string]int64{"sms.received": 2, "calls.missed": 5}) {
+		t.Errorf("Counters() = %v, %v", counters, err)
+	}
+}
+
+func testPersistence(t *testing.T, open Opener, path string) {
+	s, err := open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := s.AddMessage(storage.Message{Time: start, Direction: storage.DirectionOut, Number: "+33612345678", Text: "hello"})
+	s.AddCall(storage.Call{Time: start, Direction: storage.DirectionIn, Number: "+33612345678", Status: "answered"})
+	s.Set("kept", []byte{0, 1, 2})
+	s.Set("deleted", []byte("gone"))
+	s.Delete("deleted")
+	s.Incr("calls", 5)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = mustOpen(t, open, path)
+	messages, _ := s.Messages("", 10)
+	if len(messages) != 1 || messages[0].ID != id || messages[0].Text != "hello" || !messages[0].Time.Equal(start) {
+		t.Errorf("Messages() after reopening = %+v", messages)
+	}
+	if calls, _ := s.Calls("", 10); len(calls) != 1 {
+		t.Errorf("Calls() after reopening = %+v", calls)
+	}
+	if value, err := s.Get("kept"); err != nil || !bytes.Equal(value, []byte{0, 1, 2}) {
+		t.Errorf("Get() after reopening = %v, %v", value, err)
+	}
+	if _, err := s.Get("deleted"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get(deleted) after reopening = %v", err)
+	}
+	if n, _ := s.Counter("calls"); n != 5 {
+		t.Errorf("Counter() after reopening = %d", n)
+	}
+
+	// IDs keep growing across restarts
+	if next, _ := s.AddMessage(storage.Message{Time: start, Number: "+33612345678"}); next <= id {
+		t.Errorf("AddMessage() after reopening = %d, want more than %d", next, id)
+	}
+
+	stats, err := s.Stats()
+	if err != nil || stats.Version != storage.SchemaVersion || stats.Messages != 2 || stats.Calls != 1 || stats.Keys != 1 || stats.Counters != 1 {
+		t.Errorf("Stats() = %+v, %v", stats, err)
+	}
+}
+
+func testVacuum(t *testing.T, open Opener, path string) {
+	s := mustOpen(t, open, path)
+	for i := range 50 {
+		s.Set("state", []byte(fmt.Sprint(i)))
+		s.Incr("count", 1)
+	}
+	s.Set("gone", []byte("soon"))
+	s.Delete("gone")
+
+	if err := s.Vacuum(); err != nil {
+		t.Fatal(err)
+	}
+	stats, _ := s.Stats()
+	if stats.Reclaimable != 0 {
+		t.Errorf("Reclaimable after Vacuum() = %d", stats.Reclaimable)
+	}
+
+	// The store keeps working and holds the same data
+	s.Incr("count", 1)
+	if value, _ := s.Get("state"); string(value) != "49" {
+		t.Errorf("Get() after Vacuum() = %q", value)
+	}
+	if n, _ := s.Counter("count"); n != 51 {
+		t.Errorf("Counter() after Vacuum() = %d", n)
+	}
+	s.Close()
+
+	s = mustOpen(t, open, path)
+	if n, _ := s.Counter("count"); n != 51 {
+		t.Errorf("Counter() after Vacuum() and reopening = %d", n)
+	}
+	if _, err := s.Get("gone"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get(gone) after Vacuum() = %v", err)
+	}
+}
+
+func testConcurrency(t *testing.T, open Opener, path string) {
+	s := mustOpen(t, open, path)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 25 {
+				s.Incr("n", 1)
+				s.AddMessage(storage.Message{Time: start, Number: "+33612345678"})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n, _ := s.Counter("n"); n != 200 {
+		t.Errorf("Counter() = %d, want 200", n)
+	}
+	messages, _ := s.Messages("", 0)
+	seen := make(map[int64]bool)
+	for _, m := range messages {
+		seen[m.ID] = true
+	}
+	if len(messages) != 200 || len(seen) != 200 {
+		t.Errorf("%d messages with %d IDs, want 200 unique", len(messages), len(seen))
+	}
+}
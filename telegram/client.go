@@ -0,0 +1,181 @@
+// Package telegram is a small client of the Telegram Bot API, enough to post
+// messages and read the ones sent to a bot by long polling.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the Bot API server of Telegram
+const DefaultAPIURL = "https://api.telegram.org"
+
+// ParseModeHTML makes Telegram read the text of a message as HTML, see
+// https://core.telegram.org/bots/api#html-style
+const ParseModeHTML = "HTML"
+
+// requestSlack is added to the long poll timeout for the HTTP request, so
+// the server answers before the client gives up
+const requestSlack = 10 * time.Second
+
+// ErrUnauthorized is returned when Telegram refuses the bot token
+var ErrUnauthorized = errors.New("telegram: invalid bot token")
+
+// APIError is an error answered by the Bot API
+type APIError struct {
+	Method      string
+	Code        int
+	Description string
+	RetryAfter  time.Duration // how long to wait when rate limited, 0 otherwise
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram: %s: %d %s", e.Method, e.Code, e.Description)
+}
+
+// User is a Telegram user or bot
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username,omitempty"`
+}
+
+// Chat is a private chat, group or channel
+type Chat struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"`
+	Title    string `json:"title,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// Message is a message of a chat
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from,omitempty"`
+	Chat      Chat   `json:"chat"`
+	Date      int64  `json:"date"`
+	Text      string `json:"text,omitempty"`
+}
+
+// Update is an event of the bot, only messages are read
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message,omitempty"`
+}
+
+// response is the envelope of every Bot API answer
+type response struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// Client calls the Bot API with a bot token
+type Client struct {
+	token  string
+	apiURL string
+	http   *http.Client
+}
+
+// NewClient returns a client of the bot with token, on the Bot API server at
+// apiURL or DefaultAPIURL if empty
+func NewClient(token, apiURL string) *Client {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	return &Client{token: token, apiURL: strings.TrimSuffix(apiURL, "/"), http: &http.Client{}}
+}
+
+// GetMe returns the bot, checking the token
+func (c *Client) GetMe(ctx context.Context) (User, error) {
+	var me User
+	err := c.call(ctx, "getMe", struct{}{}, &me)
+	return me, err
+}
+
+// SendMessage posts text to the chat, read as parseMode if not empty
+func (c *Client) SendMessage(ctx context.Context, chatID int64, text, parseMode string) (Message, error) {
+	params := struct {
+		ChatID             int64  `json:"chat_id"`
+		Text               string `json:"text"`
+		ParseMode          string `json:"parse_mode,omitempty"`
+		DisableLinkPreview bool   `json:"disable_web_page_preview"`
+	}{chatID, text, parseMode, true}
+
+	var sent Message
+	err := c.call(ctx, "sendMessage", params, &sent)
+	return sent, err
+}
+
+// GetUpdates returns the updates from offset on, the ones before are
+// confirmed and not returned again. It waits up to timeout for one to come.
+func (c *Client) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error) {
+	params := struct {
+		Offset         int64    `json:"offset,omitempty"`
+		Timeout        int      `json:"timeout"`
+		AllowedUpdates []string `json:"allowed_updates"`
+	}{offset, int(timeout.Seconds()), []string{"message"}}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout+requestSlack)
+	defer cancel()
+
+	var updates []Update
+	err := c.call(ctx, "getUpdates", params, &updates)
+	return updates, err
+}
+
+// call posts params as JSON to the method and decodes its result into v
+func (c *Client) call(ctx context.Context, method string, params, v any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL+"/bot"+c.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		// The URL holds the token, keep it out of logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram: %s: %w", method, err)
+	}
+	defer res.Body.Close()
+
+	var r response
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return fmt.Errorf("telegram: %s: invalid answer (HTTP %d): %w", method, res.StatusCode, err)
+	}
+	if !r.OK {
+		if r.ErrorCode == http.StatusUnauthorized {
+			return ErrUnauthorized
+		}
+		return &APIError{
+			Method:      method,
+			Code:        r.ErrorCode,
+			Description: r.Description,
+			RetryAfter:  time.Duration(r.Parameters.RetryAfter) * time.Second,
+		}
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Result, v)
+}
@@ -0,0 +1,99 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testToken = "123456:test-token"
+
+// fakeAPI answers each Bot API method with the JSON methods has for it.
+// params returns the last request made to a method, decoded.
+func fakeAPI(t *testing.T, methods map[string]string) (client *Client, params func(method string) map[string]any) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		requests = make(map[string]map[string]any)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, ok := strings.CutPrefix(r.URL.Path, "/bot"+testToken+"/")
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"ok": false, "error_code": 401, "description": "Unauthorized"}`)
+			return
+		}
+		var params map[string]any
+		json.NewDecoder(r.Body).Decode(&params)
+		mu.Lock()
+		requests[method] = params
+		mu.Unlock()
+		io.WriteString(w, methods[method])
+	}))
+	t.Cleanup(server.Close)
+	return NewClient(testToken, server.URL+"/"), func(method string) map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests[method]
+	}
+}
+
+func TestClient(t *testing.T) {
+	client, params := fakeAPI(t, map[string]string{
+		"getMe":       `{"ok": true, "result": {"id": 123456, "is_bot": true, "first_name": "golte", "username": "golte_bot"}}`,
+		"sendMessage": `{"ok": true, "result": {"message_id": 7, "chat": {"id": -100123, "type": "supergroup"}, "date": 1, "text": "hi"}}`,
+		"getUpdates":  `{"ok": true, "result": [{"update_id": 42, "message": {"message_id": 8, "from": {"id": 99, "first_name": "Alice"}, "chat": {"id": 99, "type": "private"}, "date": 2, "text": "/send +33612345678 hello"}}]}`,
+	})
+	ctx := context.Background()
+
+	me, err := client.GetMe(ctx)
+	if err != nil || me.Username != "golte_bot" || !me.IsBot {
+		t.Errorf("GetMe() = %+v, %v", me, err)
+	}
+
+	sent, err := client.SendMessage(ctx, -100123, "<b>hi</b>", ParseModeHTML)
+	if err != nil || sent.MessageID != 7 {
+		t.Errorf("SendMessage() = %+v, %v", sent, err)
+	}
+	if p := params("sendMessage"); p["chat_id"] != float64(-100123) || p["text"] != "<b>hi</b>" || p["parse_mode"] != "HTML" {
+		t.Errorf("sendMessage params = %v", p)
+	}
+
+	updates, err := client.GetUpdates(ctx, 42, time.Second)
+	if err != nil || len(updates) != 1 || updates[0].Message == nil || updates[0].Message.Text != "/send +33612345678 hello" || updates[0].Message.From.ID != 99 {
+		t.Fatalf("GetUpdates() = %+v, %v", updates, err)
+	}
+	if p := params("getUpdates"); p["offset"] != float64(42) || p["timeout"] != float64(1) {
+		t.Errorf("getUpdates params = %v", p)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	client, _ := fakeAPI(t, map[string]string{
+		"sendMessage": `{"ok": false, "error_code": 429, "description": "Too Many Requests: retry after 5", "parameters": {"retry_after": 5}}`,
+	})
+
+	_, err := client.SendMessage(context.Background(), 1, "hi", "")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 429 || apiErr.RetryAfter != 5*time.Second {
+		t.Errorf("SendMessage() = %v, want a rate limit APIError", err)
+	}
+
+	bad := NewClient("654321:wrong", client.apiURL)
+	if _, err := bad.GetMe(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("GetMe() with a wrong token = %v, want ErrUnauthorized", err)
+	}
+
+	// Connection errors don't show the URL, it holds the token
+	down := NewClient(testToken, "http://127.0.0.1:1")
+	if _, err := down.GetMe(context.Background()); err == nil || strings.Contains(err.Error(), testToken) {
+		t.Errorf("GetMe() of a server down = %v, want an error without the token", err)
+	}
+}